@@ -1,8 +1,15 @@
 package broker
 
 import (
+	gocontext "context"
 	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	bus "github.com/moleculer-go/goemitter"
@@ -13,8 +20,10 @@ import (
 	"github.com/moleculer-go/moleculer/middleware"
 	"github.com/moleculer-go/moleculer/payload"
 	"github.com/moleculer-go/moleculer/registry"
+	"github.com/moleculer-go/moleculer/repl"
 	"github.com/moleculer-go/moleculer/serializer"
 	"github.com/moleculer-go/moleculer/service"
+	"github.com/moleculer-go/moleculer/transit"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -29,60 +38,48 @@ func mergeMaps(base, new map[string]interface{}) map[string]interface{} {
 	return base
 }
 
+// mergeConfigs merge the user configs over the base config, in order. Every field with a
+// non-zero value replaces the base value, nested structs (like RetryPolicy) are merged field
+// by field and the Services settings are merged by service name.
 func mergeConfigs(baseConfig moleculer.Config, userConfig []*moleculer.Config) moleculer.Config {
-	if len(userConfig) > 0 {
-		for _, config := range userConfig {
-			if config.Services != nil {
-				baseConfig.Services = mergeMaps(baseConfig.Services, config.Services)
-			}
-			if config.Namespace != "" {
-				baseConfig.Namespace = config.Namespace
-			}
-			if config.LogLevel != "" {
-				baseConfig.LogLevel = config.LogLevel
-			}
-			if config.LogFormat != "" {
-				baseConfig.LogFormat = config.LogFormat
-			}
-			if config.DiscoverNodeID != nil {
-				baseConfig.DiscoverNodeID = config.DiscoverNodeID
-			}
-			if config.Transporter != "" {
-				baseConfig.Transporter = config.Transporter
-			}
-			if config.TransporterFactory != nil {
-				baseConfig.TransporterFactory = config.TransporterFactory
-			}
-			if config.StrategyFactory != nil {
-				baseConfig.StrategyFactory = config.StrategyFactory
-			}
-			if config.DisableInternalMiddlewares {
-				baseConfig.DisableInternalMiddlewares = config.DisableInternalMiddlewares
-			}
-			if config.DisableInternalServices {
-				baseConfig.DisableInternalServices = config.DisableInternalServices
-			}
-			if config.Metrics {
-				baseConfig.Metrics = config.Metrics
-			}
-
-			if config.MetricsRate > 0 {
-				baseConfig.MetricsRate = config.MetricsRate
-			}
-
-			if config.DontWaitForNeighbours {
-				baseConfig.DontWaitForNeighbours = config.DontWaitForNeighbours
-			}
+	for _, config := range userConfig {
+		if config == nil {
+			continue
+		}
+		mergeFields(reflect.ValueOf(&baseConfig).Elem(), reflect.ValueOf(config).Elem())
+	}
+	return baseConfig
+}
 
-			if config.Middlewares != nil {
-				baseConfig.Middlewares = config.Middlewares
-			}
-			if config.RequestTimeout != 0 {
-				baseConfig.RequestTimeout = config.RequestTimeout
+func mergeFields(base, config reflect.Value) {
+	for index := 0; index < config.NumField(); index++ {
+		field := config.Field(index)
+		target := base.Field(index)
+		if !target.CanSet() || isZeroValue(field) {
+			continue
+		}
+		switch field.Kind() {
+		case reflect.Struct:
+			mergeFields(target, field)
+		case reflect.Map:
+			if services, isMap := field.Interface().(map[string]interface{}); isMap {
+				target.Set(reflect.ValueOf(mergeMaps(target.Interface().(map[string]interface{}), services)))
+				continue
 			}
+			target.Set(field)
+		default:
+			target.Set(field)
 		}
 	}
-	return baseConfig
+}
+
+// isZeroValue check if the field has the zero value of its type. Functions are zero only when nil.
+func isZeroValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Func, reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface, reflect.Chan:
+		return value.IsNil()
+	}
+	return reflect.DeepEqual(value.Interface(), reflect.Zero(value.Type()).Interface())
 }
 
 type ServiceBroker struct {
@@ -100,10 +97,12 @@ type ServiceBroker struct {
 
 	serializer *serializer.Serializer
 
-	services []*service.Service
+	services      []*service.Service
+	servicesMutex *sync.Mutex
 
-	started  bool
-	starting bool
+	// started and starting are read by the calls and WaitUntilStarted while Start runs in another goroutine.
+	started  atomicFlag
+	starting atomicFlag
 
 	rootContext moleculer.BrokerContext
 
@@ -124,7 +123,9 @@ func (broker *ServiceBroker) LocalBus() *bus.Emitter {
 // stopService stop the service.
 func (broker *ServiceBroker) stopService(svc *service.Service) {
 	broker.middlewares.CallHandlers("serviceStopping", svc)
-	svc.Stop(broker.rootContext.ChildActionContext("service.stop", payload.Empty()))
+	if err := svc.Stop(broker.rootContext.ChildActionContext("service.stop", payload.Empty())); err != nil {
+		broker.logger.Error("Broker - error stopping service: ", svc.FullName(), " - error: ", err)
+	}
 	broker.middlewares.CallHandlers("serviceStopped", svc)
 }
 
@@ -160,8 +161,9 @@ func (broker *ServiceBroker) applyServiceConfig(svc *service.Service) {
 	}
 }
 
-// startService start a service.
-func (broker *ServiceBroker) startService(svc *service.Service) {
+// startService start a service. The Started handler is called before the service is added
+// to the registry, so the service is only published when it started successfully.
+func (broker *ServiceBroker) startService(svc *service.Service) error {
 
 	broker.logger.Debug("Broker start service: ", svc.FullName())
 
@@ -171,98 +173,241 @@ func (broker *ServiceBroker) startService(svc *service.Service) {
 
 	broker.waitForDependencies(svc)
 
+	broker.wrapHandlers(svc)
+
+	if err := svc.Start(broker.rootContext.ChildActionContext("service.start", payload.Empty())); err != nil {
+		return errors.New("Could not start service: " + svc.FullName() + " - error: " + err.Error())
+	}
+
 	broker.registry.AddLocalService(svc)
 
 	broker.middlewares.CallHandlers("serviceStarted", svc)
-
-	svc.Start(broker.rootContext.ChildActionContext("service.start", payload.Empty()))
+	return nil
 }
 
 // waitForDependencies wait for all services listed in the service dependencies to be discovered.
+// Dependencies can require a version, a minimum number of nodes or only remote nodes, and the
+// service DependenciesTimeout takes precedence over the config WaitForDependenciesTimeout.
 func (broker *ServiceBroker) waitForDependencies(service *service.Service) {
-	if len(service.Dependencies()) == 0 {
+	dependencies := service.DependsOn()
+	if len(dependencies) == 0 {
 		return
 	}
+	timeout := service.DependenciesTimeout()
+	if timeout == 0 {
+		timeout = broker.config.WaitForDependenciesTimeout
+	}
 	start := time.Now()
 	for {
-		if !broker.started {
+		if !broker.started.isSet() && !broker.starting.isSet() {
 			break
 		}
 		found := true
-		for _, dependency := range service.Dependencies() {
-			known := broker.registry.KnowService(dependency)
-			if !known {
+		for _, dependency := range dependencies {
+			if !broker.registry.DependencyAvailable(dependency) {
 				found = false
 				break
 			}
 		}
 		if found {
-			broker.logger.Debug("waitForDependencies() - All dependencies were found :) -> service: ", service.Name(), " wait For Dependencies: ", service.Dependencies())
+			broker.logger.Debug("waitForDependencies() - All dependencies were found :) -> service: ", service.Name(), " wait For Dependencies: ", dependencies)
 			break
 		}
-		if time.Since(start) > broker.config.WaitForDependenciesTimeout {
-			broker.logger.Warn("waitForDependencies() - Time out ! service: ", service.Name(), " wait For Dependencies: ", service.Dependencies())
+		if time.Since(start) > timeout {
+			broker.logger.Warn("waitForDependencies() - Time out ! service: ", service.Name(), " wait For Dependencies: ", dependencies)
 			break
 		}
 		time.Sleep(time.Microsecond)
 	}
 }
 
+// broadcastLocal emit an internal event (e.g. $broker.started) on the local bus.
+// Local services with an event handler for it are notified by the registry.
 func (broker *ServiceBroker) broadcastLocal(eventName string, params ...interface{}) {
 	broker.LocalBus().EmitAsync(eventName, params)
 }
 
+// servicesChanged broadcast the $services.changed event when a local or remote service is added, removed
+// or updated. The payload has the service (name, version and nodeID) and the actions and events added
+// and removed, e.g. {"added": {"actions": ["math.add"], "events": []}, "removed": {...}}, so gateways
+// and metrics can react to each change instead of reloading all the services. The remote nodes emit
+// the event for the local services too, when they receive the node info sent for each change.
+func (broker *ServiceBroker) servicesChanged(args ...interface{}) {
+	localService := false
+	changed := map[string]interface{}{}
+	if len(args) > 0 {
+		if svc, ok := args[0].(map[string]string); ok {
+			localService = svc["nodeID"] == broker.id
+			for key, value := range svc {
+				changed[key] = value
+			}
+		}
+	}
+	params := map[string]interface{}{"localService": localService, "service": changed}
+	noChanges := map[string]interface{}{"actions": []string{}, "events": []string{}}
+	params["added"], params["removed"] = noChanges, noChanges
+	if len(args) > 1 {
+		if diff, ok := args[1].(map[string]interface{}); ok {
+			params["added"], params["removed"] = diff["added"], diff["removed"]
+		}
+	}
+	broker.broadcastLocal("$services.changed", params)
+}
+
+// createBrokerLogger create the logger of this broker, so the level and format of each broker
+// are independent and the global logrus logger is not changed. When Config.Logger is set
+// the entries are sent to it instead of the output.
 func (broker *ServiceBroker) createBrokerLogger() *log.Entry {
+	logger := log.New()
 	if strings.ToUpper(broker.config.LogFormat) == "JSON" {
-		log.SetFormatter(&log.JSONFormatter{})
+		logger.SetFormatter(&log.JSONFormatter{})
 	} else {
-		log.SetFormatter(&log.TextFormatter{})
+		logger.SetFormatter(&log.TextFormatter{})
 	}
 
 	if strings.ToUpper(broker.config.LogLevel) == "WARN" {
-		log.SetLevel(log.WarnLevel)
+		logger.SetLevel(log.WarnLevel)
 	} else if strings.ToUpper(broker.config.LogLevel) == "DEBUG" {
-		log.SetLevel(log.DebugLevel)
+		logger.SetLevel(log.DebugLevel)
 	} else if strings.ToUpper(broker.config.LogLevel) == "TRACE" {
-		log.SetLevel(log.TraceLevel)
+		logger.SetLevel(log.TraceLevel)
 	} else if strings.ToUpper(broker.config.LogLevel) == "ERROR" {
-		log.SetLevel(log.ErrorLevel)
+		logger.SetLevel(log.ErrorLevel)
 	} else if strings.ToUpper(broker.config.LogLevel) == "FATAL" {
-		log.SetLevel(log.FatalLevel)
+		logger.SetLevel(log.FatalLevel)
 	} else {
-		log.SetLevel(log.InfoLevel)
+		logger.SetLevel(log.InfoLevel)
+	}
+
+	if broker.config.Logger != nil {
+		logger.SetOutput(ioutil.Discard)
+		logger.AddHook(&loggerHook{broker.config.Logger})
 	}
 
-	brokerLogger := log.WithFields(log.Fields{
+	brokerLogger := logger.WithFields(log.Fields{
 		"broker": broker.id,
 	})
 	//broker.logger.Debug("Broker Log Setup -> Level", log.GetLevel(), " nodeID: ", nodeID)
 	return brokerLogger
 }
 
+// loggerHook send the log entries to the logger informed in the config.
+type loggerHook struct {
+	logger moleculer.Logger
+}
+
+func (hook *loggerHook) Levels() []log.Level {
+	return log.AllLevels
+}
+
+func (hook *loggerHook) Fire(entry *log.Entry) error {
+	hook.logger.Log(entry.Level, entry.Message, entry.Data)
+	return nil
+}
+
 // addService internal addService .. adds one service.Service instance to broker.services list.
-func (broker *ServiceBroker) addService(svc *service.Service) {
+// When the broker is started the service is started and it is not added when it fails to start.
+func (broker *ServiceBroker) addService(svc *service.Service) error {
 	svc.SetNodeID(broker.localNode.GetID())
-	broker.services = append(broker.services, svc)
-	if broker.started || broker.starting {
-		broker.startService(svc)
+	if broker.started.isSet() || broker.starting.isSet() {
+		if err := broker.startService(svc); err != nil {
+			return err
+		}
 	}
+	broker.servicesMutex.Lock()
+	broker.services = append(broker.services, svc)
+	broker.servicesMutex.Unlock()
 	broker.logger.Debug("Broker - addService() - fullname: ", svc.FullName(), " # actions: ", len(svc.Actions()), " # events: ", len(svc.Events()))
+	return nil
+}
+
+// DestroyService stop the service with the given name and remove it from the broker. The name can
+// include the version (e.g. v2.users). Remote nodes receive the updated node info.
+func (broker *ServiceBroker) DestroyService(name string) error {
+	return broker.destroyServices(func(svc *service.Service) bool {
+		return svc.Name() == name || svc.FullName() == name
+	}, name)
+}
+
+// DestroyServiceVersion stop the service with the given name and version and remove it from the broker.
+func (broker *ServiceBroker) DestroyServiceVersion(name, version string) error {
+	return broker.destroyServices(func(svc *service.Service) bool {
+		return svc.FullName() == service.JoinVersionToName(name, version)
+	}, name+" version: "+version)
+}
+
+// destroyServices stop and remove the services that match.
+func (broker *ServiceBroker) destroyServices(match func(svc *service.Service) bool, label string) error {
+	var toKeep, destroyed []*service.Service
+	broker.servicesMutex.Lock()
+	for _, svc := range broker.services {
+		if match(svc) {
+			destroyed = append(destroyed, svc)
+		} else {
+			toKeep = append(toKeep, svc)
+		}
+	}
+	if len(destroyed) > 0 {
+		broker.services = toKeep
+	}
+	broker.servicesMutex.Unlock()
+	if len(destroyed) == 0 {
+		return errors.New("DestroyService() - Service not found: " + label)
+	}
+	for _, svc := range destroyed {
+		if broker.started.isSet() || broker.starting.isSet() {
+			broker.stopService(svc)
+			broker.registry.RemoveLocalService(svc)
+		}
+		broker.logger.Debug("Broker - DestroyService() - fullname: ", svc.FullName())
+	}
+	return nil
 }
 
 // createService create a new service instance, from a struct or a schema :)
+// createService create the service from a schema or an object. The createService middlewares
+// receive the schema and can change it, e.g. to add actions or mixins.
 func (broker *ServiceBroker) createService(svc interface{}) (*service.Service, error) {
 	schema, isSchema := svc.(moleculer.ServiceSchema)
 	if !isSchema {
-		svc, err := service.FromObject(svc, broker.delegates)
+		var err error
+		schema, err = service.SchemaFromObject(svc)
 		if err != nil {
 			return nil, err
 		}
-		return svc, nil
 	}
+	schema = broker.middlewares.CallHandlers("createService", schema).(moleculer.ServiceSchema)
 	return service.FromSchema(schema, broker.delegates), nil
 }
 
+// wrapHandlers wrap the service action and event handlers with the localAction and localEvent middlewares.
+func (broker *ServiceBroker) wrapHandlers(svc *service.Service) {
+	if broker.middlewares.Has("localAction") {
+		svc.WrapActions(func(action service.Action) moleculer.ActionHandler {
+			params := broker.middlewares.CallHandlers("localAction", middleware.ActionHandlerParams{
+				Action:   action.FullName(),
+				Service:  svc.FullName(),
+				NodeID:   svc.NodeID(),
+				Handler:  action.Handler(),
+				Bulkhead: action.Bulkhead(),
+				Cache:    action.Cache(),
+			})
+			return params.(middleware.ActionHandlerParams).Handler
+		})
+	}
+	if broker.middlewares.Has("localEvent") {
+		svc.WrapEvents(func(event service.Event) moleculer.EventHandler {
+			params := broker.middlewares.CallHandlers("localEvent", middleware.EventHandlerParams{
+				Event:   event.Name(),
+				Group:   event.Group(),
+				Service: svc.FullName(),
+				Handler: event.Handler(),
+			})
+			return params.(middleware.EventHandlerParams).Handler
+		})
+	}
+}
+
 // WaitFor : wait for all services to be available
 func (broker *ServiceBroker) WaitFor(services ...string) error {
 	for _, svc := range services {
@@ -273,6 +418,53 @@ func (broker *ServiceBroker) WaitFor(services ...string) error {
 	return nil
 }
 
+// WaitForServices wait for the services to be available in the registry, either local or
+// in a remote node. Versioned services use the full name, e.g. v2.orders. When the timeout is 0
+// the WaitForDependenciesTimeout config is used. It returns an error with the type
+// WAITFOR_SERVICES and the missing services when they are not found within the timeout.
+func (broker *ServiceBroker) WaitForServices(services []string, timeout time.Duration) error {
+	if timeout == 0 {
+		timeout = broker.config.WaitForDependenciesTimeout
+	}
+	start := time.Now()
+	for {
+		missing := broker.missingServices(services)
+		if len(missing) == 0 {
+			return nil
+		}
+		if time.Since(start) > timeout {
+			err := moleculer.NewError("Services waiting is timed out.", 500, "WAITFOR_SERVICES", map[string]interface{}{"services": missing})
+			broker.logger.Error("WaitForServices() - Timeout ! missing services: ", missing)
+			return err
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func (broker *ServiceBroker) missingServices(services []string) []string {
+	missing := []string{}
+	for _, name := range services {
+		if !broker.registry.KnowService(name) {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// WaitUntilStarted wait for the broker to be started, e.g. when Start is called in another goroutine.
+// It returns moleculer.ErrBrokerNotStarted when the broker is not started within the timeout.
+func (broker *ServiceBroker) WaitUntilStarted(timeout time.Duration) error {
+	start := time.Now()
+	for !broker.IsStarted() {
+		if time.Since(start) > timeout {
+			broker.logger.Error("WaitUntilStarted() - Timeout ! error: ", moleculer.ErrBrokerNotStarted)
+			return moleculer.ErrBrokerNotStarted
+		}
+		time.Sleep(time.Millisecond)
+	}
+	return nil
+}
+
 // WaitForNodes : wait for all nodes to be available
 func (broker *ServiceBroker) WaitForNodes(nodes ...string) error {
 	for _, nodeID := range nodes {
@@ -353,19 +545,24 @@ func (broker *ServiceBroker) waitForNode(nodeID string) error {
 func (broker *ServiceBroker) Publish(services ...interface{}) {
 	for _, item := range services {
 		svc, err := broker.createService(item)
+		if err == nil {
+			err = broker.addService(svc)
+		}
 		if err != nil {
 			panic(errors.New("Could not publish service - error: " + err.Error()))
 		}
-		broker.addService(svc)
 	}
 }
 
-func (broker *ServiceBroker) Start() {
+// Start start the registry and the services, calling the Started handlers in the order the
+// services were published. When a service fails to start the services already started are
+// stopped in the reverse order, the broker is not started and the error is returned.
+func (broker *ServiceBroker) Start() error {
 	if broker.IsStarted() {
 		broker.logger.Warn("broker.Start() called on a broker that already started!")
-		return
+		return nil
 	}
-	broker.starting = true
+	broker.starting.set(true)
 	broker.logger.Info("Moleculer is starting...")
 	broker.logger.Info("Node ID: ", broker.localNode.GetID())
 
@@ -374,31 +571,54 @@ func (broker *ServiceBroker) Start() {
 	broker.registry.Start()
 
 	internalServices := broker.registry.LocalServices()
-	for _, service := range internalServices {
-		service.SetNodeID(broker.localNode.GetID())
-		broker.startService(service)
-	}
-
-	for _, service := range broker.services {
-		broker.startService(service)
-	}
-
-	for _, service := range internalServices {
-		broker.addService(service)
+	services := broker.localServices()
+	started := make([]*service.Service, 0, len(internalServices)+len(services))
+	for _, svc := range append(internalServices, services...) {
+		svc.SetNodeID(broker.localNode.GetID())
+		if err := broker.startService(svc); err != nil {
+			broker.abortStart(started, err)
+			return err
+		}
+		started = append(started, svc)
 	}
+	broker.servicesMutex.Lock()
+	broker.services = append(broker.services, internalServices...)
+	broker.servicesMutex.Unlock()
 
 	broker.logger.Debug("Broker -> registry started!")
 
 	defer broker.broadcastLocal("$broker.started")
 	defer broker.middlewares.CallHandlers("brokerStarted", broker.delegates)
 
-	broker.started = true
-	broker.starting = false
-	broker.logger.Info("Service Broker with ", len(broker.services), " service(s) started successfully.")
+	broker.started.set(true)
+	broker.starting.set(false)
+	if broker.config.Started != nil {
+		broker.config.Started(broker.delegates)
+	}
+	broker.logger.Info("Service Broker with ", len(started), " service(s) started successfully.")
+	return nil
+}
+
+// localServices return a copy of the services of the broker, that can be published while it starts and stops.
+func (broker *ServiceBroker) localServices() []*service.Service {
+	broker.servicesMutex.Lock()
+	defer broker.servicesMutex.Unlock()
+	return append([]*service.Service{}, broker.services...)
+}
+
+// abortStart stop the services already started in the reverse order and the registry, when a service fails to start.
+func (broker *ServiceBroker) abortStart(started []*service.Service, err error) {
+	broker.logger.Error("Broker - start failed - error: ", err)
+	for index := len(started) - 1; index >= 0; index-- {
+		broker.stopService(started[index])
+		broker.registry.RemoveLocalService(started[index])
+	}
+	broker.registry.Stop()
+	broker.starting.set(false)
 }
 
 func (broker *ServiceBroker) Stop() {
-	if !broker.started {
+	if !broker.started.isSet() {
 		broker.logger.Info("Broker is not started!")
 		return
 	}
@@ -406,13 +626,20 @@ func (broker *ServiceBroker) Stop() {
 
 	broker.middlewares.CallHandlers("brokerStopping", broker.delegates)
 
-	for _, service := range broker.services {
-		broker.stopService(service)
+	services := broker.localServices()
+	for index := len(services) - 1; index >= 0; index-- {
+		broker.stopService(services[index])
 	}
 
 	broker.registry.Stop()
+	if broker.cache != nil {
+		broker.cache.Close()
+	}
 
-	broker.started = false
+	broker.started.set(false)
+	if broker.config.Stopped != nil {
+		broker.config.Stopped(broker.delegates)
+	}
 	broker.broadcastLocal("$broker.stopped")
 
 	broker.middlewares.CallHandlers("brokerStopped", broker.delegates)
@@ -423,18 +650,18 @@ type callPair struct {
 	result moleculer.Payload
 }
 
-func (broker *ServiceBroker) invokeMCalls(callMaps map[string]map[string]interface{}, result chan map[string]moleculer.Payload) {
-	if len(callMaps) == 0 {
+func (broker *ServiceBroker) invokeMCalls(calls map[string]moleculer.CallDefinition, result chan map[string]moleculer.Payload) {
+	if len(calls) == 0 {
 		result <- make(map[string]moleculer.Payload)
 		return
 	}
 
 	resultChan := make(chan callPair)
-	for label, content := range callMaps {
-		go func(label, actionName string, params interface{}, results chan callPair) {
-			result := <-broker.Call(actionName, params)
+	for label, call := range calls {
+		go func(label string, call moleculer.CallDefinition, results chan callPair) {
+			result := <-broker.Call(call.Action, call.Params, call.Options)
 			results <- callPair{label, result}
-		}(label, content["action"].(string), content["params"], resultChan)
+		}(label, call, resultChan)
 	}
 
 	timeoutChan := make(chan bool, 1)
@@ -448,14 +675,14 @@ func (broker *ServiceBroker) invokeMCalls(callMaps map[string]map[string]interfa
 		select {
 		case pair := <-resultChan:
 			results[pair.label] = pair.result
-			if len(results) == len(callMaps) {
+			if len(results) == len(calls) {
 				result <- results
 				return
 			}
 		case <-timeoutChan:
 			timeoutError := errors.New("MCall timeout error.")
 			broker.logger.Error(timeoutError)
-			for label, _ := range callMaps {
+			for label, _ := range calls {
 				if _, exists := results[label]; !exists {
 					results[label] = payload.New(timeoutError)
 				}
@@ -466,43 +693,213 @@ func (broker *ServiceBroker) invokeMCalls(callMaps map[string]map[string]interfa
 	}
 }
 
-// MCall perform multiple calls and return all results together in a nice map indexed by name.
-func (broker *ServiceBroker) MCall(callMaps map[string]map[string]interface{}) chan map[string]moleculer.Payload {
+// multCall perform the calls in the format used by the context (label -> {action, params})
+// and return a channel which will deliver all results together.
+func (broker *ServiceBroker) multCall(callMaps map[string]map[string]interface{}) chan map[string]moleculer.Payload {
+	calls := make(map[string]moleculer.CallDefinition, len(callMaps))
+	for label, content := range callMaps {
+		action, _ := content["action"].(string)
+		calls[label] = moleculer.CallDefinition{Action: action, Params: content["params"]}
+	}
 	result := make(chan map[string]moleculer.Payload, 1)
-	go broker.invokeMCalls(callMaps, result)
+	go broker.invokeMCalls(calls, result)
 	return result
 }
 
+// MCall perform multiple calls concurrently and wait for all of them. The result is a map payload
+// with the result of each call indexed by its label. Failed calls (and calls not completed
+// within Config.MCallTimeout) have an error payload in their label.
+func (broker *ServiceBroker) MCall(calls map[string]moleculer.CallDefinition) moleculer.Payload {
+	result := make(chan map[string]moleculer.Payload, 1)
+	go broker.invokeMCalls(calls, result)
+	results := make(map[string]interface{})
+	for label, value := range <-result {
+		results[label] = value
+	}
+	return payload.New(results)
+}
+
+// CallSync invoke a service action and wait for the result. The error is the one returned by the action,
+// in which case the result payload contains the same error.
+func (broker *ServiceBroker) CallSync(actionName string, params interface{}, opts ...moleculer.Options) (moleculer.Payload, error) {
+	result := <-broker.Call(actionName, params, opts...)
+	if result.IsError() {
+		return result, result.Error()
+	}
+	return result, nil
+}
+
 // Call :  invoke a service action and return a channel which will eventualy deliver the results ;)
 func (broker *ServiceBroker) Call(actionName string, params interface{}, opts ...moleculer.Options) chan moleculer.Payload {
 	broker.logger.Trace("Broker - Call() actionName: ", actionName, " params: ", params, " opts: ", opts)
 	if !broker.IsStarted() {
-		panic(errors.New("Broker must be started before making calls :("))
+		broker.logger.Error("Broker - Call() action: ", actionName, " error: ", moleculer.ErrBrokerNotStarted)
+		result := make(chan moleculer.Payload, 1)
+		result <- payload.New(moleculer.ErrBrokerNotStarted)
+		return result
 	}
 	actionContext := broker.rootContext.ChildActionContext(actionName, payload.New(params), opts...)
 	return broker.registry.LoadBalanceCall(actionContext, opts...)
 }
 
-func (broker *ServiceBroker) Emit(event string, params interface{}, groups ...string) {
+// CallContext invoke a service action like Call, but the request is cancelled when ctx is done:
+// the result is an error and the remote node is notified to stop the action. The deadline of
+// ctx is sent to the remote node as the request timeout.
+func (broker *ServiceBroker) CallContext(ctx gocontext.Context, actionName string, params interface{}, opts ...moleculer.Options) chan moleculer.Payload {
+	if err := ctx.Err(); err != nil {
+		result := make(chan moleculer.Payload, 1)
+		result <- payload.New(fmt.Errorf("RequestCancelled: Request to call '%s' action was cancelled before it started. Error: %s", actionName, err))
+		return result
+	}
+	options := moleculer.Options{}
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	options.Context = ctx
+	return broker.Call(actionName, params, options)
+}
+
+// Emit a balanced event. It returns moleculer.ErrBrokerNotStarted when the broker is not started.
+func (broker *ServiceBroker) Emit(event string, params interface{}, groups ...string) error {
 	broker.logger.Trace("Broker - Emit() event: ", event, " params: ", params, " groups: ", groups)
 	if !broker.IsStarted() {
-		panic(errors.New("Broker must be started before emiting events :("))
+		broker.logger.Error("Broker - Emit() event: ", event, " error: ", moleculer.ErrBrokerNotStarted)
+		return moleculer.ErrBrokerNotStarted
 	}
 	newContext := broker.rootContext.ChildEventContext(event, payload.New(params), groups, false)
 	broker.registry.LoadBalanceEvent(newContext)
+	return nil
+}
+
+// EmitWithOptions emit a balanced event to the groups of the options. When opts.NodeID is set only
+// the handlers of that node receive the event. It returns moleculer.ErrBrokerNotStarted when the
+// broker is not started.
+func (broker *ServiceBroker) EmitWithOptions(event string, params interface{}, opts moleculer.EventOptions) error {
+	broker.logger.Trace("Broker - EmitWithOptions() event: ", event, " params: ", params, " opts: ", opts)
+	if !broker.IsStarted() {
+		broker.logger.Error("Broker - EmitWithOptions() event: ", event, " error: ", moleculer.ErrBrokerNotStarted)
+		return moleculer.ErrBrokerNotStarted
+	}
+	newContext := broker.rootContext.ChildEventContext(event, payload.New(params), opts.Groups, false)
+	newContext.SetTargetNodeID(opts.NodeID)
+	broker.registry.LoadBalanceEvent(newContext)
+	return nil
+}
+
+// EmitConfirmed emit a balanced event and wait until the transporter acknowledges the
+// remote packets, retrying on failure according to the Config.EventDelivery policy.
+// The returned channel receives nil when delivered or the error of the last attempt.
+// Transports that do not support confirmations (e.g. NATS) are used in best-effort mode.
+func (broker *ServiceBroker) EmitConfirmed(event string, params interface{}, groups ...string) chan error {
+	broker.logger.Trace("Broker - EmitConfirmed() event: ", event, " params: ", params, " groups: ", groups)
+	result := make(chan error, 1)
+	if !broker.IsStarted() {
+		result <- moleculer.ErrBrokerNotStarted
+		return result
+	}
+	newContext := broker.rootContext.ChildEventContext(event, payload.New(params), groups, false)
+	go func() {
+		result <- broker.registry.LoadBalanceEventConfirmed(newContext)
+	}()
+	return result
+}
+
+// EmitLocal emit a balanced event to the handlers of the local services only. The event is not
+// sent to the transporter, so the remote nodes never receive it. It returns
+// moleculer.ErrBrokerNotStarted when the broker is not started.
+func (broker *ServiceBroker) EmitLocal(event string, params interface{}, groups ...string) error {
+	broker.logger.Trace("Broker - EmitLocal() event: ", event, " params: ", params, " groups: ", groups)
+	if !broker.IsStarted() {
+		broker.logger.Error("Broker - EmitLocal() event: ", event, " error: ", moleculer.ErrBrokerNotStarted)
+		return moleculer.ErrBrokerNotStarted
+	}
+	newContext := broker.rootContext.ChildEventContext(event, payload.New(params), groups, false)
+	broker.registry.EmitLocalEvent(newContext)
+	return nil
 }
 
-func (broker *ServiceBroker) Broadcast(event string, params interface{}, groups ...string) {
+// Broadcast an event to all nodes. It returns moleculer.ErrBrokerNotStarted when the broker is not started.
+func (broker *ServiceBroker) Broadcast(event string, params interface{}, groups ...string) error {
 	broker.logger.Trace("Broker - Broadcast() event: ", event, " params: ", params, " groups: ", groups)
 	if !broker.IsStarted() {
-		panic(errors.New("Broker must be started before broadcasting events :("))
+		broker.logger.Error("Broker - Broadcast() event: ", event, " error: ", moleculer.ErrBrokerNotStarted)
+		return moleculer.ErrBrokerNotStarted
 	}
 	newContext := broker.rootContext.ChildEventContext(event, payload.New(params), groups, true)
 	broker.registry.BroadcastEvent(newContext)
+	return nil
+}
+
+// BroadcastWithOptions broadcast an event to the groups of the options. When opts.NodeID is set only
+// the handlers of that node receive the event. It returns moleculer.ErrBrokerNotStarted when the
+// broker is not started.
+func (broker *ServiceBroker) BroadcastWithOptions(event string, params interface{}, opts moleculer.EventOptions) error {
+	broker.logger.Trace("Broker - BroadcastWithOptions() event: ", event, " params: ", params, " opts: ", opts)
+	if !broker.IsStarted() {
+		broker.logger.Error("Broker - BroadcastWithOptions() event: ", event, " error: ", moleculer.ErrBrokerNotStarted)
+		return moleculer.ErrBrokerNotStarted
+	}
+	newContext := broker.rootContext.ChildEventContext(event, payload.New(params), opts.Groups, true)
+	newContext.SetTargetNodeID(opts.NodeID)
+	broker.registry.BroadcastEvent(newContext)
+	return nil
+}
+
+// PauseTransit stops processing the requests and events from remote nodes, e.g. during
+// a local overload. Heartbeats keep flowing, so the node is not considered offline.
+func (broker *ServiceBroker) PauseTransit() {
+	broker.registry.PauseTransit()
+}
+
+// ResumeTransit restart processing the requests and events from remote nodes.
+func (broker *ServiceBroker) ResumeTransit() {
+	broker.registry.ResumeTransit()
+}
+
+func (broker *ServiceBroker) IsTransitPaused() bool {
+	return broker.registry.IsTransitPaused()
+}
+
+// TransitStatus return the state of the connection with the transporter: connected,
+// number of reconnects, last error and the round-trip latency with the other nodes.
+// Services can also listen to the $transporter.connected, $transporter.disconnected,
+// $transporter.reconnected and $transporter.error events.
+func (broker *ServiceBroker) TransitStatus() transit.ConnectionStatus {
+	return broker.registry.TransitStatus()
+}
+
+// KnownNodes return the nodes known by the registry, with their availability, latency and services.
+func (broker *ServiceBroker) KnownNodes() []registry.NodeInfo {
+	return broker.registry.KnownNodesInfo()
+}
+
+// ActionsFor return the actions of the service with the nodes where they can be called. The name
+// can include the version (e.g. v2.users), otherwise the actions of all versions are returned.
+func (broker *ServiceBroker) ActionsFor(service string) []registry.ActionInfo {
+	return broker.registry.ActionsFor(service)
+}
+
+// EndpointsFor return the nodes where the action can be called, with their availability and
+// latency, so gateways can inspect the routing state.
+func (broker *ServiceBroker) EndpointsFor(action string) []registry.EndpointInfo {
+	return broker.registry.EndpointsFor(action)
+}
+
+// HealthData return the health of the local node: cpu, memory, uptime, transit status and the
+// status of each local service, the same payload returned by the $node.health action.
+// It can back the liveness and readiness probes of orchestrators like Kubernetes.
+func (broker *ServiceBroker) HealthData() moleculer.Payload {
+	return payload.New(broker.registry.Health())
+}
+
+// StartRepl start an interactive console in the terminal to call actions, emit events and
+// inspect the nodes, services, actions and events of the cluster. It blocks until the exit command.
+func (broker *ServiceBroker) StartRepl() error {
+	return repl.New(broker, os.Stdin, os.Stdout).Run()
 }
 
 func (broker *ServiceBroker) IsStarted() bool {
-	return broker.started
+	return broker.started.isSet()
 }
 
 func (broker *ServiceBroker) GetLogger(name string, value string) *log.Entry {
@@ -520,9 +917,15 @@ func (broker *ServiceBroker) newLogger(name string, value string) *log.Entry {
 func (broker *ServiceBroker) setupLocalBus() {
 	broker.localBus = bus.Construct()
 
-	broker.localBus.On("$registry.service.added", func(args ...interface{}) {
-		//TODO check code from -> this.broker.servicesChanged(true)
-	})
+	broker.localBus.On("$registry.service.added", broker.servicesChanged)
+	broker.localBus.On("$registry.service.removed", broker.servicesChanged)
+	broker.localBus.On("$registry.service.updated", broker.servicesChanged)
+	if broker.config.StopOnNodeIDConflict {
+		broker.localBus.Once("$node.id.conflict", func(...interface{}) {
+			broker.logger.Error("Stopping the broker - another broker instance uses the node ID: ", broker.id)
+			go broker.Stop()
+		})
+	}
 }
 
 func (broker *ServiceBroker) registerMiddlewares() {
@@ -530,17 +933,49 @@ func (broker *ServiceBroker) registerMiddlewares() {
 	for _, mware := range broker.config.Middlewares {
 		broker.middlewares.Add(mware)
 	}
+	if broker.config.AccessLog {
+		broker.middlewares.Add(middleware.AccessLog())
+	}
+	broker.middlewares.Add(middleware.Bulkhead(broker.config.Bulkhead))
+	broker.cache = broker.createCacher()
+	if broker.cache != nil {
+		broker.middlewares.Add(middleware.Cacher(broker.cache))
+	}
 	if !broker.config.DisableInternalMiddlewares {
 		broker.registerInternalMiddlewares()
 	}
 }
 
+// createCacher create the cache of the config: the CacherFactory, or the cache of the Cacher url.
+// It returns nil when none is configured.
+func (broker *ServiceBroker) createCacher() cache.Cache {
+	if broker.config.CacherFactory != nil {
+		return broker.config.CacherFactory().(cache.Cache)
+	}
+	if broker.config.Cacher != "" {
+		if cacher, exists := cache.Create(broker.config.Cacher, broker.logger.WithField("cache", broker.config.Cacher)); exists {
+			return cacher
+		}
+		broker.logger.Warn("Cacher not found: ", broker.config.Cacher)
+	}
+	return nil
+}
+
+// Cacher return the cache of the action results, nil when the Config.Cacher is not set.
+// Services can use it to clean the cached results when the data changes, e.g. Cacher().Clean("users.*").
+func (broker *ServiceBroker) Cacher() cache.Cache {
+	return broker.cache
+}
+
 func (broker *ServiceBroker) registerInternalMiddlewares() {
 	broker.middlewares.Add(metrics.Middlewares())
 }
 
 func (broker *ServiceBroker) init() {
-	broker.id = broker.config.DiscoverNodeID()
+	broker.id = broker.config.NodeID
+	if broker.id == "" {
+		broker.id = broker.config.DiscoverNodeID()
+	}
 	broker.logger = broker.createBrokerLogger()
 	broker.setupLocalBus()
 
@@ -553,6 +988,9 @@ func (broker *ServiceBroker) init() {
 	broker.localNode = broker.registry.LocalNode()
 	broker.rootContext = context.BrokerContext(broker.delegates)
 
+	if broker.config.Created != nil {
+		broker.config.Created(broker.delegates)
+	}
 }
 
 func (broker *ServiceBroker) createDelegates() *moleculer.BrokerDelegates {
@@ -586,7 +1024,7 @@ func (broker *ServiceBroker) createDelegates() *moleculer.BrokerDelegates {
 			return nil
 		},
 		MultActionDelegate: func(callMaps map[string]map[string]interface{}) chan map[string]moleculer.Payload {
-			return broker.MCall(callMaps)
+			return broker.multCall(callMaps)
 		},
 		BrokerContext: func() moleculer.BrokerContext {
 			return broker.rootContext
@@ -594,6 +1032,12 @@ func (broker *ServiceBroker) createDelegates() *moleculer.BrokerDelegates {
 		MiddlewareHandler: broker.middlewares.CallHandlers,
 		Publish:           broker.Publish,
 		WaitFor:           broker.WaitFor,
+		KnowNode: func(nodeID string) bool {
+			return broker.registry.KnowNode(nodeID)
+		},
+		IsNodeAvailable: func(nodeID string) bool {
+			return broker.registry.IsNodeAvailable(nodeID)
+		},
 	}
 }
 
@@ -601,7 +1045,22 @@ func (broker *ServiceBroker) createDelegates() *moleculer.BrokerDelegates {
 // this is usually called when creating a broker to starting the service(s)
 func New(userConfig ...*moleculer.Config) *ServiceBroker {
 	config := mergeConfigs(moleculer.DefaultConfig, userConfig)
-	broker := ServiceBroker{config: config}
+	broker := ServiceBroker{config: config, servicesMutex: &sync.Mutex{}}
 	broker.init()
 	return &broker
 }
+
+// atomicFlag is a bool read and written by different goroutines.
+type atomicFlag int32
+
+func (flag *atomicFlag) set(value bool) {
+	var intValue int32
+	if value {
+		intValue = 1
+	}
+	atomic.StoreInt32((*int32)(flag), intValue)
+}
+
+func (flag *atomicFlag) isSet() bool {
+	return atomic.LoadInt32((*int32)(flag)) == 1
+}
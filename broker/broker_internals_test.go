@@ -176,6 +176,8 @@ var _ = Describe("Broker Internals", func() {
 				})
 				visualBroker.Start()
 				visualBroker.WaitForNodes("SoundsBroker")
+				//the vj service waits for its dependencies, so it is published after the start.
+				Expect(soundsBroker.WaitForServices([]string{"vj"}, time.Second)).Should(Succeed())
 				Expect(snap.SnapshotMulti("visualBroker-KnownNodes", visualBroker.registry.KnownNodes())).Should(Succeed())
 
 				counters.Clear()
@@ -438,10 +440,10 @@ var _ = Describe("Broker Internals", func() {
 				},
 			}
 
-			mcallResults := <-bkr2.MCall(mParams)
+			mcallResults := <-bkr2.multCall(mParams)
 			Expect(snap.SnapshotMulti("bkr2-results", mcallResults)).Should(Succeed())
 
-			mcallResults = <-bkr1.MCall(mParams)
+			mcallResults = <-bkr1.multCall(mParams)
 			Expect(snap.SnapshotMulti("bkr1-results", mcallResults)).Should(Succeed())
 
 			bkr1.Stop()
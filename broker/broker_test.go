@@ -1,14 +1,27 @@
 package broker_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/moleculer-go/moleculer/transit/memory"
 	log "github.com/sirupsen/logrus"
 
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/broker"
+	"github.com/moleculer-go/moleculer/discovery"
+	"github.com/moleculer-go/moleculer/middleware"
+	"github.com/moleculer-go/moleculer/payload"
+	"github.com/moleculer-go/moleculer/registry"
+	"github.com/moleculer-go/moleculer/strategy"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -73,6 +86,7 @@ var _ = Describe("Broker", func() {
 		bkr := broker.New(baseConfig, bkrConfig)
 		bkr.Publish(service)
 		bkr.Start()
+		defer bkr.Stop()
 
 		result := <-bkr.Call("do.panic", true)
 
@@ -102,6 +116,7 @@ var _ = Describe("Broker", func() {
 		bkrRemote := broker.New(baseConfig, bkrConfig)
 		bkrRemote.Publish(service)
 		bkrRemote.Start()
+		defer bkrRemote.Stop()
 
 		bkrRemote.WaitFor("do")
 		result = <-bkrRemote.Call("remote.panic", true)
@@ -114,9 +129,245 @@ var _ = Describe("Broker", func() {
 
 		Expect(result.IsError()).Should(Equal(false))
 		Expect(result.String()).Should(BeEquivalentTo("no panic"))
+	})
+
+	It("Should return ErrBrokerNotStarted for calls and events before the broker is started", func() {
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "fatal",
+			DiscoverNodeID: func() string { return "not-started-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "do",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "stuff",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return "done"
+					},
+				},
+			},
+		})
+
+		result := <-bkr.Call("do.stuff", nil)
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(result.Error()).Should(Equal(moleculer.ErrBrokerNotStarted))
+		_, err := bkr.CallSync("do.stuff", nil)
+		Expect(err).Should(Equal(moleculer.ErrBrokerNotStarted))
+		Expect(bkr.Emit("stuff.done", nil)).Should(Equal(moleculer.ErrBrokerNotStarted))
+		Expect(bkr.Broadcast("stuff.done", nil)).Should(Equal(moleculer.ErrBrokerNotStarted))
+		Expect(bkr.EmitLocal("stuff.done", nil)).Should(Equal(moleculer.ErrBrokerNotStarted))
+		Expect(<-bkr.EmitConfirmed("stuff.done", nil)).Should(Equal(moleculer.ErrBrokerNotStarted))
+		Expect(bkr.WaitUntilStarted(10 * time.Millisecond)).Should(Equal(moleculer.ErrBrokerNotStarted))
+
+		go bkr.Start()
+		Expect(bkr.WaitUntilStarted(time.Second)).Should(Succeed())
+		defer bkr.Stop()
+		Expect(bkr.Emit("stuff.done", nil)).Should(Succeed())
+		result = <-bkr.Call("do.stuff", nil)
+		Expect(result.String()).Should(Equal("done"))
+	})
+
+	It("Should wait for local and remote services", func() {
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "fatal",
+			Transporter:    "memory://wait-for-services",
+			DiscoverNodeID: func() string { return "waiting-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{Name: "local"})
+		bkr.Start()
+		defer bkr.Stop()
+
+		Expect(bkr.WaitForServices([]string{"local"}, time.Second)).Should(Succeed())
+		err := bkr.WaitForServices([]string{"local", "v2.remote"}, 20*time.Millisecond)
+		Expect(err).Should(HaveOccurred())
+		Expect(err.(*moleculer.Error).Type).Should(Equal("WAITFOR_SERVICES"))
+		Expect(err.(*moleculer.Error).Data).Should(Equal(map[string]interface{}{"services": []string{"v2.remote"}}))
+
+		bkrRemote := broker.New(&moleculer.Config{
+			LogLevel:       "fatal",
+			Transporter:    "memory://wait-for-services",
+			DiscoverNodeID: func() string { return "remote-broker" },
+		})
+		bkrRemote.Publish(moleculer.ServiceSchema{Name: "remote", Version: "v2"})
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			bkrRemote.Start()
+		}()
+		defer bkrRemote.Stop()
+
+		Expect(bkr.WaitForServices([]string{"local", "v2.remote"}, 2*time.Second)).Should(Succeed())
+	})
+
+	It("Should wait for versioned and remote only dependencies", func() {
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:                   "fatal",
+			Transporter:                "memory://dependencies-test",
+			WaitForDependenciesTimeout: 10 * time.Second,
+			DiscoverNodeID:             func() string { return "consumer-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{Name: "orders", Version: "v2"})
+		bkr.Start()
+		defer bkr.Stop()
+
+		start := time.Now()
+		bkr.Publish(moleculer.ServiceSchema{
+			Name:                "impatient",
+			Dependencies:        []string{"orders:v3"},
+			DependenciesTimeout: 50 * time.Millisecond,
+		})
+		Expect(time.Since(start)).Should(BeNumerically("<", time.Second))
+
+		var mutex sync.Mutex
+		started := false
+		isStarted := func() bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return started
+		}
+		go bkr.Publish(moleculer.ServiceSchema{
+			Name:      "consumer",
+			DependsOn: []moleculer.Dependency{moleculer.Dependency{Name: "orders", Version: "v2", RemoteOnly: true}},
+			Started: func(ctx moleculer.BrokerContext, svc moleculer.ServiceSchema) {
+				mutex.Lock()
+				started = true
+				mutex.Unlock()
+			},
+		})
+		Consistently(isStarted, 100*time.Millisecond).Should(BeFalse())
+
+		bkrRemote := broker.New(&moleculer.Config{
+			LogLevel:       "fatal",
+			Transporter:    "memory://dependencies-test",
+			DiscoverNodeID: func() string { return "orders-broker" },
+		})
+		bkrRemote.Publish(moleculer.ServiceSchema{Name: "orders", Version: "v2"})
+		bkrRemote.Start()
+		defer bkrRemote.Stop()
+
+		Eventually(isStarted, 2*time.Second).Should(BeTrue())
+	})
+
+	It("Should wait for the dependencies of the services published before the start", func() {
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:                   "fatal",
+			Transporter:                "memory://dependencies-before-start",
+			WaitForDependenciesTimeout: 10 * time.Second,
+			DiscoverNodeID:             func() string { return "consumer-broker" },
+		})
+		var mutex sync.Mutex
+		started := false
+		isStarted := func() bool {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return started
+		}
+		bkr.Publish(moleculer.ServiceSchema{
+			Name:      "consumer",
+			DependsOn: []moleculer.Dependency{moleculer.Dependency{Name: "orders", Version: "v2", RemoteOnly: true}},
+			Started: func(ctx moleculer.BrokerContext, svc moleculer.ServiceSchema) {
+				mutex.Lock()
+				started = true
+				mutex.Unlock()
+			},
+		})
+		go bkr.Start()
+		defer bkr.Stop()
+		Consistently(isStarted, 100*time.Millisecond).Should(BeFalse())
+		Expect(bkr.IsStarted()).Should(BeFalse())
+
+		bkrRemote := broker.New(&moleculer.Config{
+			LogLevel:       "fatal",
+			Transporter:    "memory://dependencies-before-start",
+			DiscoverNodeID: func() string { return "orders-broker" },
+		})
+		bkrRemote.Publish(moleculer.ServiceSchema{Name: "orders", Version: "v2"})
+		bkrRemote.Start()
+		defer bkrRemote.Stop()
+
+		Eventually(isStarted, 2*time.Second).Should(BeTrue())
+		Expect(bkr.WaitUntilStarted(time.Second)).Should(Succeed())
+	})
 
-		bkrRemote.Stop()
+	It("Should receive the response of a remote call in flight while it stops", func() {
+		bkrRemote := broker.New(&moleculer.Config{
+			LogLevel:       "fatal",
+			Transporter:    "memory://stop-in-flight",
+			DiscoverNodeID: func() string { return "slow-broker" },
+		})
+		bkrRemote.Publish(moleculer.ServiceSchema{
+			Name: "slow",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "work",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						time.Sleep(300 * time.Millisecond)
+						return "done"
+					},
+				},
+			},
+		})
+		bkrRemote.Start()
+		defer bkrRemote.Stop()
+
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:            "fatal",
+			Transporter:         "memory://stop-in-flight",
+			GracefulStopTimeout: 5 * time.Second,
+			DiscoverNodeID:      func() string { return "caller-broker" },
+		})
+		bkr.Start()
+		Expect(bkr.WaitForServices([]string{"slow"}, time.Second)).Should(Succeed())
+
+		result := make(chan moleculer.Payload, 1)
+		go func() {
+			result <- <-bkr.Call("slow.work", nil)
+		}()
+		time.Sleep(50 * time.Millisecond)
+		start := time.Now()
 		bkr.Stop()
+		Expect(time.Since(start)).Should(BeNumerically("<", 2*time.Second))
+		Expect((<-result).Value()).Should(Equal("done"))
+	})
+
+	It("Should return the health data of the local node", func() {
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "fatal",
+			DiscoverNodeID: func() string { return "health-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name:    "users",
+			Version: "v2",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name:    "list",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} { return nil },
+				},
+			},
+		})
+		Expect(bkr.HealthData().Get("status").String()).Should(Equal("stopped"))
+
+		bkr.Start()
+		defer bkr.Stop()
+		health := bkr.HealthData()
+		Expect(health.Get("nodeID").String()).Should(Equal("health-broker"))
+		Expect(health.Get("status").String()).Should(Equal("started"))
+		Expect(health.Get("transit").Get("connected").Bool()).Should(BeTrue())
+		Expect(health.Get("process").Get("uptime").Float()).Should(BeNumerically(">=", 0))
+		Expect(health.Get("mem").Exists()).Should(BeTrue())
+
+		var users moleculer.Payload
+		for _, svc := range health.Get("services").Array() {
+			if svc.Get("fullName").String() == "v2.users" {
+				users = svc
+			}
+		}
+		Expect(users).ShouldNot(BeNil())
+		Expect(users.Get("status").String()).Should(Equal("started"))
+		Expect(users.Get("actions").Int()).Should(Equal(1))
+
+		result := <-bkr.Call("$node.health", nil)
+		Expect(result.Get("nodeID").String()).Should(Equal("health-broker"))
+		Expect(result.Get("services").Len()).Should(Equal(health.Get("services").Len()))
 	})
 
 	It("Should call multiple local calls (in chain)", func() {
@@ -162,4 +413,3032 @@ var _ = Describe("Broker", func() {
 		Expect(result.Value()).Should(Equal(actionResult))
 	})
 
-})
+	It("Should perform multiple calls with MCall and wait for the result with CallSync", func() {
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			DiscoverNodeID: func() string { return "sync-calls-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "math",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "add",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return params.Get("a").Int() + params.Get("b").Int()
+					},
+				},
+				moleculer.Action{
+					Name: "divide",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						if params.Get("b").Int() == 0 {
+							return errors.New("division by zero")
+						}
+						return params.Get("a").Int() / params.Get("b").Int()
+					},
+				},
+			},
+		})
+		bkr.Start()
+		defer bkr.Stop()
+
+		results := bkr.MCall(map[string]moleculer.CallDefinition{
+			"sum": moleculer.CallDefinition{
+				Action: "math.add",
+				Params: map[string]interface{}{"a": 1, "b": 2},
+			},
+			"half": moleculer.CallDefinition{
+				Action:  "math.divide",
+				Params:  map[string]interface{}{"a": 10, "b": 2},
+				Options: moleculer.Options{Timeout: time.Second},
+			},
+			"fail": moleculer.CallDefinition{
+				Action: "math.divide",
+				Params: map[string]interface{}{"a": 10, "b": 0},
+			},
+		})
+		Expect(results.Len()).Should(Equal(3))
+		Expect(results.Get("sum").Int()).Should(Equal(3))
+		Expect(results.Get("half").Int()).Should(Equal(5))
+		Expect(results.Get("fail").IsError()).Should(BeTrue())
+		Expect(results.Get("fail").Error().Error()).Should(Equal("division by zero"))
+
+		result, err := bkr.CallSync("math.add", map[string]interface{}{"a": 2, "b": 3})
+		Expect(err).Should(Succeed())
+		Expect(result.Int()).Should(Equal(5))
+
+		result, err = bkr.CallSync("math.divide", map[string]interface{}{"a": 2, "b": 0})
+		Expect(err).Should(MatchError("division by zero"))
+		Expect(result.IsError()).Should(BeTrue())
+	})
+
+	It("Should send the error code, type and data of remote failures", func() {
+		mem := &memory.SharedMemory{}
+		baseConfig := &moleculer.Config{
+			LogLevel: "error",
+			TransporterFactory: func() interface{} {
+				transport := memory.Create(log.WithField("transport", "memory"), mem)
+				return &transport
+			},
+		}
+		bkr := broker.New(baseConfig, &moleculer.Config{
+			DiscoverNodeID: func() string { return "orders-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "orders",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "create",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return moleculer.NewRetryableError("Stock is locked", 503, "STOCK_LOCKED", map[string]interface{}{"sku": "A-1"})
+					},
+				},
+				moleculer.Action{
+					Name: "fail",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return errors.New("plain error")
+					},
+				},
+			},
+		})
+		bkr.Start()
+		defer bkr.Stop()
+
+		bkrRemote := broker.New(baseConfig, &moleculer.Config{
+			DiscoverNodeID: func() string { return "orders-remote-broker" },
+		})
+		bkrRemote.Start()
+		defer bkrRemote.Stop()
+		bkrRemote.WaitFor("orders")
+
+		result := <-bkrRemote.Call("orders.create", nil)
+		Expect(result.IsError()).Should(BeTrue())
+		err, isMoleculerError := result.Error().(*moleculer.Error)
+		Expect(isMoleculerError).Should(BeTrue())
+		Expect(err.Message).Should(Equal("Stock is locked"))
+		Expect(err.Name).Should(Equal("MoleculerRetryableError"))
+		Expect(err.Code).Should(Equal(503))
+		Expect(err.Type).Should(Equal("STOCK_LOCKED"))
+		Expect(err.Retryable).Should(BeTrue())
+		Expect(err.NodeID).Should(Equal("orders-broker"))
+		Expect(err.Data).Should(Equal(map[string]interface{}{"sku": "A-1"}))
+
+		result = <-bkrRemote.Call("orders.fail", nil)
+		err, isMoleculerError = result.Error().(*moleculer.Error)
+		Expect(isMoleculerError).Should(BeTrue())
+		Expect(err.Name).Should(Equal("Error"))
+		Expect(err.Message).Should(Equal("plain error"))
+		Expect(err.NodeID).Should(Equal("orders-broker"))
+	})
+
+	It("Should create the registered error types for remote errors", func() {
+		moleculer.RegisterError("InsufficientFundsError", func(err *moleculer.Error) error {
+			return &insufficientFundsError{err}
+		})
+		mem := &memory.SharedMemory{}
+		baseConfig := &moleculer.Config{
+			LogLevel: "error",
+			TransporterFactory: func() interface{} {
+				transport := memory.Create(log.WithField("transport", "memory"), mem)
+				return &transport
+			},
+		}
+		bkr := broker.New(baseConfig, &moleculer.Config{
+			DiscoverNodeID: func() string { return "bank-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "bank",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "withdraw",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						err := moleculer.NewError("Insufficient funds", 402, "NO_FUNDS", map[string]interface{}{"balance": 10})
+						err.Name = "InsufficientFundsError"
+						return &insufficientFundsError{err}
+					},
+				},
+			},
+		})
+		bkr.Start()
+		defer bkr.Stop()
+
+		bkrRemote := broker.New(baseConfig, &moleculer.Config{
+			DiscoverNodeID: func() string { return "bank-remote-broker" },
+		})
+		bkrRemote.Start()
+		defer bkrRemote.Stop()
+		bkrRemote.WaitFor("bank")
+
+		result := <-bkrRemote.Call("bank.withdraw", nil)
+		Expect(result.IsError()).Should(BeTrue())
+		err, isTyped := result.Error().(*insufficientFundsError)
+		Expect(isTyped).Should(BeTrue())
+		Expect(err.cause.Code).Should(Equal(402))
+		Expect(err.cause.Data).Should(Equal(map[string]interface{}{"balance": float64(10)}))
+	})
+
+	It("Should validate the action params before invoking the handler", func() {
+		mem := &memory.SharedMemory{}
+		baseConfig := &moleculer.Config{
+			LogLevel: "error",
+			TransporterFactory: func() interface{} {
+				transport := memory.Create(log.WithField("transport", "memory"), mem)
+				return &transport
+			},
+		}
+		invoked := 0
+		bkr := broker.New(baseConfig, &moleculer.Config{
+			DiscoverNodeID: func() string { return "users-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "users",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "create",
+					Schema: map[string]interface{}{
+						"name": "string|min:3",
+						"age":  "number|optional|min:18",
+					},
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						invoked++
+						return params.Get("name").String()
+					},
+				},
+			},
+		})
+		bkr.Start()
+		defer bkr.Stop()
+
+		bkrRemote := broker.New(baseConfig, &moleculer.Config{
+			DiscoverNodeID: func() string { return "users-remote-broker" },
+		})
+		bkrRemote.Start()
+		defer bkrRemote.Stop()
+		bkrRemote.WaitFor("users")
+
+		result := <-bkrRemote.Call("users.create", map[string]interface{}{"name": "Jo", "age": 10})
+		Expect(result.IsError()).Should(BeTrue())
+		err, isMoleculerError := result.Error().(*moleculer.Error)
+		Expect(isMoleculerError).Should(BeTrue())
+		Expect(err.Name).Should(Equal("ValidationError"))
+		Expect(err.Code).Should(Equal(422))
+		Expect(err.Data).Should(HaveLen(2))
+		Expect(invoked).Should(Equal(0))
+
+		result = <-bkr.Call("users.create", nil)
+		Expect(result.Error()).Should(MatchError("Parameters validation error!"))
+
+		result = <-bkrRemote.Call("users.create", map[string]interface{}{"name": "John"})
+		Expect(result.String()).Should(Equal("John"))
+		Expect(invoked).Should(Equal(1))
+	})
+
+	It("Should use the validator set in the config", func() {
+		schemas := []moleculer.ActionSchema{}
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			DiscoverNodeID: func() string { return "validator-broker" },
+			Validator: validatorFunc(func(schema moleculer.ActionSchema, params moleculer.Payload) error {
+				schemas = append(schemas, schema)
+				if !params.Get("name").Exists() {
+					return errors.New("name is required")
+				}
+				return nil
+			}),
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "users",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name:   "create",
+					Schema: "custom-schema",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return params.Get("name").String()
+					},
+				},
+			},
+		})
+		bkr.Start()
+		defer bkr.Stop()
+
+		result := <-bkr.Call("users.create", map[string]interface{}{"age": 10})
+		Expect(result.Error()).Should(MatchError("name is required"))
+
+		result = <-bkr.Call("users.create", map[string]interface{}{"name": "John"})
+		Expect(result.String()).Should(Equal("John"))
+		Expect(schemas).Should(Equal([]moleculer.ActionSchema{"custom-schema", "custom-schema"}))
+	})
+
+	It("Should send and receive streams in remote calls", func() {
+		content := strings.Repeat("moleculer stream ", 10000)
+		mem := &memory.SharedMemory{}
+		baseConfig := &moleculer.Config{
+			LogLevel: "error",
+			TransporterFactory: func() interface{} {
+				transport := memory.Create(log.WithField("transport", "memory"), mem)
+				return &transport
+			},
+		}
+		bkr := broker.New(baseConfig, &moleculer.Config{
+			DiscoverNodeID: func() string { return "files-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "files",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "upload",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						bts, err := ioutil.ReadAll(params.Value().(io.Reader))
+						if err != nil {
+							return err
+						}
+						return string(bts)
+					},
+				},
+				moleculer.Action{
+					Name: "download",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return strings.NewReader(content)
+					},
+				},
+			},
+		})
+		bkr.Start()
+		defer bkr.Stop()
+
+		bkrRemote := broker.New(baseConfig, &moleculer.Config{
+			DiscoverNodeID: func() string { return "remote-broker" },
+		})
+		bkrRemote.Start()
+		defer bkrRemote.Stop()
+		bkrRemote.WaitFor("files")
+
+		result := <-bkrRemote.Call("files.upload", strings.NewReader(content))
+		Expect(result.IsError()).Should(BeFalse())
+		Expect(result.String()).Should(Equal(content))
+
+		result = <-bkrRemote.Call("files.download", nil)
+		Expect(result.IsError()).Should(BeFalse())
+		reader, isReader := result.Value().(io.Reader)
+		Expect(isReader).Should(BeTrue())
+		bts, err := ioutil.ReadAll(reader)
+		Expect(err).Should(BeNil())
+		Expect(string(bts)).Should(Equal(content))
+	})
+
+	It("Should decode big lists from remote streams item by item", func() {
+		mem := &memory.SharedMemory{}
+		baseConfig := &moleculer.Config{
+			LogLevel: "error",
+			TransporterFactory: func() interface{} {
+				transport := memory.Create(log.WithField("transport", "memory"), mem)
+				return &transport
+			},
+		}
+		bkr := broker.New(baseConfig, &moleculer.Config{
+			DiscoverNodeID: func() string { return "report-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "reports",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "rows",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						reader, writer := io.Pipe()
+						go func() {
+							writer.Write([]byte("["))
+							for i := 0; i < 5000; i++ {
+								if i > 0 {
+									writer.Write([]byte(","))
+								}
+								writer.Write([]byte(fmt.Sprintf(`{"row":%d}`, i)))
+							}
+							writer.Write([]byte("]"))
+							writer.Close()
+						}()
+						return reader
+					},
+				},
+			},
+		})
+		bkr.Start()
+		defer bkr.Stop()
+
+		bkrRemote := broker.New(baseConfig, &moleculer.Config{
+			DiscoverNodeID: func() string { return "report-reader-broker" },
+		})
+		bkrRemote.Start()
+		defer bkrRemote.Stop()
+		bkrRemote.WaitFor("reports")
+
+		result := <-bkrRemote.Call("reports.rows", nil)
+		Expect(result.IsError()).Should(BeFalse())
+		rows := 0
+		err := result.DecodeArrayStream(func(item moleculer.Payload) bool {
+			Expect(item.Get("row").Int()).Should(Equal(rows))
+			rows++
+			return true
+		})
+		Expect(err).Should(BeNil())
+		Expect(rows).Should(Equal(5000))
+	})
+
+	It("Should discover and call brokers sharing the same memory bus", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://shared-bus-test",
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "bus-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "greeter",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "hello",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return "Hello " + params.String()
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "bus-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("greeter")).Should(Succeed())
+
+		result := <-bkr2.Call("greeter.hello", "John")
+		Expect(result.IsError()).Should(BeFalse())
+		Expect(result.String()).Should(Equal("Hello John"))
+	})
+
+	It("Should destroy a service at runtime and remove it from the remote nodes", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://destroy-service-test",
+		}
+		stopped := make(chan string, 2)
+		received := make(chan string, 4)
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "destroy-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "users",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "get",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return "John"
+					},
+				},
+			},
+			Events: []moleculer.Event{
+				moleculer.Event{
+					Name: "user.created",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+						received <- "users"
+					},
+				},
+			},
+			Stopped: func(ctx moleculer.BrokerContext, svc moleculer.ServiceSchema) {
+				stopped <- svc.Name
+			},
+		}, moleculer.ServiceSchema{
+			Name: "emails",
+			Events: []moleculer.Event{
+				moleculer.Event{
+					Name: "user.created",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+						received <- "emails"
+					},
+				},
+			},
+		}, moleculer.ServiceSchema{
+			Name:    "orders",
+			Version: "v2",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "list",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return []string{}
+					},
+				},
+			},
+			Stopped: func(ctx moleculer.BrokerContext, svc moleculer.ServiceSchema) {
+				stopped <- svc.Name
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "destroy-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("users", "orders")).Should(Succeed())
+		Expect((<-bkr2.Call("users.get", nil)).String()).Should(Equal("John"))
+
+		Expect(bkr1.DestroyService("users")).Should(Succeed())
+		Eventually(stopped).Should(Receive(Equal("users")))
+		Expect((<-bkr1.Call("users.get", nil)).IsError()).Should(BeTrue())
+		Eventually(func() bool { return bkr2.KnowAction("users.get") }).Should(BeFalse())
+		Expect((<-bkr2.Call("users.get", nil)).IsError()).Should(BeTrue())
+
+		bkr1.Broadcast("user.created", nil)
+		Eventually(received).Should(Receive(Equal("emails")))
+		Consistently(received, 100*time.Millisecond).ShouldNot(Receive())
+
+		Expect(bkr1.DestroyService("users")).Should(HaveOccurred())
+		Expect(bkr1.DestroyServiceVersion("orders", "v1")).Should(HaveOccurred())
+		Expect(bkr1.DestroyServiceVersion("orders", "v2")).Should(Succeed())
+		Eventually(stopped).Should(Receive(Equal("orders")))
+		Expect(bkr1.KnowAction("v2.orders.list")).Should(BeFalse())
+	})
+
+	It("Should isolate brokers with different namespaces sharing the same transporter", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://namespace-test",
+		}
+		bkrStaging := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "staging-broker" },
+			Namespace:      "staging",
+		})
+		bkrStaging.Publish(moleculer.ServiceSchema{
+			Name: "greeter",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "hello",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return "Hello from staging"
+					},
+				},
+			},
+		})
+		bkrStaging.Start()
+		defer bkrStaging.Stop()
+
+		bkrProduction := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "production-broker" },
+			Namespace:      "production",
+		})
+		bkrProduction.Start()
+		defer bkrProduction.Stop()
+
+		bkrClient := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "staging-client" },
+			Namespace:      "staging",
+		})
+		bkrClient.Start()
+		defer bkrClient.Stop()
+		Expect(bkrClient.WaitFor("greeter")).Should(Succeed())
+		result := <-bkrClient.Call("greeter.hello", nil)
+		Expect(result.String()).Should(Equal("Hello from staging"))
+
+		Expect(bkrProduction.KnowAction("greeter.hello")).Should(BeFalse())
+		Expect((<-bkrProduction.Call("greeter.hello", nil)).IsError()).Should(BeTrue())
+	})
+
+	It("Should report the transit packet metrics", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://transit-metrics-test",
+		}
+		reports := make(chan moleculer.Payload, 10)
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "metrics-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "reporter",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "echo",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return params.Value()
+					},
+				},
+			},
+			Events: []moleculer.Event{
+				moleculer.Event{
+					Name: "metrics.transit",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+						if params.Get("nodeID").String() == "metrics-broker2" {
+							reports <- params
+						}
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID:         func() string { return "metrics-broker2" },
+			Metrics:                true,
+			TransitMetricsInterval: 50 * time.Millisecond,
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("reporter")).Should(Succeed())
+		Expect((<-bkr2.Call("reporter.echo", "hello")).String()).Should(Equal("hello"))
+
+		var report moleculer.Payload
+		Eventually(reports, time.Second).Should(Receive(&report))
+		Expect(report.Get("packets").Get("sent").Get("REQ").Int()).Should(BeNumerically(">=", 1))
+		Expect(report.Get("packets").Get("received").Get("RES").Int()).Should(BeNumerically(">=", 1))
+		Expect(report.Get("bytes").Get("sent").Int()).Should(BeNumerically(">", 0))
+	})
+
+	It("Should report the node cpu and memory usage in the heartbeats", func() {
+		config := &moleculer.Config{
+			LogLevel:           "error",
+			Transporter:        "memory://stats-test",
+			HeartbeatFrequency: 50 * time.Millisecond,
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "stats-broker1" },
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "stats-broker2" },
+			StatsProvider: func() moleculer.SystemStats {
+				return moleculer.SystemStats{CPU: 42, Cores: 4, MemTotal: 1000, MemFree: 250}
+			},
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr1.WaitForNodes("stats-broker2")).Should(Succeed())
+
+		health := <-bkr2.Call("$node.health", nil)
+		Expect(health.Get("cpu").Get("utilization").Int()).Should(Equal(42))
+		Expect(health.Get("cpu").Get("cores").Int()).Should(Equal(4))
+		Expect(health.Get("mem").Get("percent").Int()).Should(Equal(75))
+
+		remoteNode := func() moleculer.Payload {
+			var result moleculer.Payload
+			(<-bkr1.Call("$node.list", nil)).ForEach(func(index interface{}, node moleculer.Payload) bool {
+				if node.Get("id").String() == "stats-broker2" {
+					result = node
+				}
+				return true
+			})
+			return result
+		}
+		Eventually(func() int { return remoteNode().Get("cpu").Int() }, time.Second).Should(Equal(42))
+		Expect(remoteNode().Get("mem").Int()).Should(Equal(75))
+	})
+
+	It("Should cancel the remote action when the call times out", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://cancel-test",
+		}
+		cancelled := make(chan error, 1)
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "cancel-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "slow",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "work",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						select {
+						case <-ctx.Done():
+							cancelled <- ctx.Err()
+						case <-time.After(5 * time.Second):
+						}
+						return "done"
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "cancel-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("slow")).Should(Succeed())
+
+		result := <-bkr2.Call("slow.work", nil, moleculer.Options{Timeout: 50 * time.Millisecond})
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(result.Error().Error()).Should(HavePrefix("RequestTimedOut"))
+		Eventually(cancelled, time.Second).Should(Receive(Or(Equal(context.Canceled), Equal(context.DeadlineExceeded))))
+	})
+
+	It("Should enforce the call timeout on the node running the action", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://action-timeout-test",
+		}
+		deadlines := make(chan time.Duration, 1)
+		errs := make(chan error, 1)
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "action-timeout-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "job",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "deadline",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						if deadline, hasDeadline := ctx.Deadline(); hasDeadline {
+							deadlines <- time.Until(deadline)
+						}
+						return "done"
+					},
+				},
+				moleculer.Action{
+					Name: "slow",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						time.Sleep(300 * time.Millisecond)
+						errs <- ctx.Err()
+						return "done"
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "action-timeout-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("job")).Should(Succeed())
+
+		result := <-bkr2.Call("job.deadline", nil, moleculer.Options{Timeout: 2 * time.Second})
+		Expect(result.String()).Should(Equal("done"))
+		Eventually(deadlines).Should(Receive(BeNumerically("~", 2*time.Second, 100*time.Millisecond)))
+
+		start := time.Now()
+		result = <-bkr1.Call("job.slow", nil, moleculer.Options{Timeout: 50 * time.Millisecond})
+		Expect(time.Since(start)).Should(BeNumerically("<", 200*time.Millisecond))
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(result.Error().(*moleculer.Error).Type).Should(Equal("REQUEST_TIMEOUT"))
+		Eventually(errs, time.Second).Should(Receive(Equal(context.DeadlineExceeded)))
+	})
+
+	It("Should cancel the remote action when the context of CallContext is done", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://call-context-test",
+		}
+		cancelled := make(chan error, 1)
+		timeouts := make(chan time.Duration, 1)
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "call-context-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "slow",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "work",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						select {
+						case <-ctx.Done():
+							cancelled <- ctx.Err()
+						case <-time.After(5 * time.Second):
+						}
+						return "done"
+					},
+				},
+				moleculer.Action{
+					Name: "deadline",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						timeouts <- ctx.(moleculer.BrokerContext).Timeout()
+						return "done"
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "call-context-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("slow")).Should(Succeed())
+
+		ctx, cancel := context.WithCancel(context.Background())
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+		}()
+		result := <-bkr2.CallContext(ctx, "slow.work", nil)
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(result.Error().Error()).Should(HavePrefix("RequestCancelled"))
+		Eventually(cancelled, time.Second).Should(Receive(Equal(context.Canceled)))
+
+		result = <-bkr2.CallContext(ctx, "slow.work", nil)
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(result.Error().Error()).Should(HavePrefix("RequestCancelled"))
+		Consistently(cancelled, 100*time.Millisecond).ShouldNot(Receive())
+
+		ctx, cancel = context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		result = <-bkr2.CallContext(ctx, "slow.deadline", nil)
+		Expect(result.String()).Should(Equal("done"))
+		Eventually(timeouts).Should(Receive(BeNumerically("~", 3*time.Second, 100*time.Millisecond)))
+	})
+
+	It("Should call with the timeout, retries, nodeID, meta and fallback response options", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://call-options-test",
+		}
+		var attempts int32
+		nodeService := func(nodeID string) moleculer.ServiceSchema {
+			return moleculer.ServiceSchema{
+				Name: "node",
+				Actions: []moleculer.Action{
+					moleculer.Action{
+						Name: "info",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return map[string]interface{}{
+								"nodeID": nodeID,
+								"user":   ctx.Meta().Get("user").String(),
+							}
+						},
+					},
+					moleculer.Action{
+						Name: "flaky",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							if atomic.AddInt32(&attempts, 1) <= int32(params.Get("failures").Int()) {
+								return moleculer.NewRetryableError("flaky failure", 503, "FLAKY", nil)
+							}
+							return "done"
+						},
+					},
+					moleculer.Action{
+						Name: "slow",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							time.Sleep(300 * time.Millisecond)
+							return "done"
+						},
+					},
+				},
+			}
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "call-options-broker1" },
+		})
+		bkr1.Publish(nodeService("call-options-broker1"))
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "call-options-broker2" },
+		})
+		bkr2.Publish(moleculer.ServiceSchema{Name: "other"})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("node")).Should(Succeed())
+
+		result := <-bkr2.Call("node.info", nil, moleculer.Options{
+			NodeID: "call-options-broker1",
+			Meta:   payload.New(map[string]interface{}{"user": "john"}),
+		})
+		Expect(result.Get("nodeID").String()).Should(Equal("call-options-broker1"))
+		Expect(result.Get("user").String()).Should(Equal("john"))
+
+		result = <-bkr1.Call("node.info", nil, moleculer.Options{NodeID: "call-options-broker2"})
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(result.Error().Error()).Should(ContainSubstring("call-options-broker2"))
+
+		result = <-bkr2.Call("node.slow", nil, moleculer.Options{Timeout: 50 * time.Millisecond})
+		Expect(result.IsError()).Should(BeTrue())
+
+		result = <-bkr2.Call("node.flaky", map[string]interface{}{"failures": 2})
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(atomic.LoadInt32(&attempts)).Should(Equal(int32(1)))
+
+		atomic.StoreInt32(&attempts, 0)
+		result = <-bkr2.Call("node.flaky", map[string]interface{}{"failures": 2}, moleculer.Options{Retries: 2})
+		Expect(result.String()).Should(Equal("done"))
+		Expect(atomic.LoadInt32(&attempts)).Should(Equal(int32(3)))
+
+		atomic.StoreInt32(&attempts, 0)
+		result = <-bkr2.Call("node.flaky", map[string]interface{}{"failures": 5}, moleculer.Options{
+			Retries:          1,
+			FallbackResponse: "fallback",
+		})
+		Expect(result.String()).Should(Equal("fallback"))
+		Expect(atomic.LoadInt32(&attempts)).Should(Equal(int32(2)))
+
+		var fallbackErr error
+		result = <-bkr2.Call("node.slow", nil, moleculer.Options{
+			Timeout: 50 * time.Millisecond,
+			FallbackResponse: moleculer.FallbackFunc(func(ctx moleculer.Context, err error) interface{} {
+				fallbackErr = err
+				return map[string]interface{}{"cached": true}
+			}),
+		})
+		Expect(result.Get("cached").Bool()).Should(BeTrue())
+		Expect(fallbackErr).ShouldNot(BeNil())
+	})
+
+	It("Should retry the failed calls according to the RetryPolicy of the config", func() {
+		var attempts int32
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			DiscoverNodeID: func() string { return "retry-policy-broker" },
+			RetryPolicy: moleculer.RetryPolicy{
+				Enabled: true,
+				Retries: 3,
+				Delay:   10,
+				Factor:  2,
+				Check: func(err error) bool {
+					return err.Error() != "not retryable"
+				},
+			},
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "flaky",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "work",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						atomic.AddInt32(&attempts, 1)
+						return errors.New(params.String())
+					},
+				},
+			},
+		})
+		bkr.Start()
+		defer bkr.Stop()
+
+		start := time.Now()
+		result := <-bkr.Call("flaky.work", "retryable")
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(atomic.LoadInt32(&attempts)).Should(Equal(int32(4)))
+		Expect(time.Since(start)).Should(BeNumerically(">=", 70*time.Millisecond))
+
+		atomic.StoreInt32(&attempts, 0)
+		result = <-bkr.Call("flaky.work", "not retryable")
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(atomic.LoadInt32(&attempts)).Should(Equal(int32(1)))
+	})
+
+	It("Should retry the timed out and retryable remote calls with a new timeout on each attempt", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://retry-remote-test",
+			RetryPolicy: moleculer.RetryPolicy{Enabled: true, Retries: 2, Delay: 10},
+		}
+		var attempts int32
+		requestIDs := make(chan string, 10)
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "retry-remote-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "remote",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "slowOnce",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						requestIDs <- ctx.(moleculer.BrokerContext).RequestID()
+						if atomic.AddInt32(&attempts, 1) == 1 {
+							time.Sleep(200 * time.Millisecond)
+						}
+						return "done"
+					},
+				},
+				moleculer.Action{
+					Name: "invalid",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						atomic.AddInt32(&attempts, 1)
+						return moleculer.NewError("invalid params", 422, "INVALID", nil)
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "retry-remote-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("remote")).Should(Succeed())
+
+		result := <-bkr2.Call("remote.slowOnce", nil, moleculer.Options{Timeout: 100 * time.Millisecond})
+		Expect(result.Error()).Should(BeNil())
+		Expect(result.String()).Should(Equal("done"))
+		Expect(atomic.LoadInt32(&attempts)).Should(Equal(int32(2)))
+		first, second := <-requestIDs, <-requestIDs
+		Expect(second).Should(Equal(first))
+
+		atomic.StoreInt32(&attempts, 0)
+		result = <-bkr2.Call("remote.invalid", nil)
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(atomic.LoadInt32(&attempts)).Should(Equal(int32(1)))
+
+		result = <-bkr2.Call("remote.missing", nil)
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(moleculer.IsRetryable(result.Error())).Should(BeTrue())
+	})
+
+	It("Should limit the concurrent executions of the actions with a bulkhead", func() {
+		var running, maxRunning int32
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			DiscoverNodeID: func() string { return "bulkhead-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "report",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name:     "generate",
+					Bulkhead: &moleculer.BulkheadPolicy{Enabled: true, Concurrency: 2, MaxQueueSize: 2},
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						current := atomic.AddInt32(&running, 1)
+						for {
+							max := atomic.LoadInt32(&maxRunning)
+							if current <= max || atomic.CompareAndSwapInt32(&maxRunning, max, current) {
+								break
+							}
+						}
+						time.Sleep(100 * time.Millisecond)
+						atomic.AddInt32(&running, -1)
+						return "done"
+					},
+				},
+			},
+		})
+		bkr.Start()
+		defer bkr.Stop()
+
+		results := make(chan moleculer.Payload, 5)
+		for i := 0; i < 5; i++ {
+			go func() {
+				results <- <-bkr.Call("report.generate", nil)
+			}()
+		}
+		done, rejected := 0, 0
+		for i := 0; i < 5; i++ {
+			result := <-results
+			if result.IsError() {
+				Expect(result.Error().(*moleculer.Error).Type).Should(Equal("QUEUE_FULL"))
+				rejected++
+			} else {
+				done++
+			}
+		}
+		Expect(done).Should(Equal(4))
+		Expect(rejected).Should(Equal(1))
+		Expect(atomic.LoadInt32(&maxRunning)).Should(Equal(int32(2)))
+	})
+
+	It("Should reject the calls over the rate limit of each caller", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://rate-limit-test",
+		}
+		service := moleculer.ServiceSchema{
+			Name: "search",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "query",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return "found"
+					},
+				},
+				moleculer.Action{
+					Name: "suggest",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return "suggested"
+					},
+				},
+			},
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "rate-limit-broker1" },
+			Middlewares: []moleculer.Middlewares{middleware.RateLimit(
+				middleware.RateLimitRule{Actions: "search.query", Limit: 2, Window: time.Minute},
+				middleware.RateLimitRule{Actions: "search.*", Limit: 1, Window: time.Minute, Key: middleware.RateLimitByMeta("user")},
+			)},
+		})
+		bkr1.Publish(service)
+		bkr1.Start()
+		defer bkr1.Stop()
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "rate-limit-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("search")).Should(Succeed())
+
+		for _, bkr := range []*broker.ServiceBroker{bkr1, bkr2} {
+			Expect((<-bkr.Call("search.query", nil)).String()).Should(Equal("found"))
+			Expect((<-bkr.Call("search.query", nil)).String()).Should(Equal("found"))
+			result := <-bkr.Call("search.query", nil)
+			Expect(result.IsError()).Should(BeTrue())
+			err := result.Error().(*moleculer.Error)
+			Expect(err.Code).Should(Equal(429))
+			Expect(err.Type).Should(Equal("RATE_LIMIT_EXCEEDED"))
+		}
+
+		meta := func(user string) moleculer.Options {
+			return moleculer.Options{Meta: payload.New(map[string]interface{}{"user": user})}
+		}
+		Expect((<-bkr2.Call("search.suggest", nil, meta("john"))).String()).Should(Equal("suggested"))
+		Expect((<-bkr2.Call("search.suggest", nil, meta("john"))).IsError()).Should(BeTrue())
+		Expect((<-bkr1.Call("search.suggest", nil, meta("mary"))).String()).Should(Equal("suggested"))
+	})
+
+	It("Should propagate the call chain and reject the calls deeper than MaxCallLevel", func() {
+		config := &moleculer.Config{
+			LogLevel:     "fatal",
+			Transporter:  "memory://max-call-level-test",
+			MaxCallLevel: 6,
+		}
+		type chainLink struct {
+			id, parentID, requestID string
+			level                   int
+		}
+		links := make(chan chainLink, 10)
+		pingPong := func(name, other string) moleculer.ServiceSchema {
+			return moleculer.ServiceSchema{
+				Name: name,
+				Actions: []moleculer.Action{
+					moleculer.Action{
+						Name: "hit",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							brokerContext := ctx.(moleculer.BrokerContext)
+							links <- chainLink{brokerContext.ID(), brokerContext.ParentID(), brokerContext.RequestID(), brokerContext.Level()}
+							return <-ctx.Call(other+".hit", nil)
+						},
+					},
+				},
+			}
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "max-call-level-broker1" },
+		})
+		bkr1.Publish(pingPong("ping", "pong"))
+		bkr1.Start()
+		defer bkr1.Stop()
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "max-call-level-broker2" },
+		})
+		bkr2.Publish(pingPong("pong", "ping"))
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr1.WaitFor("pong")).Should(Succeed())
+		Expect(bkr2.WaitFor("ping")).Should(Succeed())
+
+		result := <-bkr1.Call("ping.hit", nil)
+		Expect(result.IsError()).Should(BeTrue())
+		err := result.Error().(*moleculer.Error)
+		Expect(err.Type).Should(Equal("MAX_CALL_LEVEL"))
+		Expect(err.Name).Should(Equal("MaxCallLevelError"))
+
+		Expect(links).Should(HaveLen(5))
+		previous := <-links
+		Expect(previous.level).Should(Equal(2))
+		for i := 1; i < 5; i++ {
+			link := <-links
+			Expect(link.level).Should(Equal(previous.level + 1))
+			Expect(link.parentID).Should(Equal(previous.id))
+			Expect(link.requestID).Should(Equal(previous.requestID))
+			previous = link
+		}
+	})
+
+	It("Should send the meta to the called actions and merge back the meta they change", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://meta-merge-test",
+		}
+		setMeta := func(ctx moleculer.Context, field string, value interface{}) {
+			brokerContext := ctx.(moleculer.BrokerContext)
+			brokerContext.UpdateMeta(brokerContext.Meta().Add(field, value))
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "meta-merge-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "auth",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "login",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						setMeta(ctx, "user", ctx.Meta().Get("token").String()+"-user")
+						return true
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "meta-merge-broker2" },
+		})
+		bkr2.Publish(moleculer.ServiceSchema{
+			Name: "gateway",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "handle",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						<-ctx.Call("auth.login", nil)
+						<-ctx.Call("gateway.audit", nil)
+						return ctx.Meta()
+					},
+				},
+				moleculer.Action{
+					Name: "audit",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						setMeta(ctx, "audited", ctx.Meta().Get("user").String())
+						return true
+					},
+				},
+			},
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("auth")).Should(Succeed())
+
+		result := <-bkr2.Call("gateway.handle", nil, moleculer.Options{
+			Meta: payload.New(map[string]interface{}{"token": "abc"}),
+		})
+		Expect(result.Error()).Should(BeNil())
+		Expect(result.Get("token").String()).Should(Equal("abc"))
+		Expect(result.Get("user").String()).Should(Equal("abc-user"))
+		Expect(result.Get("audited").String()).Should(Equal("abc-user"))
+
+		result = <-bkr2.Call("gateway.handle", nil)
+		Expect(result.Get("token").Exists()).Should(BeFalse())
+		Expect(result.Get("user").String()).ShouldNot(Equal("abc-user"))
+	})
+
+	It("Should keep the context locals in the local node", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://locals-test",
+		}
+		authMiddleware := moleculer.Middlewares{
+			"beforeLocalAction": func(params interface{}, next func(...interface{})) {
+				context := params.(moleculer.BrokerContext)
+				context.Locals()["user"] = "user-of-" + context.ActionName()
+				next()
+			},
+		}
+		localUser := func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+			return map[string]interface{}{
+				"user":   ctx.Locals()["user"],
+				"locals": len(ctx.Locals()),
+			}
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "locals-broker1" },
+			Middlewares:    []moleculer.Middlewares{authMiddleware},
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name:    "profile",
+			Actions: []moleculer.Action{moleculer.Action{Name: "get", Handler: localUser}},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "locals-broker2" },
+		})
+		bkr2.Publish(moleculer.ServiceSchema{
+			Name: "gateway",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "handle",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						ctx.Locals()["transaction"] = "tx-1"
+						return <-ctx.Call("profile.get", nil)
+					},
+				},
+			},
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("profile")).Should(Succeed())
+
+		result := <-bkr2.Call("gateway.handle", nil)
+		Expect(result.Error()).Should(BeNil())
+		Expect(result.Get("user").String()).Should(Equal("user-of-profile.get"))
+		Expect(result.Get("locals").Int()).Should(Equal(1))
+	})
+
+	It("Should run multiple versions of a service side by side in local and remote nodes", func() {
+		mem := &memory.SharedMemory{}
+		config := &moleculer.Config{
+			LogLevel: "error",
+			TransporterFactory: func() interface{} {
+				transport := memory.Create(log.WithField("transport", "memory"), mem)
+				return &transport
+			},
+		}
+		usersVersion := func(version string) moleculer.ServiceSchema {
+			return moleculer.ServiceSchema{
+				Name:    "users",
+				Version: version,
+				Actions: []moleculer.Action{
+					moleculer.Action{
+						Name: "get",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return "users " + version
+						},
+					},
+				},
+			}
+		}
+
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "versions-broker1" },
+		})
+		bkr1.Publish(usersVersion("1"), usersVersion("2"), usersVersion("staging"))
+		bkr1.Start()
+		defer bkr1.Stop()
+		Expect((<-bkr1.Call("v1.users.get", nil)).String()).Should(Equal("users 1"))
+		Expect((<-bkr1.Call("v2.users.get", nil)).String()).Should(Equal("users 2"))
+		Expect((<-bkr1.Call("staging.users.get", nil)).String()).Should(Equal("users staging"))
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "versions-broker2" },
+		})
+		bkr2.Publish(moleculer.ServiceSchema{
+			Name:      "profile",
+			DependsOn: []moleculer.Dependency{moleculer.Dependency{Name: "users", Version: "2", RemoteOnly: true}},
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "get",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return <-ctx.Call("v2.users.get", nil)
+					},
+				},
+			},
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("v1.users", "v2.users", "profile")).Should(Succeed())
+		Expect((<-bkr2.Call("v1.users.get", nil)).String()).Should(Equal("users 1"))
+		Expect((<-bkr2.Call("profile.get", nil)).String()).Should(Equal("users 2"))
+
+		Expect(bkr1.DestroyServiceVersion("users", "1")).Should(Succeed())
+		Eventually(func() bool { return bkr2.KnowAction("v1.users.get") }).Should(BeFalse())
+		Expect(bkr2.KnowAction("v2.users.get")).Should(BeTrue())
+		Expect((<-bkr2.Call("v2.users.get", nil)).String()).Should(Equal("users 2"))
+	})
+
+	It("Should expose the settings and metadata of remote services without the secure settings", func() {
+		changed := make(chan string, 10)
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://service-settings-test",
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "settings-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "gateway",
+			Events: []moleculer.Event{
+				moleculer.Event{
+					Name: "$services.changed",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+						if !params.Get("localService").Bool() {
+							changed <- params.Get("service").Get("name").String()
+						}
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "settings-broker2" },
+		})
+		bkr2.Publish(moleculer.ServiceSchema{
+			Name: "users",
+			Settings: map[string]interface{}{
+				"rest":            "/users",
+				"apiKey":          "secret",
+				"$secureSettings": []string{"apiKey"},
+			},
+			Metadata: map[string]interface{}{"region": "eu"},
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr1.WaitFor("users")).Should(Succeed())
+		Eventually(changed).Should(Receive(Equal("users")))
+
+		services := <-bkr1.Call("$node.services", map[string]interface{}{"skipInternal": true})
+		Expect(services.Error()).Should(BeNil())
+		var users moleculer.Payload
+		services.ForEach(func(index interface{}, item moleculer.Payload) bool {
+			if item.Get("name").String() == "users" {
+				users = item
+			}
+			return true
+		})
+		Expect(users).ShouldNot(BeNil())
+		Expect(users.Get("settings").Get("rest").String()).Should(Equal("/users"))
+		Expect(users.Get("settings").Get("apiKey").Exists()).Should(BeFalse())
+		Expect(users.Get("metadata").Get("region").String()).Should(Equal("eu"))
+	})
+
+	It("Should call the lifecycle handlers in order and fail the start when a service fails to start", func() {
+		var calls []string
+		record := func(name string) {
+			calls = append(calls, name)
+		}
+		lifecycle := func(name string) moleculer.ServiceSchema {
+			return moleculer.ServiceSchema{
+				Name: name,
+				Mixins: []moleculer.Mixin{moleculer.Mixin{
+					Name: name + "-mixin",
+					Created: func(svc moleculer.ServiceSchema, logger *log.Entry) {
+						record(name + "-mixin created")
+					},
+					Started: func(ctx moleculer.BrokerContext, svc moleculer.ServiceSchema) {
+						record(name + "-mixin started")
+					},
+				}},
+				Created: func(svc moleculer.ServiceSchema, logger *log.Entry) {
+					record(name + " created")
+				},
+				Started: func(ctx moleculer.BrokerContext, svc moleculer.ServiceSchema) {
+					record(name + " started")
+				},
+				Stopped: func(ctx moleculer.BrokerContext, svc moleculer.ServiceSchema) {
+					record(name + " stopped")
+				},
+			}
+		}
+
+		bkr := broker.New(&moleculer.Config{LogLevel: "fatal"})
+		bkr.Publish(lifecycle("users"), lifecycle("orders"))
+		Expect(calls).Should(Equal([]string{"users-mixin created", "users created", "orders-mixin created", "orders created"}))
+
+		calls = nil
+		Expect(bkr.Start()).Should(Succeed())
+		defer bkr.Stop()
+		Expect(calls).Should(Equal([]string{"users-mixin started", "users started", "orders-mixin started", "orders started"}))
+
+		calls = nil
+		bkr.Stop()
+		Expect(calls).Should(Equal([]string{"orders stopped", "users stopped"}))
+
+		calls = nil
+		failing := lifecycle("payments")
+		failing.Started = func(ctx moleculer.BrokerContext, svc moleculer.ServiceSchema) {
+			panic(errors.New("no connection to the payment gateway"))
+		}
+		bkr = broker.New(&moleculer.Config{LogLevel: "fatal"})
+		bkr.Publish(lifecycle("users"), lifecycle("orders"), failing)
+		calls = nil
+		err := bkr.Start()
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("no connection to the payment gateway"))
+		Expect(calls).Should(Equal([]string{
+			"users-mixin started", "users started", "orders-mixin started", "orders started", "payments-mixin started",
+			"orders stopped", "users stopped",
+		}))
+		Expect(bkr.IsStarted()).Should(BeFalse())
+	})
+
+	It("Should publish a struct service with typed params, validation and event methods", func() {
+		inventory := &inventoryService{reserved: make(chan string, 1)}
+		bkr := broker.New(&moleculer.Config{LogLevel: "error"})
+		bkr.Publish(inventory)
+		bkr.Start()
+		defer bkr.Stop()
+
+		result := <-bkr.Call("inventory.reserve", map[string]interface{}{"sku": "book-1", "quantity": 2})
+		Expect(result.Error()).Should(BeNil())
+		Expect(result.String()).Should(Equal("reserved 2 of book-1"))
+
+		result = <-bkr.Call("inventory.reserve", map[string]interface{}{"sku": "book-1", "quantity": 0})
+		Expect(result.IsError()).Should(BeTrue())
+		err, isMoleculerError := result.Error().(*moleculer.Error)
+		Expect(isMoleculerError).Should(BeTrue())
+		Expect(err.Name).Should(Equal("ValidationError"))
+
+		bkr.Emit("order.created", map[string]interface{}{"sku": "book-2", "quantity": 1})
+		Eventually(inventory.reserved).Should(Receive(Equal("book-2")))
+	})
+
+	It("Should restrict the calls to protected and private actions", func() {
+		config := &moleculer.Config{
+			LogLevel:    "fatal",
+			Transporter: "memory://visibility-test",
+			RetryPolicy: moleculer.RetryPolicy{Enabled: false},
+		}
+		handler := func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+			return "token"
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "visibility-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "auth",
+			Actions: []moleculer.Action{
+				moleculer.Action{Name: "login", Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+					return <-ctx.Call("auth.sign", nil)
+				}},
+				moleculer.Action{Name: "verify", Visibility: moleculer.VisibilityProtected, Handler: handler},
+				moleculer.Action{Name: "sign", Visibility: moleculer.VisibilityPrivate, Handler: handler},
+			},
+		}, moleculer.ServiceSchema{
+			Name: "profile",
+			Actions: []moleculer.Action{
+				moleculer.Action{Name: "sign", Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+					return <-ctx.Call("auth.sign", nil)
+				}},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		Expect((<-bkr1.Call("auth.login", nil)).String()).Should(Equal("token"))
+		Expect((<-bkr1.Call("auth.verify", nil)).String()).Should(Equal("token"))
+		Expect((<-bkr1.Call("auth.sign", nil)).Error()).Should(HaveOccurred())
+		err := (<-bkr1.Call("profile.sign", nil)).Error()
+		Expect(err).Should(HaveOccurred())
+		Expect(err.(*moleculer.Error).Name).Should(Equal("ActionNotAccessibleError"))
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "visibility-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitForActions("auth.login")).Should(Succeed())
+		Expect(bkr2.KnowAction("auth.verify")).Should(BeFalse())
+		Expect(bkr2.KnowAction("auth.sign")).Should(BeFalse())
+		Expect((<-bkr2.Call("auth.login", nil)).String()).Should(Equal("token"))
+		Expect((<-bkr2.Call("auth.verify", nil)).Error()).Should(HaveOccurred())
+	})
+
+	It("Should emit the events once per group and broadcast them to all handlers", func() {
+		var mutex sync.Mutex
+		received := map[string]int{}
+		counter := func(name string) moleculer.ServiceSchema {
+			return moleculer.ServiceSchema{
+				Name: name,
+				Events: []moleculer.Event{
+					moleculer.Event{
+						Name: "order.created",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+							mutex.Lock()
+							received[name]++
+							mutex.Unlock()
+						},
+					},
+				},
+			}
+		}
+		total := func(names ...string) int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			count := 0
+			for _, name := range names {
+				count += received[name]
+			}
+			return count
+		}
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://event-groups-test",
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "event-groups-broker1" },
+		})
+		workerA := counter("worker-a")
+		workerA.Events[0].Group = "workers"
+		workerB := counter("worker-b")
+		workerB.Events[0].Group = "workers"
+		bkr1.Publish(counter("mailer"), counter("audit"), workerA, workerB)
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "event-groups-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("mailer", "audit", "worker-a", "worker-b")).Should(Succeed())
+
+		for i := 0; i < 10; i++ {
+			bkr2.Emit("order.created", nil)
+		}
+		Eventually(func() int { return total("mailer") }).Should(Equal(10))
+		Eventually(func() int { return total("audit") }).Should(Equal(10))
+		Eventually(func() int { return total("worker-a", "worker-b") }).Should(Equal(10))
+
+		bkr2.Emit("order.created", nil, "audit")
+		Eventually(func() int { return total("audit") }).Should(Equal(11))
+
+		bkr2.Broadcast("order.created", nil)
+		Eventually(func() int { return total("worker-a", "worker-b") }).Should(Equal(12))
+		Eventually(func() int { return total("mailer") }).Should(Equal(11))
+		Consistently(func() int { return total("audit") }, 100*time.Millisecond).Should(Equal(12))
+	})
+
+	It("Should emit the local events only to the handlers of the local services", func() {
+		var mutex sync.Mutex
+		received := map[string]int{}
+		cache := func(nodeID string) moleculer.ServiceSchema {
+			return moleculer.ServiceSchema{
+				Name: "cache",
+				Events: []moleculer.Event{
+					moleculer.Event{
+						Name: "cache.clean",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+							mutex.Lock()
+							received[nodeID]++
+							mutex.Unlock()
+						},
+					},
+					moleculer.Event{
+						Name: "cache.ping",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+							mutex.Lock()
+							received[nodeID+" ping"]++
+							mutex.Unlock()
+						},
+					},
+				},
+			}
+		}
+		count := func(nodeID string) int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return received[nodeID]
+		}
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://emit-local-test",
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "emit-local-broker1" },
+		})
+		bkr1.Publish(cache("emit-local-broker1"))
+		bkr1.Start()
+		defer bkr1.Stop()
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "emit-local-broker2" },
+		})
+		bkr2.Publish(cache("emit-local-broker2"))
+		bkr2.Start()
+		defer bkr2.Stop()
+		Eventually(func() int {
+			bkr2.Broadcast("cache.ping", nil)
+			return count("emit-local-broker1 ping")
+		}).ShouldNot(Equal(0))
+
+		Expect(bkr2.EmitLocal("cache.clean", nil)).Should(Succeed())
+		Expect(bkr2.EmitLocal("cache.clean", nil, "cache")).Should(Succeed())
+		Expect(count("emit-local-broker2")).Should(Equal(2))
+		Consistently(func() int { return count("emit-local-broker1") }, 100*time.Millisecond).Should(Equal(0))
+	})
+
+	It("Should return the known nodes, the actions of the services and their endpoints", func() {
+		users := func(version string) moleculer.ServiceSchema {
+			return moleculer.ServiceSchema{
+				Name:    "users",
+				Version: version,
+				Actions: []moleculer.Action{
+					{
+						Name: "get",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return version
+						},
+					},
+					{
+						Name:       "audit",
+						Visibility: moleculer.VisibilityProtected,
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return nil
+						},
+					},
+				},
+			}
+		}
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://registry-query-test",
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "query-broker1" },
+		})
+		bkr1.Publish(users("1"), users("2"))
+		bkr1.Start()
+		defer bkr1.Stop()
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "query-broker2" },
+		})
+		bkr2.Publish(users("2"))
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitForActions("v1.users.get")).Should(Succeed())
+
+		nodes := bkr2.KnownNodes()
+		Expect(len(nodes)).Should(Equal(2))
+		Expect(nodes[0].ID).Should(Equal("query-broker1"))
+		Expect(nodes[0].Local).Should(BeFalse())
+		Expect(nodes[0].Available).Should(BeTrue())
+		Expect(nodes[0].Services).Should(Equal([]string{"$node", "v1.users", "v2.users"}))
+		Expect(nodes[1].ID).Should(Equal("query-broker2"))
+		Expect(nodes[1].Local).Should(BeTrue())
+
+		actions := bkr2.ActionsFor("users")
+		Expect(len(actions)).Should(Equal(3))
+		Expect(actions[0].Name).Should(Equal("v1.users.get"))
+		Expect(actions[0].Version).Should(Equal("1"))
+		Expect(actions[0].Endpoints).Should(Equal([]registry.EndpointInfo{
+			{NodeID: "query-broker1", Available: true},
+		}))
+		Expect(actions[1].Name).Should(Equal("v2.users.audit"))
+		Expect(actions[1].Visibility).Should(Equal(moleculer.VisibilityProtected))
+		Expect(len(actions[1].Endpoints)).Should(Equal(1))
+		Expect(actions[2].Name).Should(Equal("v2.users.get"))
+		Expect(len(actions[2].Endpoints)).Should(Equal(2))
+
+		Expect(len(bkr2.ActionsFor("v2.users"))).Should(Equal(2))
+		Expect(bkr2.ActionsFor("orders")).Should(BeEmpty())
+
+		bkr2.LocalBus().EmitAsync("$node.pong", []interface{}{map[string]interface{}{"nodeID": "query-broker1", "elapsedTime": int64(12)}})
+		Eventually(func() []registry.EndpointInfo { return bkr2.EndpointsFor("v2.users.get") }).Should(Equal([]registry.EndpointInfo{
+			{NodeID: "query-broker1", Available: true, Latency: 12 * time.Millisecond},
+			{NodeID: "query-broker2", Local: true, Available: true},
+		}))
+		Expect(bkr2.EndpointsFor("orders.get")).Should(BeEmpty())
+
+		bkr1.Stop()
+		Eventually(func() bool { return bkr2.KnownNodes()[0].Available }).Should(BeFalse())
+	})
+
+	It("Should measure the latency of the nodes with the latency strategy", func() {
+		latencyStrategy := strategy.NewLatencyStrategy()
+		latencyStrategy.Interval = 50 * time.Millisecond
+		bkr1 := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			Transporter:    "memory://latency-strategy-test",
+			DiscoverNodeID: func() string { return "latency-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "clock",
+			Actions: []moleculer.Action{
+				{
+					Name: "now",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return "tick"
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+		bkr2 := broker.New(&moleculer.Config{
+			LogLevel:        "error",
+			Transporter:     "memory://latency-strategy-test",
+			DiscoverNodeID:  func() string { return "latency-broker2" },
+			StrategyFactory: func() interface{} { return latencyStrategy },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitForActions("clock.now")).Should(Succeed())
+
+		Eventually(func() bool {
+			_, known := latencyStrategy.Latency("latency-broker1")
+			return known
+		}).Should(BeTrue())
+		Expect((<-bkr2.Call("clock.now", nil)).String()).Should(Equal("tick"))
+	})
+
+	It("Should route the calls with the same shard key to the same node with the shard strategy", func() {
+		accounts := func(nodeID string) *broker.ServiceBroker {
+			bkr := broker.New(&moleculer.Config{
+				LogLevel:       "error",
+				Transporter:    "memory://shard-strategy-test",
+				DiscoverNodeID: func() string { return nodeID },
+			})
+			bkr.Publish(moleculer.ServiceSchema{
+				Name: "accounts",
+				Actions: []moleculer.Action{
+					{
+						Name: "balance",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return nodeID
+						},
+					},
+				},
+			})
+			bkr.Start()
+			return bkr
+		}
+		bkr1 := accounts("shard-broker1")
+		defer bkr1.Stop()
+		bkr2 := accounts("shard-broker2")
+		defer bkr2.Stop()
+		bkr3 := broker.New(&moleculer.Config{
+			LogLevel:        "error",
+			Transporter:     "memory://shard-strategy-test",
+			DiscoverNodeID:  func() string { return "shard-broker3" },
+			StrategyFactory: func() interface{} { return strategy.NewShardStrategy("accountID") },
+		})
+		bkr3.Start()
+		defer bkr3.Stop()
+		Eventually(func() int { return len(bkr3.EndpointsFor("accounts.balance")) }).Should(Equal(2))
+
+		nodes := map[string]bool{}
+		for accountID := 0; accountID < 20; accountID++ {
+			params := map[string]interface{}{"accountID": accountID}
+			nodeID := (<-bkr3.Call("accounts.balance", params)).String()
+			for i := 0; i < 3; i++ {
+				Expect((<-bkr3.Call("accounts.balance", params)).String()).Should(Equal(nodeID))
+			}
+			nodes[nodeID] = true
+		}
+		Expect(nodes).Should(Equal(map[string]bool{"shard-broker1": true, "shard-broker2": true}))
+	})
+
+	It("Should select the endpoints with the strategy of the config or of the call", func() {
+		echo := func(nodeID string) *broker.ServiceBroker {
+			bkr := broker.New(&moleculer.Config{
+				LogLevel:       "error",
+				Transporter:    "memory://strategy-config-test",
+				DiscoverNodeID: func() string { return nodeID },
+			})
+			bkr.Publish(moleculer.ServiceSchema{
+				Name: "echo",
+				Actions: []moleculer.Action{
+					{
+						Name: "node",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return nodeID
+						},
+					},
+				},
+			})
+			bkr.Start()
+			return bkr
+		}
+		bkr1 := echo("strategy-broker1")
+		defer bkr1.Stop()
+		bkr2 := echo("strategy-broker2")
+		defer bkr2.Stop()
+		bkr3 := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			Transporter:    "memory://strategy-config-test",
+			DiscoverNodeID: func() string { return "strategy-broker3" },
+			Strategy:       "RoundRobin",
+		})
+		bkr3.Start()
+		defer bkr3.Stop()
+		Eventually(func() int { return len(bkr3.EndpointsFor("echo.node")) }).Should(Equal(2))
+
+		alternates := func(call func() string) {
+			previous := call()
+			for i := 0; i < 4; i++ {
+				current := call()
+				Expect(current).ShouldNot(Equal(previous))
+				previous = current
+			}
+		}
+		alternates(func() string {
+			return (<-bkr3.Call("echo.node", nil)).String()
+		})
+
+		bkr4 := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			Transporter:    "memory://strategy-config-test",
+			DiscoverNodeID: func() string { return "strategy-broker4" },
+		})
+		bkr4.Start()
+		defer bkr4.Stop()
+		Eventually(func() int { return len(bkr4.EndpointsFor("echo.node")) }).Should(Equal(2))
+		alternates(func() string {
+			return (<-bkr4.Call("echo.node", nil, moleculer.Options{Strategy: "RoundRobin"})).String()
+		})
+	})
+
+	It("Should prefer the local endpoints unless PreferLocal is false in the config or in the call", func() {
+		preferLocal := false
+		echo := func(nodeID string, preferLocal *bool) *broker.ServiceBroker {
+			bkr := broker.New(&moleculer.Config{
+				LogLevel:       "error",
+				Transporter:    "memory://prefer-local-test",
+				DiscoverNodeID: func() string { return nodeID },
+				Strategy:       "RoundRobin",
+				PreferLocal:    preferLocal,
+			})
+			bkr.Publish(moleculer.ServiceSchema{
+				Name: "echo",
+				Actions: []moleculer.Action{
+					{
+						Name: "node",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return nodeID
+						},
+					},
+				},
+			})
+			bkr.Start()
+			return bkr
+		}
+		bkr1 := echo("prefer-local-broker1", nil)
+		defer bkr1.Stop()
+		bkr2 := echo("prefer-local-broker2", &preferLocal)
+		defer bkr2.Stop()
+		Eventually(func() int { return len(bkr1.EndpointsFor("echo.node")) }).Should(Equal(2))
+		Eventually(func() int { return len(bkr2.EndpointsFor("echo.node")) }).Should(Equal(2))
+
+		nodes := func(bkr *broker.ServiceBroker, opts ...moleculer.Options) map[string]int {
+			counts := map[string]int{}
+			for i := 0; i < 4; i++ {
+				counts[(<-bkr.Call("echo.node", nil, opts...)).String()]++
+			}
+			return counts
+		}
+		Expect(nodes(bkr1)).Should(Equal(map[string]int{"prefer-local-broker1": 4}))
+		Expect(nodes(bkr1, moleculer.Options{PreferLocal: &preferLocal})).Should(And(
+			HaveKey("prefer-local-broker1"), HaveKey("prefer-local-broker2")))
+		Expect(nodes(bkr2)).Should(And(HaveKey("prefer-local-broker1"), HaveKey("prefer-local-broker2")))
+		preferred := true
+		Expect(nodes(bkr2, moleculer.Options{PreferLocal: &preferred})).Should(Equal(map[string]int{"prefer-local-broker2": 4}))
+	})
+
+	It("Should pin the calls of a session to the same node while it is available", func() {
+		echo := func(nodeID string) *broker.ServiceBroker {
+			bkr := broker.New(&moleculer.Config{
+				LogLevel:       "error",
+				Transporter:    "memory://sticky-session-test",
+				DiscoverNodeID: func() string { return nodeID },
+			})
+			bkr.Publish(moleculer.ServiceSchema{
+				Name: "echo",
+				Actions: []moleculer.Action{
+					{
+						Name: "node",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return nodeID
+						},
+					},
+				},
+			})
+			bkr.Start()
+			return bkr
+		}
+		bkr1 := echo("sticky-broker1")
+		defer bkr1.Stop()
+		bkr2 := echo("sticky-broker2")
+		defer bkr2.Stop()
+		bkr3 := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			Transporter:    "memory://sticky-session-test",
+			DiscoverNodeID: func() string { return "sticky-broker3" },
+			Strategy:       "RoundRobin",
+		})
+		bkr3.Start()
+		defer bkr3.Stop()
+		Eventually(func() int { return len(bkr3.EndpointsFor("echo.node")) }).Should(Equal(2))
+
+		call := func(opts ...moleculer.Options) string {
+			return (<-bkr3.Call("echo.node", nil, opts...)).String()
+		}
+		pinned := call(moleculer.Options{Session: "session-1"})
+		for i := 0; i < 4; i++ {
+			Expect(call(moleculer.Options{Session: "session-1"})).Should(Equal(pinned))
+		}
+		meta := payload.New(map[string]interface{}{moleculer.SessionMetaKey: "session-2"})
+		metaPinned := call(moleculer.Options{Meta: meta})
+		for i := 0; i < 4; i++ {
+			Expect(call(moleculer.Options{Meta: meta})).Should(Equal(metaPinned))
+		}
+
+		if pinned == "sticky-broker1" {
+			bkr1.Stop()
+			Eventually(func() int { return len(bkr3.EndpointsFor("echo.node")) }).Should(Equal(1))
+			Expect(call(moleculer.Options{Session: "session-1"})).Should(Equal("sticky-broker2"))
+			bkr2.Stop()
+		} else {
+			bkr2.Stop()
+			Eventually(func() int { return len(bkr3.EndpointsFor("echo.node")) }).Should(Equal(1))
+			Expect(call(moleculer.Options{Session: "session-1"})).Should(Equal("sticky-broker1"))
+			bkr1.Stop()
+		}
+	})
+
+	It("Should disconnect, restore and remove the offline nodes with their actions", func() {
+		events := make(chan string, 10)
+		watcher := broker.New(&moleculer.Config{
+			LogLevel:              "error",
+			Transporter:           "memory://node-lifecycle-test",
+			DiscoverNodeID:        func() string { return "lifecycle-watcher" },
+			OfflineCheckFrequency: 100 * time.Millisecond,
+			OfflineTimeout:        time.Second,
+		})
+		watcher.Publish(moleculer.ServiceSchema{
+			Name: "watcher",
+			Events: []moleculer.Event{
+				{Name: "$node.disconnected", Handler: func(ctx moleculer.Context, params moleculer.Payload) { events <- "disconnected" }},
+				{Name: "$node.reconnected", Handler: func(ctx moleculer.Context, params moleculer.Payload) { events <- "reconnected" }},
+				{Name: "$node.removed", Handler: func(ctx moleculer.Context, params moleculer.Payload) { events <- "removed " + params.String() }},
+			},
+		})
+		watcher.Start()
+		defer watcher.Stop()
+		worker := func() *broker.ServiceBroker {
+			bkr := broker.New(&moleculer.Config{
+				LogLevel:       "error",
+				Transporter:    "memory://node-lifecycle-test",
+				DiscoverNodeID: func() string { return "lifecycle-worker" },
+			})
+			bkr.Publish(moleculer.ServiceSchema{
+				Name: "worker",
+				Actions: []moleculer.Action{
+					{
+						Name: "work",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return "done"
+						},
+					},
+				},
+			})
+			bkr.Start()
+			return bkr
+		}
+		available := func() int { return len(watcher.EndpointsFor("worker.work")) }
+
+		bkr := worker()
+		defer bkr.Stop()
+		Eventually(available).Should(Equal(1))
+		bkr.Stop()
+		Eventually(events).Should(Receive(Equal("disconnected")))
+		Expect(available()).Should(Equal(0))
+
+		bkr = worker()
+		Eventually(events).Should(Receive(Equal("reconnected")))
+		Eventually(available).Should(Equal(1))
+		Expect((<-watcher.Call("worker.work", nil)).String()).Should(Equal("done"))
+		bkr.Stop()
+		Eventually(events).Should(Receive(Equal("disconnected")))
+
+		Eventually(events, 5*time.Second).Should(Receive(Equal("removed lifecycle-worker")))
+		for _, node := range watcher.KnownNodes() {
+			Expect(node.ID).ShouldNot(Equal("lifecycle-worker"))
+		}
+	})
+
+	It("Should send the node metadata in the INFO and route the calls to the same zone", func() {
+		echo := func(nodeID, zone string) *broker.ServiceBroker {
+			bkr := broker.New(&moleculer.Config{
+				LogLevel:       "error",
+				Transporter:    "memory://node-metadata-test",
+				DiscoverNodeID: func() string { return nodeID },
+				Metadata:       map[string]interface{}{"zone": zone},
+			})
+			bkr.Publish(moleculer.ServiceSchema{
+				Name: "echo",
+				Actions: []moleculer.Action{
+					{
+						Name: "node",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return nodeID
+						},
+					},
+				},
+			})
+			bkr.Start()
+			return bkr
+		}
+		bkr1 := echo("metadata-broker1", "eu-1")
+		defer bkr1.Stop()
+		bkr2 := echo("metadata-broker2", "us-1")
+		defer bkr2.Stop()
+		bkr3 := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			Transporter:    "memory://node-metadata-test",
+			DiscoverNodeID: func() string { return "metadata-broker3" },
+			Metadata:       map[string]interface{}{"zone": "us-1", "team": "billing"},
+			Strategy:       "Zone",
+		})
+		bkr3.Start()
+		defer bkr3.Stop()
+		Eventually(func() int { return len(bkr3.EndpointsFor("echo.node")) }).Should(Equal(2))
+
+		nodes := map[string]registry.NodeInfo{}
+		for _, node := range bkr3.KnownNodes() {
+			nodes[node.ID] = node
+		}
+		Expect(nodes["metadata-broker1"].Metadata).Should(Equal(map[string]interface{}{"zone": "eu-1"}))
+		Expect(nodes["metadata-broker1"].Client).Should(HaveKeyWithValue("type", "go"))
+		Expect(nodes["metadata-broker3"].Metadata).Should(HaveKeyWithValue("team", "billing"))
+
+		for i := 0; i < 10; i++ {
+			Expect((<-bkr3.Call("echo.node", nil)).String()).Should(Equal("metadata-broker2"))
+		}
+	})
+
+	It("Should call the endpoints of other zones only when the zone of the local node has none", func() {
+		echo := func(nodeID, zone string) *broker.ServiceBroker {
+			bkr := broker.New(&moleculer.Config{
+				LogLevel:       "error",
+				Transporter:    "memory://zone-fallback-test",
+				DiscoverNodeID: func() string { return nodeID },
+				Metadata:       map[string]interface{}{"zone": zone},
+			})
+			bkr.Publish(moleculer.ServiceSchema{
+				Name: "echo",
+				Actions: []moleculer.Action{
+					{
+						Name: "node",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return nodeID
+						},
+					},
+				},
+			})
+			bkr.Start()
+			return bkr
+		}
+		bkr1 := echo("zone-broker1", "eu-1a")
+		defer bkr1.Stop()
+		bkr2 := echo("zone-broker2", "us-1a")
+		defer bkr2.Stop()
+		bkr3 := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			Transporter:    "memory://zone-fallback-test",
+			DiscoverNodeID: func() string { return "zone-broker3" },
+			Metadata:       map[string]interface{}{"zone": "eu-1a"},
+			StrategyFactory: func() interface{} {
+				return strategy.ZoneAware(strategy.NewRoundRobinStrategy())
+			},
+		})
+		bkr3.Start()
+		defer bkr3.Stop()
+		Eventually(func() int { return len(bkr3.EndpointsFor("echo.node")) }).Should(Equal(2))
+		for i := 0; i < 5; i++ {
+			Expect((<-bkr3.Call("echo.node", nil)).String()).Should(Equal("zone-broker1"))
+		}
+
+		bkr1.Stop()
+		Eventually(func() int { return len(bkr3.EndpointsFor("echo.node")) }).Should(Equal(1))
+		Expect((<-bkr3.Call("echo.node", nil)).String()).Should(Equal("zone-broker2"))
+	})
+
+	It("Should create the node ID from the hostname and the process ID, or use the config NodeID", func() {
+		hostname, _ := os.Hostname()
+		prefix := fmt.Sprint(strings.ToLower(hostname), "-", os.Getpid())
+		first := broker.New(&moleculer.Config{LogLevel: "error"}).LocalNode().GetID()
+		second := broker.New(&moleculer.Config{LogLevel: "error"}).LocalNode().GetID()
+		Expect(first).Should(HavePrefix(prefix))
+		Expect(first).Should(MatchRegexp("^[a-z0-9._-]+$"))
+		Expect(second).Should(HavePrefix(prefix + "-"))
+		Expect(second).ShouldNot(Equal(first))
+
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			NodeID:         "billing-1",
+			DiscoverNodeID: func() string { return "discovered" },
+		})
+		Expect(bkr.LocalNode().GetID()).Should(Equal("billing-1"))
+	})
+
+	It("Should detect another broker instance with the same node ID", func() {
+		bkr1 := broker.New(&moleculer.Config{
+			LogLevel:             "fatal",
+			Transporter:          "memory://node-id-conflict-test",
+			NodeID:               "conflict-node",
+			StopOnNodeIDConflict: true,
+		})
+		bkr1.Start()
+
+		conflicts := make(chan string, 2)
+		bkr2 := broker.New(&moleculer.Config{
+			LogLevel:    "fatal",
+			Transporter: "memory://node-id-conflict-test",
+			NodeID:      "conflict-node",
+		})
+		bkr2.Publish(moleculer.ServiceSchema{
+			Name: "watcher",
+			Events: []moleculer.Event{
+				{
+					Name: "$node.id.conflict",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+						conflicts <- params.Get("nodeID").String()
+					},
+				},
+			},
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+
+		Eventually(conflicts).Should(Receive(Equal("conflict-node")))
+		Eventually(bkr1.IsStarted).Should(BeFalse())
+		Expect(bkr2.IsStarted()).Should(BeTrue())
+		Expect(bkr2.KnownNodes()).Should(HaveLen(1))
+	})
+
+	It("Should track the heartbeats of each node and expire it after its own heartbeat interval", func() {
+		bkr1 := broker.New(&moleculer.Config{
+			LogLevel:           "fatal",
+			Transporter:        "memory://heartbeat-scheduler-test",
+			NodeID:             "heartbeat-broker1",
+			HeartbeatFrequency: 20 * time.Millisecond,
+			HeartbeatTimeout:   100 * time.Millisecond,
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+		bkr2 := broker.New(&moleculer.Config{
+			LogLevel:           "fatal",
+			Transporter:        "memory://heartbeat-scheduler-test",
+			NodeID:             "heartbeat-broker2",
+			HeartbeatFrequency: 20 * time.Millisecond,
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		slowBroker := broker.New(&moleculer.Config{
+			LogLevel:           "fatal",
+			Transporter:        "memory://heartbeat-scheduler-test",
+			NodeID:             "heartbeat-broker3",
+			HeartbeatFrequency: time.Hour,
+		})
+		slowBroker.Start()
+		defer slowBroker.Stop()
+		Expect(bkr1.WaitForNodes("heartbeat-broker2", "heartbeat-broker3")).Should(Succeed())
+
+		availableNodes := func() []string {
+			result := []string{}
+			for _, node := range bkr1.KnownNodes() {
+				if node.Available && !node.Local {
+					Expect(node.LastHeartbeat).Should(BeTemporally(">", time.Now().Add(-time.Hour)))
+					result = append(result, node.ID)
+				}
+			}
+			return result
+		}
+		Consistently(availableNodes, 300*time.Millisecond).Should(Equal([]string{"heartbeat-broker2", "heartbeat-broker3"}))
+	})
+
+	It("Should discover and disconnect the peers reported by the discoverer", func() {
+		discoverer := &peerDiscoverer{}
+		events := make(chan string, 10)
+		bkr1 := broker.New(&moleculer.Config{
+			LogLevel:          "fatal",
+			Transporter:       "memory://discoverer-test",
+			NodeID:            "discoverer-broker1",
+			DiscovererFactory: func() interface{} { return discoverer },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "watcher",
+			Events: []moleculer.Event{
+				{Name: "$node.disconnected", Handler: func(ctx moleculer.Context, params moleculer.Payload) { events <- "disconnected " + params.String() }},
+				{Name: "$node.reconnected", Handler: func(ctx moleculer.Context, params moleculer.Payload) { events <- "reconnected " + params.String() }},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+		Expect(discoverer.started()).Should(BeTrue())
+		bkr2 := broker.New(&moleculer.Config{
+			LogLevel:    "fatal",
+			Transporter: "memory://discoverer-test",
+			NodeID:      "discoverer-broker2",
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr1.WaitForNodes("discoverer-broker2")).Should(Succeed())
+
+		discoverer.lost(discovery.Peer{Name: "pod-2", NodeID: "discoverer-broker2"})
+		Eventually(events).Should(Receive(Equal("disconnected discoverer-broker2")))
+		discoverer.found(discovery.Peer{Name: "pod-2", NodeID: "discoverer-broker2"})
+		Eventually(events).Should(Receive(Equal("reconnected discoverer-broker2")))
+
+		bkr2.Stop()
+		bkr1.Stop()
+		Expect(discoverer.started()).Should(BeFalse())
+	})
+
+	It("Should emit $services.changed with the actions and events added and removed", func() {
+		changes := make(chan string, 10)
+		bkr1 := broker.New(&moleculer.Config{
+			LogLevel:    "fatal",
+			Transporter: "memory://services-diff-test",
+			NodeID:      "diff-broker1",
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "gateway",
+			Events: []moleculer.Event{
+				{
+					Name: "$services.changed",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+						if params.Get("service").Get("name").String() == "calc" {
+							changes <- fmt.Sprint(params.Get("localService").Bool(), " added: ", params.Get("added").RawMap(), " removed: ", params.Get("removed").RawMap())
+						}
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+		bkr2 := broker.New(&moleculer.Config{
+			LogLevel:    "fatal",
+			Transporter: "memory://services-diff-test",
+			NodeID:      "diff-broker2",
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		bkr2.Publish(moleculer.ServiceSchema{
+			Name: "calc",
+			Actions: []moleculer.Action{
+				{Name: "add", Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} { return nil }},
+			},
+			Events: []moleculer.Event{
+				{Name: "numbers.reset", Handler: func(ctx moleculer.Context, params moleculer.Payload) {}},
+			},
+		})
+		Eventually(changes).Should(Receive(Equal("false added: map[actions:[calc.add] events:[numbers.reset]] removed: map[actions:[] events:[]]")))
+
+		Expect(bkr2.DestroyService("calc")).Should(Succeed())
+		Eventually(changes).Should(Receive(Equal("false added: map[actions:[] events:[]] removed: map[actions:[calc.add] events:[numbers.reset]]")))
+	})
+
+	It("Should ramp up the calls sent to a node that joined during the slow start window", func() {
+		counterService := func(nodeID string) moleculer.ServiceSchema {
+			return moleculer.ServiceSchema{
+				Name: "counter",
+				Actions: []moleculer.Action{
+					{Name: "node", Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} { return nodeID }},
+				},
+			}
+		}
+		newBroker := func(nodeID string, window time.Duration) *broker.ServiceBroker {
+			return broker.New(&moleculer.Config{
+				LogLevel:        "fatal",
+				Transporter:     "memory://slow-start-test",
+				NodeID:          nodeID,
+				SlowStartWindow: window,
+			})
+		}
+		caller := newBroker("slow-start-caller", 500*time.Millisecond)
+		defer caller.Stop()
+		caller.Start()
+		oldNode := newBroker("slow-start-old", 0)
+		defer oldNode.Stop()
+		oldNode.Publish(counterService("slow-start-old"))
+		oldNode.Start()
+		Expect(caller.WaitForNodes("slow-start-old")).Should(Succeed())
+		time.Sleep(600 * time.Millisecond)
+
+		newNode := newBroker("slow-start-new", 0)
+		defer newNode.Stop()
+		newNode.Publish(counterService("slow-start-new"))
+		newNode.Start()
+		Expect(caller.WaitForNodes("slow-start-new")).Should(Succeed())
+		Expect(caller.WaitForActions("counter.node")).Should(Succeed())
+		callNodes := func() map[string]int {
+			counts := map[string]int{}
+			for index := 0; index < 40; index++ {
+				counts[(<-caller.Call("counter.node", nil)).String()]++
+			}
+			return counts
+		}
+		Expect(callNodes()["slow-start-new"]).Should(BeNumerically("<", 10))
+		Eventually(func() int { return callNodes()["slow-start-new"] }, 2*time.Second).Should(BeNumerically(">", 10))
+	})
+
+	It("Should deliver the events emitted and broadcast with a target node to that node only", func() {
+		received := make(chan string, 10)
+		workerService := func(nodeID string) moleculer.ServiceSchema {
+			return moleculer.ServiceSchema{
+				Name: "worker",
+				Events: []moleculer.Event{
+					{Name: "worker.reload", Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+						received <- nodeID + " " + params.String()
+					}},
+				},
+			}
+		}
+		newBroker := func(nodeID string) *broker.ServiceBroker {
+			return broker.New(&moleculer.Config{
+				LogLevel:    "fatal",
+				Transporter: "memory://target-node-events-test",
+				NodeID:      nodeID,
+			})
+		}
+		controller := newBroker("target-controller")
+		defer controller.Stop()
+		controller.Publish(moleculer.ServiceSchema{
+			Name: "controller",
+			Actions: []moleculer.Action{
+				{Name: "reload", Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+					ctx.EmitWithOptions("worker.reload", "from action", moleculer.EventOptions{NodeID: params.String()})
+					return nil
+				}},
+			},
+		})
+		controller.Start()
+		worker1 := newBroker("target-worker1")
+		defer worker1.Stop()
+		worker1.Publish(workerService("target-worker1"))
+		worker1.Start()
+		worker2 := newBroker("target-worker2")
+		defer worker2.Stop()
+		worker2.Publish(workerService("target-worker2"))
+		worker2.Start()
+		Expect(controller.WaitForNodes("target-worker1", "target-worker2")).Should(Succeed())
+
+		for index := 0; index < 5; index++ {
+			Expect(controller.EmitWithOptions("worker.reload", "emit", moleculer.EventOptions{NodeID: "target-worker2"})).Should(Succeed())
+			Eventually(received).Should(Receive(Equal("target-worker2 emit")))
+		}
+		Expect(controller.BroadcastWithOptions("worker.reload", "broadcast", moleculer.EventOptions{NodeID: "target-worker1", Groups: []string{"worker"}})).Should(Succeed())
+		Eventually(received).Should(Receive(Equal("target-worker1 broadcast")))
+		<-controller.Call("controller.reload", "target-worker1")
+		Eventually(received).Should(Receive(Equal("target-worker1 from action")))
+
+		Expect(controller.EmitWithOptions("worker.reload", "unknown", moleculer.EventOptions{NodeID: "target-unknown"})).Should(Succeed())
+		Consistently(received, 200*time.Millisecond).ShouldNot(Receive())
+	})
+
+	It("Should cache the results of the local and remote actions with cache settings", func() {
+		var mutex sync.Mutex
+		calls := map[string]int{}
+		count := func(action string) int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return calls[action]
+		}
+		handler := func(action string) moleculer.ActionHandler {
+			return func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+				mutex.Lock()
+				defer mutex.Unlock()
+				calls[action]++
+				return map[string]interface{}{"id": params.Get("id").Int(), "calls": calls[action]}
+			}
+		}
+		config := &moleculer.Config{
+			LogLevel:    "fatal",
+			Transporter: "memory://cacher-test",
+			Cacher:      "memory://?ttl=1m",
+		}
+		bkr1 := broker.New(config, &moleculer.Config{NodeID: "cacher-broker1"})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "users",
+			Actions: []moleculer.Action{
+				{Name: "get", Handler: handler("users.get"), Cache: &moleculer.CacheSettings{Enabled: true, Keys: []string{"id"}}},
+				{Name: "count", Handler: handler("users.count")},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+		bkr2 := broker.New(config, &moleculer.Config{NodeID: "cacher-broker2"})
+		bkr2.Publish(moleculer.ServiceSchema{
+			Name: "posts",
+			Actions: []moleculer.Action{
+				{Name: "find", Handler: handler("posts.find"), Settings: map[string]interface{}{"cache": map[string]interface{}{"keys": []string{"id", "#tenant"}}}},
+			},
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr1.WaitForActions("posts.find")).Should(Succeed())
+
+		for index := 0; index < 3; index++ {
+			Expect((<-bkr1.Call("users.get", map[string]interface{}{"id": 1})).Get("calls").Int()).Should(Equal(1))
+			Expect((<-bkr1.Call("users.count", map[string]interface{}{"id": 1})).Get("calls").Int()).Should(Equal(index + 1))
+		}
+		Expect((<-bkr1.Call("users.get", map[string]interface{}{"id": 2})).Get("calls").Int()).Should(Equal(2))
+
+		tenant := func(name string) moleculer.Options {
+			return moleculer.Options{Meta: payload.New(map[string]interface{}{"tenant": name})}
+		}
+		Expect((<-bkr1.Call("posts.find", map[string]interface{}{"id": 1}, tenant("a"))).Get("calls").Int()).Should(Equal(1))
+		Expect((<-bkr1.Call("posts.find", map[string]interface{}{"id": 1}, tenant("a"))).Get("calls").Int()).Should(Equal(1))
+		Expect((<-bkr1.Call("posts.find", map[string]interface{}{"id": 1}, tenant("b"))).Get("calls").Int()).Should(Equal(2))
+		_, cached := bkr1.Cacher().Get("posts.find:1|a")
+		Expect(cached).Should(BeTrue())
+
+		bkr1.Cacher().Clean("users.*")
+		Expect((<-bkr1.Call("users.get", map[string]interface{}{"id": 1})).Get("calls").Int()).Should(Equal(3))
+		Expect(count("posts.find")).Should(Equal(2))
+	})
+
+	It("Should deliver a copy of the payload to each local event handler with CopyOnEmit", func() {
+		received := make(chan string, 2)
+		handler := func(ctx moleculer.Context, params moleculer.Payload) {
+			received <- params.Get("status").String()
+			params.RawMap()["status"] = "changed"
+		}
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			DiscoverNodeID: func() string { return "copy-on-emit-broker" },
+			CopyOnEmit:     true,
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name:   "users",
+			Events: []moleculer.Event{moleculer.Event{Name: "user.created", Handler: handler}},
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name:   "emails",
+			Events: []moleculer.Event{moleculer.Event{Name: "user.created", Handler: handler}},
+		})
+		bkr.Start()
+		defer bkr.Stop()
+
+		params := map[string]interface{}{"status": "new"}
+		bkr.Broadcast("user.created", params)
+		Eventually(received).Should(Receive(Equal("new")))
+		Eventually(received).Should(Receive(Equal("new")))
+		Expect(params["status"]).Should(Equal("new"))
+	})
+
+	It("Should keep 64-bit ids intact in remote calls with PreserveNumbers", func() {
+		config := &moleculer.Config{
+			LogLevel:        "error",
+			Transporter:     "memory://preserve-numbers-test",
+			PreserveNumbers: true,
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "numbers-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "orders",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "get",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return map[string]interface{}{"id": params.Get("id").Int64()}
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "numbers-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("orders")).Should(Succeed())
+
+		id := int64(9007199254740993)
+		result := <-bkr2.Call("orders.get", map[string]interface{}{"id": id})
+		Expect(result.Error()).Should(BeNil())
+		Expect(result.Get("id").Int64()).Should(Equal(id))
+	})
+
+	It("Should send the logs to the logger of the config without changing the global logger", func() {
+		globalLevel := log.GetLevel()
+		recorder := &logRecorder{}
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "debug",
+			DiscoverNodeID: func() string { return "logger-broker" },
+			Logger:         recorder,
+		})
+		bkr.Start()
+		bkr.Stop()
+
+		Expect(log.GetLevel()).Should(Equal(globalLevel))
+		Expect(recorder.messages(log.InfoLevel)).Should(ContainElement("Service Broker is stopping..."))
+		Expect(recorder.messages(log.DebugLevel)).ShouldNot(BeEmpty())
+		Expect(recorder.messages(log.TraceLevel)).Should(BeEmpty())
+		Expect(recorder.fields("broker")).Should(ContainElement("logger-broker"))
+	})
+
+	It("Should wrap the action and event handlers with middlewares", func() {
+		var mutex sync.Mutex
+		events := []string{}
+		auth := moleculer.Middlewares{
+			"createService": func(params interface{}, next func(...interface{})) {
+				schema := params.(moleculer.ServiceSchema)
+				schema.Actions = append(schema.Actions, moleculer.Action{
+					Name: "ping",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return "pong"
+					},
+				})
+				next(schema)
+			},
+			"localAction": func(params interface{}, next func(...interface{})) {
+				wrap := params.(middleware.ActionHandlerParams)
+				if strings.HasPrefix(wrap.Action, "$") {
+					next()
+					return
+				}
+				handler := wrap.Handler
+				wrap.Handler = func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+					if params.Get("token").String() != "secret" {
+						return errors.New("access denied to " + wrap.Action)
+					}
+					return handler(ctx, params)
+				}
+				next(wrap)
+			},
+			"localEvent": func(params interface{}, next func(...interface{})) {
+				wrap := params.(middleware.EventHandlerParams)
+				handler := wrap.Handler
+				wrap.Handler = func(ctx moleculer.Context, params moleculer.Payload) {
+					mutex.Lock()
+					events = append(events, wrap.Service+":"+wrap.Event)
+					mutex.Unlock()
+					handler(ctx, params)
+				}
+				next(wrap)
+			},
+		}
+		bkr1 := broker.New(&moleculer.Config{
+			LogLevel:       "fatal",
+			Transporter:    "memory://wrap-middlewares",
+			DiscoverNodeID: func() string { return "wrapped-broker" },
+			Middlewares:    []moleculer.Middlewares{auth},
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "secrets",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "get",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return "the secret"
+					},
+				},
+			},
+			Events: []moleculer.Event{
+				moleculer.Event{
+					Name:    "secret.read",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) {},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		remoteCalls := 0
+		cache := map[string]interface{}{}
+		bkr2 := broker.New(&moleculer.Config{
+			LogLevel:       "fatal",
+			Transporter:    "memory://wrap-middlewares",
+			DiscoverNodeID: func() string { return "caller-broker" },
+			Middlewares: []moleculer.Middlewares{
+				moleculer.Middlewares{
+					"remoteAction": func(params interface{}, next func(...interface{})) {
+						wrap := params.(middleware.ActionHandlerParams)
+						handler := wrap.Handler
+						wrap.Handler = func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							key := wrap.NodeID + ":" + wrap.Action + ":" + params.String()
+							if result, cached := cache[key]; cached {
+								return result
+							}
+							remoteCalls++
+							result := handler(ctx, params)
+							cache[key] = result
+							return result
+						}
+						next(wrap)
+					},
+				},
+			},
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("secrets")).Should(Succeed())
+
+		Expect((<-bkr1.Call("secrets.get", nil)).Error()).Should(MatchError("access denied to secrets.get"))
+		Expect((<-bkr1.Call("secrets.get", map[string]interface{}{"token": "secret"})).String()).Should(Equal("the secret"))
+		Expect((<-bkr1.Call("secrets.ping", map[string]interface{}{"token": "secret"})).String()).Should(Equal("pong"))
+
+		for i := 0; i < 3; i++ {
+			Expect((<-bkr2.Call("secrets.get", map[string]interface{}{"token": "secret"})).String()).Should(Equal("the secret"))
+		}
+		Expect(remoteCalls).Should(Equal(1))
+
+		bkr1.Emit("secret.read", nil)
+		Eventually(func() []string {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return events
+		}).Should(ContainElement("secrets:secret.read"))
+	})
+
+	It("Should run brokers with different configs in the same process", func() {
+		create := func(namespace, level string, recorder *logRecorder) *broker.ServiceBroker {
+			bkr := broker.New(&moleculer.Config{
+				LogLevel:       level,
+				Logger:         recorder,
+				Namespace:      namespace,
+				Transporter:    "memory://multiple-brokers",
+				DiscoverNodeID: func() string { return namespace + "-broker" },
+			})
+			bkr.Publish(moleculer.ServiceSchema{
+				Name: "echo",
+				Actions: []moleculer.Action{
+					moleculer.Action{
+						Name: "namespace",
+						Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+							return namespace
+						},
+					},
+				},
+			})
+			return bkr
+		}
+		debugRecorder, errorRecorder := &logRecorder{}, &logRecorder{}
+		brokers := make([]*broker.ServiceBroker, 2)
+		var wg sync.WaitGroup
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			brokers[0] = create("alpha", "debug", debugRecorder)
+			brokers[0].Start()
+		}()
+		go func() {
+			defer wg.Done()
+			brokers[1] = create("beta", "error", errorRecorder)
+			brokers[1].Start()
+		}()
+		wg.Wait()
+		defer brokers[0].Stop()
+		defer brokers[1].Stop()
+
+		for index, namespace := range []string{"alpha", "beta"} {
+			for i := 0; i < 10; i++ {
+				Expect((<-brokers[index].Call("echo.namespace", nil)).String()).Should(Equal(namespace))
+			}
+			Expect((<-brokers[index].Call("$node.list", nil)).Len()).Should(Equal(1))
+		}
+		brokers[0].Stop()
+		brokers[1].Stop()
+
+		Expect(debugRecorder.messages(log.DebugLevel)).ShouldNot(BeEmpty())
+		Expect(debugRecorder.fields("broker")).ShouldNot(ContainElement("beta-broker"))
+		Expect(errorRecorder.messages(log.DebugLevel)).Should(BeEmpty())
+		Expect(errorRecorder.messages(log.InfoLevel)).Should(BeEmpty())
+		Expect(errorRecorder.fields("broker")).ShouldNot(ContainElement("alpha-broker"))
+	})
+
+	It("Should log the requestID, action, caller, nodeID, duration and error of each action with AccessLog", func() {
+		config := &moleculer.Config{
+			LogLevel:    "info",
+			Transporter: "memory://access-log-test",
+			AccessLog:   true,
+		}
+		recorder := &logRecorder{}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "access-log-broker1" },
+			Logger:         recorder,
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "users",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "get",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						if params.String() == "unknown" {
+							return errors.New("user not found")
+						}
+						return "John"
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "access-log-broker2" },
+			Logger:         &logRecorder{},
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("users")).Should(Succeed())
+
+		Expect((<-bkr2.Call("users.get", "john")).String()).Should(Equal("John"))
+		Expect((<-bkr1.Call("users.get", "unknown")).IsError()).Should(BeTrue())
+
+		finished := recorder.entriesWith("Action finished")
+		Expect(finished).Should(HaveLen(1))
+		Expect(finished[0].Level).Should(Equal(log.InfoLevel))
+		Expect(finished[0].Data["action"]).Should(Equal("users.get"))
+		Expect(finished[0].Data["caller"]).Should(Equal("access-log-broker2"))
+		Expect(finished[0].Data["nodeID"]).Should(Equal("access-log-broker1"))
+		Expect(finished[0].Data["requestID"]).ShouldNot(BeEmpty())
+		Expect(finished[0].Data["duration"]).Should(BeNumerically(">=", 0))
+
+		failed := recorder.entriesWith("Action failed")
+		Expect(failed).Should(HaveLen(1))
+		Expect(failed[0].Level).Should(Equal(log.ErrorLevel))
+		Expect(failed[0].Data["caller"]).Should(Equal("access-log-broker1"))
+		Expect(failed[0].Data["error"]).Should(Equal("user not found"))
+	})
+
+	It("Should call the Created, Started and Stopped hooks of the config", func() {
+		events := []string{}
+		bkr := broker.New(&moleculer.Config{
+			LogLevel:       "error",
+			DiscoverNodeID: func() string { return "lifecycle-broker" },
+			Created: func(delegates *moleculer.BrokerDelegates) {
+				events = append(events, "created "+delegates.LocalNode().GetID())
+			},
+			Started: func(delegates *moleculer.BrokerDelegates) {
+				result := <-delegates.BrokerContext().Call("db.connect", nil)
+				events = append(events, "started "+result.String())
+			},
+			Stopped: func(delegates *moleculer.BrokerDelegates) {
+				events = append(events, fmt.Sprint("stopped ", delegates.IsStarted()))
+			},
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "db",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "connect",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return "connected"
+					},
+				},
+			},
+		})
+		Expect(events).Should(Equal([]string{"created lifecycle-broker"}))
+
+		bkr.Start()
+		defer bkr.Stop()
+		Expect(events).Should(Equal([]string{"created lifecycle-broker", "started connected"}))
+
+		bkr.Stop()
+		Expect(events).Should(Equal([]string{"created lifecycle-broker", "started connected", "stopped false"}))
+	})
+
+	It("Should deliver the internal $broker, $node and $services events to local services", func() {
+		var mutex sync.Mutex
+		received := []string{}
+		handler := func(ctx moleculer.Context, params moleculer.Payload) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			name := ctx.(moleculer.BrokerContext).EventName()
+			if params.IsMap() {
+				received = append(received, fmt.Sprint(name, " localService: ", params.Get("localService").Bool()))
+			} else {
+				received = append(received, fmt.Sprint(name, " ", params.Value()))
+			}
+		}
+		receivedEvents := func() []string {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return append([]string{}, received...)
+		}
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://internal-events-test",
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "internal-events-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "watcher",
+			Events: []moleculer.Event{
+				moleculer.Event{Name: "$broker.started", Handler: handler},
+				moleculer.Event{Name: "$broker.stopped", Handler: handler},
+				moleculer.Event{Name: "$node.connected", Handler: handler},
+				moleculer.Event{Name: "$node.disconnected", Handler: handler},
+				moleculer.Event{Name: "$services.changed", Handler: handler},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+		Eventually(receivedEvents).Should(ContainElement("$broker.started <nil>"))
+		Eventually(receivedEvents).Should(ContainElement("$services.changed localService: true"))
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "internal-events-broker2" },
+		})
+		bkr2.Publish(moleculer.ServiceSchema{Name: "other"})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr1.WaitFor("other")).Should(Succeed())
+		Eventually(receivedEvents).Should(ContainElement("$node.connected internal-events-broker2"))
+		Eventually(receivedEvents).Should(ContainElement("$services.changed localService: false"))
+
+		bkr2.Stop()
+		Eventually(receivedEvents).Should(ContainElement("$node.disconnected internal-events-broker2"))
+
+		bkr1.Stop()
+		Eventually(receivedEvents).Should(ContainElement("$broker.stopped <nil>"))
+	})
+
+	It("Should confirm the delivery of events to remote nodes", func() {
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://confirmed-events-test",
+		}
+		received := make(chan string, 1)
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "confirm-broker1" },
+		})
+		bkr1.Publish(moleculer.ServiceSchema{
+			Name: "users",
+			Events: []moleculer.Event{
+				moleculer.Event{
+					Name: "user.created",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+						received <- params.String()
+					},
+				},
+			},
+		})
+		bkr1.Start()
+		defer bkr1.Stop()
+
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "confirm-broker2" },
+		})
+		bkr2.Start()
+		defer bkr2.Stop()
+		Expect(bkr2.WaitFor("users")).Should(Succeed())
+
+		Expect(<-bkr2.EmitConfirmed("user.created", "John")).Should(Succeed())
+		Eventually(received).Should(Receive(Equal("John")))
+
+		Expect(<-bkr2.EmitConfirmed("user.deleted", "John")).ShouldNot(Succeed())
+	})
+
+})
+
+type reserveParams struct {
+	SKU      string `json:"sku" validate:"min:1"`
+	Quantity int    `json:"quantity" validate:"min:1"`
+}
+
+type inventoryService struct {
+	reserved chan string
+}
+
+func (s *inventoryService) Name() string {
+	return "inventory"
+}
+
+func (s *inventoryService) Reserve(ctx moleculer.Context, params reserveParams) (string, error) {
+	return fmt.Sprintf("reserved %d of %s", params.Quantity, params.SKU), nil
+}
+
+func (s *inventoryService) OrderCreatedEvent(ctx moleculer.Context, params reserveParams) {
+	s.reserved <- params.SKU
+}
+
+type insufficientFundsError struct {
+	cause *moleculer.Error
+}
+
+func (e *insufficientFundsError) Error() string {
+	return e.cause.Message
+}
+
+func (e *insufficientFundsError) MoleculerError() *moleculer.Error {
+	return e.cause
+}
+
+// validatorFunc adapts a function to the moleculer.Validator interface, the same way
+// an adapter for go-playground/validator or any other engine would be plugged.
+type validatorFunc func(schema moleculer.ActionSchema, params moleculer.Payload) error
+
+func (validate validatorFunc) Validate(schema moleculer.ActionSchema, params moleculer.Payload) error {
+	return validate(schema, params)
+}
+
+type logRecorder struct {
+	mutex   sync.Mutex
+	entries []*log.Entry
+}
+
+func (r *logRecorder) Log(level log.Level, message string, fields map[string]interface{}) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.entries = append(r.entries, &log.Entry{Level: level, Message: message, Data: fields})
+}
+
+func (r *logRecorder) messages(level log.Level) []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	result := []string{}
+	for _, entry := range r.entries {
+		if entry.Level == level {
+			result = append(result, entry.Message)
+		}
+	}
+	return result
+}
+
+func (r *logRecorder) entriesWith(message string) []*log.Entry {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	result := []*log.Entry{}
+	for _, entry := range r.entries {
+		if entry.Message == message {
+			result = append(result, entry)
+		}
+	}
+	return result
+}
+
+func (r *logRecorder) fields(name string) []interface{} {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	result := []interface{}{}
+	for _, entry := range r.entries {
+		result = append(result, entry.Data[name])
+	}
+	return result
+}
+
+// peerDiscoverer is a discoverer that reports the peers when the test calls found and lost.
+type peerDiscoverer struct {
+	mutex     sync.Mutex
+	onFound   func(peer discovery.Peer)
+	onLost    func(peer discovery.Peer)
+	isStarted bool
+}
+
+func (discoverer *peerDiscoverer) Start(found func(peer discovery.Peer), lost func(peer discovery.Peer)) error {
+	discoverer.mutex.Lock()
+	defer discoverer.mutex.Unlock()
+	discoverer.onFound, discoverer.onLost, discoverer.isStarted = found, lost, true
+	return nil
+}
+
+func (discoverer *peerDiscoverer) Stop() {
+	discoverer.mutex.Lock()
+	defer discoverer.mutex.Unlock()
+	discoverer.isStarted = false
+}
+
+func (discoverer *peerDiscoverer) started() bool {
+	discoverer.mutex.Lock()
+	defer discoverer.mutex.Unlock()
+	return discoverer.isStarted
+}
+
+func (discoverer *peerDiscoverer) found(peer discovery.Peer) {
+	discoverer.mutex.Lock()
+	found := discoverer.onFound
+	discoverer.mutex.Unlock()
+	found(peer)
+}
+
+func (discoverer *peerDiscoverer) lost(peer discovery.Peer) {
+	discoverer.mutex.Lock()
+	lost := discoverer.onLost
+	discoverer.mutex.Unlock()
+	lost(peer)
+}
@@ -0,0 +1,146 @@
+package broker
+
+import (
+	"github.com/moleculer-go/moleculer"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix is the prefix of the environment variables read by FromEnv and LoadConfig.
+// The variable name is the prefix followed by the config field in upper case, example: MOL_LOGLEVEL.
+const EnvPrefix = "MOL"
+
+// FromEnv return a config with the fields set in the environment variables:
+// MOL_NODEID, MOL_NAMESPACE, MOL_TRANSPORTER, MOL_SERIALIZER, MOL_LOGLEVEL, MOL_LOGFORMAT,
+// MOL_REQUESTTIMEOUT (durations like 5s), MOL_METRICS, MOL_ACCESSLOG, etc.
+// Fields without a variable keep their zero values, so the config can be layered with broker.New(base, FromEnv(), code).
+func FromEnv() *moleculer.Config {
+	return configFrom(envViper())
+}
+
+// FromFile return a config with the fields set in the config file. The format is
+// detected by the file extension: .yaml, .yml, .json or .toml.
+func FromFile(path string) (*moleculer.Config, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return configFrom(v), nil
+}
+
+// LoadConfig return the config from the file, with the values of the environment variables
+// taking precedence. When path is empty only the environment variables are read.
+func LoadConfig(path string) (*moleculer.Config, error) {
+	v := envViper()
+	if path != "" {
+		v.SetConfigFile(path)
+		if err := v.ReadInConfig(); err != nil {
+			return nil, err
+		}
+	}
+	return configFrom(v), nil
+}
+
+func envViper() *viper.Viper {
+	v := viper.New()
+	v.SetEnvPrefix(EnvPrefix)
+	v.AutomaticEnv()
+	return v
+}
+
+// configFrom create a config with the fields set in viper.
+func configFrom(v *viper.Viper) *moleculer.Config {
+	config := &moleculer.Config{}
+	if v.IsSet("nodeID") {
+		config.NodeID = v.GetString("nodeID")
+	}
+	if v.IsSet("namespace") {
+		config.Namespace = v.GetString("namespace")
+	}
+	if v.IsSet("transporter") {
+		config.Transporter = v.GetString("transporter")
+	}
+	if v.IsSet("strategy") {
+		config.Strategy = v.GetString("strategy")
+	}
+	if v.IsSet("preferLocal") {
+		preferLocal := v.GetBool("preferLocal")
+		config.PreferLocal = &preferLocal
+	}
+	if v.IsSet("discoverer") {
+		config.Discoverer = v.GetString("discoverer")
+	}
+	if v.IsSet("cacher") {
+		config.Cacher = v.GetString("cacher")
+	}
+	if v.IsSet("serializer") {
+		config.Serializer = v.GetString("serializer")
+	}
+	if v.IsSet("logLevel") {
+		config.LogLevel = v.GetString("logLevel")
+	}
+	if v.IsSet("logFormat") {
+		config.LogFormat = v.GetString("logFormat")
+	}
+	if v.IsSet("topicPrefix") {
+		config.TopicPrefix = v.GetString("topicPrefix")
+	}
+	if v.IsSet("compression") {
+		config.Compression = v.GetString("compression")
+	}
+	if v.IsSet("compressionThreshold") {
+		config.CompressionThreshold = v.GetInt("compressionThreshold")
+	}
+	if v.IsSet("maxPacketSize") {
+		config.MaxPacketSize = v.GetInt("maxPacketSize")
+	}
+	if v.IsSet("maxQueueSize") {
+		config.MaxQueueSize = v.GetInt("maxQueueSize")
+	}
+	if v.IsSet("maxCallLevel") {
+		config.MaxCallLevel = v.GetInt("maxCallLevel")
+	}
+	if v.IsSet("requestTimeout") {
+		config.RequestTimeout = v.GetDuration("requestTimeout")
+	}
+	if v.IsSet("slowStartWindow") {
+		config.SlowStartWindow = v.GetDuration("slowStartWindow")
+	}
+	if v.IsSet("heartbeatFrequency") {
+		config.HeartbeatFrequency = v.GetDuration("heartbeatFrequency")
+	}
+	if v.IsSet("heartbeatTimeout") {
+		config.HeartbeatTimeout = v.GetDuration("heartbeatTimeout")
+	}
+	if v.IsSet("offlineCheckFrequency") {
+		config.OfflineCheckFrequency = v.GetDuration("offlineCheckFrequency")
+	}
+	if v.IsSet("offlineTimeout") {
+		config.OfflineTimeout = v.GetDuration("offlineTimeout")
+	}
+	if v.IsSet("gracefulStopTimeout") {
+		config.GracefulStopTimeout = v.GetDuration("gracefulStopTimeout")
+	}
+	if v.IsSet("metrics") {
+		config.Metrics = v.GetBool("metrics")
+	}
+	if v.IsSet("accessLog") {
+		config.AccessLog = v.GetBool("accessLog")
+	}
+	if v.IsSet("disableInternalServices") {
+		config.DisableInternalServices = v.GetBool("disableInternalServices")
+	}
+	if v.IsSet("disableInternalMiddlewares") {
+		config.DisableInternalMiddlewares = v.GetBool("disableInternalMiddlewares")
+	}
+	if v.IsSet("preserveNumbers") {
+		config.PreserveNumbers = v.GetBool("preserveNumbers")
+	}
+	if v.IsSet("metadata") {
+		config.Metadata = v.GetStringMap("metadata")
+	}
+	if v.IsSet("services") {
+		config.Services = v.GetStringMap("services")
+	}
+	return config
+}
@@ -0,0 +1,120 @@
+package broker
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+type noopValidator struct{}
+
+func (noopValidator) Validate(schema moleculer.ActionSchema, params moleculer.Payload) error {
+	return nil
+}
+
+// Log implements moleculer.Logger, so noopValidator also fills the Logger field.
+func (noopValidator) Log(level log.Level, message string, fields map[string]interface{}) {}
+
+// fillFields set a non-zero value in every field, so new config fields are covered by the merge test.
+func fillFields(value reflect.Value) {
+	for index := 0; index < value.NumField(); index++ {
+		field := value.Field(index)
+		name := value.Type().Field(index).Name
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString("custom-" + name)
+		case reflect.Bool:
+			field.SetBool(true)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			field.SetInt(int64(index + 3))
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			field.SetUint(uint64(index + 3))
+		case reflect.Float32, reflect.Float64:
+			field.SetFloat(float64(index) + 0.5)
+		case reflect.Func:
+			field.Set(reflect.MakeFunc(field.Type(), func(args []reflect.Value) []reflect.Value {
+				results := make([]reflect.Value, field.Type().NumOut())
+				for i := range results {
+					results[i] = reflect.Zero(field.Type().Out(i))
+				}
+				return results
+			}))
+		case reflect.Map:
+			item := reflect.MakeMap(field.Type())
+			item.SetMapIndex(reflect.ValueOf("custom-"+name).Convert(field.Type().Key()), reflect.Zero(field.Type().Elem()))
+			field.Set(item)
+		case reflect.Slice:
+			field.Set(reflect.MakeSlice(field.Type(), 1, 1))
+		case reflect.Ptr:
+			field.Set(reflect.New(field.Type().Elem()))
+		case reflect.Struct:
+			fillFields(field)
+		case reflect.Interface:
+			validator := reflect.ValueOf(noopValidator{})
+			if !validator.Type().Implements(field.Type()) {
+				Fail(fmt.Sprint("No test value for the config field: ", name))
+			}
+			field.Set(validator)
+		default:
+			Fail(fmt.Sprint("No test value for the config field: ", name))
+		}
+	}
+}
+
+// expectSameFields check that every field of merged has the value of expected.
+func expectSameFields(merged, expected reflect.Value) {
+	for index := 0; index < expected.NumField(); index++ {
+		name := expected.Type().Field(index).Name
+		mergedField := merged.Field(index)
+		expectedField := expected.Field(index)
+		switch expectedField.Kind() {
+		case reflect.Struct:
+			expectSameFields(mergedField, expectedField)
+		case reflect.Func:
+			Expect(mergedField.Pointer()).Should(Equal(expectedField.Pointer()), "config field not merged: "+name)
+		default:
+			Expect(mergedField.Interface()).Should(Equal(expectedField.Interface()), "config field not merged: "+name)
+		}
+	}
+}
+
+var _ = Describe("mergeConfigs", func() {
+
+	It("should merge every config field", func() {
+		custom := moleculer.Config{}
+		fillFields(reflect.ValueOf(&custom).Elem())
+
+		merged := mergeConfigs(moleculer.DefaultConfig, []*moleculer.Config{&custom})
+		expectSameFields(reflect.ValueOf(merged), reflect.ValueOf(custom))
+	})
+
+	It("should keep the base values for zero fields and apply the configs in order", func() {
+		merged := mergeConfigs(moleculer.DefaultConfig, []*moleculer.Config{
+			&moleculer.Config{
+				Namespace:   "first",
+				LogLevel:    "warn",
+				RetryPolicy: moleculer.RetryPolicy{Enabled: true, Retries: 5},
+				Services:    map[string]interface{}{"users": "first"},
+			},
+			nil,
+			&moleculer.Config{
+				Namespace:      "second",
+				RequestTimeout: 10 * time.Second,
+				RetryPolicy:    moleculer.RetryPolicy{Delay: 100},
+				Services:       map[string]interface{}{"emails": "second"},
+			},
+		})
+		Expect(merged.Namespace).Should(Equal("second"))
+		Expect(merged.LogLevel).Should(Equal("warn"))
+		Expect(merged.RequestTimeout).Should(Equal(10 * time.Second))
+		Expect(merged.MCallTimeout).Should(Equal(moleculer.DefaultConfig.MCallTimeout))
+		Expect(merged.RetryPolicy).Should(Equal(moleculer.RetryPolicy{Enabled: true, Retries: 5, Delay: 100, MaxDelay: 1000, Factor: 2}))
+		Expect(merged.ReconnectPolicy).Should(Equal(moleculer.DefaultConfig.ReconnectPolicy))
+		Expect(merged.Services).Should(Equal(map[string]interface{}{"users": "first", "emails": "second"}))
+	})
+})
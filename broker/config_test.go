@@ -0,0 +1,113 @@
+package broker_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/broker"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Broker config loading", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "moleculer-config")
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+		os.Unsetenv("MOL_TRANSPORTER")
+		os.Unsetenv("MOL_LOGLEVEL")
+		os.Unsetenv("MOL_NAMESPACE")
+		os.Unsetenv("MOL_REQUESTTIMEOUT")
+		os.Unsetenv("MOL_METRICS")
+		os.Unsetenv("MOL_ACCESSLOG")
+		os.Unsetenv("MOL_NODEID")
+		os.Unsetenv("MOL_STRATEGY")
+		os.Unsetenv("MOL_PREFERLOCAL")
+		os.Unsetenv("MOL_DISCOVERER")
+		os.Unsetenv("MOL_SLOWSTARTWINDOW")
+		os.Unsetenv("MOL_CACHER")
+	})
+
+	writeFile := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		Expect(ioutil.WriteFile(path, []byte(content), 0644)).Should(Succeed())
+		return path
+	}
+
+	It("FromEnv should read the MOL_ variables", func() {
+		os.Setenv("MOL_TRANSPORTER", "nats://localhost:4222")
+		os.Setenv("MOL_LOGLEVEL", "warn")
+		os.Setenv("MOL_NAMESPACE", "staging")
+		os.Setenv("MOL_REQUESTTIMEOUT", "5s")
+		os.Setenv("MOL_METRICS", "true")
+		os.Setenv("MOL_ACCESSLOG", "true")
+		os.Setenv("MOL_NODEID", "node-1")
+		os.Setenv("MOL_STRATEGY", "RoundRobin")
+		os.Setenv("MOL_PREFERLOCAL", "false")
+		os.Setenv("MOL_DISCOVERER", "kubernetes://?labelSelector=app%3Dusers")
+		os.Setenv("MOL_SLOWSTARTWINDOW", "30s")
+		os.Setenv("MOL_CACHER", "memory://?ttl=30s")
+
+		config := broker.FromEnv()
+		Expect(config.Transporter).Should(Equal("nats://localhost:4222"))
+		Expect(config.LogLevel).Should(Equal("warn"))
+		Expect(config.Namespace).Should(Equal("staging"))
+		Expect(config.RequestTimeout).Should(Equal(5 * time.Second))
+		Expect(config.Metrics).Should(BeTrue())
+		Expect(config.AccessLog).Should(BeTrue())
+		Expect(config.NodeID).Should(Equal("node-1"))
+		Expect(config.Strategy).Should(Equal("RoundRobin"))
+		Expect(*config.PreferLocal).Should(BeFalse())
+		Expect(config.Discoverer).Should(Equal("kubernetes://?labelSelector=app%3Dusers"))
+		Expect(config.SlowStartWindow).Should(Equal(30 * time.Second))
+		Expect(config.Cacher).Should(Equal("memory://?ttl=30s"))
+		Expect(config.Serializer).Should(Equal(""))
+		Expect(config.MaxCallLevel).Should(Equal(0))
+	})
+
+	It("FromFile should read YAML, JSON and TOML files", func() {
+		files := []string{
+			writeFile("config.yaml", "transporter: memory://\nnamespace: yaml\nheartbeatFrequency: 2s\nservices:\n  user:\n    table: users\nmetadata:\n  zone: eu-1\n"),
+			writeFile("config.json", `{"transporter": "memory://", "namespace": "json", "heartbeatFrequency": "2s", "services": {"user": {"table": "users"}}, "metadata": {"zone": "eu-1"}}`),
+			writeFile("config.toml", "transporter = \"memory://\"\nnamespace = \"toml\"\nheartbeatFrequency = \"2s\"\n[services.user]\ntable = \"users\"\n[metadata]\nzone = \"eu-1\"\n"),
+		}
+		for index, namespace := range []string{"yaml", "json", "toml"} {
+			config, err := broker.FromFile(files[index])
+			Expect(err).Should(BeNil())
+			Expect(config.Transporter).Should(Equal("memory://"))
+			Expect(config.Namespace).Should(Equal(namespace))
+			Expect(config.HeartbeatFrequency).Should(Equal(2 * time.Second))
+			Expect(config.Services["user"]).Should(HaveKeyWithValue("table", "users"))
+			Expect(config.Metadata).Should(HaveKeyWithValue("zone", "eu-1"))
+		}
+
+		_, err := broker.FromFile(filepath.Join(dir, "missing.yaml"))
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("LoadConfig should give precedence to the environment variables over the file", func() {
+		path := writeFile("config.yaml", "transporter: memory://file\nlogLevel: info\n")
+		os.Setenv("MOL_LOGLEVEL", "error")
+
+		config, err := broker.LoadConfig(path)
+		Expect(err).Should(BeNil())
+		Expect(config.Transporter).Should(Equal("memory://file"))
+		Expect(config.LogLevel).Should(Equal("error"))
+
+		bkr := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "loaded-config-broker" },
+		})
+		bkr.Start()
+		Expect(bkr.LocalNode().GetID()).Should(Equal("loaded-config-broker"))
+		bkr.Stop()
+	})
+})
@@ -0,0 +1,26 @@
+package broker
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Run start the broker and block until the process receives SIGINT or SIGTERM,
+// then stop the broker gracefully. It is meant to be the last call of main().
+func (broker *ServiceBroker) Run() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	broker.runUntil(signals)
+}
+
+// runUntil start the broker, wait for a signal and stop the broker.
+func (broker *ServiceBroker) runUntil(signals <-chan os.Signal) {
+	if err := broker.Start(); err != nil {
+		return
+	}
+	received := <-signals
+	broker.logger.Info("Received signal: ", received, " - stopping the broker...")
+	broker.Stop()
+}
@@ -0,0 +1,40 @@
+package broker
+
+import (
+	"os"
+	"syscall"
+
+	"github.com/moleculer-go/moleculer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Run", func() {
+
+	It("should start the broker and stop it when a signal is received", func() {
+		stopped := make(chan bool, 1)
+		bkr := New(&moleculer.Config{
+			LogLevel:       "error",
+			DiscoverNodeID: func() string { return "run-broker" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "worker",
+			Stopped: func(ctx moleculer.BrokerContext, svc moleculer.ServiceSchema) {
+				stopped <- true
+			},
+		})
+		signals := make(chan os.Signal, 1)
+		done := make(chan bool)
+		go func() {
+			bkr.runUntil(signals)
+			close(done)
+		}()
+		Eventually(bkr.IsStarted).Should(BeTrue())
+		Consistently(done).ShouldNot(BeClosed())
+
+		signals <- syscall.SIGTERM
+		Eventually(done).Should(BeClosed())
+		Expect(stopped).Should(Receive(BeTrue()))
+		Expect(bkr.IsStarted()).Should(BeFalse())
+	})
+})
@@ -1,4 +1,108 @@
 package cache
 
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	log "github.com/sirupsen/logrus"
+)
+
+// Cache stores the results of the actions by key, so they are returned without calling the action again.
 type Cache interface {
+	// Get return the value of the key, false when the key is not cached or expired.
+	Get(key string) (moleculer.Payload, bool)
+	// Set store the value of the key for the ttl. When the ttl is zero the default TTL of the cache is used.
+	Set(key string, value moleculer.Payload, ttl time.Duration)
+	Delete(keys ...string)
+	// Clean remove the keys that match the pattern, e.g. "users.*", where * matches any characters.
+	// All the keys are removed when the pattern is empty.
+	Clean(pattern string)
+	// Close stop the background work of the cache, e.g. the cleanup of the expired keys.
+	Close()
+}
+
+// patternMatcher return a function that checks if a key matches the pattern, where * matches any characters.
+func patternMatcher(pattern string) func(key string) bool {
+	if pattern == "" || pattern == "*" {
+		return func(key string) bool { return true }
+	}
+	if !strings.Contains(pattern, "*") {
+		return func(key string) bool { return key == pattern }
+	}
+	expression := regexp.MustCompile("^" + strings.Replace(regexp.QuoteMeta(pattern), `\*`, ".*", -1) + "$")
+	return expression.MatchString
+}
+
+// Create return the cache of the config, e.g. "memory" or "memory://?ttl=30s&maxItems=1000&clone=true".
+// The second value is false when the config is not a known cache.
+func Create(config string, logger *log.Entry) (Cache, bool) {
+	parsed, err := url.Parse(config)
+	if err != nil {
+		return nil, false
+	}
+	name := parsed.Scheme
+	if name == "" {
+		name = parsed.Path
+	}
+	switch strings.ToLower(name) {
+	case "memory":
+		return Memory(memoryOptionsFrom(parsed, logger)), true
+	}
+	return nil, false
+}
+
+// memoryOptionsFrom read the ttl, maxItems, cleanupInterval and clone options from the query params of the url.
+func memoryOptionsFrom(config *url.URL, logger *log.Entry) MemoryOptions {
+	query := config.Query()
+	options := MemoryOptions{Logger: logger}
+	options.TTL, _ = time.ParseDuration(query.Get("ttl"))
+	options.MaxItems, _ = strconv.Atoi(query.Get("maxItems"))
+	options.CleanupInterval, _ = time.ParseDuration(query.Get("cleanupInterval"))
+	options.Clone, _ = strconv.ParseBool(query.Get("clone"))
+	return options
+}
+
+// Key return the cache key of a call: the action name followed by the values of the keys, separated
+// by |, or all the params when there are no keys. The keys starting with # are read from the meta.
+func Key(action string, keys []string, params moleculer.Payload, meta moleculer.Payload) string {
+	if len(keys) == 0 {
+		return action + ":" + keyValue(params)
+	}
+	values := make([]string, len(keys))
+	for index, key := range keys {
+		if strings.HasPrefix(key, "#") {
+			values[index] = keyValue(field(meta, key[1:]))
+		} else {
+			values[index] = keyValue(field(params, key))
+		}
+	}
+	return action + ":" + strings.Join(values, "|")
+}
+
+func field(source moleculer.Payload, name string) moleculer.Payload {
+	if source == nil || !source.Exists() || !source.IsMap() {
+		return nil
+	}
+	return source.Get(name)
+}
+
+// keyValue return the value in a cache key: the strings as they are and the other values as JSON.
+func keyValue(value moleculer.Payload) string {
+	if value == nil || !value.Exists() {
+		return "<nil>"
+	}
+	if text, isString := value.Value().(string); isString {
+		return text
+	}
+	encoded, err := json.Marshal(value.Value())
+	if err != nil {
+		return fmt.Sprint(value.Value())
+	}
+	return string(encoded)
 }
@@ -0,0 +1,40 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cache", func() {
+
+	It("should create the cache of the config", func() {
+		cache, exists := Create("memory://?ttl=30s&maxItems=100&clone=true", nil)
+		Expect(exists).Should(BeTrue())
+		memory := cache.(*MemoryCache)
+		defer memory.Close()
+		Expect(memory.options.TTL).Should(Equal(30 * time.Second))
+		Expect(memory.options.MaxItems).Should(Equal(100))
+		Expect(memory.options.Clone).Should(BeTrue())
+
+		cache, exists = Create("Memory", nil)
+		Expect(exists).Should(BeTrue())
+		cache.Close()
+		_, exists = Create("redis://localhost:6379", nil)
+		Expect(exists).Should(BeFalse())
+	})
+
+	It("should build the keys from the selected params and meta fields", func() {
+		params := payload.New(map[string]interface{}{"id": 10, "name": "John", "filter": map[string]interface{}{"active": true}})
+		meta := payload.New(map[string]interface{}{"userID": "user-1"})
+
+		Expect(Key("users.get", []string{"id"}, params, meta)).Should(Equal("users.get:10"))
+		Expect(Key("users.get", []string{"name", "#userID"}, params, meta)).Should(Equal("users.get:John|user-1"))
+		Expect(Key("users.get", []string{"filter", "missing"}, params, meta)).Should(Equal(`users.get:{"active":true}|<nil>`))
+		Expect(Key("users.get", nil, params, meta)).Should(Equal(`users.get:{"filter":{"active":true},"id":10,"name":"John"}`))
+		Expect(Key("users.count", nil, payload.New(nil), meta)).Should(Equal("users.count:<nil>"))
+		Expect(Key("users.get", []string{"#userID"}, params, payload.Empty())).Should(Equal("users.get:<nil>"))
+	})
+})
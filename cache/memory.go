@@ -0,0 +1,189 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	log "github.com/sirupsen/logrus"
+)
+
+// MemoryOptions configure the memory cache.
+type MemoryOptions struct {
+	// TTL is the default time to live of the keys. The keys do not expire when zero.
+	TTL time.Duration
+	// MaxItems is the maximum number of keys. When a key is added to a full cache the least recently
+	// used key is removed. There is no limit when zero.
+	MaxItems int
+	// CleanupInterval is the interval of the removal of the expired keys, 30 seconds when zero.
+	// The expired keys are also removed when they are read.
+	CleanupInterval time.Duration
+	// Clone return a copy of the cached value on each Get, so the callers can change the values
+	// they receive without changing the cache.
+	Clone  bool
+	Logger *log.Entry
+}
+
+// memoryEntry is a cached value in the LRU list.
+type memoryEntry struct {
+	key     string
+	value   moleculer.Payload
+	expires time.Time
+}
+
+func (entry *memoryEntry) expired(now time.Time) bool {
+	return !entry.expires.IsZero() && now.After(entry.expires)
+}
+
+// MemoryCache keep the values in memory, in a list ordered by the last use for the LRU eviction.
+type MemoryCache struct {
+	options MemoryOptions
+	entries map[string]*list.Element
+	lru     *list.List
+	mutex   sync.Mutex
+	done    chan struct{}
+	closed  sync.Once
+}
+
+// Memory return a cache that keeps the values in memory. The expired keys are removed in the
+// background until Close is called.
+func Memory(options MemoryOptions) *MemoryCache {
+	if options.CleanupInterval <= 0 {
+		options.CleanupInterval = 30 * time.Second
+	}
+	if options.Logger == nil {
+		options.Logger = log.WithField("cache", "memory")
+	}
+	cache := &MemoryCache{
+		options: options,
+		entries: map[string]*list.Element{},
+		lru:     list.New(),
+		done:    make(chan struct{}),
+	}
+	go cache.cleanupLoop()
+	return cache
+}
+
+// Get return the value of the key and mark it as the most recently used.
+func (cache *MemoryCache) Get(key string) (moleculer.Payload, bool) {
+	cache.mutex.Lock()
+	element, exists := cache.entries[key]
+	if !exists {
+		cache.mutex.Unlock()
+		return nil, false
+	}
+	entry := element.Value.(*memoryEntry)
+	if entry.expired(time.Now()) {
+		cache.removeElement(element)
+		cache.mutex.Unlock()
+		return nil, false
+	}
+	cache.lru.MoveToFront(element)
+	value := entry.value
+	cache.mutex.Unlock()
+	if cache.options.Clone {
+		value = value.Clone()
+	}
+	return value, true
+}
+
+// Set store the value of the key, removing the least recently used keys when the cache is full.
+func (cache *MemoryCache) Set(key string, value moleculer.Payload, ttl time.Duration) {
+	if ttl == 0 {
+		ttl = cache.options.TTL
+	}
+	if cache.options.Clone {
+		value = value.Clone()
+	}
+	entry := &memoryEntry{key: key, value: value}
+	if ttl > 0 {
+		entry.expires = time.Now().Add(ttl)
+	}
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if element, exists := cache.entries[key]; exists {
+		element.Value = entry
+		cache.lru.MoveToFront(element)
+		return
+	}
+	cache.entries[key] = cache.lru.PushFront(entry)
+	for cache.options.MaxItems > 0 && cache.lru.Len() > cache.options.MaxItems {
+		oldest := cache.lru.Back()
+		cache.options.Logger.Trace("Memory cache - evicting the least recently used key: ", oldest.Value.(*memoryEntry).key)
+		cache.removeElement(oldest)
+	}
+}
+
+// Delete remove the keys.
+func (cache *MemoryCache) Delete(keys ...string) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for _, key := range keys {
+		if element, exists := cache.entries[key]; exists {
+			cache.removeElement(element)
+		}
+	}
+}
+
+// Clean remove the keys that match the pattern, all the keys when the pattern is empty.
+func (cache *MemoryCache) Clean(pattern string) {
+	match := patternMatcher(pattern)
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	for key, element := range cache.entries {
+		if match(key) {
+			cache.removeElement(element)
+		}
+	}
+}
+
+// Len return the number of keys in the cache, including the expired keys not removed yet.
+func (cache *MemoryCache) Len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.lru.Len()
+}
+
+// Close stop the cleanup of the expired keys.
+func (cache *MemoryCache) Close() {
+	cache.closed.Do(func() {
+		close(cache.done)
+	})
+}
+
+// removeElement remove the entry from the list and the map. The mutex must be locked.
+func (cache *MemoryCache) removeElement(element *list.Element) {
+	cache.lru.Remove(element)
+	delete(cache.entries, element.Value.(*memoryEntry).key)
+}
+
+// removeExpired remove all the expired keys.
+func (cache *MemoryCache) removeExpired() {
+	now := time.Now()
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	removed := 0
+	for _, element := range cache.entries {
+		if element.Value.(*memoryEntry).expired(now) {
+			cache.removeElement(element)
+			removed++
+		}
+	}
+	if removed > 0 {
+		cache.options.Logger.Debug("Memory cache - removed ", removed, " expired keys")
+	}
+}
+
+func (cache *MemoryCache) cleanupLoop() {
+	ticker := time.NewTicker(cache.options.CleanupInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-cache.done:
+			return
+		case <-ticker.C:
+			cache.removeExpired()
+		}
+	}
+}
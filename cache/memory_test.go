@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"time"
+
+	"github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memory cache", func() {
+
+	It("should get the values set and remove the deleted and cleaned keys", func() {
+		cache := Memory(MemoryOptions{})
+		defer cache.Close()
+		cache.Set("users.get:1", payload.New("John"), 0)
+		cache.Set("users.get:2", payload.New("Mary"), 0)
+		cache.Set("posts.find:1", payload.New("post"), 0)
+
+		value, exists := cache.Get("users.get:1")
+		Expect(exists).Should(BeTrue())
+		Expect(value.String()).Should(Equal("John"))
+		_, exists = cache.Get("users.get:3")
+		Expect(exists).Should(BeFalse())
+
+		cache.Delete("users.get:1")
+		_, exists = cache.Get("users.get:1")
+		Expect(exists).Should(BeFalse())
+
+		cache.Clean("users.*")
+		Expect(cache.Len()).Should(Equal(1))
+		cache.Clean("")
+		Expect(cache.Len()).Should(BeZero())
+	})
+
+	It("should expire the keys after their ttl or the default ttl", func() {
+		cache := Memory(MemoryOptions{TTL: 20 * time.Millisecond})
+		defer cache.Close()
+		cache.Set("short", payload.New(1), 0)
+		cache.Set("long", payload.New(2), time.Minute)
+		time.Sleep(40 * time.Millisecond)
+
+		_, exists := cache.Get("short")
+		Expect(exists).Should(BeFalse())
+		value, exists := cache.Get("long")
+		Expect(exists).Should(BeTrue())
+		Expect(value.Int()).Should(Equal(2))
+	})
+
+	It("should remove the expired keys in the background until closed", func() {
+		cache := Memory(MemoryOptions{TTL: 10 * time.Millisecond, CleanupInterval: 10 * time.Millisecond})
+		cache.Set("a", payload.New(1), 0)
+		cache.Set("b", payload.New(2), 0)
+		Eventually(cache.Len).Should(BeZero())
+
+		cache.Close()
+		cache.Set("c", payload.New(3), 0)
+		Consistently(cache.Len, 50*time.Millisecond).Should(Equal(1))
+		cache.Close()
+	})
+
+	It("should evict the least recently used keys when full", func() {
+		cache := Memory(MemoryOptions{MaxItems: 2})
+		defer cache.Close()
+		cache.Set("a", payload.New(1), 0)
+		cache.Set("b", payload.New(2), 0)
+		cache.Get("a")
+		cache.Set("c", payload.New(3), 0)
+
+		Expect(cache.Len()).Should(Equal(2))
+		_, exists := cache.Get("b")
+		Expect(exists).Should(BeFalse())
+		_, exists = cache.Get("a")
+		Expect(exists).Should(BeTrue())
+		_, exists = cache.Get("c")
+		Expect(exists).Should(BeTrue())
+
+		cache.Set("a", payload.New(10), 0)
+		Expect(cache.Len()).Should(Equal(2))
+		value, _ := cache.Get("a")
+		Expect(value.Int()).Should(Equal(10))
+	})
+
+	It("should return copies of the values with Clone", func() {
+		user := map[string]interface{}{"name": "John"}
+		cache := Memory(MemoryOptions{Clone: true})
+		defer cache.Close()
+		cache.Set("user", payload.New(user), 0)
+		user["name"] = "changed after set"
+
+		value, _ := cache.Get("user")
+		Expect(value.Get("name").String()).Should(Equal("John"))
+		value.RawMap()["name"] = "changed after get"
+		value, _ = cache.Get("user")
+		Expect(value.Get("name").String()).Should(Equal("John"))
+
+		shared := Memory(MemoryOptions{})
+		defer shared.Close()
+		shared.Set("user", payload.New(user), 0)
+		value, _ = shared.Get("user")
+		value.RawMap()["name"] = "shared"
+		value, _ = shared.Get("user")
+		Expect(value.Get("name").String()).Should(Equal("shared"))
+	})
+
+	It("should match the keys with the patterns", func() {
+		Expect(patternMatcher("users.*")("users.get:1")).Should(BeTrue())
+		Expect(patternMatcher("users.*")("posts.get:1")).Should(BeFalse())
+		Expect(patternMatcher("*.get:1")("users.get:1")).Should(BeTrue())
+		Expect(patternMatcher("users.get:1")("users.get:1")).Should(BeTrue())
+		Expect(patternMatcher("users.get")("users.get:1")).Should(BeFalse())
+		Expect(patternMatcher("")("anything")).Should(BeTrue())
+	})
+})
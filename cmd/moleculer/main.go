@@ -0,0 +1,17 @@
+// Command moleculer starts a service broker with the services loaded from Go plugins.
+//
+//	go build -buildmode=plugin -o services/users.so ./users
+//	moleculer --config moleculer.config.yaml --repl services/
+package main
+
+import (
+	"os"
+
+	"github.com/moleculer-go/moleculer/runner"
+)
+
+func main() {
+	if err := runner.Command().Execute(); err != nil {
+		os.Exit(1)
+	}
+}
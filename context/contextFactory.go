@@ -1,8 +1,10 @@
 package context
 
 import (
+	gocontext "context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/payload"
@@ -26,6 +28,11 @@ type Context struct {
 	meta         moleculer.Payload
 	timeout      int
 	level        int
+	ctx          gocontext.Context
+	cancel       gocontext.CancelFunc
+	parent       *Context
+	options      []moleculer.Options
+	locals       map[string]interface{}
 }
 
 func BrokerContext(broker *moleculer.BrokerDelegates) moleculer.BrokerContext {
@@ -66,6 +73,7 @@ func (context *Context) ChildEventContext(eventName string, params moleculer.Pay
 		level:     parentContext.level + 1,
 		meta:      meta,
 		parentID:  parentContext.id,
+		ctx:       parentContext.ctx,
 	}
 	return &eventContext
 }
@@ -85,6 +93,16 @@ func (context *Context) ChildActionContext(actionName string, params moleculer.P
 	if len(opts) > 0 && opts[0].Meta != nil && opts[0].Meta.Len() > 0 {
 		meta = meta.AddMany(opts[0].Meta.RawMap())
 	}
+	timeout := 0
+	if len(opts) > 0 && opts[0].Timeout > 0 {
+		timeout = int(opts[0].Timeout / time.Millisecond)
+	}
+	parentGoContext := parentContext.goContext()
+	hasGoContext := len(opts) > 0 && opts[0].Context != nil
+	if hasGoContext {
+		parentGoContext = opts[0].Context
+		timeout = deadlineTimeout(parentGoContext, timeout)
+	}
 	id := util.RandomString(12)
 	var requestID string
 	if parentContext.requestID != "" {
@@ -100,14 +118,51 @@ func (context *Context) ChildActionContext(actionName string, params moleculer.P
 		params:     params,
 		level:      parentContext.level + 1,
 		meta:       meta,
+		timeout:    timeout,
 		parentID:   parentContext.id,
+		ctx:        parentContext.ctx,
+		parent:     parentContext,
+		options:    opts,
+		locals:     make(map[string]interface{}),
+	}
+	// the child is cancelled with the parent (or the context informed in the call options),
+	// or when the call timeout expires.
+	if timeout > 0 {
+		actionContext.ctx, actionContext.cancel = gocontext.WithTimeout(parentGoContext, time.Duration(timeout)*time.Millisecond)
+	} else if hasGoContext {
+		actionContext.ctx, actionContext.cancel = gocontext.WithCancel(parentGoContext)
 	}
 	return &actionContext
 }
 
-// Max calling level check to avoid calling loops
-func checkMaxCalls(context *Context) {
+// Retry create a context to call the action again, with the same request ID and meta, a new ID
+// and a new timeout. Contexts received from remote nodes are returned as they are.
+func (context *Context) Retry() moleculer.BrokerContext {
+	if context.parent == nil {
+		return context
+	}
+	retry := context.parent.ChildActionContext(context.actionName, context.params, context.options...).(*Context)
+	retry.requestID = context.requestID
+	retry.meta = context.meta
+	retry.locals = context.locals
+	return retry
+}
 
+// deadlineTimeout return the time left until the deadline of the context in milliseconds,
+// when it is shorter than the timeout (or the timeout is not set).
+func deadlineTimeout(ctx gocontext.Context, timeout int) int {
+	deadline, hasDeadline := ctx.Deadline()
+	if !hasDeadline {
+		return timeout
+	}
+	left := int(time.Until(deadline) / time.Millisecond)
+	if left < 1 {
+		left = 1
+	}
+	if timeout == 0 || left < timeout {
+		return left
+	}
+	return timeout
 }
 
 // ActionContext create an action context for remote call.
@@ -141,17 +196,32 @@ func ActionContext(broker *moleculer.BrokerDelegates, values map[string]interfac
 		meta = payload.Empty()
 	}
 
+	// the request ID is kept across the nodes, so the logs of a request can be correlated.
+	requestID := id
+	if value, ok := values["requestID"].(string); ok && value != "" {
+		requestID = value
+	}
+
 	newContext := Context{
 		broker:       broker,
 		sourceNodeID: sourceNodeID,
 		targetNodeID: sourceNodeID,
 		id:           id,
+		requestID:    requestID,
 		actionName:   actionName.(string),
 		parentID:     parentID,
 		params:       params,
 		meta:         meta,
 		timeout:      timeout,
 		level:        level,
+		locals:       make(map[string]interface{}),
+	}
+	// cancelled by transit when the caller sends a CANCEL packet or the action ends,
+	// and by the timeout of the caller, so the action does not run after the caller gave up.
+	if timeout > 0 {
+		newContext.ctx, newContext.cancel = gocontext.WithTimeout(gocontext.Background(), time.Duration(timeout)*time.Millisecond)
+	} else {
+		newContext.ctx, newContext.cancel = gocontext.WithCancel(gocontext.Background())
 	}
 
 	return &newContext
@@ -179,10 +249,18 @@ func EventContext(broker *moleculer.BrokerDelegates, values map[string]interface
 		sourceNodeID: sourceNodeID,
 		id:           id,
 		eventName:    eventName.(string),
-		broadcast:    values["broadcast"].(bool),
 		params:       payload.New(values["data"]),
 		meta:         meta,
 	}
+	if broadcast, ok := values["broadcast"].(bool); ok {
+		newContext.broadcast = broadcast
+	}
+	if requestID, ok := values["requestID"].(string); ok {
+		newContext.requestID = requestID
+	}
+	if level, ok := values["level"]; ok {
+		newContext.level = payload.New(level).Int()
+	}
 	if values["groups"] != nil {
 		temp := values["groups"]
 		aTransformer := payload.ArrayTransformer(&temp)
@@ -206,6 +284,16 @@ func (context *Context) RequestID() string {
 	return context.requestID
 }
 
+// ParentID return the ID of the context that made the call.
+func (context *Context) ParentID() string {
+	return context.parentID
+}
+
+// Level return the depth of the context in the call chain.
+func (context *Context) Level() int {
+	return context.level
+}
+
 // AsMap : export context info in a map[string]
 func (context *Context) AsMap() map[string]interface{} {
 	mapResult := make(map[string]interface{})
@@ -247,9 +335,17 @@ func (context *Context) MCall(callMaps map[string]map[string]interface{}) chan m
 
 // Call : main entry point to call actions.
 // chained action invocation
+// The meta changed by the called action, local or remote, is merged back into this context,
+// unless this is the broker root context, shared by all calls.
 func (context *Context) Call(actionName string, params interface{}, opts ...moleculer.Options) chan moleculer.Payload {
 	actionContext := context.ChildActionContext(actionName, payload.New(params), opts...)
-	return context.broker.ActionDelegate(actionContext, opts...)
+	result := <-context.broker.ActionDelegate(actionContext, opts...)
+	if context.actionName != "" || context.eventName != "" {
+		context.meta = context.meta.AddMany(actionContext.Meta().RawMap())
+	}
+	resultChan := make(chan moleculer.Payload, 1)
+	resultChan <- result
+	return resultChan
 }
 
 // Emit : Emit an event (grouped & balanced global event)
@@ -265,6 +361,20 @@ func (context *Context) Broadcast(eventName string, params interface{}, groups .
 	context.broker.BroadcastEvent(newContext)
 }
 
+// EmitWithOptions : Emit an event to the groups and the node of the options
+func (context *Context) EmitWithOptions(eventName string, params interface{}, opts moleculer.EventOptions) {
+	newContext := context.ChildEventContext(eventName, payload.New(params), opts.Groups, false)
+	newContext.SetTargetNodeID(opts.NodeID)
+	context.broker.EmitEvent(newContext)
+}
+
+// BroadcastWithOptions : Broadcast an event to the groups and the node of the options
+func (context *Context) BroadcastWithOptions(eventName string, params interface{}, opts moleculer.EventOptions) {
+	newContext := context.ChildEventContext(eventName, payload.New(params), opts.Groups, true)
+	newContext.SetTargetNodeID(opts.NodeID)
+	context.broker.BroadcastEvent(newContext)
+}
+
 func (context *Context) WaitFor(services ...string) error {
 	return context.broker.WaitFor(services...)
 }
@@ -289,6 +399,11 @@ func (context *Context) Payload() moleculer.Payload {
 	return context.params
 }
 
+// SetGroups change the groups of an event context.
+func (context *Context) SetGroups(groups []string) {
+	context.groups = groups
+}
+
 func (context *Context) SetTargetNodeID(targetNodeID string) {
 	context.Logger().Debug("context factory SetTargetNodeID() targetNodeID: ", targetNodeID)
 	context.targetNodeID = targetNodeID
@@ -302,10 +417,24 @@ func (context *Context) SourceNodeID() string {
 	return context.sourceNodeID
 }
 
+// CallerAction return the name of the action that made the call, empty when the call was
+// not made by an action handler, e.g. by the broker or by an event handler.
+func (context *Context) CallerAction() string {
+	if context.parent == nil {
+		return ""
+	}
+	return context.parent.actionName
+}
+
 func (context *Context) ID() string {
 	return context.id
 }
 
+// Timeout return the request timeout informed in the call options, or zero when not informed.
+func (context *Context) Timeout() time.Duration {
+	return time.Duration(context.timeout) * time.Millisecond
+}
+
 func (context *Context) Meta() moleculer.Payload {
 	return context.meta
 }
@@ -314,6 +443,14 @@ func (context *Context) UpdateMeta(meta moleculer.Payload) {
 	context.meta = meta
 }
 
+// Locals return the values of this request in the local node. They are not sent to other nodes.
+func (context *Context) Locals() map[string]interface{} {
+	if context.locals == nil {
+		context.locals = make(map[string]interface{})
+	}
+	return context.locals
+}
+
 func (context *Context) Logger() *log.Entry {
 	if context.actionName != "" {
 		return context.broker.Logger("action", context.actionName)
@@ -323,3 +460,37 @@ func (context *Context) Logger() *log.Entry {
 	}
 	return context.broker.Logger("context", "<root>")
 }
+
+func (context *Context) goContext() gocontext.Context {
+	if context.ctx == nil {
+		return gocontext.Background()
+	}
+	return context.ctx
+}
+
+// Deadline return the time when the call times out, if a timeout was informed.
+func (context *Context) Deadline() (time.Time, bool) {
+	return context.goContext().Deadline()
+}
+
+// Done return a channel that is closed when the context is cancelled or times out.
+func (context *Context) Done() <-chan struct{} {
+	return context.goContext().Done()
+}
+
+// Err return the reason the context was cancelled, or nil while it is active.
+func (context *Context) Err() error {
+	return context.goContext().Err()
+}
+
+func (context *Context) Value(key interface{}) interface{} {
+	return context.goContext().Value(key)
+}
+
+// Cancel the context and its children. Contexts created without a timeout share
+// the cancellation of the parent, so Cancel has no effect on them.
+func (context *Context) Cancel() {
+	if context.cancel != nil {
+		context.cancel()
+	}
+}
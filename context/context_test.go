@@ -1,6 +1,9 @@
 package context
 
 import (
+	gocontext "context"
+	"time"
+
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/payload"
 	"github.com/moleculer-go/moleculer/test"
@@ -96,6 +99,26 @@ var _ = g.Describe("Context", func() {
 		}).Should(Panic())
 	})
 
+	g.It("Should create an event context from a packet without the broadcast field", func() {
+		delegates := test.DelegatesWithIdAndConfig("x", moleculer.Config{})
+		eventContext := EventContext(delegates, map[string]interface{}{
+			"sender":    "test",
+			"id":        "id",
+			"requestID": "request-id",
+			"level":     float64(2),
+			"event":     "event",
+			"data":      map[string]interface{}{},
+			"groups":    []interface{}{"a"},
+		})
+		Expect(eventContext.IsBroadcast()).Should(BeFalse())
+		Expect(eventContext.Groups()).Should(Equal([]string{"a"}))
+		Expect(eventContext.RequestID()).Should(Equal("request-id"))
+		Expect(eventContext.AsMap()["level"]).Should(Equal(2))
+
+		eventContext.SetGroups([]string{"b"})
+		Expect(eventContext.Groups()).Should(Equal([]string{"b"}))
+	})
+
 	g.It("Should create a child context with metrics on", func() {
 
 		config := moleculer.Config{
@@ -125,6 +148,63 @@ var _ = g.Describe("Context", func() {
 
 	})
 
+	g.It("Should create a child context with the timeout from the call options", func() {
+		brokerContext := BrokerContext(test.DelegatesWithIdAndConfig("nodex", moleculer.Config{}))
+
+		actionContext := brokerContext.ChildActionContext("actionx", payload.Empty(), moleculer.Options{Timeout: 3 * time.Second})
+		Expect(actionContext.Timeout()).Should(Equal(3 * time.Second))
+		Expect(actionContext.AsMap()["timeout"]).Should(Equal(3000))
+
+		actionContext = brokerContext.ChildActionContext("actionx", nil)
+		Expect(actionContext.Timeout()).Should(BeZero())
+	})
+
+	g.It("Should cancel the child contexts with the parent or when the timeout expires", func() {
+		delegates := test.DelegatesWithIdAndConfig("nodex", moleculer.Config{})
+		brokerContext := BrokerContext(delegates)
+		Expect(brokerContext.Done()).Should(BeNil())
+
+		timed := brokerContext.ChildActionContext("actionx", payload.Empty(), moleculer.Options{Timeout: 10 * time.Millisecond})
+		_, hasDeadline := timed.Deadline()
+		Expect(hasDeadline).Should(BeTrue())
+		Eventually(timed.Done()).Should(BeClosed())
+		Expect(timed.Err()).Should(Equal(gocontext.DeadlineExceeded))
+
+		remote := ActionContext(delegates, map[string]interface{}{
+			"sender": "test",
+			"id":     "id",
+			"action": "action",
+			"level":  2,
+		})
+		child := remote.ChildActionContext("actiony", payload.Empty())
+		event := remote.ChildEventContext("eventx", payload.Empty(), nil, false)
+		Expect(child.Err()).Should(BeNil())
+		remote.Cancel()
+		Expect(child.Done()).Should(BeClosed())
+		Expect(event.Done()).Should(BeClosed())
+		Expect(child.Err()).Should(Equal(gocontext.Canceled))
+	})
+
+	g.It("Should cancel the child context with the Go context from the call options", func() {
+		delegates := test.DelegatesWithIdAndConfig("nodex", moleculer.Config{})
+		brokerContext := BrokerContext(delegates)
+
+		ctx, cancel := gocontext.WithCancel(gocontext.Background())
+		child := brokerContext.ChildActionContext("actionx", payload.Empty(), moleculer.Options{Context: ctx})
+		Expect(child.Timeout()).Should(BeZero())
+		Expect(child.Err()).Should(BeNil())
+		cancel()
+		Expect(child.Done()).Should(BeClosed())
+		Expect(child.Err()).Should(Equal(gocontext.Canceled))
+
+		ctx, cancel = gocontext.WithTimeout(gocontext.Background(), 2*time.Second)
+		defer cancel()
+		child = brokerContext.ChildActionContext("actionx", payload.Empty(), moleculer.Options{Context: ctx})
+		Expect(child.Timeout()).Should(BeNumerically("~", 2*time.Second, 100*time.Millisecond))
+		child = brokerContext.ChildActionContext("actionx", payload.Empty(), moleculer.Options{Context: ctx, Timeout: time.Second})
+		Expect(child.Timeout()).Should(Equal(time.Second))
+	})
+
 	g.It("Should call MCall and delegate it to broker", func() {
 		delegates := test.DelegatesWithIdAndConfig("x", moleculer.Config{})
 		called := false
@@ -0,0 +1,198 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/service"
+)
+
+// Job is a scheduled job of a service. On each run it calls the Action, emits the Event
+// and calls OnTick, in this order, for the ones that are set.
+type Job struct {
+	Name string
+	// Cron is the schedule of the job, e.g. "*/5 * * * *" or "@every 30s". See Parse.
+	Cron string
+	// Location is the time zone of the schedule, the default is time.Local.
+	Location *time.Location
+	// Action is called with the Params on each run.
+	Action string
+	// Event is emitted with the Params on each run.
+	Event  string
+	Params interface{}
+	// OnTick is called on each run with the context of the service.
+	OnTick func(context moleculer.BrokerContext)
+	// SingleRun runs the job in one node of the cluster only, when the service runs in many nodes.
+	// The node is elected on each run from the registry: the available node with the lowest node ID
+	// that runs the service, so another node takes over when it leaves the cluster.
+	SingleRun bool
+}
+
+// Mixin return the mixin that schedules the jobs when the service is started and stops them
+// when the service is stopped. A job with an invalid cron expression fails the service start.
+//
+//	Mixins: []moleculer.Mixin{cron.Mixin(cron.Job{Name: "cleanup", Cron: "0 3 * * *", Action: "files.cleanup"})}
+func Mixin(jobs ...Job) moleculer.Mixin {
+	var mutex sync.Mutex
+	schedulers := map[string]*scheduler{}
+	return moleculer.Mixin{
+		Name: "cron",
+		Started: func(context moleculer.BrokerContext, schema moleculer.ServiceSchema) {
+			scheduler, err := newScheduler(context, schema, jobs)
+			if err != nil {
+				panic(err)
+			}
+			mutex.Lock()
+			schedulers[scheduler.service] = scheduler
+			mutex.Unlock()
+			scheduler.start()
+		},
+		Stopped: func(context moleculer.BrokerContext, schema moleculer.ServiceSchema) {
+			name := service.JoinVersionToName(schema.Name, schema.Version)
+			mutex.Lock()
+			scheduler, exists := schedulers[name]
+			delete(schedulers, name)
+			mutex.Unlock()
+			if exists {
+				scheduler.stop()
+			}
+		},
+	}
+}
+
+// scheduledJob is a job with its parsed schedule.
+type scheduledJob struct {
+	Job
+	schedule Schedule
+}
+
+// scheduler run the jobs of a service until it is stopped.
+type scheduler struct {
+	service string
+	context moleculer.BrokerContext
+	jobs    []scheduledJob
+	done    chan struct{}
+	running sync.WaitGroup
+}
+
+func newScheduler(context moleculer.BrokerContext, schema moleculer.ServiceSchema, jobs []Job) (*scheduler, error) {
+	name := service.JoinVersionToName(schema.Name, schema.Version)
+	scheduled := make([]scheduledJob, len(jobs))
+	for index, job := range jobs {
+		if job.Action == "" && job.Event == "" && job.OnTick == nil {
+			return nil, errors.New("Invalid cron job: " + job.Name + " of service: " + name + " - it must have an Action, an Event or an OnTick function")
+		}
+		schedule, err := Parse(job.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid cron job: %s of service: %s - error: %s", job.Name, name, err)
+		}
+		if job.Location == nil {
+			job.Location = time.Local
+		}
+		scheduled[index] = scheduledJob{job, schedule}
+	}
+	return &scheduler{
+		service: name,
+		context: context,
+		jobs:    scheduled,
+		done:    make(chan struct{}),
+	}, nil
+}
+
+func (scheduler *scheduler) start() {
+	for _, job := range scheduler.jobs {
+		scheduler.running.Add(1)
+		go scheduler.loop(job)
+	}
+}
+
+// stop the jobs and wait for the running ones to finish.
+func (scheduler *scheduler) stop() {
+	close(scheduler.done)
+	scheduler.running.Wait()
+}
+
+// loop wait for the next time of the job and run it, until the scheduler is stopped.
+func (scheduler *scheduler) loop(job scheduledJob) {
+	defer scheduler.running.Done()
+	for {
+		next := job.schedule.Next(time.Now().In(job.Location))
+		if next.IsZero() {
+			scheduler.context.Logger().Warn("Cron job: ", job.Name, " of service: ", scheduler.service, " has no next run. It was stopped.")
+			return
+		}
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-scheduler.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+			if !job.SingleRun || scheduler.isLeader() {
+				scheduler.run(job)
+			}
+		}
+	}
+}
+
+// run the job, logging the errors of the action and the panics of OnTick.
+func (scheduler *scheduler) run(job scheduledJob) {
+	logger := scheduler.context.Logger()
+	defer func() {
+		if err := recover(); err != nil {
+			logger.Error("Cron job: ", job.Name, " of service: ", scheduler.service, " panic: ", err)
+		}
+	}()
+	logger.Debug("Cron job: ", job.Name, " of service: ", scheduler.service, " started.")
+	if job.Action != "" {
+		result := <-scheduler.context.Call(job.Action, job.Params)
+		if result.IsError() {
+			logger.Error("Cron job: ", job.Name, " of service: ", scheduler.service, " action: ", job.Action, " error: ", result.Error())
+		}
+	}
+	if job.Event != "" {
+		scheduler.context.Emit(job.Event, job.Params)
+	}
+	if job.OnTick != nil {
+		job.OnTick(scheduler.context)
+	}
+}
+
+// isLeader check in the registry if the local node is the available node
+// with the lowest node ID that runs the service.
+func (scheduler *scheduler) isLeader() bool {
+	delegates, ok := scheduler.context.(interface {
+		BrokerDelegates() *moleculer.BrokerDelegates
+	})
+	if !ok {
+		return true
+	}
+	localNodeID := delegates.BrokerDelegates().LocalNode().GetID()
+	result := <-scheduler.context.Call("$node.services", map[string]interface{}{
+		"withEndpoints": true,
+		"onlyAvailable": true,
+	}, moleculer.Options{NodeID: localNodeID})
+	if result.IsError() {
+		scheduler.context.Logger().Error("Cron - could not find the nodes of the service: ", scheduler.service, " error: ", result.Error())
+		return false
+	}
+	nodes := []string{}
+	for _, item := range result.Array() {
+		if item.Get("fullName").String() != scheduler.service {
+			continue
+		}
+		for _, endpoint := range item.Get("endpoints").Array() {
+			if endpoint.Get("available").Bool() {
+				nodes = append(nodes, endpoint.Get("nodeID").String())
+			}
+		}
+	}
+	if len(nodes) == 0 {
+		return false
+	}
+	sort.Strings(nodes)
+	return nodes[0] == localNodeID
+}
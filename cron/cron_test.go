@@ -0,0 +1,129 @@
+package cron
+
+import (
+	"sync"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/broker"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cron", func() {
+
+	It("should call the actions, emit the events and call OnTick on each run", func() {
+		var mutex sync.Mutex
+		var calls []string
+		record := func(name string) {
+			mutex.Lock()
+			calls = append(calls, name)
+			mutex.Unlock()
+		}
+		count := func() int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return len(calls)
+		}
+		bkr := broker.New(&moleculer.Config{LogLevel: "error"})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "reports",
+			Mixins: []moleculer.Mixin{Mixin(Job{
+				Name:   "daily-report",
+				Cron:   "@every 1s",
+				Action: "reports.generate",
+				Event:  "reports.generated",
+				Params: map[string]interface{}{"kind": "daily"},
+				OnTick: func(context moleculer.BrokerContext) {
+					record("tick")
+				},
+			})},
+			Actions: []moleculer.Action{
+				{
+					Name: "generate",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						record("action " + params.Get("kind").String())
+						return nil
+					},
+				},
+			},
+			Events: []moleculer.Event{
+				{
+					Name: "reports.generated",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+						record("event " + params.Get("kind").String())
+					},
+				},
+			},
+		})
+		Expect(bkr.Start()).Should(Succeed())
+
+		Eventually(count, 3*time.Second).Should(Equal(3))
+		mutex.Lock()
+		Expect(calls).Should(ConsistOf("action daily", "event daily", "tick"))
+		mutex.Unlock()
+
+		bkr.Stop()
+		Consistently(count, 1500*time.Millisecond).Should(Equal(3))
+	})
+
+	It("should run the single run jobs in one node of the cluster", func() {
+		var mutex sync.Mutex
+		runs := map[string]int{}
+		count := func(nodeID string) int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return runs[nodeID]
+		}
+		cleaner := func(nodeID string) moleculer.ServiceSchema {
+			return moleculer.ServiceSchema{
+				Name: "cleaner",
+				Mixins: []moleculer.Mixin{Mixin(Job{
+					Name:      "cleanup",
+					Cron:      "@every 1s",
+					SingleRun: true,
+					OnTick: func(context moleculer.BrokerContext) {
+						mutex.Lock()
+						runs[nodeID]++
+						mutex.Unlock()
+					},
+				})},
+			}
+		}
+		config := &moleculer.Config{
+			LogLevel:    "error",
+			Transporter: "memory://cron-test",
+		}
+		bkr1 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "cron-node-b" },
+		})
+		bkr1.Publish(cleaner("cron-node-b"))
+		bkr2 := broker.New(config, &moleculer.Config{
+			DiscoverNodeID: func() string { return "cron-node-a" },
+		})
+		bkr2.Publish(cleaner("cron-node-a"))
+		Expect(bkr1.Start()).Should(Succeed())
+		Expect(bkr2.Start()).Should(Succeed())
+		Expect(bkr1.WaitForNodes("cron-node-a")).Should(Succeed())
+		Expect(bkr2.WaitForNodes("cron-node-b")).Should(Succeed())
+
+		Eventually(func() int { return count("cron-node-a") }, 3*time.Second).Should(BeNumerically(">=", 2))
+		Expect(count("cron-node-b")).Should(BeNumerically("<=", 1))
+
+		bkr2.Stop()
+		Eventually(func() int { return count("cron-node-b") }, 3*time.Second).Should(BeNumerically(">=", 2))
+		bkr1.Stop()
+	})
+
+	It("should fail the service start with an invalid cron expression", func() {
+		bkr := broker.New(&moleculer.Config{LogLevel: "fatal"})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name:   "invalid",
+			Mixins: []moleculer.Mixin{Mixin(Job{Name: "never", Cron: "* * *", OnTick: func(moleculer.BrokerContext) {}})},
+		})
+		err := bkr.Start()
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("Invalid cron job: never of service: invalid"))
+	})
+})
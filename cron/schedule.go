@@ -0,0 +1,206 @@
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule return the next time a job runs after the given time.
+type Schedule interface {
+	Next(time.Time) time.Time
+}
+
+// field is the range of values of a cron expression field.
+type field struct {
+	name     string
+	min, max uint
+}
+
+var (
+	seconds    = field{"second", 0, 59}
+	minutes    = field{"minute", 0, 59}
+	hours      = field{"hour", 0, 23}
+	daysOfMon  = field{"day of month", 1, 31}
+	months     = field{"month", 1, 12}
+	daysOfWeek = field{"day of week", 0, 7}
+)
+
+// descriptors are the predefined schedules, like in the crontab.
+var descriptors = map[string]string{
+	"@yearly":   "0 0 0 1 1 *",
+	"@annually": "0 0 0 1 1 *",
+	"@monthly":  "0 0 0 1 * *",
+	"@weekly":   "0 0 0 * * 0",
+	"@daily":    "0 0 0 * * *",
+	"@midnight": "0 0 0 * * *",
+	"@hourly":   "0 0 * * * *",
+}
+
+// Parse a cron expression. The standard expressions have 5 fields (minute hour day-of-month month
+// day-of-week) and an optional seconds field can be added at the start. Fields support *, lists (1,15),
+// ranges (1-5) and steps (*/10). It also accepts the descriptors @yearly, @monthly, @weekly, @daily,
+// @hourly and @every <duration>, e.g. "@every 30s".
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasPrefix(spec, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(spec, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("Invalid cron expression: %s - error: %s", spec, err)
+		}
+		if interval < time.Second {
+			return nil, fmt.Errorf("Invalid cron expression: %s - the interval must be at least 1 second", spec)
+		}
+		return everySchedule{interval}, nil
+	}
+	if descriptor, exists := descriptors[spec]; exists {
+		return Parse(descriptor)
+	}
+
+	items := strings.Fields(spec)
+	if len(items) == 5 {
+		items = append([]string{"0"}, items...)
+	}
+	if len(items) != 6 {
+		return nil, fmt.Errorf("Invalid cron expression: %s - expected 5 or 6 fields, found %d", spec, len(items))
+	}
+	fields := []field{seconds, minutes, hours, daysOfMon, months, daysOfWeek}
+	bits := make([]uint64, len(fields))
+	for index, item := range items {
+		value, err := parseField(item, fields[index])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid cron expression: %s - error: %s", spec, err)
+		}
+		bits[index] = value
+	}
+	// sunday is 0 or 7
+	if bits[5]&(1<<7) > 0 {
+		bits[5] |= 1
+	}
+	return &cronSchedule{
+		second: bits[0],
+		minute: bits[1],
+		hour:   bits[2],
+		dom:    bits[3],
+		month:  bits[4],
+		dow:    bits[5],
+		anyDom: items[3] == "*" || items[3] == "?",
+		anyDow: items[5] == "*" || items[5] == "?",
+	}, nil
+}
+
+// parseField return the bits of the values of a field. Each item of the list
+// is a value, a range or * with an optional step.
+func parseField(value string, f field) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(value, ",") {
+		start, end, step := f.min, f.max, uint(1)
+		rangeAndStep := strings.Split(item, "/")
+		if len(rangeAndStep) > 2 {
+			return 0, errors.New("invalid " + f.name + ": " + item)
+		}
+		if len(rangeAndStep) == 2 {
+			parsed, err := strconv.Atoi(rangeAndStep[1])
+			if err != nil || parsed <= 0 {
+				return 0, errors.New("invalid " + f.name + " step: " + item)
+			}
+			step = uint(parsed)
+		}
+		if rangeAndStep[0] != "*" && rangeAndStep[0] != "?" {
+			bounds := strings.Split(rangeAndStep[0], "-")
+			if len(bounds) > 2 {
+				return 0, errors.New("invalid " + f.name + " range: " + item)
+			}
+			var err error
+			if start, err = parseValue(bounds[0], f); err != nil {
+				return 0, err
+			}
+			end = start
+			if len(bounds) == 2 {
+				if end, err = parseValue(bounds[1], f); err != nil {
+					return 0, err
+				}
+			} else if len(rangeAndStep) == 2 {
+				end = f.max
+			}
+			if start > end {
+				return 0, errors.New("invalid " + f.name + " range: " + item)
+			}
+		}
+		for current := start; current <= end; current += step {
+			bits |= 1 << current
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(value string, f field) (uint, error) {
+	parsed, err := strconv.Atoi(value)
+	if err != nil || parsed < int(f.min) || parsed > int(f.max) {
+		return 0, fmt.Errorf("invalid %s: %s - it must be between %d and %d", f.name, value, f.min, f.max)
+	}
+	return uint(parsed), nil
+}
+
+// everySchedule runs the job at a fixed interval.
+type everySchedule struct {
+	interval time.Duration
+}
+
+func (schedule everySchedule) Next(t time.Time) time.Time {
+	return t.Add(schedule.interval).Truncate(time.Second)
+}
+
+// cronSchedule has the bits of the values of each field of a cron expression.
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	anyDom, anyDow                        bool
+}
+
+// maxYears is how far in the future Next looks for a matching time,
+// so an expression that never matches (e.g. 30 of february) ends.
+const maxYears = 5
+
+// Next return the first time after t that matches the expression, or the zero
+// time when there is none. The time is in the location of t.
+func (schedule *cronSchedule) Next(t time.Time) time.Time {
+	t = t.Add(time.Second - time.Duration(t.Nanosecond())*time.Nanosecond)
+	limit := t.Year() + maxYears
+	for t.Year() <= limit {
+		if schedule.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !schedule.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if schedule.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if schedule.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Truncate(time.Minute).Add(time.Minute)
+			continue
+		}
+		if schedule.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay check the day of month and the day of week. Like in the crontab, when
+// both are restricted the day matches any of them.
+func (schedule *cronSchedule) matchDay(t time.Time) bool {
+	domMatch := schedule.dom&(1<<uint(t.Day())) > 0
+	dowMatch := schedule.dow&(1<<uint(t.Weekday())) > 0
+	if schedule.anyDom || schedule.anyDow {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
@@ -0,0 +1,54 @@
+package cron
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Schedule", func() {
+
+	start := time.Date(2020, time.January, 30, 10, 15, 30, 500, time.UTC)
+	next := func(spec string, from time.Time) time.Time {
+		schedule, err := Parse(spec)
+		Expect(err).Should(BeNil())
+		return schedule.Next(from)
+	}
+
+	It("should return the next time of the cron expressions", func() {
+		Expect(next("* * * * *", start)).Should(Equal(time.Date(2020, time.January, 30, 10, 16, 0, 0, time.UTC)))
+		Expect(next("*/10 * * * * *", start)).Should(Equal(time.Date(2020, time.January, 30, 10, 15, 40, 0, time.UTC)))
+		Expect(next("0 3 * * *", start)).Should(Equal(time.Date(2020, time.January, 31, 3, 0, 0, 0, time.UTC)))
+		Expect(next("30 9-17/4 1,15 * *", start)).Should(Equal(time.Date(2020, time.February, 1, 9, 30, 0, 0, time.UTC)))
+		Expect(next("0 0 29 2 *", start)).Should(Equal(time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)))
+		Expect(next("0 0 29 2 *", time.Date(2020, time.March, 1, 0, 0, 0, 0, time.UTC))).Should(
+			Equal(time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)))
+		Expect(next("0 0 30 2 *", start).IsZero()).Should(BeTrue())
+	})
+
+	It("should match the day of month or the day of week when both are set", func() {
+		// 2020-01-30 is a thursday
+		Expect(next("0 12 * * 7", start)).Should(Equal(time.Date(2020, time.February, 2, 12, 0, 0, 0, time.UTC)))
+		Expect(next("0 12 * * 1-5", start)).Should(Equal(time.Date(2020, time.January, 30, 12, 0, 0, 0, time.UTC)))
+		Expect(next("0 0 1 * 6", start)).Should(Equal(time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(next("0 0 15 * 5", start)).Should(Equal(time.Date(2020, time.January, 31, 0, 0, 0, 0, time.UTC)))
+	})
+
+	It("should parse the descriptors and the intervals", func() {
+		Expect(next("@hourly", start)).Should(Equal(time.Date(2020, time.January, 30, 11, 0, 0, 0, time.UTC)))
+		Expect(next("@daily", start)).Should(Equal(time.Date(2020, time.January, 31, 0, 0, 0, 0, time.UTC)))
+		Expect(next("@weekly", start)).Should(Equal(time.Date(2020, time.February, 2, 0, 0, 0, 0, time.UTC)))
+		Expect(next("@monthly", start)).Should(Equal(time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(next("@yearly", start)).Should(Equal(time.Date(2021, time.January, 1, 0, 0, 0, 0, time.UTC)))
+		Expect(next("@every 90s", start)).Should(Equal(time.Date(2020, time.January, 30, 10, 17, 0, 0, time.UTC)))
+	})
+
+	It("should return an error for invalid expressions", func() {
+		for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "* * * 13 *",
+			"* * * * 8", "5-1 * * * *", "*/0 * * * *", "a * * * *", "@every 1ms", "@every soon", "@sometimes"} {
+			_, err := Parse(spec)
+			Expect(err).Should(HaveOccurred(), spec)
+		}
+	})
+})
@@ -0,0 +1,203 @@
+package discovery
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ConsulOptions configure the Consul discoverer.
+type ConsulOptions struct {
+	// Address of the HTTP API of the Consul agent, http://127.0.0.1:8500 when empty.
+	Address string
+	// Token is the ACL token of the requests, none when empty.
+	Token string
+	// Service is the name of the Consul service of the brokers, "moleculer" when empty.
+	Service string
+	// NodeID of the local broker, registered as an instance of the service.
+	NodeID string
+	// Hostname is the address registered for the local broker, the os hostname when empty.
+	Hostname string
+	// TTL of the health check of the local broker, 15 seconds when zero. The broker passes the check
+	// every third of the TTL, and Consul deregisters it when the check is critical for a minute.
+	TTL time.Duration
+	// WaitTime is the maximum duration of the blocking queries that watch the peers, 5 minutes when zero.
+	WaitTime time.Duration
+	// RetryDelay is the wait before querying the peers again after an error, 5 seconds when zero.
+	RetryDelay time.Duration
+	Client     *http.Client
+	Logger     *log.Entry
+}
+
+// ConsulDiscoverer register the local broker as an instance of a Consul service and watch the
+// passing instances of the service with blocking queries.
+type ConsulDiscoverer struct {
+	options ConsulOptions
+	peers   *peerSet
+	cancel  context.CancelFunc
+	mutex   sync.Mutex
+}
+
+// Consul return a discoverer that uses the Consul service catalog.
+func Consul(options ConsulOptions) *ConsulDiscoverer {
+	if options.Address == "" {
+		options.Address = "http://127.0.0.1:8500"
+	}
+	if options.Service == "" {
+		options.Service = "moleculer"
+	}
+	if options.Hostname == "" {
+		options.Hostname, _ = os.Hostname()
+	}
+	if options.TTL <= 0 {
+		options.TTL = 15 * time.Second
+	}
+	if options.WaitTime <= 0 {
+		options.WaitTime = 5 * time.Minute
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = 5 * time.Second
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{}
+	}
+	if options.Logger == nil {
+		options.Logger = log.WithField("discoverer", "consul")
+	}
+	return &ConsulDiscoverer{options: options, peers: newPeerSet(options.Logger)}
+}
+
+func (discoverer *ConsulDiscoverer) serviceID() string {
+	return discoverer.options.Service + "-" + discoverer.options.NodeID
+}
+
+func (discoverer *ConsulDiscoverer) request(ctx context.Context, method, path string, body, result interface{}) (*http.Response, error) {
+	headers := map[string]string{}
+	if discoverer.options.Token != "" {
+		headers["X-Consul-Token"] = discoverer.options.Token
+	}
+	return requestJSON(ctx, discoverer.options.Client, method, discoverer.options.Address+path, headers, body, result)
+}
+
+// register add the local broker to the service, with a TTL health check.
+func (discoverer *ConsulDiscoverer) register(ctx context.Context) error {
+	_, err := discoverer.request(ctx, http.MethodPut, "/v1/agent/service/register", map[string]interface{}{
+		"ID":      discoverer.serviceID(),
+		"Name":    discoverer.options.Service,
+		"Address": discoverer.options.Hostname,
+		"Meta":    map[string]string{"nodeID": discoverer.options.NodeID},
+		"Check": map[string]interface{}{
+			"CheckID":                        "service:" + discoverer.serviceID(),
+			"TTL":                            discoverer.options.TTL.String(),
+			"DeregisterCriticalServiceAfter": "1m",
+		},
+	}, nil)
+	return err
+}
+
+// passCheck pass the health check every third of the TTL, registering the broker again when the check is unknown.
+func (discoverer *ConsulDiscoverer) passCheck(ctx context.Context) {
+	ticker := time.NewTicker(discoverer.options.TTL / 3)
+	defer ticker.Stop()
+	for {
+		_, err := discoverer.request(ctx, http.MethodPut, "/v1/agent/check/pass/"+url.PathEscape("service:"+discoverer.serviceID()), nil, nil)
+		if err != nil && ctx.Err() == nil {
+			discoverer.options.Logger.Warn("Consul discoverer - could not pass the health check: ", err, " - registering again")
+			if err := discoverer.register(ctx); err != nil && ctx.Err() == nil {
+				discoverer.options.Logger.Warn("Consul discoverer - could not register the service: ", err)
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type consulEntry struct {
+	Node struct {
+		Address string
+	}
+	Service struct {
+		ID      string
+		Address string
+		Meta    map[string]string
+	}
+}
+
+// watch wait for a change of the passing instances of the service since the index, and return the new index.
+func (discoverer *ConsulDiscoverer) watch(ctx context.Context, index uint64) (uint64, error) {
+	path := fmt.Sprint("/v1/health/service/", url.PathEscape(discoverer.options.Service), "?passing=true&index=", index, "&wait=", int(discoverer.options.WaitTime.Seconds()), "s")
+	var entries []consulEntry
+	response, err := discoverer.request(ctx, http.MethodGet, path, nil, &entries)
+	if err != nil {
+		return 0, err
+	}
+	peers := []Peer{}
+	for _, entry := range entries {
+		nodeID := entry.Service.Meta["nodeID"]
+		if nodeID == discoverer.options.NodeID {
+			continue
+		}
+		address := entry.Service.Address
+		if address == "" {
+			address = entry.Node.Address
+		}
+		peers = append(peers, Peer{Name: entry.Service.ID, NodeID: nodeID, Address: address})
+	}
+	discoverer.peers.replace(peers)
+	next, _ := strconv.ParseUint(response.Header.Get("X-Consul-Index"), 10, 64)
+	if next < index {
+		next = 0
+	}
+	return next, nil
+}
+
+// Start register the local broker and watch the peers until Stop is called.
+func (discoverer *ConsulDiscoverer) Start(found func(peer Peer), lost func(peer Peer)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := discoverer.register(ctx); err != nil {
+		cancel()
+		return err
+	}
+	discoverer.peers.listen(found, lost)
+	discoverer.mutex.Lock()
+	discoverer.cancel = cancel
+	discoverer.mutex.Unlock()
+	go discoverer.passCheck(ctx)
+	var index uint64
+	go watchUntilDone(ctx, discoverer.options.RetryDelay, discoverer.options.Logger, func(ctx context.Context) error {
+		next, err := discoverer.watch(ctx, index)
+		if err != nil {
+			index = 0
+			return err
+		}
+		index = next
+		return nil
+	})
+	return nil
+}
+
+// Stop end the watch and deregister the local broker.
+func (discoverer *ConsulDiscoverer) Stop() {
+	discoverer.mutex.Lock()
+	defer discoverer.mutex.Unlock()
+	if discoverer.cancel == nil {
+		return
+	}
+	discoverer.cancel()
+	discoverer.cancel = nil
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if _, err := discoverer.request(ctx, http.MethodPut, "/v1/agent/service/deregister/"+url.PathEscape(discoverer.serviceID()), nil, nil); err != nil {
+		discoverer.options.Logger.Warn("Consul discoverer - could not deregister the service: ", err)
+	}
+}
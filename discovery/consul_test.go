@@ -0,0 +1,111 @@
+package discovery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+func consulEntryJSON(nodeID, address string) string {
+	return fmt.Sprintf(`{"Node":{"Address":"10.0.0.9"},"Service":{"ID":"moleculer-%s","Address":"%s","Meta":{"nodeID":"%s"}}}`, nodeID, address, nodeID)
+}
+
+var _ = Describe("Consul Discoverer", func() {
+	logger := log.WithField("unit test pkg", "discovery_test")
+
+	It("should parse the discoverer url", func() {
+		discoverer, exists := Create("consul://consul:8500?service=users&token=secret&ttl=30s", "node-1", logger)
+		Expect(exists).Should(BeTrue())
+		options := discoverer.(*ConsulDiscoverer).options
+		Expect(options.Address).Should(Equal("http://consul:8500"))
+		Expect(options.Service).Should(Equal("users"))
+		Expect(options.Token).Should(Equal("secret"))
+		Expect(options.NodeID).Should(Equal("node-1"))
+		Expect(options.TTL.String()).Should(Equal("30s"))
+	})
+
+	It("should register the local broker and report the passing peers", func() {
+		events := make(chan string, 10)
+		var mutex sync.Mutex
+		var registered map[string]interface{}
+		calls := []string{}
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			mutex.Lock()
+			calls = append(calls, request.Method+" "+request.URL.Path+" "+request.Header.Get("X-Consul-Token"))
+			if request.URL.Path == "/v1/agent/service/register" {
+				json.NewDecoder(request.Body).Decode(&registered)
+			}
+			mutex.Unlock()
+			if request.URL.Path != "/v1/health/service/moleculer" {
+				return
+			}
+			if request.URL.Query().Get("passing") != "true" {
+				writer.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			switch request.URL.Query().Get("index") {
+			case "0":
+				writer.Header().Set("X-Consul-Index", "5")
+				fmt.Fprint(writer, "[", consulEntryJSON("node-1", "host-1"), ",", consulEntryJSON("node-2", "host-2"), ",", consulEntryJSON("node-3", ""), "]")
+			case "5":
+				writer.Header().Set("X-Consul-Index", "6")
+				fmt.Fprint(writer, "[", consulEntryJSON("node-1", "host-1"), ",", consulEntryJSON("node-3", ""), "]")
+			default:
+				<-request.Context().Done()
+			}
+		}))
+		defer server.Close()
+
+		discoverer := Consul(ConsulOptions{
+			Address:  server.URL,
+			Token:    "secret",
+			NodeID:   "node-1",
+			Hostname: "host-1",
+			Client:   server.Client(),
+			Logger:   logger,
+		})
+		err := discoverer.Start(
+			func(peer Peer) { events <- "found " + peer.Name + " " + peer.NodeID + " " + peer.Address },
+			func(peer Peer) { events <- "lost " + peer.Name + " " + peer.NodeID },
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		received := []string{}
+		for index := 0; index < 3; index++ {
+			var event string
+			Eventually(events).Should(Receive(&event))
+			received = append(received, event)
+		}
+		Expect(received).Should(Equal([]string{
+			"found moleculer-node-2 node-2 host-2",
+			"found moleculer-node-3 node-3 10.0.0.9",
+			"lost moleculer-node-2 node-2",
+		}))
+		discoverer.Stop()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(registered["ID"]).Should(Equal("moleculer-node-1"))
+		Expect(registered["Address"]).Should(Equal("host-1"))
+		Expect(registered["Meta"]).Should(Equal(map[string]interface{}{"nodeID": "node-1"}))
+		Expect(registered["Check"]).Should(HaveKeyWithValue("TTL", "15s"))
+		Expect(calls[0]).Should(Equal("PUT /v1/agent/service/register secret"))
+		Expect(calls).Should(ContainElement("PUT /v1/agent/check/pass/service:moleculer-node-1 secret"))
+		Expect(calls[len(calls)-1]).Should(Equal("PUT /v1/agent/service/deregister/moleculer-node-1 secret"))
+	})
+
+	It("should fail to start when the broker can not be registered", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			writer.WriteHeader(http.StatusForbidden)
+		}))
+		defer server.Close()
+		discoverer := Consul(ConsulOptions{Address: server.URL, NodeID: "node-1", Logger: logger})
+		Expect(discoverer.Start(func(Peer) {}, func(Peer) {})).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,139 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Peer is a broker found by a discoverer, e.g. a pod of a Kubernetes deployment.
+type Peer struct {
+	// Name of the peer in the discoverer, e.g. the pod name.
+	Name string
+	// NodeID of the broker, empty when the discoverer does not know it.
+	NodeID string
+	// Address of the peer, e.g. the pod IP.
+	Address string
+}
+
+// Discoverer finds the peers of the broker outside of the transporter. The registry sends a DISCOVER
+// to each peer found, so the peers are added to the node catalog without waiting for their heartbeats,
+// and disconnects the peers lost without waiting for the heartbeat timeout.
+type Discoverer interface {
+	// Start watch the peers until Stop is called, calling found when a peer is ready
+	// and lost when it is removed or not ready anymore.
+	Start(found func(peer Peer), lost func(peer Peer)) error
+	Stop()
+}
+
+// Create return the discoverer of the config for the local node, e.g. "kubernetes://?labelSelector=app%3Dusers",
+// "consul://127.0.0.1:8500?service=users" or "etcd://127.0.0.1:2379?prefix=users/". The second value is false
+// when the config is not a known discoverer.
+func Create(config string, nodeID string, logger *log.Entry) (Discoverer, bool) {
+	parsed, err := url.Parse(config)
+	if err != nil {
+		return nil, false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "kubernetes", "k8s":
+		return Kubernetes(kubernetesOptionsFrom(parsed, logger)), true
+	case "consul":
+		return Consul(consulOptionsFrom(parsed, nodeID, logger)), true
+	case "etcd", "etcd3":
+		return Etcd(etcdOptionsFrom(parsed, nodeID, logger)), true
+	}
+	return nil, false
+}
+
+// kubernetesOptionsFrom read the options from the url: the host is the API server, the in-cluster
+// one when empty, and the namespace, labelSelector, nodeIDAnnotation and retryDelay are query params.
+func kubernetesOptionsFrom(config *url.URL, logger *log.Entry) KubernetesOptions {
+	query := config.Query()
+	options := KubernetesOptions{
+		Namespace:        query.Get("namespace"),
+		LabelSelector:    query.Get("labelSelector"),
+		NodeIDAnnotation: query.Get("nodeIDAnnotation"),
+		Logger:           logger,
+	}
+	if config.Host != "" {
+		options.APIServer = "https://" + config.Host
+	}
+	options.RetryDelay, _ = time.ParseDuration(query.Get("retryDelay"))
+	return options
+}
+
+// consulOptionsFrom read the options from the url: the host is the Consul agent, and the service,
+// token, ttl and retryDelay are query params.
+func consulOptionsFrom(config *url.URL, nodeID string, logger *log.Entry) ConsulOptions {
+	query := config.Query()
+	options := ConsulOptions{
+		Service: query.Get("service"),
+		Token:   query.Get("token"),
+		NodeID:  nodeID,
+		Logger:  logger,
+	}
+	if config.Host != "" {
+		options.Address = "http://" + config.Host
+	}
+	options.TTL, _ = time.ParseDuration(query.Get("ttl"))
+	options.RetryDelay, _ = time.ParseDuration(query.Get("retryDelay"))
+	return options
+}
+
+// etcdOptionsFrom read the options from the url: the host is the etcd JSON gateway, and the prefix,
+// ttl and retryDelay are query params.
+func etcdOptionsFrom(config *url.URL, nodeID string, logger *log.Entry) EtcdOptions {
+	query := config.Query()
+	options := EtcdOptions{
+		Prefix: query.Get("prefix"),
+		NodeID: nodeID,
+		Logger: logger,
+	}
+	if config.Host != "" {
+		options.Address = "http://" + config.Host
+	}
+	options.TTL, _ = time.ParseDuration(query.Get("ttl"))
+	options.RetryDelay, _ = time.ParseDuration(query.Get("retryDelay"))
+	return options
+}
+
+// requestJSON send the body encoded as JSON and decode the JSON response into the result, when not nil.
+func requestJSON(ctx context.Context, client *http.Client, method, address string, headers map[string]string, body, result interface{}) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	request, err := http.NewRequest(method, address, reader)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		request.Header.Set(name, value)
+	}
+	response, err := client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s - status: %s", method, request.URL.Path, response.Status)
+	}
+	if result != nil {
+		if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+			return nil, err
+		}
+	}
+	return response, nil
+}
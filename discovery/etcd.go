@@ -0,0 +1,301 @@
+package discovery
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// EtcdOptions configure the etcd discoverer.
+type EtcdOptions struct {
+	// Address of the etcd v3 JSON gateway, http://127.0.0.1:2379 when empty.
+	Address string
+	// Prefix of the keys of the brokers, "moleculer/discovery/" when empty. The key of a broker is the prefix
+	// followed by its node ID.
+	Prefix string
+	// NodeID of the local broker, registered with its key.
+	NodeID string
+	// Hostname is the address registered for the local broker, the os hostname when empty.
+	Hostname string
+	// TTL of the lease of the local key, 15 seconds when zero. The broker keeps the lease alive every
+	// third of the TTL, so etcd deletes the key of a broker that stops without revoking its lease.
+	TTL time.Duration
+	// RetryDelay is the wait before reading the peers again after an error, 5 seconds when zero.
+	RetryDelay time.Duration
+	Client     *http.Client
+	Logger     *log.Entry
+}
+
+// EtcdDiscoverer register the local broker with a key leased in etcd and watch the keys of the peers.
+type EtcdDiscoverer struct {
+	options EtcdOptions
+	peers   *peerSet
+	leaseID string
+	cancel  context.CancelFunc
+	mutex   sync.Mutex
+}
+
+// Etcd return a discoverer that uses the etcd v3 key-value store.
+func Etcd(options EtcdOptions) *EtcdDiscoverer {
+	if options.Address == "" {
+		options.Address = "http://127.0.0.1:2379"
+	}
+	if options.Prefix == "" {
+		options.Prefix = "moleculer/discovery/"
+	}
+	if options.Hostname == "" {
+		options.Hostname, _ = os.Hostname()
+	}
+	if options.TTL <= 0 {
+		options.TTL = 15 * time.Second
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = 5 * time.Second
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{}
+	}
+	if options.Logger == nil {
+		options.Logger = log.WithField("discoverer", "etcd")
+	}
+	return &EtcdDiscoverer{options: options, peers: newPeerSet(options.Logger)}
+}
+
+func encodeKey(key string) string {
+	return base64.StdEncoding.EncodeToString([]byte(key))
+}
+
+func decodeKey(key string) string {
+	decoded, _ := base64.StdEncoding.DecodeString(key)
+	return string(decoded)
+}
+
+// rangeEnd return the end of the range of the keys with the prefix, the prefix with the last byte increased.
+func rangeEnd(prefix string) string {
+	end := []byte(prefix)
+	for index := len(end) - 1; index >= 0; index-- {
+		if end[index] < 0xff {
+			end[index]++
+			return string(end[:index+1])
+		}
+	}
+	return "\x00"
+}
+
+func (discoverer *EtcdDiscoverer) request(ctx context.Context, path string, body, result interface{}) error {
+	_, err := requestJSON(ctx, discoverer.options.Client, http.MethodPost, discoverer.options.Address+path, nil, body, result)
+	return err
+}
+
+// register grant a lease and put the key of the local broker with it.
+func (discoverer *EtcdDiscoverer) register(ctx context.Context) error {
+	var lease struct {
+		ID string
+	}
+	err := discoverer.request(ctx, "/v3/lease/grant", map[string]interface{}{"TTL": int(discoverer.options.TTL.Seconds())}, &lease)
+	if err != nil {
+		return err
+	}
+	value, _ := json.Marshal(map[string]string{"nodeID": discoverer.options.NodeID, "address": discoverer.options.Hostname})
+	err = discoverer.request(ctx, "/v3/kv/put", map[string]interface{}{
+		"key":   encodeKey(discoverer.options.Prefix + discoverer.options.NodeID),
+		"value": base64.StdEncoding.EncodeToString(value),
+		"lease": lease.ID,
+	}, nil)
+	if err != nil {
+		return err
+	}
+	discoverer.mutex.Lock()
+	discoverer.leaseID = lease.ID
+	discoverer.mutex.Unlock()
+	return nil
+}
+
+func (discoverer *EtcdDiscoverer) lease() string {
+	discoverer.mutex.Lock()
+	defer discoverer.mutex.Unlock()
+	return discoverer.leaseID
+}
+
+// keepAlive renew the lease every third of the TTL, registering the broker again when the lease expired.
+func (discoverer *EtcdDiscoverer) keepAlive(ctx context.Context) {
+	ticker := time.NewTicker(discoverer.options.TTL / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var response struct {
+			Result struct {
+				TTL string
+			}
+		}
+		err := discoverer.request(ctx, "/v3/lease/keepalive", map[string]interface{}{"ID": discoverer.lease()}, &response)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil || response.Result.TTL == "" || response.Result.TTL == "0" {
+			discoverer.options.Logger.Warn("etcd discoverer - the lease expired or could not be renewed: ", err, " - registering again")
+			if err := discoverer.register(ctx); err != nil && ctx.Err() == nil {
+				discoverer.options.Logger.Warn("etcd discoverer - could not register the broker: ", err)
+			}
+		}
+	}
+}
+
+type etcdKeyValue struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// peer return the peer of the key, false for the local broker.
+func (discoverer *EtcdDiscoverer) peer(kv etcdKeyValue) (Peer, bool) {
+	nodeID := strings.TrimPrefix(decodeKey(kv.Key), discoverer.options.Prefix)
+	peer := Peer{Name: nodeID, NodeID: nodeID}
+	var value struct {
+		Address string `json:"address"`
+	}
+	if decoded, err := base64.StdEncoding.DecodeString(kv.Value); err == nil {
+		json.Unmarshal(decoded, &value)
+	}
+	peer.Address = value.Address
+	return peer, nodeID != discoverer.options.NodeID
+}
+
+// list read the keys of the brokers, reporting the peers found and lost since the last list,
+// and return the revision to watch from.
+func (discoverer *EtcdDiscoverer) list(ctx context.Context) (int64, error) {
+	var response struct {
+		Header struct {
+			Revision string `json:"revision"`
+		} `json:"header"`
+		Kvs []etcdKeyValue `json:"kvs"`
+	}
+	err := discoverer.request(ctx, "/v3/kv/range", map[string]interface{}{
+		"key":       encodeKey(discoverer.options.Prefix),
+		"range_end": encodeKey(rangeEnd(discoverer.options.Prefix)),
+	}, &response)
+	if err != nil {
+		return 0, err
+	}
+	peers := []Peer{}
+	for _, kv := range response.Kvs {
+		if peer, remote := discoverer.peer(kv); remote {
+			peers = append(peers, peer)
+		}
+	}
+	discoverer.peers.replace(peers)
+	return strconv.ParseInt(response.Header.Revision, 10, 64)
+}
+
+type etcdWatchResponse struct {
+	Result struct {
+		Canceled bool `json:"canceled"`
+		Events   []struct {
+			Type string       `json:"type"`
+			Kv   etcdKeyValue `json:"kv"`
+		} `json:"events"`
+	} `json:"result"`
+	Error json.RawMessage `json:"error"`
+}
+
+// watch apply the changes of the keys of the brokers after the revision, until the watch ends.
+func (discoverer *EtcdDiscoverer) watch(ctx context.Context, revision int64) error {
+	body, _ := json.Marshal(map[string]interface{}{"create_request": map[string]interface{}{
+		"key":            encodeKey(discoverer.options.Prefix),
+		"range_end":      encodeKey(rangeEnd(discoverer.options.Prefix)),
+		"start_revision": strconv.FormatInt(revision+1, 10),
+	}})
+	request, err := http.NewRequest(http.MethodPost, discoverer.options.Address+"/v3/watch", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	response, err := discoverer.options.Client.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("etcd watch - status: %s", response.Status)
+	}
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var event etcdWatchResponse
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil || err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if len(event.Error) > 0 {
+			return fmt.Errorf("etcd watch error: %s", event.Error)
+		}
+		if event.Result.Canceled {
+			return nil
+		}
+		for _, item := range event.Result.Events {
+			peer, remote := discoverer.peer(item.Kv)
+			if !remote {
+				continue
+			}
+			if item.Type == "DELETE" {
+				discoverer.peers.remove(peer.Name)
+			} else {
+				discoverer.peers.add(peer)
+			}
+		}
+	}
+}
+
+// Start register the local broker and watch the peers until Stop is called.
+func (discoverer *EtcdDiscoverer) Start(found func(peer Peer), lost func(peer Peer)) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := discoverer.register(ctx); err != nil {
+		cancel()
+		return err
+	}
+	discoverer.peers.listen(found, lost)
+	discoverer.mutex.Lock()
+	discoverer.cancel = cancel
+	discoverer.mutex.Unlock()
+	go discoverer.keepAlive(ctx)
+	go watchUntilDone(ctx, discoverer.options.RetryDelay, discoverer.options.Logger, func(ctx context.Context) error {
+		revision, err := discoverer.list(ctx)
+		if err != nil {
+			return err
+		}
+		return discoverer.watch(ctx, revision)
+	})
+	return nil
+}
+
+// Stop end the watch and revoke the lease, so etcd deletes the key of the local broker.
+func (discoverer *EtcdDiscoverer) Stop() {
+	discoverer.mutex.Lock()
+	cancel := discoverer.cancel
+	discoverer.cancel = nil
+	leaseID := discoverer.leaseID
+	discoverer.mutex.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	ctx, cancelRevoke := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancelRevoke()
+	if err := discoverer.request(ctx, "/v3/lease/revoke", map[string]interface{}{"ID": leaseID}, nil); err != nil {
+		discoverer.options.Logger.Warn("etcd discoverer - could not revoke the lease: ", err)
+	}
+}
@@ -0,0 +1,96 @@
+package discovery
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+func etcdKeyValueJSON(nodeID, address string) string {
+	value := base64.StdEncoding.EncodeToString([]byte(`{"nodeID":"` + nodeID + `","address":"` + address + `"}`))
+	return fmt.Sprintf(`{"key":"%s","value":"%s"}`, encodeKey("moleculer/discovery/"+nodeID), value)
+}
+
+var _ = Describe("etcd Discoverer", func() {
+	logger := log.WithField("unit test pkg", "discovery_test")
+
+	It("should parse the discoverer url", func() {
+		discoverer, exists := Create("etcd://etcd:2379?prefix=users/&ttl=30s", "node-1", logger)
+		Expect(exists).Should(BeTrue())
+		options := discoverer.(*EtcdDiscoverer).options
+		Expect(options.Address).Should(Equal("http://etcd:2379"))
+		Expect(options.Prefix).Should(Equal("users/"))
+		Expect(options.NodeID).Should(Equal("node-1"))
+		Expect(options.TTL.String()).Should(Equal("30s"))
+	})
+
+	It("should return the end of the range of a prefix", func() {
+		Expect(rangeEnd("moleculer/")).Should(Equal("moleculer0"))
+		Expect(rangeEnd("a\xff")).Should(Equal("b"))
+		Expect(rangeEnd("")).Should(Equal("\x00"))
+	})
+
+	It("should register the local broker with a lease and report the peers", func() {
+		events := make(chan string, 10)
+		var mutex sync.Mutex
+		requests := map[string]map[string]interface{}{}
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			body := map[string]interface{}{}
+			json.NewDecoder(request.Body).Decode(&body)
+			mutex.Lock()
+			requests[request.URL.Path] = body
+			mutex.Unlock()
+			switch request.URL.Path {
+			case "/v3/lease/grant":
+				fmt.Fprint(writer, `{"ID":"42","TTL":"15"}`)
+			case "/v3/kv/range":
+				fmt.Fprint(writer, `{"header":{"revision":"7"},"kvs":[`, etcdKeyValueJSON("node-1", "host-1"), ",", etcdKeyValueJSON("node-2", "host-2"), "]}")
+			case "/v3/watch":
+				fmt.Fprintln(writer, `{"result":{"created":true}}`)
+				fmt.Fprintln(writer, `{"result":{"events":[{"kv":`+etcdKeyValueJSON("node-3", "host-3")+`},{"type":"DELETE","kv":{"key":"`+encodeKey("moleculer/discovery/node-2")+`"}}]}}`)
+				writer.(http.Flusher).Flush()
+				<-request.Context().Done()
+			default:
+				fmt.Fprint(writer, `{}`)
+			}
+		}))
+		defer server.Close()
+
+		discoverer := Etcd(EtcdOptions{
+			Address:  server.URL,
+			NodeID:   "node-1",
+			Hostname: "host-1",
+			Client:   server.Client(),
+			Logger:   logger,
+		})
+		err := discoverer.Start(
+			func(peer Peer) { events <- "found " + peer.NodeID + " " + peer.Address },
+			func(peer Peer) { events <- "lost " + peer.NodeID },
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Eventually(events).Should(Receive(Equal("found node-2 host-2")))
+		Eventually(events).Should(Receive(Equal("found node-3 host-3")))
+		Eventually(events).Should(Receive(Equal("lost node-2")))
+		discoverer.Stop()
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		Expect(requests["/v3/lease/grant"]).Should(Equal(map[string]interface{}{"TTL": float64(15)}))
+		put := requests["/v3/kv/put"]
+		Expect(put["key"]).Should(Equal(encodeKey("moleculer/discovery/node-1")))
+		Expect(put["lease"]).Should(Equal("42"))
+		value, _ := base64.StdEncoding.DecodeString(put["value"].(string))
+		Expect(string(value)).Should(Equal(`{"address":"host-1","nodeID":"node-1"}`))
+		Expect(requests["/v3/kv/range"]["range_end"]).Should(Equal(encodeKey("moleculer/discovery0")))
+		Expect(requests["/v3/watch"]["create_request"]).Should(HaveKeyWithValue("start_revision", "8"))
+		Expect(requests["/v3/lease/revoke"]).Should(Equal(map[string]interface{}{"ID": "42"}))
+	})
+})
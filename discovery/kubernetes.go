@@ -0,0 +1,263 @@
+package discovery
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const serviceAccountPath = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// DefaultNodeIDAnnotation is the pod annotation with the node ID of the broker running in the pod.
+const DefaultNodeIDAnnotation = "moleculer.services/node-id"
+
+// KubernetesOptions configure the Kubernetes discoverer. The zero values are read from the
+// service account of the pod, so only the LabelSelector is needed when running in the cluster.
+type KubernetesOptions struct {
+	// APIServer is the url of the Kubernetes API, the in-cluster one when empty.
+	APIServer string
+	// Token is the bearer token of the API requests, the service account token when empty.
+	Token string
+	// Namespace of the pods, the namespace of the service account when empty.
+	Namespace string
+	// LabelSelector of the pods of the brokers, e.g. app=users. All pods of the namespace when empty.
+	LabelSelector string
+	// NodeIDAnnotation is the pod annotation with the node ID, DefaultNodeIDAnnotation when empty.
+	// A peer without it is found with an empty node ID, and the registry broadcasts the DISCOVER.
+	NodeIDAnnotation string
+	// RetryDelay is the wait before listing the pods again after an error, 5 seconds when zero.
+	RetryDelay time.Duration
+	// Client of the API requests, a client that trusts the service account CA when nil.
+	Client *http.Client
+	// Hostname is the name of the local pod, which is not a peer. The os hostname when empty.
+	Hostname string
+	Logger   *log.Entry
+}
+
+// KubernetesDiscoverer watch the ready pods that match the label selector using the Kubernetes API.
+type KubernetesDiscoverer struct {
+	options KubernetesOptions
+	peers   *peerSet
+	cancel  context.CancelFunc
+	mutex   sync.Mutex
+}
+
+// Kubernetes return a discoverer of the pods of the brokers in a Kubernetes cluster.
+func Kubernetes(options KubernetesOptions) *KubernetesDiscoverer {
+	if options.NodeIDAnnotation == "" {
+		options.NodeIDAnnotation = DefaultNodeIDAnnotation
+	}
+	if options.RetryDelay <= 0 {
+		options.RetryDelay = 5 * time.Second
+	}
+	if options.Hostname == "" {
+		options.Hostname, _ = os.Hostname()
+	}
+	if options.Logger == nil {
+		options.Logger = log.WithField("discoverer", "kubernetes")
+	}
+	return &KubernetesDiscoverer{options: options, peers: newPeerSet(options.Logger)}
+}
+
+// inCluster fill the options not set with the service account of the pod.
+func (discoverer *KubernetesDiscoverer) inCluster() error {
+	options := &discoverer.options
+	if options.APIServer == "" {
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return errors.New("Kubernetes discoverer - not running in a cluster: KUBERNETES_SERVICE_HOST and KUBERNETES_SERVICE_PORT are not set")
+		}
+		options.APIServer = "https://" + host + ":" + port
+	}
+	if options.Token == "" {
+		if token, err := ioutil.ReadFile(serviceAccountPath + "/token"); err == nil {
+			options.Token = strings.TrimSpace(string(token))
+		}
+	}
+	if options.Namespace == "" {
+		options.Namespace = "default"
+		if namespace, err := ioutil.ReadFile(serviceAccountPath + "/namespace"); err == nil {
+			options.Namespace = strings.TrimSpace(string(namespace))
+		}
+	}
+	if options.Client == nil {
+		options.Client = &http.Client{}
+		if ca, err := ioutil.ReadFile(serviceAccountPath + "/ca.crt"); err == nil {
+			pool := x509.NewCertPool()
+			pool.AppendCertsFromPEM(ca)
+			options.Client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+		}
+	}
+	return nil
+}
+
+// Start list the pods and watch their changes until Stop is called. The pods are listed again
+// when the watch ends, e.g. after the API server timeout, and after an error.
+func (discoverer *KubernetesDiscoverer) Start(found func(peer Peer), lost func(peer Peer)) error {
+	if err := discoverer.inCluster(); err != nil {
+		return err
+	}
+	discoverer.peers.listen(found, lost)
+	ctx, cancel := context.WithCancel(context.Background())
+	discoverer.mutex.Lock()
+	discoverer.cancel = cancel
+	discoverer.mutex.Unlock()
+	go watchUntilDone(ctx, discoverer.options.RetryDelay, discoverer.options.Logger, func(ctx context.Context) error {
+		resourceVersion, err := discoverer.list(ctx)
+		if err != nil {
+			return err
+		}
+		return discoverer.watch(ctx, resourceVersion)
+	})
+	return nil
+}
+
+// Stop end the watch of the pods.
+func (discoverer *KubernetesDiscoverer) Stop() {
+	discoverer.mutex.Lock()
+	defer discoverer.mutex.Unlock()
+	if discoverer.cancel != nil {
+		discoverer.cancel()
+		discoverer.cancel = nil
+	}
+}
+
+type pod struct {
+	Metadata struct {
+		Name              string            `json:"name"`
+		Annotations       map[string]string `json:"annotations"`
+		DeletionTimestamp string            `json:"deletionTimestamp"`
+	} `json:"metadata"`
+	Status struct {
+		PodIP      string `json:"podIP"`
+		Conditions []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// ready check if the pod can receive packets: it is ready and is not being deleted.
+func (pod pod) ready() bool {
+	if pod.Metadata.DeletionTimestamp != "" || pod.Status.PodIP == "" {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == "Ready" {
+			return condition.Status == "True"
+		}
+	}
+	return false
+}
+
+type podList struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Items []pod `json:"items"`
+}
+
+type podEvent struct {
+	Type   string          `json:"type"`
+	Object json.RawMessage `json:"object"`
+}
+
+func (discoverer *KubernetesDiscoverer) request(ctx context.Context, query url.Values) (*http.Response, error) {
+	if discoverer.options.LabelSelector != "" {
+		query.Set("labelSelector", discoverer.options.LabelSelector)
+	}
+	address := fmt.Sprint(strings.TrimSuffix(discoverer.options.APIServer, "/"), "/api/v1/namespaces/", discoverer.options.Namespace, "/pods?", query.Encode())
+	request, err := http.NewRequest(http.MethodGet, address, nil)
+	if err != nil {
+		return nil, err
+	}
+	if discoverer.options.Token != "" {
+		request.Header.Set("Authorization", "Bearer "+discoverer.options.Token)
+	}
+	response, err := discoverer.options.Client.Do(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode != http.StatusOK {
+		response.Body.Close()
+		return nil, fmt.Errorf("Kubernetes API error - status: %s", response.Status)
+	}
+	return response, nil
+}
+
+// list read the pods, reporting the peers found and lost since the last list, and return
+// the resource version to watch from.
+func (discoverer *KubernetesDiscoverer) list(ctx context.Context) (string, error) {
+	response, err := discoverer.request(ctx, url.Values{})
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	var list podList
+	if err := json.NewDecoder(response.Body).Decode(&list); err != nil {
+		return "", err
+	}
+	peers := []Peer{}
+	for _, item := range list.Items {
+		if peer, ready := discoverer.peer(item); ready {
+			peers = append(peers, peer)
+		}
+	}
+	discoverer.peers.replace(peers)
+	return list.Metadata.ResourceVersion, nil
+}
+
+// watch apply the changes of the pods since the resource version, until the API server ends the watch.
+func (discoverer *KubernetesDiscoverer) watch(ctx context.Context, resourceVersion string) error {
+	response, err := discoverer.request(ctx, url.Values{"watch": {"true"}, "resourceVersion": {resourceVersion}})
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	decoder := json.NewDecoder(response.Body)
+	for {
+		var event podEvent
+		if err := decoder.Decode(&event); err != nil {
+			if ctx.Err() != nil || err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		if event.Type == "ERROR" {
+			return fmt.Errorf("Kubernetes API watch error: %s", event.Object)
+		}
+		var item pod
+		if err := json.Unmarshal(event.Object, &item); err != nil {
+			return err
+		}
+		peer, ready := discoverer.peer(item)
+		if event.Type != "DELETED" && ready {
+			discoverer.peers.add(peer)
+		} else {
+			discoverer.peers.remove(item.Metadata.Name)
+		}
+	}
+}
+
+// peer return the peer of the pod and if it is ready. The local pod is never ready, since it is not a peer.
+func (discoverer *KubernetesDiscoverer) peer(item pod) (Peer, bool) {
+	peer := Peer{
+		Name:    item.Metadata.Name,
+		NodeID:  item.Metadata.Annotations[discoverer.options.NodeIDAnnotation],
+		Address: item.Status.PodIP,
+	}
+	return peer, item.ready() && peer.Name != discoverer.options.Hostname
+}
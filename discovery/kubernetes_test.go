@@ -0,0 +1,95 @@
+package discovery
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+func podJSON(name, nodeID string, ready bool) string {
+	status := "False"
+	if ready {
+		status = "True"
+	}
+	return fmt.Sprintf(`{"metadata":{"name":"%s","annotations":{"moleculer.services/node-id":"%s"}},`+
+		`"status":{"podIP":"10.0.0.1","conditions":[{"type":"Ready","status":"%s"}]}}`, name, nodeID, status)
+}
+
+var _ = Describe("Kubernetes Discoverer", func() {
+	logger := log.WithField("unit test pkg", "discovery_test")
+
+	It("should parse the discoverer url", func() {
+		discoverer, exists := Create("kubernetes://10.0.0.1:6443?namespace=prod&labelSelector=app%3Dusers&retryDelay=1s", "node-1", logger)
+		Expect(exists).Should(BeTrue())
+		options := discoverer.(*KubernetesDiscoverer).options
+		Expect(options.APIServer).Should(Equal("https://10.0.0.1:6443"))
+		Expect(options.Namespace).Should(Equal("prod"))
+		Expect(options.LabelSelector).Should(Equal("app=users"))
+		Expect(options.NodeIDAnnotation).Should(Equal(DefaultNodeIDAnnotation))
+		Expect(options.RetryDelay).Should(Equal(time.Second))
+
+		_, exists = Create("zookeeper://localhost:2181", "node-1", logger)
+		Expect(exists).Should(BeFalse())
+	})
+
+	It("should fail to start outside of a cluster without an API server", func() {
+		Expect(Kubernetes(KubernetesOptions{Logger: logger}).Start(func(Peer) {}, func(Peer) {})).Should(HaveOccurred())
+	})
+
+	It("should report the ready pods found and lost while watching", func() {
+		events := make(chan string, 10)
+		var requests []string
+		var requestsMutex sync.Mutex
+		server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			requestsMutex.Lock()
+			requests = append(requests, request.URL.RawQuery+" "+request.Header.Get("Authorization"))
+			requestsMutex.Unlock()
+			if request.URL.Query().Get("watch") != "true" {
+				fmt.Fprint(writer, `{"metadata":{"resourceVersion":"10"},"items":[`,
+					podJSON("users-1", "node-1", true), ",",
+					podJSON("users-2", "node-2", false), ",",
+					podJSON("users-self", "node-self", true), "]}")
+				return
+			}
+			flusher := writer.(http.Flusher)
+			fmt.Fprintln(writer, `{"type":"MODIFIED","object":`+podJSON("users-2", "node-2", true)+`}`)
+			fmt.Fprintln(writer, `{"type":"DELETED","object":`+podJSON("users-1", "node-1", true)+`}`)
+			flusher.Flush()
+			<-request.Context().Done()
+		}))
+		defer server.Close()
+
+		discoverer := Kubernetes(KubernetesOptions{
+			APIServer:     server.URL,
+			Token:         "secret",
+			Namespace:     "prod",
+			LabelSelector: "app=users",
+			Hostname:      "users-self",
+			Client:        server.Client(),
+			Logger:        logger,
+		})
+		err := discoverer.Start(
+			func(peer Peer) { events <- "found " + peer.Name + " " + peer.NodeID + " " + peer.Address },
+			func(peer Peer) { events <- "lost " + peer.Name + " " + peer.NodeID },
+		)
+		Expect(err).ShouldNot(HaveOccurred())
+
+		Eventually(events).Should(Receive(Equal("found users-1 node-1 10.0.0.1")))
+		Eventually(events).Should(Receive(Equal("found users-2 node-2 10.0.0.1")))
+		Eventually(events).Should(Receive(Equal("lost users-1 node-1")))
+		discoverer.Stop()
+		Consistently(events).ShouldNot(Receive())
+
+		requestsMutex.Lock()
+		defer requestsMutex.Unlock()
+		Expect(requests).Should(HaveLen(2))
+		Expect(requests[0]).Should(Equal("labelSelector=app%3Dusers Bearer secret"))
+		Expect(requests[1]).Should(Equal("labelSelector=app%3Dusers&resourceVersion=10&watch=true Bearer secret"))
+	})
+})
@@ -0,0 +1,91 @@
+package discovery
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// peerSet keep the peers found by a discoverer, so each peer is reported once when it is
+// found, again when it changes, and once when it is lost.
+type peerSet struct {
+	mutex  sync.Mutex
+	peers  map[string]Peer
+	found  func(peer Peer)
+	lost   func(peer Peer)
+	logger *log.Entry
+}
+
+func newPeerSet(logger *log.Entry) *peerSet {
+	return &peerSet{peers: map[string]Peer{}, logger: logger}
+}
+
+func (set *peerSet) listen(found func(peer Peer), lost func(peer Peer)) {
+	set.mutex.Lock()
+	defer set.mutex.Unlock()
+	set.found = found
+	set.lost = lost
+}
+
+// add report the peer as found when it is new or changed.
+func (set *peerSet) add(peer Peer) {
+	set.mutex.Lock()
+	current, exists := set.peers[peer.Name]
+	set.peers[peer.Name] = peer
+	found := set.found
+	set.mutex.Unlock()
+	if (!exists || current != peer) && found != nil {
+		set.logger.Debug("Discoverer - peer found: ", peer.Name, " nodeID: ", peer.NodeID)
+		found(peer)
+	}
+}
+
+// remove report the peer as lost when it was found before.
+func (set *peerSet) remove(name string) {
+	set.mutex.Lock()
+	peer, exists := set.peers[name]
+	delete(set.peers, name)
+	lost := set.lost
+	set.mutex.Unlock()
+	if exists && lost != nil {
+		set.logger.Debug("Discoverer - peer lost: ", name, " nodeID: ", peer.NodeID)
+		lost(peer)
+	}
+}
+
+// replace add the peers and remove the ones that are not in the list.
+func (set *peerSet) replace(peers []Peer) {
+	listed := map[string]bool{}
+	for _, peer := range peers {
+		listed[peer.Name] = true
+		set.add(peer)
+	}
+	set.mutex.Lock()
+	removed := []string{}
+	for name := range set.peers {
+		if !listed[name] {
+			removed = append(removed, name)
+		}
+	}
+	set.mutex.Unlock()
+	for _, name := range removed {
+		set.remove(name)
+	}
+}
+
+// watchUntilDone call the watch function until the context is done. The watch is called again right
+// away when it ends without an error, e.g. after a server timeout, and after the retry delay otherwise.
+func watchUntilDone(ctx context.Context, retryDelay time.Duration, logger *log.Entry, watch func(ctx context.Context) error) {
+	for ctx.Err() == nil {
+		err := watch(ctx)
+		if err != nil && ctx.Err() == nil {
+			logger.Warn("Discoverer - error watching the peers: ", err, " - retrying in ", retryDelay)
+			select {
+			case <-ctx.Done():
+			case <-time.After(retryDelay):
+			}
+		}
+	}
+}
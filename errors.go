@@ -0,0 +1,163 @@
+package moleculer
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Error is the error sent between nodes, equivalent to the moleculer-js MoleculerError.
+// Errors returned by remote actions are received as *Error, so callers can check
+// the code, type and data of the failure.
+type Error struct {
+	Name       string
+	Message    string
+	Code       int
+	Type       string
+	Data       interface{}
+	Retryable  bool
+	NodeID     string
+	StackTrace string
+}
+
+// NewError create an error with the code, type and data.
+func NewError(message string, code int, errorType string, data interface{}) *Error {
+	return &Error{
+		Name:    "MoleculerError",
+		Message: message,
+		Code:    code,
+		Type:    errorType,
+		Data:    data,
+	}
+}
+
+// NewRetryableError create an error that signals the caller the request can be retried.
+func NewRetryableError(message string, code int, errorType string, data interface{}) *Error {
+	err := NewError(message, code, errorType, data)
+	err.Name = "MoleculerRetryableError"
+	err.Retryable = true
+	return err
+}
+
+// NewRequestTimeoutError create the retryable error of a call that did not finish before its timeout.
+func NewRequestTimeoutError(action, nodeID string) *Error {
+	err := NewRetryableError(
+		fmt.Sprintf("RequestTimedOut: Request is timed out when call '%s' action on '%s' node.", action, nodeID),
+		504, "REQUEST_TIMEOUT", map[string]interface{}{"action": action, "nodeID": nodeID})
+	err.Name = "RequestTimeoutError"
+	return err
+}
+
+// NewMaxCallLevelError create the error of a call chain deeper than Config.MaxCallLevel,
+// usually caused by actions calling each other in a loop.
+func NewMaxCallLevelError(nodeID string, level int) *Error {
+	err := NewError(fmt.Sprintf("Request level is reached the limit (%d) on '%s' node.", level, nodeID),
+		500, "MAX_CALL_LEVEL", map[string]interface{}{"nodeID": nodeID, "level": level})
+	err.Name = "MaxCallLevelError"
+	return err
+}
+
+// IsRetryable check if the error signals the request can be retried, like the errors
+// created with NewRetryableError or received from another node with retryable true.
+func IsRetryable(err error) bool {
+	exporter, ok := err.(ErrorExporter)
+	return ok && exporter.MoleculerError().Retryable
+}
+
+// ErrBrokerNotStarted is the error of the calls made and events emitted before the broker is started.
+var ErrBrokerNotStarted = &Error{
+	Name:    "BrokerNotStartedError",
+	Message: "Broker must be started before making calls or emitting events.",
+	Code:    503,
+	Type:    "BROKER_NOT_STARTED",
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// ErrorExporter is implemented by error types that are sent to the caller with the fields of an *Error.
+type ErrorExporter interface {
+	MoleculerError() *Error
+}
+
+// MoleculerError return the error itself.
+func (e *Error) MoleculerError() *Error {
+	return e
+}
+
+func (e *Error) Stack() string {
+	return e.StackTrace
+}
+
+// Map export the error fields using the moleculer-js names. Empty optional fields are omitted.
+func (e *Error) Map() map[string]interface{} {
+	values := map[string]interface{}{
+		"name":      e.Name,
+		"message":   e.Message,
+		"retryable": e.Retryable,
+		"nodeID":    e.NodeID,
+	}
+	if e.Code != 0 {
+		values["code"] = e.Code
+	}
+	if e.Type != "" {
+		values["type"] = e.Type
+	}
+	if e.Data != nil {
+		values["data"] = e.Data
+	}
+	if e.StackTrace != "" {
+		values["stack"] = e.StackTrace
+	}
+	return values
+}
+
+// ErrorFromMap create the error from the fields received from another node.
+func ErrorFromMap(values map[string]interface{}) *Error {
+	err := &Error{Name: "Error", Data: values["data"]}
+	if name, ok := values["name"].(string); ok && name != "" {
+		err.Name = name
+	}
+	err.Message, _ = values["message"].(string)
+	err.Type, _ = values["type"].(string)
+	err.NodeID, _ = values["nodeID"].(string)
+	err.StackTrace, _ = values["stack"].(string)
+	err.Retryable, _ = values["retryable"].(bool)
+	switch code := values["code"].(type) {
+	case float64:
+		err.Code = int(code)
+	case int:
+		err.Code = code
+	case int64:
+		err.Code = int(code)
+	}
+	return err
+}
+
+// ErrorFactory create a typed error from the error received from another node.
+type ErrorFactory func(err *Error) error
+
+var errorFactories = map[string]ErrorFactory{}
+var errorFactoriesMutex = &sync.RWMutex{}
+
+// RegisterError register the factory for the error name. Remote errors with the name
+// are created by the factory, so callers receive the typed error. Error types should
+// implement ErrorExporter, so their fields are also sent when they are returned by an action.
+func RegisterError(name string, factory ErrorFactory) {
+	errorFactoriesMutex.Lock()
+	defer errorFactoriesMutex.Unlock()
+	errorFactories[name] = factory
+}
+
+// ParseError create the error from the fields received from another node, using the
+// factory registered for the error name. Errors without a factory are returned as *Error.
+func ParseError(values map[string]interface{}) error {
+	err := ErrorFromMap(values)
+	errorFactoriesMutex.RLock()
+	factory, exists := errorFactories[err.Name]
+	errorFactoriesMutex.RUnlock()
+	if exists {
+		return factory(err)
+	}
+	return err
+}
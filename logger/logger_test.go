@@ -0,0 +1,25 @@
+package logger
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+)
+
+var _ = Describe("Logger adapters", func() {
+	fields := map[string]interface{}{"broker": "node-1", "service": "users"}
+
+	It("Should send the entries to a logrus logger", func() {
+		logger, hook := test.NewNullLogger()
+		logger.SetLevel(log.DebugLevel)
+		Logrus(logger).Log(log.WarnLevel, "disk almost full", fields)
+		Logrus(logger).Log(log.TraceLevel, "ignored", fields)
+
+		Expect(hook.AllEntries()).Should(HaveLen(1))
+		Expect(hook.LastEntry().Level).Should(Equal(log.WarnLevel))
+		Expect(hook.LastEntry().Message).Should(Equal("disk almost full"))
+		Expect(hook.LastEntry().Data).Should(Equal(log.Fields(fields)))
+	})
+
+})
@@ -0,0 +1,26 @@
+// Package logger contains the adapters to send the broker logs to other logging libraries,
+// using the Config.Logger field:
+//
+//	broker.New(&moleculer.Config{Logger: logger.Logrus(logrusLogger)})
+//
+// The zap and zerolog adapters are in the zaplogger and zerologger modules, so their libraries
+// are not dependencies of the broker.
+package logger
+
+import (
+	"github.com/moleculer-go/moleculer"
+	log "github.com/sirupsen/logrus"
+)
+
+type logrusLogger struct {
+	logger *log.Logger
+}
+
+// Logrus send the broker logs to a logrus logger, so the broker uses the same output, format and hooks of the application.
+func Logrus(logger *log.Logger) moleculer.Logger {
+	return &logrusLogger{logger}
+}
+
+func (l *logrusLogger) Log(level log.Level, message string, fields map[string]interface{}) {
+	l.logger.WithFields(fields).Log(level, message)
+}
@@ -0,0 +1,15 @@
+module github.com/moleculer-go/moleculer/logger/zaplogger
+
+go 1.12
+
+require (
+	github.com/moleculer-go/moleculer v0.0.0
+	github.com/onsi/ginkgo v1.8.0
+	github.com/onsi/gomega v1.5.0
+	github.com/sirupsen/logrus v1.4.1
+	go.uber.org/atomic v1.4.0 // indirect
+	go.uber.org/multierr v1.1.0 // indirect
+	go.uber.org/zap v1.10.0
+)
+
+replace github.com/moleculer-go/moleculer => ../..
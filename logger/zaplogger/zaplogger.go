@@ -0,0 +1,59 @@
+// Package zaplogger send the broker logs to a zap logger. It is a separate module, so zap is only
+// downloaded by the applications using it:
+//
+//	broker.New(&moleculer.Config{Logger: zaplogger.New(zapLogger)})
+package zaplogger
+
+import (
+	"sort"
+
+	"github.com/moleculer-go/moleculer"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type zapLogger struct {
+	logger *zap.Logger
+}
+
+// New send the broker logs to a zap logger. The trace level is logged as debug.
+func New(logger *zap.Logger) moleculer.Logger {
+	return &zapLogger{logger}
+}
+
+func (l *zapLogger) Log(level log.Level, message string, fields map[string]interface{}) {
+	if entry := l.logger.Check(zapLevel(level), message); entry != nil {
+		entry.Write(zapFields(fields)...)
+	}
+}
+
+func zapLevel(level log.Level) zapcore.Level {
+	switch level {
+	case log.PanicLevel:
+		return zapcore.PanicLevel
+	case log.FatalLevel:
+		return zapcore.FatalLevel
+	case log.ErrorLevel:
+		return zapcore.ErrorLevel
+	case log.WarnLevel:
+		return zapcore.WarnLevel
+	case log.InfoLevel:
+		return zapcore.InfoLevel
+	}
+	return zapcore.DebugLevel
+}
+
+// zapFields convert the fields sorted by name, so the output is always in the same order.
+func zapFields(fields map[string]interface{}) []zap.Field {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]zap.Field, len(names))
+	for index, name := range names {
+		result[index] = zap.Any(name, fields[name])
+	}
+	return result
+}
@@ -0,0 +1,13 @@
+package zaplogger
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestZaplogger(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Zaplogger Suite")
+}
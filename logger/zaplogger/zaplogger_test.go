@@ -0,0 +1,28 @@
+package zaplogger
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var _ = Describe("Zap adapter", func() {
+	fields := map[string]interface{}{"broker": "node-1", "service": "users"}
+
+	It("Should send the entries to a zap logger", func() {
+		core, logs := observer.New(zapcore.DebugLevel)
+		logger := New(zap.New(core))
+		logger.Log(log.ErrorLevel, "call failed", fields)
+		logger.Log(log.TraceLevel, "trace as debug", nil)
+
+		entries := logs.AllUntimed()
+		Expect(entries).Should(HaveLen(2))
+		Expect(entries[0].Level).Should(Equal(zapcore.ErrorLevel))
+		Expect(entries[0].Message).Should(Equal("call failed"))
+		Expect(entries[0].ContextMap()).Should(Equal(fields))
+		Expect(entries[1].Level).Should(Equal(zapcore.DebugLevel))
+	})
+})
@@ -0,0 +1,13 @@
+module github.com/moleculer-go/moleculer/logger/zerologger
+
+go 1.12
+
+require (
+	github.com/moleculer-go/moleculer v0.0.0
+	github.com/onsi/ginkgo v1.8.0
+	github.com/onsi/gomega v1.5.0
+	github.com/rs/zerolog v1.14.3
+	github.com/sirupsen/logrus v1.4.1
+)
+
+replace github.com/moleculer-go/moleculer => ../..
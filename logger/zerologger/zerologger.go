@@ -0,0 +1,40 @@
+// Package zerologger send the broker logs to a zerolog logger. It is a separate module, so zerolog
+// is only downloaded by the applications using it:
+//
+//	broker.New(&moleculer.Config{Logger: zerologger.New(zerologLogger)})
+package zerologger
+
+import (
+	"github.com/moleculer-go/moleculer"
+	"github.com/rs/zerolog"
+	log "github.com/sirupsen/logrus"
+)
+
+type zerologLogger struct {
+	logger zerolog.Logger
+}
+
+// New send the broker logs to a zerolog logger. The trace level is logged as debug.
+func New(logger zerolog.Logger) moleculer.Logger {
+	return &zerologLogger{logger}
+}
+
+func (l *zerologLogger) Log(level log.Level, message string, fields map[string]interface{}) {
+	l.logger.WithLevel(zerologLevel(level)).Fields(fields).Msg(message)
+}
+
+func zerologLevel(level log.Level) zerolog.Level {
+	switch level {
+	case log.PanicLevel:
+		return zerolog.PanicLevel
+	case log.FatalLevel:
+		return zerolog.FatalLevel
+	case log.ErrorLevel:
+		return zerolog.ErrorLevel
+	case log.WarnLevel:
+		return zerolog.WarnLevel
+	case log.InfoLevel:
+		return zerolog.InfoLevel
+	}
+	return zerolog.DebugLevel
+}
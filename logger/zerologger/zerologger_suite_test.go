@@ -0,0 +1,13 @@
+package zerologger
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestZerologger(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Zerologger Suite")
+}
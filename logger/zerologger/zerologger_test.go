@@ -0,0 +1,31 @@
+package zerologger
+
+import (
+	"bytes"
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	"github.com/rs/zerolog"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Zerolog adapter", func() {
+	fields := map[string]interface{}{"broker": "node-1", "service": "users"}
+
+	It("Should send the entries to a zerolog logger", func() {
+		buffer := &bytes.Buffer{}
+		logger := New(zerolog.New(buffer).Level(zerolog.InfoLevel))
+		logger.Log(log.InfoLevel, "broker started", fields)
+		logger.Log(log.DebugLevel, "ignored", fields)
+
+		entry := map[string]interface{}{}
+		Expect(json.Unmarshal(buffer.Bytes(), &entry)).Should(Succeed())
+		Expect(entry).Should(Equal(map[string]interface{}{
+			"level":   "info",
+			"message": "broker started",
+			"broker":  "node-1",
+			"service": "users",
+		}))
+	})
+})
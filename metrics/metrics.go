@@ -80,6 +80,7 @@ func createShouldMetric(Config moleculer.Config) func(context moleculer.BrokerCo
 func Middlewares() moleculer.Middlewares {
 	var Config = moleculer.DefaultConfig
 	shouldMetric := createShouldMetric(Config)
+	reporter := transitReporter{metrics: CreateTransitMetrics()}
 	return map[string]moleculer.MiddlewareHandler{
 		// store the broker config
 		"Config": func(params interface{}, next func(...interface{})) {
@@ -103,5 +104,23 @@ func Middlewares() moleculer.Middlewares {
 			}
 			next()
 		},
+		"transitPacketSent": func(params interface{}, next func(...interface{})) {
+			reporter.metrics.packetSent(params.(middleware.TransitPacketParams))
+			next()
+		},
+		"transitPacketReceived": func(params interface{}, next func(...interface{})) {
+			reporter.metrics.packetReceived(params.(middleware.TransitPacketParams))
+			next()
+		},
+		"brokerStarted": func(params interface{}, next func(...interface{})) {
+			if Config.Metrics && Config.TransitMetricsInterval > 0 {
+				reporter.start(params.(*moleculer.BrokerDelegates), Config.TransitMetricsInterval)
+			}
+			next()
+		},
+		"brokerStopping": func(params interface{}, next func(...interface{})) {
+			reporter.close()
+			next()
+		},
 	}
 }
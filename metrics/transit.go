@@ -0,0 +1,160 @@
+package metrics
+
+import (
+	"sync"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/middleware"
+)
+
+// serializationBuckets are the upper bounds of the serialization time histogram.
+var serializationBuckets = []time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+}
+
+// Histogram count the observed durations in buckets.
+type Histogram struct {
+	buckets []time.Duration
+	counts  []int64
+	count   int64
+	sum     time.Duration
+}
+
+func createHistogram(buckets []time.Duration) *Histogram {
+	return &Histogram{buckets: buckets, counts: make([]int64, len(buckets)+1)}
+}
+
+func (histogram *Histogram) observe(value time.Duration) {
+	index := len(histogram.buckets)
+	for i, bucket := range histogram.buckets {
+		if value <= bucket {
+			index = i
+			break
+		}
+	}
+	histogram.counts[index]++
+	histogram.count++
+	histogram.sum += value
+}
+
+// export the histogram with the count of each bucket, keyed by the upper bound ("+Inf" for the last).
+func (histogram *Histogram) export() map[string]interface{} {
+	buckets := map[string]int64{}
+	for i, bucket := range histogram.buckets {
+		buckets[bucket.String()] = histogram.counts[i]
+	}
+	buckets["+Inf"] = histogram.counts[len(histogram.buckets)]
+	return map[string]interface{}{
+		"count":   histogram.count,
+		"sum":     float64(histogram.sum.Nanoseconds()) / 1000000,
+		"buckets": buckets,
+	}
+}
+
+// TransitMetrics count the packets and bytes sent and received by transit.
+type TransitMetrics struct {
+	mutex           sync.Mutex
+	packetsSent     map[string]int64
+	packetsReceived map[string]int64
+	bytesSent       int64
+	bytesReceived   int64
+	serialization   *Histogram
+}
+
+func CreateTransitMetrics() *TransitMetrics {
+	return &TransitMetrics{
+		packetsSent:     map[string]int64{},
+		packetsReceived: map[string]int64{},
+		serialization:   createHistogram(serializationBuckets),
+	}
+}
+
+func (metrics *TransitMetrics) packetSent(packet middleware.TransitPacketParams) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.packetsSent[packet.Command]++
+	metrics.bytesSent += int64(packet.Bytes)
+	metrics.serialization.observe(packet.Duration)
+}
+
+func (metrics *TransitMetrics) packetReceived(packet middleware.TransitPacketParams) {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	metrics.packetsReceived[packet.Command]++
+	metrics.bytesReceived += int64(packet.Bytes)
+}
+
+// Export return the current values. Durations are in milliseconds.
+func (metrics *TransitMetrics) Export() map[string]interface{} {
+	metrics.mutex.Lock()
+	defer metrics.mutex.Unlock()
+	return map[string]interface{}{
+		"packets": map[string]interface{}{
+			"sent":     copyCounters(metrics.packetsSent),
+			"received": copyCounters(metrics.packetsReceived),
+		},
+		"bytes": map[string]int64{
+			"sent":     metrics.bytesSent,
+			"received": metrics.bytesReceived,
+		},
+		"serialization": metrics.serialization.export(),
+	}
+}
+
+func copyCounters(counters map[string]int64) map[string]int64 {
+	result := make(map[string]int64, len(counters))
+	for command, value := range counters {
+		result[command] = value
+	}
+	return result
+}
+
+// transitReporter emit the metrics.transit event with the transit metrics of the node on each interval.
+// The stop channel is guarded by the mutex, since the broker starts and stops from different goroutines.
+type transitReporter struct {
+	metrics *TransitMetrics
+	mutex   sync.Mutex
+	stop    chan bool
+}
+
+func (reporter *transitReporter) start(delegates *moleculer.BrokerDelegates, interval time.Duration) {
+	stop := make(chan bool)
+	reporter.mutex.Lock()
+	reporter.stop = stop
+	reporter.mutex.Unlock()
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				reporter.report(delegates)
+			}
+		}
+	}()
+}
+
+func (reporter *transitReporter) report(delegates *moleculer.BrokerDelegates) {
+	values := reporter.metrics.Export()
+	values["nodeID"] = delegates.LocalNode().GetID()
+	delegates.BrokerContext().Emit("metrics.transit", values)
+}
+
+func (reporter *transitReporter) close() {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	if reporter.stop != nil {
+		close(reporter.stop)
+		reporter.stop = nil
+	}
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/context"
+	"github.com/moleculer-go/moleculer/middleware"
+	"github.com/moleculer-go/moleculer/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transit metrics", func() {
+
+	It("should count the packets per command, bytes and serialization time", func() {
+		metrics := CreateTransitMetrics()
+		metrics.packetSent(middleware.TransitPacketParams{Command: "REQ", NodeID: "node-2", Bytes: 100, Duration: 20 * time.Microsecond})
+		metrics.packetSent(middleware.TransitPacketParams{Command: "REQ", NodeID: "node-2", Bytes: 50, Duration: time.Second})
+		metrics.packetSent(middleware.TransitPacketParams{Command: "EVENT", Bytes: 10, Duration: 5 * time.Microsecond})
+		metrics.packetReceived(middleware.TransitPacketParams{Command: "RES", NodeID: "node-2", Bytes: 30})
+
+		values := metrics.Export()
+		Expect(values["packets"]).Should(Equal(map[string]interface{}{
+			"sent":     map[string]int64{"REQ": 2, "EVENT": 1},
+			"received": map[string]int64{"RES": 1},
+		}))
+		Expect(values["bytes"]).Should(Equal(map[string]int64{"sent": 160, "received": 30}))
+
+		serialization := values["serialization"].(map[string]interface{})
+		Expect(serialization["count"]).Should(Equal(int64(3)))
+		buckets := serialization["buckets"].(map[string]int64)
+		Expect(buckets["10µs"]).Should(Equal(int64(1)))
+		Expect(buckets["50µs"]).Should(Equal(int64(1)))
+		Expect(buckets["+Inf"]).Should(Equal(int64(1)))
+	})
+
+	It("should update the metrics from the middlewares and emit them on each interval", func() {
+		events := make(chan moleculer.Payload, 10)
+		delegates := test.DelegatesWithIdAndConfig("nodex", moleculer.Config{})
+		delegates.EmitEvent = func(context moleculer.BrokerContext) {
+			Expect(context.EventName()).Should(Equal("metrics.transit"))
+			events <- context.Payload()
+		}
+		delegates.BrokerContext = func() moleculer.BrokerContext {
+			return context.BrokerContext(delegates)
+		}
+		dispatcher := middleware.Dispatcher(delegates.Logger("middleware", "dispatcher"))
+		dispatcher.Add(Middlewares())
+		dispatcher.CallHandlers("Config", moleculer.Config{Metrics: true, MetricsRate: 1, TransitMetricsInterval: 10 * time.Millisecond})
+		dispatcher.CallHandlers("transitPacketSent", middleware.TransitPacketParams{Command: "REQ", Bytes: 100})
+		dispatcher.CallHandlers("transitPacketReceived", middleware.TransitPacketParams{Command: "RES", Bytes: 40})
+		dispatcher.CallHandlers("brokerStarted", delegates)
+
+		var event moleculer.Payload
+		Eventually(events).Should(Receive(&event))
+		Expect(event.Get("nodeID").String()).Should(Equal("nodex"))
+		Expect(event.Get("packets").Get("sent").Get("REQ").Int()).Should(Equal(1))
+		Expect(event.Get("packets").Get("received").Get("RES").Int()).Should(Equal(1))
+		Expect(event.Get("bytes").Get("sent").Int()).Should(Equal(100))
+
+		dispatcher.CallHandlers("brokerStopping", delegates)
+	})
+})
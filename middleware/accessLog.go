@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	log "github.com/sirupsen/logrus"
+)
+
+// AccessLog return middlewares that log every action invocation with the fields: requestID, action,
+// caller (the node that made the call), nodeID (the node running the action), duration (in milliseconds)
+// and error. The start is logged in the debug level and the end in the info level, or in the error
+// level when the action fails. It is added by the broker when Config.AccessLog is enabled.
+func AccessLog() moleculer.Middlewares {
+	var nodeID string
+	startTimes := sync.Map{}
+	return moleculer.Middlewares{
+		"brokerStarting": func(params interface{}, next func(...interface{})) {
+			nodeID = params.(*moleculer.BrokerDelegates).LocalNode().GetID()
+			next()
+		},
+		"beforeLocalAction": func(params interface{}, next func(...interface{})) {
+			context := params.(moleculer.BrokerContext)
+			startTimes.Store(context.ID(), time.Now())
+			accessLogEntry(context, nodeID).Debug("Action started")
+			next()
+		},
+		"afterLocalAction": func(params interface{}, next func(...interface{})) {
+			context := params.(AfterActionParams).BrokerContext
+			result := params.(AfterActionParams).Result
+			entry := accessLogEntry(context, nodeID)
+			if start, exists := startTimes.Load(context.ID()); exists {
+				startTimes.Delete(context.ID())
+				entry = entry.WithField("duration", float64(time.Since(start.(time.Time)))/float64(time.Millisecond))
+			}
+			if result.IsError() {
+				entry.WithField("error", result.Error().Error()).Error("Action failed")
+			} else {
+				entry.Info("Action finished")
+			}
+			next()
+		},
+	}
+}
+
+// accessLogEntry create the log entry with the fields of the action context.
+func accessLogEntry(context moleculer.BrokerContext, nodeID string) *log.Entry {
+	caller := nodeID
+	if source, ok := context.(interface{ SourceNodeID() string }); ok && source.SourceNodeID() != "" {
+		caller = source.SourceNodeID()
+	}
+	return context.Logger().WithFields(log.Fields{
+		"requestID": context.RequestID(),
+		"action":    context.ActionName(),
+		"caller":    caller,
+		"nodeID":    nodeID,
+	})
+}
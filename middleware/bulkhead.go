@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// Bulkhead return the localAction middleware that limits the concurrent executions of each action,
+// according to the policy of the config and the Bulkhead of the action schema, which takes precedence.
+// Calls over the concurrency limit wait in a queue and are rejected with a retryable QUEUE_FULL
+// error when the queue is full. It is added by the broker.
+func Bulkhead(config moleculer.BulkheadPolicy) moleculer.Middlewares {
+	return moleculer.Middlewares{
+		"localAction": func(params interface{}, next func(...interface{})) {
+			action := params.(ActionHandlerParams)
+			policy := bulkheadPolicy(config, action.Bulkhead)
+			if !policy.Enabled || policy.Concurrency <= 0 {
+				next()
+				return
+			}
+			limiter := &bulkhead{policy: policy}
+			handler := action.Handler
+			action.Handler = func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+				if !limiter.acquire() {
+					return queueFullError(action.Action, action.NodeID)
+				}
+				defer limiter.release()
+				return handler(ctx, params)
+			}
+			next(action)
+		},
+	}
+}
+
+// bulkheadPolicy return the action policy with the zero limits replaced by the config limits.
+func bulkheadPolicy(config moleculer.BulkheadPolicy, action *moleculer.BulkheadPolicy) moleculer.BulkheadPolicy {
+	if action == nil {
+		return config
+	}
+	policy := *action
+	if policy.Concurrency == 0 {
+		policy.Concurrency = config.Concurrency
+	}
+	if policy.MaxQueueSize == 0 {
+		policy.MaxQueueSize = config.MaxQueueSize
+	}
+	return policy
+}
+
+func queueFullError(action, nodeID string) error {
+	err := moleculer.NewRetryableError(fmt.Sprintf("Queue is full. Request '%s' action on '%s' node is rejected.", action, nodeID),
+		429, "QUEUE_FULL", map[string]interface{}{"action": action, "nodeID": nodeID})
+	err.Name = "QueueIsFullError"
+	return err
+}
+
+// bulkhead count the executions in flight and the queued calls of an action.
+type bulkhead struct {
+	policy   moleculer.BulkheadPolicy
+	mutex    sync.Mutex
+	inFlight int
+	queue    []chan struct{}
+}
+
+// acquire an execution slot, waiting in the queue when all slots are in use.
+// It returns false when the queue is full.
+func (limiter *bulkhead) acquire() bool {
+	limiter.mutex.Lock()
+	if limiter.inFlight < limiter.policy.Concurrency {
+		limiter.inFlight++
+		limiter.mutex.Unlock()
+		return true
+	}
+	if len(limiter.queue) >= limiter.policy.MaxQueueSize {
+		limiter.mutex.Unlock()
+		return false
+	}
+	ready := make(chan struct{})
+	limiter.queue = append(limiter.queue, ready)
+	limiter.mutex.Unlock()
+	<-ready
+	return true
+}
+
+// release the execution slot, handing it over to the first queued call.
+func (limiter *bulkhead) release() {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	if len(limiter.queue) > 0 {
+		close(limiter.queue[0])
+		limiter.queue = limiter.queue[1:]
+		return
+	}
+	limiter.inFlight--
+}
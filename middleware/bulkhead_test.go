@@ -0,0 +1,82 @@
+package middleware
+
+import (
+	"sync"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Bulkhead", func() {
+
+	wrap := func(config moleculer.BulkheadPolicy, action *moleculer.BulkheadPolicy, handler moleculer.ActionHandler) moleculer.ActionHandler {
+		dispatcher := Dispatcher(createLogger("midlewares", "dispatcher"))
+		dispatcher.Add(Bulkhead(config))
+		params := dispatcher.CallHandlers("localAction", ActionHandlerParams{
+			Action:   "math.add",
+			NodeID:   "node-1",
+			Handler:  handler,
+			Bulkhead: action,
+		})
+		return params.(ActionHandlerParams).Handler
+	}
+
+	It("should limit the concurrent executions, queue the calls and reject them when the queue is full", func() {
+		var mutex sync.Mutex
+		running, maxRunning := 0, 0
+		release := make(chan bool)
+		handler := wrap(moleculer.BulkheadPolicy{Enabled: true, Concurrency: 2, MaxQueueSize: 1}, nil,
+			func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+				mutex.Lock()
+				running++
+				if running > maxRunning {
+					maxRunning = running
+				}
+				mutex.Unlock()
+				<-release
+				mutex.Lock()
+				running--
+				mutex.Unlock()
+				return "done"
+			})
+
+		results := make(chan interface{}, 4)
+		for i := 0; i < 3; i++ {
+			go func() {
+				results <- handler(nil, nil)
+			}()
+		}
+		Eventually(func() int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return running
+		}).Should(Equal(2))
+		time.Sleep(20 * time.Millisecond)
+
+		rejected := handler(nil, nil)
+		Expect(rejected).Should(BeAssignableToTypeOf(&moleculer.Error{}))
+		Expect(rejected.(*moleculer.Error).Type).Should(Equal("QUEUE_FULL"))
+		Expect(moleculer.IsRetryable(rejected.(error))).Should(BeTrue())
+
+		close(release)
+		for i := 0; i < 3; i++ {
+			Eventually(results).Should(Receive(Equal("done")))
+		}
+		Expect(maxRunning).Should(Equal(2))
+	})
+
+	It("should use the policy of the action over the config", func() {
+		handler := func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+			return "done"
+		}
+		config := moleculer.BulkheadPolicy{Enabled: false, Concurrency: 3, MaxQueueSize: 10}
+		Expect(wrap(config, nil, handler)(nil, nil)).Should(Equal("done"))
+		Expect(bulkheadPolicy(config, nil)).Should(Equal(config))
+		Expect(bulkheadPolicy(config, &moleculer.BulkheadPolicy{Enabled: true, Concurrency: 1})).Should(Equal(
+			moleculer.BulkheadPolicy{Enabled: true, Concurrency: 1, MaxQueueSize: 10}))
+		Expect(bulkheadPolicy(moleculer.BulkheadPolicy{Enabled: true, Concurrency: 3}, &moleculer.BulkheadPolicy{Enabled: false})).Should(Equal(
+			moleculer.BulkheadPolicy{Enabled: false, Concurrency: 3}))
+	})
+})
@@ -0,0 +1,41 @@
+package middleware
+
+import (
+	"io"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/cache"
+	"github.com/moleculer-go/moleculer/payload"
+)
+
+// Cacher return the localAction and remoteAction middlewares that cache the results of the actions
+// with cache settings. The result of a call is returned from the cacher when it has the key of
+// the call, otherwise the action is called and its result is cached, unless it is an error or a stream.
+// It is added by the broker when the Config.Cacher is set.
+func Cacher(cacher cache.Cache) moleculer.Middlewares {
+	wrap := func(params interface{}, next func(...interface{})) {
+		action := params.(ActionHandlerParams)
+		if action.Cache == nil || !action.Cache.Enabled {
+			next()
+			return
+		}
+		settings := *action.Cache
+		handler := action.Handler
+		action.Handler = func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+			key := cache.Key(action.Action, settings.Keys, params, ctx.Meta())
+			if cached, exists := cacher.Get(key); exists {
+				return cached
+			}
+			result := payload.New(handler(ctx, params))
+			if _, isStream := result.Value().(io.Reader); !result.IsError() && !isStream {
+				cacher.Set(key, result, settings.TTL)
+			}
+			return result
+		}
+		next(action)
+	}
+	return moleculer.Middlewares{
+		"localAction":  wrap,
+		"remoteAction": wrap,
+	}
+}
@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"errors"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/cache"
+	"github.com/moleculer-go/moleculer/context"
+	"github.com/moleculer-go/moleculer/payload"
+	"github.com/moleculer-go/moleculer/test"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cacher", func() {
+
+	wrap := func(cacher cache.Cache, settings *moleculer.CacheSettings, handler moleculer.ActionHandler) moleculer.ActionHandler {
+		dispatcher := Dispatcher(createLogger("midlewares", "dispatcher"))
+		dispatcher.Add(Cacher(cacher))
+		params := dispatcher.CallHandlers("remoteAction", ActionHandlerParams{
+			Action:  "users.get",
+			NodeID:  "node-1",
+			Handler: handler,
+			Cache:   settings,
+		})
+		return params.(ActionHandlerParams).Handler
+	}
+	call := func(handler moleculer.ActionHandler, params map[string]interface{}, meta map[string]interface{}) moleculer.Payload {
+		ctx := context.BrokerContext(test.DelegatesWithId("node-1")).ChildActionContext("users.get", payload.New(params), moleculer.Options{Meta: payload.New(meta)})
+		return payload.New(handler(ctx.(moleculer.Context), ctx.Payload()))
+	}
+
+	It("should return the cached result of the calls with the same keys", func() {
+		cacher := cache.Memory(cache.MemoryOptions{})
+		defer cacher.Close()
+		calls := 0
+		handler := wrap(cacher, &moleculer.CacheSettings{Enabled: true, Keys: []string{"id", "#tenant"}},
+			func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+				calls++
+				return map[string]interface{}{"id": params.Get("id").Int(), "call": calls}
+			})
+
+		Expect(call(handler, map[string]interface{}{"id": 1, "details": true}, map[string]interface{}{"tenant": "a"}).Get("call").Int()).Should(Equal(1))
+		Expect(call(handler, map[string]interface{}{"id": 1, "details": false}, map[string]interface{}{"tenant": "a"}).Get("call").Int()).Should(Equal(1))
+		Expect(call(handler, map[string]interface{}{"id": 2}, map[string]interface{}{"tenant": "a"}).Get("call").Int()).Should(Equal(2))
+		Expect(call(handler, map[string]interface{}{"id": 1}, map[string]interface{}{"tenant": "b"}).Get("call").Int()).Should(Equal(3))
+		Expect(calls).Should(Equal(3))
+
+		cacher.Clean("users.get:*")
+		Expect(call(handler, map[string]interface{}{"id": 1}, map[string]interface{}{"tenant": "a"}).Get("call").Int()).Should(Equal(4))
+	})
+
+	It("should not cache the errors and the actions without cache settings", func() {
+		cacher := cache.Memory(cache.MemoryOptions{TTL: time.Minute})
+		defer cacher.Close()
+		calls := 0
+		failing := wrap(cacher, &moleculer.CacheSettings{Enabled: true}, func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+			calls++
+			return errors.New("not found")
+		})
+		Expect(call(failing, map[string]interface{}{"id": 1}, nil).IsError()).Should(BeTrue())
+		Expect(call(failing, map[string]interface{}{"id": 1}, nil).IsError()).Should(BeTrue())
+		Expect(calls).Should(Equal(2))
+
+		uncached := wrap(cacher, nil, func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+			calls++
+			return calls
+		})
+		Expect(call(uncached, map[string]interface{}{"id": 1}, nil).Int()).Should(Equal(3))
+		Expect(call(uncached, map[string]interface{}{"id": 1}, nil).Int()).Should(Equal(4))
+		Expect(cacher.Len()).Should(BeZero())
+	})
+})
@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// Encryption return middlewares that encrypt the transit packets using AES-GCM.
+// The key must have 16, 24 or 32 bytes to select AES-128, AES-192 or AES-256.
+// All nodes in the cluster must use the same key. Received packets that can't be decrypted
+// are rejected with an error and dropped by the transit.
+func Encryption(key []byte) moleculer.Middlewares {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		panic(errors.New(fmt.Sprint("Invalid encryption key. error: ", err)))
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		panic(errors.New(fmt.Sprint("Could not create AES-GCM cipher. error: ", err)))
+	}
+	return moleculer.Middlewares{
+		"transporterSend": func(params interface{}, next func(...interface{})) {
+			data := params.([]byte)
+			nonce := make([]byte, gcm.NonceSize())
+			if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+				panic(errors.New(fmt.Sprint("Could not generate nonce. error: ", err)))
+			}
+			next(gcm.Seal(nonce, nonce, data, nil))
+		},
+		"transporterReceive": func(params interface{}, next func(...interface{})) {
+			data := params.([]byte)
+			if len(data) < gcm.NonceSize() {
+				next(errors.New("Could not decrypt packet. error: the packet is shorter than the nonce"))
+				return
+			}
+			nonce, encrypted := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+			decrypted, err := gcm.Open(nil, nonce, encrypted, nil)
+			if err != nil {
+				next(errors.New(fmt.Sprint("Could not decrypt packet. error: ", err)))
+				return
+			}
+			next(decrypted)
+		},
+	}
+}
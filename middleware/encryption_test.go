@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encryption", func() {
+
+	It("should encrypt on transporterSend and decrypt on transporterReceive", func() {
+		dispatcher := Dispatcher(createLogger("midlewares", "dispatcher"))
+		dispatcher.Add(Encryption([]byte("0123456789abcdef0123456789abcdef")))
+		Expect(dispatcher.Has("transporterSend")).Should(BeTrue())
+		Expect(dispatcher.Has("transporterReceive")).Should(BeTrue())
+
+		data := []byte(`{"sender":"node-1"}`)
+		encrypted := dispatcher.CallHandlers("transporterSend", data).([]byte)
+		Expect(encrypted).ShouldNot(Equal(data))
+		Expect(encrypted).ShouldNot(ContainSubstring("node-1"))
+
+		decrypted := dispatcher.CallHandlers("transporterReceive", encrypted).([]byte)
+		Expect(decrypted).Should(Equal(data))
+	})
+
+	It("should reject packets encrypted with another key", func() {
+		sender := Dispatcher(createLogger("midlewares", "sender"))
+		sender.Add(Encryption([]byte("0123456789abcdef")))
+		receiver := Dispatcher(createLogger("midlewares", "receiver"))
+		receiver.Add(Encryption([]byte("fedcba9876543210")))
+
+		encrypted := sender.CallHandlers("transporterSend", []byte("secret")).([]byte)
+		result := receiver.CallHandlers("transporterReceive", encrypted)
+		Expect(result).Should(BeAssignableToTypeOf(errors.New("")))
+		Expect(result.(error).Error()).Should(ContainSubstring("Could not decrypt packet"))
+	})
+
+	It("should reject packets shorter than the nonce", func() {
+		receiver := Dispatcher(createLogger("midlewares", "receiver"))
+		receiver.Add(Encryption([]byte("0123456789abcdef")))
+
+		result := receiver.CallHandlers("transporterReceive", []byte("short"))
+		Expect(result).Should(BeAssignableToTypeOf(errors.New("")))
+		Expect(result.(error).Error()).Should(ContainSubstring("shorter than the nonce"))
+	})
+
+	It("should panic with an invalid key", func() {
+		Expect(func() {
+			Encryption([]byte("short"))
+		}).Should(Panic())
+	})
+})
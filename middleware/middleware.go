@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"time"
+
 	"github.com/moleculer-go/moleculer"
 	log "github.com/sirupsen/logrus"
 )
@@ -10,6 +12,39 @@ type AfterActionParams struct {
 	Result        moleculer.Payload
 }
 
+// TransitPacketParams is the param of the transitPacketSent and transitPacketReceived middlewares.
+// Duration is the time the transport spent serializing the sent packets and deserializing the received ones.
+type TransitPacketParams struct {
+	Command  string
+	NodeID   string
+	Bytes    int
+	Duration time.Duration
+}
+
+// ActionHandlerParams is the param of the localAction and remoteAction middlewares. Middlewares can
+// wrap or replace the Handler and call next with the new params, e.g. to check the caller permissions
+// or return a cached result. The localAction handlers are wrapped once when the service starts and
+// the remoteAction handlers on each remote call.
+type ActionHandlerParams struct {
+	Action  string
+	Service string
+	NodeID  string
+	Handler moleculer.ActionHandler
+	// Bulkhead is the bulkhead policy of the action schema, nil when not set or in remote actions.
+	Bulkhead *moleculer.BulkheadPolicy
+	// Cache is the cache settings of the action, nil when its results are not cached.
+	Cache *moleculer.CacheSettings
+}
+
+// EventHandlerParams is the param of the localEvent middlewares. Like ActionHandlerParams, the
+// Handler can be wrapped or replaced, once when the service starts.
+type EventHandlerParams struct {
+	Event   string
+	Group   string
+	Service string
+	Handler moleculer.EventHandler
+}
+
 type Dispatch struct {
 	handlers map[string][]moleculer.MiddlewareHandler
 	logger   *log.Entry
@@ -20,7 +55,7 @@ func Dispatcher(logger *log.Entry) *Dispatch {
 	return &Dispatch{handlers, logger}
 }
 
-var validHandlers = []string{"Config", "brokerStopping", "brokerStopped", "brokerStarting", "brokerStarted", "serviceStopping", "serviceStopped", "serviceStarting", "serviceStarted", "beforeLocalAction", "afterLocalAction", "beforeRemoteAction", "afterRemoteAction"}
+var validHandlers = []string{"Config", "brokerStopping", "brokerStopped", "brokerStarting", "brokerStarted", "serviceStopping", "serviceStopped", "serviceStarting", "serviceStarted", "beforeLocalAction", "afterLocalAction", "beforeRemoteAction", "afterRemoteAction", "transporterSend", "transporterReceive", "transitPacketSent", "transitPacketReceived", "localAction", "remoteAction", "localEvent", "createService"}
 
 // validHandler check if the name of handlers midlewares are tryignt o register exists!
 func (dispatch *Dispatch) validHandler(name string) bool {
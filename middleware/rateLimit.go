@@ -0,0 +1,149 @@
+package middleware
+
+import (
+	"fmt"
+	"path"
+	"sync"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// RateLimitRule limits the calls of the actions matching the pattern, counting the calls
+// of each action separately for each key (by default the caller nodeID).
+type RateLimitRule struct {
+	// Actions is the pattern of the action names, with the path.Match syntax, e.g. "users.*".
+	// Empty matches all actions.
+	Actions string
+	// Limit is the number of calls allowed in the Window, the default window is 1 second.
+	Limit  int
+	Window time.Duration
+	// Burst is the number of calls allowed at once, the default is Limit.
+	Burst int
+	// Key return the key the calls are counted by, e.g. RateLimitByMeta("user").
+	// The default is RateLimitByCaller.
+	Key func(context moleculer.BrokerContext) string
+}
+
+// RateLimitByCaller count the calls by the node that made the call.
+func RateLimitByCaller(context moleculer.BrokerContext) string {
+	if source, ok := context.(interface{ SourceNodeID() string }); ok && source.SourceNodeID() != "" {
+		return source.SourceNodeID()
+	}
+	if delegates, ok := context.(interface {
+		BrokerDelegates() *moleculer.BrokerDelegates
+	}); ok {
+		return delegates.BrokerDelegates().LocalNode().GetID()
+	}
+	return ""
+}
+
+// RateLimitByMeta count the calls by a meta field, e.g. the user ID set by an API gateway.
+func RateLimitByMeta(field string) func(context moleculer.BrokerContext) string {
+	return func(context moleculer.BrokerContext) string {
+		return context.Meta().Get(field).String()
+	}
+}
+
+// RateLimit return the localAction middleware that limits the calls of the actions with token buckets.
+// The first rule matching the action is applied, and calls over the limit are rejected with a
+// RateLimitExceeded error (code 429, type RATE_LIMIT_EXCEEDED). Add it to Config.Middlewares.
+func RateLimit(rules ...RateLimitRule) moleculer.Middlewares {
+	return moleculer.Middlewares{
+		"localAction": func(params interface{}, next func(...interface{})) {
+			action := params.(ActionHandlerParams)
+			rule, found := matchRateLimitRule(rules, action.Action)
+			if !found || rule.Limit <= 0 {
+				next()
+				return
+			}
+			limiter := newRateLimiter(rule)
+			handler := action.Handler
+			action.Handler = func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+				key := limiter.key(ctx.(moleculer.BrokerContext))
+				if !limiter.allow(key) {
+					return rateLimitError(action.Action, key)
+				}
+				return handler(ctx, params)
+			}
+			next(action)
+		},
+	}
+}
+
+func matchRateLimitRule(rules []RateLimitRule, action string) (RateLimitRule, bool) {
+	for _, rule := range rules {
+		if rule.Actions == "" {
+			return rule, true
+		}
+		if matched, _ := path.Match(rule.Actions, action); matched {
+			return rule, true
+		}
+	}
+	return RateLimitRule{}, false
+}
+
+func rateLimitError(action, key string) error {
+	err := moleculer.NewError(fmt.Sprintf("Rate limit exceeded. Request '%s' action is rejected.", action),
+		429, "RATE_LIMIT_EXCEEDED", map[string]interface{}{"action": action, "key": key})
+	err.Name = "RateLimitExceeded"
+	return err
+}
+
+// tokenBucket is refilled with rate tokens per second up to its capacity.
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keep the token buckets of an action, one for each key.
+type rateLimiter struct {
+	key      func(context moleculer.BrokerContext) string
+	rate     float64
+	capacity float64
+	mutex    sync.Mutex
+	buckets  map[string]*tokenBucket
+}
+
+func newRateLimiter(rule RateLimitRule) *rateLimiter {
+	window := rule.Window
+	if window <= 0 {
+		window = time.Second
+	}
+	burst := rule.Burst
+	if burst <= 0 {
+		burst = rule.Limit
+	}
+	key := rule.Key
+	if key == nil {
+		key = RateLimitByCaller
+	}
+	return &rateLimiter{
+		key:      key,
+		rate:     float64(rule.Limit) / window.Seconds(),
+		capacity: float64(burst),
+		buckets:  map[string]*tokenBucket{},
+	}
+}
+
+// allow take a token from the bucket of the key, returning false when it is empty.
+func (limiter *rateLimiter) allow(key string) bool {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	now := time.Now()
+	bucket, exists := limiter.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: limiter.capacity, updated: now}
+		limiter.buckets[key] = bucket
+	}
+	bucket.tokens += now.Sub(bucket.updated).Seconds() * limiter.rate
+	if bucket.tokens > limiter.capacity {
+		bucket.tokens = limiter.capacity
+	}
+	bucket.updated = now
+	if bucket.tokens < 1 {
+		return false
+	}
+	bucket.tokens--
+	return true
+}
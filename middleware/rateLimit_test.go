@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("RateLimit", func() {
+
+	It("should apply the first rule matching the action", func() {
+		rules := []RateLimitRule{
+			RateLimitRule{Actions: "users.*", Limit: 1},
+			RateLimitRule{Actions: "", Limit: 10},
+		}
+		rule, found := matchRateLimitRule(rules, "users.create")
+		Expect(found).Should(BeTrue())
+		Expect(rule.Limit).Should(Equal(1))
+		rule, found = matchRateLimitRule(rules, "orders.list")
+		Expect(found).Should(BeTrue())
+		Expect(rule.Limit).Should(Equal(10))
+		_, found = matchRateLimitRule(rules[:1], "orders.list")
+		Expect(found).Should(BeFalse())
+	})
+
+	It("should allow the burst and refill the bucket of each key over the window", func() {
+		limiter := newRateLimiter(RateLimitRule{Limit: 2, Window: 100 * time.Millisecond})
+		Expect(limiter.allow("node-1")).Should(BeTrue())
+		Expect(limiter.allow("node-1")).Should(BeTrue())
+		Expect(limiter.allow("node-1")).Should(BeFalse())
+		Expect(limiter.allow("node-2")).Should(BeTrue())
+
+		time.Sleep(60 * time.Millisecond)
+		Expect(limiter.allow("node-1")).Should(BeTrue())
+		Expect(limiter.allow("node-1")).Should(BeFalse())
+	})
+
+	It("should not wrap the actions without a matching rule", func() {
+		dispatcher := Dispatcher(createLogger("midlewares", "dispatcher"))
+		dispatcher.Add(RateLimit(RateLimitRule{Actions: "users.*", Limit: 1}))
+		params := dispatcher.CallHandlers("localAction", ActionHandlerParams{
+			Action: "orders.list",
+			Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+				return "done"
+			},
+		}).(ActionHandlerParams)
+		for i := 0; i < 3; i++ {
+			Expect(params.Handler(nil, nil)).Should(Equal("done"))
+		}
+	})
+})
@@ -1,8 +1,12 @@
 package moleculer
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"regexp"
+	"strings"
+	"sync"
 	"time"
 
 	bus "github.com/moleculer-go/goemitter"
@@ -16,19 +20,36 @@ type ForEachFunc func(iterator func(key interface{}, value Payload) bool)
 
 // Payload contains the data sent/return to actions.
 // I has convinience methods to read action parameters by name with the right type.
+// Payloads are immutable: methods like Add, Remove and Merge return a new payload.
+// The same payload is delivered to all the local event handlers, so handlers must not change
+// the maps and slices returned by Value() or RawMap(). Use Clone() or Config.CopyOnEmit instead.
 type Payload interface {
 	First() Payload
 	Sort(field string) Payload
+	//Filter return a list with the items of the array payload that match the predicate
+	Filter(predicate func(item Payload) bool) Payload
+	//Slice return a list with up to limit items (all when limit <= 0) of the array payload, starting at offset
+	Slice(offset, limit int) Payload
 	Remove(fields ...string) Payload
 	AddItem(value interface{}) Payload
 	Add(field string, value interface{}) Payload
 	AddMany(map[string]interface{}) Payload
+	//Merge return a new payload with the values of other deep merged over this payload
+	Merge(other Payload) Payload
+	//Diff return a new payload with the fields of other that are new or different from this payload
+	Diff(other Payload) Payload
+	//Clone return a deep copy of the payload
+	Clone() Payload
 	MapArray() []map[string]interface{}
 	RawMap() map[string]interface{}
 	Bson() bson.M
 	BsonArray() bson.A
 	Map() map[string]Payload
 	Exists() bool
+	//IsNil return true when the value is missing or nil (null in JSON)
+	IsNil() bool
+	//GetOrDefault return the field value, or the default value when the field is missing or nil
+	GetOrDefault(path string, defaultValue interface{}) Payload
 	IsError() bool
 	Error() error
 	Value() interface{}
@@ -47,9 +68,22 @@ type Payload interface {
 	StringArray() []string
 	Bool() bool
 	BoolArray() []bool
+	//Bytes return binary values. Strings are decoded as base64, the JSON encoding of binary values
+	Bytes() []byte
 	ByteArray() []byte
 	Time() time.Time
+	//StringOk, IntOk, Int64Ok, FloatOk, BoolOk and TimeOk return false when the value is missing or has another type,
+	//so a missing field can be told apart from a zero value
+	StringOk() (string, bool)
+	IntOk() (int, bool)
+	Int64Ok() (int64, bool)
+	FloatOk() (float64, bool)
+	BoolOk() (bool, bool)
+	TimeOk() (time.Time, bool)
 	TimeArray() []time.Time
+	//Duration return the duration value. Numbers are milliseconds and strings are parsed with time.ParseDuration
+	Duration() time.Duration
+	DurationArray() []time.Duration
 	Array() []Payload
 	Len() int
 	Get(path string) Payload
@@ -58,22 +92,60 @@ type Payload interface {
 	IsArray() bool
 	IsMap() bool
 	ForEach(iterator func(key interface{}, value Payload) bool)
+	//DecodeArrayStream call the iterator for each item of a list or of a JSON array stream (io.Reader), without loading the whole stream
+	DecodeArrayStream(iterator func(item Payload) bool) error
+	//Unmarshal decode the payload into the target struct, map or slice
+	Unmarshal(target interface{}) error
 }
 
 // ActionSchema is used by the validation engine to check if parameters sent to the action are valid.
 type ActionSchema interface {
 }
 
+// Validator check the action params against the action schema before the handler is invoked.
+// It can be set in the broker config to plug a different validation engine.
+type Validator interface {
+	Validate(schema ActionSchema, params Payload) error
+}
+
 type ObjectSchema struct {
 	Source interface{}
 }
 
+// Action visibility, like moleculer-js: published (the default) and public actions can be called
+// by all nodes, protected actions only by the local node and private actions only by the actions
+// of the same service. Protected and private actions are not sent to the other nodes.
+const (
+	VisibilityPublished = "published"
+	VisibilityPublic    = "public"
+	VisibilityProtected = "protected"
+	VisibilityPrivate   = "private"
+)
+
 type Action struct {
 	Name        string
 	Handler     ActionHandler
 	Schema      ActionSchema
 	Settings    map[string]interface{}
 	Description string
+	// Visibility is VisibilityPublished when empty.
+	Visibility string
+	// Bulkhead overrides the Config.Bulkhead policy for the action. Zero limits use the config values.
+	Bulkhead *BulkheadPolicy
+	// Cache enables the caching of the results of the action by the Config.Cacher. It is stored in the
+	// "cache" setting, which can also be set in the Settings.
+	Cache *CacheSettings
+}
+
+// CacheSettings are the cache settings of an action. The results are cached by the action name and the
+// values of the Keys, so the calls with the same keys return the cached result until it expires.
+type CacheSettings struct {
+	Enabled bool
+	// Keys are the params used in the cache key, all the params when empty. The keys starting
+	// with # are meta fields, e.g. "#userID".
+	Keys []string
+	// TTL of the cached results, the default TTL of the cacher when zero.
+	TTL time.Duration
 }
 
 type Event struct {
@@ -82,19 +154,37 @@ type Event struct {
 	Handler EventHandler
 }
 
+// Dependency is a service that must be available before a service is started.
+// Dependencies can also be declared as "name" or "name:version" in ServiceSchema.Dependencies.
+type Dependency struct {
+	Name    string
+	Version string
+	// MinNodes is the number of nodes that must run the service, the default is 1.
+	MinNodes int
+	// RemoteOnly ignores the service when it runs in the local node.
+	RemoteOnly bool
+}
+
+// ServiceSchema is the definition of a service. When the Version is set the actions are named
+// with it, so multiple versions of a service can run side by side: the numeric versions are
+// prefixed with v (2 -> v2.users.get) and the other versions are kept (staging.users.get).
 type ServiceSchema struct {
 	Name         string
 	Version      string
 	Dependencies []string
-	Settings     map[string]interface{}
-	Metadata     map[string]interface{}
-	Hooks        map[string]interface{}
-	Mixins       []Mixin
-	Actions      []Action
-	Events       []Event
-	Created      CreatedFunc
-	Started      LifecycleFunc
-	Stopped      LifecycleFunc
+	DependsOn    []Dependency
+	// DependenciesTimeout is the time to wait for the dependencies, when 0 the config
+	// WaitForDependenciesTimeout is used.
+	DependenciesTimeout time.Duration
+	Settings            map[string]interface{}
+	Metadata            map[string]interface{}
+	Hooks               map[string]interface{}
+	Mixins              []Mixin
+	Actions             []Action
+	Events              []Event
+	Created             CreatedFunc
+	Started             LifecycleFunc
+	Stopped             LifecycleFunc
 }
 
 type Mixin struct {
@@ -112,14 +202,28 @@ type Mixin struct {
 
 type TransporterFactoryFunc func() interface{}
 type StrategyFactoryFunc func() interface{}
+type DiscovererFactoryFunc func() interface{}
+type CacherFactoryFunc func() interface{}
 
 type Config struct {
 	LogLevel                   string
 	LogFormat                  string
+	Logger                     Logger
+	NodeID                     string // when empty the DiscoverNodeID function creates it
 	DiscoverNodeID             func() string
+	StopOnNodeIDConflict       bool // stop the broker when another broker instance uses the same node ID
 	Transporter                string
 	TransporterFactory         TransporterFactoryFunc
+	Serializer                 string
 	StrategyFactory            StrategyFactoryFunc
+	Strategy                   string
+	PreferLocal                *bool                  // call the local endpoint of an action when available, true when nil
+	Metadata                   map[string]interface{} // labels of the local node sent in the INFO packet, e.g. zone and region
+	SlowStartWindow            time.Duration          // ramp the share of the calls sent to a node over this window after it joins
+	Discoverer                 string                 // finds the peers outside of the transporter: kubernetes://, consul:// or etcd://
+	DiscovererFactory          DiscovererFactoryFunc
+	Cacher                     string // caches the results of the actions with cache settings, e.g. memory://?ttl=30s&maxItems=1000
+	CacherFactory              CacherFactoryFunc
 	HeartbeatFrequency         time.Duration
 	HeartbeatTimeout           time.Duration
 	OfflineCheckFrequency      time.Duration
@@ -128,19 +232,37 @@ type Config struct {
 	WaitForDependenciesTimeout time.Duration
 	Middlewares                []Middlewares
 	Namespace                  string
+	TopicPrefix                string
 	RequestTimeout             time.Duration
 	MCallTimeout               time.Duration
+	GracefulStopTimeout        time.Duration
+	Compression                string
+	CompressionThreshold       int
+	MaxPacketSize              int
+	MaxQueueSize               int
 	RetryPolicy                RetryPolicy
+	Bulkhead                   BulkheadPolicy
+	ReconnectPolicy            ReconnectPolicy
+	OfflineBuffer              OfflineBufferPolicy
+	EventDelivery              DeliveryPolicy
+	DeadLetterHandler          DeadLetterHandler
+	DeadLetterTopic            string
+	StatsProvider              StatsProviderFunc
+	Validator                  Validator
+	CopyOnEmit                 bool
+	PreserveNumbers            bool
 	MaxCallLevel               int
 	Metrics                    bool
+	AccessLog                  bool
 	MetricsRate                float32
+	TransitMetricsInterval     time.Duration
 	DisableInternalServices    bool
 	DisableInternalMiddlewares bool
 	DontWaitForNeighbours      bool
 	WaitForNeighboursInterval  time.Duration
-	Created                    func()
-	Started                    func()
-	Stopped                    func()
+	Created                    BrokerLifecycleFunc
+	Started                    BrokerLifecycleFunc
+	Stopped                    BrokerLifecycleFunc
 
 	Services map[string]interface{}
 }
@@ -150,6 +272,8 @@ var DefaultConfig = Config{
 	LogFormat:                  "TEXT",
 	DiscoverNodeID:             discoverNodeID,
 	Transporter:                "MEMORY",
+	TopicPrefix:                "MOL",
+	Serializer:                 "JSON",
 	HeartbeatFrequency:         5 * time.Second,
 	HeartbeatTimeout:           15 * time.Second,
 	OfflineCheckFrequency:      20 * time.Second,
@@ -159,29 +283,79 @@ var DefaultConfig = Config{
 	WaitForDependenciesTimeout: 2 * time.Second,
 	Metrics:                    false,
 	MetricsRate:                1,
+	TransitMetricsInterval:     10 * time.Second,
 	DisableInternalServices:    false,
 	DisableInternalMiddlewares: false,
-	Created:                    func() {},
-	Started:                    func() {},
-	Stopped:                    func() {},
+	Created:                    func(broker *BrokerDelegates) {},
+	Started:                    func(broker *BrokerDelegates) {},
+	Stopped:                    func(broker *BrokerDelegates) {},
 	MaxCallLevel:               100,
 	RetryPolicy: RetryPolicy{
-		Enabled: false,
+		Enabled:  false,
+		Retries:  5,
+		Delay:    100,
+		MaxDelay: 1000,
+		Factor:   2,
+	},
+	Bulkhead: BulkheadPolicy{
+		Enabled:      false,
+		Concurrency:  3,
+		MaxQueueSize: 10,
+	},
+	ReconnectPolicy: ReconnectPolicy{
+		Delay:    time.Second,
+		MaxDelay: 30 * time.Second,
+		Factor:   2,
+		Jitter:   0.2,
+	},
+	OfflineBuffer: OfflineBufferPolicy{
+		MaxPackets: 1000,
+		TTL:        30 * time.Second,
+	},
+	EventDelivery: DeliveryPolicy{
+		Retries: 3,
+		Delay:   500 * time.Millisecond,
 	},
 	RequestTimeout:            1 * time.Minute,
 	MCallTimeout:              5 * time.Second,
+	GracefulStopTimeout:       2 * time.Second,
+	CompressionThreshold:      1024,
+	MaxQueueSize:              50000,
 	WaitForNeighboursInterval: 200 * time.Millisecond,
 }
 
-// discoverNodeID - should return the node id for this machine
+var invalidNodeIDChars = regexp.MustCompile("[^a-z0-9._-]+")
+
+var usedNodeIDs = map[string]int{}
+var usedNodeIDsMutex sync.Mutex
+
+// discoverNodeID return the node ID for this process, like moleculer-js: the hostname and the
+// process ID, e.g. server-1-4321. The brokers created later by the same process have a suffix
+// with the count of brokers, e.g. server-1-4321-2, so each node ID is unique.
 func discoverNodeID() string {
 	hostname, err := os.Hostname()
 	if err != nil {
 		hostname = "node-" + util.RandomString(2)
 	}
-	return fmt.Sprint(hostname, "-", util.RandomString(5))
+	hostname = strings.Trim(invalidNodeIDChars.ReplaceAllString(strings.ToLower(hostname), "-"), "-")
+	if hostname == "" {
+		hostname = "node"
+	}
+	nodeID := fmt.Sprint(hostname, "-", os.Getpid())
+
+	usedNodeIDsMutex.Lock()
+	defer usedNodeIDsMutex.Unlock()
+	usedNodeIDs[nodeID]++
+	if count := usedNodeIDs[nodeID]; count > 1 {
+		return fmt.Sprint(nodeID, "-", count)
+	}
+	return nodeID
 }
 
+// RetryPolicy controls the retries of failed action calls. Each attempt selects the endpoint again,
+// so the call can succeed on another node. Delay and MaxDelay are in milliseconds, the delay is
+// multiplied by Factor after each attempt. Check decides if an error is retried, when nil only the
+// retryable errors (see IsRetryable) are retried, like request timeouts and unavailable services.
 type RetryPolicy struct {
 	Enabled  bool
 	Retries  int
@@ -191,9 +365,108 @@ type RetryPolicy struct {
 	Check    func(error) bool
 }
 
+// BulkheadPolicy limits the concurrent executions of each local action. When Concurrency executions
+// are in flight the calls wait in a queue, and when the queue has MaxQueueSize calls the new calls
+// are rejected with a QUEUE_FULL error.
+type BulkheadPolicy struct {
+	Enabled      bool
+	Concurrency  int
+	MaxQueueSize int
+}
+
+// ReconnectPolicy controls how transit reconnects when the transporter connection drops.
+// The delay grows by Factor on each attempt up to MaxDelay, and Jitter (0..1) randomizes it.
+// Retries = 0 keeps trying forever.
+type ReconnectPolicy struct {
+	Disabled bool
+	Retries  int
+	Delay    time.Duration
+	MaxDelay time.Duration
+	Factor   float64
+	Jitter   float64
+}
+
+// OfflineBufferPolicy controls the buffering of the outgoing requests and events while the transporter
+// reconnects. When enabled, up to MaxPackets packets are kept and published when the connection is back,
+// instead of being sent to a disconnected transporter. Packets older than TTL are not published: the
+// requests fail and the events are sent to the dead-letter handler.
+type OfflineBufferPolicy struct {
+	Enabled    bool
+	MaxPackets int
+	TTL        time.Duration
+}
+
+// DeliveryPolicy controls the confirmed event delivery (broker.EmitConfirmed). Publishing
+// an event is retried up to Retries times, waiting Delay between the attempts, until the
+// transporter acknowledges it.
+type DeliveryPolicy struct {
+	Retries int
+	Delay   time.Duration
+}
+
+// DeadLetter is a transit packet (RES or EVENT) that could not be delivered to the target node.
+type DeadLetter struct {
+	Command string
+	NodeID  string
+	Packet  map[string]interface{}
+	Error   error
+}
+
+// SystemStats is the resource usage of the host reported in the HEARTBEAT and INFO packets.
+// CPU is the utilization percentage and memory values are in bytes.
+type SystemStats struct {
+	CPU      int64
+	Cores    int
+	MemTotal uint64
+	MemFree  uint64
+}
+
+// MemUsage return the percentage of memory in use.
+func (stats SystemStats) MemUsage() int64 {
+	if stats.MemTotal == 0 {
+		return 0
+	}
+	return int64((stats.MemTotal - stats.MemFree) * 100 / stats.MemTotal)
+}
+
+// StatsProviderFunc return the current resource usage of the host.
+type StatsProviderFunc func() SystemStats
+
+// BrokerLifecycleFunc is called when the broker is created, started and stopped, so resources
+// like DB pools and HTTP servers can follow the broker lifecycle.
+type BrokerLifecycleFunc func(broker *BrokerDelegates)
+
+// DeadLetterHandler receives the packets that could not be delivered, so they can be inspected or replayed.
+type DeadLetterHandler func(letter DeadLetter)
+
 type ActionHandler func(context Context, params Payload) interface{}
 type EventHandler func(context Context, params Payload)
+
+// BeforeHookFunc is an action hook called before the handler. It returns the params passed to the
+// handler, or an error payload to fail the call without calling the handler.
+// Hooks are declared in the "before", "after" and "error" keys of ServiceSchema.Hooks, mapping the
+// action names, or patterns like "*" and "get*", to a hook or a list of hooks, e.g.
+//
+//	Hooks: map[string]interface{}{
+//		"before": map[string]interface{}{"*": checkUser, "create": []moleculer.BeforeHookFunc{trim, validate}},
+//	}
+type BeforeHookFunc func(context Context, params Payload) Payload
+
+// AfterHookFunc is an action hook called after the handler succeeds. It returns the action response.
+type AfterHookFunc func(context Context, result interface{}) interface{}
+
+// ErrorHookFunc is an action hook called when the call fails. It returns the error, or a
+// response to recover from the failure.
+type ErrorHookFunc func(context Context, err error) interface{}
+
+// CreatedFunc is called when the service is created, before the broker is started. The Created
+// of the mixins are called before the one of the service. A panic fails the Publish of the service.
 type CreatedFunc func(ServiceSchema, *log.Entry)
+
+// LifecycleFunc is called when the service is started, before its actions and events are
+// published, and when it is stopped. The Started of the mixins are called before the one of the
+// service. A Started that panics with an error fails the broker Start, which stops the services
+// already started in the reverse order.
 type LifecycleFunc func(BrokerContext, ServiceSchema)
 
 type LoggerFunc func(name string, value string) *log.Entry
@@ -208,10 +481,17 @@ type BrokerContextFunc func() BrokerContext
 type MiddlewareHandlerFunc func(name string, params interface{}) interface{}
 type PublishFunc func(...interface{})
 type WaitForFunc func(...string) error
+type NodeStatusFunc func(nodeID string) bool
 type MiddlewareHandler func(params interface{}, next func(...interface{}))
 
 type Middlewares map[string]MiddlewareHandler
 
+// Logger receives the log entries of the broker, so they can be written by another logging library.
+// The entries are filtered by Config.LogLevel. Adapters for logrus, zap and zerolog are in the logger package and its sub-modules.
+type Logger interface {
+	Log(level log.Level, message string, fields map[string]interface{})
+}
+
 type Middleware interface {
 	CallHandlers(name string, params interface{}) interface{}
 }
@@ -222,31 +502,89 @@ type Node interface {
 	Available()
 	Unavailable()
 	IsExpired(timeout time.Duration) bool
+	IsOffline(timeout time.Duration) bool
 	Update(id string, info map[string]interface{}) bool
 
 	IncreaseSequence()
+	UpdateStats(stats SystemStats)
 	HeartBeat(heartbeat map[string]interface{})
 	Publish(service map[string]interface{})
+	Unpublish(name, version string)
 }
 
+// SessionMetaKey is the meta key of the session of the calls, see Options.Session.
+const SessionMetaKey = "session"
+
+// Options of an action call.
 type Options struct {
-	Meta   Payload
+	// Meta is merged into the meta of the call context.
+	Meta Payload
+	// NodeID calls the action in the given node. The call fails when the node does not have the action.
 	NodeID string
+	// Timeout of each attempt of the call. When zero the Config.RequestTimeout is used.
+	Timeout time.Duration
+	// Retries is the number of times a failed call is retried, according to the Config.RetryPolicy
+	// delay and check. When zero the Config.RetryPolicy retries are used if enabled.
+	Retries int
+	// FallbackResponse is returned instead of the error when the call fails. It can be a value
+	// or a FallbackFunc, called with the context and the error.
+	FallbackResponse interface{}
+	// Strategy is the name of a registered strategy (e.g. "RoundRobin") used to select the
+	// endpoint of this call, instead of the strategy of the broker.
+	Strategy string
+	// PreferLocal overrides the Config.PreferLocal of this call: when true a local endpoint
+	// is used if available, when false the strategy selects one of all the endpoints.
+	PreferLocal *bool
+	// Session pins the calls of the same session to the node selected by its first call of each action,
+	// while the node is available. It can also be set in the meta of the context with the SessionMetaKey.
+	Session string
+	// Context cancels the call when it is done. Its deadline is used as the call timeout.
+	Context context.Context
+}
+
+// FallbackFunc returns the response of a failed call.
+type FallbackFunc func(ctx Context, err error) interface{}
+
+// CallDefinition is one of the calls performed by broker.MCall.
+type CallDefinition struct {
+	Action  string
+	Params  interface{}
+	Options Options
 }
 
+// EventOptions are the options of the events emitted with EmitWithOptions and BroadcastWithOptions.
+type EventOptions struct {
+	// Groups limits the event to the handlers of these groups. All the groups receive it when empty.
+	Groups []string
+	// NodeID delivers the event to the handlers of the given node only, so control messages can be sent
+	// point-to-point. The event is discarded when the node has no handler for it.
+	NodeID string
+}
+
+// Context implements context.Context, so actions can stop their work when
+// the caller cancels the request or it times out.
 type Context interface {
+	context.Context
+
 	//context methods used by services
 	MCall(map[string]map[string]interface{}) chan map[string]Payload
 	Call(actionName string, params interface{}, opts ...Options) chan Payload
 	Emit(eventName string, params interface{}, groups ...string)
 	Broadcast(eventName string, params interface{}, groups ...string)
+	EmitWithOptions(eventName string, params interface{}, opts EventOptions)
+	BroadcastWithOptions(eventName string, params interface{}, opts EventOptions)
 	Logger() *log.Entry
 
 	Payload() Payload
 	Meta() Payload
+	// Locals are the values of this request in the local node, like the authenticated user or a
+	// database transaction set by a middleware. Unlike the meta they are never sent to other nodes.
+	Locals() map[string]interface{}
 }
 
 type BrokerContext interface {
+	context.Context
+
 	Call(actionName string, params interface{}, opts ...Options) chan Payload
 	Emit(eventName string, params interface{}, groups ...string)
 
@@ -264,15 +602,29 @@ type BrokerContext interface {
 
 	SetTargetNodeID(targetNodeID string)
 	TargetNodeID() string
+	SetGroups(groups []string)
 
 	ID() string
+	//RequestID is the ID of the root context of the call chain, kept across the nodes.
 	RequestID() string
+	//ParentID is the ID of the context that made the call.
+	ParentID() string
+	//Level is the depth of the context in the call chain.
+	Level() int
+	Timeout() time.Duration
 	Meta() Payload
 	UpdateMeta(Payload)
+	Locals() map[string]interface{}
 	Logger() *log.Entry
 
 	Publish(...interface{})
 	WaitFor(services ...string) error
+
+	//Cancel the context, signaling the action to stop its work.
+	Cancel()
+
+	//Retry create a context to call the action again, with a new ID and timeout.
+	Retry() BrokerContext
 }
 
 //Needs Refactoring..2 broker interfaces.. one for regiwstry.. and for for all others.
@@ -292,4 +644,6 @@ type BrokerDelegates struct {
 	MiddlewareHandler  MiddlewareHandlerFunc
 	Publish            PublishFunc
 	WaitFor            WaitForFunc
+	KnowNode           NodeStatusFunc
+	IsNodeAvailable    NodeStatusFunc
 }
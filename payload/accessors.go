@@ -0,0 +1,63 @@
+package payload
+
+import (
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// IsNil return true when the value is missing or nil.
+func (p *RawPayload) IsNil() bool {
+	return p.source == nil
+}
+
+// GetOrDefault return the value of the field, or a payload with the default value when the field is missing or nil.
+func (p *RawPayload) GetOrDefault(path string, defaultValue interface{}) moleculer.Payload {
+	if value := p.Get(path); !value.IsNil() {
+		return value
+	}
+	return New(defaultValue)
+}
+
+// StringOk return the string value and true, or false when the value is not a string.
+func (p *RawPayload) StringOk() (string, bool) {
+	value, ok := p.source.(string)
+	return value, ok
+}
+
+// IntOk return the int value and true, or false when the value is not a number.
+func (p *RawPayload) IntOk() (int, bool) {
+	if _, isNumber := numberValue(p.source); !isNumber {
+		return 0, false
+	}
+	return p.Int(), true
+}
+
+// Int64Ok return the int64 value and true, or false when the value is not a number.
+func (p *RawPayload) Int64Ok() (int64, bool) {
+	if _, isNumber := numberValue(p.source); !isNumber {
+		return 0, false
+	}
+	return p.Int64(), true
+}
+
+// FloatOk return the float64 value and true, or false when the value is not a number.
+func (p *RawPayload) FloatOk() (float64, bool) {
+	value, isNumber := numberValue(p.source)
+	return value, isNumber
+}
+
+// BoolOk return the bool value and true, or false when the value is not a bool.
+func (p *RawPayload) BoolOk() (bool, bool) {
+	value, ok := p.source.(bool)
+	return value, ok
+}
+
+// TimeOk return the time value and true, or false when the value can't be converted with ParseTime.
+func (p *RawPayload) TimeOk() (time.Time, bool) {
+	if value, isTime := p.source.(time.Time); isTime {
+		return value, true
+	}
+	value := ParseTime(p.source)
+	return value, !value.IsZero()
+}
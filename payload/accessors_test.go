@@ -0,0 +1,70 @@
+package payload_test
+
+import (
+	"time"
+
+	. "github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Null-safe accessors", func() {
+	p := New(map[string]interface{}{
+		"name":    "John",
+		"age":     0,
+		"height":  1.8,
+		"active":  false,
+		"created": "2019-05-01T18:57:06Z",
+		"nothing": nil,
+	})
+
+	It("IsNil should be true for missing and nil values", func() {
+		Expect(p.Get("nothing").IsNil()).Should(BeTrue())
+		Expect(p.Get("missing").IsNil()).Should(BeTrue())
+		Expect(p.Get("age").IsNil()).Should(BeFalse())
+		Expect(p.Get("active").IsNil()).Should(BeFalse())
+	})
+
+	It("GetOrDefault should return the default only for missing and nil values", func() {
+		Expect(p.GetOrDefault("missing", 18).Int()).Should(Equal(18))
+		Expect(p.GetOrDefault("nothing", "none").String()).Should(Equal("none"))
+		Expect(p.GetOrDefault("age", 18).Int()).Should(Equal(0))
+		Expect(p.GetOrDefault("name", "Jon").String()).Should(Equal("John"))
+	})
+
+	It("typed accessors should tell missing values apart from zero values", func() {
+		age, ok := p.Get("age").IntOk()
+		Expect(age).Should(Equal(0))
+		Expect(ok).Should(BeTrue())
+		_, ok = p.Get("missing").IntOk()
+		Expect(ok).Should(BeFalse())
+		_, ok = p.Get("name").IntOk()
+		Expect(ok).Should(BeFalse())
+
+		age64, ok := p.Get("age").Int64Ok()
+		Expect(age64).Should(Equal(int64(0)))
+		Expect(ok).Should(BeTrue())
+
+		height, ok := p.Get("height").FloatOk()
+		Expect(height).Should(Equal(1.8))
+		Expect(ok).Should(BeTrue())
+
+		active, ok := p.Get("active").BoolOk()
+		Expect(active).Should(BeFalse())
+		Expect(ok).Should(BeTrue())
+		_, ok = p.Get("nothing").BoolOk()
+		Expect(ok).Should(BeFalse())
+
+		name, ok := p.Get("name").StringOk()
+		Expect(name).Should(Equal("John"))
+		Expect(ok).Should(BeTrue())
+		_, ok = p.Get("age").StringOk()
+		Expect(ok).Should(BeFalse())
+
+		created, ok := p.Get("created").TimeOk()
+		Expect(created).Should(Equal(time.Date(2019, 5, 1, 18, 57, 6, 0, time.UTC)))
+		Expect(ok).Should(BeTrue())
+		_, ok = p.Get("name").TimeOk()
+		Expect(ok).Should(BeFalse())
+	})
+})
@@ -0,0 +1,80 @@
+package payload
+
+import (
+	"reflect"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// Clone return a deep copy of the payload. Maps, slices, arrays and nested payloads are copied,
+// keeping their types, so changes to the values of the copy do not affect the source.
+// Pointers to structs point to a copy of the struct. Errors and streams are shared.
+func Clone(source moleculer.Payload) moleculer.Payload {
+	if source.IsError() {
+		return source
+	}
+	return New(cloneValue(source.Value()))
+}
+
+func cloneValue(value interface{}) interface{} {
+	if value == nil {
+		return nil
+	}
+	if pl, isPayload := value.(moleculer.Payload); isPayload {
+		return pl.Clone()
+	}
+	return cloneReflect(reflect.ValueOf(value)).Interface()
+}
+
+func cloneReflect(value reflect.Value) reflect.Value {
+	switch value.Kind() {
+	case reflect.Map:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.MakeMapWithSize(value.Type(), value.Len())
+		for _, key := range value.MapKeys() {
+			result.SetMapIndex(key, cloneItem(value.MapIndex(key)))
+		}
+		return result
+	case reflect.Slice:
+		if value.IsNil() {
+			return value
+		}
+		result := reflect.MakeSlice(value.Type(), value.Len(), value.Len())
+		for index := 0; index < value.Len(); index++ {
+			result.Index(index).Set(cloneItem(value.Index(index)))
+		}
+		return result
+	case reflect.Array:
+		result := reflect.New(value.Type()).Elem()
+		for index := 0; index < value.Len(); index++ {
+			result.Index(index).Set(cloneItem(value.Index(index)))
+		}
+		return result
+	case reflect.Ptr:
+		if value.IsNil() || value.Elem().Kind() != reflect.Struct {
+			return value
+		}
+		result := reflect.New(value.Elem().Type())
+		result.Elem().Set(value.Elem())
+		return result
+	}
+	return value
+}
+
+// cloneItem clone an item of a map, slice or array. Interface items are cloned by their
+// dynamic type, so a []interface{} with nested maps is copied all the way down.
+func cloneItem(item reflect.Value) reflect.Value {
+	if item.Kind() != reflect.Interface {
+		return cloneReflect(item)
+	}
+	if item.IsNil() {
+		return item
+	}
+	cloned := cloneValue(item.Interface())
+	if cloned == nil {
+		return reflect.Zero(item.Type())
+	}
+	return reflect.ValueOf(cloned)
+}
@@ -0,0 +1,60 @@
+package payload_test
+
+import (
+	"errors"
+
+	. "github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type person struct {
+	Name string
+}
+
+var _ = Describe("Clone", func() {
+
+	It("should deep copy maps, lists and nested payloads keeping their types", func() {
+		source := New(map[string]interface{}{
+			"name": "John",
+			"address": map[string]string{
+				"city": "Winterfell",
+			},
+			"tags":    []interface{}{"stark", map[string]interface{}{"house": "Stark"}},
+			"friend":  New(map[string]interface{}{"name": "Sam"}),
+			"father":  &person{Name: "Ned"},
+			"content": []byte("winter"),
+		})
+		clone := source.Clone()
+		Expect(clone.RawMap()).Should(Equal(source.RawMap()))
+
+		clone.RawMap()["name"] = "Jon"
+		clone.Get("address").Value().(map[string]string)["city"] = "Castle Black"
+		clone.Get("tags").Value().([]interface{})[1].(map[string]interface{})["house"] = "Targaryen"
+		clone.Get("friend").RawMap()["name"] = "Samwell"
+		clone.Get("father").Value().(*person).Name = "Rhaegar"
+		clone.Get("content").Value().([]byte)[0] = 'W'
+
+		Expect(source.Get("name").String()).Should(Equal("John"))
+		Expect(source.Get("address").Get("city").String()).Should(Equal("Winterfell"))
+		Expect(source.Get("tags").Array()[1].Get("house").String()).Should(Equal("Stark"))
+		Expect(source.Get("friend").Get("name").String()).Should(Equal("Sam"))
+		Expect(source.Get("father").Value().(*person).Name).Should(Equal("Ned"))
+		Expect(string(source.Get("content").Value().([]byte))).Should(Equal("winter"))
+	})
+
+	It("should clone lists and simple values", func() {
+		list := New([]string{"a", "b"})
+		clone := list.Clone()
+		clone.Value().([]string)[0] = "z"
+		Expect(list.StringArray()).Should(Equal([]string{"a", "b"}))
+
+		Expect(New(10).Clone().Int()).Should(Equal(10))
+		Expect(New(nil).Clone().Exists()).Should(BeFalse())
+	})
+
+	It("should return error payloads as they are", func() {
+		source := New(errors.New("some error"))
+		Expect(source.Clone()).Should(BeIdenticalTo(source))
+	})
+})
@@ -0,0 +1,138 @@
+package payload
+
+import (
+	"reflect"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// Merge return a new payload with the values of other deep merged over the values of source.
+// Nested maps are merged, any other value (including lists) in other replaces the source value.
+// When any of them is not a map, other is returned.
+func Merge(source, other moleculer.Payload) moleculer.Payload {
+	if !source.IsMap() || !other.IsMap() {
+		return other
+	}
+	return New(mergeMaps(plainMap(source.Value()), plainMap(other.Value())))
+}
+
+func mergeMaps(source, other map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(source))
+	for key, value := range source {
+		result[key] = value
+	}
+	for key, value := range other {
+		sourceMap, sourceIsMap := result[key].(map[string]interface{})
+		otherMap, otherIsMap := value.(map[string]interface{})
+		if sourceIsMap && otherIsMap {
+			result[key] = mergeMaps(sourceMap, otherMap)
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// Diff return a new payload with the fields of other that are new or different from source.
+// Nested maps are compared field by field and fields removed in other are returned with a nil value.
+// When any of them is not a map, Diff return an empty payload if they are equal and other otherwise.
+func Diff(source, other moleculer.Payload) moleculer.Payload {
+	if !source.IsMap() || !other.IsMap() {
+		if equalValues(plainValue(source.Value()), plainValue(other.Value())) {
+			return Empty()
+		}
+		return other
+	}
+	return New(diffMaps(plainMap(source.Value()), plainMap(other.Value())))
+}
+
+func diffMaps(source, other map[string]interface{}) map[string]interface{} {
+	result := map[string]interface{}{}
+	for key, value := range other {
+		sourceValue, exists := source[key]
+		if !exists {
+			result[key] = value
+			continue
+		}
+		sourceMap, sourceIsMap := sourceValue.(map[string]interface{})
+		otherMap, otherIsMap := value.(map[string]interface{})
+		if sourceIsMap && otherIsMap {
+			if nested := diffMaps(sourceMap, otherMap); len(nested) > 0 {
+				result[key] = nested
+			}
+			continue
+		}
+		if !equalValues(sourceValue, value) {
+			result[key] = value
+		}
+	}
+	for key := range source {
+		if _, exists := other[key]; !exists {
+			result[key] = nil
+		}
+	}
+	return result
+}
+
+func plainMap(value interface{}) map[string]interface{} {
+	if plain, isMap := plainValue(value).(map[string]interface{}); isMap {
+		return plain
+	}
+	return map[string]interface{}{}
+}
+
+// plainValue convert payloads, typed maps and lists into map[string]interface{} and []interface{}.
+func plainValue(value interface{}) interface{} {
+	if pl, isPayload := value.(moleculer.Payload); isPayload {
+		return plainValue(pl.Value())
+	}
+	switch value.(type) {
+	case nil, string, []byte:
+		return value
+	}
+	if transformer := MapTransformer(&value); transformer != nil {
+		source := transformer.AsMap(&value)
+		result := make(map[string]interface{}, len(source))
+		for key, item := range source {
+			result[key] = plainValue(item)
+		}
+		return result
+	}
+	if transformer := ArrayTransformer(&value); transformer != nil {
+		source := transformer.InterfaceArray(&value)
+		result := make([]interface{}, len(source))
+		for index, item := range source {
+			result[index] = plainValue(item)
+		}
+		return result
+	}
+	return value
+}
+
+// equalValues compare plain values. Numbers are compared by value, so 1 and 1.0 are equal.
+func equalValues(a, b interface{}) bool {
+	na, aNumber := numberValue(a)
+	nb, bNumber := numberValue(b)
+	if aNumber && bNumber {
+		return na == nb
+	}
+	la, aList := a.([]interface{})
+	lb, bList := b.([]interface{})
+	if aList && bList {
+		if len(la) != len(lb) {
+			return false
+		}
+		for index := range la {
+			if !equalValues(la[index], lb[index]) {
+				return false
+			}
+		}
+		return true
+	}
+	ma, aMap := a.(map[string]interface{})
+	mb, bMap := b.(map[string]interface{})
+	if aMap && bMap {
+		return len(ma) == len(mb) && len(diffMaps(ma, mb)) == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
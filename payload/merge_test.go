@@ -0,0 +1,62 @@
+package payload_test
+
+import (
+	. "github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Merge and Diff", func() {
+	source := New(map[string]interface{}{
+		"name": "John",
+		"age":  23,
+		"address": map[string]string{
+			"city":    "Winterfell",
+			"country": "North",
+		},
+		"tags": []string{"stark", "snow"},
+	})
+
+	It("Merge should deep merge the values into a new payload", func() {
+		merged := source.Merge(New(map[string]interface{}{
+			"age":     24,
+			"address": map[string]interface{}{"city": "Castle Black"},
+			"tags":    []string{"watch"},
+		}))
+		Expect(merged.Get("name").String()).Should(Equal("John"))
+		Expect(merged.Get("age").Int()).Should(Equal(24))
+		Expect(merged.Get("address").Get("city").String()).Should(Equal("Castle Black"))
+		Expect(merged.Get("address").Get("country").String()).Should(Equal("North"))
+		Expect(merged.Get("tags").StringArray()).Should(Equal([]string{"watch"}))
+
+		Expect(source.Get("age").Int()).Should(Equal(23))
+		Expect(source.Get("address").Get("city").String()).Should(Equal("Winterfell"))
+	})
+
+	It("Merge should return other when the payloads are not maps", func() {
+		Expect(New(10).Merge(New(20)).Int()).Should(Equal(20))
+	})
+
+	It("Diff should return the new, changed and removed fields", func() {
+		diff := source.Diff(New(map[string]interface{}{
+			"name":    "John",
+			"age":     float64(23),
+			"address": map[string]interface{}{"city": "Castle Black", "country": "North"},
+			"tags":    []interface{}{"stark", "snow"},
+			"title":   "Lord Commander",
+		}))
+		Expect(diff.RawMap()).Should(Equal(map[string]interface{}{
+			"address": map[string]interface{}{"city": "Castle Black"},
+			"title":   "Lord Commander",
+		}))
+
+		removed := source.Diff(New(map[string]interface{}{"name": "John"}))
+		Expect(removed.RawMap()).Should(HaveKeyWithValue("age", BeNil()))
+		Expect(removed.RawMap()).Should(HaveKey("address"))
+		Expect(removed.RawMap()).ShouldNot(HaveKey("name"))
+
+		Expect(source.Diff(source).Len()).Should(Equal(0))
+		Expect(New(10).Diff(New(10)).Len()).Should(Equal(0))
+		Expect(New(10).Diff(New(11)).Int()).Should(Equal(11))
+	})
+})
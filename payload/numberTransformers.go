@@ -1,6 +1,7 @@
 package payload
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 )
@@ -23,6 +24,21 @@ type numberTransformer struct {
 	toUint64 toUint64Func
 }
 
+// stringToInt64 parse integers without going through float64, so 64-bit values keep their precision.
+func stringToInt64(value string) int64 {
+	if ivalue, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return ivalue
+	}
+	return int64(stringToFloat64(value))
+}
+
+func stringToUint64(value string) uint64 {
+	if uvalue, err := strconv.ParseUint(value, 10, 64); err == nil {
+		return uvalue
+	}
+	return uint64(stringToFloat64(value))
+}
+
 func stringToFloat64(value string) float64 {
 	fvalue, err := strconv.ParseFloat(value, 64)
 	if err != nil {
@@ -38,7 +54,7 @@ var numberTransformers = []numberTransformer{
 			return int(stringToFloat64((*source).(string)))
 		},
 		toInt64: func(source *interface{}) int64 {
-			return int64(stringToFloat64((*source).(string)))
+			return stringToInt64((*source).(string))
 		},
 		toFloat32: func(source *interface{}) float32 {
 			return float32(stringToFloat64((*source).(string)))
@@ -47,7 +63,25 @@ var numberTransformers = []numberTransformer{
 			return float64(stringToFloat64((*source).(string)))
 		},
 		toUint64: func(source *interface{}) uint64 {
-			return uint64(stringToFloat64((*source).(string)))
+			return stringToUint64((*source).(string))
+		},
+	},
+	{
+		name: "json.Number",
+		toInt: func(source *interface{}) int {
+			return int(stringToInt64(string((*source).(json.Number))))
+		},
+		toInt64: func(source *interface{}) int64 {
+			return stringToInt64(string((*source).(json.Number)))
+		},
+		toFloat32: func(source *interface{}) float32 {
+			return float32(stringToFloat64(string((*source).(json.Number))))
+		},
+		toFloat64: func(source *interface{}) float64 {
+			return stringToFloat64(string((*source).(json.Number)))
+		},
+		toUint64: func(source *interface{}) uint64 {
+			return stringToUint64(string((*source).(json.Number)))
 		},
 	},
 	{
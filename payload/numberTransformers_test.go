@@ -0,0 +1,24 @@
+package payload_test
+
+import (
+	"encoding/json"
+
+	. "github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Number transformers", func() {
+
+	It("should convert json.Number and numeric strings without losing precision", func() {
+		Expect(New(json.Number("9007199254740993")).Int64()).Should(Equal(int64(9007199254740993)))
+		Expect(New(json.Number("18446744073709551615")).Uint()).Should(Equal(uint64(18446744073709551615)))
+		Expect(New(json.Number("10.5")).Float()).Should(Equal(10.5))
+		Expect(New(json.Number("10.5")).Int()).Should(Equal(10))
+		Expect(New("9007199254740993").Int64()).Should(Equal(int64(9007199254740993)))
+
+		value, ok := New(json.Number("42")).IntOk()
+		Expect(value).Should(Equal(42))
+		Expect(ok).Should(BeTrue())
+	})
+})
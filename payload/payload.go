@@ -1,6 +1,8 @@
 package payload
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sort"
@@ -72,8 +74,14 @@ func (p *RawPayload) Uint() uint64 {
 	return value
 }
 
+// Time return the time value. Strings and numbers (milliseconds since epoch) are converted with ParseTime.
 func (p *RawPayload) Time() time.Time {
-	return p.source.(time.Time)
+	return ParseTime(p.source)
+}
+
+// Duration return the duration value. Strings and numbers (milliseconds) are converted with ParseDuration.
+func (p *RawPayload) Duration() time.Duration {
+	return ParseDuration(p.source)
 }
 
 func (p *RawPayload) StringArray() []string {
@@ -179,14 +187,31 @@ func (p *RawPayload) BoolArray() []bool {
 	return nil
 }
 
-func (p *RawPayload) ByteArray() []byte {
-	ba, ok := p.source.([]byte)
-	if ok {
-		return ba
+// Bytes return the binary value. Strings are decoded as base64, since it is how
+// the JSON serializer encodes binary values.
+func (p *RawPayload) Bytes() []byte {
+	switch value := p.source.(type) {
+	case []byte:
+		return value
+	case string:
+		return decodeBase64(value)
 	}
 	return nil
 }
 
+// ByteArray is the same as Bytes.
+func (p *RawPayload) ByteArray() []byte {
+	return p.Bytes()
+}
+
+func decodeBase64(value string) []byte {
+	decoded, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil
+	}
+	return decoded
+}
+
 func (p *RawPayload) TimeArray() []time.Time {
 	if source := p.Array(); source != nil {
 		array := make([]time.Time, len(source))
@@ -198,6 +223,17 @@ func (p *RawPayload) TimeArray() []time.Time {
 	return nil
 }
 
+func (p *RawPayload) DurationArray() []time.Duration {
+	if source := p.Array(); source != nil {
+		array := make([]time.Duration, len(source))
+		for index, item := range source {
+			array[index] = item.Duration()
+		}
+		return array
+	}
+	return nil
+}
+
 func (p *RawPayload) Len() int {
 	if transformer := ArrayTransformer(&p.source); transformer != nil {
 		return transformer.ArrayLen(&p.source)
@@ -465,12 +501,49 @@ func (s *Sortable) Len() int {
 
 // Less reports whether the element with
 // index i should sort before the element with index j.
+// Numbers and times are compared by value, other values by their string representation.
 func (s *Sortable) Less(i, j int) bool {
 	vi := s.List[i].Get(s.Field)
 	vj := s.List[j].Get(s.Field)
+	ni, iNumber := numberValue(vi.Value())
+	nj, jNumber := numberValue(vj.Value())
+	if iNumber && jNumber {
+		return ni < nj
+	}
+	ti, iTime := vi.Value().(time.Time)
+	tj, jTime := vj.Value().(time.Time)
+	if iTime && jTime {
+		return ti.Before(tj)
+	}
 	return vi.String() < vj.String()
 }
 
+func numberValue(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case int:
+		return float64(number), true
+	case int32:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case uint:
+		return float64(number), true
+	case uint32:
+		return float64(number), true
+	case uint64:
+		return float64(number), true
+	case float32:
+		return float64(number), true
+	case float64:
+		return number, true
+	case json.Number:
+		if float, err := number.Float64(); err == nil {
+			return float, true
+		}
+	}
+	return 0, false
+}
+
 // Swap swaps the elements with indexes i and j.
 func (s *Sortable) Swap(i, j int) {
 	vi := s.List[i]
@@ -492,6 +565,46 @@ func (p *RawPayload) Sort(field string) moleculer.Payload {
 	return ps.Payload()
 }
 
+func (p *RawPayload) Filter(predicate func(item moleculer.Payload) bool) moleculer.Payload {
+	if !p.IsArray() {
+		return p
+	}
+	return FilterList(p.Array(), predicate)
+}
+
+func (p *RawPayload) Slice(offset, limit int) moleculer.Payload {
+	if !p.IsArray() {
+		return p
+	}
+	return SliceList(p.Array(), offset, limit)
+}
+
+// FilterList return a list payload with the items that match the predicate.
+func FilterList(list []moleculer.Payload, predicate func(item moleculer.Payload) bool) moleculer.Payload {
+	result := []moleculer.Payload{}
+	for _, item := range list {
+		if predicate(item) {
+			result = append(result, item)
+		}
+	}
+	return New(result)
+}
+
+// SliceList return a list payload with up to limit items starting at offset. A limit <= 0 return all items after the offset.
+func SliceList(list []moleculer.Payload, offset, limit int) moleculer.Payload {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(list) {
+		offset = len(list)
+	}
+	end := len(list)
+	if limit > 0 && offset+limit < end {
+		end = offset + limit
+	}
+	return New(list[offset:end])
+}
+
 func (p *RawPayload) Remove(fields ...string) moleculer.Payload {
 	if p.IsMap() {
 		new := map[string]interface{}{}
@@ -527,7 +640,7 @@ func (p *RawPayload) Add(field string, value interface{}) moleculer.Payload {
 	if !p.IsMap() {
 		return Error("payload.Add can only deal with map payloads.")
 	}
-	m := p.RawMap()
+	m := p.copyMap()
 	m[field] = value
 	return New(m)
 }
@@ -537,13 +650,35 @@ func (p *RawPayload) AddMany(toAdd map[string]interface{}) moleculer.Payload {
 	if !p.IsMap() {
 		return Error("payload.Add can only deal with map payloads.")
 	}
-	m := p.RawMap()
+	m := p.copyMap()
 	for key, value := range toAdd {
 		m[key] = value
 	}
 	return New(m)
 }
 
+func (p *RawPayload) Merge(other moleculer.Payload) moleculer.Payload {
+	return Merge(p, other)
+}
+
+func (p *RawPayload) Diff(other moleculer.Payload) moleculer.Payload {
+	return Diff(p, other)
+}
+
+func (p *RawPayload) Clone() moleculer.Payload {
+	return Clone(p)
+}
+
+// copyMap return a shallow copy of the map values, so changes do not affect the source payload.
+func (p *RawPayload) copyMap() map[string]interface{} {
+	source := p.RawMap()
+	m := make(map[string]interface{}, len(source))
+	for key, value := range source {
+		m[key] = value
+	}
+	return m
+}
+
 func Error(msgs ...interface{}) moleculer.Payload {
 	return New(errors.New(fmt.Sprint(msgs...)))
 }
@@ -62,6 +62,49 @@ var _ = Describe("Payload", func() {
 		Expect(snap.SnapshotMulti("Add()", m)).ShouldNot(HaveOccurred())
 	})
 
+	It("Add and AddMany should not change the source payload", func() {
+		source := map[string]interface{}{"name": "John"}
+		p := New(source)
+
+		m := p.Add("lastname", "Snow").AddMany(map[string]interface{}{"page": 1})
+		Expect(m.Get("lastname").String()).Should(Equal("Snow"))
+		Expect(m.Get("page").Int()).Should(Equal(1))
+		Expect(p.Get("lastname").Exists()).Should(BeFalse())
+		Expect(source).Should(HaveLen(1))
+	})
+
+	It("Bytes should return binary values and decode base64 strings", func() {
+		content := []byte{0, 1, 2, 255}
+		Expect(New(content).Bytes()).Should(Equal(content))
+		Expect(New("AAEC/w==").Bytes()).Should(Equal(content))
+		Expect(New(map[string]interface{}{"file": content}).Get("file").ByteArray()).Should(Equal(content))
+		Expect(New(10).Bytes()).Should(BeNil())
+	})
+
+	It("Filter, Sort and Slice should be chainable on lists", func() {
+		p := New([]map[string]interface{}{
+			{"name": "John", "age": 23},
+			{"name": "Arya", "age": 9},
+			{"name": "Sansa", "age": 13},
+			{"name": "Robb", "age": 25},
+		})
+
+		teens := p.Filter(func(item moleculer.Payload) bool {
+			return item.Get("age").Int() < 24
+		}).Sort("age")
+		Expect(teens.Len()).Should(Equal(3))
+		Expect(teens.First().Get("name").String()).Should(Equal("Arya"))
+
+		page := p.Sort("age").Slice(1, 2)
+		Expect(page.Len()).Should(Equal(2))
+		Expect(page.Array()[0].Get("name").String()).Should(Equal("Sansa"))
+		Expect(page.Array()[1].Get("name").String()).Should(Equal("John"))
+
+		Expect(p.Slice(3, 0).Len()).Should(Equal(1))
+		Expect(p.Slice(10, 5).Len()).Should(Equal(0))
+		Expect(p.Slice(10, 5).First().Exists()).Should(BeFalse())
+	})
+
 	type customMap map[string]interface{}
 	type customArray []map[string]interface{}
 
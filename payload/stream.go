@@ -0,0 +1,51 @@
+package payload
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// DecodeArrayStream decode the items of a JSON array one at a time from the reader and call the
+// iterator for each one, so big lists can be processed without loading the whole list in memory.
+// The decoding stops when the iterator returns false.
+func DecodeArrayStream(reader io.Reader, iterator func(item moleculer.Payload) bool) error {
+	decoder := json.NewDecoder(reader)
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, isDelim := token.(json.Delim); !isDelim || delim != '[' {
+		return errors.New("payload.DecodeArrayStream() the stream does not contain a JSON array")
+	}
+	for decoder.More() {
+		var item interface{}
+		if err := decoder.Decode(&item); err != nil {
+			return err
+		}
+		if !iterator(New(item)) {
+			return nil
+		}
+	}
+	_, err = decoder.Token()
+	return err
+}
+
+// DecodeArrayStream call the iterator for each item of the list. When the payload is a
+// stream (io.Reader) the items are decoded from the JSON array as they are read.
+func (p *RawPayload) DecodeArrayStream(iterator func(item moleculer.Payload) bool) error {
+	if reader, isReader := p.source.(io.Reader); isReader {
+		return DecodeArrayStream(reader, iterator)
+	}
+	if !p.IsArray() {
+		return errors.New("payload.DecodeArrayStream() can only deal with lists and streams.")
+	}
+	for _, item := range p.Array() {
+		if !iterator(item) {
+			break
+		}
+	}
+	return nil
+}
@@ -0,0 +1,62 @@
+package payload_test
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/moleculer-go/moleculer"
+	. "github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Array streams", func() {
+
+	It("should decode the items as they are written to the stream", func() {
+		reader, writer := io.Pipe()
+		go func() {
+			writer.Write([]byte("["))
+			for i := 0; i < 1000; i++ {
+				if i > 0 {
+					writer.Write([]byte(","))
+				}
+				writer.Write([]byte(fmt.Sprintf(`{"index":%d}`, i)))
+			}
+			writer.Write([]byte("]"))
+			writer.Close()
+		}()
+
+		count := 0
+		err := New(reader).DecodeArrayStream(func(item moleculer.Payload) bool {
+			Expect(item.Get("index").Int()).Should(Equal(count))
+			count++
+			return true
+		})
+		Expect(err).Should(BeNil())
+		Expect(count).Should(Equal(1000))
+	})
+
+	It("should stop when the iterator returns false", func() {
+		items := []string{}
+		err := DecodeArrayStream(strings.NewReader(`["a","b","c"]`), func(item moleculer.Payload) bool {
+			items = append(items, item.String())
+			return len(items) < 2
+		})
+		Expect(err).Should(BeNil())
+		Expect(items).Should(Equal([]string{"a", "b"}))
+	})
+
+	It("should iterate over lists and fail for other values", func() {
+		count := 0
+		Expect(New([]int{1, 2, 3}).DecodeArrayStream(func(item moleculer.Payload) bool {
+			count += item.Int()
+			return true
+		})).Should(Succeed())
+		Expect(count).Should(Equal(6))
+
+		Expect(New(10).DecodeArrayStream(func(item moleculer.Payload) bool { return true })).ShouldNot(Succeed())
+		Expect(DecodeArrayStream(strings.NewReader(`{"a":1}`), func(item moleculer.Payload) bool { return true })).ShouldNot(Succeed())
+		Expect(DecodeArrayStream(strings.NewReader(`[1, 2`), func(item moleculer.Payload) bool { return true })).ShouldNot(Succeed())
+	})
+})
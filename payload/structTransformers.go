@@ -0,0 +1,167 @@
+package payload
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Create return a payload for the source, converting structs (including nested structs,
+// slices and maps of structs) into maps keyed by the json tag names.
+// time.Time and error values are kept as they are, so they can be read with payload.Time() and payload.Error().
+func Create(source interface{}) moleculer.Payload {
+	if pl, isPayload := source.(moleculer.Payload); isPayload {
+		return pl
+	}
+	return New(toPlainValue(reflect.ValueOf(source)))
+}
+
+// toPlainValue convert structs into maps and walk slices and maps converting their items.
+func toPlainValue(value reflect.Value) interface{} {
+	if !value.IsValid() {
+		return nil
+	}
+	if value.Type().Implements(errorType) && value.CanInterface() {
+		return value.Interface()
+	}
+	if value.Kind() == reflect.Ptr || value.Kind() == reflect.Interface {
+		if value.IsNil() {
+			return nil
+		}
+		if _, isPayload := value.Interface().(moleculer.Payload); isPayload {
+			return value.Interface()
+		}
+		return toPlainValue(value.Elem())
+	}
+	switch value.Kind() {
+	case reflect.Struct:
+		if value.Type() == timeType {
+			return value.Interface()
+		}
+		result := map[string]interface{}{}
+		structToMap(value, result)
+		return result
+	case reflect.Slice, reflect.Array:
+		if value.Kind() == reflect.Slice && value.IsNil() {
+			return nil
+		}
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			return value.Interface()
+		}
+		result := make([]interface{}, value.Len())
+		for i := 0; i < value.Len(); i++ {
+			result[i] = toPlainValue(value.Index(i))
+		}
+		return result
+	case reflect.Map:
+		if value.IsNil() {
+			return nil
+		}
+		if value.Type().Key().Kind() != reflect.String {
+			return value.Interface()
+		}
+		result := make(map[string]interface{}, value.Len())
+		for _, key := range value.MapKeys() {
+			result[key.String()] = toPlainValue(value.MapIndex(key))
+		}
+		return result
+	}
+	return value.Interface()
+}
+
+// structToMap add the exported fields of the struct to the map, following the encoding/json
+// rules: the json tag name is used when present, "-" skips the field, omitempty skips empty
+// values and the fields of embedded structs without a tag are promoted.
+func structToMap(value reflect.Value, result map[string]interface{}) {
+	valueType := value.Type()
+	for i := 0; i < valueType.NumField(); i++ {
+		field := valueType.Field(i)
+		name, omitEmpty, skip := jsonFieldName(field)
+		if skip {
+			continue
+		}
+		fieldValue := value.Field(i)
+		if field.Anonymous && field.Tag.Get("json") == "" {
+			embedded := fieldValue
+			if embedded.Kind() == reflect.Ptr {
+				if embedded.IsNil() {
+					continue
+				}
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct && embedded.Type() != timeType {
+				structToMap(embedded, result)
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if omitEmpty && isEmptyValue(fieldValue) {
+			continue
+		}
+		result[name] = toPlainValue(fieldValue)
+	}
+}
+
+func jsonFieldName(field reflect.StructField) (name string, omitEmpty bool, skip bool) {
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false, true
+	}
+	parts := strings.Split(tag, ",")
+	name = parts[0]
+	if name == "" {
+		name = field.Name
+	}
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+	return name, omitEmpty, false
+}
+
+func isEmptyValue(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Array, reflect.Map, reflect.Slice, reflect.String:
+		return value.Len() == 0
+	case reflect.Bool:
+		return !value.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return value.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() == 0
+	case reflect.Interface, reflect.Ptr:
+		return value.IsNil()
+	}
+	return false
+}
+
+// MarshalJSON encode the payload source, so payloads can be nested in values encoded with encoding/json.
+func (p *RawPayload) MarshalJSON() ([]byte, error) {
+	if p.IsError() {
+		return json.Marshal(p.Error().Error())
+	}
+	return json.Marshal(p.source)
+}
+
+// Unmarshal decode the payload into the target, following the encoding/json rules (json tags, nested structs, slices and time.Time).
+func (p *RawPayload) Unmarshal(target interface{}) error {
+	if p.IsError() {
+		return p.Error()
+	}
+	data, err := json.Marshal(p.source)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
@@ -0,0 +1,87 @@
+package payload_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type address struct {
+	City    string `json:"city"`
+	Country string `json:"country,omitempty"`
+}
+
+type audit struct {
+	CreatedBy string `json:"createdBy"`
+}
+
+type user struct {
+	audit
+	Name      string     `json:"name"`
+	Email     string     `json:"-"`
+	Age       int        `json:"age"`
+	Address   address    `json:"address"`
+	Previous  []*address `json:"previous"`
+	Tags      []string   `json:"tags,omitempty"`
+	CreatedAt time.Time  `json:"createdAt"`
+	Nickname  string
+	password  string
+}
+
+var _ = Describe("Struct payloads", func() {
+	createdAt := time.Date(2019, 5, 1, 18, 57, 6, 0, time.UTC)
+	source := user{
+		audit:     audit{CreatedBy: "admin"},
+		Name:      "John",
+		Email:     "john@snow.com",
+		Age:       23,
+		Address:   address{City: "Winterfell", Country: "North"},
+		Previous:  []*address{&address{City: "Castle Black"}},
+		CreatedAt: createdAt,
+		Nickname:  "Lord Snow",
+		password:  "ghost",
+	}
+
+	It("Create should convert structs into map payloads using the json tags", func() {
+		p := Create(&source)
+		Expect(p.IsMap()).Should(BeTrue())
+		Expect(p.Get("name").String()).Should(Equal("John"))
+		Expect(p.Get("age").Int()).Should(Equal(23))
+		Expect(p.Get("createdBy").String()).Should(Equal("admin"))
+		Expect(p.Get("Nickname").String()).Should(Equal("Lord Snow"))
+		Expect(p.Get("address").Get("city").String()).Should(Equal("Winterfell"))
+		Expect(p.Get("previous").First().Get("city").String()).Should(Equal("Castle Black"))
+		Expect(p.Get("previous").First().Get("country").Exists()).Should(BeFalse())
+		Expect(p.Get("createdAt").Time()).Should(Equal(createdAt))
+		Expect(p.Get("Email").Exists()).Should(BeFalse())
+		Expect(p.Get("tags").Exists()).Should(BeFalse())
+		Expect(p.Get("password").Exists()).Should(BeFalse())
+	})
+
+	It("Create should keep errors and plain values", func() {
+		Expect(Create(errors.New("some error")).Error()).Should(MatchError("some error"))
+		Expect(Create(10).Int()).Should(Equal(10))
+		Expect(Create([]address{address{City: "Braavos"}}).First().Get("city").String()).Should(Equal("Braavos"))
+	})
+
+	It("Unmarshal should decode the payload into structs", func() {
+		var target user
+		Expect(Create(source).Unmarshal(&target)).Should(Succeed())
+		Expect(target.Name).Should(Equal("John"))
+		Expect(target.CreatedBy).Should(Equal("admin"))
+		Expect(target.Address).Should(Equal(source.Address))
+		Expect(target.Previous).Should(HaveLen(1))
+		Expect(target.Previous[0].City).Should(Equal("Castle Black"))
+		Expect(target.CreatedAt.Equal(createdAt)).Should(BeTrue())
+		Expect(target.Email).Should(BeEmpty())
+
+		var list []address
+		Expect(New([]interface{}{New(map[string]interface{}{"city": "Braavos"})}).Unmarshal(&list)).Should(Succeed())
+		Expect(list).Should(Equal([]address{address{City: "Braavos"}}))
+
+		Expect(New(errors.New("failed")).Unmarshal(&target)).Should(MatchError("failed"))
+	})
+})
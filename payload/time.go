@@ -0,0 +1,94 @@
+package payload
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeFormat is the format used to serialize time values: RFC3339 with fractional seconds.
+// It is compatible with the JSON encoding of a JS Date (Date.prototype.toJSON).
+const TimeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// timeLayouts are the layouts accepted when parsing a time string.
+var timeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// FormatTime format the time value using TimeFormat.
+func FormatTime(value time.Time) string {
+	return value.Format(TimeFormat)
+}
+
+// FormatDuration return the duration in milliseconds, the unit used by moleculer-js for timeouts and intervals.
+func FormatDuration(value time.Duration) float64 {
+	return float64(value) / float64(time.Millisecond)
+}
+
+// ParseTime convert the value into a time. Strings are parsed as RFC3339 (with or without
+// fractional seconds and timezone) and numbers are milliseconds since the Unix epoch, like
+// the JS Date.getTime(). It returns the zero time when the value can't be converted.
+func ParseTime(value interface{}) time.Time {
+	switch source := value.(type) {
+	case time.Time:
+		return source
+	case *time.Time:
+		if source != nil {
+			return *source
+		}
+		return time.Time{}
+	case string:
+		return parseTimeString(source)
+	}
+	if number, isNumber := numberValue(value); isNumber {
+		return time.Unix(0, millisToNanos(number))
+	}
+	return time.Time{}
+}
+
+func parseTimeString(value string) time.Time {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if parsed, err := time.Parse(layout, value); err == nil {
+			return parsed
+		}
+	}
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return ParseTime(number)
+	}
+	return time.Time{}
+}
+
+// ParseDuration convert the value into a duration. Strings are parsed with time.ParseDuration
+// ("1m30s") or as milliseconds when they are numbers, and numbers are milliseconds.
+// It returns 0 when the value can't be converted.
+func ParseDuration(value interface{}) time.Duration {
+	switch source := value.(type) {
+	case time.Duration:
+		return source
+	case string:
+		if parsed, err := time.ParseDuration(strings.TrimSpace(source)); err == nil {
+			return parsed
+		}
+		if number, err := strconv.ParseFloat(strings.TrimSpace(source), 64); err == nil {
+			return ParseDuration(number)
+		}
+		return 0
+	}
+	if number, isNumber := numberValue(value); isNumber {
+		return time.Duration(millisToNanos(number))
+	}
+	return 0
+}
+
+// millisToNanos convert milliseconds to nanoseconds without losing the precision of big
+// values, like the milliseconds since epoch.
+func millisToNanos(millis float64) int64 {
+	whole := math.Trunc(millis)
+	return int64(whole)*int64(time.Millisecond) + int64(math.Round((millis-whole)*float64(time.Millisecond)))
+}
@@ -0,0 +1,55 @@
+package payload_test
+
+import (
+	"time"
+
+	. "github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Time and Duration", func() {
+	created := time.Date(2019, 5, 1, 18, 57, 6, 123000000, time.UTC)
+
+	It("should format times with fractional seconds", func() {
+		Expect(FormatTime(created)).Should(Equal("2019-05-01T18:57:06.123000000Z"))
+		Expect(FormatTime(time.Date(2019, 5, 1, 18, 57, 6, 0, time.UTC))).Should(Equal("2019-05-01T18:57:06.000000000Z"))
+		Expect(FormatDuration(1500 * time.Microsecond)).Should(Equal(1.5))
+	})
+
+	It("should parse times from strings and milliseconds since epoch", func() {
+		Expect(ParseTime(created)).Should(Equal(created))
+		Expect(ParseTime(&created)).Should(Equal(created))
+		Expect(ParseTime("2019-05-01T18:57:06.123Z").Equal(created)).Should(BeTrue())
+		Expect(ParseTime("2019-05-01T18:57:06.123000000Z").Equal(created)).Should(BeTrue())
+		Expect(ParseTime("2019-05-01T20:57:06.123+02:00").Equal(created)).Should(BeTrue())
+		Expect(ParseTime("2019-05-01T18:57:06.123").Equal(created)).Should(BeTrue())
+		Expect(ParseTime("2019-05-01").Equal(time.Date(2019, 5, 1, 0, 0, 0, 0, time.UTC))).Should(BeTrue())
+		Expect(ParseTime(int64(1556737026123)).Equal(created)).Should(BeTrue())
+		Expect(ParseTime("1556737026123").Equal(created)).Should(BeTrue())
+		Expect(ParseTime("not a date").IsZero()).Should(BeTrue())
+		Expect(ParseTime(true).IsZero()).Should(BeTrue())
+	})
+
+	It("should parse durations from strings and milliseconds", func() {
+		Expect(ParseDuration(time.Second)).Should(Equal(time.Second))
+		Expect(ParseDuration("1m30s")).Should(Equal(90 * time.Second))
+		Expect(ParseDuration("250")).Should(Equal(250 * time.Millisecond))
+		Expect(ParseDuration(1500)).Should(Equal(1500 * time.Millisecond))
+		Expect(ParseDuration(0.5)).Should(Equal(500 * time.Microsecond))
+		Expect(ParseDuration("soon")).Should(Equal(time.Duration(0)))
+	})
+
+	It("Time, Duration and DurationArray should convert the payload values", func() {
+		p := New(map[string]interface{}{
+			"created":   "2019-05-01T18:57:06.123Z",
+			"timeout":   2 * time.Second,
+			"timeouts":  []interface{}{100, "1s", time.Minute},
+			"timestamp": 1556737026123,
+		})
+		Expect(p.Get("created").Time().Equal(created)).Should(BeTrue())
+		Expect(p.Get("timestamp").Time().Equal(created)).Should(BeTrue())
+		Expect(p.Get("timeout").Duration()).Should(Equal(2 * time.Second))
+		Expect(p.Get("timeouts").DurationArray()).Should(Equal([]time.Duration{100 * time.Millisecond, time.Second, time.Minute}))
+	})
+})
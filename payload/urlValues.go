@@ -0,0 +1,145 @@
+package payload
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// ToURLValues convert a map payload into url.Values, to be sent as query or form params.
+// Nested maps use the bracket notation (address[city]=Winterfell), lists repeat the key
+// (tags=a&tags=b) and maps inside lists use the item index (users[0][name]=John).
+// Times are formatted with TimeFormat and binary values are encoded as base64.
+func ToURLValues(source moleculer.Payload) url.Values {
+	values := url.Values{}
+	if !source.IsMap() {
+		return values
+	}
+	addURLValues(values, "", source)
+	return values
+}
+
+func addURLValues(values url.Values, prefix string, source moleculer.Payload) {
+	source.ForEach(func(key interface{}, value moleculer.Payload) bool {
+		field := fmt.Sprint(key)
+		if prefix != "" {
+			field = prefix + "[" + field + "]"
+		}
+		addURLValue(values, field, value)
+		return true
+	})
+}
+
+func addURLValue(values url.Values, field string, value moleculer.Payload) {
+	switch source := value.Value().(type) {
+	case nil:
+		return
+	case []byte:
+		values.Add(field, base64.StdEncoding.EncodeToString(source))
+		return
+	case time.Time:
+		values.Add(field, FormatTime(source))
+		return
+	}
+	if value.IsMap() {
+		addURLValues(values, field, value)
+		return
+	}
+	if value.IsArray() {
+		for index, item := range value.Array() {
+			if item.IsMap() {
+				addURLValues(values, fmt.Sprintf("%s[%d]", field, index), item)
+				continue
+			}
+			addURLValue(values, field, item)
+		}
+		return
+	}
+	values.Add(field, value.String())
+}
+
+// FromURLValues convert query or form params into a map payload. Keys with a single value
+// are strings and keys with many values (or ending with []) are lists of strings.
+// The bracket notation creates nested maps: address[city]=Winterfell -> {address: {city: Winterfell}},
+// and nested maps with the keys 0..n-1 (users[0][name]=John) become lists.
+// Values are not converted, since url values have no types.
+func FromURLValues(values url.Values) moleculer.Payload {
+	result := map[string]interface{}{}
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		path, isList := urlKeyPath(key)
+		var value interface{}
+		if isList || len(values[key]) > 1 {
+			value = append([]string{}, values[key]...)
+		} else if len(values[key]) == 1 {
+			value = values[key][0]
+		} else {
+			continue
+		}
+		setURLValue(result, path, value)
+	}
+	return New(indexedLists(result))
+}
+
+// indexedLists convert the nested maps with the keys 0..n-1 into lists.
+func indexedLists(source map[string]interface{}) map[string]interface{} {
+	for key, value := range source {
+		if nested, isMap := value.(map[string]interface{}); isMap {
+			source[key] = listOrMap(indexedLists(nested))
+		}
+	}
+	return source
+}
+
+func listOrMap(source map[string]interface{}) interface{} {
+	if len(source) == 0 {
+		return source
+	}
+	list := make([]interface{}, len(source))
+	for index := range list {
+		item, exists := source[strconv.Itoa(index)]
+		if !exists {
+			return source
+		}
+		list[index] = item
+	}
+	return list
+}
+
+// urlKeyPath split the key in the bracket notation (a[b][c] -> a, b, c).
+// A trailing [] means the value is a list.
+func urlKeyPath(key string) ([]string, bool) {
+	isList := strings.HasSuffix(key, "[]")
+	key = strings.TrimSuffix(key, "[]")
+	open := strings.Index(key, "[")
+	if open <= 0 || !strings.HasSuffix(key, "]") {
+		return []string{key}, isList
+	}
+	path := []string{key[:open]}
+	for _, part := range strings.Split(key[open+1:len(key)-1], "][") {
+		path = append(path, part)
+	}
+	return path, isList
+}
+
+func setURLValue(target map[string]interface{}, path []string, value interface{}) {
+	for _, field := range path[:len(path)-1] {
+		nested, isMap := target[field].(map[string]interface{})
+		if !isMap {
+			nested = map[string]interface{}{}
+			target[field] = nested
+		}
+		target = nested
+	}
+	target[path[len(path)-1]] = value
+}
@@ -0,0 +1,70 @@
+package payload_test
+
+import (
+	"net/url"
+	"time"
+
+	. "github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("URL values", func() {
+
+	It("ToURLValues should encode nested maps and lists with the bracket notation", func() {
+		values := ToURLValues(New(map[string]interface{}{
+			"name":    "John",
+			"age":     23,
+			"active":  true,
+			"address": map[string]interface{}{"city": "Winterfell"},
+			"tags":    []string{"stark", "snow"},
+			"users":   []interface{}{map[string]interface{}{"name": "Sam"}},
+			"created": time.Date(2019, 5, 1, 18, 57, 6, 0, time.UTC),
+			"content": []byte("winter"),
+			"missing": nil,
+		}))
+		Expect(values).Should(Equal(url.Values{
+			"name":           []string{"John"},
+			"age":            []string{"23"},
+			"active":         []string{"true"},
+			"address[city]":  []string{"Winterfell"},
+			"tags":           []string{"stark", "snow"},
+			"users[0][name]": []string{"Sam"},
+			"created":        []string{"2019-05-01T18:57:06.000000000Z"},
+			"content":        []string{"d2ludGVy"},
+		}))
+		Expect(values.Encode()).Should(ContainSubstring("address%5Bcity%5D=Winterfell"))
+
+		Expect(ToURLValues(New("John"))).Should(BeEmpty())
+	})
+
+	It("FromURLValues should decode the params into a map payload", func() {
+		query, err := url.ParseQuery("name=John&tags=stark&tags=snow&ids[]=1&address[city]=Winterfell&address[geo][lat]=10&users[0][name]=Sam&users[1][name]=Jon&0=zero")
+		Expect(err).Should(BeNil())
+		p := FromURLValues(query)
+		Expect(p.RawMap()).Should(Equal(map[string]interface{}{
+			"name": "John",
+			"tags": []string{"stark", "snow"},
+			"ids":  []string{"1"},
+			"address": map[string]interface{}{
+				"city": "Winterfell",
+				"geo":  map[string]interface{}{"lat": "10"},
+			},
+			"users": []interface{}{
+				map[string]interface{}{"name": "Sam"},
+				map[string]interface{}{"name": "Jon"},
+			},
+			"0": "zero",
+		}))
+		Expect(p.Get("address").Get("geo").Get("lat").String()).Should(Equal("10"))
+	})
+
+	It("FromURLValues should read the values encoded by ToURLValues", func() {
+		source := New(map[string]interface{}{
+			"name":    "John",
+			"address": map[string]interface{}{"city": "Winterfell"},
+			"tags":    []string{"stark", "snow"},
+		})
+		Expect(FromURLValues(ToURLValues(source)).RawMap()).Should(Equal(source.RawMap()))
+	})
+})
@@ -1,10 +1,12 @@
 package registry
 
 import (
+	gocontext "context"
 	"fmt"
 	"runtime/debug"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/payload"
@@ -23,9 +25,14 @@ type ActionEntry struct {
 
 type actionsMap map[string][]ActionEntry
 
+// ActionCatalog is copy-on-write: the writers are serialized by the mutex and store new lists,
+// so the calls read the entries of an action without locking.
 type ActionCatalog struct {
-	actions sync.Map
-	logger  *log.Entry
+	actions         sync.Map
+	mutex           sync.Mutex
+	logger          *log.Entry
+	slowStartWindow time.Duration
+	uptimeOf        func(nodeID string) time.Duration
 }
 
 func CreateActionCatalog(logger *log.Entry) *ActionCatalog {
@@ -73,13 +80,18 @@ func (actionEntry *ActionEntry) catchActionError(context moleculer.BrokerContext
 	}
 }
 
-func (actionEntry *ActionEntry) invokeLocalAction(context moleculer.BrokerContext) chan moleculer.Payload {
+func (actionEntry *ActionEntry) invokeLocalAction(context moleculer.BrokerContext, validator moleculer.Validator) chan moleculer.Payload {
 	result := make(chan moleculer.Payload, 1)
 
 	actionEntry.logger.Trace("Before Invoking action: ", context.ActionName(), " params: ", context.Payload())
 
 	go func() {
 		defer actionEntry.catchActionError(context, result)
+		if err := validator.Validate(actionEntry.action.Params(), context.Payload()); err != nil {
+			actionEntry.logger.Debug("Invalid params for action: ", context.ActionName(), " error: ", err)
+			result <- payload.New(err)
+			return
+		}
 		handler := actionEntry.action.Handler()
 		actionResult := handler(context.(moleculer.Context), context.Payload())
 
@@ -87,6 +99,36 @@ func (actionEntry *ActionEntry) invokeLocalAction(context moleculer.BrokerContex
 		result <- payload.New(actionResult)
 	}()
 
+	if _, hasDeadline := context.Deadline(); hasDeadline {
+		return actionEntry.enforceDeadline(context, result)
+	}
+
+	return result
+}
+
+// enforceDeadline deliver the action result, or a timeout error when the deadline of the context
+// expires before the action ends. The handler is signaled by the context and its late result is discarded.
+func (actionEntry *ActionEntry) enforceDeadline(context moleculer.BrokerContext, actionResult chan moleculer.Payload) chan moleculer.Payload {
+	result := make(chan moleculer.Payload, 1)
+	go func() {
+		select {
+		case value := <-actionResult:
+			result <- value
+		case <-context.Done():
+			select {
+			case value := <-actionResult:
+				result <- value
+				return
+			default:
+			}
+			if context.Err() != gocontext.DeadlineExceeded {
+				result <- <-actionResult
+				return
+			}
+			actionEntry.logger.Warn("Action timed out: ", context.ActionName(), " timeout: ", context.Timeout(), " - the result is discarded.")
+			result <- payload.New(moleculer.NewRequestTimeoutError(context.ActionName(), actionEntry.targetNodeID))
+		}
+	}()
 	return result
 }
 
@@ -102,6 +144,10 @@ func (actionEntry ActionEntry) Service() *service.Service {
 	return actionEntry.service
 }
 
+func (actionEntry ActionEntry) Action() *service.Action {
+	return actionEntry.action
+}
+
 func (actionCatalog *ActionCatalog) listByName() map[string][]ActionEntry {
 	result := make(map[string][]ActionEntry)
 	actionCatalog.actions.Range(func(key, value interface{}) bool {
@@ -115,21 +161,36 @@ func (actionCatalog *ActionCatalog) listByName() map[string][]ActionEntry {
 func (actionCatalog *ActionCatalog) Add(action service.Action, service *service.Service, local bool) {
 	entry := ActionEntry{service.NodeID(), &action, local, service, actionCatalog.logger}
 	name := action.FullName()
-	list, exists := actionCatalog.actions.Load(name)
-	if !exists {
-		list = []ActionEntry{entry}
-	} else {
-		list = append(list.([]ActionEntry), entry)
-	}
-	actionCatalog.actions.Store(name, list)
+	actionCatalog.mutex.Lock()
+	defer actionCatalog.mutex.Unlock()
+	current := actionCatalog.Find(name)
+	list := make([]ActionEntry, len(current), len(current)+1)
+	copy(list, current)
+	actionCatalog.actions.Store(name, append(list, entry))
 }
 
+// Update replace the action of the remote node with a copy updated from the action info of the INFO packet,
+// so the calls already holding the entry keep the action they got.
 func (actionCatalog *ActionCatalog) Update(nodeID string, fullname string, updates map[string]interface{}) {
-	//TODO .. the only thing that can be udpated is the Action Schema (validation) and that does not exist yet
+	actionCatalog.mutex.Lock()
+	defer actionCatalog.mutex.Unlock()
+	current := actionCatalog.Find(fullname)
+	list := make([]ActionEntry, len(current))
+	copy(list, current)
+	for index, entry := range list {
+		if entry.targetNodeID == nodeID && !entry.isLocal {
+			action := *entry.action
+			action.UpdateFromMap(updates)
+			list[index].action = &action
+		}
+	}
+	actionCatalog.actions.Store(fullname, list)
 }
 
 // RemoveByNode remove actions for the given nodeID.
 func (actionCatalog *ActionCatalog) RemoveByNode(nodeID string) {
+	actionCatalog.mutex.Lock()
+	defer actionCatalog.mutex.Unlock()
 	actionCatalog.actions.Range(func(key, value interface{}) bool {
 		name := key.(string)
 		actions := value.([]ActionEntry)
@@ -148,7 +209,31 @@ func (actionCatalog *ActionCatalog) RemoveByNode(nodeID string) {
 	})
 }
 
+// RemoveByService remove the actions of the given service.
+func (actionCatalog *ActionCatalog) RemoveByService(svc *service.Service) {
+	actionCatalog.mutex.Lock()
+	defer actionCatalog.mutex.Unlock()
+	actionCatalog.actions.Range(func(key, value interface{}) bool {
+		name := key.(string)
+		actions := value.([]ActionEntry)
+		var toKeep []ActionEntry
+		for _, action := range actions {
+			if action.service != svc {
+				toKeep = append(toKeep, action)
+			}
+		}
+		if len(toKeep) == 0 {
+			actionCatalog.actions.Delete(name)
+		} else {
+			actionCatalog.actions.Store(name, toKeep)
+		}
+		return true
+	})
+}
+
 func (actionCatalog *ActionCatalog) Remove(nodeID string, name string) {
+	actionCatalog.mutex.Lock()
+	defer actionCatalog.mutex.Unlock()
 	value, exists := actionCatalog.actions.Load(name)
 	if !exists {
 		return
@@ -192,6 +277,20 @@ func (actionCatalog *ActionCatalog) printDebugActions() {
 
 // Next find all actions registered in this node and use the strategy to select and return the best one to be called.
 func (actionCatalog *ActionCatalog) Next(actionName string, stg strategy.Strategy) *ActionEntry {
+	return actionCatalog.Select(actionName, stg, true)
+}
+
+// SlowStart ramp the share of the calls sent to the nodes that joined less than the window ago.
+// The uptimeOf function returns how long a node is available.
+func (actionCatalog *ActionCatalog) SlowStart(window time.Duration, uptimeOf func(nodeID string) time.Duration) {
+	actionCatalog.slowStartWindow = window
+	actionCatalog.uptimeOf = uptimeOf
+}
+
+// Select return the entry of the action to be invoked. When preferLocal is true and the action
+// is local it returns the local entry, otherwise the strategy selects one of all the entries,
+// after the slow start removed some of the entries of the nodes that joined recently.
+func (actionCatalog *ActionCatalog) Select(actionName string, stg strategy.Strategy, preferLocal bool) *ActionEntry {
 	actions := actionCatalog.Find(actionName)
 	if actions == nil {
 		actionCatalog.logger.Debug("actionCatalog.Next() action not found: ", actionName, "  actionCatalog.actions: ", actionCatalog.actions)
@@ -200,10 +299,13 @@ func (actionCatalog *ActionCatalog) Next(actionName string, stg strategy.Strateg
 	nodes := make([]strategy.Selector, len(actions))
 	for index, action := range actions {
 		nodes[index] = action
-		if action.IsLocal() {
+		if preferLocal && action.IsLocal() {
 			return &action
 		}
 	}
+	if actionCatalog.slowStartWindow > 0 {
+		nodes = strategy.SlowStart(nodes, actionCatalog.slowStartWindow, actionCatalog.uptimeOf)
+	}
 	if selected := stg.Select(nodes); selected != nil {
 		entry := (*selected).(ActionEntry)
 		return &entry
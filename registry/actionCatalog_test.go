@@ -1,6 +1,9 @@
 package registry_test
 
 import (
+	"fmt"
+	"sync"
+
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/registry"
 	"github.com/moleculer-go/moleculer/service"
@@ -111,6 +114,44 @@ var _ = Describe("Actions Catalog", func() {
 			Expect(nextAction).Should(BeNil())
 		})
 
+		It("Should keep the entries added and removed concurrently while the calls select them", func() {
+			catalog := registry.CreateActionCatalog(logger)
+			var wait sync.WaitGroup
+			for index := 0; index < 50; index++ {
+				wait.Add(2)
+				go func(index int) {
+					defer wait.Done()
+					testService := service.Service{}
+					testService.SetNodeID(fmt.Sprint("node-", index))
+					catalog.Add(service.CreateServiceAction("bank", "credit", handler, params), &testService, false)
+					if index%2 == 1 {
+						catalog.RemoveByNode(fmt.Sprint("node-", index))
+					}
+				}(index)
+				go func() {
+					defer wait.Done()
+					catalog.Next("bank.credit", strategy)
+				}()
+			}
+			wait.Wait()
+			Expect(len(catalog.Find("bank.credit"))).Should(Equal(25))
+		})
+
+		It("Should update the cache settings of the remote action without changing the entry in use", func() {
+			catalog := registry.CreateActionCatalog(logger)
+			testService := service.Service{}
+			testService.SetNodeID(node2.GetID())
+			catalog.Add(service.CreateServiceAction("bank", "credit", handler, params), &testService, false)
+			inUse := catalog.NextFromNode("bank.credit", node2.GetID())
+			Expect(inUse.Action().Cache()).Should(BeNil())
+
+			catalog.Update(node2.GetID(), "bank.credit", map[string]interface{}{"name": "bank.credit", "cache": true})
+
+			updated := catalog.NextFromNode("bank.credit", node2.GetID())
+			Expect(updated.Action().Cache()).Should(Equal(&moleculer.CacheSettings{Enabled: true}))
+			Expect(inUse.Action().Cache()).Should(BeNil())
+		})
+
 	})
 
 })
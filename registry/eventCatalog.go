@@ -39,17 +39,26 @@ func catchEventError(context moleculer.BrokerContext, logger *log.Entry) {
 	}
 }
 
-func (eventEntry *EventEntry) emitLocalEvent(context moleculer.BrokerContext) {
+// emitLocalEvent invoke the event handler. When copyPayload is true the handler
+// receives a deep copy of the payload, so it can't affect the other handlers.
+func (eventEntry *EventEntry) emitLocalEvent(context moleculer.BrokerContext, copyPayload bool) {
 	logger := context.Logger().WithField("eventCatalog", "emitLocalEvent")
 	logger.Debug("Invoking local event: ", context.EventName())
 	defer catchEventError(context, logger)
+	params := context.Payload()
+	if copyPayload {
+		params = params.Clone()
+	}
 	handler := eventEntry.event.Handler()
-	handler(context.(moleculer.Context), context.Payload())
+	handler(context.(moleculer.Context), params)
 	logger.Trace("After invoking local event: ", context.EventName())
 }
 
+// EventCatalog is copy-on-write: the writers are serialized by the mutex and store new lists,
+// so the emitters read the entries of an event without locking.
 type EventCatalog struct {
 	events sync.Map
+	mutex  sync.Mutex
 	logger *log.Entry
 }
 
@@ -63,13 +72,15 @@ func (eventCatalog *EventCatalog) Add(event service.Event, service *service.Serv
 	entry := EventEntry{service.NodeID(), service, &event, local}
 	name := event.Name()
 	eventCatalog.logger.Debug("Add event name: ", name, " serviceName: ", event.ServiceName())
-	list, exists := eventCatalog.events.Load(name)
-	if !exists {
-		list = []EventEntry{entry}
-	} else {
-		list = append(list.([]EventEntry), entry)
+	eventCatalog.mutex.Lock()
+	defer eventCatalog.mutex.Unlock()
+	var current []EventEntry
+	if value, exists := eventCatalog.events.Load(name); exists {
+		current = value.([]EventEntry)
 	}
-	eventCatalog.events.Store(name, list)
+	list := make([]EventEntry, len(current), len(current)+1)
+	copy(list, current)
+	eventCatalog.events.Store(name, append(list, entry))
 }
 
 func (eventCatalog *EventCatalog) Update(nodeID string, name string, updates map[string]interface{}) {
@@ -86,6 +97,8 @@ func (eventCatalog *EventCatalog) listByName() map[string][]EventEntry {
 }
 
 func (eventCatalog *EventCatalog) Remove(nodeID string, name string) {
+	eventCatalog.mutex.Lock()
+	defer eventCatalog.mutex.Unlock()
 	removed := 0
 	list, exists := eventCatalog.events.Load(name)
 	if !exists {
@@ -104,6 +117,8 @@ func (eventCatalog *EventCatalog) Remove(nodeID string, name string) {
 
 // RemoveByNode remove events for the given nodeID.
 func (eventCatalog *EventCatalog) RemoveByNode(nodeID string) {
+	eventCatalog.mutex.Lock()
+	defer eventCatalog.mutex.Unlock()
 	removed := 0
 	eventCatalog.events.Range(func(key, value interface{}) bool {
 		name := key.(string)
@@ -121,6 +136,24 @@ func (eventCatalog *EventCatalog) RemoveByNode(nodeID string) {
 	})
 }
 
+// RemoveByService remove the events of the given service.
+func (eventCatalog *EventCatalog) RemoveByService(svc *service.Service) {
+	eventCatalog.mutex.Lock()
+	defer eventCatalog.mutex.Unlock()
+	eventCatalog.events.Range(func(key, value interface{}) bool {
+		name := key.(string)
+		events := value.([]EventEntry)
+		var toKeep []EventEntry
+		for _, event := range events {
+			if event.service != svc {
+				toKeep = append(toKeep, event)
+			}
+		}
+		eventCatalog.events.Store(name, toKeep)
+		return true
+	})
+}
+
 func matchGroup(event *service.Event, groups []string) bool {
 	if groups == nil || len(groups) == 0 {
 		return true
@@ -133,16 +166,21 @@ func matchGroup(event *service.Event, groups []string) bool {
 	return false
 }
 
-func findLocal(events []EventEntry) *EventEntry {
-	for _, item := range events {
-		if item.IsLocal() {
-			return &item
+// findLocal return the local entries, so the event is balanced between the local
+// handlers of the group, e.g. two local services that share a group.
+func findLocal(events []EventEntry) []EventEntry {
+	var local []EventEntry
+	for _, entry := range events {
+		if entry.IsLocal() {
+			local = append(local, entry)
 		}
 	}
-	return nil
+	return local
 }
 
-// Find find all events registered in this node and use the strategy to select and return the best one to be called.
+// Find find the handlers of the event, grouped by the event group. Each group is handled by one entry
+// selected with the strategy (preferring the local entries when preferLocal is true), or by all the
+// entries of the group when the strategy is nil (broadcast).
 func (eventCatalog *EventCatalog) Find(name string, groups []string, preferLocal bool, localOnly bool, stg strategy.Strategy) []*EventEntry {
 	events, exists := eventCatalog.events.Load(name)
 	if !exists {
@@ -161,23 +199,24 @@ func (eventCatalog *EventCatalog) Find(name string, groups []string, preferLocal
 	}
 	var result []*EventEntry
 	for _, entries := range entryGroups {
-		if local := findLocal(entries); preferLocal && local != nil {
+		if local := findLocal(entries); preferLocal && len(local) > 0 {
 			eventCatalog.logger.Trace("event: ", name, " found local: ", local)
-			result = append(result, local)
-		} else if len(entries) == 1 {
+			entries = local
+		}
+		if len(entries) == 1 {
 			eventCatalog.logger.Debug("event: ", name, " found a single one :)  ", entries[0])
 			result = append(result, &entries[0])
 		} else if len(entries) > 1 {
 			if stg == nil {
 				eventCatalog.logger.Debug("event: ", name, " no strategy. return all entries: ", entries)
-				for _, entry := range entries {
-					result = append(result, &entry)
+				for index := range entries {
+					result = append(result, &entries[index])
 				}
 			} else {
 				eventCatalog.logger.Debug("event: ", name, "using strategy to load balance between options: ", entries)
 				nodes := make([]strategy.Selector, len(entries))
-				for index, entry := range entries {
-					nodes[index] = &entry
+				for index := range entries {
+					nodes[index] = &entries[index]
 				}
 				if selected := stg.Select(nodes); selected != nil {
 					entry := (*selected).(*EventEntry)
@@ -191,6 +230,30 @@ func (eventCatalog *EventCatalog) Find(name string, groups []string, preferLocal
 	return result
 }
 
+// FindOnNode find the handlers of the event in the node. When balanced is true each group is handled
+// by the first handler of the node, otherwise all the handlers of the node receive the event.
+func (eventCatalog *EventCatalog) FindOnNode(name string, groups []string, nodeID string, balanced bool) []*EventEntry {
+	events, exists := eventCatalog.events.Load(name)
+	if !exists {
+		return make([]*EventEntry, 0)
+	}
+	entries := events.([]EventEntry)
+	handled := map[string]bool{}
+	var result []*EventEntry
+	for index := range entries {
+		entry := &entries[index]
+		if entry.TargetNodeID() != nodeID || !matchGroup(entry.event, groups) {
+			continue
+		}
+		if balanced && handled[entry.event.Group()] {
+			continue
+		}
+		handled[entry.event.Group()] = true
+		result = append(result, entry)
+	}
+	return result
+}
+
 func (eventCatalog *EventCatalog) list() []EventEntry {
 	var result []EventEntry
 	eventCatalog.events.Range(func(key, value interface{}) bool {
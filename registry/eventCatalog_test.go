@@ -2,12 +2,14 @@ package registry_test
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/moleculer-go/moleculer/test"
 
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/registry"
 	"github.com/moleculer-go/moleculer/service"
+	"github.com/moleculer-go/moleculer/strategy"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 	log "github.com/sirupsen/logrus"
@@ -34,4 +36,89 @@ var _ = Describe("Event Catalog", func() {
 		catalog.Add(srv.Events()[0], srv, true)
 		Expect(catalog).ShouldNot(BeNil())
 	})
+	It("Should return all the entries of a group when there is no strategy", func() {
+		catalog := registry.CreateEventCatalog(log.New().WithField("catalog", "events"))
+		for _, nodeID := range []string{"node-1", "node-2", "node-3"} {
+			srv := service.FromSchema(moleculer.ServiceSchema{
+				Name: "x",
+				Events: []moleculer.Event{
+					moleculer.Event{
+						Name:    "user.added",
+						Handler: handler,
+					},
+				},
+			}, test.DelegatesWithId(nodeID))
+			srv.SetNodeID(nodeID)
+			catalog.Add(srv.Events()[0], srv, false)
+		}
+
+		entries := catalog.Find("user.added", []string{}, false, false, nil)
+		Expect(len(entries)).Should(Equal(3))
+		nodes := []string{}
+		for _, entry := range entries {
+			nodes = append(nodes, entry.TargetNodeID())
+		}
+		Expect(nodes).Should(ConsistOf("node-1", "node-2", "node-3"))
+	})
+
+	It("Should select one entry per group and balance the local entries of a group", func() {
+		catalog := registry.CreateEventCatalog(log.New().WithField("catalog", "events"))
+		add := func(name, group, nodeID string, local bool) {
+			srv := service.FromSchema(moleculer.ServiceSchema{
+				Name: name,
+				Events: []moleculer.Event{
+					moleculer.Event{Name: "order.created", Group: group, Handler: handler},
+				},
+			}, test.DelegatesWithId(nodeID))
+			srv.SetNodeID(nodeID)
+			catalog.Add(srv.Events()[0], srv, local)
+		}
+		add("worker-a", "workers", "node-1", true)
+		add("worker-b", "workers", "node-1", true)
+		add("worker-c", "workers", "node-2", false)
+		add("mailer", "", "node-2", false)
+
+		selected := map[string]int{}
+		roundRobin := strategy.NewRoundRobinStrategy()
+		for i := 0; i < 4; i++ {
+			entries := catalog.Find("order.created", []string{}, true, false, roundRobin)
+			Expect(entries).Should(HaveLen(2))
+			for _, entry := range entries {
+				selected[entry.String()]++
+			}
+		}
+		Expect(selected).Should(HaveLen(3))
+		for entry, count := range selected {
+			Expect(entry).ShouldNot(ContainSubstring("worker-c"))
+			if strings.Contains(entry, "mailer") {
+				Expect(count).Should(Equal(4))
+			}
+		}
+
+		entries := catalog.Find("order.created", []string{"workers"}, false, false, nil)
+		Expect(entries).Should(HaveLen(3))
+	})
+
+	It("Should find the entries of a single node", func() {
+		catalog := registry.CreateEventCatalog(log.New().WithField("catalog", "events"))
+		add := func(name, group, nodeID string) {
+			srv := service.FromSchema(moleculer.ServiceSchema{
+				Name: name,
+				Events: []moleculer.Event{
+					moleculer.Event{Name: "order.created", Group: group, Handler: handler},
+				},
+			}, test.DelegatesWithId(nodeID))
+			srv.SetNodeID(nodeID)
+			catalog.Add(srv.Events()[0], srv, false)
+		}
+		add("worker-a", "workers", "node-1")
+		add("worker-b", "workers", "node-1")
+		add("worker-c", "workers", "node-2")
+		add("mailer", "", "node-1")
+
+		Expect(catalog.FindOnNode("order.created", []string{}, "node-1", true)).Should(HaveLen(2))
+		Expect(catalog.FindOnNode("order.created", []string{}, "node-1", false)).Should(HaveLen(3))
+		Expect(catalog.FindOnNode("order.created", []string{"workers"}, "node-2", false)).Should(HaveLen(1))
+		Expect(catalog.FindOnNode("order.created", []string{}, "node-3", false)).Should(BeEmpty())
+	})
 })
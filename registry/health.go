@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"net/http"
+	"os"
+	"os/user"
+	"runtime"
+	"time"
+)
+
+// Health return the health status of the local node: cpu, memory, os, process, network,
+// transit and the local services, using the moleculer-js $node.health field names.
+// The status is "started" when the broker is started and "stopped" otherwise.
+func (registry *ServiceRegistry) Health() map[string]interface{} {
+	nodeInfo := registry.localNode.ExportAsMap()
+	status := registry.transit.Status()
+	var lastError string
+	if status.LastError != nil {
+		lastError = status.LastError.Error()
+	}
+	brokerStatus := "stopped"
+	if registry.broker.IsStarted() {
+		brokerStatus = "started"
+	}
+	systemStats := registry.lastStats()
+	now := time.Now()
+	return map[string]interface{}{
+		"nodeID": registry.localNode.GetID(),
+		"status": brokerStatus,
+		"cpu": map[string]interface{}{
+			"cores":       systemStats.Cores,
+			"utilization": systemStats.CPU,
+		},
+		"mem": map[string]interface{}{
+			"free":    systemStats.MemFree,
+			"total":   systemStats.MemTotal,
+			"percent": systemStats.MemUsage(),
+		},
+		"os":      osHealth(nodeInfo["hostname"]),
+		"process": processHealth(registry.startedTime),
+		"client":  nodeInfo["client"],
+		"net": map[string]interface{}{
+			"ip": nodeInfo["ipList"],
+		},
+		"transit": map[string]interface{}{
+			"connected":  status.Connected,
+			"reconnects": status.Reconnects,
+			"lastError":  lastError,
+			"latency":    status.Latency.Seconds() * 1000,
+			"paused":     registry.transit.IsPaused(),
+		},
+		"services": registry.servicesHealth(),
+		"time": map[string]interface{}{
+			"now": now.UnixNano() / int64(time.Millisecond),
+			"iso": now.UTC().Format("2006-01-02T15:04:05.000Z"),
+			"utc": now.UTC().Format(http.TimeFormat),
+		},
+	}
+}
+
+// servicesHealth list the local services with their status and number of actions and events.
+func (registry *ServiceRegistry) servicesHealth() []map[string]interface{} {
+	result := make([]map[string]interface{}, 0)
+	for _, svc := range registry.services.listByNode(registry.localNode.GetID()) {
+		status := "started"
+		if !registry.localNode.IsAvailable() {
+			status = "unavailable"
+		}
+		result = append(result, map[string]interface{}{
+			"name":     svc.Name(),
+			"version":  svc.Version(),
+			"fullName": svc.FullName(),
+			"status":   status,
+			"actions":  len(svc.Actions()),
+			"events":   len(svc.Events()),
+		})
+	}
+	return result
+}
+
+// osHealth return the os section of $node.health, using the moleculer-js field names.
+func osHealth(hostname interface{}) map[string]interface{} {
+	result := map[string]interface{}{
+		"type":     runtime.GOOS,
+		"arch":     runtime.GOARCH,
+		"platform": runtime.GOOS,
+		"hostname": hostname,
+	}
+	if current, err := user.Current(); err == nil {
+		result["user"] = map[string]interface{}{
+			"uid":      current.Uid,
+			"gid":      current.Gid,
+			"username": current.Username,
+			"homedir":  current.HomeDir,
+		}
+	}
+	return result
+}
+
+// processHealth return the process section of $node.health. The uptime is in seconds and the
+// memory is read from the Go runtime: rss is the memory obtained from the OS and the heap
+// values are the heap in use and reserved.
+func processHealth(startedTime time.Time) map[string]interface{} {
+	uptime := 0.0
+	if !startedTime.IsZero() {
+		uptime = time.Since(startedTime).Seconds()
+	}
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return map[string]interface{}{
+		"pid":  os.Getpid(),
+		"argv": os.Args,
+		"memory": map[string]interface{}{
+			"rss":       memStats.Sys,
+			"heapTotal": memStats.HeapSys,
+			"heapUsed":  memStats.HeapAlloc,
+		},
+		"uptime":     uptime,
+		"goroutines": runtime.NumGoroutine(),
+	}
+}
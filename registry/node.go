@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strings"
+	"sync"
 
 	"net"
 	"time"
@@ -13,18 +14,25 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// Node is updated by the transit messages and read by the calls, so its fields are guarded by the mutex.
 type Node struct {
+	mutex             sync.RWMutex
 	id                string
+	instanceID        string
 	sequence          int64
 	ipList            []string
 	hostname          string
 	client            map[string]interface{}
+	metadata          map[string]interface{}
 	services          []map[string]interface{}
 	isAvailable       bool
 	cpu               int64
 	cpuSequence       int64
+	mem               int64
 	lastHeartBeatTime int64
 	offlineSince      int64
+	availableSince    int64
+	heartbeatInterval time.Duration
 	isLocal           bool
 	logger            *log.Entry
 }
@@ -64,12 +72,13 @@ func CreateNode(id string, local bool, logger *log.Entry) moleculer.Node {
 	node := Node{
 		id: id,
 		client: map[string]interface{}{
-			"type":        "moleculer-go",
+			"type":        "go",
 			"version":     version.Moleculer(),
 			"langVersion": version.Go(),
 		},
 		ipList:   ipList,
 		hostname: hostname,
+		metadata: map[string]interface{}{},
 		services: services,
 		logger:   logger,
 		isLocal:  local,
@@ -84,22 +93,34 @@ func (node *Node) Update(id string, info map[string]interface{}) bool {
 		panic(fmt.Errorf("Node.Update() - the id received : %s does not match this node.id : %s", id, node.id))
 	}
 	node.logger.Debug("node.Update() info: ", info)
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
 	reconnected := !node.isAvailable
 
+	if reconnected {
+		node.availableSince = time.Now().UnixNano()
+	}
 	node.isAvailable = true
-	node.lastHeartBeatTime = time.Now().Unix()
+	node.lastHeartBeatTime = time.Now().UnixNano()
 	node.offlineSince = 0
+	node.heartbeatInterval = time.Duration(floatField(info, "heartbeatInterval", 0) * float64(time.Second))
 
 	node.ipList = interfaceToString(info["ipList"].([]interface{}))
 	node.hostname = info["hostname"].(string)
 	node.client = info["client"].(map[string]interface{})
+	node.metadata, _ = info["metadata"].(map[string]interface{})
+	if node.metadata == nil {
+		node.metadata = map[string]interface{}{}
+	}
 
 	node.services = filterServices(info)
 	node.logger.Debug("node.Update() node.services: ", node.services)
 
+	node.instanceID, _ = info["instanceID"].(string)
 	node.sequence = int64Field(info, "seq", 0)
 	node.cpu = int64Field(info, "cpu", 0)
 	node.cpuSequence = int64Field(info, "cpuSeq", 0)
+	node.mem = int64Field(info, "mem", 0)
 
 	return reconnected
 }
@@ -133,6 +154,14 @@ func int64Field(values map[string]interface{}, field string, def int64) int64 {
 	return int64(fv)
 }
 
+func floatField(values map[string]interface{}, field string, def float64) float64 {
+	fv, valid := values[field].(float64)
+	if !valid {
+		return def
+	}
+	return fv
+}
+
 func interfaceToString(list []interface{}) []string {
 	result := make([]string, len(list))
 	for index, item := range list {
@@ -144,62 +173,162 @@ func interfaceToString(list []interface{}) []string {
 // ExportAsMap export the node info as a map
 // this map is used to publish the node info to other nodes.
 func (node *Node) ExportAsMap() map[string]interface{} {
+	node.mutex.RLock()
+	defer node.mutex.RUnlock()
 	resultMap := make(map[string]interface{})
 	resultMap["id"] = node.id
-	resultMap["services"] = node.services // node.removeInternalServices(node.services)
+	resultMap["services"] = append([]map[string]interface{}{}, node.services...) // node.removeInternalServices(node.services)
 	resultMap["ipList"] = node.ipList
 	resultMap["hostname"] = node.hostname
 	resultMap["client"] = node.client
+	resultMap["metadata"] = node.metadata
 	resultMap["seq"] = node.sequence
 	resultMap["cpu"] = node.cpu
 	resultMap["cpuSeq"] = node.cpuSequence
-	resultMap["available"] = node.IsAvailable()
+	resultMap["mem"] = node.mem
+	resultMap["available"] = node.isLocal || node.isAvailable
 	return resultMap
 }
 
+// Metadata return the metadata of the node, sent in the INFO packet.
+func (node *Node) Metadata() map[string]interface{} {
+	node.mutex.RLock()
+	defer node.mutex.RUnlock()
+	return node.metadata
+}
+
+// sameInstance check if the info was sent by the broker instance that sent the last info of the node.
+func (node *Node) sameInstance(info map[string]interface{}) bool {
+	instanceID, _ := info["instanceID"].(string)
+	node.mutex.RLock()
+	defer node.mutex.RUnlock()
+	return node.instanceID == instanceID
+}
+
 func (node *Node) GetID() string {
 	return node.id
 }
+
+// IsExpired check if the node did not send a heartbeat for longer than the timeout. A remote node that
+// sends its heartbeats less often than the timeout allows is given three of its own intervals instead.
 func (node *Node) IsExpired(timeout time.Duration) bool {
-	if node.IsLocal() || !node.IsAvailable() {
+	node.mutex.RLock()
+	defer node.mutex.RUnlock()
+	if node.isLocal || !node.isAvailable {
+		return false
+	}
+	if timeout < 3*node.heartbeatInterval {
+		timeout = 3 * node.heartbeatInterval
+	}
+	return time.Duration(time.Now().UnixNano()-node.lastHeartBeatTime) > timeout
+}
+
+// LastHeartbeat return the time of the last INFO or HEARTBEAT received from the node, zero when none was received.
+func (node *Node) LastHeartbeat() time.Time {
+	node.mutex.RLock()
+	defer node.mutex.RUnlock()
+	if node.lastHeartBeatTime == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, node.lastHeartBeatTime)
+}
+
+// IsOffline check if the node is unavailable for longer than the timeout.
+func (node *Node) IsOffline(timeout time.Duration) bool {
+	node.mutex.RLock()
+	defer node.mutex.RUnlock()
+	if node.isLocal || node.isAvailable {
 		return false
 	}
-	diff := time.Now().Unix() - node.lastHeartBeatTime
-	return diff > int64(timeout.Seconds())
+	return time.Duration(time.Now().UnixNano()-node.offlineSince) > timeout
 }
 
 func (node *Node) HeartBeat(heartbeat map[string]interface{}) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
 	if !node.isAvailable {
 		node.isAvailable = true
 		node.offlineSince = 0
+		node.availableSince = time.Now().UnixNano()
 	}
 	node.cpu = int64Field(heartbeat, "cpu", 0)
 	node.cpuSequence = int64Field(heartbeat, "cpuSeq", 0)
-	node.lastHeartBeatTime = time.Now().Unix()
+	node.mem = int64Field(heartbeat, "mem", 0)
+	node.lastHeartBeatTime = time.Now().UnixNano()
+}
+
+// UpdateStats set the cpu and memory usage of the node, increasing the cpu sequence when it changes.
+func (node *Node) UpdateStats(stats moleculer.SystemStats) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	if node.cpu != stats.CPU {
+		node.cpu = stats.CPU
+		node.cpuSequence++
+	}
+	node.mem = stats.MemUsage()
 }
 
 func (node *Node) Publish(service map[string]interface{}) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
 	node.services = append(node.services, service)
 }
 
+// Unpublish remove the service with the name and version from the node services.
+func (node *Node) Unpublish(name, version string) {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	services := make([]map[string]interface{}, 0, len(node.services))
+	for _, service := range node.services {
+		if service["name"] != name || service["version"] != version {
+			services = append(services, service)
+		}
+	}
+	node.services = services
+}
+
 func (node *Node) IsAvailable() bool {
+	node.mutex.RLock()
+	defer node.mutex.RUnlock()
 	return node.isLocal || node.isAvailable
 }
 
-//Unavailable mark the node as unavailable
+// Unavailable mark the node as unavailable
 func (node *Node) Unavailable() {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	if node.isAvailable {
+		node.offlineSince = time.Now().UnixNano()
+	}
 	node.isAvailable = false
 }
 
-//Unavailable mark the node as available
+// Unavailable mark the node as available
 func (node *Node) Available() {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
+	if !node.isAvailable {
+		node.availableSince = time.Now().UnixNano()
+	}
 	node.isAvailable = true
 }
 
+// Uptime return how long the node is available since it joined or reconnected, zero when it is unavailable.
+func (node *Node) Uptime() time.Duration {
+	node.mutex.RLock()
+	defer node.mutex.RUnlock()
+	if !node.isAvailable {
+		return 0
+	}
+	return time.Duration(time.Now().UnixNano() - node.availableSince)
+}
+
 func (node *Node) IsLocal() bool {
 	return node.isLocal
 }
 
 func (node *Node) IncreaseSequence() {
+	node.mutex.Lock()
+	defer node.mutex.Unlock()
 	node.sequence++
 }
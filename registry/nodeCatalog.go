@@ -54,6 +54,19 @@ func (catalog *NodeCatalog) expiredNodes(timeout time.Duration) []moleculer.Node
 	return result
 }
 
+// offlineNodes return the unavailable nodes that are offline for longer than the timeout.
+func (catalog *NodeCatalog) offlineNodes(timeout time.Duration) []moleculer.Node {
+	var result []moleculer.Node
+	catalog.nodes.Range(func(key, value interface{}) bool {
+		node := value.(moleculer.Node)
+		if node.IsOffline(timeout) {
+			result = append(result, node)
+		}
+		return true
+	})
+	return result
+}
+
 // findNode : return a Node instance from the catalog
 func (catalog *NodeCatalog) findNode(nodeID string) (moleculer.Node, bool) {
 	node, exists := catalog.nodes.Load(nodeID)
@@ -74,6 +87,23 @@ func (catalog *NodeCatalog) Add(node moleculer.Node) {
 	catalog.nodes.Store(node.GetID(), node)
 }
 
+// outdated check if the info is older than the info of the available node. The info packets
+// are handled concurrently, so an older info (e.g. the reply of a discover) can arrive after a
+// newer one. The sequence of the node is increased each time its services change. A node restarted
+// with the same ID starts again at the first sequence, so the info of another instance is never outdated.
+func (catalog *NodeCatalog) outdated(info map[string]interface{}) bool {
+	sender := info["sender"].(string)
+	node, exists := catalog.findNode(sender)
+	if !exists || !node.IsAvailable() {
+		return false
+	}
+	if remote, isNode := node.(*Node); isNode && !remote.sameInstance(info) {
+		return false
+	}
+	current, _ := node.ExportAsMap()["seq"].(int64)
+	return int64Field(info, "seq", 0) < current
+}
+
 // Info : process info received about a NODE. It can be new, update to existing
 func (catalog *NodeCatalog) Info(info map[string]interface{}) (bool, bool) {
 	sender := info["sender"].(string)
@@ -0,0 +1,36 @@
+package registry
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Node catalog", func() {
+
+	info := func(instanceID string, seq int) map[string]interface{} {
+		return map[string]interface{}{
+			"sender":     "node-a",
+			"instanceID": instanceID,
+			"seq":        float64(seq),
+			"ipList":     []interface{}{"127.0.0.1"},
+			"hostname":   "node-a-host",
+			"client":     map[string]interface{}{"type": "go"},
+			"services":   []interface{}{},
+		}
+	}
+
+	It("should ignore an older info of the same instance but not the info of a restarted node", func() {
+		catalog := CreateNodesCatalog(log.WithField("unit", "nodeCatalog"))
+		Expect(catalog.outdated(info("first", 3))).Should(BeFalse())
+		catalog.Info(info("first", 3))
+
+		Expect(catalog.outdated(info("first", 2))).Should(BeTrue())
+		Expect(catalog.outdated(info("first", 3))).Should(BeFalse())
+		Expect(catalog.outdated(info("second", 1))).Should(BeFalse())
+
+		catalog.Info(info("second", 1))
+		Expect(catalog.outdated(info("second", 1))).Should(BeFalse())
+		Expect(catalog.outdated(info("first", 3))).Should(BeFalse())
+	})
+})
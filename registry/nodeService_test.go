@@ -21,6 +21,9 @@ func cleanupNode(in map[string]interface{}) map[string]interface{} {
 	in["ipList"] = []string{"100.100.0.100"}
 	in["hostname"] = "removed"
 	in["seq"] = "removed"
+	in["cpu"] = "removed"
+	in["cpuSeq"] = "removed"
+	in["mem"] = "removed"
 	return in
 }
 
@@ -285,6 +288,34 @@ var _ = Describe("nodeService", func() {
 				"onlyLocal":     true,
 			}, extractServices), timeout)
 		})
+
+		It("$node.health should return the health of a remote node", func() {
+			mem := &memory.SharedMemory{}
+			printerBroker := createPrinterBroker(mem)
+			printerBroker.Start()
+			defer printerBroker.Stop()
+			scannerBroker := createScannerBroker(mem)
+			scannerBroker.Start()
+			defer scannerBroker.Stop()
+			scannerBroker.WaitForNodes("node_printerBroker")
+			scannerBroker.WaitFor("printer")
+			printerBroker.WaitForNodes("node_scannerBroker")
+
+			result := <-scannerBroker.Call("$node.health", nil, moleculer.Options{NodeID: "node_printerBroker"})
+			Expect(result.IsError()).Should(BeFalse())
+			for _, section := range []string{"cpu", "mem", "os", "process", "client", "net", "transit", "time"} {
+				Expect(result.Get(section).Exists()).Should(BeTrue(), section)
+			}
+			Expect(result.Get("os").Get("platform").String()).ShouldNot(BeEmpty())
+			Expect(result.Get("process").Get("pid").Int()).Should(BeNumerically(">", 0))
+			Expect(result.Get("process").Get("memory").Get("heapUsed").Int()).Should(BeNumerically(">", 0))
+			Expect(result.Get("time").Get("now").Int64()).Should(BeNumerically(">", 0))
+			Expect(result.Get("transit").Get("connected").Bool()).Should(BeTrue())
+
+			nodes := <-scannerBroker.Call("$node.list", nil, moleculer.Options{NodeID: "node_printerBroker"})
+			Expect(findBy("id", "node_printerBroker", nodes.Array())[0]["local"]).Should(BeTrue())
+			Expect(findBy("id", "node_scannerBroker", nodes.Array())[0]["local"]).Should(BeFalse())
+		})
 	})
 })
 
@@ -0,0 +1,201 @@
+package registry
+
+import (
+	"sort"
+	"time"
+
+	"github.com/moleculer-go/moleculer/strategy"
+)
+
+// NodeInfo is a node known by the registry.
+type NodeInfo struct {
+	ID        string
+	Local     bool
+	Available bool
+	Hostname  string
+	IPList    []string
+	CPU       int64
+	// Client is the type, version and language version of the moleculer client of the node.
+	Client map[string]interface{}
+	// Metadata of the node, set in its Config.Metadata.
+	Metadata map[string]interface{}
+	// Latency is the round-trip time of the last PING/PONG with the node, zero when it is unknown.
+	Latency time.Duration
+	// LastHeartbeat is when the last INFO or HEARTBEAT of the node was received, zero for the local node.
+	LastHeartbeat time.Time
+	// Services are the full names of the services of the node, e.g. v2.users.
+	Services []string
+}
+
+// EndpointInfo is a node where an action can be called.
+type EndpointInfo struct {
+	NodeID    string
+	Local     bool
+	Available bool
+	Latency   time.Duration
+}
+
+// ActionInfo is an action of a service with the nodes where it can be called.
+type ActionInfo struct {
+	// Name is the full name of the action, including the version of the service, e.g. v2.users.get.
+	Name       string
+	Service    string
+	Version    string
+	Visibility string
+	Endpoints  []EndpointInfo
+}
+
+// nodeLatency save the latency of the node, received in the $node.pong event,
+// and add it as a sample to the latency strategy.
+func (registry *ServiceRegistry) nodeLatency(args ...interface{}) {
+	pong, valid := args[0].(map[string]interface{})
+	if !valid {
+		return
+	}
+	nodeID, _ := pong["nodeID"].(string)
+	elapsed, _ := pong["elapsedTime"].(int64)
+	latency := time.Duration(elapsed) * time.Millisecond
+	registry.latencies.Store(nodeID, latency)
+	if meter, isMeter := registry.strategy.(strategy.LatencyMeter); isMeter {
+		meter.AddSample(nodeID, latency)
+	}
+	registry.callStrategies.Range(func(name, stg interface{}) bool {
+		if meter, isMeter := stg.(strategy.LatencyMeter); isMeter {
+			meter.AddSample(nodeID, latency)
+		}
+		return true
+	})
+}
+
+func (registry *ServiceRegistry) latency(nodeID string) time.Duration {
+	if value, exists := registry.latencies.Load(nodeID); exists {
+		return value.(time.Duration)
+	}
+	return 0
+}
+
+// nodeMetadata return the metadata of the node, empty when the node is unknown.
+func (registry *ServiceRegistry) nodeMetadata(nodeID string) map[string]interface{} {
+	if node, exists := registry.nodes.findNode(nodeID); exists {
+		if node, valid := node.(*Node); valid {
+			return node.Metadata()
+		}
+	}
+	return map[string]interface{}{}
+}
+
+// nodeUptime return how long the node is available. The local node and the unknown nodes
+// are not ramped up by the slow start, so their uptime is the slow start window.
+func (registry *ServiceRegistry) nodeUptime(nodeID string) time.Duration {
+	if nodeID != registry.localNode.GetID() {
+		if node, exists := registry.nodes.findNode(nodeID); exists {
+			if node, valid := node.(*Node); valid {
+				return node.Uptime()
+			}
+		}
+	}
+	return registry.broker.Config.SlowStartWindow
+}
+
+// useNodeMetadata give the metadata of the nodes to the strategies that select the endpoints by it.
+func (registry *ServiceRegistry) useNodeMetadata(stg strategy.Strategy) {
+	if metadataStrategy, valid := stg.(strategy.MetadataStrategy); valid {
+		metadataStrategy.SetNodeMetadata(registry.localNode.GetID(), registry.nodeMetadata)
+	}
+}
+
+func (registry *ServiceRegistry) isNodeAvailable(nodeID string) bool {
+	node, exists := registry.nodes.findNode(nodeID)
+	return exists && node.IsAvailable()
+}
+
+// KnownNodesInfo return the nodes known by the registry, sorted by node ID.
+func (registry *ServiceRegistry) KnownNodesInfo() []NodeInfo {
+	nodes := registry.nodes.list()
+	result := make([]NodeInfo, 0, len(nodes))
+	for _, node := range nodes {
+		info := node.ExportAsMap()
+		nodeID := node.GetID()
+		services := []string{}
+		for _, svc := range registry.services.listByNode(nodeID) {
+			services = append(services, svc.FullName())
+		}
+		sort.Strings(services)
+		hostname, _ := info["hostname"].(string)
+		ipList, _ := info["ipList"].([]string)
+		cpu, _ := info["cpu"].(int64)
+		client, _ := info["client"].(map[string]interface{})
+		var lastHeartbeat time.Time
+		if registryNode, isNode := node.(*Node); isNode {
+			lastHeartbeat = registryNode.LastHeartbeat()
+		}
+		result = append(result, NodeInfo{
+			ID:            nodeID,
+			Local:         nodeID == registry.localNode.GetID(),
+			Available:     node.IsAvailable(),
+			Hostname:      hostname,
+			IPList:        ipList,
+			CPU:           cpu,
+			Client:        client,
+			Metadata:      registry.nodeMetadata(nodeID),
+			Latency:       registry.latency(nodeID),
+			LastHeartbeat: lastHeartbeat,
+			Services:      services,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+// ActionsFor return the actions of the service, sorted by name. The name can include the
+// version (v2.users) to return only the actions of that version, otherwise the actions
+// of all versions of the service are returned.
+func (registry *ServiceRegistry) ActionsFor(serviceName string) []ActionInfo {
+	result := []ActionInfo{}
+	for name, entries := range registry.actions.listByName() {
+		matching := []ActionEntry{}
+		for _, entry := range entries {
+			if entry.service.Name() == serviceName || entry.service.FullName() == serviceName {
+				matching = append(matching, entry)
+			}
+		}
+		if len(matching) == 0 {
+			continue
+		}
+		svc := matching[0].service
+		result = append(result, ActionInfo{
+			Name:       name,
+			Service:    svc.FullName(),
+			Version:    svc.Version(),
+			Visibility: matching[0].action.Visibility(),
+			Endpoints:  registry.endpoints(matching),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+// EndpointsFor return the nodes where the action can be called, sorted by node ID.
+func (registry *ServiceRegistry) EndpointsFor(actionName string) []EndpointInfo {
+	return registry.endpoints(registry.actions.Find(actionName))
+}
+
+func (registry *ServiceRegistry) endpoints(entries []ActionEntry) []EndpointInfo {
+	result := make([]EndpointInfo, 0, len(entries))
+	for _, entry := range entries {
+		result = append(result, EndpointInfo{
+			NodeID:    entry.targetNodeID,
+			Local:     entry.isLocal,
+			Available: entry.isLocal || registry.isNodeAvailable(entry.targetNodeID),
+			Latency:   registry.latency(entry.targetNodeID),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].NodeID < result[j].NodeID
+	})
+	return result
+}
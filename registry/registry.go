@@ -3,9 +3,12 @@ package registry
 import (
 	"errors"
 	"fmt"
+	"math/rand"
+	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/moleculer-go/moleculer/middleware"
@@ -13,8 +16,11 @@ import (
 	"github.com/moleculer-go/moleculer/payload"
 
 	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/discovery"
 	"github.com/moleculer-go/moleculer/service"
+	"github.com/moleculer-go/moleculer/stats"
 	"github.com/moleculer-go/moleculer/strategy"
+	"github.com/moleculer-go/moleculer/validator"
 
 	"github.com/moleculer-go/moleculer/transit"
 	"github.com/moleculer-go/moleculer/transit/pubsub"
@@ -33,12 +39,24 @@ type ServiceRegistry struct {
 	events                *EventCatalog
 	broker                *moleculer.BrokerDelegates
 	strategy              strategy.Strategy
-	stopping              bool
+	discoverer            discovery.Discoverer
+	stopping              int32
+	done                  chan struct{}
 	heartbeatFrequency    time.Duration
 	heartbeatTimeout      time.Duration
 	offlineCheckFrequency time.Duration
 	offlineTimeout        time.Duration
 	nodeReceivedMutex     *sync.Mutex
+	statsProvider         moleculer.StatsProviderFunc
+	statsMutex            sync.Mutex
+	systemStats           moleculer.SystemStats
+	validator             moleculer.Validator
+	copyOnEmit            bool
+	startedTime           time.Time
+	latencies             sync.Map
+	callStrategies        sync.Map
+	sessions              sync.Map
+	conflicts             sync.Map
 }
 
 // createTransit create a transit instance based on the config.
@@ -47,27 +65,67 @@ func createTransit(broker *moleculer.BrokerDelegates) transit.Transit {
 	return transit
 }
 
-// createStrategy create a strategy instance based on the config.
+// createStrategy create a strategy instance based on the config: the StrategyFactory, or
+// the strategy registered with the Strategy name. The default is the random strategy.
 func createStrategy(broker *moleculer.BrokerDelegates) strategy.Strategy {
-	//TODO: when new strategies are addes.. adde config check here to load the right one.
 	if broker.Config.StrategyFactory != nil {
 		return broker.Config.StrategyFactory().(strategy.Strategy)
 	}
-
+	if broker.Config.Strategy != "" {
+		if stg, exists := strategy.Create(broker.Config.Strategy); exists {
+			return stg
+		}
+		broker.Logger("registry", "strategy").Warn("Strategy not found: ", broker.Config.Strategy, " - using the random strategy.")
+	}
 	return strategy.RandomStrategy{}
 }
 
+// createDiscoverer create the discoverer of the config: the DiscovererFactory, or the discoverer
+// of the Discoverer url. It returns nil when none is configured.
+func createDiscoverer(broker *moleculer.BrokerDelegates, nodeID string) discovery.Discoverer {
+	if broker.Config.DiscovererFactory != nil {
+		return broker.Config.DiscovererFactory().(discovery.Discoverer)
+	}
+	if broker.Config.Discoverer != "" {
+		if discoverer, exists := discovery.Create(broker.Config.Discoverer, nodeID, broker.Logger("registry", "discoverer")); exists {
+			return discoverer
+		}
+		broker.Logger("registry", "discoverer").Warn("Discoverer not found: ", broker.Config.Discoverer)
+	}
+	return nil
+}
+
+// createStatsProvider return the configured stats provider or the host stats.
+// Each registry has its own sampler, since the cpu utilization is measured since the previous sample.
+func createStatsProvider(broker *moleculer.BrokerDelegates) moleculer.StatsProviderFunc {
+	if broker.Config.StatsProvider != nil {
+		return broker.Config.StatsProvider
+	}
+	return stats.CreateSampler().Stats
+}
+
+func createValidator(broker *moleculer.BrokerDelegates) moleculer.Validator {
+	if broker.Config.Validator != nil {
+		return broker.Config.Validator
+	}
+	return validator.Default()
+}
+
 func CreateRegistry(nodeID string, broker *moleculer.BrokerDelegates) *ServiceRegistry {
 	config := broker.Config
 	transit := createTransit(broker)
 	strategy := createStrategy(broker)
 	logger := broker.Logger("registry", nodeID)
 	localNode := CreateNode(nodeID, true, logger.WithField("Node", nodeID))
+	if config.Metadata != nil {
+		localNode.(*Node).metadata = config.Metadata
+	}
 	localNode.Unavailable()
 	registry := &ServiceRegistry{
 		broker:                broker,
 		transit:               transit,
 		strategy:              strategy,
+		discoverer:            createDiscoverer(broker, nodeID),
 		logger:                logger,
 		localNode:             localNode,
 		actions:               CreateActionCatalog(logger.WithField("catalog", "Actions")),
@@ -78,10 +136,14 @@ func CreateRegistry(nodeID string, broker *moleculer.BrokerDelegates) *ServiceRe
 		heartbeatTimeout:      config.HeartbeatTimeout,
 		offlineCheckFrequency: config.OfflineCheckFrequency,
 		offlineTimeout:        config.OfflineTimeout,
-		stopping:              false,
 		nodeReceivedMutex:     &sync.Mutex{},
+		statsProvider:         createStatsProvider(broker),
+		validator:             createValidator(broker),
+		copyOnEmit:            config.CopyOnEmit,
 	}
 
+	registry.useNodeMetadata(strategy)
+	registry.actions.SlowStart(config.SlowStartWindow, registry.nodeUptime)
 	registry.logger.Debug("Service Registry created for broker: ", nodeID)
 
 	broker.Bus().On("$broker.started", func(args ...interface{}) {
@@ -90,6 +152,7 @@ func CreateRegistry(nodeID string, broker *moleculer.BrokerDelegates) *ServiceRe
 	})
 
 	registry.setupMessageHandlers()
+	broker.Bus().On("$node.pong", registry.nodeLatency)
 
 	return registry
 }
@@ -98,6 +161,19 @@ func (registry *ServiceRegistry) KnowService(name string) bool {
 	return registry.services.FindByName(name)
 }
 
+// DependencyAvailable check if the dependency runs in the required number of nodes.
+func (registry *ServiceRegistry) DependencyAvailable(dependency moleculer.Dependency) bool {
+	skipNodeID := ""
+	if dependency.RemoteOnly {
+		skipNodeID = registry.localNode.GetID()
+	}
+	minNodes := dependency.MinNodes
+	if minNodes < 1 {
+		minNodes = 1
+	}
+	return registry.services.CountNodes(dependency.Name, dependency.Version, skipNodeID) >= minNodes
+}
+
 func (registry *ServiceRegistry) KnowAction(name string) bool {
 	return registry.actions.Find(name) != nil
 }
@@ -107,6 +183,12 @@ func (registry *ServiceRegistry) KnowNode(nodeID string) bool {
 	return found
 }
 
+// IsNodeAvailable check if the node is known and connected.
+func (registry *ServiceRegistry) IsNodeAvailable(nodeID string) bool {
+	node, found := registry.nodes.findNode(nodeID)
+	return found && node.IsAvailable()
+}
+
 func (registry *ServiceRegistry) LocalNode() moleculer.Node {
 	return registry.localNode
 }
@@ -131,7 +213,14 @@ func (registry *ServiceRegistry) setupMessageHandlers() {
 
 func (registry *ServiceRegistry) Stop() {
 	registry.logger.Debug("Registry Stopping...")
-	registry.stopping = true
+	atomic.StoreInt32(&registry.stopping, 1)
+	if registry.discoverer != nil {
+		registry.discoverer.Stop()
+	}
+	if registry.done != nil {
+		close(registry.done)
+		registry.done = nil
+	}
 	err := <-registry.transit.Disconnect()
 	registry.localNode.Unavailable()
 	if err != nil {
@@ -148,7 +237,9 @@ func (registry *ServiceRegistry) LocalServices() []*service.Service {
 // Start : start the registry background processes.
 func (registry *ServiceRegistry) Start() {
 	registry.logger.Debug("Registry Start() ")
-	registry.stopping = false
+	atomic.StoreInt32(&registry.stopping, 0)
+	registry.startedTime = time.Now()
+	registry.updateStats()
 	err := <-registry.transit.Connect()
 	if err != nil {
 		panic(errors.New(fmt.Sprint("Could not connect to the transit. err: ", err)))
@@ -157,17 +248,100 @@ func (registry *ServiceRegistry) Start() {
 
 	registry.nodes.Add(registry.localNode)
 
+	done := make(chan struct{})
+	registry.done = done
 	if registry.heartbeatFrequency > 0 {
-		go registry.loopWhileAlive(registry.heartbeatFrequency, registry.transit.SendHeartbeat)
+		go registry.loopWhileAlive(done, jitter(registry.heartbeatFrequency), registry.sendHeartbeat)
 	}
 	if registry.heartbeatTimeout > 0 {
-		go registry.loopWhileAlive(registry.heartbeatTimeout, registry.checkExpiredRemoteNodes)
+		go registry.loopWhileAlive(done, registry.expiredCheckFrequency(), registry.checkExpiredRemoteNodes)
 	}
 	if registry.offlineCheckFrequency > 0 {
-		go registry.loopWhileAlive(registry.offlineCheckFrequency, registry.checkOfflineNodes)
+		go registry.loopWhileAlive(done, registry.offlineCheckFrequency, registry.checkOfflineNodes)
+	}
+	if meter, isMeter := registry.strategy.(strategy.LatencyMeter); isMeter {
+		go registry.loopWhileAlive(done, meter.PingInterval(), registry.pingNodes)
+	}
+	if registry.discoverer != nil {
+		if err := registry.discoverer.Start(registry.peerFound, registry.peerLost); err != nil {
+			registry.logger.Error("Could not start the discoverer - error: ", err)
+		}
+	}
+}
+
+// peerFound send a DISCOVER to a peer found by the discoverer, or to all nodes when its node ID
+// is unknown, so the peer is added to the catalog without waiting for its heartbeat.
+func (registry *ServiceRegistry) peerFound(peer discovery.Peer) {
+	if registry.isStopping() || peer.NodeID == registry.localNode.GetID() {
+		return
+	}
+	if node, exists := registry.nodes.findNode(peer.NodeID); exists && node.IsAvailable() {
+		return
+	}
+	registry.logger.Debug("Discoverer found peer: ", peer.Name, " nodeID: ", peer.NodeID)
+	registry.transit.DiscoverNode(peer.NodeID)
+}
+
+// peerLost disconnect the node of a peer lost by the discoverer, without waiting for the heartbeat timeout.
+func (registry *ServiceRegistry) peerLost(peer discovery.Peer) {
+	if registry.isStopping() || peer.NodeID == "" {
+		return
+	}
+	registry.logger.Debug("Discoverer lost peer: ", peer.Name, " nodeID: ", peer.NodeID)
+	registry.disconnectNode(peer.NodeID)
+}
+
+// jitter return the frequency changed by a random amount of up to 10%, so the brokers started
+// together do not send their heartbeats at the same time.
+func jitter(frequency time.Duration) time.Duration {
+	spread := int64(frequency / 5)
+	if spread <= 0 {
+		return frequency
+	}
+	return frequency - frequency/10 + time.Duration(rand.Int63n(spread))
+}
+
+// expiredCheckFrequency return how often the remote nodes are checked for expired heartbeats: on each
+// heartbeat, so a node is disconnected soon after the timeout, and at least 3 times per timeout.
+func (registry *ServiceRegistry) expiredCheckFrequency() time.Duration {
+	frequency := registry.heartbeatTimeout / 3
+	if registry.heartbeatFrequency > 0 && registry.heartbeatFrequency < frequency {
+		frequency = registry.heartbeatFrequency
+	}
+	return frequency
+}
+
+// updateStats read the resource usage of the host into the local node.
+func (registry *ServiceRegistry) updateStats() {
+	current := registry.statsProvider()
+	registry.statsMutex.Lock()
+	registry.systemStats = current
+	registry.statsMutex.Unlock()
+	registry.localNode.UpdateStats(current)
+}
+
+// lastStats return the resource usage read on the last heartbeat.
+func (registry *ServiceRegistry) lastStats() moleculer.SystemStats {
+	registry.statsMutex.Lock()
+	defer registry.statsMutex.Unlock()
+	return registry.systemStats
+}
+
+// sendHeartbeat update the local node stats and send the heartbeat.
+// pingNodes send a PING to each available remote node, to measure its latency.
+func (registry *ServiceRegistry) pingNodes() {
+	for _, node := range registry.nodes.list() {
+		if node.GetID() != registry.localNode.GetID() && node.IsAvailable() {
+			registry.transit.SendPing(node.GetID())
+		}
 	}
 }
 
+func (registry *ServiceRegistry) sendHeartbeat() {
+	registry.updateStats()
+	registry.transit.SendHeartbeat()
+}
+
 func (registry *ServiceRegistry) ServiceForAction(name string) []*service.Service {
 	actions := registry.actions.Find(name)
 	if actions != nil {
@@ -184,23 +358,44 @@ func (registry *ServiceRegistry) ServiceForAction(name string) []*service.Servic
 func (registry *ServiceRegistry) HandleRemoteEvent(context moleculer.BrokerContext) {
 	name := context.EventName()
 	groups := context.Groups()
-	if registry.stopping {
+	if registry.isStopping() {
 		registry.logger.Error("HandleRemoteEvent() - registry is stopping. Discarding event -> name: ", name, " groups: ", groups)
 		return
 	}
 	broadcast := context.IsBroadcast()
 	registry.logger.Debug("HandleRemoteEvent() - name: ", name, " groups: ", groups)
 
+	// broadcast events are delivered to all local handlers of the groups,
+	// balanced events to one local handler per group.
 	var stg strategy.Strategy
 	if !broadcast {
 		stg = registry.strategy
 	}
-	entries := registry.events.Find(name, groups, true, true, stg)
+	entries := registry.events.Find(name, groups, !broadcast, true, stg)
 	for _, localEvent := range entries {
-		go localEvent.emitLocalEvent(context)
+		go localEvent.emitLocalEvent(context, registry.copyOnEmit)
 	}
 }
 
+// PauseTransit stops processing the requests and events from remote nodes.
+func (registry *ServiceRegistry) PauseTransit() {
+	registry.transit.Pause()
+}
+
+// ResumeTransit restart processing the requests and events from remote nodes.
+func (registry *ServiceRegistry) ResumeTransit() {
+	registry.transit.Resume()
+}
+
+func (registry *ServiceRegistry) IsTransitPaused() bool {
+	return registry.transit.IsPaused()
+}
+
+// TransitStatus return the state of the connection with the transporter.
+func (registry *ServiceRegistry) TransitStatus() transit.ConnectionStatus {
+	return registry.transit.Status()
+}
+
 // LoadBalanceEvent load balance an event based on the known targetNodes.
 func (registry *ServiceRegistry) LoadBalanceEvent(context moleculer.BrokerContext) []*EventEntry {
 	name := context.EventName()
@@ -209,21 +404,56 @@ func (registry *ServiceRegistry) LoadBalanceEvent(context moleculer.BrokerContex
 	eventSig := fmt.Sprint("name: ", name, " groups: ", groups)
 	registry.logger.Trace("LoadBalanceEvent() - ", eventSig, " params: ", params)
 
-	entries := registry.events.Find(name, groups, true, false, registry.strategy)
-	if entries == nil {
-		msg := fmt.Sprint("Broker - no endpoints found for event: ", name, " it was discarded!")
+	entries := registry.findEvents(context, true)
+	if len(entries) == 0 {
+		msg := fmt.Sprint("Broker - no endpoints found for event: ", name, registry.onTargetNode(context), " it was discarded!")
 		registry.logger.Warn(msg)
 		return nil
 	}
 
+	registry.emitEvent(context, entries)
+	registry.logger.Trace("LoadBalanceEvent() - ", eventSig, " End.")
+	return entries
+}
+
+// LoadBalanceEventConfirmed emit a balanced event and wait for the transporter to acknowledge
+// the remote packets. It returns an error when there are no endpoints for the event
+// or a remote packet could not be delivered.
+func (registry *ServiceRegistry) LoadBalanceEventConfirmed(context moleculer.BrokerContext) error {
+	name := context.EventName()
+	groups := context.Groups()
+	eventSig := fmt.Sprint("name: ", name, " groups: ", groups)
+	registry.logger.Trace("LoadBalanceEventConfirmed() - ", eventSig, " params: ", context.Payload())
+
+	entries := registry.findEvents(context, true)
+	if len(entries) == 0 {
+		msg := fmt.Sprint("Broker - no endpoints found for event: ", name, registry.onTargetNode(context), " it was discarded!")
+		registry.logger.Warn(msg)
+		return errors.New(msg)
+	}
+
+	err := registry.emitEventConfirmed(context, entries)
+	registry.logger.Trace("LoadBalanceEventConfirmed() - ", eventSig, " End.")
+	return err
+}
+
+// EmitLocalEvent deliver the event to the local handlers only, one handler per group,
+// without sending it to the remote nodes.
+func (registry *ServiceRegistry) EmitLocalEvent(context moleculer.BrokerContext) []*EventEntry {
+	name := context.EventName()
+	groups := context.Groups()
+	eventSig := fmt.Sprint("name: ", name, " groups: ", groups)
+	registry.logger.Trace("EmitLocalEvent() - ", eventSig, " params: ", context.Payload())
+
+	entries := registry.events.Find(name, groups, true, true, registry.strategy)
+	if len(entries) == 0 {
+		registry.logger.Warn("Broker - no local endpoints found for event: ", name, " it was discarded!")
+		return nil
+	}
 	for _, eventEntry := range entries {
-		if eventEntry.isLocal {
-			eventEntry.emitLocalEvent(context)
-		} else {
-			registry.emitRemoteEvent(context, eventEntry)
-		}
+		eventEntry.emitLocalEvent(context, registry.copyOnEmit)
 	}
-	registry.logger.Trace("LoadBalanceEvent() - ", eventSig, " End.")
+	registry.logger.Trace("EmitLocalEvent() - ", eventSig, " End.")
 	return entries
 }
 
@@ -233,66 +463,245 @@ func (registry *ServiceRegistry) BroadcastEvent(context moleculer.BrokerContext)
 	eventSig := fmt.Sprint("name: ", name, " groups: ", groups)
 	registry.logger.Trace("BroadcastEvent() - ", eventSig, " payload: ", context.Payload())
 
-	entries := registry.events.Find(name, groups, false, false, nil)
-	if entries == nil {
-		msg := fmt.Sprint("Broker - no endpoints found for event: ", name, " it was discarded!")
+	entries := registry.findEvents(context, false)
+	if len(entries) == 0 {
+		msg := fmt.Sprint("Broker - no endpoints found for event: ", name, registry.onTargetNode(context), " it was discarded!")
 		registry.logger.Warn(msg)
 		return nil
 	}
 
-	for _, eventEntry := range entries {
-		if eventEntry.isLocal {
-			eventEntry.emitLocalEvent(context)
-		} else {
-			registry.emitRemoteEvent(context, eventEntry)
-		}
-	}
+	registry.emitEvent(context, entries)
 	registry.logger.Trace("BroadcastEvent() - ", eventSig, " End.")
 	return entries
 }
 
 // DelegateCall : invoke a service action and return a channel which will eventualy deliver the results ;).
-// This call might be local or remote.
+// This call might be local or remote. Failed calls are retried according to the call options
+// and the Config.RetryPolicy, and replaced by the fallback response when it is set.
 func (registry *ServiceRegistry) LoadBalanceCall(context moleculer.BrokerContext, opts ...moleculer.Options) chan moleculer.Payload {
 	actionName := context.ActionName()
 	params := context.Payload()
 	registry.logger.Trace("LoadBalanceCall() - actionName: ", actionName, " params: ", params, " opts: ", opts)
 
-	actionEntry := registry.nextAction(actionName, registry.strategy, opts...)
+	resultChan := make(chan moleculer.Payload, 1)
+	maxCallLevel := registry.broker.Config.MaxCallLevel
+	if maxCallLevel > 0 && context.Level() > maxCallLevel {
+		registry.logger.Error("LoadBalanceCall() - actionName: ", actionName, " level: ", context.Level(), " exceeds the MaxCallLevel: ", maxCallLevel)
+		resultChan <- payload.New(moleculer.NewMaxCallLevelError(registry.localNode.GetID(), context.Level()))
+		return resultChan
+	}
+
+	options := moleculer.Options{}
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+	result := registry.callEndpoint(context, options)
+	policy := registry.broker.Config.RetryPolicy
+	retries := options.Retries
+	if retries == 0 && policy.Enabled {
+		retries = policy.Retries
+	}
+	delay := policy.Delay
+	attemptContext := context
+	for attempt := 0; attempt < retries && registry.shouldRetry(result); attempt++ {
+		registry.logger.Warn("LoadBalanceCall() - actionName: ", actionName, " retry: ", attempt+1, " of ", retries, " error: ", result.Error())
+		time.Sleep(time.Duration(delay) * time.Millisecond)
+		delay = nextRetryDelay(delay, policy)
+		retry := attemptContext.Retry()
+		if retry.Err() != nil {
+			break
+		}
+		attemptContext = retry
+		result = registry.callEndpoint(attemptContext, options)
+	}
+	if attemptContext != context {
+		// the meta changed by the last attempt is kept, like in a call without retries.
+		context.UpdateMeta(attemptContext.Meta())
+	}
+	if result.IsError() && options.FallbackResponse != nil {
+		registry.logger.Debug("LoadBalanceCall() - actionName: ", actionName, " using fallback response - error: ", result.Error())
+		result = fallbackResponse(context, options.FallbackResponse, result.Error())
+	}
+	resultChan <- result
+	return resultChan
+}
+
+// callEndpoint invoke the next endpoint of the action and wait for the result.
+func (registry *ServiceRegistry) callEndpoint(context moleculer.BrokerContext, options moleculer.Options) moleculer.Payload {
+	actionName := context.ActionName()
+	actionEntry := registry.selectAction(context, actionName, options)
 	if actionEntry == nil {
 		msg := fmt.Sprint("Registry - endpoint not found for actionName: ", actionName)
+		if options.NodeID != "" {
+			msg = fmt.Sprint(msg, " nodeID: ", options.NodeID)
+		}
 		registry.logger.Error(msg)
-		resultChan := make(chan moleculer.Payload, 1)
-		resultChan <- payload.Error(msg)
-		return resultChan
+		err := moleculer.NewRetryableError(msg, 404, "SERVICE_NOT_FOUND", map[string]interface{}{
+			"action": actionName,
+			"nodeID": options.NodeID,
+		})
+		err.Name = "ServiceNotFoundError"
+		return payload.New(err)
 	}
 	registry.logger.Debug("LoadBalanceCall() - actionName: ", actionName, " target nodeID: ", actionEntry.TargetNodeID())
 
 	if actionEntry.isLocal {
+		if err := registry.checkVisibility(context, actionEntry); err != nil {
+			registry.logger.Warn("LoadBalanceCall() - actionName: ", actionName, " error: ", err)
+			return payload.New(err)
+		}
 		registry.broker.MiddlewareHandler("beforeLocalAction", context)
-		result := <-actionEntry.invokeLocalAction(context)
+		result := <-actionEntry.invokeLocalAction(context, registry.validator)
 		tempParams := registry.broker.MiddlewareHandler("afterLocalAction", middleware.AfterActionParams{context, result})
-		actionParams := tempParams.(middleware.AfterActionParams)
-
-		resultChan := make(chan moleculer.Payload, 1)
-		resultChan <- actionParams.Result
-		return resultChan
+		return tempParams.(middleware.AfterActionParams).Result
 	}
 
 	registry.broker.MiddlewareHandler("beforeRemoteAction", context)
 	result := <-registry.invokeRemoteAction(context, actionEntry)
 	tempParams := registry.broker.MiddlewareHandler("afterRemoteAction", middleware.AfterActionParams{context, result})
-	actionParams := tempParams.(middleware.AfterActionParams)
+	return tempParams.(middleware.AfterActionParams).Result
+}
 
-	resultChan := make(chan moleculer.Payload, 1)
-	resultChan <- actionParams.Result
-	return resultChan
+// checkVisibility reject the calls from remote nodes to protected and private actions, and
+// the calls to private actions that are not made by an action of the same service.
+func (registry *ServiceRegistry) checkVisibility(context moleculer.BrokerContext, actionEntry *ActionEntry) error {
+	action := actionEntry.action
+	if !action.IsLocalOnly() {
+		return nil
+	}
+	if source, ok := context.(interface{ SourceNodeID() string }); ok && source.SourceNodeID() != "" && source.SourceNodeID() != registry.localNode.GetID() {
+		return actionNotAccessible(action)
+	}
+	if action.Visibility() == moleculer.VisibilityPrivate {
+		caller := ""
+		if callerAction, ok := context.(interface{ CallerAction() string }); ok {
+			caller = callerAction.CallerAction()
+		}
+		if !strings.HasPrefix(caller, actionEntry.service.FullName()+".") {
+			return actionNotAccessible(action)
+		}
+	}
+	return nil
+}
+
+func actionNotAccessible(action *service.Action) error {
+	err := moleculer.NewError(fmt.Sprintf("Action '%s' is %s and it can not be called from this caller.", action.FullName(), action.Visibility()),
+		403, "ACTION_NOT_ACCESSIBLE", map[string]interface{}{"action": action.FullName(), "visibility": action.Visibility()})
+	err.Name = "ActionNotAccessibleError"
+	return err
+}
+
+// shouldRetry check if a call result should be retried using the Config.RetryPolicy.Check,
+// or retrying the retryable errors when it is not set.
+func (registry *ServiceRegistry) shouldRetry(result moleculer.Payload) bool {
+	if !result.IsError() {
+		return false
+	}
+	check := registry.broker.Config.RetryPolicy.Check
+	if check == nil {
+		check = moleculer.IsRetryable
+	}
+	return check(result.Error())
+}
 
+// nextRetryDelay multiply the delay by the policy factor, up to the policy max delay.
+func nextRetryDelay(delay int, policy moleculer.RetryPolicy) int {
+	if policy.Factor > 1 {
+		delay = delay * policy.Factor
+	}
+	if policy.MaxDelay > 0 && delay > policy.MaxDelay {
+		delay = policy.MaxDelay
+	}
+	return delay
+}
+
+// fallbackResponse return the fallback of a failed call, calling it when it is a FallbackFunc.
+func fallbackResponse(context moleculer.BrokerContext, fallback interface{}, err error) moleculer.Payload {
+	switch handler := fallback.(type) {
+	case moleculer.FallbackFunc:
+		return payload.New(handler(context.(moleculer.Context), err))
+	case func(moleculer.Context, error) interface{}:
+		return payload.New(handler(context.(moleculer.Context), err))
+	}
+	return payload.New(fallback)
+}
+
+// findEvents find the handlers of the event of the context, one per group when balanced is true
+// and all of them otherwise. When the context has a target node only its handlers are returned.
+func (registry *ServiceRegistry) findEvents(context moleculer.BrokerContext, balanced bool) []*EventEntry {
+	if nodeID := context.TargetNodeID(); nodeID != "" {
+		return registry.events.FindOnNode(context.EventName(), context.Groups(), nodeID, balanced)
+	}
+	if balanced {
+		return registry.events.Find(context.EventName(), context.Groups(), true, false, registry.strategy)
+	}
+	return registry.events.Find(context.EventName(), context.Groups(), false, false, nil)
+}
+
+func (registry *ServiceRegistry) onTargetNode(context moleculer.BrokerContext) string {
+	if nodeID := context.TargetNodeID(); nodeID != "" {
+		return " on node: " + nodeID
+	}
+	return ""
+}
+
+// emitEvent invoke the local entries and send a single packet to each remote node,
+// with the groups selected for that node, so the remote node delivers the
+// event once per group.
+func (registry *ServiceRegistry) emitEvent(context moleculer.BrokerContext, entries []*EventEntry) {
+	nodes, nodeGroups := registry.emitLocalEvents(context, entries)
+	for _, nodeID := range nodes {
+		registry.emitRemoteEvent(context, nodeID, nodeGroups[nodeID])
+	}
+}
+
+// emitEventConfirmed emit the event like emitEvent, but wait for the acknowledgment of
+// each remote packet and return the first delivery error.
+func (registry *ServiceRegistry) emitEventConfirmed(context moleculer.BrokerContext, entries []*EventEntry) error {
+	var result error
+	nodes, nodeGroups := registry.emitLocalEvents(context, entries)
+	for _, nodeID := range nodes {
+		context.SetTargetNodeID(nodeID)
+		context.SetGroups(nodeGroups[nodeID])
+		if err := registry.transit.EmitConfirmed(context); err != nil && result == nil {
+			result = err
+		}
+	}
+	return result
+}
+
+// emitLocalEvents invoke the local entries and return the remote nodes that must
+// receive the event, with the groups selected for each node.
+func (registry *ServiceRegistry) emitLocalEvents(context moleculer.BrokerContext, entries []*EventEntry) ([]string, map[string][]string) {
+	var nodes []string
+	nodeGroups := make(map[string][]string)
+	for _, eventEntry := range entries {
+		if eventEntry.isLocal {
+			eventEntry.emitLocalEvent(context, registry.copyOnEmit)
+			continue
+		}
+		nodeID := eventEntry.TargetNodeID()
+		if _, exists := nodeGroups[nodeID]; !exists {
+			nodes = append(nodes, nodeID)
+		}
+		nodeGroups[nodeID] = appendGroup(nodeGroups[nodeID], eventEntry.event.Group())
+	}
+	return nodes, nodeGroups
+}
+
+func appendGroup(groups []string, group string) []string {
+	for _, item := range groups {
+		if item == group {
+			return groups
+		}
+	}
+	return append(groups, group)
 }
 
-func (registry *ServiceRegistry) emitRemoteEvent(context moleculer.BrokerContext, eventEntry *EventEntry) {
-	context.SetTargetNodeID(eventEntry.TargetNodeID())
-	registry.logger.Trace("Before invoking remote event: ", context.EventName(), " context.TargetNodeID: ", context.TargetNodeID(), " context.Payload(): ", context.Payload())
+func (registry *ServiceRegistry) emitRemoteEvent(context moleculer.BrokerContext, nodeID string, groups []string) {
+	context.SetTargetNodeID(nodeID)
+	context.SetGroups(groups)
+	registry.logger.Trace("Before invoking remote event: ", context.EventName(), " context.TargetNodeID: ", context.TargetNodeID(), " groups: ", groups, " context.Payload(): ", context.Payload())
 	registry.transit.Emit(context)
 }
 
@@ -301,19 +710,32 @@ func (registry *ServiceRegistry) invokeRemoteAction(context moleculer.BrokerCont
 	context.SetTargetNodeID(actionEntry.TargetNodeID())
 	registry.logger.Trace("Before invoking remote action: ", context.ActionName(), " context.TargetNodeID: ", context.TargetNodeID(), " context.Payload(): ", context.Payload())
 
+	handler := registry.remoteActionHandler(context, actionEntry)
 	go func() {
-		actionResult := <-registry.transit.Request(context)
+		actionResult := payload.New(handler(context.(moleculer.Context), context.Payload()))
 		registry.logger.Trace("remote request done! action: ", context.ActionName(), " results: ", actionResult)
-		if registry.stopping {
-			registry.logger.Error("invokeRemoteAction() - registry is stopping. Discarding action result -> name: ", context.ActionName())
-			result <- payload.New(errors.New("can't complete request! registry stopping..."))
-		} else {
-			result <- actionResult
-		}
+		// the results received while the registry is stopping are delivered, since
+		// the transit drains the pending requests before it disconnects.
+		result <- actionResult
 	}()
 	return result
 }
 
+// remoteActionHandler return the handler that sends the request to the remote node, wrapped by the remoteAction middlewares.
+func (registry *ServiceRegistry) remoteActionHandler(context moleculer.BrokerContext, actionEntry *ActionEntry) moleculer.ActionHandler {
+	request := func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+		return <-registry.transit.Request(ctx.(moleculer.BrokerContext))
+	}
+	params := registry.broker.MiddlewareHandler("remoteAction", middleware.ActionHandlerParams{
+		Action:  context.ActionName(),
+		Service: actionEntry.Service().FullName(),
+		NodeID:  actionEntry.TargetNodeID(),
+		Handler: request,
+		Cache:   actionEntry.action.Cache(),
+	})
+	return params.(middleware.ActionHandlerParams).Handler
+}
+
 // removeServicesByNodeID
 func (registry *ServiceRegistry) removeServicesByNodeID(nodeID string) {
 	svcs := registry.services.RemoveByNode(nodeID)
@@ -321,7 +743,7 @@ func (registry *ServiceRegistry) removeServicesByNodeID(nodeID string) {
 		for _, svc := range svcs {
 			registry.broker.Bus().EmitAsync(
 				"$registry.service.removed",
-				[]interface{}{svc.Summary()})
+				[]interface{}{svc.Summary(), serviceDiff(nil, svc.Actions(), nil, svc.Events())})
 		}
 	}
 	registry.actions.RemoveByNode(nodeID)
@@ -331,10 +753,11 @@ func (registry *ServiceRegistry) removeServicesByNodeID(nodeID string) {
 // disconnectNode remove node info (actions, events) from local registry.
 func (registry *ServiceRegistry) disconnectNode(nodeID string) {
 	node, exists := registry.nodes.findNode(nodeID)
-	if !exists {
+	if !exists || !node.IsAvailable() {
 		return
 	}
 	registry.removeServicesByNodeID(nodeID)
+	registry.removeSessionsByNodeID(nodeID)
 	node.Unavailable()
 	registry.broker.Bus().EmitAsync("$node.disconnected", []interface{}{nodeID})
 	registry.logger.Warnf("Node %s disconnected ", nodeID)
@@ -348,32 +771,89 @@ func (registry *ServiceRegistry) checkExpiredRemoteNodes() {
 }
 
 func (registry *ServiceRegistry) checkOfflineNodes() {
-	expiredNodes := registry.nodes.expiredNodes(registry.offlineTimeout)
-	for _, node := range expiredNodes {
-		nodeID := node.GetID()
-		registry.nodes.removeNode(nodeID)
-		registry.logger.Warnf("Removed offline Node: %s  from the registry because it hasn't submitted heartbeat in %d seconds.", nodeID, registry.offlineTimeout)
+	offlineNodes := registry.nodes.offlineNodes(registry.offlineTimeout)
+	for _, node := range offlineNodes {
+		registry.removeNode(node.GetID())
+	}
+}
+
+// removeNode remove an offline node from the registry with its services and the
+// routing state kept for it. It emits the $node.removed event.
+func (registry *ServiceRegistry) removeNode(nodeID string) {
+	registry.nodeReceivedMutex.Lock()
+	defer registry.nodeReceivedMutex.Unlock()
+	node, exists := registry.nodes.findNode(nodeID)
+	if !exists || !node.IsOffline(registry.offlineTimeout) {
+		return
+	}
+	registry.nodes.removeNode(nodeID)
+	registry.removeServicesByNodeID(nodeID)
+	registry.removeSessionsByNodeID(nodeID)
+	registry.latencies.Delete(nodeID)
+	registry.broker.Bus().EmitAsync("$node.removed", []interface{}{nodeID})
+	registry.logger.Warnf("Removed offline Node: %s from the registry because it is offline for more than %s.", nodeID, registry.offlineTimeout)
+}
+
+// nodeIDConflict handle an INFO or HEARTBEAT of another broker instance with the local node ID. The
+// registry ignores its packets and emits the $node.id.conflict event once for each instance.
+func (registry *ServiceRegistry) nodeIDConflict(instanceID string) {
+	if _, reported := registry.conflicts.LoadOrStore(instanceID, true); reported {
+		return
+	}
+	nodeID := registry.localNode.GetID()
+	registry.logger.Errorf("Fatal protocol error - the node ID %s is used by another broker instance: %s. Its packets are ignored, set a unique node ID for each broker.", nodeID, instanceID)
+	// the other instance may not have received a packet of this instance yet, e.g. when this broker
+	// stops on the conflict, the heartbeat with the local instanceID lets it detect the conflict too.
+	registry.transit.SendHeartbeat()
+	conflict := []interface{}{map[string]interface{}{
+		"nodeID":     nodeID,
+		"instanceID": instanceID,
+	}}
+	if registry.broker.IsStarted() {
+		registry.broker.Bus().EmitAsync("$node.id.conflict", conflict)
+	} else {
+		// detected while starting, emit it when the local services are subscribed.
+		registry.broker.Bus().Once("$broker.started", func(...interface{}) {
+			registry.broker.Bus().EmitAsync("$node.id.conflict", conflict)
+		})
 	}
 }
 
+// isStopping check if the registry is stopping. It is read by the transit and loop goroutines.
+func (registry *ServiceRegistry) isStopping() bool {
+	return atomic.LoadInt32(&registry.stopping) == 1
+}
+
 // loopWhileAlive : can the delegate runction in the given frequency and stop whe  the registry is stopping
-func (registry *ServiceRegistry) loopWhileAlive(frequency time.Duration, delegate func()) {
+// loopWhileAlive call the delegate on each tick until the done channel is closed by Stop. The ticker keeps
+// the calls on schedule, so the time spent by the delegate does not delay the next call.
+func (registry *ServiceRegistry) loopWhileAlive(done chan struct{}, frequency time.Duration, delegate func()) {
+	ticker := time.NewTicker(frequency)
+	defer ticker.Stop()
 	for {
-		if registry.stopping {
-			break
+		if registry.isStopping() {
+			return
 		}
 		delegate()
-		time.Sleep(frequency)
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
 	}
 }
 
 func (registry *ServiceRegistry) filterMessages(handler func(message moleculer.Payload)) func(message moleculer.Payload) {
 	return func(message moleculer.Payload) {
-		if registry.stopping {
+		if registry.isStopping() {
 			registry.logger.Warn("filterMessages() - registry is stopping. Discarding message: ", message)
 			return
 		}
 		if message.Get("sender").Exists() && message.Get("sender").String() == registry.localNode.GetID() {
+			if instanceID := message.Get("instanceID"); instanceID.Exists() && instanceID.String() != registry.transit.InstanceID() {
+				registry.nodeIDConflict(instanceID.String())
+				return
+			}
 			registry.logger.Debug("filterMessages() - Same host message (sender == localNodeID). discarding... ", message)
 			return
 		}
@@ -406,6 +886,11 @@ func compatibility(info map[string]interface{}) map[string]interface{} {
 	if !exists {
 		info["version"] = ""
 	}
+	for _, field := range []string{"settings", "metadata"} {
+		if _, isMap := info[field].(map[string]interface{}); !isMap {
+			info[field] = map[string]interface{}{}
+		}
+	}
 	return info
 }
 
@@ -414,18 +899,24 @@ func (registry *ServiceRegistry) remoteNodeInfoReceived(message moleculer.Payloa
 	registry.nodeReceivedMutex.Lock()
 	defer registry.nodeReceivedMutex.Unlock()
 	nodeID := message.Get("sender").String()
+	if registry.nodes.outdated(message.RawMap()) {
+		registry.logger.Debug("remoteNodeInfoReceived() - ignoring outdated info of node: ", nodeID, " seq: ", message.Get("seq").Int64())
+		return
+	}
 	services := message.Get("services").MapArray()
 	exists, reconnected := registry.nodes.Info(message.RawMap())
 	for _, serviceInfo := range services {
 		serviceInfo = compatibility(serviceInfo)
+		settings, metadata := registry.services.settingsOf(nodeID, serviceInfo)
 		svc, newService, updatedActions, newActions, deletedActions, updatedEvents, newEvents, deletedEvents := registry.services.updateRemote(nodeID, serviceInfo)
 
 		for _, newAction := range newActions {
 			serviceAction := service.CreateServiceAction(
-				serviceInfo["name"].(string),
+				svc.FullName(),
 				newAction.Name(),
 				nil,
 				moleculer.ObjectSchema{nil})
+			serviceAction.SetCache(newAction.Cache())
 			registry.actions.Add(serviceAction, svc, false)
 		}
 
@@ -463,9 +954,17 @@ func (registry *ServiceRegistry) remoteNodeInfoReceived(message moleculer.Payloa
 
 			registry.broker.Bus().EmitAsync(
 				"$registry.service.added",
-				[]interface{}{svc.Summary()})
+				[]interface{}{svc.Summary(), serviceDiff(newActions, nil, newEvents, nil)})
+		} else if len(newActions) > 0 || len(deletedActions) > 0 || len(newEvents) > 0 || len(deletedEvents) > 0 ||
+			!reflect.DeepEqual(settings, svc.Settings()) || !reflect.DeepEqual(metadata, svc.Metadata()) {
+			registry.logger.Debugf("Registry - remote %s service is updated.", svc.FullName())
+
+			registry.broker.Bus().EmitAsync(
+				"$registry.service.updated",
+				[]interface{}{svc.Summary(), serviceDiff(newActions, deletedActions, newEvents, deletedEvents)})
 		}
 	}
+	registry.removeMissingServices(nodeID, services)
 
 	var neighbours int64
 	if message.Get("neighbours").Exists() {
@@ -474,17 +973,21 @@ func (registry *ServiceRegistry) remoteNodeInfoReceived(message moleculer.Payloa
 
 	eventParam := []interface{}{nodeID, neighbours}
 	eventName := "$node.connected"
-	if exists {
-		eventName = "$node.updated"
-	} else if reconnected {
+	if reconnected {
 		eventName = "$node.reconnected"
+	} else if exists {
+		eventName = "$node.updated"
 	}
 	registry.broker.Bus().EmitAsync(eventName, eventParam)
 }
 
 // subscribeInternalEvent subscribe event listeners for internal events (e.g. $node.disconnected) using the localBus.
-func (registry *ServiceRegistry) subscribeInternalEvent(event service.Event) {
+// The listener is ignored after the service is removed, since the bus does not support removing listeners.
+func (registry *ServiceRegistry) subscribeInternalEvent(event service.Event, svc *service.Service) {
 	registry.broker.Bus().On(event.Name(), func(data ...interface{}) {
+		if !registry.services.Contains(svc) {
+			return
+		}
 		params := payload.New(nil)
 		if len(data) > 0 {
 			params = payload.New(data[0])
@@ -513,38 +1016,200 @@ func (registry *ServiceRegistry) AddLocalService(service *service.Service) {
 	}
 	for _, event := range events {
 		if strings.Index(event.Name(), "$") == 0 {
-			registry.subscribeInternalEvent(event)
+			registry.subscribeInternalEvent(event, service)
 		} else {
 			registry.events.Add(event, service, true)
 		}
 	}
 	registry.localNode.Publish(service.AsMap())
 	registry.logger.Debug("Registry published local service: ", service.FullName(), " # actions: ", len(actions), " # events: ", len(events), " nodeID: ", service.NodeID())
-	registry.notifyServiceAdded(service.Summary())
+	registry.notifyServiceAdded(service.Summary(), serviceDiff(actions, nil, events, nil))
+}
+
+// RemoveLocalService remove a local service and its actions and events from the registry.
+// The local node info is updated, so remote nodes receive the new list of services.
+func (registry *ServiceRegistry) RemoveLocalService(service *service.Service) {
+	if !registry.services.Contains(service) {
+		registry.logger.Trace("registry - RemoveLocalService() - Service not registered, will ignore.. service fullName: ", service.FullName())
+		return
+	}
+	registry.removeService(service)
+	registry.localNode.Unpublish(service.Name(), service.Version())
+	registry.logger.Debug("Registry removed local service: ", service.FullName())
+}
+
+// removeService remove the service, its actions and events from the registry.
+func (registry *ServiceRegistry) removeService(svc *service.Service) {
+	if registry.services.Remove(svc.Name(), svc.Version(), svc.NodeID()) == nil {
+		return
+	}
+	registry.actions.RemoveByService(svc)
+	registry.events.RemoveByService(svc)
+	registry.broker.Bus().EmitAsync(
+		"$registry.service.removed",
+		[]interface{}{svc.Summary(), serviceDiff(nil, svc.Actions(), nil, svc.Events())})
+}
+
+// removeMissingServices remove the services of the remote node that are not in its node info anymore.
+func (registry *ServiceRegistry) removeMissingServices(nodeID string, services []map[string]interface{}) {
+	for _, svc := range registry.services.listByNode(nodeID) {
+		found := false
+		for _, serviceInfo := range services {
+			if serviceInfo["name"] == svc.Name() && service.ParseVersion(serviceInfo["version"]) == svc.Version() {
+				found = true
+				break
+			}
+		}
+		if !found {
+			registry.logger.Infof("Registry - remote %s service is removed.", svc.FullName())
+			registry.removeService(svc)
+		}
+	}
 }
 
 // notifyServiceAdded notify when a service is added to the registry.
-func (registry *ServiceRegistry) notifyServiceAdded(svc map[string]string) {
+func (registry *ServiceRegistry) notifyServiceAdded(svc map[string]string, diff map[string]interface{}) {
 	if registry.broker.IsStarted() {
 		registry.broker.Bus().EmitAsync(
 			"$registry.service.added",
-			[]interface{}{svc})
+			[]interface{}{svc, diff})
 	} else {
 		registry.broker.Bus().Once("$broker.started", func(...interface{}) {
 			registry.broker.Bus().EmitAsync(
 				"$registry.service.added",
-				[]interface{}{svc})
+				[]interface{}{svc, diff})
 		})
 	}
 }
 
+// serviceDiff return the actions (full names) and events added and removed by a service change. It is
+// the second value of the $registry.service events, so the listeners can react to each change.
+func serviceDiff(addedActions, removedActions []service.Action, addedEvents, removedEvents []service.Event) map[string]interface{} {
+	return map[string]interface{}{
+		"added":   map[string]interface{}{"actions": actionNames(addedActions), "events": eventNames(addedEvents)},
+		"removed": map[string]interface{}{"actions": actionNames(removedActions), "events": eventNames(removedEvents)},
+	}
+}
+
+func actionNames(actions []service.Action) []string {
+	names := make([]string, 0, len(actions))
+	for index := range actions {
+		names = append(names, actions[index].FullName())
+	}
+	return names
+}
+
+func eventNames(events []service.Event) []string {
+	names := make([]string, 0, len(events))
+	for index := range events {
+		names = append(names, events[index].Name())
+	}
+	return names
+}
+
 // nextAction it will find and return the next action to be invoked.
 // If multiple nodes that contain this action are found it will use the strategy to decide which one to use.
 func (registry *ServiceRegistry) nextAction(actionName string, strategy strategy.Strategy, opts ...moleculer.Options) *ActionEntry {
 	if len(opts) > 0 && opts[0].NodeID != "" {
 		return registry.actions.NextFromNode(actionName, opts[0].NodeID)
 	}
-	return registry.actions.Next(actionName, strategy)
+	return registry.actions.Select(actionName, strategy, registry.preferLocal(opts...))
+}
+
+// sessionOf return the session of the call: the Session of the call options, or the session
+// key of the context meta. It is empty when the call has no session.
+func sessionOf(context moleculer.BrokerContext, options moleculer.Options) string {
+	if options.Session != "" {
+		return options.Session
+	}
+	if meta := context.Meta(); meta != nil && meta.Get(moleculer.SessionMetaKey).Exists() {
+		return meta.Get(moleculer.SessionMetaKey).String()
+	}
+	return ""
+}
+
+// selectAction return the entry of the action to invoke. When the call has a session, the node selected
+// by the first call of the session and the action is used again while it is available.
+func (registry *ServiceRegistry) selectAction(context moleculer.BrokerContext, actionName string, options moleculer.Options) *ActionEntry {
+	session := sessionOf(context, options)
+	if session == "" || options.NodeID != "" {
+		return registry.nextAction(actionName, strategyFor(context, registry.callStrategy(options)), options)
+	}
+	key := session + "|" + actionName
+	if nodeID, exists := registry.sessions.Load(key); exists {
+		if node, exists := registry.nodes.findNode(nodeID.(string)); exists && node.IsAvailable() {
+			if actionEntry := registry.actions.NextFromNode(actionName, nodeID.(string)); actionEntry != nil {
+				return actionEntry
+			}
+		}
+		registry.sessions.Delete(key)
+	}
+	actionEntry := registry.nextAction(actionName, strategyFor(context, registry.callStrategy(options)), options)
+	if actionEntry != nil {
+		registry.sessions.Store(key, actionEntry.TargetNodeID())
+	}
+	return actionEntry
+}
+
+// removeSessionsByNodeID remove the sessions pinned to a node, so they select a new node on the next call.
+func (registry *ServiceRegistry) removeSessionsByNodeID(nodeID string) {
+	registry.sessions.Range(func(key, value interface{}) bool {
+		if value.(string) == nodeID {
+			registry.sessions.Delete(key)
+		}
+		return true
+	})
+}
+
+// preferLocal return if the local endpoint is preferred for the call: the PreferLocal of the
+// call options, or of the config. The default is true.
+func (registry *ServiceRegistry) preferLocal(opts ...moleculer.Options) bool {
+	if len(opts) > 0 && opts[0].PreferLocal != nil {
+		return *opts[0].PreferLocal
+	}
+	if preferLocal := registry.broker.Config.PreferLocal; preferLocal != nil {
+		return *preferLocal
+	}
+	return true
+}
+
+// callStrategy return the strategy of the call options, or the strategy of the registry. The
+// strategies of the calls are created once for each name, so they keep their state (e.g. the
+// round robin counter) between the calls.
+func (registry *ServiceRegistry) callStrategy(options moleculer.Options) strategy.Strategy {
+	if options.Strategy == "" {
+		return registry.strategy
+	}
+	name := strings.ToLower(options.Strategy)
+	if stg, exists := registry.callStrategies.Load(name); exists {
+		return stg.(strategy.Strategy)
+	}
+	stg, exists := strategy.Create(name)
+	if !exists {
+		registry.logger.Warn("Strategy not found: ", options.Strategy, " - using the strategy of the broker.")
+		return registry.strategy
+	}
+	registry.useNodeMetadata(stg)
+	stored, _ := registry.callStrategies.LoadOrStore(name, stg)
+	return stored.(strategy.Strategy)
+}
+
+// contextSelector select the endpoints of a call with a strategy that uses the call context.
+type contextSelector struct {
+	strategy.ContextStrategy
+	context moleculer.BrokerContext
+}
+
+func (selector contextSelector) Select(nodes []strategy.Selector) *strategy.Selector {
+	return selector.SelectFor(selector.context, nodes)
+}
+
+// strategyFor return the strategy to select the endpoint of the call.
+func strategyFor(context moleculer.BrokerContext, stg strategy.Strategy) strategy.Strategy {
+	if contextStrategy, isContextStrategy := stg.(strategy.ContextStrategy); isContextStrategy {
+		return contextSelector{contextStrategy, context}
+	}
+	return stg
 }
 
 func (registry *ServiceRegistry) KnownEventListeners(addNode bool) []string {
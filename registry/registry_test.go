@@ -9,6 +9,7 @@ import (
 	bus "github.com/moleculer-go/goemitter"
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/broker"
+	"github.com/moleculer-go/moleculer/registry"
 	"github.com/moleculer-go/moleculer/transit/memory"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -167,22 +168,29 @@ var _ = Describe("Registry", func() {
 							addedMutex.Lock()
 							defer addedMutex.Unlock()
 							serviceAdded = append(serviceAdded, params)
-							go events.EmitSync("$registry.service.added", serviceAdded)
+							go events.EmitSync("$registry.service.added", append([]moleculer.Payload{}, serviceAdded...))
 						},
 					},
 					moleculer.Event{
 						Name: "$registry.service.removed",
 						Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+							addedMutex.Lock()
+							defer addedMutex.Unlock()
 							serviceRemoved = append(serviceRemoved, params)
-							go events.EmitSync("$registry.service.removed", serviceRemoved)
+							go events.EmitSync("$registry.service.removed", append([]moleculer.Payload{}, serviceRemoved...))
 						},
 					},
 				},
 			})
 			onEvent := func(event string, callback func(list []moleculer.Payload, cancel func())) {
-				events.On(event, func(v ...interface{}) {
+				addedMutex.Lock()
+				emitter := events
+				addedMutex.Unlock()
+				emitter.On(event, func(v ...interface{}) {
 					list := v[0].([]moleculer.Payload)
 					callback(list, func() {
+						addedMutex.Lock()
+						defer addedMutex.Unlock()
 						events = bus.Construct()
 					})
 				})
@@ -210,14 +218,14 @@ var _ = Describe("Registry", func() {
 
 			scannerBroker.Start()
 
-			step := make(chan bool)
+			scannerAdded := make(chan bool)
 			onEvent("$registry.service.added", func(list []moleculer.Payload, cancel func()) {
-				if hasNode(serviceAdded, "node_scannerBroker") {
+				if hasNode(list, "node_scannerBroker") {
 					cancel()
-					step <- true
+					scannerAdded <- true
 				}
 			})
-			<-step
+			<-scannerAdded
 
 			scanResult = <-scannerBroker.Call("scanner.scan", scanText)
 			Expect(scanResult.IsError()).ShouldNot(Equal(true))
@@ -229,15 +237,17 @@ var _ = Describe("Registry", func() {
 
 			cpuBroker.Start()
 
+			addedMutex.Lock()
 			serviceAdded = []moleculer.Payload{}
-			step = make(chan bool)
+			addedMutex.Unlock()
+			cpuAdded := make(chan bool)
 			onEvent("$registry.service.added", func(list []moleculer.Payload, cancel func()) {
-				if hasNode(serviceAdded, "node_cpuBroker") {
+				if hasNode(list, "node_cpuBroker") {
 					cancel()
-					step <- true
+					cpuAdded <- true
 				}
 			})
-			<-step
+			<-cpuAdded
 			cpuBroker.WaitForActions("scanner.scan", "printer.print")
 			time.Sleep(time.Millisecond)
 
@@ -249,20 +259,76 @@ var _ = Describe("Registry", func() {
 			//stopping broker B
 			scannerBroker.Stop()
 
-			step = make(chan bool)
+			scannerRemoved := make(chan bool)
 			onEvent("$registry.service.removed", func(list []moleculer.Payload, cancel func()) {
-				if hasNode(serviceRemoved, "node_scannerBroker") {
+				if hasNode(list, "node_scannerBroker") {
 					cancel()
-					step <- true
+					scannerRemoved <- true
 				}
 			})
-			<-step
+			<-scannerRemoved
 
-			Expect(func() {
-				<-scannerBroker.Call("scanner.scan", scanText)
-			}).Should(Panic()) //broker B is stopped ... so it should panic
+			result := <-scannerBroker.Call("scanner.scan", scanText)
+			Expect(result.Error()).Should(Equal(moleculer.ErrBrokerNotStarted)) //broker B is stopped ... so it should fail
 
 			close(done)
 		}, 3)
 	})
+
+	It("Should update and export a node concurrently", func() {
+		node := registry.CreateNode("concurrent-node", false, logger)
+		info := map[string]interface{}{
+			"ipList":   []interface{}{"10.0.0.1"},
+			"hostname": "concurrent-host",
+			"client":   map[string]interface{}{},
+			"services": []interface{}{},
+		}
+		var wait sync.WaitGroup
+		for index := 0; index < 20; index++ {
+			wait.Add(3)
+			go func(index int) {
+				defer wait.Done()
+				node.Update("concurrent-node", info)
+				node.HeartBeat(map[string]interface{}{"cpu": float64(index)})
+			}(index)
+			go func() {
+				defer wait.Done()
+				node.Publish(map[string]interface{}{"name": "math"})
+				node.IncreaseSequence()
+			}()
+			go func() {
+				defer wait.Done()
+				node.ExportAsMap()
+				node.IsExpired(time.Second)
+				node.Unavailable()
+			}()
+		}
+		wait.Wait()
+		Expect(node.ExportAsMap()["hostname"]).Should(Equal("concurrent-host"))
+	})
+
+	It("Should expire a node after the timeout or three of its heartbeat intervals", func() {
+		info := func(interval float64) map[string]interface{} {
+			return map[string]interface{}{
+				"ipList":            []interface{}{"10.0.0.1"},
+				"hostname":          "expiring-host",
+				"client":            map[string]interface{}{},
+				"services":          []interface{}{},
+				"heartbeatInterval": interval,
+			}
+		}
+		node := registry.CreateNode("expiring-node", false, logger)
+		node.Update("expiring-node", info(0))
+		Expect(node.IsExpired(50 * time.Millisecond)).Should(BeFalse())
+		Eventually(func() bool { return node.IsExpired(50 * time.Millisecond) }).Should(BeTrue())
+
+		node.HeartBeat(map[string]interface{}{})
+		Expect(node.(*registry.Node).LastHeartbeat()).Should(BeTemporally("~", time.Now(), 20*time.Millisecond))
+		Expect(node.IsExpired(50 * time.Millisecond)).Should(BeFalse())
+
+		node.Update("expiring-node", info(0.1))
+		time.Sleep(100 * time.Millisecond)
+		Expect(node.IsExpired(50 * time.Millisecond)).Should(BeFalse())
+		Eventually(func() bool { return node.IsExpired(50 * time.Millisecond) }).Should(BeTrue())
+	})
 })
@@ -8,9 +8,12 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
+// ServiceCatalog is read without locking, the mutex serializes the writers so
+// the counters of servicesByName are not lost by concurrent updates.
 type ServiceCatalog struct {
 	services       sync.Map
 	servicesByName sync.Map
+	mutex          sync.Mutex
 	logger         *log.Entry
 }
 
@@ -20,9 +23,7 @@ type ServiceEntry struct {
 }
 
 func CreateServiceCatalog(logger *log.Entry) *ServiceCatalog {
-	services := sync.Map{}
-	servicesByName := sync.Map{}
-	return &ServiceCatalog{services, servicesByName, logger}
+	return &ServiceCatalog{logger: logger}
 }
 
 // createKey creates the catalogy key used in the map
@@ -37,9 +38,58 @@ func (serviceCatalog *ServiceCatalog) Find(name string, version string, nodeID s
 	return exists
 }
 
+// settingsOf return the current settings and metadata of the remote service, nil when it is not registered.
+func (serviceCatalog *ServiceCatalog) settingsOf(nodeID string, serviceInfo map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	key := createKey(serviceInfo["name"].(string), service.ParseVersion(serviceInfo["version"]), nodeID)
+	item, exists := serviceCatalog.services.Load(key)
+	if !exists {
+		return nil, nil
+	}
+	svc := item.(ServiceEntry).service
+	return svc.Settings(), svc.Metadata()
+}
+
 func (serviceCatalog *ServiceCatalog) FindByName(name string) bool {
-	_, exists := serviceCatalog.servicesByName.Load(name)
-	return exists
+	value, exists := serviceCatalog.servicesByName.Load(name)
+	return exists && value.(int) > 0
+}
+
+// CountNodes count the nodes running the service with the given name, and version when it is not empty.
+// Without a version the name can also be the full name (v2.orders). Services of the node skipNodeID are ignored.
+func (serviceCatalog *ServiceCatalog) CountNodes(name, version, skipNodeID string) int {
+	nodes := map[string]bool{}
+	serviceCatalog.services.Range(func(key, value interface{}) bool {
+		entry := value.(ServiceEntry)
+		if entry.nodeID == skipNodeID {
+			return true
+		}
+		svc := entry.service
+		if version == "" && (svc.Name() == name || svc.FullName() == name) ||
+			version != "" && svc.FullName() == service.JoinVersionToName(name, version) {
+			nodes[entry.nodeID] = true
+		}
+		return true
+	})
+	return len(nodes)
+}
+
+// Contains check if the service instance is registered in the catalog.
+func (serviceCatalog *ServiceCatalog) Contains(service *service.Service) bool {
+	item, exists := serviceCatalog.services.Load(createKey(service.Name(), service.Version(), service.NodeID()))
+	return exists && item.(ServiceEntry).service == service
+}
+
+// listByNode list the services of the given nodeID.
+func (serviceCatalog *ServiceCatalog) listByNode(nodeID string) []*service.Service {
+	var result []*service.Service
+	serviceCatalog.services.Range(func(key, value interface{}) bool {
+		entry := value.(ServiceEntry)
+		if entry.nodeID == nodeID {
+			result = append(result, entry.service)
+		}
+		return true
+	})
+	return result
 }
 
 // Get : Return the service for the given name, version and nodeID if it exists in the catalog.
@@ -47,8 +97,7 @@ func (serviceCatalog *ServiceCatalog) Get(name string, version string, nodeID st
 	key := createKey(name, version, nodeID)
 	item, exists := serviceCatalog.services.Load(key)
 	if exists {
-		svc := item.(service.Service)
-		return &svc
+		return item.(ServiceEntry).service
 	}
 	return nil
 }
@@ -83,6 +132,8 @@ func (serviceCatalog *ServiceCatalog) listByName() map[string][]ServiceEntry {
 
 // RemoveByNode remove services for the given nodeID.
 func (serviceCatalog *ServiceCatalog) RemoveByNode(nodeID string) []*service.Service {
+	serviceCatalog.mutex.Lock()
+	defer serviceCatalog.mutex.Unlock()
 	var removed []*service.Service
 	serviceCatalog.logger.Debug("RemoveByNode() nodeID: ", nodeID)
 	var keysRemove []string
@@ -103,32 +154,48 @@ func (serviceCatalog *ServiceCatalog) RemoveByNode(nodeID string) []*service.Ser
 		serviceCatalog.services.Delete(key)
 	}
 	for _, name := range namesRemove {
-		value, exists := serviceCatalog.servicesByName.Load(name)
-		if exists {
-			counter := value.(int)
-			counter = counter - 1
-			if counter < 0 {
-				counter = 0
-			}
-			serviceCatalog.servicesByName.Store(name, counter)
-		}
+		serviceCatalog.decrementName(name)
 	}
 	for _, name := range fullNamesRemove {
-		value, exists := serviceCatalog.servicesByName.Load(name)
-		if exists {
-			counter := value.(int)
-			counter = counter - 1
-			if counter < 0 {
-				counter = 0
-			}
-			serviceCatalog.servicesByName.Store(name, counter)
-		}
+		serviceCatalog.decrementName(name)
 	}
 	return removed
 }
 
+// Remove remove the service with the given name, version and nodeID.
+// It returns the removed service or nil when it is not in the catalog.
+func (serviceCatalog *ServiceCatalog) Remove(name string, version string, nodeID string) *service.Service {
+	serviceCatalog.mutex.Lock()
+	defer serviceCatalog.mutex.Unlock()
+	key := createKey(name, version, nodeID)
+	item, exists := serviceCatalog.services.Load(key)
+	if !exists {
+		return nil
+	}
+	serviceCatalog.services.Delete(key)
+	removed := item.(ServiceEntry).service
+	serviceCatalog.decrementName(removed.Name())
+	serviceCatalog.decrementName(removed.FullName())
+	return removed
+}
+
+// decrementName decrement the number of services with the name.
+func (serviceCatalog *ServiceCatalog) decrementName(name string) {
+	value, exists := serviceCatalog.servicesByName.Load(name)
+	if exists {
+		counter := value.(int)
+		counter = counter - 1
+		if counter < 0 {
+			counter = 0
+		}
+		serviceCatalog.servicesByName.Store(name, counter)
+	}
+}
+
 // Add : add a service to the catalog.
 func (serviceCatalog *ServiceCatalog) Add(service *service.Service) {
+	serviceCatalog.mutex.Lock()
+	defer serviceCatalog.mutex.Unlock()
 	nodeID := service.NodeID()
 	key := createKey(service.Name(), service.Version(), nodeID)
 	serviceCatalog.services.Store(key, ServiceEntry{service, nodeID})
@@ -194,10 +261,14 @@ func (serviceCatalog *ServiceCatalog) updateActions(serviceMap map[string]interf
 	var updatedActions []map[string]interface{}
 	var newActions, deletedActions []service.Action
 
+	// moleculer-js nodes key the actions by the full name and go nodes by the raw name,
+	// so the actions are compared by the full name in the values.
 	actions := serviceMap["actions"].(map[string]interface{})
+	received := map[string]bool{}
 	for _, item := range actions {
 		action := item.(map[string]interface{})
 		name := action["name"].(string)
+		received[name] = true
 		if serviceActionExists(name, current.Actions()) {
 			updatedActions = append(updatedActions, action)
 		} else {
@@ -206,11 +277,9 @@ func (serviceCatalog *ServiceCatalog) updateActions(serviceMap map[string]interf
 		}
 	}
 	for _, action := range current.Actions() {
-		name := action.Name()
-		_, exists := actions[name]
-		if !exists {
+		if !received[action.FullName()] {
 			deletedActions = append(deletedActions, action)
-			current.RemoveAction(name)
+			current.RemoveAction(action.FullName())
 		}
 	}
 	return updatedActions, newActions, deletedActions
@@ -1,8 +1,13 @@
 package registry_test
 
 import (
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/registry"
+	"github.com/moleculer-go/moleculer/service"
+	"github.com/moleculer-go/moleculer/test"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
 )
 
 var _ = Describe("Service Catalog", func() {
@@ -17,4 +22,54 @@ var _ = Describe("Service Catalog", func() {
 
 	})
 
+	It("Should count the nodes running a service", func() {
+		services := registry.CreateServiceCatalog(log.New().WithField("catalog", "services"))
+		add := func(name, version, nodeID string) {
+			svc := service.FromSchema(moleculer.ServiceSchema{Name: name, Version: version}, test.DelegatesWithId(nodeID))
+			svc.SetNodeID(nodeID)
+			services.Add(svc)
+		}
+		add("orders", "v2", "node-1")
+		add("orders", "v2", "node-2")
+		add("orders", "v3", "node-3")
+		add("users", "", "node-1")
+
+		Expect(services.CountNodes("orders", "", "")).Should(Equal(3))
+		Expect(services.CountNodes("orders", "v2", "")).Should(Equal(2))
+		Expect(services.CountNodes("v2.orders", "", "")).Should(Equal(2))
+		Expect(services.CountNodes("orders", "v2", "node-1")).Should(Equal(1))
+		Expect(services.CountNodes("orders", "v4", "")).Should(Equal(0))
+		Expect(services.CountNodes("users", "", "node-1")).Should(Equal(0))
+	})
+
+	It("Should remove a service and only its events", func() {
+		handler := func(ctx moleculer.Context, params moleculer.Payload) {}
+		services := registry.CreateServiceCatalog(log.New().WithField("catalog", "services"))
+		events := registry.CreateEventCatalog(log.New().WithField("catalog", "events"))
+		create := func(name string) *service.Service {
+			svc := service.FromSchema(moleculer.ServiceSchema{
+				Name:   name,
+				Events: []moleculer.Event{moleculer.Event{Name: "user.created", Handler: handler}},
+			}, test.DelegatesWithId("node-1"))
+			svc.SetNodeID("node-1")
+			services.Add(svc)
+			events.Add(svc.Events()[0], svc, true)
+			return svc
+		}
+		users := create("users")
+		emails := create("emails")
+		Expect(services.Contains(users)).Should(BeTrue())
+
+		Expect(services.Remove("users", "", "node-1")).Should(Equal(users))
+		events.RemoveByService(users)
+		Expect(services.Remove("users", "", "node-1")).Should(BeNil())
+		Expect(services.Contains(users)).Should(BeFalse())
+		Expect(services.FindByName("users")).Should(BeFalse())
+		Expect(services.Contains(emails)).Should(BeTrue())
+
+		Expect(events.Find("user.created", []string{}, false, false, nil)).Should(HaveLen(1))
+		Expect(events.Find("user.created", []string{"emails"}, false, false, nil)).Should(HaveLen(1))
+		Expect(events.Find("user.created", []string{"users"}, false, false, nil)).Should(BeEmpty())
+	})
+
 })
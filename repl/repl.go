@@ -0,0 +1,370 @@
+// Package repl is an interactive console to inspect and debug a cluster during development,
+// the Go equivalent of moleculer-repl. Commands are read line by line, e.g.:
+//
+//	call math.add --a 5 --b 3
+//	call math.add {"a": 5, "b": 3}
+//	dcall node-1 math.add --a 5 --b 3
+//	emit user.created --id 10
+//	bench --num 1000 math.add --a 5 --b 3
+//	nodes | services | actions | events | info
+package repl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// Broker is the broker API used by the repl commands.
+type Broker interface {
+	Call(actionName string, params interface{}, opts ...moleculer.Options) chan moleculer.Payload
+	Emit(event string, params interface{}, groups ...string) error
+	Broadcast(event string, params interface{}, groups ...string) error
+	HealthData() moleculer.Payload
+}
+
+// Prompt is printed before reading each command.
+const Prompt = "mol $ "
+
+type command struct {
+	usage       string
+	description string
+	run         func(args string) error
+}
+
+// Repl read commands from the input and write the results to the output.
+type Repl struct {
+	broker   Broker
+	in       io.Reader
+	out      io.Writer
+	commands map[string]command
+}
+
+// errExit is returned by the exit command to stop the loop.
+var errExit = errors.New("exit")
+
+// New create a repl for the broker.
+func New(broker Broker, in io.Reader, out io.Writer) *Repl {
+	repl := &Repl{broker: broker, in: in, out: out}
+	repl.commands = map[string]command{
+		"call":      {"call <action> [params]", "Call an action.", repl.call},
+		"dcall":     {"dcall <nodeID> <action> [params]", "Call an action in the given node.", repl.directCall},
+		"emit":      {"emit <event> [params]", "Emit an event.", repl.emit},
+		"broadcast": {"broadcast <event> [params]", "Broadcast an event to all nodes.", repl.broadcast},
+		"bench":     {"bench [--num <count>] [--time <duration>] <action> [params]", "Benchmark an action (default --time 5s).", repl.bench},
+		"nodes":     {"nodes", "List the nodes.", repl.nodes},
+		"services":  {"services", "List the services.", repl.services},
+		"actions":   {"actions", "List the actions.", repl.actions},
+		"events":    {"events", "List the event subscriptions.", repl.events},
+		"info":      {"info", "Show the health of the local node.", repl.info},
+		"help":      {"help", "Show the commands.", repl.help},
+		"exit":      {"exit", "Exit the repl.", func(string) error { return errExit }},
+	}
+	repl.commands["quit"] = repl.commands["exit"]
+	return repl
+}
+
+// Run read and execute the commands until the input ends or the exit command.
+func (repl *Repl) Run() error {
+	scanner := bufio.NewScanner(repl.in)
+	fmt.Fprint(repl.out, Prompt)
+	for scanner.Scan() {
+		err := repl.Execute(scanner.Text())
+		if err == errExit {
+			return nil
+		}
+		if err != nil {
+			fmt.Fprintln(repl.out, "Error:", err)
+		}
+		fmt.Fprint(repl.out, Prompt)
+	}
+	return scanner.Err()
+}
+
+// Execute run a single command line. Empty lines are ignored.
+func (repl *Repl) Execute(line string) error {
+	name, args := splitFirst(line)
+	if name == "" {
+		return nil
+	}
+	cmd, exists := repl.commands[name]
+	if !exists {
+		return fmt.Errorf("unknown command: %s - type help to list the commands", name)
+	}
+	return cmd.run(args)
+}
+
+func (repl *Repl) help(string) error {
+	names := make([]string, 0, len(repl.commands))
+	for name := range repl.commands {
+		if name != "quit" {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	table := repl.table()
+	for _, name := range names {
+		fmt.Fprintf(table, "  %s\t%s\n", repl.commands[name].usage, repl.commands[name].description)
+	}
+	return table.Flush()
+}
+
+func (repl *Repl) call(args string) error {
+	action, rest := splitFirst(args)
+	return repl.callAction(action, rest, moleculer.Options{})
+}
+
+func (repl *Repl) directCall(args string) error {
+	nodeID, rest := splitFirst(args)
+	action, rest := splitFirst(rest)
+	if nodeID == "" {
+		return errors.New("missing node ID")
+	}
+	return repl.callAction(action, rest, moleculer.Options{NodeID: nodeID})
+}
+
+func (repl *Repl) callAction(action, args string, opts moleculer.Options) error {
+	if action == "" {
+		return errors.New("missing action name")
+	}
+	params, err := parseParams(args)
+	if err != nil {
+		return err
+	}
+	start := time.Now()
+	result := <-repl.broker.Call(action, params, opts)
+	duration := time.Since(start)
+	if result.IsError() {
+		return result.Error()
+	}
+	fmt.Fprintf(repl.out, ">> Response (%s):\n", duration)
+	return repl.printJSON(result)
+}
+
+func (repl *Repl) emit(args string) error {
+	event, rest := splitFirst(args)
+	return repl.sendEvent(event, rest, repl.broker.Emit)
+}
+
+func (repl *Repl) broadcast(args string) error {
+	event, rest := splitFirst(args)
+	return repl.sendEvent(event, rest, repl.broker.Broadcast)
+}
+
+func (repl *Repl) sendEvent(event, args string, send func(string, interface{}, ...string) error) error {
+	if event == "" {
+		return errors.New("missing event name")
+	}
+	params, err := parseParams(args)
+	if err != nil {
+		return err
+	}
+	if err := send(event, params); err != nil {
+		return err
+	}
+	fmt.Fprintf(repl.out, ">> Event '%s' sent.\n", event)
+	return nil
+}
+
+// bench call the action sequentially for a number of times or a duration and print the throughput.
+func (repl *Repl) bench(args string) error {
+	num := 0
+	duration := 5 * time.Second
+	for strings.HasPrefix(args, "--") {
+		var option, value string
+		option, args = splitFirst(args)
+		value, args = splitFirst(args)
+		switch option {
+		case "--num":
+			parsed, err := strconv.Atoi(value)
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid --num: %s", value)
+			}
+			num = parsed
+		case "--time":
+			parsed, err := time.ParseDuration(value)
+			if err != nil || parsed <= 0 {
+				return fmt.Errorf("invalid --time: %s", value)
+			}
+			duration = parsed
+		default:
+			return fmt.Errorf("unknown option: %s", option)
+		}
+	}
+	action, rest := splitFirst(args)
+	if action == "" {
+		return errors.New("missing action name")
+	}
+	params, err := parseParams(rest)
+	if err != nil {
+		return err
+	}
+	count, failed := 0, 0
+	start := time.Now()
+	for (num > 0 && count < num) || (num == 0 && time.Since(start) < duration) {
+		result := <-repl.broker.Call(action, params)
+		count++
+		if result.IsError() {
+			failed++
+		}
+	}
+	elapsed := time.Since(start)
+	fmt.Fprintf(repl.out, ">> Benchmark '%s': %d requests in %s, %d errors, %.2f req/s, average %s\n",
+		action, count, elapsed, failed, float64(count)/elapsed.Seconds(), elapsed/time.Duration(count))
+	return nil
+}
+
+func (repl *Repl) nodes(string) error {
+	result := <-repl.broker.Call("$node.list", nil)
+	if result.IsError() {
+		return result.Error()
+	}
+	table := repl.table()
+	fmt.Fprintln(table, "Node ID\tType\tVersion\tIP\tState\tCPU")
+	for _, node := range sortedBy(result.Array(), "id") {
+		id := node.Get("id").String()
+		if node.Get("local").Bool() {
+			id = id + " (*)"
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%s%%\n", id, node.Get("client").Get("type").String(),
+			node.Get("client").Get("version").String(), strings.Join(node.Get("ipList").StringArray(), ", "),
+			state(node.Get("available").Bool()), node.Get("cpu").String())
+	}
+	return table.Flush()
+}
+
+func (repl *Repl) services(string) error {
+	result := <-repl.broker.Call("$node.services", map[string]interface{}{
+		"withActions":   true,
+		"withEvents":    true,
+		"withEndpoints": true,
+	})
+	if result.IsError() {
+		return result.Error()
+	}
+	table := repl.table()
+	fmt.Fprintln(table, "Service\tVersion\tState\tActions\tEvents\tNodes")
+	for _, svc := range sortedBy(result.Array(), "name") {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\t%d\t%d\n", svc.Get("name").String(), svc.Get("version").String(),
+			state(svc.Get("available").Bool()), svc.Get("actions").Len(), svc.Get("events").Len(), svc.Get("endpoints").Len())
+	}
+	return table.Flush()
+}
+
+func (repl *Repl) actions(string) error {
+	result := <-repl.broker.Call("$node.actions", map[string]interface{}{"withEndpoints": true})
+	if result.IsError() {
+		return result.Error()
+	}
+	table := repl.table()
+	fmt.Fprintln(table, "Action\tState\tNodes")
+	for _, action := range sortedBy(result.Array(), "name") {
+		fmt.Fprintf(table, "%s\t%s\t%d\n", action.Get("name").String(), state(action.Get("available").Bool()), action.Get("count").Int())
+	}
+	return table.Flush()
+}
+
+func (repl *Repl) events(string) error {
+	result := <-repl.broker.Call("$node.events", map[string]interface{}{"withEndpoints": true})
+	if result.IsError() {
+		return result.Error()
+	}
+	table := repl.table()
+	fmt.Fprintln(table, "Event\tGroup\tState\tNodes")
+	for _, event := range sortedBy(result.Array(), "name") {
+		fmt.Fprintf(table, "%s\t%s\t%s\t%d\n", event.Get("name").String(), event.Get("group").String(),
+			state(event.Get("available").Bool()), event.Get("count").Int())
+	}
+	return table.Flush()
+}
+
+func (repl *Repl) info(string) error {
+	return repl.printJSON(repl.broker.HealthData())
+}
+
+func (repl *Repl) printJSON(value moleculer.Payload) error {
+	bytes, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(repl.out, string(bytes))
+	return nil
+}
+
+func (repl *Repl) table() *tabwriter.Writer {
+	return tabwriter.NewWriter(repl.out, 0, 0, 2, ' ', 0)
+}
+
+func state(available bool) string {
+	if available {
+		return "OK"
+	}
+	return "FAILED"
+}
+
+func sortedBy(list []moleculer.Payload, field string) []moleculer.Payload {
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Get(field).String() < list[j].Get(field).String()
+	})
+	return list
+}
+
+// splitFirst split the first word of the line from the rest.
+func splitFirst(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	index := strings.IndexAny(line, " \t")
+	if index < 0 {
+		return line, ""
+	}
+	return line[:index], strings.TrimSpace(line[index+1:])
+}
+
+// parseParams parse the params of a command, either JSON ({"a": 5}) or
+// options (--a 5 --b text --flag). Option values that are numbers or booleans are converted.
+func parseParams(source string) (interface{}, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(source, "{") || strings.HasPrefix(source, "[") {
+		var value interface{}
+		if err := json.Unmarshal([]byte(source), &value); err != nil {
+			return nil, fmt.Errorf("invalid JSON params: %s", err)
+		}
+		return value, nil
+	}
+	params := map[string]interface{}{}
+	fields := strings.Fields(source)
+	for index := 0; index < len(fields); index++ {
+		if !strings.HasPrefix(fields[index], "--") {
+			return nil, fmt.Errorf("invalid param: %s", fields[index])
+		}
+		key := strings.TrimPrefix(fields[index], "--")
+		if index+1 < len(fields) && !strings.HasPrefix(fields[index+1], "--") {
+			params[key] = parseValue(fields[index+1])
+			index++
+		} else {
+			params[key] = true
+		}
+	}
+	return params, nil
+}
+
+func parseValue(value string) interface{} {
+	if number, err := strconv.ParseFloat(value, 64); err == nil {
+		return number
+	}
+	if boolean, err := strconv.ParseBool(value); err == nil {
+		return boolean
+	}
+	return value
+}
@@ -0,0 +1,128 @@
+package repl_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/broker"
+	"github.com/moleculer-go/moleculer/repl"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Repl", func() {
+	var bkr *broker.ServiceBroker
+	var received []moleculer.Payload
+	var mutex sync.Mutex
+
+	BeforeEach(func() {
+		received = nil
+		bkr = broker.New(&moleculer.Config{
+			LogLevel:       "fatal",
+			DiscoverNodeID: func() string { return "repl-node" },
+		})
+		bkr.Publish(moleculer.ServiceSchema{
+			Name: "math",
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "add",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return params.Get("a").Int() + params.Get("b").Int()
+					},
+				},
+			},
+			Events: []moleculer.Event{
+				moleculer.Event{
+					Name: "math.done",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) {
+						mutex.Lock()
+						received = append(received, params)
+						mutex.Unlock()
+					},
+				},
+			},
+		})
+		bkr.Start()
+	})
+
+	AfterEach(func() {
+		bkr.Stop()
+	})
+
+	execute := func(line string) (string, error) {
+		out := &bytes.Buffer{}
+		err := repl.New(bkr, strings.NewReader(""), out).Execute(line)
+		return out.String(), err
+	}
+
+	It("should call actions with JSON or option params", func() {
+		out, err := execute(`call math.add {"a": 5, "b": 3}`)
+		Expect(err).Should(BeNil())
+		Expect(out).Should(ContainSubstring("8"))
+
+		out, err = execute("call math.add --a 10 --b 3")
+		Expect(err).Should(BeNil())
+		Expect(out).Should(ContainSubstring("13"))
+
+		out, err = execute("dcall repl-node math.add --a 1 --b 1")
+		Expect(err).Should(BeNil())
+		Expect(out).Should(ContainSubstring("2"))
+
+		_, err = execute("call math.missing")
+		Expect(err).Should(HaveOccurred())
+		_, err = execute("call math.add {invalid")
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should emit events", func() {
+		out, err := execute("emit math.done --result 8")
+		Expect(err).Should(BeNil())
+		Expect(out).Should(ContainSubstring("math.done"))
+		Eventually(func() int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return len(received)
+		}).Should(Equal(1))
+		Expect(received[0].Get("result").Int()).Should(Equal(8))
+	})
+
+	It("should list the nodes, services, actions and events", func() {
+		out, err := execute("nodes")
+		Expect(err).Should(BeNil())
+		Expect(out).Should(ContainSubstring("repl-node (*)"))
+
+		out, err = execute("services")
+		Expect(err).Should(BeNil())
+		Expect(out).Should(ContainSubstring("math"))
+
+		out, err = execute("actions")
+		Expect(err).Should(BeNil())
+		Expect(out).Should(ContainSubstring("math.add"))
+
+		out, err = execute("events")
+		Expect(err).Should(BeNil())
+		Expect(out).Should(ContainSubstring("math.done"))
+
+		out, err = execute("info")
+		Expect(err).Should(BeNil())
+		Expect(out).Should(ContainSubstring(`"nodeID": "repl-node"`))
+	})
+
+	It("should benchmark an action", func() {
+		out, err := execute("bench --num 50 math.add --a 1 --b 2")
+		Expect(err).Should(BeNil())
+		Expect(out).Should(ContainSubstring("50 requests"))
+		Expect(out).Should(ContainSubstring("0 errors"))
+	})
+
+	It("should run the commands until exit", func() {
+		out := &bytes.Buffer{}
+		in := strings.NewReader("help\nunknown\ncall math.add --a 2 --b 2\nexit\ncall math.add --a 3 --b 3\n")
+		Expect(repl.New(bkr, in, out).Run()).Should(Succeed())
+		Expect(out.String()).Should(ContainSubstring("bench [--num <count>]"))
+		Expect(out.String()).Should(ContainSubstring("Error: unknown command: unknown"))
+		Expect(strings.Count(out.String(), ">> Response")).Should(Equal(1))
+	})
+})
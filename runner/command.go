@@ -0,0 +1,35 @@
+package runner
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// Command return the cobra command of the runner, so applications can build their own runner
+// binary with the services registered by their packages:
+//
+//	func main() {
+//		if err := runner.Command().Execute(); err != nil {
+//			os.Exit(1)
+//		}
+//	}
+//
+// Arguments are plugin files or directories and the flags are --config, --plugins and --repl.
+func Command() *cobra.Command {
+	opts := Options{}
+	command := &cobra.Command{
+		Use:   "moleculer [plugins...]",
+		Short: "Moleculer Go runner",
+		Long: `Starts a service broker with the services registered by the application and the ones
+loaded from Go plugins. The config is read from the config file and the MOL_ environment variables.
+The broker is stopped on SIGINT and SIGTERM.`,
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			opts.Plugins = append(opts.Plugins, args...)
+			return Run(opts)
+		},
+	}
+	command.Flags().StringVarP(&opts.ConfigFile, "config", "c", "", "config file (default is moleculer.config.[yaml|yml|json|toml] in the working directory)")
+	command.Flags().StringSliceVarP(&opts.Plugins, "plugins", "p", nil, "plugin files or directories with *.so plugins")
+	command.Flags().BoolVarP(&opts.Repl, "repl", "r", false, "start the interactive console")
+	return command
+}
@@ -0,0 +1,174 @@
+// Package runner starts a service broker with the services registered by the application
+// packages or loaded from Go plugins, the Go equivalent of moleculer-runner.
+//
+// Service packages register their services in init:
+//
+//	func init() {
+//		runner.Register(&UsersService{})
+//	}
+//
+// and the application main only imports them and calls runner.Command().Execute().
+// Plugins (.so files built with go build -buildmode=plugin) export a Services symbol,
+// either a func() []interface{} or a []interface{} variable.
+package runner
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"plugin"
+	"sync"
+	"syscall"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/broker"
+)
+
+// Options of the runner.
+type Options struct {
+	// ConfigFile is the broker config file (.yaml, .yml, .json or .toml). When empty the
+	// first moleculer.config file found in the working directory is used, if any.
+	ConfigFile string
+	// Plugins are the plugin files or directories with plugin files (*.so) to load services from.
+	Plugins []string
+	// Config takes precedence over the config file and the environment variables.
+	Config *moleculer.Config
+	// Repl start the interactive console after the broker is started.
+	Repl bool
+}
+
+// PluginSymbol is the symbol looked up in the plugins.
+const PluginSymbol = "Services"
+
+// configFiles are the config files looked up in the working directory.
+var configFiles = []string{"moleculer.config.yaml", "moleculer.config.yml", "moleculer.config.json", "moleculer.config.toml"}
+
+var registered []interface{}
+var registeredMutex sync.Mutex
+
+// Register add services to be published by the runner. The services can be schemas or objects,
+// like the ones accepted by broker.Publish.
+func Register(services ...interface{}) {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+	registered = append(registered, services...)
+}
+
+// Registered return the services added with Register.
+func Registered() []interface{} {
+	registeredMutex.Lock()
+	defer registeredMutex.Unlock()
+	return append([]interface{}{}, registered...)
+}
+
+// LoadPlugins load the services exported by the plugins. Paths can be plugin files or
+// directories, in which case all *.so files of the directory are loaded.
+func LoadPlugins(paths ...string) ([]interface{}, error) {
+	var services []interface{}
+	for _, path := range paths {
+		files, err := pluginFiles(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			loaded, err := loadPlugin(file)
+			if err != nil {
+				return nil, err
+			}
+			services = append(services, loaded...)
+		}
+	}
+	return services, nil
+}
+
+func pluginFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	return filepath.Glob(filepath.Join(path, "*.so"))
+}
+
+func loadPlugin(file string) ([]interface{}, error) {
+	plug, err := plugin.Open(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not open plugin %s - error: %s", file, err)
+	}
+	symbol, err := plug.Lookup(PluginSymbol)
+	if err != nil {
+		return nil, fmt.Errorf("plugin %s does not export %s", file, PluginSymbol)
+	}
+	switch services := symbol.(type) {
+	case func() []interface{}:
+		return services(), nil
+	case *[]interface{}:
+		return *services, nil
+	}
+	return nil, fmt.Errorf("plugin %s exports %s with the invalid type %T, expected func() []interface{} or []interface{}", file, PluginSymbol, symbol)
+}
+
+// loadConfig load the config file and environment variables.
+func loadConfig(opts Options) (*moleculer.Config, error) {
+	path := opts.ConfigFile
+	if path == "" {
+		for _, name := range configFiles {
+			if _, err := os.Stat(name); err == nil {
+				path = name
+				break
+			}
+		}
+	}
+	return broker.LoadConfig(path)
+}
+
+// Start create and start a broker with the registered and plugin services.
+func Start(opts Options) (*broker.ServiceBroker, error) {
+	config, err := loadConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+	services, err := LoadPlugins(opts.Plugins...)
+	if err != nil {
+		return nil, err
+	}
+	services = append(Registered(), services...)
+	if len(services) == 0 {
+		return nil, errors.New("no services to start - register services with runner.Register or load them from plugins")
+	}
+	configs := []*moleculer.Config{config}
+	if opts.Config != nil {
+		configs = append(configs, opts.Config)
+	}
+	bkr := broker.New(configs...)
+	bkr.Publish(services...)
+	if err := bkr.Start(); err != nil {
+		return nil, err
+	}
+	return bkr, nil
+}
+
+// Run start the broker and stop it when the process receives SIGINT or SIGTERM,
+// or when the repl is closed.
+func Run(opts Options) error {
+	bkr, err := Start(opts)
+	if err != nil {
+		return err
+	}
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	if opts.Repl {
+		go func() {
+			bkr.StartRepl()
+			stop <- os.Interrupt
+		}()
+	}
+	<-stop
+	bkr.Stop()
+	return nil
+}
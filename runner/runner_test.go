@@ -0,0 +1,81 @@
+package runner_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/runner"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Runner", func() {
+	var dir string
+
+	BeforeEach(func() {
+		var err error
+		dir, err = ioutil.TempDir("", "moleculer-runner")
+		Expect(err).Should(BeNil())
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("should fail to load missing or invalid plugins", func() {
+		_, err := runner.LoadPlugins(filepath.Join(dir, "missing.so"))
+		Expect(err).Should(HaveOccurred())
+
+		invalid := filepath.Join(dir, "invalid.so")
+		Expect(ioutil.WriteFile(invalid, []byte("not a plugin"), 0644)).Should(Succeed())
+		_, err = runner.LoadPlugins(dir)
+		Expect(err).Should(HaveOccurred())
+	})
+
+	It("should load no services from a directory without plugins", func() {
+		services, err := runner.LoadPlugins(dir)
+		Expect(err).Should(BeNil())
+		Expect(services).Should(BeEmpty())
+	})
+
+	It("should start a broker with the config file and the registered services", func() {
+		config := filepath.Join(dir, "moleculer.config.yaml")
+		Expect(ioutil.WriteFile(config, []byte("nodeID: runner-node\nlogLevel: fatal\nservices:\n  greeter:\n    settings:\n      greeting: Hello\n"), 0644)).Should(Succeed())
+
+		greeting := ""
+		runner.Register(moleculer.ServiceSchema{
+			Name: "greeter",
+			Started: func(ctx moleculer.BrokerContext, svc moleculer.ServiceSchema) {
+				greeting = svc.Settings["greeting"].(string)
+			},
+			Actions: []moleculer.Action{
+				moleculer.Action{
+					Name: "hello",
+					Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+						return greeting + " " + params.String()
+					},
+				},
+			},
+		})
+		Expect(runner.Registered()).Should(HaveLen(1))
+
+		bkr, err := runner.Start(runner.Options{
+			ConfigFile: config,
+			Config:     &moleculer.Config{Namespace: "runner"},
+		})
+		Expect(err).Should(BeNil())
+		defer bkr.Stop()
+
+		Expect(bkr.LocalNode().GetID()).Should(Equal("runner-node"))
+		result := <-bkr.Call("greeter.hello", "John")
+		Expect(result.Error()).Should(BeNil())
+		Expect(result.String()).Should(Equal("Hello John"))
+	})
+
+	It("should fail to start with a missing config file", func() {
+		_, err := runner.Start(runner.Options{ConfigFile: filepath.Join(dir, "missing.yaml")})
+		Expect(err).Should(HaveOccurred())
+	})
+})
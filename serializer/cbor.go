@@ -0,0 +1,517 @@
+package serializer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/payload"
+	log "github.com/sirupsen/logrus"
+)
+
+// CBORSerializer serializes the packets with CBOR (RFC 7049). The []byte values are sent as
+// byte strings, without the base64 inflation of JSON, and are received as []byte, which is useful
+// for image and file payloads. The other values are received with the same types as the JSON
+// serializer: numbers as float64, lists as []interface{} and objects as map[string]interface{}.
+type CBORSerializer struct {
+	logger *log.Entry
+}
+
+func CreateCBORSerializer(logger *log.Entry) CBORSerializer {
+	return CBORSerializer{logger}
+}
+
+const (
+	cborUnsigned = 0
+	cborNegative = 1
+	cborBytes    = 2
+	cborString   = 3
+	cborArray    = 4
+	cborMap      = 5
+	cborTag      = 6
+	cborSimple   = 7
+
+	cborFalse      = 0xf4
+	cborTrue       = 0xf5
+	cborNull       = 0xf6
+	cborFloat64    = 0xfb
+	cborBreak      = 0xff
+	cborIndefinite = 31
+)
+
+func (serializer CBORSerializer) BytesToPayload(bytes *[]byte) moleculer.Payload {
+	value, err := decodeCBOR(*bytes)
+	if err != nil {
+		serializer.logger.Error("BytesToPayload() Error when decoding CBOR - error: ", err)
+		return payload.Error("Error decoding CBOR. error: ", err.Error())
+	}
+	return payload.New(value)
+}
+
+func (serializer CBORSerializer) PayloadToBytes(message moleculer.Payload) []byte {
+	data, err := encodeCBOR(message)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// PayloadToContextMap make sure all value types are compatible with the context fields.
+func (serializer CBORSerializer) PayloadToContextMap(message moleculer.Payload) map[string]interface{} {
+	values := message.RawMap()
+	if level, isNumber := values["level"].(float64); isNumber {
+		values["level"] = int(level)
+	}
+	if timeout, isNumber := values["timeout"].(float64); isNumber {
+		values["timeout"] = int(timeout)
+	}
+	return values
+}
+
+func (serializer CBORSerializer) MapToPayload(mapValue *map[string]interface{}) (moleculer.Payload, error) {
+	value, err := cborValue(*mapValue)
+	if err != nil {
+		serializer.logger.Error("MapToPayload() Error when converting the map: ", *mapValue, " Error: ", err)
+		return nil, err
+	}
+	return payload.New(value), nil
+}
+
+// cborValue convert the value to the types the encoder writes: nil, bool, numbers, string, []byte,
+// []interface{} and map[string]interface{}. Functions and channels in maps are removed and the
+// other types (e.g. times and structs) are converted the same way as the JSON serializer does.
+func cborValue(value interface{}) (interface{}, error) {
+	if converted, isConverted := serializableValue(value); isConverted {
+		return converted, nil
+	}
+	switch source := value.(type) {
+	case nil, bool, string, []byte, float32, float64,
+		int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return source, nil
+	case moleculer.Payload:
+		if source.IsError() {
+			return map[string]interface{}{"error": source.Error().Error()}, nil
+		}
+		return cborValue(source.Value())
+	case error:
+		return source.Error(), nil
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(source))
+		for key, item := range source {
+			if !serializable(item) {
+				continue
+			}
+			converted, err := cborValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = converted
+		}
+		return result, nil
+	case []interface{}:
+		result := make([]interface{}, len(source))
+		for index, item := range source {
+			converted, err := cborValue(item)
+			if err != nil {
+				return nil, err
+			}
+			result[index] = converted
+		}
+		return result, nil
+	}
+	kind := reflect.TypeOf(value).Kind()
+	if kind == reflect.Map {
+		if transformer := payload.MapTransformer(&value); transformer != nil {
+			return cborValue(transformer.AsMap(&value))
+		}
+	}
+	if kind == reflect.Slice || kind == reflect.Array {
+		if transformer := payload.ArrayTransformer(&value); transformer != nil {
+			return cborValue(transformer.InterfaceArray(&value))
+		}
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var result interface{}
+	if err := json.Unmarshal(encoded, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func serializable(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	kind := reflect.TypeOf(value).Kind()
+	return kind != reflect.Func && kind != reflect.Chan
+}
+
+// encodeCBOR encode the value of the payload, or a map with the error message when the payload is an error.
+func encodeCBOR(message moleculer.Payload) ([]byte, error) {
+	value, err := cborValue(message)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	if err := writeCBOR(&buffer, value); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func writeCBOR(buffer *bytes.Buffer, value interface{}) error {
+	switch source := value.(type) {
+	case nil:
+		buffer.WriteByte(cborNull)
+	case bool:
+		if source {
+			buffer.WriteByte(cborTrue)
+		} else {
+			buffer.WriteByte(cborFalse)
+		}
+	case string:
+		writeCBORHead(buffer, cborString, uint64(len(source)))
+		buffer.WriteString(source)
+	case []byte:
+		writeCBORHead(buffer, cborBytes, uint64(len(source)))
+		buffer.Write(source)
+	case float32:
+		writeCBORFloat(buffer, float64(source))
+	case float64:
+		writeCBORFloat(buffer, source)
+	case int:
+		writeCBORInt(buffer, int64(source))
+	case int8:
+		writeCBORInt(buffer, int64(source))
+	case int16:
+		writeCBORInt(buffer, int64(source))
+	case int32:
+		writeCBORInt(buffer, int64(source))
+	case int64:
+		writeCBORInt(buffer, source)
+	case uint:
+		writeCBORHead(buffer, cborUnsigned, uint64(source))
+	case uint8:
+		writeCBORHead(buffer, cborUnsigned, uint64(source))
+	case uint16:
+		writeCBORHead(buffer, cborUnsigned, uint64(source))
+	case uint32:
+		writeCBORHead(buffer, cborUnsigned, uint64(source))
+	case uint64:
+		writeCBORHead(buffer, cborUnsigned, source)
+	case []interface{}:
+		writeCBORHead(buffer, cborArray, uint64(len(source)))
+		for _, item := range source {
+			if err := writeCBOR(buffer, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		// the keys are sorted, so the same map is always encoded to the same bytes.
+		keys := make([]string, 0, len(source))
+		for key := range source {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		writeCBORHead(buffer, cborMap, uint64(len(keys)))
+		for _, key := range keys {
+			writeCBORHead(buffer, cborString, uint64(len(key)))
+			buffer.WriteString(key)
+			if err := writeCBOR(buffer, source[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("CBOR serializer - type %T is not supported", value)
+	}
+	return nil
+}
+
+func writeCBORInt(buffer *bytes.Buffer, value int64) {
+	if value < 0 {
+		writeCBORHead(buffer, cborNegative, uint64(-1-value))
+		return
+	}
+	writeCBORHead(buffer, cborUnsigned, uint64(value))
+}
+
+func writeCBORFloat(buffer *bytes.Buffer, value float64) {
+	buffer.WriteByte(cborFloat64)
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], math.Float64bits(value))
+	buffer.Write(data[:])
+}
+
+// writeCBORHead write the major type and the argument (a length or a number) in the shortest form.
+func writeCBORHead(buffer *bytes.Buffer, major byte, argument uint64) {
+	major = major << 5
+	switch {
+	case argument < 24:
+		buffer.WriteByte(major | byte(argument))
+	case argument <= math.MaxUint8:
+		buffer.WriteByte(major | 24)
+		buffer.WriteByte(byte(argument))
+	case argument <= math.MaxUint16:
+		buffer.WriteByte(major | 25)
+		var data [2]byte
+		binary.BigEndian.PutUint16(data[:], uint16(argument))
+		buffer.Write(data[:])
+	case argument <= math.MaxUint32:
+		buffer.WriteByte(major | 26)
+		var data [4]byte
+		binary.BigEndian.PutUint32(data[:], uint32(argument))
+		buffer.Write(data[:])
+	default:
+		buffer.WriteByte(major | 27)
+		var data [8]byte
+		binary.BigEndian.PutUint64(data[:], argument)
+		buffer.Write(data[:])
+	}
+}
+
+var errCBORTruncated = errors.New("CBOR serializer - unexpected end of data")
+var errCBORBreak = errors.New("CBOR serializer - unexpected break")
+
+// cborDecoder read the values of a CBOR message. Definite and indefinite lengths are supported
+// and the tags are ignored, so the messages of other CBOR encoders can be read.
+type cborDecoder struct {
+	data   []byte
+	offset int
+}
+
+func decodeCBOR(data []byte) (interface{}, error) {
+	decoder := &cborDecoder{data: data}
+	value, err := decoder.value()
+	if err != nil {
+		return nil, err
+	}
+	if decoder.offset != len(data) {
+		return nil, fmt.Errorf("CBOR serializer - %d unexpected bytes after the value", len(data)-decoder.offset)
+	}
+	return value, nil
+}
+
+func (decoder *cborDecoder) next(size int) ([]byte, error) {
+	if size < 0 || len(decoder.data)-decoder.offset < size {
+		return nil, errCBORTruncated
+	}
+	data := decoder.data[decoder.offset : decoder.offset+size]
+	decoder.offset += size
+	return data, nil
+}
+
+// head read the major type, the additional info and the argument of the next item.
+func (decoder *cborDecoder) head() (byte, byte, uint64, error) {
+	initial, err := decoder.next(1)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	major, info := initial[0]>>5, initial[0]&0x1f
+	var argument uint64
+	switch {
+	case info < 24:
+		argument = uint64(info)
+	case info == 24:
+		data, err := decoder.next(1)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		argument = uint64(data[0])
+	case info == 25:
+		data, err := decoder.next(2)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		argument = uint64(binary.BigEndian.Uint16(data))
+	case info == 26:
+		data, err := decoder.next(4)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		argument = uint64(binary.BigEndian.Uint32(data))
+	case info == 27:
+		data, err := decoder.next(8)
+		if err != nil {
+			return 0, 0, 0, err
+		}
+		argument = binary.BigEndian.Uint64(data)
+	case info == cborIndefinite:
+	default:
+		return 0, 0, 0, fmt.Errorf("CBOR serializer - invalid additional info %d", info)
+	}
+	return major, info, argument, nil
+}
+
+func (decoder *cborDecoder) value() (interface{}, error) {
+	major, info, argument, err := decoder.head()
+	if err != nil {
+		return nil, err
+	}
+	indefinite := info == cborIndefinite
+	if indefinite && (major == cborUnsigned || major == cborNegative || major == cborTag) {
+		return nil, fmt.Errorf("CBOR serializer - major type %d can't have an indefinite length", major)
+	}
+	switch major {
+	case cborUnsigned:
+		return float64(argument), nil
+	case cborNegative:
+		return -1 - float64(argument), nil
+	case cborBytes, cborString:
+		data, err := decoder.bytes(major, indefinite, argument)
+		if err != nil {
+			return nil, err
+		}
+		if major == cborString {
+			return string(data), nil
+		}
+		return data, nil
+	case cborArray:
+		return decoder.array(indefinite, argument)
+	case cborMap:
+		return decoder.mapValue(indefinite, argument)
+	case cborTag:
+		return decoder.value()
+	}
+	return decoder.simple(info, argument)
+}
+
+// bytes read a byte string or a text string. The chunks of the indefinite length strings are joined.
+func (decoder *cborDecoder) bytes(major byte, indefinite bool, length uint64) ([]byte, error) {
+	if !indefinite {
+		if length > uint64(len(decoder.data)) {
+			return nil, errCBORTruncated
+		}
+		data, err := decoder.next(int(length))
+		if err != nil {
+			return nil, err
+		}
+		result := make([]byte, len(data))
+		copy(result, data)
+		return result, nil
+	}
+	result := []byte{}
+	for {
+		if decoder.atBreak() {
+			return result, nil
+		}
+		chunkMajor, chunkInfo, chunkLength, err := decoder.head()
+		if err != nil {
+			return nil, err
+		}
+		if chunkMajor != major || chunkInfo == cborIndefinite {
+			return nil, errors.New("CBOR serializer - invalid chunk in an indefinite length string")
+		}
+		chunk, err := decoder.bytes(major, false, chunkLength)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, chunk...)
+	}
+}
+
+func (decoder *cborDecoder) array(indefinite bool, length uint64) ([]interface{}, error) {
+	if !indefinite && length > uint64(len(decoder.data)-decoder.offset) {
+		return nil, errCBORTruncated
+	}
+	result := make([]interface{}, 0, int(length))
+	for index := uint64(0); indefinite || index < length; index++ {
+		if indefinite && decoder.atBreak() {
+			break
+		}
+		item, err := decoder.value()
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+// mapValue read a map. The keys that are not strings are converted to strings.
+func (decoder *cborDecoder) mapValue(indefinite bool, length uint64) (map[string]interface{}, error) {
+	if !indefinite && length > uint64(len(decoder.data)-decoder.offset) {
+		return nil, errCBORTruncated
+	}
+	result := make(map[string]interface{}, int(length))
+	for index := uint64(0); indefinite || index < length; index++ {
+		if indefinite && decoder.atBreak() {
+			break
+		}
+		key, err := decoder.value()
+		if err != nil {
+			return nil, err
+		}
+		item, err := decoder.value()
+		if err != nil {
+			return nil, err
+		}
+		name, isString := key.(string)
+		if !isString {
+			name = fmt.Sprint(key)
+		}
+		result[name] = item
+	}
+	return result, nil
+}
+
+// atBreak check if the next byte is the break of an indefinite length item, and skip it.
+func (decoder *cborDecoder) atBreak() bool {
+	if decoder.offset < len(decoder.data) && decoder.data[decoder.offset] == cborBreak {
+		decoder.offset++
+		return true
+	}
+	return false
+}
+
+// simple read the booleans, null, undefined and the floats.
+func (decoder *cborDecoder) simple(info byte, argument uint64) (interface{}, error) {
+	switch info {
+	case cborFalse & 0x1f:
+		return false, nil
+	case cborTrue & 0x1f:
+		return true, nil
+	case cborNull & 0x1f, 23:
+		return nil, nil
+	case 25:
+		return halfToFloat(uint16(argument)), nil
+	case 26:
+		return float64(math.Float32frombits(uint32(argument))), nil
+	case 27:
+		return math.Float64frombits(argument), nil
+	case cborIndefinite:
+		return nil, errCBORBreak
+	}
+	return nil, fmt.Errorf("CBOR serializer - simple value %d is not supported", argument)
+}
+
+// halfToFloat convert a IEEE 754 half precision float.
+func halfToFloat(half uint16) float64 {
+	exponent := int((half >> 10) & 0x1f)
+	mantissa := float64(half & 0x3ff)
+	var value float64
+	switch exponent {
+	case 0:
+		value = math.Ldexp(mantissa, -24)
+	case 0x1f:
+		if mantissa == 0 {
+			value = math.Inf(1)
+		} else {
+			value = math.NaN()
+		}
+	default:
+		value = math.Ldexp(mantissa+1024, exponent-25)
+	}
+	if half&0x8000 != 0 {
+		return -value
+	}
+	return value
+}
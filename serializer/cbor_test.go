@@ -0,0 +1,131 @@
+package serializer_test
+
+import (
+	"time"
+
+	"github.com/moleculer-go/moleculer/context"
+	"github.com/moleculer-go/moleculer/payload"
+	"github.com/moleculer-go/moleculer/serializer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+// the specs are in a named function, so the snapshot names of the JSON serializer specs do not change.
+var _ = Describe("CBOR Serializer", cborSerializerSpecs)
+
+func cborSerializerSpecs() {
+
+	brokerDelegates := BrokerDelegates("test-node")
+	contextA := context.BrokerContext(brokerDelegates)
+	logger := log.WithField("serializer", "CBOR")
+
+	It("Should keep the binary values without base64", func() {
+		serial := serializer.CreateCBORSerializer(logger)
+		image := make([]byte, 3000)
+		for index := range image {
+			image[index] = byte(index % 256)
+		}
+		message, err := serial.MapToPayload(&map[string]interface{}{
+			"name":    "photo.png",
+			"content": image,
+			"size":    len(image),
+			"ratio":   1.5,
+			"public":  true,
+			"owner":   nil,
+			"tags":    []string{"beach", "sunset"},
+			"exif":    map[string]interface{}{"iso": 100, "offset": -2},
+			"skipped": func() {},
+		})
+		Expect(err).Should(BeNil())
+
+		bytes := serial.PayloadToBytes(message)
+		Expect(len(bytes)).Should(BeNumerically("<", len(image)+200))
+		Expect(len(bytes)).Should(BeNumerically("<", len(serializer.CreateJSONSerializer(logger).PayloadToBytes(message))))
+
+		received := serial.BytesToPayload(&bytes)
+		Expect(received.IsError()).Should(BeFalse())
+		Expect(received.Get("content").Value()).Should(Equal(image))
+		Expect(received.Get("name").String()).Should(Equal("photo.png"))
+		Expect(received.Get("size").Value()).Should(Equal(float64(3000)))
+		Expect(received.Get("ratio").Float()).Should(Equal(1.5))
+		Expect(received.Get("public").Bool()).Should(BeTrue())
+		Expect(received.Get("owner").Exists()).Should(BeFalse())
+		Expect(received.Get("tags").Value()).Should(Equal([]interface{}{"beach", "sunset"}))
+		Expect(received.Get("exif").RawMap()).Should(Equal(map[string]interface{}{"iso": float64(100), "offset": float64(-2)}))
+		Expect(received.RawMap()).ShouldNot(HaveKey("skipped"))
+	})
+
+	It("Should serialize the values, lists and errors that are not maps", func() {
+		serial := serializer.CreateCBORSerializer(logger)
+		roundtrip := func(value interface{}) interface{} {
+			bytes := serial.PayloadToBytes(payload.New(value))
+			return serial.BytesToPayload(&bytes).Value()
+		}
+		Expect(roundtrip("text")).Should(Equal("text"))
+		Expect(roundtrip(-500000)).Should(Equal(float64(-500000)))
+		Expect(roundtrip([]byte{0, 1, 2})).Should(Equal([]byte{0, 1, 2}))
+		Expect(roundtrip([]interface{}{1, "a", []byte{9}})).Should(Equal([]interface{}{float64(1), "a", []byte{9}}))
+		created := time.Date(2020, 5, 17, 10, 30, 0, 0, time.UTC)
+		Expect(roundtrip(map[string]interface{}{"created": created, "timeout": 2 * time.Second})).Should(Equal(map[string]interface{}{
+			"created": payload.FormatTime(created),
+			"timeout": payload.FormatDuration(2 * time.Second),
+		}))
+
+		bytes := serial.PayloadToBytes(payload.Error("something went wrong"))
+		Expect(serial.BytesToPayload(&bytes).Get("error").String()).Should(Equal("something went wrong"))
+	})
+
+	It("Should read the indefinite lengths, tags and floats of other encoders", func() {
+		serial := serializer.CreateCBORSerializer(logger)
+		// {_ "a": [_ 1, 2.5 (half float)], "b": h'0102' in 2 chunks, "c": 1(1363896240) tagged epoch}
+		bytes := []byte{
+			0xbf,
+			0x61, 'a', 0x9f, 0x01, 0xf9, 0x41, 0x00, 0xff,
+			0x61, 'b', 0x5f, 0x41, 0x01, 0x41, 0x02, 0xff,
+			0x61, 'c', 0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0,
+			0xff,
+		}
+		message := serial.BytesToPayload(&bytes)
+		Expect(message.IsError()).Should(BeFalse())
+		Expect(message.Get("a").Value()).Should(Equal([]interface{}{float64(1), 2.5}))
+		Expect(message.Get("b").Value()).Should(Equal([]byte{1, 2}))
+		Expect(message.Get("c").Int64()).Should(Equal(int64(1363896240)))
+	})
+
+	It("Should return an error payload when the data is not valid CBOR", func() {
+		serial := serializer.CreateCBORSerializer(logger)
+		truncated := []byte{0x62, 'a'}
+		Expect(serial.BytesToPayload(&truncated).IsError()).Should(BeTrue())
+		trailing := []byte{0x01, 0x02}
+		Expect(serial.BytesToPayload(&trailing).IsError()).Should(BeTrue())
+	})
+
+	It("Should convert between context and Transit Message", func() {
+		serial := serializer.CreateCBORSerializer(logger)
+
+		actionName := "some.service.action"
+		params := map[string]interface{}{
+			"name": "John",
+			"file": []byte("binary content"),
+		}
+		actionContext := contextA.ChildActionContext(actionName, payload.New(params))
+
+		contextMap := actionContext.AsMap()
+		contextMap["sender"] = "original_sender"
+		message, _ := serial.MapToPayload(&contextMap)
+		bytes := serial.PayloadToBytes(message)
+		message = serial.BytesToPayload(&bytes)
+
+		Expect(message.Get("action").String()).Should(Equal(actionName))
+		Expect(message.Get("params").Get("name").String()).Should(Equal("John"))
+
+		values := serial.PayloadToContextMap(message)
+		contextAgain := context.ActionContext(brokerDelegates, values)
+
+		Expect(contextAgain.TargetNodeID()).Should(Equal("original_sender"))
+		Expect(contextAgain.ActionName()).Should(Equal(actionName))
+		Expect(contextAgain.Payload().Get("name").String()).Should(Equal("John"))
+		Expect(contextAgain.Payload().Get("file").Value()).Should(Equal([]byte("binary content")))
+	})
+}
@@ -0,0 +1,107 @@
+package serializer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/moleculer-go/moleculer"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// SupportedCompressions list the algorithms this node can decompress.
+var SupportedCompressions = []string{CompressionGzip, CompressionZstd}
+
+var gzipMagic = []byte{0x1f, 0x8b}
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+var zstdEncoder, _ = zstd.NewWriter(nil)
+var zstdDecoder, _ = zstd.NewReader(nil)
+
+// CompressionSerializer wraps a serializer and compresses the packets marked as Compressible that are
+// bigger than the threshold. Compressed packets are detected by the algorithm magic number, so
+// uncompressed packets are still accepted.
+type CompressionSerializer struct {
+	Serializer
+	algorithm string
+	threshold int
+	logger    *log.Entry
+}
+
+// CreateCompressionSerializer wraps the serializer.
+func CreateCompressionSerializer(serializer Serializer, algorithm string, threshold int, logger *log.Entry) CompressionSerializer {
+	return CompressionSerializer{serializer, algorithm, threshold, logger}
+}
+
+// compressiblePayload is a payload sent to a node that supports the compression.
+type compressiblePayload struct {
+	moleculer.Payload
+}
+
+// Compressible mark the payload to be compressed, when it is bigger than the threshold.
+// Only the packets sent to nodes that can decompress them must be marked.
+func Compressible(payload moleculer.Payload) moleculer.Payload {
+	return compressiblePayload{payload}
+}
+
+func (serializer CompressionSerializer) PayloadToBytes(payload moleculer.Payload) []byte {
+	compressible, isCompressible := payload.(compressiblePayload)
+	if !isCompressible {
+		return serializer.Serializer.PayloadToBytes(payload)
+	}
+	data := serializer.Serializer.PayloadToBytes(compressible.Payload)
+	if len(data) < serializer.threshold {
+		return data
+	}
+	compressed, err := compress(serializer.algorithm, data)
+	if err != nil {
+		serializer.logger.Error("Could not compress packet - error: ", err)
+		return data
+	}
+	return compressed
+}
+
+func (serializer CompressionSerializer) BytesToPayload(data *[]byte) moleculer.Payload {
+	if bytes.HasPrefix(*data, gzipMagic) || bytes.HasPrefix(*data, zstdMagic) {
+		decompressed, err := decompress(*data)
+		if err != nil {
+			serializer.logger.Error("Could not decompress packet - error: ", err)
+		} else {
+			data = &decompressed
+		}
+	}
+	return serializer.Serializer.BytesToPayload(data)
+}
+
+func compress(algorithm string, data []byte) ([]byte, error) {
+	if algorithm == CompressionZstd {
+		return zstdEncoder.EncodeAll(data, nil), nil
+	}
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func decompress(data []byte) ([]byte, error) {
+	if bytes.HasPrefix(data, zstdMagic) {
+		return zstdDecoder.DecodeAll(data, nil)
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	return ioutil.ReadAll(reader)
+}
@@ -0,0 +1,38 @@
+package serializer_test
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/moleculer-go/moleculer/payload"
+	"github.com/moleculer-go/moleculer/serializer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Compression", func() {
+	json := serializer.CreateJSONSerializer(logger)
+	big := payload.New(map[string]interface{}{"text": strings.Repeat("moleculer ", 200)})
+	small := payload.New(map[string]interface{}{"text": "moleculer"})
+
+	for _, algorithm := range []string{serializer.CompressionGzip, serializer.CompressionZstd} {
+		algorithm := algorithm
+		It("should compress packets above the threshold with "+algorithm, func() {
+			compression := serializer.CreateCompressionSerializer(json, algorithm, 100, logger)
+
+			bts := compression.PayloadToBytes(serializer.Compressible(big))
+			Expect(len(bts)).Should(BeNumerically("<", len(json.PayloadToBytes(big))))
+			Expect(compression.BytesToPayload(&bts).Get("text").String()).Should(Equal(big.Get("text").String()))
+
+			bts = compression.PayloadToBytes(serializer.Compressible(small))
+			Expect(bts).Should(Equal(json.PayloadToBytes(small)))
+			Expect(compression.BytesToPayload(&bts).Get("text").String()).Should(Equal("moleculer"))
+		})
+	}
+
+	It("should not compress the packets not marked as compressible", func() {
+		compression := serializer.CreateCompressionSerializer(json, serializer.CompressionGzip, 100, logger)
+		Expect(bytes.Equal(compression.PayloadToBytes(big), json.PayloadToBytes(big))).Should(BeTrue())
+		Expect(bytes.Equal(compression.PayloadToBytes(serializer.Compressible(big)), json.PayloadToBytes(big))).Should(BeFalse())
+	})
+})
@@ -1,6 +1,8 @@
 package serializer
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"sort"
 	"strconv"
@@ -15,32 +17,41 @@ import (
 )
 
 type JSONSerializer struct {
-	logger *log.Entry
+	logger          *log.Entry
+	preserveNumbers bool
 }
 
 type JSONPayload struct {
-	result gjson.Result
-	logger *log.Entry
+	result          gjson.Result
+	logger          *log.Entry
+	preserveNumbers bool
 }
 
 func CreateJSONSerializer(logger *log.Entry) JSONSerializer {
-	return JSONSerializer{logger}
+	return JSONSerializer{logger: logger}
+}
+
+// PreservingNumbers return a copy of the serializer that decodes the JSON numbers as json.Number,
+// instead of float64, so 64-bit integers (like IDs) keep all their digits.
+func (serializer JSONSerializer) PreservingNumbers() JSONSerializer {
+	serializer.preserveNumbers = true
+	return serializer
 }
 
 // mapToContext make sure all value types are compatible with the context fields.
 func (serializer JSONSerializer) contextMap(values map[string]interface{}) map[string]interface{} {
 	if values["level"] != nil {
-		values["level"] = int(values["level"].(float64))
+		values["level"] = payload.New(values["level"]).Int()
 	}
 	if values["timeout"] != nil {
-		values["timeout"] = int(values["timeout"].(float64))
+		values["timeout"] = payload.New(values["timeout"]).Int()
 	}
 	return values
 }
 
 func (serializer JSONSerializer) BytesToPayload(bytes *[]byte) moleculer.Payload {
 	result := gjson.ParseBytes(*bytes)
-	payload := JSONPayload{result, serializer.logger}
+	payload := JSONPayload{result, serializer.logger, serializer.preserveNumbers}
 	return payload
 }
 
@@ -48,6 +59,9 @@ func (serializer JSONSerializer) PayloadToBytes(payload moleculer.Payload) []byt
 	var err error
 	jp, isJson := payload.(JSONPayload)
 	if !isJson {
+		if bts, isBytes := payload.Value().([]byte); isBytes {
+			return []byte(strconv.Quote(base64.StdEncoding.EncodeToString(bts)))
+		}
 		if payload.IsArray() {
 			jp, err = serializer.arrayToJsonPayload(payload.ValueArray())
 			if err != nil {
@@ -66,17 +80,26 @@ func (serializer JSONSerializer) PayloadToBytes(payload moleculer.Payload) []byt
 			}
 			return []byte(jp.result.String())
 		}
-		json, err := sjson.Set("{root:false}", "root", payload.Value())
+		value, _ := serializableValue(payload.Value())
+		json, err := sjson.Set("{root:false}", "root", value)
 		if err != nil {
 			panic(err)
 		}
-		jp = JSONPayload{gjson.Get(json, "root"), serializer.logger}
+		jp = JSONPayload{gjson.Get(json, "root"), serializer.logger, serializer.preserveNumbers}
 		return []byte(jp.result.String())
 	}
 	return []byte(jp.result.String())
 }
 
 func (jpayload JSONPayload) Remove(fields ...string) moleculer.Payload {
+	if jpayload.IsArray() {
+		arr := jpayload.Array()
+		items := make([]moleculer.Payload, len(arr))
+		for index, item := range arr {
+			items[index] = item.Remove(fields...)
+		}
+		return payload.New(items)
+	}
 	var err error
 	json := jpayload.result.Raw
 	for _, item := range fields {
@@ -85,7 +108,7 @@ func (jpayload JSONPayload) Remove(fields ...string) moleculer.Payload {
 			return payload.Error("Error serializng value into JSON. error: ", err.Error())
 		}
 	}
-	return JSONPayload{gjson.Parse(json), jpayload.logger}
+	return JSONPayload{gjson.Parse(json), jpayload.logger, jpayload.preserveNumbers}
 }
 
 func (jpayload JSONPayload) AddItem(value interface{}) moleculer.Payload {
@@ -97,19 +120,12 @@ func (jpayload JSONPayload) AddItem(value interface{}) moleculer.Payload {
 	return payload.New(arr)
 }
 
+// Add set the field (sjson path) and return a new payload. The source json is not changed.
 func (jpayload JSONPayload) Add(field string, value interface{}) moleculer.Payload {
-	if !jpayload.IsMap() {
-		return payload.Error("payload.Add can only deal with map payloads.")
-	}
-	var err error
-	json := jpayload.result.Raw
-	json, err = sjson.Set(json, field, value)
-	if err != nil {
-		return payload.Error("Error serializng value into JSON. error: ", err.Error())
-	}
-	return JSONPayload{gjson.Parse(json), jpayload.logger}
+	return jpayload.AddMany(map[string]interface{}{field: value})
 }
 
+// AddMany set all fields (sjson paths) and return a new payload. The source json is not changed.
 func (jpayload JSONPayload) AddMany(toAdd map[string]interface{}) moleculer.Payload {
 	if !jpayload.IsMap() {
 		return payload.Error("payload.Add can only deal with map payloads.")
@@ -117,12 +133,28 @@ func (jpayload JSONPayload) AddMany(toAdd map[string]interface{}) moleculer.Payl
 	var err error
 	json := jpayload.result.Raw
 	for key, value := range toAdd {
+		if nested, isPayload := value.(moleculer.Payload); isPayload {
+			value = nested.Value()
+		}
 		json, err = sjson.Set(json, key, value)
 		if err != nil {
 			return payload.Error("Error serializng value into JSON. error: ", err.Error())
 		}
 	}
-	return JSONPayload{gjson.Parse(json), jpayload.logger}
+	return JSONPayload{gjson.Parse(json), jpayload.logger, jpayload.preserveNumbers}
+}
+
+func (jpayload JSONPayload) Merge(other moleculer.Payload) moleculer.Payload {
+	return payload.Merge(jpayload, other)
+}
+
+func (jpayload JSONPayload) Diff(other moleculer.Payload) moleculer.Payload {
+	return payload.Diff(jpayload, other)
+}
+
+// Clone return the payload itself, since the parsed JSON can't be changed.
+func (jpayload JSONPayload) Clone() moleculer.Payload {
+	return jpayload
 }
 
 var invalidTypes = []string{"func()"}
@@ -136,10 +168,26 @@ func validTypeForSerializing(vType string) bool {
 	return true
 }
 
+// serializableValue convert time values to payload.TimeFormat strings and durations to
+// milliseconds, so they are read the same way by the Go and JS nodes.
+func serializableValue(value interface{}) (interface{}, bool) {
+	switch source := value.(type) {
+	case time.Time:
+		return payload.FormatTime(source), true
+	case time.Duration:
+		return payload.FormatDuration(source), true
+	}
+	return value, false
+}
+
 // cleanUpForSerialization clean the map from invalid values for serialization, example: functions.
 func cleanUpForSerialization(values *map[string]interface{}) *map[string]interface{} {
 	result := map[string]interface{}{}
 	for key, value := range *values {
+		if converted, isConverted := serializableValue(value); isConverted {
+			result[key] = converted
+			continue
+		}
 		vType := payload.GetValueType(&value)
 		mTransformer := payload.MapTransformer(&value)
 		if mTransformer != nil {
@@ -150,25 +198,12 @@ func cleanUpForSerialization(values *map[string]interface{}) *map[string]interfa
 		}
 		btsArray, isBytesArray := value.([]byte)
 		if isBytesArray {
-			result[key] = string(btsArray)
+			result[key] = base64.StdEncoding.EncodeToString(btsArray)
 			continue
 		}
 		aTransformer := payload.ArrayTransformer(&value)
 		if aTransformer != nil {
-			iArray := aTransformer.InterfaceArray(&value)
-			valueA := []interface{}{}
-			for _, item := range iArray {
-				mTransformer := payload.MapTransformer(&item)
-				if mTransformer != nil {
-					mValue := mTransformer.AsMap(&item)
-					valueA = append(valueA, cleanUpForSerialization(&mValue))
-					continue
-				}
-				if validTypeForSerializing(payload.GetValueType(&item)) {
-					valueA = append(valueA, item)
-				}
-			}
-			result[key] = valueA
+			result[key] = cleanUpList(aTransformer.InterfaceArray(&value))
 			continue
 		}
 		if validTypeForSerializing(vType) {
@@ -178,13 +213,38 @@ func cleanUpForSerialization(values *map[string]interface{}) *map[string]interfa
 	return &result
 }
 
+// cleanUpList clean the list items, the same way cleanUpForSerialization does with map values.
+func cleanUpList(list []interface{}) []interface{} {
+	result := []interface{}{}
+	for _, item := range list {
+		if converted, isConverted := serializableValue(item); isConverted {
+			result = append(result, converted)
+			continue
+		}
+		mTransformer := payload.MapTransformer(&item)
+		if mTransformer != nil {
+			mValue := mTransformer.AsMap(&item)
+			result = append(result, cleanUpForSerialization(&mValue))
+			continue
+		}
+		if bts, isBytes := item.([]byte); isBytes {
+			result = append(result, base64.StdEncoding.EncodeToString(bts))
+			continue
+		}
+		if validTypeForSerializing(payload.GetValueType(&item)) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 func (serializer JSONSerializer) arrayToJsonPayload(list []interface{}) (JSONPayload, error) {
-	json, err := sjson.Set("{root:false}", "root", list)
+	json, err := sjson.Set("{root:false}", "root", cleanUpList(list))
 	if err != nil {
 		serializer.logger.Error("arrayToJsonPayload() Error when parsing the map: ", list, " Error: ", err)
 		return JSONPayload{}, err
 	}
-	return JSONPayload{gjson.Get(json, "root"), serializer.logger}, nil
+	return JSONPayload{gjson.Get(json, "root"), serializer.logger, serializer.preserveNumbers}, nil
 }
 
 func (serializer JSONSerializer) mapToJsonPayload(mapValue *map[string]interface{}) (JSONPayload, error) {
@@ -194,7 +254,7 @@ func (serializer JSONSerializer) mapToJsonPayload(mapValue *map[string]interface
 		serializer.logger.Error("mapToJsonPayload() Error when parsing the map: ", mapValue, " Error: ", err)
 		return JSONPayload{}, err
 	}
-	return JSONPayload{gjson.Get(json, "root"), serializer.logger}, nil
+	return JSONPayload{gjson.Get(json, "root"), serializer.logger, serializer.preserveNumbers}, nil
 }
 
 func (serializer JSONSerializer) MapToPayload(mapValue *map[string]interface{}) (moleculer.Payload, error) {
@@ -207,7 +267,7 @@ func (serializer JSONSerializer) PayloadToContextMap(message moleculer.Payload)
 
 func (payload JSONPayload) Get(path string) moleculer.Payload {
 	result := payload.result.Get(path)
-	message := JSONPayload{result, payload.logger}
+	message := JSONPayload{result, payload.logger, payload.preserveNumbers}
 	return message
 }
 
@@ -215,7 +275,7 @@ func (payload JSONPayload) Get(path string) moleculer.Payload {
 func (p JSONPayload) Only(path string) moleculer.Payload {
 	result := p.result.Get(path)
 	if result.Exists() {
-		return payload.Empty().Add(path, JSONPayload{result, p.logger})
+		return payload.Empty().Add(path, JSONPayload{result, p.logger, p.preserveNumbers})
 	}
 	return payload.New(nil)
 }
@@ -224,8 +284,45 @@ func (payload JSONPayload) Exists() bool {
 	return payload.result.Exists()
 }
 
+// IsNil return true when the value is missing or null.
+func (payload JSONPayload) IsNil() bool {
+	return payload.result.Type == gjson.Null
+}
+
+func (jpayload JSONPayload) GetOrDefault(path string, defaultValue interface{}) moleculer.Payload {
+	if value := jpayload.Get(path); !value.IsNil() {
+		return value
+	}
+	return payload.New(defaultValue)
+}
+
+func (payload JSONPayload) StringOk() (string, bool) {
+	return payload.result.Str, payload.result.Type == gjson.String
+}
+
+func (payload JSONPayload) IntOk() (int, bool) {
+	return int(payload.result.Int()), payload.result.Type == gjson.Number
+}
+
+func (payload JSONPayload) Int64Ok() (int64, bool) {
+	return payload.result.Int(), payload.result.Type == gjson.Number
+}
+
+func (payload JSONPayload) FloatOk() (float64, bool) {
+	return payload.result.Num, payload.result.Type == gjson.Number
+}
+
+func (payload JSONPayload) BoolOk() (bool, bool) {
+	return payload.result.Bool(), payload.result.Type == gjson.True || payload.result.Type == gjson.False
+}
+
+func (payload JSONPayload) TimeOk() (time.Time, bool) {
+	value := resultTime(payload.result)
+	return value, !value.IsZero()
+}
+
 func (payload JSONPayload) Value() interface{} {
-	return payload.result.Value()
+	return resultValue(payload.result, payload.preserveNumbers)
 }
 
 func (payload JSONPayload) Int() int {
@@ -240,8 +337,34 @@ func (payload JSONPayload) Uint() uint64 {
 	return payload.result.Uint()
 }
 
+// Time parse RFC3339 strings and numbers (milliseconds since epoch, like JS Date.getTime()).
 func (payload JSONPayload) Time() time.Time {
-	return payload.result.Time()
+	return resultTime(payload.result)
+}
+
+func resultTime(result gjson.Result) time.Time {
+	switch result.Type {
+	case gjson.String:
+		return payload.ParseTime(result.Str)
+	case gjson.Number:
+		return payload.ParseTime(result.Num)
+	}
+	return time.Time{}
+}
+
+// Duration parse numbers as milliseconds and strings like "1m30s".
+func (payload JSONPayload) Duration() time.Duration {
+	return resultDuration(payload.result)
+}
+
+func resultDuration(result gjson.Result) time.Duration {
+	switch result.Type {
+	case gjson.String:
+		return payload.ParseDuration(result.Str)
+	case gjson.Number:
+		return payload.ParseDuration(result.Num)
+	}
+	return 0
 }
 
 func (jp JSONPayload) Len() int {
@@ -252,8 +375,8 @@ func (jp JSONPayload) Len() int {
 }
 
 func (jp JSONPayload) First() moleculer.Payload {
-	if jp.IsArray() {
-		return JSONPayload{jp.result.Array()[0], jp.logger}
+	if jp.IsArray() && len(jp.result.Array()) > 0 {
+		return JSONPayload{jp.result.Array()[0], jp.logger, jp.preserveNumbers}
 	}
 	return payload.New(nil)
 }
@@ -270,43 +393,61 @@ func (payload JSONPayload) StringArray() []string {
 	return nil
 }
 
-func resultToArray(results []gjson.Result, allTheWay bool) []interface{} {
+func resultToArray(results []gjson.Result, allTheWay, preserveNumbers bool) []interface{} {
 	list := make([]interface{}, len(results))
 	for index, item := range results {
 		var value interface{}
 		if item.IsObject() {
-			value = resultToMap(item, allTheWay)
+			value = resultToMap(item, allTheWay, preserveNumbers)
 		} else if item.IsArray() {
-			value = resultToArray(item.Array(), allTheWay)
+			value = resultToArray(item.Array(), allTheWay, preserveNumbers)
 		} else {
-			value = item.Value()
+			value = resultValue(item, preserveNumbers)
 		}
 		list[index] = value
 	}
 	return list
 }
 
-func resultToMap(result gjson.Result, allTheWay bool) map[string]interface{} {
+func resultToMap(result gjson.Result, allTheWay, preserveNumbers bool) map[string]interface{} {
 	mvalues := make(map[string]interface{})
 	result.ForEach(func(key, value gjson.Result) bool {
 		if allTheWay && value.IsObject() {
-			mvalues[key.String()] = resultToMap(value, allTheWay)
+			mvalues[key.String()] = resultToMap(value, allTheWay, preserveNumbers)
 		} else if allTheWay && value.IsArray() {
-			mvalues[key.String()] = resultToArray(value.Array(), allTheWay)
+			mvalues[key.String()] = resultToArray(value.Array(), allTheWay, preserveNumbers)
 		} else {
-			mvalues[key.String()] = value.Value()
+			mvalues[key.String()] = resultValue(value, preserveNumbers)
 		}
 		return true
 	})
 	return mvalues
 }
 
+// resultValue return the go value of the result. When preserveNumbers is true the numbers,
+// including the nested ones, are json.Number with the raw JSON text instead of float64.
+func resultValue(result gjson.Result, preserveNumbers bool) interface{} {
+	if !preserveNumbers {
+		return result.Value()
+	}
+	if result.IsObject() {
+		return resultToMap(result, true, true)
+	}
+	if result.IsArray() {
+		return resultToArray(result.Array(), true, true)
+	}
+	if result.Type == gjson.Number {
+		return json.Number(result.Raw)
+	}
+	return result.Value()
+}
+
 func (payload JSONPayload) MapArray() []map[string]interface{} {
 	if payload.IsArray() {
 		source := payload.result.Array()
 		array := make([]map[string]interface{}, len(source))
 		for index, item := range source {
-			array[index] = resultToMap(item, true)
+			array[index] = resultToMap(item, true, payload.preserveNumbers)
 		}
 		return array
 	}
@@ -318,7 +459,7 @@ func (payload JSONPayload) ValueArray() []interface{} {
 		source := payload.result.Array()
 		array := make([]interface{}, len(source))
 		for index, item := range source {
-			array[index] = item.Value()
+			array[index] = resultValue(item, payload.preserveNumbers)
 		}
 		return array
 	}
@@ -431,15 +572,52 @@ func (payload JSONPayload) BoolArray() []bool {
 	return nil
 }
 
+// Bytes decode the binary value, encoded as a base64 string. Buffers serialized by
+// moleculer-js ({"type":"Buffer","data":[...]}) and arrays of bytes are also accepted.
+func (payload JSONPayload) Bytes() []byte {
+	result := payload.result
+	if result.IsObject() && result.Get("type").String() == "Buffer" {
+		result = result.Get("data")
+	}
+	if result.Type == gjson.String {
+		decoded, err := base64.StdEncoding.DecodeString(result.Str)
+		if err != nil {
+			return nil
+		}
+		return decoded
+	}
+	if result.IsArray() {
+		items := result.Array()
+		bytes := make([]byte, len(items))
+		for index, item := range items {
+			bytes[index] = byte(item.Uint())
+		}
+		return bytes
+	}
+	return nil
+}
+
+// ByteArray is the same as Bytes.
 func (payload JSONPayload) ByteArray() []byte {
-	return []byte(payload.result.Raw)
+	return payload.Bytes()
 }
 
 func (payload JSONPayload) TimeArray() []time.Time {
 	if source := payload.result.Array(); source != nil {
 		array := make([]time.Time, len(source))
 		for index, item := range source {
-			array[index] = item.Time()
+			array[index] = resultTime(item)
+		}
+		return array
+	}
+	return nil
+}
+
+func (payload JSONPayload) DurationArray() []time.Duration {
+	if source := payload.result.Array(); source != nil {
+		array := make([]time.Duration, len(source))
+		for index, item := range source {
+			array[index] = resultDuration(item)
 		}
 		return array
 	}
@@ -451,7 +629,7 @@ func (payload JSONPayload) Array() []moleculer.Payload {
 		source := payload.result.Array()
 		array := make([]moleculer.Payload, len(source))
 		for index, item := range source {
-			array[index] = JSONPayload{item, payload.logger}
+			array[index] = JSONPayload{item, payload.logger, payload.preserveNumbers}
 		}
 		return array
 	}
@@ -467,6 +645,20 @@ func (p JSONPayload) Sort(field string) moleculer.Payload {
 	return ps.Payload()
 }
 
+func (p JSONPayload) Filter(predicate func(item moleculer.Payload) bool) moleculer.Payload {
+	if !p.IsArray() {
+		return p
+	}
+	return payload.FilterList(p.Array(), predicate)
+}
+
+func (p JSONPayload) Slice(offset, limit int) moleculer.Payload {
+	if !p.IsArray() {
+		return p
+	}
+	return payload.SliceList(p.Array(), offset, limit)
+}
+
 func (payload JSONPayload) IsArray() bool {
 	return payload.result.IsArray()
 }
@@ -477,10 +669,36 @@ func (payload JSONPayload) IsMap() bool {
 
 func (payload JSONPayload) ForEach(iterator func(key interface{}, value moleculer.Payload) bool) {
 	payload.result.ForEach(func(key, value gjson.Result) bool {
-		return iterator(key.Value(), &JSONPayload{value, payload.logger})
+		return iterator(key.Value(), &JSONPayload{value, payload.logger, payload.preserveNumbers})
 	})
 }
 
+func (payload JSONPayload) Unmarshal(target interface{}) error {
+	if payload.IsError() {
+		return payload.Error()
+	}
+	return json.Unmarshal([]byte(payload.result.Raw), target)
+}
+
+// MarshalJSON return the raw json, so payloads can be nested in values encoded with encoding/json.
+func (payload JSONPayload) MarshalJSON() ([]byte, error) {
+	if !payload.Exists() {
+		return []byte("null"), nil
+	}
+	return []byte(payload.result.Raw), nil
+}
+
+// DecodeArrayStream iterate over the items of the array lazily, without creating the list of all items.
+func (jpayload JSONPayload) DecodeArrayStream(iterator func(item moleculer.Payload) bool) error {
+	if !jpayload.IsArray() {
+		return errors.New("payload.DecodeArrayStream() can only deal with lists and streams.")
+	}
+	jpayload.result.ForEach(func(key, value gjson.Result) bool {
+		return iterator(JSONPayload{value, jpayload.logger, jpayload.preserveNumbers})
+	})
+	return nil
+}
+
 func (payload JSONPayload) Bool() bool {
 	return payload.result.Bool()
 }
@@ -499,6 +717,9 @@ func (payload JSONPayload) IsError() bool {
 
 func (payload JSONPayload) Error() error {
 	if payload.IsError() {
+		if payload.Get("error").Get("message").Exists() {
+			return moleculer.ParseError(payload.Get("error").RawMap())
+		}
 		return errors.New(payload.Get("error").String())
 	}
 	return nil
@@ -538,7 +759,7 @@ func (jp JSONPayload) String() string {
 }
 
 func (payload JSONPayload) RawMap() map[string]interface{} {
-	mapValue, ok := payload.result.Value().(map[string]interface{})
+	mapValue, ok := payload.Value().(map[string]interface{})
 	if !ok {
 		payload.logger.Warn("RawMap() Could not convert result.Value() into a map[string]interface{} - result: ", payload.result)
 		return nil
@@ -550,7 +771,7 @@ func (payload JSONPayload) Map() map[string]moleculer.Payload {
 	if source := payload.result.Map(); source != nil {
 		newMap := make(map[string]moleculer.Payload, len(source))
 		for key, item := range source {
-			newMap[key] = &JSONPayload{item, payload.logger}
+			newMap[key] = &JSONPayload{item, payload.logger, payload.preserveNumbers}
 		}
 		return newMap
 	}
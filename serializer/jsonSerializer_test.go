@@ -1,6 +1,7 @@
 package serializer_test
 
 import (
+	"encoding/json"
 	"os"
 	"time"
 
@@ -69,6 +70,208 @@ var _ = Describe("JSON Serializer", func() {
 		Expect(snap.SnapshotMulti("Add()", m)).ShouldNot(HaveOccurred())
 	})
 
+	It("Add, AddMany and Remove should return new payloads and keep the source unchanged", func() {
+
+		serial := serializer.CreateJSONSerializer(log.WithField("unit", "test"))
+		p, _ := serial.MapToPayload(&map[string]interface{}{
+			"name":     "John",
+			"lastname": "Snow",
+		})
+
+		added := p.Add("house", payload.New(map[string]interface{}{"name": "Stark"})).Add("address.city", "Winterfell")
+		Expect(added.Get("house").Get("name").String()).Should(Equal("Stark"))
+		Expect(added.Get("address").Get("city").String()).Should(Equal("Winterfell"))
+		Expect(p.Get("house").Exists()).Should(BeFalse())
+
+		removed := added.Remove("lastname", "address.city")
+		Expect(removed.Get("lastname").Exists()).Should(BeFalse())
+		Expect(removed.Get("address").Get("city").Exists()).Should(BeFalse())
+		Expect(added.Get("lastname").String()).Should(Equal("Snow"))
+
+		list, _ := serial.MapToPayload(&map[string]interface{}{
+			"items": []interface{}{
+				map[string]interface{}{"name": "John", "secret": 1},
+				map[string]interface{}{"name": "Arya", "secret": 2},
+			},
+		})
+		items := list.Get("items").Remove("secret")
+		Expect(items.Len()).Should(Equal(2))
+		Expect(items.First().Get("secret").Exists()).Should(BeFalse())
+		Expect(items.First().Get("name").String()).Should(Equal("John"))
+	})
+
+	It("Unmarshal should decode the json into structs", func() {
+		type item struct {
+			Name    string    `json:"name"`
+			Amount  int       `json:"amount"`
+			Created time.Time `json:"created"`
+		}
+		created := time.Date(2019, 5, 1, 18, 57, 6, 0, time.UTC)
+		serial := serializer.CreateJSONSerializer(log.WithField("unit", "test"))
+		p, _ := serial.MapToPayload(&map[string]interface{}{
+			"items": []interface{}{payload.Create(item{Name: "pen", Amount: 2, Created: created})},
+		})
+
+		var target struct {
+			Items []item `json:"items"`
+		}
+		Expect(p.Unmarshal(&target)).Should(Succeed())
+		Expect(target.Items).Should(Equal([]item{item{Name: "pen", Amount: 2, Created: created}}))
+	})
+
+	It("Should encode binary values as base64 and decode them with Bytes()", func() {
+		serial := serializer.CreateJSONSerializer(log.WithField("unit", "test"))
+		content := []byte{0, 1, 2, 255, 'a'}
+
+		bts := serial.PayloadToBytes(payload.New(map[string]interface{}{
+			"file":  content,
+			"files": []interface{}{content},
+		}))
+		p := serial.BytesToPayload(&bts)
+		Expect(p.Get("file").String()).Should(Equal("AAEC/2E="))
+		Expect(p.Get("file").Bytes()).Should(Equal(content))
+		Expect(p.Get("files").First().Bytes()).Should(Equal(content))
+
+		bts = serial.PayloadToBytes(payload.New(content))
+		Expect(serial.BytesToPayload(&bts).Bytes()).Should(Equal(content))
+
+		buffer := []byte(`{"type":"Buffer","data":[0,1,2,255,97]}`)
+		Expect(serial.BytesToPayload(&buffer).Bytes()).Should(Equal(content))
+		Expect(serial.BytesToPayload(&buffer).ByteArray()).Should(Equal(content))
+	})
+
+	It("Should serialize times as RFC3339 with fractional seconds and durations as milliseconds", func() {
+		serial := serializer.CreateJSONSerializer(log.WithField("unit", "test"))
+		created := time.Date(2019, 5, 1, 18, 57, 6, 123000000, time.UTC)
+
+		bts := serial.PayloadToBytes(payload.New(map[string]interface{}{
+			"created":  created,
+			"dates":    []time.Time{created},
+			"timeout":  1500 * time.Millisecond,
+			"timeouts": []interface{}{time.Second, 2 * time.Minute},
+		}))
+		p := serial.BytesToPayload(&bts)
+		Expect(p.Get("created").String()).Should(Equal("2019-05-01T18:57:06.123000000Z"))
+		Expect(p.Get("created").Time().Equal(created)).Should(BeTrue())
+		Expect(p.Get("dates").TimeArray()[0].Equal(created)).Should(BeTrue())
+		Expect(p.Get("timeout").Float()).Should(Equal(1500.0))
+		Expect(p.Get("timeout").Duration()).Should(Equal(1500 * time.Millisecond))
+		Expect(p.Get("timeouts").DurationArray()).Should(Equal([]time.Duration{time.Second, 2 * time.Minute}))
+
+		bts = serial.PayloadToBytes(payload.New(created))
+		Expect(string(bts)).Should(Equal("2019-05-01T18:57:06.123000000Z"))
+	})
+
+	It("Should read the JS Date values", func() {
+		serial := serializer.CreateJSONSerializer(log.WithField("unit", "test"))
+		created := time.Date(2019, 5, 1, 18, 57, 6, 123000000, time.UTC)
+		// JSON.stringify({ created: date, timestamp: date.getTime(), timeout: 1500, interval: "2m" })
+		bts := []byte(`{"created":"2019-05-01T18:57:06.123Z","timestamp":1556737026123,"timeout":1500,"interval":"2m"}`)
+		p := serial.BytesToPayload(&bts)
+		Expect(p.Get("created").Time().Equal(created)).Should(BeTrue())
+		Expect(p.Get("timestamp").Time().Equal(created)).Should(BeTrue())
+		Expect(p.Get("timeout").Duration()).Should(Equal(1500 * time.Millisecond))
+		Expect(p.Get("interval").Duration()).Should(Equal(2 * time.Minute))
+		Expect(p.Get("missing").Time().IsZero()).Should(BeTrue())
+		Expect(p.Get("interval").Time().IsZero()).Should(BeTrue())
+	})
+
+	It("Should tell missing and null values apart from zero values", func() {
+		serial := serializer.CreateJSONSerializer(log.WithField("unit", "test"))
+		bts := []byte(`{"name":"John","age":0,"active":false,"nothing":null,"created":"2019-05-01T18:57:06Z"}`)
+		p := serial.BytesToPayload(&bts)
+
+		Expect(p.Get("nothing").IsNil()).Should(BeTrue())
+		Expect(p.Get("missing").IsNil()).Should(BeTrue())
+		Expect(p.Get("age").IsNil()).Should(BeFalse())
+		Expect(p.GetOrDefault("nothing", 18).Int()).Should(Equal(18))
+		Expect(p.GetOrDefault("age", 18).Int()).Should(Equal(0))
+
+		age, ok := p.Get("age").IntOk()
+		Expect(age).Should(Equal(0))
+		Expect(ok).Should(BeTrue())
+		_, ok = p.Get("missing").Int64Ok()
+		Expect(ok).Should(BeFalse())
+		_, ok = p.Get("name").FloatOk()
+		Expect(ok).Should(BeFalse())
+		active, ok := p.Get("active").BoolOk()
+		Expect(active).Should(BeFalse())
+		Expect(ok).Should(BeTrue())
+		_, ok = p.Get("nothing").StringOk()
+		Expect(ok).Should(BeFalse())
+		_, ok = p.Get("created").TimeOk()
+		Expect(ok).Should(BeTrue())
+	})
+
+	It("Should keep the precision of 64-bit integers when preserving numbers", func() {
+		bts := []byte(`{"id":9007199254740993,"price":10.25,"ids":[9223372036854775807],"order":{"id":1234567890123456789}}`)
+
+		lossy := serializer.CreateJSONSerializer(log.WithField("unit", "test")).BytesToPayload(&bts)
+		Expect(lossy.RawMap()["id"]).Should(Equal(float64(9007199254740992)))
+
+		serial := serializer.CreateJSONSerializer(log.WithField("unit", "test")).PreservingNumbers()
+		p := serial.BytesToPayload(&bts)
+		values := p.RawMap()
+		Expect(values["id"]).Should(Equal(json.Number("9007199254740993")))
+		Expect(values["ids"]).Should(Equal([]interface{}{json.Number("9223372036854775807")}))
+		Expect(values["order"]).Should(Equal(map[string]interface{}{"id": json.Number("1234567890123456789")}))
+		Expect(p.Get("ids").ValueArray()).Should(Equal([]interface{}{json.Number("9223372036854775807")}))
+
+		params := payload.New(values)
+		Expect(params.Get("id").Int64()).Should(Equal(int64(9007199254740993)))
+		Expect(params.Get("price").Float()).Should(Equal(10.25))
+		Expect(params.Get("order").Get("id").Int64()).Should(Equal(int64(1234567890123456789)))
+
+		Expect(string(serial.PayloadToBytes(params.Remove("price", "ids")))).Should(Equal(`{"id":9007199254740993,"order":{"id":1234567890123456789}}`))
+	})
+
+	It("Filter, Sort and Slice should be chainable on json lists", func() {
+		json := []byte(`[{"name":"John","age":23},{"name":"Arya","age":9},{"name":"Sansa","age":13},{"name":"Robb","age":25}]`)
+		serial := serializer.CreateJSONSerializer(log.WithField("unit", "test"))
+		p := serial.BytesToPayload(&json)
+
+		result := p.Filter(func(item moleculer.Payload) bool {
+			return item.Get("name").String() != "John"
+		}).Sort("age").Slice(0, 2)
+		Expect(result.Len()).Should(Equal(2))
+		Expect(result.First().Get("name").String()).Should(Equal("Arya"))
+		Expect(result.Array()[1].Get("name").String()).Should(Equal("Sansa"))
+
+		empty := []byte(`[]`)
+		Expect(serial.BytesToPayload(&empty).First().Exists()).Should(BeFalse())
+	})
+
+	It("Merge and Diff should work with json payloads", func() {
+		serial := serializer.CreateJSONSerializer(log.WithField("unit", "test"))
+		json := []byte(`{"name":"John","address":{"city":"Winterfell","country":"North"},"age":23}`)
+		p := serial.BytesToPayload(&json)
+
+		merged := p.Merge(payload.New(map[string]interface{}{"address": map[string]interface{}{"city": "Castle Black"}}))
+		Expect(merged.Get("address").Get("city").String()).Should(Equal("Castle Black"))
+		Expect(merged.Get("address").Get("country").String()).Should(Equal("North"))
+
+		diff := p.Diff(merged)
+		Expect(diff.RawMap()).Should(Equal(map[string]interface{}{
+			"address": map[string]interface{}{"city": "Castle Black"},
+		}))
+		Expect(merged.Diff(p).Get("address").Get("city").String()).Should(Equal("Winterfell"))
+	})
+
+	It("DecodeArrayStream should iterate over json lists", func() {
+		serial := serializer.CreateJSONSerializer(log.WithField("unit", "test"))
+		json := []byte(`[{"id":1},{"id":2},{"id":3}]`)
+		ids := []int{}
+		err := serial.BytesToPayload(&json).DecodeArrayStream(func(item moleculer.Payload) bool {
+			ids = append(ids, item.Get("id").Int())
+			return item.Get("id").Int() < 2
+		})
+		Expect(err).Should(BeNil())
+		Expect(ids).Should(Equal([]int{1, 2}))
+
+		json = []byte(`{"id":1}`)
+		Expect(serial.BytesToPayload(&json).DecodeArrayStream(func(item moleculer.Payload) bool { return true })).ShouldNot(Succeed())
+	})
+
 	It("Should handle each return type", func() {
 		logger := log.WithField("serializer", "JSON")
 		serializer := serializer.CreateJSONSerializer(logger)
@@ -0,0 +1,44 @@
+package serializer
+
+import (
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	JSON = "JSON"
+	CBOR = "CBOR"
+)
+
+// Factory create a serializer instance.
+type Factory func(logger *log.Entry) Serializer
+
+var serializers = map[string]Factory{}
+var serializersMutex = &sync.RWMutex{}
+
+func init() {
+	Register(JSON, func(logger *log.Entry) Serializer {
+		return CreateJSONSerializer(logger)
+	})
+	Register(CBOR, func(logger *log.Entry) Serializer {
+		return CreateCBORSerializer(logger)
+	})
+}
+
+// Register register a serializer factory for a name. The name is matched (case insensitive)
+// against the broker serializer config. Registering an existing name replaces the factory.
+func Register(name string, factory Factory) {
+	serializersMutex.Lock()
+	defer serializersMutex.Unlock()
+	serializers[strings.ToLower(name)] = factory
+}
+
+// Find return the factory registered for the name.
+func Find(name string) (Factory, bool) {
+	serializersMutex.RLock()
+	defer serializersMutex.RUnlock()
+	factory, exists := serializers[strings.ToLower(name)]
+	return factory, exists
+}
@@ -0,0 +1,55 @@
+package serializer_test
+
+import (
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/serializer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+type namedSerializer struct {
+	serializer.JSONSerializer
+	name string
+}
+
+var _ = Describe("Serializer registry", func() {
+
+	It("should use the JSON serializer by default", func() {
+		delegates := BrokerDelegates("registry-node")
+		Expect(serializer.New(delegates)).Should(BeAssignableToTypeOf(serializer.JSONSerializer{}))
+
+		delegates.Config = moleculer.Config{Serializer: "json"}
+		Expect(serializer.New(delegates)).Should(BeAssignableToTypeOf(serializer.JSONSerializer{}))
+	})
+
+	It("should create the CBOR serializer", func() {
+		delegates := BrokerDelegates("registry-node")
+		delegates.Config = moleculer.Config{Serializer: "CBOR"}
+		Expect(serializer.New(delegates)).Should(BeAssignableToTypeOf(serializer.CBORSerializer{}))
+	})
+
+	It("should create the serializer registered for the config name", func() {
+		serializer.Register("Custom", func(logger *log.Entry) serializer.Serializer {
+			return namedSerializer{serializer.CreateJSONSerializer(logger), "custom"}
+		})
+		factory, exists := serializer.Find("CUSTOM")
+		Expect(exists).Should(BeTrue())
+		Expect(factory).ShouldNot(BeNil())
+
+		delegates := BrokerDelegates("registry-node")
+		delegates.Config = moleculer.Config{Serializer: "custom"}
+		created := serializer.New(delegates)
+		Expect(created).Should(BeAssignableToTypeOf(namedSerializer{}))
+		Expect(created.(namedSerializer).name).Should(Equal("custom"))
+	})
+
+	It("should fall back to JSON when the serializer is not registered", func() {
+		_, exists := serializer.Find("unknown")
+		Expect(exists).Should(BeFalse())
+
+		delegates := BrokerDelegates("registry-node")
+		delegates.Config = moleculer.Config{Serializer: "unknown"}
+		Expect(serializer.New(delegates)).Should(BeAssignableToTypeOf(serializer.JSONSerializer{}))
+	})
+})
@@ -1,6 +1,10 @@
 package serializer
 
-import "github.com/moleculer-go/moleculer"
+import (
+	"strings"
+
+	"github.com/moleculer-go/moleculer"
+)
 
 type Serializer interface {
 	BytesToPayload(*[]byte) moleculer.Payload
@@ -9,6 +13,22 @@ type Serializer interface {
 	MapToPayload(*map[string]interface{}) (moleculer.Payload, error)
 }
 
+// New create the serializer selected in the broker config. Unknown serializers fall back to JSON.
+// With Config.PreserveNumbers the JSON serializer decodes numbers as json.Number.
 func New(broker *moleculer.BrokerDelegates) Serializer {
-	return CreateJSONSerializer(broker.Logger("serializer", "json"))
+	name := broker.Config.Serializer
+	if name == "" {
+		name = JSON
+	}
+	logger := broker.Logger("serializer", strings.ToLower(name))
+	factory, exists := Find(name)
+	if !exists {
+		logger.Warn("Serializer '", name, "' is not registered, using JSON instead.")
+		factory, _ = Find(JSON)
+	}
+	serializer := factory(logger)
+	if json, isJSON := serializer.(JSONSerializer); isJSON && broker.Config.PreserveNumbers {
+		return json.PreservingNumbers()
+	}
+	return serializer
 }
@@ -0,0 +1,60 @@
+package service
+
+import (
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// cacheAsSettings return the cache settings in the format of the "cache" setting, sent to the other
+// nodes in the INFO packet like moleculer-js: the ttl is in seconds.
+func cacheAsSettings(cache moleculer.CacheSettings) map[string]interface{} {
+	settings := map[string]interface{}{"enabled": cache.Enabled}
+	if len(cache.Keys) > 0 {
+		settings["keys"] = cache.Keys
+	}
+	if cache.TTL > 0 {
+		settings["ttl"] = cache.TTL.Seconds()
+	}
+	return settings
+}
+
+// cacheFromSettings parse the "cache" setting of an action: true, or a map with the enabled, keys
+// and ttl (in seconds) fields, enabled when not set. It returns nil when the cache is not enabled.
+func cacheFromSettings(value interface{}) *moleculer.CacheSettings {
+	switch setting := value.(type) {
+	case bool:
+		if setting {
+			return &moleculer.CacheSettings{Enabled: true}
+		}
+	case map[string]interface{}:
+		cache := moleculer.CacheSettings{Enabled: true}
+		if enabled, isBool := setting["enabled"].(bool); isBool {
+			cache.Enabled = enabled
+		}
+		switch keys := setting["keys"].(type) {
+		case []string:
+			cache.Keys = keys
+		case []interface{}:
+			for _, key := range keys {
+				if name, isString := key.(string); isString {
+					cache.Keys = append(cache.Keys, name)
+				}
+			}
+		}
+		switch ttl := setting["ttl"].(type) {
+		case int:
+			cache.TTL = time.Duration(ttl) * time.Second
+		case int64:
+			cache.TTL = time.Duration(ttl) * time.Second
+		case float64:
+			cache.TTL = time.Duration(ttl * float64(time.Second))
+		case time.Duration:
+			cache.TTL = ttl
+		}
+		if cache.Enabled {
+			return &cache
+		}
+	}
+	return nil
+}
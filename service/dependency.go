@@ -0,0 +1,40 @@
+package service
+
+import (
+	"strings"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// ParseDependency parse a dependency declared as "name" or "name:version".
+func ParseDependency(value string) moleculer.Dependency {
+	parts := strings.SplitN(value, ":", 2)
+	dependency := moleculer.Dependency{Name: strings.TrimSpace(parts[0])}
+	if len(parts) == 2 {
+		dependency.Version = strings.TrimSpace(parts[1])
+	}
+	return dependency
+}
+
+// DependsOn return the dependencies of the service, the ones declared in the schema
+// Dependencies followed by the ones in DependsOn.
+func (service *Service) DependsOn() []moleculer.Dependency {
+	result := make([]moleculer.Dependency, 0, len(service.dependencies))
+	for _, item := range service.dependencies {
+		result = append(result, ParseDependency(item))
+	}
+	if service.schema != nil {
+		result = append(result, service.schema.DependsOn...)
+	}
+	return result
+}
+
+// DependenciesTimeout return the time to wait for the dependencies declared in the schema,
+// or 0 when the broker config should be used.
+func (service *Service) DependenciesTimeout() time.Duration {
+	if service.schema == nil {
+		return 0
+	}
+	return service.schema.DependenciesTimeout
+}
@@ -0,0 +1,154 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// actionHooks are the hooks of an action, in the order they are called.
+type actionHooks struct {
+	before []moleculer.BeforeHookFunc
+	after  []moleculer.AfterHookFunc
+	errors []moleculer.ErrorHookFunc
+}
+
+// hooksFor collect the hooks of the action from the service hooks. The before hooks of the
+// patterns are called before the ones of the action name, and the after and error hooks of
+// the action name are called before the ones of the patterns.
+func hooksFor(hooks map[string]interface{}, actionName string) actionHooks {
+	result := actionHooks{}
+	for _, item := range matchingHooks(hooks["before"], actionName, false) {
+		switch hook := item.(type) {
+		case moleculer.BeforeHookFunc:
+			result.before = append(result.before, hook)
+		case func(moleculer.Context, moleculer.Payload) moleculer.Payload:
+			result.before = append(result.before, hook)
+		default:
+			panic(invalidHook("before", actionName, item))
+		}
+	}
+	for _, item := range matchingHooks(hooks["after"], actionName, true) {
+		switch hook := item.(type) {
+		case moleculer.AfterHookFunc:
+			result.after = append(result.after, hook)
+		case func(moleculer.Context, interface{}) interface{}:
+			result.after = append(result.after, hook)
+		default:
+			panic(invalidHook("after", actionName, item))
+		}
+	}
+	for _, item := range matchingHooks(hooks["error"], actionName, true) {
+		switch hook := item.(type) {
+		case moleculer.ErrorHookFunc:
+			result.errors = append(result.errors, hook)
+		case func(moleculer.Context, error) interface{}:
+			result.errors = append(result.errors, hook)
+		default:
+			panic(invalidHook("error", actionName, item))
+		}
+	}
+	return result
+}
+
+func invalidHook(kind, actionName string, hook interface{}) error {
+	return fmt.Errorf("Invalid %s hook for action: %s - type: %T", kind, actionName, hook)
+}
+
+// matchingHooks return the hooks of the action name and of the patterns matching it.
+// Patterns are applied in the alphabetical order.
+func matchingHooks(value interface{}, actionName string, namedFirst bool) []interface{} {
+	hooks, isMap := value.(map[string]interface{})
+	if !isMap {
+		return nil
+	}
+	patterns := []string{}
+	for key := range hooks {
+		if key != actionName && strings.ContainsAny(key, "*?[") {
+			if matched, _ := path.Match(key, actionName); matched {
+				patterns = append(patterns, key)
+			}
+		}
+	}
+	sort.Strings(patterns)
+	var fromPatterns []interface{}
+	for _, pattern := range patterns {
+		fromPatterns = append(fromPatterns, hookList(hooks[pattern])...)
+	}
+	named := hookList(hooks[actionName])
+	if namedFirst {
+		return append(named, fromPatterns...)
+	}
+	return append(fromPatterns, named...)
+}
+
+// hookList return the hooks of a single hook or a list of hooks.
+func hookList(value interface{}) []interface{} {
+	if value == nil {
+		return nil
+	}
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice {
+		return []interface{}{value}
+	}
+	result := make([]interface{}, list.Len())
+	for index := range result {
+		result[index] = list.Index(index).Interface()
+	}
+	return result
+}
+
+// wrapHooks return the handler wrapped with the hooks, or the handler itself when there are no hooks.
+func wrapHooks(handler moleculer.ActionHandler, hooks actionHooks) moleculer.ActionHandler {
+	if handler == nil || (len(hooks.before) == 0 && len(hooks.after) == 0 && len(hooks.errors) == 0) {
+		return handler
+	}
+	return func(context moleculer.Context, params moleculer.Payload) interface{} {
+		for _, hook := range hooks.before {
+			params = hook(context, params)
+			if params.IsError() {
+				return hooks.onError(context, params.Error())
+			}
+		}
+		result := handler(context, params)
+		if err := resultError(result); err != nil {
+			return hooks.onError(context, err)
+		}
+		for _, hook := range hooks.after {
+			result = hook(context, result)
+			if err := resultError(result); err != nil {
+				return hooks.onError(context, err)
+			}
+		}
+		return result
+	}
+}
+
+// onError call the error hooks until one of them returns a response instead of an error.
+func (hooks actionHooks) onError(context moleculer.Context, err error) interface{} {
+	var result interface{} = err
+	for _, hook := range hooks.errors {
+		result = hook(context, err)
+		if err = resultError(result); err == nil {
+			return result
+		}
+	}
+	return result
+}
+
+// resultError return the error of an action result, or nil when it is not an error.
+func resultError(result interface{}) error {
+	switch value := result.(type) {
+	case error:
+		return value
+	case moleculer.Payload:
+		if value.IsError() {
+			return value.Error()
+		}
+	}
+	return nil
+}
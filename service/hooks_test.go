@@ -0,0 +1,98 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/payload"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Action hooks", func() {
+
+	var calls []string
+	trace := func(name string) moleculer.BeforeHookFunc {
+		return func(context moleculer.Context, params moleculer.Payload) moleculer.Payload {
+			calls = append(calls, name)
+			return params.Add("hooks", len(calls))
+		}
+	}
+	traceAfter := func(name string) moleculer.AfterHookFunc {
+		return func(context moleculer.Context, result interface{}) interface{} {
+			calls = append(calls, name)
+			return result
+		}
+	}
+
+	BeforeEach(func() {
+		calls = nil
+	})
+
+	It("should call the hooks of the patterns and of the action name in order", func() {
+		hooks := map[string]interface{}{
+			"before": map[string]interface{}{
+				"*":      trace("before *"),
+				"get*":   []moleculer.BeforeHookFunc{trace("before get* 1"), trace("before get* 2")},
+				"getAll": trace("before getAll"),
+				"create": trace("before create"),
+			},
+			"after": map[string]interface{}{
+				"*":      traceAfter("after *"),
+				"getAll": []interface{}{traceAfter("after getAll")},
+			},
+		}
+		handler := wrapHooks(func(context moleculer.Context, params moleculer.Payload) interface{} {
+			calls = append(calls, "handler")
+			return params.Get("hooks").Int()
+		}, hooksFor(hooks, "getAll"))
+
+		Expect(handler(nil, payload.New(map[string]interface{}{}))).Should(Equal(4))
+		Expect(calls).Should(Equal([]string{
+			"before *", "before get* 1", "before get* 2", "before getAll", "handler", "after getAll", "after *",
+		}))
+	})
+
+	It("should change the response with the after hooks and recover from errors with the error hooks", func() {
+		failures := 0
+		hooks := map[string]interface{}{
+			"before": map[string]interface{}{
+				"delete": func(context moleculer.Context, params moleculer.Payload) moleculer.Payload {
+					if !params.Get("admin").Bool() {
+						return payload.New(errors.New("not allowed"))
+					}
+					return params
+				},
+			},
+			"after": map[string]interface{}{
+				"*": func(context moleculer.Context, result interface{}) interface{} {
+					return map[string]interface{}{"data": result}
+				},
+			},
+			"error": map[string]interface{}{
+				"delete": func(context moleculer.Context, err error) interface{} {
+					failures++
+					return err
+				},
+				"*": func(context moleculer.Context, err error) interface{} {
+					return map[string]interface{}{"error": err.Error()}
+				},
+			},
+		}
+		handler := wrapHooks(func(context moleculer.Context, params moleculer.Payload) interface{} {
+			return "deleted"
+		}, hooksFor(hooks, "delete"))
+
+		Expect(handler(nil, payload.New(map[string]interface{}{"admin": true}))).Should(Equal(map[string]interface{}{"data": "deleted"}))
+		Expect(handler(nil, payload.New(map[string]interface{}{"admin": false}))).Should(Equal(map[string]interface{}{"error": "not allowed"}))
+		Expect(failures).Should(Equal(1))
+	})
+
+	It("should keep the handler without hooks and panic with invalid hooks", func() {
+		Expect(hooksFor(map[string]interface{}{"solar-system": "true"}, "rotate")).Should(Equal(actionHooks{}))
+		Expect(func() {
+			hooksFor(map[string]interface{}{"before": map[string]interface{}{"*": "invalid"}}, "rotate")
+		}).Should(Panic())
+	})
+})
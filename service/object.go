@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// eventSuffix is the suffix of the methods of a service object that handle events,
+// e.g. UserCreatedEvent handles the user.created event.
+const eventSuffix = "Event"
+
+var contextType = reflect.TypeOf((*moleculer.Context)(nil)).Elem()
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+func isEventMethod(name string) bool {
+	return len(name) > len(eventSuffix) && strings.HasSuffix(name, eventSuffix)
+}
+
+// eventName given an event method format the event name with dots
+// between the words. Example: UserCreatedEvent = user.created
+func eventName(method string) string {
+	name := strings.TrimSuffix(method, eventSuffix)
+	words := []string{}
+	start := 0
+	for i := 1; i < len(name); i++ {
+		if unicode.IsUpper(rune(name[i])) && !unicode.IsUpper(rune(name[i-1])) {
+			words = append(words, name[start:i])
+			start = i
+		}
+	}
+	words = append(words, name[start:])
+	return strings.ToLower(strings.Join(words, "."))
+}
+
+// methodsOf return the value with the methods of the object, including the
+// methods with pointer receivers when the object is not a pointer.
+func methodsOf(obj interface{}) reflect.Value {
+	value := reflect.ValueOf(obj)
+	if value.Kind() == reflect.Ptr {
+		return value
+	}
+	pointer := reflect.New(value.Type())
+	pointer.Elem().Set(value)
+	return pointer
+}
+
+// extractEvents create an event for each method with the Event suffix. The handler can receive the
+// context and the params as a moleculer.Payload or a struct, like the actions.
+func extractEvents(obj interface{}) ([]moleculer.Event, error) {
+	events := []moleculer.Event{}
+	value := methodsOf(obj)
+	tp := value.Type()
+	for i := 0; i < tp.NumMethod(); i++ {
+		m := tp.Method(i)
+		if !isEventMethod(m.Name) {
+			continue
+		}
+		handler := eventHandler(value.Method(i))
+		if handler == nil {
+			return nil, errors.New("Invalid event handler method: " + m.Name + " - it must receive (moleculer.Context, moleculer.Payload) or a params struct")
+		}
+		events = append(events, moleculer.Event{
+			Name:    eventName(m.Name),
+			Handler: handler,
+		})
+	}
+	return events, nil
+}
+
+func eventHandler(m reflect.Value) moleculer.EventHandler {
+	switch handler := m.Interface().(type) {
+	case func(moleculer.Context, moleculer.Payload):
+		return handler
+	case func(moleculer.Payload):
+		return func(ctx moleculer.Context, params moleculer.Payload) {
+			handler(params)
+		}
+	case func():
+		return func(ctx moleculer.Context, params moleculer.Payload) {
+			handler()
+		}
+	}
+	paramsType, typed := typedParams(m)
+	if !typed {
+		return nil
+	}
+	return func(ctx moleculer.Context, params moleculer.Payload) {
+		args, err := typedArgs(m, paramsType, ctx, params)
+		if err == nil {
+			err = resultError(typedReturn(m.Call(args)))
+		}
+		if err != nil && ctx != nil {
+			ctx.Logger().Error("Event handler error: ", err)
+		}
+	}
+}
+
+// typedParams return the type of the params struct of a method that receives
+// (moleculer.Context, params struct) or (params struct).
+func typedParams(m reflect.Value) (reflect.Type, bool) {
+	t := m.Type()
+	if t.NumIn() == 0 || t.NumIn() > 2 || (t.NumIn() == 2 && t.In(0) != contextType) {
+		return nil, false
+	}
+	paramsType := t.In(t.NumIn() - 1)
+	structType := paramsType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType.Kind() != reflect.Struct {
+		return nil, false
+	}
+	return paramsType, true
+}
+
+// typedAction create the action of a method with a params struct. The params schema is the struct,
+// validated with the validate tags, and the cache settings come from the cache tags.
+func typedAction(name string, m reflect.Value, paramsType reflect.Type) moleculer.Action {
+	structType := paramsType
+	if structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	action := moleculer.Action{
+		Name:   name,
+		Schema: moleculer.ObjectSchema{Source: reflect.New(structType).Elem().Interface()},
+		Handler: func(ctx moleculer.Context, params moleculer.Payload) interface{} {
+			args, err := typedArgs(m, paramsType, ctx, params)
+			if err != nil {
+				return err
+			}
+			return typedReturn(m.Call(args))
+		},
+	}
+	if cache := cacheSettings(structType); cache != nil {
+		action.Settings = map[string]interface{}{"cache": cache}
+	}
+	return action
+}
+
+// typedArgs decode the params into a new params struct and return the arguments of the method.
+func typedArgs(m reflect.Value, paramsType reflect.Type, ctx moleculer.Context, params moleculer.Payload) ([]reflect.Value, error) {
+	target := reflect.New(paramsType)
+	if paramsType.Kind() == reflect.Ptr {
+		target.Elem().Set(reflect.New(paramsType.Elem()))
+	}
+	if params != nil && params.Exists() {
+		if err := params.Unmarshal(target.Interface()); err != nil {
+			return nil, err
+		}
+	}
+	args := []reflect.Value{target.Elem()}
+	if m.Type().NumIn() == 2 {
+		context := reflect.New(contextType).Elem()
+		if ctx != nil {
+			context.Set(reflect.ValueOf(ctx))
+		}
+		args = []reflect.Value{context, target.Elem()}
+	}
+	return args, nil
+}
+
+// typedReturn return the first result of the method, or the error when the last result is a non nil error.
+func typedReturn(out []reflect.Value) interface{} {
+	if len(out) == 0 {
+		return nil
+	}
+	last := out[len(out)-1]
+	if last.Type() == errorType {
+		if !last.IsNil() {
+			return last.Interface()
+		}
+		out = out[:len(out)-1]
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out[0].Interface()
+}
+
+// cacheSettings return the cache settings of the params struct, like moleculer-js: the fields with
+// the tag cache:"key" are the cache keys and a blank field can set the ttl in seconds:
+//
+//	_ struct{} `cache:"ttl:60"`
+//
+// It returns nil when the struct has no cache tags.
+func cacheSettings(structType reflect.Type) map[string]interface{} {
+	keys := []string{}
+	var settings map[string]interface{}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag, hasTag := field.Tag.Lookup("cache")
+		if !hasTag {
+			continue
+		}
+		if settings == nil {
+			settings = map[string]interface{}{}
+		}
+		if field.Name == "_" {
+			for _, option := range strings.Split(tag, "|") {
+				if strings.HasPrefix(option, "ttl:") {
+					if ttl, err := strconv.Atoi(strings.TrimPrefix(option, "ttl:")); err == nil {
+						settings["ttl"] = ttl
+					}
+				}
+			}
+			continue
+		}
+		if tag == "key" {
+			name := strings.Split(field.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				name = field.Name
+			}
+			keys = append(keys, name)
+		}
+	}
+	if settings != nil {
+		settings["keys"] = keys
+	}
+	return settings
+}
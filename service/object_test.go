@@ -0,0 +1,76 @@
+package service
+
+import (
+	"errors"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/payload"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+type getUserParams struct {
+	_       struct{} `cache:"ttl:60"`
+	ID      int      `json:"id" validate:"min:1" cache:"key"`
+	Details bool     `json:"details"`
+}
+
+type usersObject struct {
+	created []string
+}
+
+func (u usersObject) Name() string {
+	return "users"
+}
+
+func (u *usersObject) Get(ctx moleculer.Context, params getUserParams) (map[string]interface{}, error) {
+	if params.ID == 404 {
+		return nil, errors.New("user not found")
+	}
+	return map[string]interface{}{"id": params.ID, "details": params.Details}, nil
+}
+
+func (u *usersObject) UserCreatedEvent(params getUserParams) {
+	u.created = append(u.created, "created")
+}
+
+var _ = Describe("Service objects", func() {
+
+	It("eventName() should return the event name with dots", func() {
+		Expect(eventName("UserCreatedEvent")).Should(Equal("user.created"))
+		Expect(eventName("OrderEvent")).Should(Equal("order"))
+		Expect(isEventMethod("Event")).Should(BeFalse())
+	})
+
+	It("should create the actions with a params struct and the events of the methods", func() {
+		schema, err := objToSchema(usersObject{})
+		Expect(err).Should(BeNil())
+		Expect(len(schema.Actions)).Should(Equal(1))
+		get := schema.Actions[0]
+		Expect(get.Name).Should(Equal("get"))
+		Expect(get.Schema).Should(Equal(moleculer.ObjectSchema{Source: getUserParams{}}))
+		Expect(get.Settings).Should(Equal(map[string]interface{}{
+			"cache": map[string]interface{}{"keys": []string{"id"}, "ttl": 60},
+		}))
+		Expect(get.Handler(nil, payload.New(map[string]interface{}{"id": 10, "details": true}))).Should(Equal(
+			map[string]interface{}{"id": 10, "details": true}))
+		Expect(get.Handler(nil, payload.New(map[string]interface{}{"id": 404}))).Should(MatchError("user not found"))
+
+		Expect(len(schema.Events)).Should(Equal(1))
+		Expect(schema.Events[0].Name).Should(Equal("user.created"))
+	})
+
+	It("should return an error for event methods with an invalid signature", func() {
+		_, err := objToSchema(invalidEventObject{})
+		Expect(err).Should(HaveOccurred())
+	})
+})
+
+type invalidEventObject struct{}
+
+func (invalidEventObject) Name() string {
+	return "invalid"
+}
+
+func (invalidEventObject) TickEvent(count int) {}
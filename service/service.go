@@ -4,7 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/payload"
@@ -16,6 +18,11 @@ type Action struct {
 	fullname string
 	handler  moleculer.ActionHandler
 	params   moleculer.ActionSchema
+	bulkhead *moleculer.BulkheadPolicy
+	settings map[string]interface{}
+	// visibility is one of the moleculer.Visibility constants, empty is published.
+	visibility string
+	cache      *moleculer.CacheSettings
 }
 
 type Event struct {
@@ -104,6 +111,9 @@ type Service struct {
 	stopped      moleculer.LifecycleFunc
 	schema       *moleculer.ServiceSchema
 	logger       *log.Entry
+	// mutex guards the settings, metadata, actions and events of the remote services,
+	// that are updated by the INFO packets while the registry exports them.
+	mutex sync.RWMutex
 }
 
 func (service *Service) Schema() *moleculer.ServiceSchema {
@@ -115,9 +125,17 @@ func (service *Service) NodeID() string {
 }
 
 func (service *Service) Settings() map[string]interface{} {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
 	return service.settings
 }
 
+func (service *Service) Metadata() map[string]interface{} {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
+	return service.metadata
+}
+
 func (service *Service) SetNodeID(nodeID string) {
 	service.nodeID = nodeID
 }
@@ -130,6 +148,45 @@ func (serviceAction *Action) Handler() moleculer.ActionHandler {
 	return serviceAction.handler
 }
 
+// Params return the params schema used to validate the action params.
+func (serviceAction *Action) Params() moleculer.ActionSchema {
+	return serviceAction.params
+}
+
+// Settings return the settings of the action schema, e.g. the cache settings.
+func (serviceAction *Action) Settings() map[string]interface{} {
+	return serviceAction.settings
+}
+
+// Visibility return the visibility of the action, moleculer.VisibilityPublished when not set.
+func (serviceAction *Action) Visibility() string {
+	if serviceAction.visibility == "" {
+		return moleculer.VisibilityPublished
+	}
+	return serviceAction.visibility
+}
+
+// IsLocalOnly return true for the protected and private actions, that are not sent to the other nodes.
+func (serviceAction *Action) IsLocalOnly() bool {
+	visibility := serviceAction.Visibility()
+	return visibility == moleculer.VisibilityProtected || visibility == moleculer.VisibilityPrivate
+}
+
+// Bulkhead return the bulkhead policy of the action schema, nil when not set.
+func (serviceAction *Action) Bulkhead() *moleculer.BulkheadPolicy {
+	return serviceAction.bulkhead
+}
+
+// Cache return the cache settings of the action, nil when its results are not cached.
+func (serviceAction *Action) Cache() *moleculer.CacheSettings {
+	return serviceAction.cache
+}
+
+// SetCache set the cache settings of the action, e.g. the settings of a remote action received in the INFO packet.
+func (serviceAction *Action) SetCache(cache *moleculer.CacheSettings) {
+	serviceAction.cache = cache
+}
+
 func (serviceAction *Action) Name() string {
 	return serviceAction.name
 }
@@ -151,9 +208,31 @@ func (service *Service) Version() string {
 }
 
 func (service *Service) Actions() []Action {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
 	return service.actions
 }
 
+// WrapActions replace the handler of each action with the one returned by wrap,
+// e.g. by the localAction middlewares.
+func (service *Service) WrapActions(wrap func(action Action) moleculer.ActionHandler) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	for index := range service.actions {
+		service.actions[index].handler = wrap(service.actions[index])
+	}
+}
+
+// WrapEvents replace the handler of each event with the one returned by wrap,
+// e.g. by the localEvent middlewares.
+func (service *Service) WrapEvents(wrap func(event Event) moleculer.EventHandler) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
+	for index := range service.events {
+		service.events[index].handler = wrap(service.events[index])
+	}
+}
+
 func (service *Service) Summary() map[string]string {
 	return map[string]string{
 		"name":    service.name,
@@ -163,6 +242,8 @@ func (service *Service) Summary() map[string]string {
 }
 
 func (service *Service) Events() []Event {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
 	return service.events
 }
 
@@ -298,15 +379,15 @@ Mixin Strategy:
 (broken)actions:    	Extend with defaultsDeep. You can disable an action from mixin if you set to false in your service.
 (done)hooks:      	Extend with defaultsDeep.
 (broken)events:     	Concatenate listeners.
+(done)created:    	Concatenate listeners.
+(done)started:    	Concatenate listeners.
+(done)stopped:    	Concatenate listeners.
 TODO:
 name:           Merge & overwrite.
 version:    	Merge & overwrite.
 methods:       	Merge & overwrite.
 mixins:	        Merge & overwrite.
 dependencies:   Merge & overwrite.
-created:    	Concatenate listeners.
-started:    	Concatenate listeners.
-stopped:    	Concatenate listeners.
 */
 
 func applyMixins(service moleculer.ServiceSchema) moleculer.ServiceSchema {
@@ -324,11 +405,16 @@ func applyMixins(service moleculer.ServiceSchema) moleculer.ServiceSchema {
 	return service
 }
 
-func joinVersionToName(name string, version string) string {
-	if version != "" {
-		return fmt.Sprintf("%s.%s", version, name)
+// JoinVersionToName return the full name of a service version, like moleculer-js: numeric
+// versions are prefixed with v (2 -> v2.users) and other versions are kept (staging.users).
+func JoinVersionToName(name string, version string) string {
+	if version == "" {
+		return name
+	}
+	if _, err := strconv.ParseFloat(version, 64); err == nil {
+		return fmt.Sprintf("v%s.%s", version, name)
 	}
-	return name
+	return fmt.Sprintf("%s.%s", version, name)
 }
 
 func CreateServiceEvent(eventName, serviceName, group string, handler moleculer.EventHandler) Event {
@@ -346,18 +432,25 @@ func CreateServiceAction(serviceName string, actionName string, handler molecule
 		fmt.Sprintf("%s.%s", serviceName, actionName),
 		handler,
 		params,
+		nil,
+		nil,
+		"",
+		nil,
 	}
 }
 
 // AsMap export the service info in a map containing: name, version, settings, metadata, nodeID, actions and events.
 // The events list does not contain internal events (events that starts with $) like $node.disconnected.
 func (service *Service) AsMap() map[string]interface{} {
+	service.mutex.RLock()
+	defer service.mutex.RUnlock()
 	serviceInfo := make(map[string]interface{})
 
 	serviceInfo["name"] = service.name
 	serviceInfo["version"] = service.version
+	serviceInfo["fullName"] = service.fullname
 
-	serviceInfo["settings"] = service.settings
+	serviceInfo["settings"] = publicSettings(service.settings)
 	serviceInfo["metadata"] = service.metadata
 	serviceInfo["nodeID"] = service.nodeID
 
@@ -367,11 +460,15 @@ func (service *Service) AsMap() map[string]interface{} {
 
 	actions := map[string]map[string]interface{}{}
 	for _, serviceAction := range service.actions {
-		if !isInternalAction(serviceAction) {
+		if !isInternalAction(serviceAction) && !serviceAction.IsLocalOnly() {
 			actionInfo := make(map[string]interface{})
 			actionInfo["name"] = serviceAction.fullname
 			actionInfo["rawName"] = serviceAction.name
+			actionInfo["visibility"] = serviceAction.Visibility()
 			actionInfo["params"] = paramsAsMap(&serviceAction.params)
+			if cache, exists := serviceAction.settings["cache"]; exists {
+				actionInfo["cache"] = cache
+			}
 			actions[serviceAction.name] = actionInfo
 		}
 	}
@@ -407,8 +504,11 @@ func paramsFromMap(schema interface{}) moleculer.ActionSchema {
 }
 
 // moleculer.ParamsAsMap converts params schema into a map.
+// Only map schemas are exported, struct schemas are not sent to other nodes.
 func paramsAsMap(params *moleculer.ActionSchema) map[string]interface{} {
-	//TODO
+	if schema, isMap := (*params).(map[string]interface{}); isMap {
+		return schema
+	}
 	schema := make(map[string]interface{})
 	return schema
 }
@@ -420,11 +520,29 @@ func (service *Service) AddActionMap(actionInfo map[string]interface{}) *Action
 		nil,
 		paramsFromMap(actionInfo["schema"]),
 	)
+	if cache, exists := actionInfo["cache"]; exists {
+		action.settings = map[string]interface{}{"cache": cache}
+		action.cache = cacheFromSettings(cache)
+	}
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
 	service.actions = append(service.actions, action)
 	return &action
 }
 
+// UpdateFromMap update the cache settings of a remote action from an actionInfo map.
+func (serviceAction *Action) UpdateFromMap(actionInfo map[string]interface{}) {
+	serviceAction.settings = nil
+	serviceAction.cache = nil
+	if cache, exists := actionInfo["cache"]; exists {
+		serviceAction.settings = map[string]interface{}{"cache": cache}
+		serviceAction.cache = cacheFromSettings(cache)
+	}
+}
+
 func (service *Service) RemoveEvent(name string) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
 	var newEvents []Event
 	for _, event := range service.events {
 		if event.name != name {
@@ -435,6 +553,8 @@ func (service *Service) RemoveEvent(name string) {
 }
 
 func (service *Service) RemoveAction(fullname string) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
 	var newActions []Action
 	for _, action := range service.actions {
 		if action.fullname != fullname {
@@ -445,21 +565,50 @@ func (service *Service) RemoveAction(fullname string) {
 }
 
 func (service *Service) AddEventMap(eventInfo map[string]interface{}) *Event {
-	group, exists := eventInfo["group"]
-	if !exists {
+	group, _ := eventInfo["group"].(string)
+	if group == "" {
 		group = service.name
 	}
 	serviceEvent := Event{
 		name:        eventInfo["name"].(string),
 		serviceName: service.name,
-		group:       group.(string),
+		group:       group,
 	}
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
 	service.events = append(service.events, serviceEvent)
 	return &serviceEvent
 }
 
+// publicSettings return the settings sent to the other nodes, without the settings listed
+// in $secureSettings (e.g. "$secureSettings": []string{"apiKey"}) like moleculer-js.
+func publicSettings(settings map[string]interface{}) map[string]interface{} {
+	var secure []string
+	switch value := settings["$secureSettings"].(type) {
+	case []string:
+		secure = value
+	case []interface{}:
+		for _, item := range value {
+			secure = append(secure, fmt.Sprint(item))
+		}
+	}
+	if len(secure) == 0 {
+		return settings
+	}
+	result := make(map[string]interface{}, len(settings))
+	for key, value := range settings {
+		result[key] = value
+	}
+	for _, key := range secure {
+		delete(result, key)
+	}
+	return result
+}
+
 //UpdateFromMap update the service metadata and settings from a serviceInfo map
 func (service *Service) UpdateFromMap(serviceInfo map[string]interface{}) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
 	service.settings = serviceInfo["settings"].(map[string]interface{})
 	service.metadata = serviceInfo["metadata"].(map[string]interface{})
 }
@@ -467,11 +616,15 @@ func (service *Service) UpdateFromMap(serviceInfo map[string]interface{}) {
 // AddSettings add settings to the service. it will be merged with the
 // existing service settings
 func (service *Service) AddSettings(settings map[string]interface{}) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
 	service.settings = MergeSettings(service.settings, settings)
 }
 
 // AddMetadata add metadata to the service. it will be merged with existing service metadata.
 func (service *Service) AddMetadata(metadata map[string]interface{}) {
+	service.mutex.Lock()
+	defer service.mutex.Unlock()
 	service.metadata = MergeSettings(service.metadata, metadata)
 }
 
@@ -482,9 +635,12 @@ func populateFromMap(service *Service, serviceInfo map[string]interface{}) {
 	}
 	service.version = ParseVersion(serviceInfo["version"])
 	service.name = serviceInfo["name"].(string)
-	service.fullname = joinVersionToName(
+	service.fullname = JoinVersionToName(
 		service.name,
 		service.version)
+	if fullname, ok := serviceInfo["fullName"].(string); ok && fullname != "" {
+		service.fullname = fullname
+	}
 
 	service.settings = serviceInfo["settings"].(map[string]interface{})
 	service.metadata = serviceInfo["metadata"].(map[string]interface{})
@@ -506,7 +662,7 @@ func (service *Service) populateFromSchema() {
 	schema := service.schema
 	service.name = schema.Name
 	service.version = schema.Version
-	service.fullname = joinVersionToName(service.name, service.version)
+	service.fullname = JoinVersionToName(service.name, service.version)
 	service.dependencies = schema.Dependencies
 	service.settings = schema.Settings
 	if service.settings == nil {
@@ -522,9 +678,16 @@ func (service *Service) populateFromSchema() {
 		service.actions[index] = CreateServiceAction(
 			service.fullname,
 			actionSchema.Name,
-			actionSchema.Handler,
+			wrapHooks(actionSchema.Handler, hooksFor(schema.Hooks, actionSchema.Name)),
 			actionSchema.Schema,
 		)
+		service.actions[index].bulkhead = actionSchema.Bulkhead
+		service.actions[index].settings = actionSchema.Settings
+		if actionSchema.Cache != nil {
+			service.actions[index].settings = MergeSettings(actionSchema.Settings, map[string]interface{}{"cache": cacheAsSettings(*actionSchema.Cache)})
+		}
+		service.actions[index].cache = cacheFromSettings(service.actions[index].settings["cache"])
+		service.actions[index].visibility = actionSchema.Visibility
 	}
 
 	service.events = make([]Event, len(schema.Events))
@@ -722,6 +885,9 @@ func variableArgsHandler(m reflect.Value) moleculer.ActionHandler {
 func wrapAction(m reflect.Method, v reflect.Value) moleculer.Action {
 	handler := handlerTemplate(v)
 	if handler == nil {
+		if paramsType, typed := typedParams(v); typed {
+			return typedAction(actionName(m.Name), v, paramsType)
+		}
 		handler = variableArgsHandler(v)
 	}
 	return moleculer.Action{
@@ -732,13 +898,14 @@ func wrapAction(m reflect.Method, v reflect.Value) moleculer.Action {
 
 // extractActions uses reflection to get all public methods of the object.
 // from a list of methods decided which ones match the criteria to be an action.
+// Methods with the Event suffix are event handlers, not actions.
 func extractActions(obj interface{}) []moleculer.Action {
 	actions := []moleculer.Action{}
-	value := reflect.ValueOf(obj)
+	value := methodsOf(obj)
 	tp := value.Type()
 	for i := 0; i < tp.NumMethod(); i++ {
 		m := tp.Method(i)
-		if validActionName(m.Name) {
+		if validActionName(m.Name) && !isEventMethod(m.Name) {
 			actions = append(actions, wrapAction(m, value.Method(i)))
 		}
 	}
@@ -752,6 +919,11 @@ type HasCreatedNoParams interface {
 	Created()
 }
 
+// HasCreatedWithError is a service that can fail when it is created, so the service is not published.
+type HasCreatedWithError interface {
+	Created(moleculer.ServiceSchema, *log.Entry) error
+}
+
 type HasStarted interface {
 	Started(moleculer.BrokerContext, moleculer.ServiceSchema)
 }
@@ -759,6 +931,11 @@ type HasStartedNoParams interface {
 	Started()
 }
 
+// HasStartedWithError is a service that can fail when it is started, so the broker startup fails.
+type HasStartedWithError interface {
+	Started(moleculer.BrokerContext, moleculer.ServiceSchema) error
+}
+
 type HasStopped interface {
 	Stopped(moleculer.BrokerContext, moleculer.ServiceSchema)
 }
@@ -777,6 +954,14 @@ func extractCreated(obj interface{}) moleculer.CreatedFunc {
 			creator2.Created()
 		}
 	}
+	creator3, hasIt3 := obj.(HasCreatedWithError)
+	if hasIt3 {
+		return func(schema moleculer.ServiceSchema, logger *log.Entry) {
+			if err := creator3.Created(schema, logger); err != nil {
+				panic(err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -791,6 +976,14 @@ func extractStarted(obj interface{}) moleculer.LifecycleFunc {
 			starter2.Started()
 		}
 	}
+	starter3, hasIt3 := obj.(HasStartedWithError)
+	if hasIt3 {
+		return func(context moleculer.BrokerContext, schema moleculer.ServiceSchema) {
+			if err := starter3.Started(context, schema); err != nil {
+				panic(err)
+			}
+		}
+	}
 	return nil
 }
 
@@ -836,24 +1029,24 @@ func objToSchema(obj interface{}) (moleculer.ServiceSchema, error) {
 	schema = copyMetadata(obj, schema)
 	schema = copyMixins(obj, schema)
 	schema = copySettings(obj, schema)
-	schema.Actions = mergeActions(extractActions(obj), extractActions(&obj))
+	schema.Actions = extractActions(obj)
+	events, err := extractEvents(obj)
+	if err != nil {
+		return schema, err
+	}
+	schema.Events = append(schema.Events, events...)
 	schema.Created = extractCreated(obj)
 	schema.Started = extractStarted(obj)
 	schema.Stopped = extractStopped(obj)
 	return schema, nil
 }
 
-func mergeActions(actions ...[]moleculer.Action) []moleculer.Action {
-	r := []moleculer.Action{}
-	for _, list := range actions {
-		for _, a := range list {
-			r = append(r, a)
-		}
-	}
-	return r
+// FromObject creates a service based on an object.
+// SchemaFromObject return the schema of a service object, the one used by FromObject.
+func SchemaFromObject(obj interface{}) (moleculer.ServiceSchema, error) {
+	return objToSchema(obj)
 }
 
-// FromObject creates a service based on an object.
 func FromObject(obj interface{}, bkr *moleculer.BrokerDelegates) (*Service, error) {
 	schema, err := objToSchema(obj)
 	if err != nil {
@@ -877,7 +1070,7 @@ func FromSchema(schema moleculer.ServiceSchema, bkr *moleculer.BrokerDelegates)
 		panic(errors.New("Service name can't be empty! Maybe it is not a valid Service schema."))
 	}
 	if service.created != nil {
-		go service.created((*service.schema), service.logger)
+		service.created((*service.schema), service.logger)
 	}
 	return service
 }
@@ -891,20 +1084,37 @@ func CreateServiceFromMap(serviceInfo map[string]interface{}) *Service {
 	return service
 }
 
-// Start called by the broker when the service is starting.
-func (service *Service) Start(context moleculer.BrokerContext) {
+// Start called by the broker when the service is starting. It returns the error
+// of a Started handler that panics, so the broker can fail the startup.
+func (service *Service) Start(context moleculer.BrokerContext) (err error) {
 	if service.started != nil {
 		service.schema.Settings = service.settings
 		service.schema.Metadata = service.metadata
+		defer recoverLifecycle("Started", &err)
 		service.started(context, (*service.schema))
 	}
+	return nil
 }
 
-// Stop called by the broker when the service is stopping.
-func (service *Service) Stop(context moleculer.BrokerContext) {
+// Stop called by the broker when the service is stopping. It returns the error
+// of a Stopped handler that panics.
+func (service *Service) Stop(context moleculer.BrokerContext) (err error) {
 	if service.stopped != nil {
+		defer recoverLifecycle("Stopped", &err)
 		service.stopped(context, (*service.schema))
 	}
+	return nil
+}
+
+// recoverLifecycle recover the panic of a lifecycle handler into the error.
+func recoverLifecycle(handler string, err *error) {
+	if value := recover(); value != nil {
+		if recovered, isError := value.(error); isError {
+			*err = recovered
+		} else {
+			*err = fmt.Errorf("%s handler panic: %v", handler, value)
+		}
+	}
 }
 
 var actionHandlerTemplates = []aHandlerTemplate{
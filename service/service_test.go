@@ -1,6 +1,7 @@
 package service_test
 
 import (
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -32,6 +33,21 @@ func (s *MathService) Sub(a int, b int) int {
 	return a - b
 }
 
+type DatabaseService struct {
+	url string
+}
+
+func (s DatabaseService) Name() string {
+	return "database"
+}
+
+func (s DatabaseService) Started(ctx moleculer.BrokerContext, schema moleculer.ServiceSchema) error {
+	if s.url == "" {
+		return fmt.Errorf("database url is not configured")
+	}
+	return nil
+}
+
 var _ = Describe("moleculer/service", func() {
 
 	moonMixIn := moleculer.Mixin{
@@ -159,4 +175,67 @@ var _ = Describe("moleculer/service", func() {
 		Expect(svc.Name()).Should(Equal(math.Name()))
 	})
 
+	It("Should return the error of the Started handler of an object service", func() {
+		svc, err := service.FromObject(DatabaseService{}, test.DelegatesWithId("test"))
+		Expect(err).Should(BeNil())
+		Expect(svc.Start(nil)).Should(MatchError("database url is not configured"))
+
+		svc, err = service.FromObject(DatabaseService{url: "mongodb://localhost"}, test.DelegatesWithId("test"))
+		Expect(err).Should(BeNil())
+		Expect(svc.Start(nil)).Should(Succeed())
+	})
+
+	It("Should parse the dependencies with versions and constraints", func() {
+		svc := service.FromSchema(moleculer.ServiceSchema{
+			Name:                "checkout",
+			Dependencies:        []string{"users", "orders:v2"},
+			DependsOn:           []moleculer.Dependency{moleculer.Dependency{Name: "payments", MinNodes: 2, RemoteOnly: true}},
+			DependenciesTimeout: 5 * time.Second,
+		}, test.DelegatesWithId("test"))
+
+		Expect(svc.DependsOn()).Should(Equal([]moleculer.Dependency{
+			moleculer.Dependency{Name: "users"},
+			moleculer.Dependency{Name: "orders", Version: "v2"},
+			moleculer.Dependency{Name: "payments", MinNodes: 2, RemoteOnly: true},
+		}))
+		Expect(svc.DependenciesTimeout()).Should(Equal(5 * time.Second))
+	})
+
+	It("Should read the cache settings of the actions and send them to the other nodes", func() {
+		handler := func(ctx moleculer.Context, params moleculer.Payload) interface{} { return nil }
+		svc := service.FromSchema(moleculer.ServiceSchema{
+			Name: "users",
+			Actions: []moleculer.Action{
+				{Name: "get", Handler: handler, Cache: &moleculer.CacheSettings{Enabled: true, Keys: []string{"id", "#tenant"}, TTL: 30 * time.Second}},
+				{Name: "find", Handler: handler, Settings: map[string]interface{}{"cache": map[string]interface{}{"keys": []string{"query"}, "ttl": 60}}},
+				{Name: "count", Handler: handler, Settings: map[string]interface{}{"cache": true}},
+				{Name: "create", Handler: handler, Cache: &moleculer.CacheSettings{Enabled: false}},
+				{Name: "remove", Handler: handler},
+			},
+		}, test.DelegatesWithId("test"))
+		svc.SetNodeID("test")
+		caches := map[string]*moleculer.CacheSettings{}
+		for _, action := range svc.Actions() {
+			caches[action.Name()] = action.Cache()
+		}
+		Expect(caches["get"]).Should(Equal(&moleculer.CacheSettings{Enabled: true, Keys: []string{"id", "#tenant"}, TTL: 30 * time.Second}))
+		Expect(caches["find"]).Should(Equal(&moleculer.CacheSettings{Enabled: true, Keys: []string{"query"}, TTL: time.Minute}))
+		Expect(caches["count"]).Should(Equal(&moleculer.CacheSettings{Enabled: true}))
+		Expect(caches["create"]).Should(BeNil())
+		Expect(caches["remove"]).Should(BeNil())
+
+		info := map[string]interface{}{}
+		encoded, _ := json.Marshal(svc.AsMap())
+		Expect(json.Unmarshal(encoded, &info)).Should(Succeed())
+		remote := service.CreateServiceFromMap(info)
+		remoteCaches := map[string]*moleculer.CacheSettings{}
+		for _, action := range remote.Actions() {
+			remoteCaches[action.Name()] = action.Cache()
+		}
+		Expect(remoteCaches).Should(HaveLen(5))
+		Expect(remoteCaches["get"]).Should(Equal(caches["get"]))
+		Expect(remoteCaches["find"]).Should(Equal(caches["find"]))
+		Expect(remoteCaches["remove"]).Should(BeNil())
+	})
+
 })
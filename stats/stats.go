@@ -0,0 +1,131 @@
+// Package stats reads the usage of the host resources, which is reported
+// to the other nodes in the HEARTBEAT and INFO packets.
+package stats
+
+import (
+	"bufio"
+	"errors"
+	"io/ioutil"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+const (
+	procStat    = "/proc/stat"
+	procMeminfo = "/proc/meminfo"
+)
+
+// cpuSample is the cpu time counters read from /proc/stat.
+type cpuSample struct {
+	total uint64
+	idle  uint64
+}
+
+// Sampler calculate the cpu utilization between two calls. Systems without /proc report zero.
+type Sampler struct {
+	mutex    sync.Mutex
+	previous cpuSample
+	readFile func(name string) ([]byte, error)
+}
+
+func CreateSampler() *Sampler {
+	return &Sampler{readFile: ioutil.ReadFile}
+}
+
+var defaultSampler = CreateSampler()
+
+// System return the stats of the host using the default sampler.
+func System() moleculer.SystemStats {
+	return defaultSampler.Stats()
+}
+
+// Stats return the cpu utilization since the previous call and the memory usage.
+func (sampler *Sampler) Stats() moleculer.SystemStats {
+	result := moleculer.SystemStats{Cores: runtime.NumCPU()}
+	if content, err := sampler.readFile(procStat); err == nil {
+		if sample, err := parseCPUSample(string(content)); err == nil {
+			result.CPU = sampler.utilization(sample)
+		}
+	}
+	if content, err := sampler.readFile(procMeminfo); err == nil {
+		result.MemTotal, result.MemFree = parseMeminfo(string(content))
+	}
+	return result
+}
+
+// utilization return the percentage of non idle cpu time since the previous sample.
+func (sampler *Sampler) utilization(sample cpuSample) int64 {
+	sampler.mutex.Lock()
+	defer sampler.mutex.Unlock()
+	total := sample.total - sampler.previous.total
+	idle := sample.idle - sampler.previous.idle
+	sampler.previous = sample
+	if total == 0 || idle > total {
+		return 0
+	}
+	return int64((total - idle) * 100 / total)
+}
+
+// parseCPUSample parse the aggregated cpu line of /proc/stat:
+// cpu user nice system idle iowait irq softirq steal ...
+func parseCPUSample(content string) (cpuSample, error) {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[0] != "cpu" {
+			continue
+		}
+		sample := cpuSample{}
+		for index, field := range fields[1:] {
+			value, err := strconv.ParseUint(field, 10, 64)
+			if err != nil {
+				return cpuSample{}, err
+			}
+			// guest time is already included in user and nice.
+			if index >= 8 {
+				break
+			}
+			sample.total += value
+			if index == 3 || index == 4 {
+				sample.idle += value
+			}
+		}
+		return sample, nil
+	}
+	return cpuSample{}, errors.New("cpu line not found")
+}
+
+// parseMeminfo return the total and available memory in bytes from /proc/meminfo.
+func parseMeminfo(content string) (total uint64, free uint64) {
+	var memFree uint64
+	available := false
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 2 {
+			continue
+		}
+		value, err := strconv.ParseUint(fields[1], 10, 64)
+		if err != nil {
+			continue
+		}
+		value = value * 1024
+		switch fields[0] {
+		case "MemTotal:":
+			total = value
+		case "MemFree:":
+			memFree = value
+		case "MemAvailable:":
+			free = value
+			available = true
+		}
+	}
+	if !available {
+		free = memFree
+	}
+	return total, free
+}
@@ -0,0 +1,49 @@
+package stats
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stats", func() {
+
+	meminfo := "MemTotal:        2048 kB\nMemFree:          512 kB\nMemAvailable:    1024 kB\n"
+
+	It("should calculate the cpu utilization between samples", func() {
+		stat := "cpu  100 0 100 700 100 0 0 0 0 0\ncpu0 100 0 100 700 100 0 0 0 0 0\n"
+		sampler := CreateSampler()
+		sampler.readFile = func(name string) ([]byte, error) {
+			if name == procStat {
+				return []byte(stat), nil
+			}
+			return []byte(meminfo), nil
+		}
+
+		stats := sampler.Stats()
+		Expect(stats.CPU).Should(Equal(int64(20)))
+		Expect(stats.Cores).Should(BeNumerically(">", 0))
+		Expect(stats.MemTotal).Should(Equal(uint64(2048 * 1024)))
+		Expect(stats.MemFree).Should(Equal(uint64(1024 * 1024)))
+
+		stat = "cpu  250 0 200 750 100 0 0 0 0 0\n"
+		Expect(sampler.Stats().CPU).Should(Equal(int64(83)))
+	})
+
+	It("should use MemFree when MemAvailable is not reported", func() {
+		total, free := parseMeminfo("MemTotal:        2048 kB\nMemFree:          512 kB\n")
+		Expect(total).Should(Equal(uint64(2048 * 1024)))
+		Expect(free).Should(Equal(uint64(512 * 1024)))
+	})
+
+	It("should report zero when the system stats are not available", func() {
+		sampler := CreateSampler()
+		sampler.readFile = func(name string) ([]byte, error) {
+			return nil, errors.New("not found")
+		}
+		stats := sampler.Stats()
+		Expect(stats.CPU).Should(BeZero())
+		Expect(stats.MemTotal).Should(BeZero())
+	})
+})
@@ -0,0 +1,104 @@
+package strategy
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// LatencyStrategy select the endpoint of the node with the lowest latency. The registry sends
+// a PING to each node every PingInterval and the latency of the node is the average of the
+// last SampleCount round-trips. On each selection it compares CollectCount random endpoints
+// and selects the first one under LowLatency, or the fastest one. When the latency of the
+// nodes is not known yet, it selects a random endpoint.
+type LatencyStrategy struct {
+	SampleCount  int
+	LowLatency   time.Duration
+	CollectCount int
+	// Interval between the PINGs to each node, the default is 10 seconds.
+	Interval time.Duration
+
+	mutex   sync.Mutex
+	samples map[string][]time.Duration
+}
+
+// NewLatencyStrategy return a latency strategy with the default options:
+// 5 samples, 10ms low latency, 5 endpoints compared and 10 seconds between PINGs.
+func NewLatencyStrategy() *LatencyStrategy {
+	return &LatencyStrategy{
+		SampleCount:  5,
+		LowLatency:   10 * time.Millisecond,
+		CollectCount: 5,
+		Interval:     10 * time.Second,
+	}
+}
+
+// PingInterval return the interval between the PINGs to each node.
+func (latencyStrategy *LatencyStrategy) PingInterval() time.Duration {
+	if latencyStrategy.Interval <= 0 {
+		return 10 * time.Second
+	}
+	return latencyStrategy.Interval
+}
+
+// AddSample add a round-trip time of the node, keeping the last SampleCount samples.
+func (latencyStrategy *LatencyStrategy) AddSample(nodeID string, latency time.Duration) {
+	latencyStrategy.mutex.Lock()
+	defer latencyStrategy.mutex.Unlock()
+	if latencyStrategy.samples == nil {
+		latencyStrategy.samples = make(map[string][]time.Duration)
+	}
+	sampleCount := latencyStrategy.SampleCount
+	if sampleCount <= 0 {
+		sampleCount = 1
+	}
+	samples := append(latencyStrategy.samples[nodeID], latency)
+	if len(samples) > sampleCount {
+		samples = samples[len(samples)-sampleCount:]
+	}
+	latencyStrategy.samples[nodeID] = samples
+}
+
+// Latency return the average latency of the node, and false when there are no samples.
+func (latencyStrategy *LatencyStrategy) Latency(nodeID string) (time.Duration, bool) {
+	latencyStrategy.mutex.Lock()
+	defer latencyStrategy.mutex.Unlock()
+	samples := latencyStrategy.samples[nodeID]
+	if len(samples) == 0 {
+		return 0, false
+	}
+	var total time.Duration
+	for _, sample := range samples {
+		total += sample
+	}
+	return total / time.Duration(len(samples)), true
+}
+
+func (latencyStrategy *LatencyStrategy) Select(nodes []Selector) *Selector {
+	if len(nodes) == 0 {
+		return nil
+	}
+	collectCount := latencyStrategy.CollectCount
+	if collectCount <= 0 || collectCount > len(nodes) {
+		collectCount = len(nodes)
+	}
+	var selected *Selector
+	var lowest time.Duration
+	for _, index := range rand.Perm(len(nodes))[:collectCount] {
+		latency, known := latencyStrategy.Latency(nodes[index].TargetNodeID())
+		if !known {
+			continue
+		}
+		if latency < latencyStrategy.LowLatency {
+			return &nodes[index]
+		}
+		if selected == nil || latency < lowest {
+			selected = &nodes[index]
+			lowest = latency
+		}
+	}
+	if selected == nil {
+		return &nodes[rand.Intn(len(nodes))]
+	}
+	return selected
+}
@@ -0,0 +1,39 @@
+package strategy
+
+import (
+	"strings"
+	"sync"
+)
+
+// Factory create a new instance of a strategy.
+type Factory func() Strategy
+
+var strategies = map[string]Factory{}
+var strategiesMutex = &sync.RWMutex{}
+
+// Register a strategy factory for a name. The name is matched (case insensitive) against the
+// broker Config.Strategy and the Options.Strategy of the calls. Example: "RoundRobin".
+func Register(name string, factory Factory) {
+	strategiesMutex.Lock()
+	defer strategiesMutex.Unlock()
+	strategies[strings.ToLower(name)] = factory
+}
+
+// Create return a new instance of the strategy registered for the name.
+func Create(name string) (Strategy, bool) {
+	strategiesMutex.RLock()
+	factory, exists := strategies[strings.ToLower(name)]
+	strategiesMutex.RUnlock()
+	if !exists {
+		return nil, false
+	}
+	return factory(), true
+}
+
+func init() {
+	Register("Random", func() Strategy { return RandomStrategy{} })
+	Register("RoundRobin", NewRoundRobinStrategy)
+	Register("WeightedRandom", func() Strategy { return &WeightedRandomStrategy{} })
+	Register("Latency", func() Strategy { return NewLatencyStrategy() })
+	Register("Zone", NewZoneStrategy)
+}
@@ -0,0 +1,141 @@
+package strategy
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// ShardStrategy route the calls with the same shard key to the same node, using consistent
+// hashing: each node has VNodes points in a hash ring and a call goes to the node of the
+// first point after the hash of its key. When a node joins or leaves the cluster only the
+// keys of its points move to other nodes. Calls without the shard key go to a random node.
+// Like the other strategies, the local endpoints are preferred by the registry.
+type ShardStrategy struct {
+	// ShardKey is the path of the key in the params (e.g. "userID" or "user.id"),
+	// or in the meta when it starts with # (e.g. "#tenant").
+	ShardKey string
+	// VNodes is the number of points of each node in the ring, the default is 10.
+	VNodes int
+
+	mutex    sync.Mutex
+	ringKey  string
+	ring     []uint64
+	ringNode map[uint64]string
+}
+
+// NewShardStrategy return a shard strategy for the shard key, with 10 virtual nodes per node.
+func NewShardStrategy(shardKey string) *ShardStrategy {
+	return &ShardStrategy{ShardKey: shardKey, VNodes: 10}
+}
+
+// Select a random node, since the shard key is only known in SelectFor.
+func (shardStrategy *ShardStrategy) Select(nodes []Selector) *Selector {
+	return RandomStrategy{}.Select(nodes)
+}
+
+// SelectFor select the node of the shard key of the call.
+func (shardStrategy *ShardStrategy) SelectFor(context moleculer.BrokerContext, nodes []Selector) *Selector {
+	if len(nodes) == 0 {
+		return nil
+	}
+	key, found := shardStrategy.key(context)
+	if !found {
+		return shardStrategy.Select(nodes)
+	}
+	nodeID := shardStrategy.nodeFor(key, nodes)
+	for index := range nodes {
+		if nodes[index].TargetNodeID() == nodeID {
+			return &nodes[index]
+		}
+	}
+	return shardStrategy.Select(nodes)
+}
+
+// key return the value of the shard key in the params or in the meta.
+func (shardStrategy *ShardStrategy) key(context moleculer.BrokerContext) (string, bool) {
+	if context == nil || shardStrategy.ShardKey == "" {
+		return "", false
+	}
+	value := context.Payload()
+	path := shardStrategy.ShardKey
+	if strings.HasPrefix(path, "#") {
+		value = context.Meta()
+		path = strings.TrimPrefix(path, "#")
+	}
+	for _, field := range strings.Split(path, ".") {
+		if value == nil || !value.IsMap() {
+			return "", false
+		}
+		value = value.Get(field)
+	}
+	if value == nil || value.IsNil() {
+		return "", false
+	}
+	return value.String(), true
+}
+
+// nodeFor return the node of the key in the ring of the nodes.
+func (shardStrategy *ShardStrategy) nodeFor(key string, nodes []Selector) string {
+	shardStrategy.mutex.Lock()
+	defer shardStrategy.mutex.Unlock()
+	shardStrategy.updateRing(nodes)
+	hash := hashOf(key)
+	index := sort.Search(len(shardStrategy.ring), func(i int) bool {
+		return shardStrategy.ring[i] >= hash
+	})
+	if index == len(shardStrategy.ring) {
+		index = 0
+	}
+	return shardStrategy.ringNode[shardStrategy.ring[index]]
+}
+
+// updateRing build the ring again when the nodes change.
+func (shardStrategy *ShardStrategy) updateRing(nodes []Selector) {
+	ids := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		ids = append(ids, node.TargetNodeID())
+	}
+	sort.Strings(ids)
+	ringKey := strings.Join(ids, ",")
+	if ringKey == shardStrategy.ringKey {
+		return
+	}
+	vnodes := shardStrategy.VNodes
+	if vnodes <= 0 {
+		vnodes = 10
+	}
+	ring := make([]uint64, 0, len(ids)*vnodes)
+	ringNode := make(map[uint64]string, len(ids)*vnodes)
+	for _, id := range ids {
+		for vnode := 0; vnode < vnodes; vnode++ {
+			point := hashOf(id + ":" + strconv.Itoa(vnode))
+			ring = append(ring, point)
+			ringNode[point] = id
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i] < ring[j]
+	})
+	shardStrategy.ringKey = ringKey
+	shardStrategy.ring = ring
+	shardStrategy.ringNode = ringNode
+}
+
+// hashOf return the hash of the value in the ring. The bits of the fnv hash are mixed,
+// since the fnv hashes of similar values (e.g. node-1:1 and node-1:2) are close.
+func hashOf(value string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(value))
+	result := hash.Sum64()
+	result ^= result >> 33
+	result *= 0xff51afd7ed558ccd
+	result ^= result >> 33
+	result *= 0xc4ceb9fe1a85ec53
+	result ^= result >> 33
+	return result
+}
@@ -0,0 +1,27 @@
+package strategy
+
+import (
+	"math/rand"
+	"time"
+)
+
+// SlowStart return the nodes that receive the call, so the share of the calls sent to a node that joined
+// less than the window ago ramps up over the window: each of these nodes is kept with a probability
+// equal to its uptime divided by the window, and the other nodes are always kept. When no node is
+// kept, all nodes are returned. The uptimeOf function returns how long a node is available.
+func SlowStart(nodes []Selector, window time.Duration, uptimeOf func(nodeID string) time.Duration) []Selector {
+	if window <= 0 || len(nodes) < 2 {
+		return nodes
+	}
+	selected := make([]Selector, 0, len(nodes))
+	for _, node := range nodes {
+		uptime := uptimeOf(node.TargetNodeID())
+		if uptime >= window || rand.Int63n(int64(window)) < int64(uptime) {
+			selected = append(selected, node)
+		}
+	}
+	if len(selected) == 0 {
+		return nodes
+	}
+	return selected
+}
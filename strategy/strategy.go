@@ -1,5 +1,11 @@
 package strategy
 
+import (
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
+
 type Selector interface {
 	TargetNodeID() string
 }
@@ -7,3 +13,26 @@ type Selector interface {
 type Strategy interface {
 	Select([]Selector) *Selector
 }
+
+// LatencyMeter is a strategy that selects the endpoints by the latency of the nodes. The
+// registry sends a PING to each node every PingInterval and adds the round-trip time
+// of each PONG as a sample.
+type LatencyMeter interface {
+	Strategy
+	PingInterval() time.Duration
+	AddSample(nodeID string, latency time.Duration)
+}
+
+// MetadataStrategy is a strategy that selects the endpoints by the metadata of their nodes, e.g. the
+// ZoneStrategy. The registry sets the local node ID and the function that returns the metadata of a node.
+type MetadataStrategy interface {
+	Strategy
+	SetNodeMetadata(localNodeID string, metadataOf func(nodeID string) map[string]interface{})
+}
+
+// ContextStrategy is a strategy that selects the endpoint of an action call using
+// the call context, e.g. the ShardStrategy selects it by a param or meta field.
+type ContextStrategy interface {
+	Strategy
+	SelectFor(context moleculer.BrokerContext, nodes []Selector) *Selector
+}
@@ -1,9 +1,15 @@
 package strategy_test
 
 import (
+	"time"
+
 	log "github.com/sirupsen/logrus"
 
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/context"
+	"github.com/moleculer-go/moleculer/payload"
 	"github.com/moleculer-go/moleculer/strategy"
+	"github.com/moleculer-go/moleculer/test"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 )
@@ -35,3 +41,222 @@ var _ = Describe("Strategy", func() {
 		Expect(thisNode).Should(BeNil())
 	})
 })
+
+var _ = Describe("Latency strategy", func() {
+	list := []strategy.Selector{SelectorImpl{"alpha"}, SelectorImpl{"beta"}, SelectorImpl{"gamma"}}
+
+	It("Should average the last samples of each node", func() {
+		latencyStrategy := strategy.NewLatencyStrategy()
+		latencyStrategy.SampleCount = 2
+		_, known := latencyStrategy.Latency("alpha")
+		Expect(known).Should(BeFalse())
+
+		latencyStrategy.AddSample("alpha", 100*time.Millisecond)
+		latencyStrategy.AddSample("alpha", 20*time.Millisecond)
+		latencyStrategy.AddSample("alpha", 40*time.Millisecond)
+		latency, known := latencyStrategy.Latency("alpha")
+		Expect(known).Should(BeTrue())
+		Expect(latency).Should(Equal(30 * time.Millisecond))
+	})
+
+	It("Should select the node with the lowest latency", func() {
+		latencyStrategy := strategy.NewLatencyStrategy()
+		latencyStrategy.AddSample("alpha", 80*time.Millisecond)
+		latencyStrategy.AddSample("beta", 30*time.Millisecond)
+		latencyStrategy.AddSample("gamma", 50*time.Millisecond)
+		for i := 0; i < 10; i++ {
+			Expect((*latencyStrategy.Select(list)).TargetNodeID()).Should(Equal("beta"))
+		}
+
+		latencyStrategy.SampleCount = 1
+		latencyStrategy.AddSample("gamma", 0)
+		Expect((*latencyStrategy.Select(list)).TargetNodeID()).Should(Equal("gamma"))
+	})
+
+	It("Should select a random node when the latencies are not known", func() {
+		latencyStrategy := strategy.NewLatencyStrategy()
+		selected := map[string]bool{}
+		for i := 0; i < 100; i++ {
+			selected[(*latencyStrategy.Select(list)).TargetNodeID()] = true
+		}
+		Expect(len(selected)).Should(Equal(3))
+		Expect(latencyStrategy.Select([]strategy.Selector{})).Should(BeNil())
+	})
+})
+
+var _ = Describe("Shard strategy", func() {
+	list := []strategy.Selector{SelectorImpl{"alpha"}, SelectorImpl{"beta"}, SelectorImpl{"gamma"}, SelectorImpl{"delta"}}
+	rootContext := context.BrokerContext(test.DelegatesWithIdAndConfig("shard-node", moleculer.Config{}))
+	callFor := func(params map[string]interface{}) moleculer.BrokerContext {
+		return rootContext.ChildActionContext("users.get", payload.New(params))
+	}
+	selectFor := func(shardStrategy *strategy.ShardStrategy, ctx moleculer.BrokerContext, nodes []strategy.Selector) string {
+		return (*shardStrategy.SelectFor(ctx, nodes)).TargetNodeID()
+	}
+
+	It("Should select the same node for the same shard key", func() {
+		shardStrategy := strategy.NewShardStrategy("user.id")
+		selected := map[string]bool{}
+		for id := 0; id < 100; id++ {
+			ctx := callFor(map[string]interface{}{"user": map[string]interface{}{"id": id}})
+			nodeID := selectFor(shardStrategy, ctx, list)
+			Expect(selectFor(shardStrategy, ctx, list)).Should(Equal(nodeID))
+			selected[nodeID] = true
+		}
+		Expect(len(selected)).Should(Equal(4))
+	})
+
+	It("Should move only the keys of a node that left", func() {
+		shardStrategy := strategy.NewShardStrategy("userID")
+		shardStrategy.VNodes = 50
+		withoutBeta := []strategy.Selector{SelectorImpl{"alpha"}, SelectorImpl{"gamma"}, SelectorImpl{"delta"}}
+		for id := 0; id < 100; id++ {
+			ctx := callFor(map[string]interface{}{"userID": id})
+			before := selectFor(shardStrategy, ctx, list)
+			after := selectFor(shardStrategy, ctx, withoutBeta)
+			if before != "beta" {
+				Expect(after).Should(Equal(before))
+			}
+			Expect(after).ShouldNot(Equal("beta"))
+		}
+	})
+
+	It("Should read the shard key from the meta and select a node without the key", func() {
+		shardStrategy := strategy.NewShardStrategy("#tenant")
+		ctx := rootContext.ChildActionContext("users.get", payload.Empty(), moleculer.Options{
+			Meta: payload.New(map[string]interface{}{"tenant": "acme"}),
+		})
+		nodeID := selectFor(shardStrategy, ctx, list)
+		for i := 0; i < 10; i++ {
+			Expect(selectFor(shardStrategy, ctx, list)).Should(Equal(nodeID))
+		}
+
+		Expect(shardStrategy.SelectFor(callFor(map[string]interface{}{}), list)).ShouldNot(BeNil())
+		Expect(shardStrategy.SelectFor(ctx, []strategy.Selector{})).Should(BeNil())
+	})
+})
+
+var _ = Describe("Weighted random strategy", func() {
+	list := []strategy.Selector{SelectorImpl{"alpha"}, SelectorImpl{"beta"}, SelectorImpl{"gamma"}}
+
+	It("Should select the nodes proportionally to their weights", func() {
+		weightedStrategy := &strategy.WeightedRandomStrategy{Weights: map[string]int{"alpha": 8, "gamma": 0}}
+		counts := map[string]int{}
+		for i := 0; i < 900; i++ {
+			counts[(*weightedStrategy.Select(list)).TargetNodeID()]++
+		}
+		Expect(counts["gamma"]).Should(Equal(0))
+		Expect(counts["alpha"]).Should(BeNumerically(">", 600))
+		Expect(counts["beta"]).Should(BeNumerically(">", 30))
+	})
+
+	It("Should select a random node when all weights are 0", func() {
+		weightedStrategy := &strategy.WeightedRandomStrategy{DefaultWeight: 2, Weights: map[string]int{"alpha": 0, "beta": 0, "gamma": 0}}
+		Expect(weightedStrategy.Select(list)).ShouldNot(BeNil())
+		Expect(weightedStrategy.Select([]strategy.Selector{})).Should(BeNil())
+	})
+})
+
+var _ = Describe("Zone strategy", func() {
+	list := []strategy.Selector{SelectorImpl{"alpha"}, SelectorImpl{"beta"}, SelectorImpl{"gamma"}, SelectorImpl{"delta"}}
+	metadata := map[string]map[string]interface{}{
+		"local": {"zone": "eu-1a", "region": "eu-1"},
+		"alpha": {"zone": "us-1a", "region": "us-1"},
+		"beta":  {"zone": "eu-1a", "region": "eu-1"},
+		"gamma": {"zone": "eu-1a", "region": "eu-1"},
+		"delta": {"zone": "eu-1b", "region": "eu-1"},
+	}
+	metadataOf := func(nodeID string) map[string]interface{} {
+		return metadata[nodeID]
+	}
+	counts := func(stg strategy.Strategy, nodes []strategy.Selector) map[string]int {
+		result := map[string]int{}
+		for i := 0; i < 100; i++ {
+			result[(*stg.Select(nodes)).TargetNodeID()]++
+		}
+		return result
+	}
+
+	It("Should select the nodes in the zone of the local node", func() {
+		zoneStrategy := strategy.NewZoneStrategy().(*strategy.ZoneStrategy)
+		zoneStrategy.SetNodeMetadata("local", metadataOf)
+		selected := counts(zoneStrategy, list)
+		Expect(selected).Should(HaveLen(2))
+		Expect(selected["beta"]).Should(BeNumerically(">", 0))
+		Expect(selected["gamma"]).Should(BeNumerically(">", 0))
+	})
+
+	It("Should fall back to the region and then to any zone", func() {
+		zoneStrategy := strategy.NewZoneStrategy().(*strategy.ZoneStrategy)
+		zoneStrategy.SetNodeMetadata("local", metadataOf)
+		Expect(counts(zoneStrategy, []strategy.Selector{list[0], list[3]})).Should(Equal(map[string]int{"delta": 100}))
+		Expect(counts(zoneStrategy, list[:1])).Should(Equal(map[string]int{"alpha": 100}))
+
+		zoneStrategy.SetNodeMetadata("unknown", metadataOf)
+		Expect(counts(zoneStrategy, list)).Should(HaveLen(4))
+		Expect(zoneStrategy.Select([]strategy.Selector{})).Should(BeNil())
+	})
+
+	It("Should select the nodes of the zone with the wrapped strategy", func() {
+		zoneStrategy := strategy.ZoneAware(strategy.NewRoundRobinStrategy(), "region").(*strategy.ZoneStrategy)
+		zoneStrategy.SetNodeMetadata("local", metadataOf)
+		selected := []string{}
+		for i := 0; i < 3; i++ {
+			selected = append(selected, (*zoneStrategy.Select(list)).TargetNodeID())
+		}
+		Expect(selected).Should(ConsistOf("beta", "gamma", "delta"))
+	})
+})
+
+var _ = Describe("Slow start", func() {
+	nodes := []strategy.Selector{SelectorImpl{"old"}, SelectorImpl{"new"}, SelectorImpl{"warming"}}
+	uptimes := map[string]time.Duration{"old": time.Hour, "new": 0, "warming": 50 * time.Second}
+	uptimeOf := func(nodeID string) time.Duration { return uptimes[nodeID] }
+
+	It("Should keep the nodes that joined before the window and ramp up the others", func() {
+		counts := map[string]int{}
+		for index := 0; index < 1000; index++ {
+			for _, node := range strategy.SlowStart(nodes, 100*time.Second, uptimeOf) {
+				counts[node.TargetNodeID()]++
+			}
+		}
+		Expect(counts["old"]).Should(Equal(1000))
+		Expect(counts["new"]).Should(Equal(0))
+		Expect(counts["warming"]).Should(BeNumerically("~", 500, 100))
+	})
+
+	It("Should return all the nodes when none is kept or the window is zero", func() {
+		newNodes := []strategy.Selector{SelectorImpl{"new"}, SelectorImpl{"new"}}
+		Expect(strategy.SlowStart(newNodes, time.Minute, uptimeOf)).Should(Equal(newNodes))
+		Expect(strategy.SlowStart(nodes, 0, uptimeOf)).Should(Equal(nodes))
+	})
+})
+
+var _ = Describe("Strategy registry", func() {
+	list := []strategy.Selector{SelectorImpl{"alpha"}, SelectorImpl{"beta"}, SelectorImpl{"gamma"}}
+
+	It("Should create the registered strategies by name", func() {
+		stg, exists := strategy.Create("roundrobin")
+		Expect(exists).Should(BeTrue())
+		Expect((*stg.Select(list)).TargetNodeID()).Should(Equal("alpha"))
+		Expect((*stg.Select(list)).TargetNodeID()).Should(Equal("beta"))
+
+		for _, name := range []string{"Random", "WeightedRandom", "Latency", "Zone"} {
+			_, exists := strategy.Create(name)
+			Expect(exists).Should(BeTrue())
+		}
+		_, exists = strategy.Create("Fastest")
+		Expect(exists).Should(BeFalse())
+
+		strategy.Register("First", func() strategy.Strategy { return firstStrategy{} })
+		stg, exists = strategy.Create("FIRST")
+		Expect(exists).Should(BeTrue())
+		Expect(stg).Should(Equal(firstStrategy{}))
+	})
+})
+
+type firstStrategy struct{}
+
+func (firstStrategy) Select(nodes []strategy.Selector) *strategy.Selector {
+	return &nodes[0]
+}
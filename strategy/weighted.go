@@ -0,0 +1,51 @@
+package strategy
+
+import (
+	"math/rand"
+)
+
+// WeightedRandomStrategy select a random node with a probability proportional to its weight,
+// e.g. to send more calls to the bigger nodes. The nodes that are not in Weights have the
+// DefaultWeight, 1 when it is not set, and the nodes with weight 0 are only selected when
+// all nodes have weight 0.
+type WeightedRandomStrategy struct {
+	Weights       map[string]int
+	DefaultWeight int
+}
+
+func (weightedStrategy *WeightedRandomStrategy) weight(nodeID string) int {
+	if weight, exists := weightedStrategy.Weights[nodeID]; exists {
+		return weight
+	}
+	if weightedStrategy.DefaultWeight > 0 {
+		return weightedStrategy.DefaultWeight
+	}
+	return 1
+}
+
+func (weightedStrategy *WeightedRandomStrategy) Select(nodes []Selector) *Selector {
+	if len(nodes) == 0 {
+		return nil
+	}
+	total := 0
+	for _, node := range nodes {
+		if weight := weightedStrategy.weight(node.TargetNodeID()); weight > 0 {
+			total += weight
+		}
+	}
+	if total == 0 {
+		return &nodes[rand.Intn(len(nodes))]
+	}
+	selected := rand.Intn(total)
+	for index, node := range nodes {
+		weight := weightedStrategy.weight(node.TargetNodeID())
+		if weight <= 0 {
+			continue
+		}
+		if selected < weight {
+			return &nodes[index]
+		}
+		selected -= weight
+	}
+	return &nodes[len(nodes)-1]
+}
@@ -0,0 +1,87 @@
+package strategy
+
+import (
+	"math/rand"
+)
+
+// ZoneStrategy select the nodes in the same zone of the local node, read from the metadata of the nodes
+// (Config.Metadata), e.g. {"zone": "eu-west-1a", "region": "eu-west-1"}. The Keys are tried in order:
+// with the default keys it selects a node in the same zone, otherwise in the same region, and only when
+// no node is in the same region it selects a node of any zone. The Strategy selects one of these nodes,
+// a random one when it is nil.
+type ZoneStrategy struct {
+	Keys     []string
+	Strategy Strategy
+
+	localNodeID string
+	metadataOf  func(nodeID string) map[string]interface{}
+}
+
+// NewZoneStrategy return a zone strategy that prefers the nodes in the same zone,
+// then in the same region, and selects a random node among them.
+func NewZoneStrategy() Strategy {
+	return ZoneAware(nil)
+}
+
+// ZoneAware wrap the strategy so it selects only among the nodes in the same zone, or region, of the
+// local node. The default keys are "zone" and "region". Example with the broker StrategyFactory:
+//
+//	StrategyFactory: func() interface{} { return strategy.ZoneAware(strategy.NewRoundRobinStrategy()) }
+func ZoneAware(stg Strategy, keys ...string) Strategy {
+	if len(keys) == 0 {
+		keys = []string{"zone", "region"}
+	}
+	return &ZoneStrategy{Keys: keys, Strategy: stg}
+}
+
+func (zoneStrategy *ZoneStrategy) SetNodeMetadata(localNodeID string, metadataOf func(nodeID string) map[string]interface{}) {
+	zoneStrategy.localNodeID = localNodeID
+	zoneStrategy.metadataOf = metadataOf
+	if metadataStrategy, valid := zoneStrategy.Strategy.(MetadataStrategy); valid {
+		metadataStrategy.SetNodeMetadata(localNodeID, metadataOf)
+	}
+}
+
+func (zoneStrategy *ZoneStrategy) label(nodeID, key string) interface{} {
+	if zoneStrategy.metadataOf == nil {
+		return nil
+	}
+	return zoneStrategy.metadataOf(nodeID)[key]
+}
+
+// candidates return the nodes with the same label of the local node for the first key that matches any node.
+func (zoneStrategy *ZoneStrategy) candidates(nodes []Selector) []int {
+	for _, key := range zoneStrategy.Keys {
+		local := zoneStrategy.label(zoneStrategy.localNodeID, key)
+		if local == nil {
+			continue
+		}
+		matching := []int{}
+		for index, node := range nodes {
+			if zoneStrategy.label(node.TargetNodeID(), key) == local {
+				matching = append(matching, index)
+			}
+		}
+		if len(matching) > 0 {
+			return matching
+		}
+	}
+	return nil
+}
+
+func (zoneStrategy *ZoneStrategy) Select(nodes []Selector) *Selector {
+	if len(nodes) == 0 {
+		return nil
+	}
+	selected := nodes
+	if matching := zoneStrategy.candidates(nodes); matching != nil {
+		selected = make([]Selector, len(matching))
+		for index, position := range matching {
+			selected[index] = nodes[position]
+		}
+	}
+	if zoneStrategy.Strategy == nil {
+		return &selected[rand.Intn(len(selected))]
+	}
+	return zoneStrategy.Strategy.Select(selected)
+}
@@ -1,15 +1,22 @@
 package test
 
-import "time"
+import (
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
 
 type NodeMock struct {
 	UpdateResult          bool
 	ID                    string
 	IncreaseSequenceCalls int
+	UnpublishCalls        int
 	HeartBeatCalls        int
+	UpdateStatsCalls      int
 	ExportAsMapResult     map[string]interface{}
 	IsAvailableResult     bool
 	IsExpiredResult       bool
+	IsOfflineResult       bool
 	PublishCalls          int
 }
 
@@ -28,10 +35,18 @@ func (node *NodeMock) GetID() string {
 	return node.ID
 }
 
+func (node *NodeMock) Unpublish(name, version string) {
+	node.UnpublishCalls++
+}
+
 func (node *NodeMock) IncreaseSequence() {
 	node.IncreaseSequenceCalls++
 }
 
+func (node *NodeMock) UpdateStats(stats moleculer.SystemStats) {
+	node.UpdateStatsCalls++
+}
+
 func (node *NodeMock) ExportAsMap() map[string]interface{} {
 	return node.ExportAsMapResult
 }
@@ -44,6 +59,9 @@ func (node *NodeMock) HeartBeat(heartbeat map[string]interface{}) {
 func (node *NodeMock) IsExpired(timeout time.Duration) bool {
 	return node.IsExpiredResult
 }
+func (node *NodeMock) IsOffline(timeout time.Duration) bool {
+	return node.IsOfflineResult
+}
 func (node *NodeMock) Publish(service map[string]interface{}) {
 	node.PublishCalls++
 }
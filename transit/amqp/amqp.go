@@ -9,11 +9,15 @@ import (
 	log "github.com/sirupsen/logrus"
 	"github.com/streadway/amqp"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	DurationNotDefined = time.Duration(-1)
+
+	// confirmTimeout is how long PublishConfirm waits for the broker acknowledgment.
+	confirmTimeout = 10 * time.Second
 )
 
 type safeHandler func(moleculer.Payload) error
@@ -49,6 +53,10 @@ type AmqpTransporter struct {
 	connection              *amqp.Connection
 	channel                 *amqp.Channel
 
+	confirmChannel *amqp.Channel
+	confirmations  chan amqp.Confirmation
+	confirmMutex   sync.Mutex
+
 	nodeID      string
 	subscribers []subscriber
 	bindings    []binding
@@ -117,6 +125,10 @@ func mergeConfigs(baseConfig AmqpOptions, userConfig AmqpOptions) AmqpOptions {
 		baseConfig.Logger = userConfig.Logger
 	}
 
+	if userConfig.Serializer != nil {
+		baseConfig.Serializer = userConfig.Serializer
+	}
+
 	return baseConfig
 }
 
@@ -124,8 +136,9 @@ func CreateAmqpTransporter(options AmqpOptions) transit.Transport {
 	options = mergeConfigs(DefaultConfig, options)
 
 	return &AmqpTransporter{
-		opts:   &options,
-		logger: options.Logger,
+		opts:       &options,
+		logger:     options.Logger,
+		serializer: options.Serializer,
 	}
 }
 
@@ -196,6 +209,8 @@ func (t *AmqpTransporter) doConnect(uri string) (chan *amqp.Error, error) {
 
 	t.logger.Info("AMQP channel is created")
 
+	t.resetConfirmChannel()
+
 	if err := t.channel.Qos(t.opts.Prefetch, 0, false); err != nil {
 		return nil, errors.Wrap(err, "AMQP failed set prefetch count")
 	}
@@ -316,13 +331,7 @@ func (t *AmqpTransporter) Publish(command, nodeID string, message moleculer.Payl
 		t.waitForRecovering()
 	}
 
-	topic := t.topicName(command, nodeID)
-	routingKey := ""
-
-	if nodeID != "" {
-		routingKey = topic
-		topic = ""
-	}
+	topic, routingKey := t.destination(command, nodeID)
 
 	data := t.serializer.PayloadToBytes(message)
 
@@ -335,6 +344,96 @@ func (t *AmqpTransporter) Publish(command, nodeID string, message moleculer.Payl
 	}
 }
 
+// PublishConfirm publish the message on a channel in confirm mode and wait for the broker
+// acknowledgment. An error is returned when the broker nacks the message, the publish fails
+// or the acknowledgment does not arrive in time.
+func (t *AmqpTransporter) PublishConfirm(command, nodeID string, message moleculer.Payload) error {
+	if t.connection == nil {
+		msg := fmt.Sprint("AMQP PublishConfirm() No connection -> command: ", command, " nodeID: ", nodeID)
+		t.logger.Error(msg)
+		return errors.New(msg)
+	}
+
+	if t.connectionRecovering {
+		t.waitForRecovering()
+	}
+
+	t.confirmMutex.Lock()
+	defer t.confirmMutex.Unlock()
+
+	if t.confirmChannel == nil {
+		if err := t.openConfirmChannel(); err != nil {
+			return err
+		}
+	}
+
+	topic, routingKey := t.destination(command, nodeID)
+	msg := amqp.Publishing{
+		Body: t.serializer.PayloadToBytes(message),
+	}
+	if err := t.confirmChannel.Publish(topic, routingKey, false, false, msg); err != nil {
+		t.closeConfirmChannel()
+		return errors.Wrap(err, "AMQP failed to publish")
+	}
+
+	select {
+	case confirmation, ok := <-t.confirmations:
+		if !ok {
+			t.closeConfirmChannel()
+			return errors.New("AMQP confirm channel closed before the acknowledgment")
+		}
+		if !confirmation.Ack {
+			return fmt.Errorf("AMQP broker rejected the message - command: %s, nodeID: %s", command, nodeID)
+		}
+		return nil
+	case <-time.After(confirmTimeout):
+		t.closeConfirmChannel()
+		return fmt.Errorf("AMQP acknowledgment timeout - command: %s, nodeID: %s", command, nodeID)
+	}
+}
+
+func (t *AmqpTransporter) openConfirmChannel() error {
+	channel, err := t.connection.Channel()
+	if err != nil {
+		return errors.Wrap(err, "AMQP failed to create confirm channel")
+	}
+	if err := channel.Confirm(false); err != nil {
+		channel.Close()
+		return errors.Wrap(err, "AMQP failed to put channel in confirm mode")
+	}
+	t.confirmChannel = channel
+	t.confirmations = channel.NotifyPublish(make(chan amqp.Confirmation, 1))
+	return nil
+}
+
+// closeConfirmChannel discard the confirm channel, so pending confirmations of a failed
+// publish are not mistaken for the next message. A new one is opened on the next publish.
+func (t *AmqpTransporter) closeConfirmChannel() {
+	if t.confirmChannel != nil {
+		t.confirmChannel.Close()
+	}
+	t.confirmChannel = nil
+	t.confirmations = nil
+}
+
+// resetConfirmChannel drop the confirm channel of a previous connection.
+func (t *AmqpTransporter) resetConfirmChannel() {
+	t.confirmMutex.Lock()
+	t.confirmChannel = nil
+	t.confirmations = nil
+	t.confirmMutex.Unlock()
+}
+
+// destination return the exchange and routing key used to publish the command.
+// Packets targeting a node are published to the default exchange using the queue name as routing key.
+func (t *AmqpTransporter) destination(command, nodeID string) (topic, routingKey string) {
+	topic = t.topicName(command, nodeID)
+	if nodeID != "" {
+		return "", topic
+	}
+	return topic, ""
+}
+
 func (t *AmqpTransporter) waitForRecovering() {
 	for {
 		if !t.connectionRecovering {
@@ -430,7 +529,7 @@ func (t *AmqpTransporter) getQueueOptions(command string, balancedQueue bool) (a
 
 		// If eventTimeToLive is specified, add to options.
 		if t.opts.EventTimeToLive != DurationNotDefined {
-			args["x-message-ttl"] = int(t.opts.AutoDeleteQueues / time.Millisecond)
+			args["x-message-ttl"] = int(t.opts.EventTimeToLive / time.Millisecond)
 		}
 
 	// Packet types meant for internal use
@@ -439,9 +538,9 @@ func (t *AmqpTransporter) getQueueOptions(command string, balancedQueue bool) (a
 
 		// If heartbeatTimeToLive is specified, add to options.
 		if t.opts.HeartbeatTimeToLive != DurationNotDefined {
-			args["x-message-ttl"] = int(t.opts.AutoDeleteQueues / time.Millisecond)
+			args["x-message-ttl"] = int(t.opts.HeartbeatTimeToLive / time.Millisecond)
 		}
-	case "DISCOVER", "DISCONNECT", "INFO", "PING", "PONG":
+	case "DISCOVER", "DISCONNECT", "INFO", "PING", "PONG", "CANCEL":
 		autoDelete = true
 	}
 
@@ -1,4 +1,4 @@
-package amqp
+package amqp_test
 
 import (
 	"github.com/moleculer-go/moleculer"
@@ -1,9 +1,10 @@
-package amqp
+package amqp_test
 
 import (
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/broker"
 	"github.com/moleculer-go/moleculer/strategy"
+	amqpTransit "github.com/moleculer-go/moleculer/transit/amqp"
 	log "github.com/sirupsen/logrus"
 	"github.com/streadway/amqp"
 	"os"
@@ -73,7 +74,7 @@ func createNode(namespace, name string, service *moleculer.ServiceSchema) *broke
 	logger := log.New()
 	logger.SetLevel(log.FatalLevel)
 
-	amqpConfig := AmqpOptions{
+	amqpConfig := amqpTransit.AmqpOptions{
 		Url: []string{"amqp://" + amqpTestHost() + ":5672"},
 		Logger: logger.WithFields(log.Fields{
 			"Unit Test": true,
@@ -89,7 +90,7 @@ func createNode(namespace, name string, service *moleculer.ServiceSchema) *broke
 			return namespace + "-" + name
 		},
 		TransporterFactory: func() interface{} {
-			return CreateAmqpTransporter(amqpConfig)
+			return amqpTransit.CreateAmqpTransporter(amqpConfig)
 		},
 		StrategyFactory: func() interface{} {
 			return strategy.NewRoundRobinStrategy()
@@ -0,0 +1,32 @@
+package amqp
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AMQP queue options", func() {
+	It("Should apply the configured time to live to event and heartbeat queues", func() {
+		options := mergeConfigs(DefaultConfig, AmqpOptions{
+			EventTimeToLive:     5 * time.Second,
+			HeartbeatTimeToLive: 2 * time.Second,
+			QueueOptions:        map[string]interface{}{"durable": true},
+		})
+		transporter := AmqpTransporter{opts: &options}
+
+		_, durable, _, args := transporter.getQueueOptions("EVENT", false)
+		Expect(durable).Should(BeTrue())
+		Expect(args["x-message-ttl"]).Should(Equal(5000))
+
+		autoDelete, _, _, args := transporter.getQueueOptions("HEARTBEAT", false)
+		Expect(autoDelete).Should(BeTrue())
+		Expect(args["x-message-ttl"]).Should(Equal(2000))
+	})
+
+	It("Should keep the default prefetch when not informed", func() {
+		Expect(mergeConfigs(DefaultConfig, AmqpOptions{}).Prefetch).Should(Equal(1))
+		Expect(mergeConfigs(DefaultConfig, AmqpOptions{Prefetch: 10}).Prefetch).Should(Equal(10))
+	})
+})
@@ -0,0 +1,258 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/serializer"
+	"github.com/moleculer-go/moleculer/transit"
+	"github.com/segmentio/kafka-go"
+	log "github.com/sirupsen/logrus"
+)
+
+type KafkaTransporter struct {
+	prefix     string
+	nodeID     string
+	brokers    []string
+	logger     *log.Entry
+	serializer serializer.Serializer
+	connected  bool
+
+	readers      []*kafka.Reader
+	writers      map[string]*kafka.Writer
+	writersMutex *sync.Mutex
+	cancel       context.CancelFunc
+	ctx          context.Context
+	// connectionMutex guard the connected flag, the context and the readers, used by the transit and the connection goroutines.
+	connectionMutex *sync.Mutex
+}
+
+type KafkaOptions struct {
+	URL        string
+	Logger     *log.Entry
+	Serializer serializer.Serializer
+}
+
+// balancedCommands are consumed by a shared consumer group, so the messages
+// are load balanced between the consumers of the same topic.
+var balancedCommands = map[string]bool{
+	"REQ":    true,
+	"REQB":   true,
+	"EVENT":  true,
+	"EVENTB": true,
+}
+
+func CreateKafkaTransporter(options KafkaOptions) transit.Transport {
+	return &KafkaTransporter{
+		brokers:         parseBrokers(options.URL),
+		logger:          options.Logger,
+		serializer:      options.Serializer,
+		readers:         []*kafka.Reader{},
+		writers:         map[string]*kafka.Writer{},
+		writersMutex:    &sync.Mutex{},
+		connectionMutex: &sync.Mutex{},
+	}
+}
+
+// parseBrokers return the list of broker addresses from a url like: kafka://host1:9092,host2:9092
+func parseBrokers(url string) []string {
+	brokers := []string{}
+	for _, item := range transit.ParseURLs(url) {
+		item = strings.TrimPrefix(item, "kafka://")
+		if item != "" {
+			brokers = append(brokers, item)
+		}
+	}
+	return brokers
+}
+
+func (t *KafkaTransporter) Connect() chan error {
+	endChan := make(chan error)
+	go func() {
+		t.logger.Debug("Kafka Connect() - brokers: ", t.brokers)
+		if len(t.brokers) == 0 {
+			endChan <- errors.New("Invalid Kafka url. No brokers informed.")
+			return
+		}
+		// any of the brokers can be used to bootstrap, so try them in order.
+		var err error
+		for _, broker := range t.brokers {
+			var conn *kafka.Conn
+			conn, err = kafka.Dial("tcp", broker)
+			if err == nil {
+				conn.Close()
+				break
+			}
+			t.logger.Warn("Kafka Connect() - Could not reach broker: ", broker, " error: ", err)
+		}
+		if err != nil {
+			t.logger.Error("Kafka Connect() - Error: ", err, " brokers: ", t.brokers)
+			endChan <- errors.New(fmt.Sprint("Error connection to Kafka. error: ", err, " brokers: ", t.brokers))
+			return
+		}
+
+		t.connectionMutex.Lock()
+		t.ctx, t.cancel = context.WithCancel(context.Background())
+		t.connected = true
+		t.connectionMutex.Unlock()
+		t.logger.Info("Connected to ", t.brokers)
+		endChan <- nil
+	}()
+	return endChan
+}
+
+func (t *KafkaTransporter) Disconnect() chan error {
+	endChan := make(chan error)
+	go func() {
+		t.connectionMutex.Lock()
+		if !t.connected {
+			t.connectionMutex.Unlock()
+			endChan <- nil
+			return
+		}
+		t.connected = false
+		t.cancel()
+		readers := t.readers
+		t.readers = []*kafka.Reader{}
+		t.connectionMutex.Unlock()
+		for _, reader := range readers {
+			if err := reader.Close(); err != nil {
+				t.logger.Error(err)
+			}
+		}
+		t.writersMutex.Lock()
+		for topic, writer := range t.writers {
+			if err := writer.Close(); err != nil {
+				t.logger.Error(err)
+			}
+			delete(t.writers, topic)
+		}
+		t.writersMutex.Unlock()
+		endChan <- nil
+	}()
+	return endChan
+}
+
+func (t *KafkaTransporter) topicName(command string, nodeID string) string {
+	parts := []string{t.prefix, command}
+	if nodeID != "" {
+		parts = append(parts, nodeID)
+	}
+	return strings.Join(parts, ".")
+}
+
+// groupID return the consumer group for a subscription. Balanced commands share
+// the group of the topic, broadcast topics get a group per node so every node
+// receives all the messages.
+func (t *KafkaTransporter) groupID(command string, nodeID string) string {
+	topic := t.topicName(command, nodeID)
+	if balancedCommands[command] {
+		return topic
+	}
+	return topic + "." + t.nodeID
+}
+
+// connection return the context of the connection, and false when not connected.
+func (t *KafkaTransporter) connection() (context.Context, bool) {
+	t.connectionMutex.Lock()
+	defer t.connectionMutex.Unlock()
+	return t.ctx, t.connected
+}
+
+func (t *KafkaTransporter) Subscribe(command, nodeID string, handler transit.TransportHandler) {
+	t.connectionMutex.Lock()
+	if !t.connected {
+		t.connectionMutex.Unlock()
+		msg := fmt.Sprint("kafka.Subscribe() No connection :( -> command: ", command, " nodeID: ", nodeID)
+		t.logger.Warn(msg)
+		panic(errors.New(msg))
+	}
+
+	topic := t.topicName(command, nodeID)
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers: t.brokers,
+		GroupID: t.groupID(command, nodeID),
+		Topic:   topic,
+	})
+	t.readers = append(t.readers, reader)
+	ctx := t.ctx
+	t.connectionMutex.Unlock()
+
+	go func() {
+		for {
+			msg, err := reader.ReadMessage(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					t.logger.Error("Error reading topic: ", topic, " error: ", err)
+				}
+				return
+			}
+			payload := t.serializer.BytesToPayload(&msg.Value)
+			t.logger.Debug(fmt.Sprintf("Incoming %s packet from '%s'", topic, payload.Get("sender").String()))
+			handler(payload)
+		}
+	}()
+}
+
+func (t *KafkaTransporter) writer(topic string) *kafka.Writer {
+	t.writersMutex.Lock()
+	defer t.writersMutex.Unlock()
+	writer, exists := t.writers[topic]
+	if !exists {
+		writer = kafka.NewWriter(kafka.WriterConfig{
+			Brokers: t.brokers,
+			Topic:   topic,
+		})
+		t.writers[topic] = writer
+	}
+	return writer
+}
+
+func (t *KafkaTransporter) Publish(command, nodeID string, message moleculer.Payload) {
+	if err := t.publish(command, nodeID, message); err != nil {
+		panic(err)
+	}
+}
+
+// PublishConfirm publish the message and return the error instead of panicking.
+// The kafka writer is synchronous and only returns after the brokers acknowledged the message.
+func (t *KafkaTransporter) PublishConfirm(command, nodeID string, message moleculer.Payload) error {
+	return t.publish(command, nodeID, message)
+}
+
+func (t *KafkaTransporter) publish(command, nodeID string, message moleculer.Payload) error {
+	ctx, connected := t.connection()
+	if !connected {
+		msg := fmt.Sprint("kafka.Publish() No connection :( -> command: ", command, " nodeID: ", nodeID)
+		t.logger.Warn(msg)
+		return errors.New(msg)
+	}
+
+	topic := t.topicName(command, nodeID)
+	t.logger.Debug("kafka.Publish() command: ", command, " topic: ", topic, " nodeID: ", nodeID)
+	t.logger.Trace("message: \n", message, "\n - end")
+	err := t.writer(topic).WriteMessages(ctx, kafka.Message{
+		Value: t.serializer.PayloadToBytes(message),
+	})
+	if err != nil {
+		t.logger.Error("Error on publish: error: ", err, " command: ", command, " topic: ", topic)
+		return err
+	}
+	return nil
+}
+
+func (t *KafkaTransporter) SetPrefix(prefix string) {
+	t.prefix = prefix
+}
+
+func (t *KafkaTransporter) SetNodeID(nodeID string) {
+	t.nodeID = nodeID
+}
+
+func (t *KafkaTransporter) SetSerializer(serializer serializer.Serializer) {
+	t.serializer = serializer
+}
@@ -0,0 +1,51 @@
+package kafka
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Kafka Transit", func() {
+	logger := log.WithField("unit test pkg", "kafka_test")
+
+	It("should parse the list of brokers from the url", func() {
+		Expect(parseBrokers("kafka://localhost:9092")).Should(Equal([]string{"localhost:9092"}))
+		Expect(parseBrokers("kafka://host1:9092,host2:9092")).Should(Equal([]string{"host1:9092", "host2:9092"}))
+		Expect(parseBrokers("kafka://")).Should(BeEmpty())
+	})
+
+	It("should use balanced consumer groups for requests and events", func() {
+		transporter := CreateKafkaTransporter(KafkaOptions{URL: "kafka://localhost:9092", Logger: logger}).(*KafkaTransporter)
+		transporter.SetPrefix("MOL")
+		transporter.SetNodeID("node-1")
+
+		Expect(transporter.topicName("REQ", "node-2")).Should(Equal("MOL.REQ.node-2"))
+		Expect(transporter.groupID("REQ", "node-2")).Should(Equal("MOL.REQ.node-2"))
+		Expect(transporter.groupID("EVENT", "node-2")).Should(Equal("MOL.EVENT.node-2"))
+	})
+
+	It("should use a unique consumer group per node for broadcast topics", func() {
+		transporter := CreateKafkaTransporter(KafkaOptions{URL: "kafka://localhost:9092", Logger: logger}).(*KafkaTransporter)
+		transporter.SetPrefix("MOL-staging")
+		transporter.SetNodeID("node-1")
+
+		Expect(transporter.groupID("INFO", "")).Should(Equal("MOL-staging.INFO.node-1"))
+		Expect(transporter.groupID("HEARTBEAT", "")).Should(Equal("MOL-staging.HEARTBEAT.node-1"))
+
+		transporter.SetNodeID("node-2")
+		Expect(transporter.groupID("HEARTBEAT", "")).Should(Equal("MOL-staging.HEARTBEAT.node-2"))
+	})
+
+	It("should fail to connect without brokers", func() {
+		transporter := CreateKafkaTransporter(KafkaOptions{URL: "kafka://", Logger: logger})
+		Expect(<-transporter.Connect()).Should(HaveOccurred())
+	})
+
+	It("should panic when publishing without a connection", func() {
+		transporter := CreateKafkaTransporter(KafkaOptions{URL: "kafka://localhost:9092", Logger: logger})
+		Expect(func() {
+			transporter.Publish("INFO", "", nil)
+		}).Should(Panic())
+	})
+})
@@ -28,6 +28,24 @@ type MemoryTransporter struct {
 	instanceID string
 	logger     *log.Entry
 	memory     *SharedMemory
+	serializer serializer.Serializer
+}
+
+var buses = map[string]*SharedMemory{}
+var busesMutex = &sync.Mutex{}
+
+// Bus return the shared memory registered with the name, creating it on the first call.
+// Transporters created with the same bus deliver messages to each other, so brokers
+// in the same process can discover and call each other.
+func Bus(name string) *SharedMemory {
+	busesMutex.Lock()
+	defer busesMutex.Unlock()
+	memory, exists := buses[name]
+	if !exists {
+		memory = &SharedMemory{handlers: make(map[string][]Subscription), mutex: &sync.Mutex{}}
+		buses[name] = memory
+	}
+	return memory
 }
 
 func Create(logger *log.Entry, memory *SharedMemory) MemoryTransporter {
@@ -48,8 +66,10 @@ func (transporter *MemoryTransporter) SetPrefix(prefix string) {
 func (transporter *MemoryTransporter) SetNodeID(nodeID string) {
 }
 
+// SetSerializer set the serializer used to encode the messages, so the subscribers
+// receive a copy of the message as they would from a network transport.
 func (transporter *MemoryTransporter) SetSerializer(serializer serializer.Serializer) {
-
+	transporter.serializer = serializer
 }
 
 func (transporter *MemoryTransporter) Connect() chan error {
@@ -66,6 +86,7 @@ func (transporter *MemoryTransporter) Disconnect() chan error {
 	endChan := make(chan error)
 	transporter.logger.Debug("[Mem-Trans-", transporter.instanceID, "] -> Disconnecting() ...")
 
+	transporter.memory.mutex.Lock()
 	newHandlers := map[string][]Subscription{}
 	for key, subscriptions := range transporter.memory.handlers {
 		keep := []Subscription{}
@@ -77,6 +98,7 @@ func (transporter *MemoryTransporter) Disconnect() chan error {
 		newHandlers[key] = keep
 	}
 	transporter.memory.handlers = newHandlers
+	transporter.memory.mutex.Unlock()
 
 	go func() {
 		endChan <- nil
@@ -115,11 +137,23 @@ func (transporter *MemoryTransporter) Publish(command, nodeID string, message mo
 	transporter.memory.mutex.Lock()
 	subscriptions, exists := transporter.memory.handlers[topic]
 	transporter.memory.mutex.Unlock()
-	if exists {
-		for _, subscription := range subscriptions {
-			if subscription.active {
-				go subscription.handler(message)
-			}
+	if !exists {
+		return
+	}
+	var bts []byte
+	if transporter.serializer != nil {
+		bts = transporter.serializer.PayloadToBytes(message)
+	}
+	for _, subscription := range subscriptions {
+		if !subscription.active {
+			continue
+		}
+		if transporter.serializer != nil {
+			data := make([]byte, len(bts))
+			copy(data, bts)
+			go subscription.handler(transporter.serializer.BytesToPayload(&data))
+		} else {
+			go subscription.handler(message)
 		}
 	}
 }
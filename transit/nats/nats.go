@@ -37,7 +37,15 @@ type NATSOptions struct {
 func natsOptions(options NATSOptions) *nats.Options {
 	opts := nats.GetDefaultOptions()
 	opts.Name = options.Name
-	opts.Url = options.URL
+	// multiple urls are tried in the informed order when connecting and reconnecting.
+	urls := transit.ParseURLs(options.URL)
+	if len(urls) > 0 {
+		opts.Url = urls[0]
+	}
+	if len(urls) > 1 {
+		opts.Servers = urls
+		opts.NoRandomize = true
+	}
 	opts.AllowReconnect = options.AllowReconnect
 	if options.ReconnectWait != 0 {
 		opts.ReconnectWait = options.ReconnectWait
@@ -94,6 +102,16 @@ func (t *NatsTransporter) Disconnect() chan error {
 	return endChan
 }
 
+// OnDisconnect register a handler called when the NATS client gives up reconnecting.
+func (t *NatsTransporter) OnDisconnect(handler func(err error)) {
+	if t.conn == nil {
+		return
+	}
+	t.conn.SetClosedHandler(func(conn *nats.Conn) {
+		handler(conn.LastError())
+	})
+}
+
 func (t *NatsTransporter) topicName(command string, nodeID string) string {
 	parts := []string{t.prefix, command}
 	if nodeID != "" {
@@ -148,5 +166,5 @@ func (t *NatsTransporter) SetNodeID(nodeID string) {
 }
 
 func (t *NatsTransporter) SetSerializer(serializer serializer.Serializer) {
-	// Ignored while transporter initialized in pubsub function
+	t.serializer = serializer
 }
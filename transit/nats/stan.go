@@ -135,16 +135,29 @@ func (transporter *StanTransporter) Subscribe(command string, nodeID string, han
 }
 
 func (transporter *StanTransporter) Publish(command, nodeID string, message moleculer.Payload) {
+	if err := transporter.publish(command, nodeID, message); err != nil {
+		panic(err)
+	}
+}
+
+// PublishConfirm publish the message and return the error instead of panicking.
+// The streaming server acknowledges each message before connection.Publish returns.
+func (transporter *StanTransporter) PublishConfirm(command, nodeID string, message moleculer.Payload) error {
+	return transporter.publish(command, nodeID, message)
+}
+
+func (transporter *StanTransporter) publish(command, nodeID string, message moleculer.Payload) error {
 	if transporter.connection == nil {
 		msg := fmt.Sprint("stan.Publish() No connection :( -> command: ", command, " nodeID: ", nodeID)
 		transporter.logger.Warn(msg)
-		panic(errors.New(msg))
+		return errors.New(msg)
 	}
 	topic := topicName(transporter, command, nodeID)
 	transporter.logger.Trace("stan.Publish() command: ", command, " nodeID: ", nodeID, " message: \n", message, "\n - end")
 	err := transporter.connection.Publish(topic, transporter.serializer.PayloadToBytes(message))
 	if err != nil {
 		transporter.logger.Error("Error on publish: error: ", err, " command: ", command, " topic: ", topic)
-		panic(err)
+		return err
 	}
+	return nil
 }
@@ -0,0 +1,104 @@
+package pubsub
+
+import (
+	gocontext "context"
+	"fmt"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/payload"
+	"github.com/moleculer-go/moleculer/transit"
+)
+
+func runningKey(sender, id string) string {
+	return sender + "." + id
+}
+
+// watchCancellation resolve the pending request with an error and notify the executing node
+// when the caller context is cancelled or times out before the response arrives. It stops
+// watching when the request completes.
+func (pubsub *PubSub) watchCancellation(context moleculer.BrokerContext, completed chan struct{}) {
+	done := context.Done()
+	if done == nil {
+		return
+	}
+	go func() {
+		select {
+		case <-done:
+		case <-completed:
+			return
+		}
+		pubsub.pendingRequestsMutex.Lock()
+		p, exists := pubsub.pendingRequests[context.ID()]
+		if exists {
+			pubsub.deletePendingRequest(context.ID())
+		}
+		pubsub.pendingRequestsMutex.Unlock()
+		if !exists {
+			return
+		}
+		p.timer.Stop()
+		pubsub.sendCancel(context)
+		(*p.resultChan) <- payload.New(cancellationError(context))
+	}()
+}
+
+func cancellationError(context moleculer.BrokerContext) error {
+	if context.Err() == gocontext.DeadlineExceeded {
+		return moleculer.NewRequestTimeoutError(context.ActionName(), context.TargetNodeID())
+	}
+	return fmt.Errorf("RequestCancelled: Request to call '%s' action on '%s' node was cancelled.", context.ActionName(), context.TargetNodeID())
+}
+
+// sendCancel publish a CANCEL packet, so the executing node can abort the action.
+// Nodes using the protocol version 3 do not support it.
+func (pubsub *PubSub) sendCancel(context moleculer.BrokerContext) {
+	targetNodeID := context.TargetNodeID()
+	if pubsub.protocolFor(targetNodeID) == "3" {
+		return
+	}
+	values := map[string]interface{}{
+		"sender": pubsub.broker.LocalNode().GetID(),
+		"id":     context.ID(),
+	}
+	pubsub.setVersion("CANCEL", targetNodeID, values)
+	message, err := pubsub.serializer.MapToPayload(&values)
+	if err != nil {
+		pubsub.logger.Error("sendCancel() Error serializing the cancel packet - id: ", context.ID(), " error: ", err)
+		return
+	}
+	if err := pubsub.safePublish("CANCEL", targetNodeID, message); err != nil {
+		pubsub.logger.Warn("sendCancel() Error publishing the cancel packet - id: ", context.ID(), " error: ", err)
+	}
+}
+
+// runRequest invoke the action, keeping the context while it runs so a CANCEL packet can cancel it.
+func (pubsub *PubSub) runRequest(sender string, context moleculer.BrokerContext) moleculer.Payload {
+	key := runningKey(sender, context.ID())
+	pubsub.runningRequests.Store(key, context)
+	defer func() {
+		pubsub.runningRequests.Delete(key)
+		context.Cancel()
+	}()
+	return <-pubsub.broker.ActionDelegate(context)
+}
+
+// cancelHandler cancel the context of the running request.
+func (pubsub *PubSub) cancelHandler() transit.TransportHandler {
+	return func(message moleculer.Payload) {
+		key := runningKey(message.Get("sender").String(), message.Get("id").String())
+		if running, exists := pubsub.runningRequests.Load(key); exists {
+			pubsub.logger.Debug("cancelHandler() cancelling request: ", key)
+			running.(moleculer.BrokerContext).Cancel()
+		}
+	}
+}
+
+// cancelRequestsByNode cancel the running requests sent by a node that disconnected.
+func (pubsub *PubSub) cancelRequestsByNode(nodeID string) {
+	pubsub.runningRequests.Range(func(key, value interface{}) bool {
+		if value.(moleculer.BrokerContext).TargetNodeID() == nodeID {
+			value.(moleculer.BrokerContext).Cancel()
+		}
+		return true
+	})
+}
@@ -0,0 +1,186 @@
+package pubsub
+
+import (
+	"encoding/base64"
+	"sync"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/serializer"
+	"github.com/moleculer-go/moleculer/transit"
+	"github.com/moleculer-go/moleculer/util"
+	"github.com/moleculer-go/moleculer/version"
+	log "github.com/sirupsen/logrus"
+)
+
+// chunkOverhead is the space reserved for the chunk packet fields.
+const chunkOverhead = 256
+
+type chunkBuffer struct {
+	parts    [][]byte
+	received int
+	created  time.Time
+}
+
+// chunkedTransport wraps a transport and splits the packets bigger than maxPacketSize
+// in chunks, which are reassembled on the receiving side before calling the handler.
+type chunkedTransport struct {
+	transit.Transport
+	maxPacketSize int
+	nodeID        string
+	serializer    serializer.Serializer
+	timeout       time.Duration
+	logger        *log.Entry
+
+	buffers      map[string]*chunkBuffer
+	buffersMutex *sync.Mutex
+}
+
+func createChunkedTransport(transport transit.Transport, maxPacketSize int, timeout time.Duration, logger *log.Entry) *chunkedTransport {
+	return &chunkedTransport{
+		Transport:     transport,
+		maxPacketSize: maxPacketSize,
+		timeout:       timeout,
+		logger:        logger,
+		buffers:       map[string]*chunkBuffer{},
+		buffersMutex:  &sync.Mutex{},
+	}
+}
+
+// chunkSize return the size of the data in each chunk, considering the base64 encoding and packet fields.
+func (t *chunkedTransport) chunkSize() int {
+	size := (t.maxPacketSize - chunkOverhead) * 3 / 4
+	if size <= 0 {
+		size = t.maxPacketSize / 2
+	}
+	if size <= 0 {
+		size = 1
+	}
+	return size
+}
+
+func (t *chunkedTransport) Publish(command, nodeID string, message moleculer.Payload) {
+	t.publish(command, nodeID, message, func(message moleculer.Payload) error {
+		t.Transport.Publish(command, nodeID, message)
+		return nil
+	})
+}
+
+// PublishConfirm split the packet like Publish and wait for the acknowledgment of each chunk.
+func (t *chunkedTransport) PublishConfirm(command, nodeID string, message moleculer.Payload) error {
+	confirm, ok := t.Transport.(transit.ConfirmTransport)
+	if !ok {
+		return t.publish(command, nodeID, message, func(message moleculer.Payload) error {
+			return publishRecovered(func() { t.Transport.Publish(command, nodeID, message) })
+		})
+	}
+	return t.publish(command, nodeID, message, func(message moleculer.Payload) error {
+		return confirm.PublishConfirm(command, nodeID, message)
+	})
+}
+
+func (t *chunkedTransport) publish(command, nodeID string, message moleculer.Payload, send func(moleculer.Payload) error) error {
+	data := t.serializer.PayloadToBytes(message)
+	if len(data) <= t.maxPacketSize {
+		return send(message)
+	}
+	size := t.chunkSize()
+	total := (len(data) + size - 1) / size
+	id := util.RandomString(12)
+	t.logger.Debug("chunkedTransport.Publish() splitting packet of ", len(data), " bytes in ", total, " chunks - command: ", command)
+	for index := 0; index < total; index++ {
+		end := (index + 1) * size
+		if end > len(data) {
+			end = len(data)
+		}
+		chunk := map[string]interface{}{
+			"sender":     t.nodeID,
+			"ver":        version.MoleculerProtocol(),
+			"chunkID":    id,
+			"chunkIndex": index,
+			"chunkTotal": total,
+			"chunkData":  base64.StdEncoding.EncodeToString(data[index*size : end]),
+		}
+		chunkMessage, err := t.serializer.MapToPayload(&chunk)
+		if err != nil {
+			t.logger.Error("chunkedTransport.Publish() Error creating chunk - error: ", err)
+			return err
+		}
+		if err := send(chunkMessage); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *chunkedTransport) Subscribe(command, nodeID string, handler transit.TransportHandler) {
+	t.Transport.Subscribe(command, nodeID, func(message moleculer.Payload) {
+		if !message.Get("chunkID").Exists() {
+			handler(message)
+			return
+		}
+		if complete := t.addChunk(message); complete != nil {
+			handler(t.serializer.BytesToPayload(&complete))
+		}
+	})
+}
+
+// addChunk store the chunk and return the reassembled packet when all chunks arrived.
+func (t *chunkedTransport) addChunk(message moleculer.Payload) []byte {
+	id := message.Get("sender").String() + "." + message.Get("chunkID").String()
+	index := message.Get("chunkIndex").Int()
+	total := message.Get("chunkTotal").Int()
+	data, err := base64.StdEncoding.DecodeString(message.Get("chunkData").String())
+	if err != nil || index < 0 || index >= total {
+		t.logger.Error("chunkedTransport - Invalid chunk received from: ", message.Get("sender").String())
+		return nil
+	}
+
+	t.buffersMutex.Lock()
+	defer t.buffersMutex.Unlock()
+	t.removeExpired()
+	buffer, exists := t.buffers[id]
+	if !exists {
+		buffer = &chunkBuffer{parts: make([][]byte, total), created: time.Now()}
+		t.buffers[id] = buffer
+	}
+	if buffer.parts[index] == nil {
+		buffer.parts[index] = data
+		buffer.received++
+	}
+	if buffer.received < total {
+		return nil
+	}
+	delete(t.buffers, id)
+	result := []byte{}
+	for _, part := range buffer.parts {
+		result = append(result, part...)
+	}
+	return result
+}
+
+// removeExpired discard incomplete packets older than the timeout.
+func (t *chunkedTransport) removeExpired() {
+	for id, buffer := range t.buffers {
+		if time.Since(buffer.created) > t.timeout {
+			t.logger.Warn("chunkedTransport - Discarding incomplete packet: ", id)
+			delete(t.buffers, id)
+		}
+	}
+}
+
+func (t *chunkedTransport) SetNodeID(nodeID string) {
+	t.nodeID = nodeID
+	t.Transport.SetNodeID(nodeID)
+}
+
+func (t *chunkedTransport) SetSerializer(serializer serializer.Serializer) {
+	t.serializer = serializer
+	t.Transport.SetSerializer(serializer)
+}
+
+func (t *chunkedTransport) OnDisconnect(handler func(err error)) {
+	if notifier, ok := t.Transport.(transit.DisconnectNotifier); ok {
+		notifier.OnDisconnect(handler)
+	}
+}
@@ -0,0 +1,81 @@
+package pubsub
+
+import (
+	"strings"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/payload"
+	"github.com/moleculer-go/moleculer/serializer"
+	"github.com/moleculer-go/moleculer/transit"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Chunked transport", func() {
+	logger := log.WithField("unit", "chunking")
+
+	It("should split big packets in chunks and reassemble them", func() {
+		loopback := &loopbackTransporter{handlers: map[string]transit.TransportHandler{}}
+		transport := createChunkedTransport(loopback, 300, time.Second, logger)
+		transport.SetNodeID("node-1")
+		transport.SetSerializer(serializer.CreateJSONSerializer(logger))
+
+		received := []moleculer.Payload{}
+		transport.Subscribe("EVENT", "node-1", func(message moleculer.Payload) {
+			received = append(received, message)
+		})
+
+		text := strings.Repeat("moleculer ", 200)
+		transport.Publish("EVENT", "node-1", payload.New(map[string]interface{}{"sender": "node-1", "text": text}))
+		Expect(loopback.published).Should(BeNumerically(">", 1))
+		Expect(received).Should(HaveLen(1))
+		Expect(received[0].Get("text").String()).Should(Equal(text))
+		Expect(transport.buffers).Should(BeEmpty())
+
+		loopback.published = 0
+		transport.Publish("EVENT", "node-1", payload.New(map[string]interface{}{"sender": "node-1", "text": "small"}))
+		Expect(loopback.published).Should(Equal(1))
+		Expect(received).Should(HaveLen(2))
+		Expect(received[1].Get("text").String()).Should(Equal("small"))
+	})
+
+	It("should discard incomplete packets after the timeout", func() {
+		transport := createChunkedTransport(&loopbackTransporter{}, 300, time.Millisecond, logger)
+		transport.addChunk(payload.New(map[string]interface{}{
+			"sender": "node-1", "chunkID": "a", "chunkIndex": 0, "chunkTotal": 2, "chunkData": "YWJj",
+		}))
+		Expect(transport.buffers).Should(HaveLen(1))
+		time.Sleep(5 * time.Millisecond)
+		transport.addChunk(payload.New(map[string]interface{}{
+			"sender": "node-1", "chunkID": "b", "chunkIndex": 0, "chunkTotal": 2, "chunkData": "YWJj",
+		}))
+		Expect(transport.buffers).Should(HaveLen(1))
+		Expect(transport.buffers).Should(HaveKey("node-1.b"))
+	})
+})
+
+// loopbackTransporter delivers the published messages to the local subscribers, serializing them as a real transport.
+type loopbackTransporter struct {
+	mockTransporter
+	serializer serializer.Serializer
+	handlers   map[string]transit.TransportHandler
+	published  int
+	sizes      []int
+}
+
+func (t *loopbackTransporter) Subscribe(command string, nodeID string, handler transit.TransportHandler) {
+	t.handlers[command+nodeID] = handler
+}
+
+func (t *loopbackTransporter) Publish(command, nodeID string, message moleculer.Payload) {
+	t.published++
+	bts := t.serializer.PayloadToBytes(message)
+	t.sizes = append(t.sizes, len(bts))
+	t.handlers[command+nodeID](t.serializer.BytesToPayload(&bts))
+}
+
+func (t *loopbackTransporter) SetSerializer(serializer serializer.Serializer) {
+	t.serializer = serializer
+}
@@ -0,0 +1,44 @@
+package pubsub
+
+import (
+	"sync"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/serializer"
+	"github.com/moleculer-go/moleculer/test"
+	"github.com/moleculer-go/moleculer/transit"
+	log "github.com/sirupsen/logrus"
+)
+
+// createPubSub create a PubSub for the unit tests, without connecting the transport. The nodes in availableNodes
+// are known, and available when true. The requests do not time out unless the config sets a RequestTimeout.
+func createPubSub(transport transit.Transport, config moleculer.Config, availableNodes map[string]bool) (*PubSub, *moleculer.BrokerDelegates) {
+	logger := log.WithField("unit", "pubsub")
+	localNode := test.NodeMock{ID: "test"}
+	if config.RequestTimeout == 0 {
+		config.RequestTimeout = time.Minute
+	}
+	delegates := &moleculer.BrokerDelegates{
+		Config: config,
+		LocalNode: func() moleculer.Node {
+			return &localNode
+		},
+		Logger: func(name string, value string) *log.Entry { return log.WithField(name, value) },
+		KnowNode: func(nodeID string) bool {
+			_, known := availableNodes[nodeID]
+			return known
+		},
+		IsNodeAvailable: func(nodeID string) bool {
+			return availableNodes[nodeID]
+		},
+	}
+	return &PubSub{
+		logger:               logger,
+		serializer:           serializer.CreateJSONSerializer(logger),
+		transport:            transport,
+		broker:               delegates,
+		pendingRequests:      map[string]pendingRequest{},
+		pendingRequestsMutex: &sync.Mutex{},
+	}, delegates
+}
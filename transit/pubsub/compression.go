@@ -0,0 +1,46 @@
+package pubsub
+
+import (
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/serializer"
+	"github.com/moleculer-go/moleculer/transit"
+)
+
+// compressionTransport wraps a transport and marks the packets sent to the nodes that support
+// the configured compression as compressible, so the compression serializer compresses them.
+type compressionTransport struct {
+	transit.Transport
+	supported func(nodeID string) bool
+}
+
+func createCompressionTransport(transport transit.Transport, supported func(nodeID string) bool) *compressionTransport {
+	return &compressionTransport{
+		Transport: transport,
+		supported: supported,
+	}
+}
+
+func (t *compressionTransport) Publish(command, nodeID string, message moleculer.Payload) {
+	t.Transport.Publish(command, nodeID, t.compressible(nodeID, message))
+}
+
+func (t *compressionTransport) PublishConfirm(command, nodeID string, message moleculer.Payload) error {
+	confirm, ok := t.Transport.(transit.ConfirmTransport)
+	if !ok {
+		return publishRecovered(func() { t.Publish(command, nodeID, message) })
+	}
+	return confirm.PublishConfirm(command, nodeID, t.compressible(nodeID, message))
+}
+
+func (t *compressionTransport) compressible(nodeID string, message moleculer.Payload) moleculer.Payload {
+	if t.supported(nodeID) {
+		return serializer.Compressible(message)
+	}
+	return message
+}
+
+func (t *compressionTransport) OnDisconnect(handler func(err error)) {
+	if notifier, ok := t.Transport.(transit.DisconnectNotifier); ok {
+		notifier.OnDisconnect(handler)
+	}
+}
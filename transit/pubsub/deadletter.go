@@ -0,0 +1,113 @@
+package pubsub
+
+import (
+	"fmt"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// publishPacket serialize and publish a RES or EVENT packet. Packets that can't be
+// delivered (unknown target node, serialization or publish error) are sent to the
+// dead-letter handler/topic. It returns false when the packet was not delivered.
+func (pubsub *PubSub) publishPacket(command, targetNodeID string, values map[string]interface{}) bool {
+	if targetNodeID != "" && !pubsub.isNodeReachable(command, targetNodeID) {
+		pubsub.deadLetter(command, targetNodeID, values, fmt.Errorf("Node '%s' is not connected.", targetNodeID))
+		return false
+	}
+	message, err := pubsub.serializer.MapToPayload(&values)
+	if err != nil {
+		pubsub.deadLetter(command, targetNodeID, values, fmt.Errorf("Error trying to serialize the packet. Error: %s", err))
+		return false
+	}
+	if err := pubsub.safePublish(command, targetNodeID, message); err != nil {
+		pubsub.deadLetter(command, targetNodeID, values, err)
+		return false
+	}
+	return true
+}
+
+// isNodeReachable check in the registry if the target node is connected. The response of a request
+// is also published when the requester is not registered yet, since its REQ can arrive before its INFO.
+func (pubsub *PubSub) isNodeReachable(command, nodeID string) bool {
+	if pubsub.broker.IsNodeAvailable(nodeID) {
+		return true
+	}
+	return command == "RES" && !pubsub.broker.KnowNode(nodeID)
+}
+
+// safePublish publish the message returning the transporter panics as errors.
+func (pubsub *PubSub) safePublish(command, nodeID string, message moleculer.Payload) error {
+	return publishRecovered(func() {
+		pubsub.currentTransport().Publish(command, nodeID, message)
+	})
+}
+
+// publishRecovered call publish and return the panic of the transporter as an error.
+func publishRecovered(publish func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if recovered, isError := r.(error); isError {
+				err = recovered
+			} else {
+				err = fmt.Errorf("%v", r)
+			}
+		}
+	}()
+	publish()
+	return nil
+}
+
+// deadLetter route an undeliverable packet to the configured handler and topic.
+// Without any of them the packet is logged and dropped.
+func (pubsub *PubSub) deadLetter(command, nodeID string, values map[string]interface{}, err error) {
+	pubsub.logger.Warn("Undeliverable packet - command: ", command, " nodeID: ", nodeID, " error: ", err)
+	letter := moleculer.DeadLetter{
+		Command: command,
+		NodeID:  nodeID,
+		Packet:  values,
+		Error:   err,
+	}
+	config := pubsub.broker.Config
+	if config.DeadLetterHandler == nil && config.DeadLetterTopic == "" {
+		pubsub.logger.Error("Undeliverable packet dropped, no dead-letter handler or topic configured - packet: ", values)
+		return
+	}
+	if config.DeadLetterHandler != nil {
+		pubsub.invokeDeadLetterHandler(config.DeadLetterHandler, letter)
+	}
+	if config.DeadLetterTopic != "" {
+		pubsub.publishDeadLetter(config.DeadLetterTopic, letter)
+	}
+}
+
+func (pubsub *PubSub) invokeDeadLetterHandler(handler moleculer.DeadLetterHandler, letter moleculer.DeadLetter) {
+	defer func() {
+		if r := recover(); r != nil {
+			pubsub.logger.Error("Dead-letter handler panicked - command: ", letter.Command, " error: ", r)
+		}
+	}()
+	handler(letter)
+}
+
+// publishDeadLetter publish the dead letter to the topic. When the packet itself can't be
+// serialized, the dead letter is published without it.
+func (pubsub *PubSub) publishDeadLetter(topic string, letter moleculer.DeadLetter) {
+	values := map[string]interface{}{
+		"sender":  pubsub.broker.LocalNode().GetID(),
+		"command": letter.Command,
+		"nodeID":  letter.NodeID,
+		"error":   letter.Error.Error(),
+		"packet":  letter.Packet,
+	}
+	message, err := pubsub.serializer.MapToPayload(&values)
+	if err != nil {
+		delete(values, "packet")
+		if message, err = pubsub.serializer.MapToPayload(&values); err != nil {
+			pubsub.logger.Error("Error serializing dead letter - command: ", letter.Command, " error: ", err)
+			return
+		}
+	}
+	if err := pubsub.safePublish(topic, "", message); err != nil {
+		pubsub.logger.Error("Error publishing dead letter to topic: ", topic, " error: ", err)
+	}
+}
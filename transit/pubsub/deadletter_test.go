@@ -0,0 +1,111 @@
+package pubsub
+
+import (
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/context"
+	"github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Dead letters", func() {
+	var availableNodes map[string]bool
+
+	BeforeEach(func() {
+		availableNodes = map[string]bool{}
+	})
+
+	It("should send events to unknown nodes to the dead-letter handler", func() {
+		letters := []moleculer.DeadLetter{}
+		transport := &mockTransporter{}
+		pubsub, delegates := createPubSub(transport, moleculer.Config{
+			DeadLetterHandler: func(letter moleculer.DeadLetter) {
+				letters = append(letters, letter)
+			},
+		}, availableNodes)
+		eventContext := context.BrokerContext(delegates).ChildEventContext("user.created", payload.New("John"), nil, false)
+		eventContext.SetTargetNodeID("gone-node")
+		pubsub.Emit(eventContext)
+
+		Expect(transport.PublishCalled).Should(BeFalse())
+		Expect(letters).Should(HaveLen(1))
+		Expect(letters[0].Command).Should(Equal("EVENT"))
+		Expect(letters[0].NodeID).Should(Equal("gone-node"))
+		Expect(letters[0].Packet["event"]).Should(Equal("user.created"))
+		Expect(letters[0].Error.Error()).Should(Equal("Node 'gone-node' is not connected."))
+
+		availableNodes["gone-node"] = true
+		pubsub.Emit(eventContext)
+		Expect(transport.PublishCalled).Should(BeTrue())
+		Expect(letters).Should(HaveLen(1))
+	})
+
+	It("should send responses that fail to publish to the dead-letter handler instead of panicking", func() {
+		letters := []moleculer.DeadLetter{}
+		pubsub, delegates := createPubSub(&panicTransporter{}, moleculer.Config{
+			DeadLetterHandler: func(letter moleculer.DeadLetter) {
+				letters = append(letters, letter)
+			},
+		}, availableNodes)
+		availableNodes["caller-node"] = true
+		actionContext := context.BrokerContext(delegates).ChildActionContext("math.add", payload.Empty())
+		actionContext.SetTargetNodeID("caller-node")
+
+		Expect(func() { pubsub.sendResponse(actionContext, payload.New(10)) }).ShouldNot(Panic())
+		Expect(letters).Should(HaveLen(1))
+		Expect(letters[0].Command).Should(Equal("RES"))
+		Expect(letters[0].Packet["data"]).Should(Equal(10))
+		Expect(letters[0].Error).Should(MatchError("No connection"))
+	})
+
+	It("should send responses to disconnected nodes to the dead-letter handler", func() {
+		letters := []moleculer.DeadLetter{}
+		transport := &mockTransporter{}
+		pubsub, delegates := createPubSub(transport, moleculer.Config{
+			DeadLetterHandler: func(letter moleculer.DeadLetter) {
+				letters = append(letters, letter)
+			},
+		}, availableNodes)
+		actionContext := context.BrokerContext(delegates).ChildActionContext("math.add", payload.Empty())
+		actionContext.SetTargetNodeID("caller-node")
+
+		// the REQ of a requester can arrive before its INFO
+		pubsub.sendResponse(actionContext, payload.New(10))
+		Expect(transport.PublishCalled).Should(BeTrue())
+		Expect(letters).Should(BeEmpty())
+
+		availableNodes["caller-node"] = false
+		transport.PublishCalled = false
+		pubsub.sendResponse(actionContext, payload.New(10))
+		Expect(transport.PublishCalled).Should(BeFalse())
+		Expect(letters).Should(HaveLen(1))
+		Expect(letters[0].Command).Should(Equal("RES"))
+		Expect(letters[0].Error.Error()).Should(Equal("Node 'caller-node' is not connected."))
+	})
+
+	It("should publish dead letters to the dead-letter topic", func() {
+		transport := &recordingTransporter{}
+		pubsub, delegates := createPubSub(transport, moleculer.Config{DeadLetterTopic: "DEADLETTER"}, availableNodes)
+		eventContext := context.BrokerContext(delegates).ChildEventContext("user.created", payload.New("John"), nil, false)
+		eventContext.SetTargetNodeID("gone-node")
+		pubsub.Emit(eventContext)
+
+		Expect(transport.commands).Should(Equal([]string{"DEADLETTER"}))
+		letter := transport.messages[0]
+		Expect(letter.Get("command").String()).Should(Equal("EVENT"))
+		Expect(letter.Get("nodeID").String()).Should(Equal("gone-node"))
+		Expect(letter.Get("error").String()).Should(Equal("Node 'gone-node' is not connected."))
+		Expect(letter.Get("packet").Get("data").String()).Should(Equal("John"))
+	})
+})
+
+type recordingTransporter struct {
+	mockTransporter
+	commands []string
+	messages []moleculer.Payload
+}
+
+func (t *recordingTransporter) Publish(command, nodeID string, message moleculer.Payload) {
+	t.commands = append(t.commands, command)
+	t.messages = append(t.messages, message)
+}
@@ -0,0 +1,116 @@
+package pubsub
+
+import (
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/middleware"
+	"github.com/moleculer-go/moleculer/serializer"
+	"github.com/moleculer-go/moleculer/transit"
+)
+
+// meteredTransport wraps a transport and invoke the transitPacketSent and transitPacketReceived
+// middlewares for each packet, with the command, size in bytes and serialization time.
+// The size and time are measured by meteredSerializer when the transport serializes the packet.
+type meteredTransport struct {
+	transit.Transport
+	middlewareHandler moleculer.MiddlewareHandlerFunc
+}
+
+func createMeteredTransport(transport transit.Transport, middlewareHandler moleculer.MiddlewareHandlerFunc) *meteredTransport {
+	return &meteredTransport{
+		Transport:         transport,
+		middlewareHandler: middlewareHandler,
+	}
+}
+
+func (t *meteredTransport) Publish(command, nodeID string, message moleculer.Payload) {
+	packet := &outgoingPacket{Payload: message}
+	t.Transport.Publish(command, nodeID, packet)
+	t.packetSent(command, nodeID, packet)
+}
+
+func (t *meteredTransport) PublishConfirm(command, nodeID string, message moleculer.Payload) error {
+	confirm, ok := t.Transport.(transit.ConfirmTransport)
+	if !ok {
+		return publishRecovered(func() { t.Publish(command, nodeID, message) })
+	}
+	packet := &outgoingPacket{Payload: message}
+	if err := confirm.PublishConfirm(command, nodeID, packet); err != nil {
+		return err
+	}
+	t.packetSent(command, nodeID, packet)
+	return nil
+}
+
+// packetSent invoke the transitPacketSent middlewares when the transport serialized the packet.
+func (t *meteredTransport) packetSent(command, nodeID string, packet *outgoingPacket) {
+	if !packet.serialized {
+		return
+	}
+	t.middlewareHandler("transitPacketSent", middleware.TransitPacketParams{Command: command, NodeID: nodeID, Bytes: packet.bytes, Duration: packet.duration})
+}
+
+func (t *meteredTransport) Subscribe(command, nodeID string, handler transit.TransportHandler) {
+	t.Transport.Subscribe(command, nodeID, func(message moleculer.Payload) {
+		params := middleware.TransitPacketParams{Command: command, NodeID: message.Get("sender").String()}
+		if packet, isPacket := message.(incomingPacket); isPacket {
+			message = packet.Payload
+			params.Bytes = packet.bytes
+			params.Duration = packet.duration
+		}
+		t.middlewareHandler("transitPacketReceived", params)
+		handler(message)
+	})
+}
+
+func (t *meteredTransport) SetSerializer(serializer serializer.Serializer) {
+	t.Transport.SetSerializer(meteredSerializer{serializer})
+}
+
+func (t *meteredTransport) OnDisconnect(handler func(err error)) {
+	if notifier, ok := t.Transport.(transit.DisconnectNotifier); ok {
+		notifier.OnDisconnect(handler)
+	}
+}
+
+// outgoingPacket is a packet published by the meteredTransport, the serializer records its size and serialization time.
+type outgoingPacket struct {
+	moleculer.Payload
+	serialized bool
+	bytes      int
+	duration   time.Duration
+}
+
+// incomingPacket is a packet received by the transport, with its size and deserialization time.
+type incomingPacket struct {
+	moleculer.Payload
+	bytes    int
+	duration time.Duration
+}
+
+// meteredSerializer measure the packets serialized and deserialized by the transport, so they
+// are not serialized again only to be measured.
+type meteredSerializer struct {
+	serializer.Serializer
+}
+
+func (ms meteredSerializer) PayloadToBytes(message moleculer.Payload) []byte {
+	packet, isPacket := message.(*outgoingPacket)
+	if !isPacket {
+		return ms.Serializer.PayloadToBytes(message)
+	}
+	start := time.Now()
+	data := ms.Serializer.PayloadToBytes(packet.Payload)
+	packet.duration = time.Since(start)
+	packet.bytes = len(data)
+	packet.serialized = true
+	return data
+}
+
+func (ms meteredSerializer) BytesToPayload(data *[]byte) moleculer.Payload {
+	start := time.Now()
+	size := len(*data)
+	message := ms.Serializer.BytesToPayload(data)
+	return incomingPacket{message, size, time.Since(start)}
+}
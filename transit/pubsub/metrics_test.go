@@ -0,0 +1,85 @@
+package pubsub
+
+import (
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/middleware"
+	"github.com/moleculer-go/moleculer/payload"
+	"github.com/moleculer-go/moleculer/serializer"
+	"github.com/moleculer-go/moleculer/transit"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Metered transport", func() {
+	logger := log.WithField("unit", "metrics")
+
+	It("should invoke the packet middlewares with the command and size", func() {
+		packets := map[string][]middleware.TransitPacketParams{}
+		loopback := &loopbackTransporter{handlers: map[string]transit.TransportHandler{}}
+		transport := createMeteredTransport(loopback, func(name string, params interface{}) interface{} {
+			packets[name] = append(packets[name], params.(middleware.TransitPacketParams))
+			return params
+		})
+		transport.SetSerializer(serializer.CreateJSONSerializer(logger))
+
+		received := 0
+		transport.Subscribe("EVENT", "node-1", func(message moleculer.Payload) {
+			received++
+		})
+		transport.Publish("EVENT", "node-1", payload.New(map[string]interface{}{"sender": "node-2", "event": "user.created"}))
+
+		Expect(received).Should(Equal(1))
+		Expect(packets["transitPacketSent"]).Should(HaveLen(1))
+		sent := packets["transitPacketSent"][0]
+		Expect(sent.Command).Should(Equal("EVENT"))
+		Expect(sent.NodeID).Should(Equal("node-1"))
+		Expect(sent.Bytes).Should(BeNumerically(">", 0))
+
+		Expect(packets["transitPacketReceived"]).Should(HaveLen(1))
+		Expect(packets["transitPacketReceived"][0].NodeID).Should(Equal("node-2"))
+		Expect(packets["transitPacketReceived"][0].Bytes).Should(Equal(sent.Bytes))
+	})
+
+	It("should measure the packets serialized by the transport without serializing them again", func() {
+		packets := map[string][]middleware.TransitPacketParams{}
+		loopback := &loopbackTransporter{handlers: map[string]transit.TransportHandler{}}
+		transport := createMeteredTransport(loopback, func(name string, params interface{}) interface{} {
+			packets[name] = append(packets[name], params.(middleware.TransitPacketParams))
+			return params
+		})
+		counting := &countingSerializer{Serializer: serializer.CreateJSONSerializer(logger)}
+		transport.SetSerializer(counting)
+
+		var received moleculer.Payload
+		transport.Subscribe("EVENT", "node-1", func(message moleculer.Payload) {
+			received = message
+		})
+		transport.Publish("EVENT", "node-1", payload.New(map[string]interface{}{"sender": "node-2", "event": "user.created"}))
+
+		Expect(counting.serialized).Should(Equal(1))
+		Expect(counting.deserialized).Should(Equal(1))
+		Expect(packets["transitPacketSent"][0].Bytes).Should(Equal(loopback.sizes[0]))
+		Expect(packets["transitPacketReceived"][0].Bytes).Should(Equal(loopback.sizes[0]))
+		_, isPacket := received.(incomingPacket)
+		Expect(isPacket).Should(BeFalse())
+		Expect(received.Get("event").String()).Should(Equal("user.created"))
+	})
+})
+
+// countingSerializer count the packets serialized and deserialized.
+type countingSerializer struct {
+	serializer.Serializer
+	serialized   int
+	deserialized int
+}
+
+func (s *countingSerializer) PayloadToBytes(message moleculer.Payload) []byte {
+	s.serialized++
+	return s.Serializer.PayloadToBytes(message)
+}
+
+func (s *countingSerializer) BytesToPayload(data *[]byte) moleculer.Payload {
+	s.deserialized++
+	return s.Serializer.BytesToPayload(data)
+}
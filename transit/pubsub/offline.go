@@ -0,0 +1,133 @@
+package pubsub
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/payload"
+)
+
+// offlinePacket is a REQ or EVENT packet kept while the transporter is disconnected.
+type offlinePacket struct {
+	command   string
+	nodeID    string
+	requestID string
+	values    map[string]interface{}
+	message   moleculer.Payload
+	expires   time.Time
+}
+
+// isOffline check if the outgoing packets must be buffered: the offline buffer is enabled and
+// the transporter is connecting or reconnecting, or the buffered packets are being published.
+func (pubsub *PubSub) isOffline() bool {
+	config := pubsub.broker.Config
+	return config.OfflineBuffer.Enabled && !config.ReconnectPolicy.Disabled &&
+		(pubsub.flushingOffline.isSet() ||
+			!pubsub.isConnected.isSet() && !pubsub.stopped.isSet() && pubsub.currentTransport() != nil)
+}
+
+// bufferOffline keep the packet until the transporter is connected. It returns false when the transit
+// is not offline anymore and the packet must be published, and an error when the buffer is full.
+func (pubsub *PubSub) bufferOffline(packet offlinePacket) (bool, error) {
+	policy := pubsub.broker.Config.OfflineBuffer
+	pubsub.offlineMutex.Lock()
+	defer pubsub.offlineMutex.Unlock()
+	if !pubsub.isOffline() {
+		return false, nil
+	}
+	if policy.MaxPackets > 0 && len(pubsub.offlinePackets) >= policy.MaxPackets {
+		pubsub.logger.Warn("Offline buffer is full. Buffered packets: ", len(pubsub.offlinePackets), " max: ", policy.MaxPackets)
+		return false, fmt.Errorf("QueueIsFull: The transporter is disconnected and the offline buffer is full. Packet '%s' is rejected.", packet.command)
+	}
+	packet.expires = time.Now().Add(policy.TTL)
+	pubsub.offlinePackets = append(pubsub.offlinePackets, packet)
+	pubsub.logger.Debug("bufferOffline() command: ", packet.command, " nodeID: ", packet.nodeID, " buffered: ", len(pubsub.offlinePackets))
+	return true, nil
+}
+
+// takeOffline remove and return the buffered packets. When the buffer is empty the flush is
+// over and the new packets are published right away.
+func (pubsub *PubSub) takeOffline() []offlinePacket {
+	pubsub.offlineMutex.Lock()
+	defer pubsub.offlineMutex.Unlock()
+	packets := pubsub.offlinePackets
+	pubsub.offlinePackets = nil
+	if len(packets) == 0 {
+		pubsub.flushingOffline.set(false)
+	}
+	return packets
+}
+
+// flushOffline publish the packets buffered while the transporter was disconnected, in the order they were sent.
+// The packets sent during the flush are buffered too, so they are not published before the older ones.
+func (pubsub *PubSub) flushOffline() {
+	for packets := pubsub.takeOffline(); len(packets) > 0; packets = pubsub.takeOffline() {
+		pubsub.logger.Info("PubSub - Publishing ", len(packets), " packets buffered while the transporter was disconnected.")
+		now := time.Now()
+		for _, packet := range packets {
+			expired := pubsub.broker.Config.OfflineBuffer.TTL > 0 && now.After(packet.expires)
+			if packet.command == "REQ" {
+				if expired {
+					pubsub.rejectPendingRequest(packet.requestID, fmt.Errorf("Request '%s' expired in the offline buffer.", packet.requestID))
+				} else if err := pubsub.safePublish(packet.command, packet.nodeID, packet.message); err != nil {
+					pubsub.rejectPendingRequest(packet.requestID, err)
+				}
+				continue
+			}
+			if expired {
+				pubsub.deadLetter(packet.command, packet.nodeID, packet.values, fmt.Errorf("The packet expired in the offline buffer."))
+				continue
+			}
+			pubsub.publishPacket(packet.command, packet.nodeID, packet.values)
+		}
+	}
+}
+
+// dropOffline reject the buffered requests and send the buffered events to the dead-letter handler/topic,
+// when the transporter could not be reconnected.
+func (pubsub *PubSub) dropOffline(err error) {
+	pubsub.offlineMutex.Lock()
+	packets := pubsub.offlinePackets
+	pubsub.offlinePackets = nil
+	pubsub.offlineMutex.Unlock()
+	for _, packet := range packets {
+		if packet.command == "REQ" {
+			pubsub.rejectPendingRequest(packet.requestID, err)
+		} else {
+			pubsub.deadLetter(packet.command, packet.nodeID, packet.values, err)
+		}
+	}
+}
+
+// rejectPendingRequest complete the pending request with the error, when it did not time out already.
+func (pubsub *PubSub) rejectPendingRequest(requestID string, err error) {
+	pubsub.pendingRequestsMutex.Lock()
+	defer pubsub.pendingRequestsMutex.Unlock()
+	p, exists := pubsub.pendingRequests[requestID]
+	if !exists {
+		return
+	}
+	pubsub.logger.Warn("Request rejected - id: ", requestID, " error: ", err)
+	(*p.resultChan) <- payload.New(err)
+	p.timer.Stop()
+	pubsub.deletePendingRequest(requestID)
+}
+
+// dropPendingRequest remove the pending request that could not be buffered and return a channel with the error.
+func (pubsub *PubSub) dropPendingRequest(requestID string, err error) chan moleculer.Payload {
+	pubsub.pendingRequestsMutex.Lock()
+	if p, exists := pubsub.pendingRequests[requestID]; exists {
+		p.timer.Stop()
+		pubsub.deletePendingRequest(requestID)
+	}
+	pubsub.pendingRequestsMutex.Unlock()
+	return errorResult(err)
+}
+
+// errorResult return a result channel holding the error of a request that was not sent.
+func errorResult(err error) chan moleculer.Payload {
+	resultChan := make(chan moleculer.Payload, 1)
+	resultChan <- payload.New(err)
+	return resultChan
+}
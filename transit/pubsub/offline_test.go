@@ -0,0 +1,165 @@
+package pubsub
+
+import (
+	"errors"
+	"time"
+
+	bus "github.com/moleculer-go/goemitter"
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/context"
+	"github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Offline buffer", func() {
+	remoteNode := map[string]bool{"remote-node": true}
+
+	It("should buffer the requests and events while disconnected and publish them when connected", func() {
+		letters := []moleculer.DeadLetter{}
+		transport := &recordingTransporter{}
+		pubsub, delegates := createPubSub(transport, moleculer.Config{
+			OfflineBuffer: moleculer.OfflineBufferPolicy{Enabled: true, MaxPackets: 2, TTL: time.Minute},
+			DeadLetterHandler: func(letter moleculer.DeadLetter) {
+				letters = append(letters, letter)
+			},
+		}, remoteNode)
+		actionContext := context.BrokerContext(delegates).ChildActionContext("math.add", payload.Empty())
+		actionContext.SetTargetNodeID("remote-node")
+		pubsub.Request(actionContext)
+		eventContext := context.BrokerContext(delegates).ChildEventContext("user.created", payload.New("John"), nil, false)
+		eventContext.SetTargetNodeID("remote-node")
+		pubsub.Emit(eventContext)
+		Expect(transport.commands).Should(BeEmpty())
+
+		pubsub.Emit(eventContext)
+		Expect(letters).Should(HaveLen(1))
+		Expect(letters[0].Error.Error()).Should(ContainSubstring("offline buffer is full"))
+		result := <-pubsub.Request(context.BrokerContext(delegates).ChildActionContext("math.sub", payload.Empty()))
+		Expect(result.IsError()).Should(BeTrue())
+		Expect(result.Error().Error()).Should(ContainSubstring("QueueIsFull"))
+
+		pubsub.isConnected.set(true)
+		pubsub.flushOffline()
+		Expect(transport.commands).Should(Equal([]string{"REQ", "EVENT"}))
+		Expect(pubsub.pendingRequests).Should(HaveKey(actionContext.ID()))
+
+		pubsub.Emit(eventContext)
+		Expect(transport.commands).Should(HaveLen(3))
+	})
+
+	It("should fail the requests and dead-letter the events that expired in the buffer", func() {
+		letters := []moleculer.DeadLetter{}
+		transport := &recordingTransporter{}
+		pubsub, delegates := createPubSub(transport, moleculer.Config{
+			OfflineBuffer: moleculer.OfflineBufferPolicy{Enabled: true, TTL: time.Millisecond},
+			DeadLetterHandler: func(letter moleculer.DeadLetter) {
+				letters = append(letters, letter)
+			},
+		}, remoteNode)
+		actionContext := context.BrokerContext(delegates).ChildActionContext("math.add", payload.Empty())
+		resultChan := pubsub.Request(actionContext)
+		eventContext := context.BrokerContext(delegates).ChildEventContext("user.created", payload.New("John"), nil, false)
+		pubsub.Emit(eventContext)
+		time.Sleep(5 * time.Millisecond)
+
+		results := make(chan moleculer.Payload, 1)
+		go func() { results <- <-resultChan }()
+		pubsub.isConnected.set(true)
+		pubsub.flushOffline()
+		var result moleculer.Payload
+		Eventually(results).Should(Receive(&result))
+		Expect(result.Error().Error()).Should(ContainSubstring("expired in the offline buffer"))
+		Expect(pubsub.offlinePackets).Should(BeEmpty())
+		Expect(transport.commands).Should(BeEmpty())
+		Expect(letters).Should(HaveLen(1))
+		Expect(letters[0].Command).Should(Equal("EVENT"))
+	})
+
+	It("should publish the packets sent during the flush after the buffered ones", func() {
+		transport := &flushingTransporter{}
+		pubsub, delegates := createPubSub(&transport.recordingTransporter, moleculer.Config{
+			OfflineBuffer: moleculer.OfflineBufferPolicy{Enabled: true},
+		}, remoteNode)
+		pubsub.transport = transport
+		created := context.BrokerContext(delegates).ChildEventContext("user.created", payload.New("John"), nil, false)
+		pubsub.Emit(created)
+		pubsub.Emit(created)
+		updated := context.BrokerContext(delegates).ChildEventContext("user.updated", payload.New("John"), nil, false)
+		transport.onPublish = func() {
+			pubsub.Emit(updated)
+		}
+
+		pubsub.flushingOffline.set(true)
+		pubsub.isConnected.set(true)
+		pubsub.flushOffline()
+		Expect(transport.commands).Should(Equal([]string{"EVENT", "EVENT", "EVENT"}))
+		Expect(transport.messages[1].Get("event").String()).Should(Equal("user.created"))
+		Expect(transport.messages[2].Get("event").String()).Should(Equal("user.updated"))
+		Expect(pubsub.isOffline()).Should(BeFalse())
+	})
+
+	It("should reject the requests and dead-letter the events buffered when the transporter can't reconnect", func() {
+		letters := []moleculer.DeadLetter{}
+		pubsub, delegates := createPubSub(&recordingTransporter{}, moleculer.Config{
+			OfflineBuffer:   moleculer.OfflineBufferPolicy{Enabled: true},
+			ReconnectPolicy: moleculer.ReconnectPolicy{Retries: 2, Delay: time.Millisecond},
+			TransporterFactory: func() interface{} {
+				return &unreachableTransporter{}
+			},
+			DeadLetterHandler: func(letter moleculer.DeadLetter) {
+				letters = append(letters, letter)
+			},
+		}, remoteNode)
+		localBus := bus.Construct()
+		delegates.Bus = func() *bus.Emitter {
+			return localBus
+		}
+		resultChan := pubsub.Request(context.BrokerContext(delegates).ChildActionContext("math.add", payload.Empty()))
+		pubsub.Emit(context.BrokerContext(delegates).ChildEventContext("user.created", payload.New("John"), nil, false))
+
+		results := make(chan moleculer.Payload, 1)
+		go func() { results <- <-resultChan }()
+		pubsub.reconnect()
+		var result moleculer.Payload
+		Eventually(results).Should(Receive(&result))
+		Expect(result.Error().Error()).Should(ContainSubstring("Could not reconnect the transporter after 2 attempts"))
+		Expect(letters).Should(HaveLen(1))
+		Expect(letters[0].Command).Should(Equal("EVENT"))
+		Expect(pubsub.offlinePackets).Should(BeEmpty())
+		Expect(pubsub.isOffline()).Should(BeFalse())
+	})
+
+	It("should publish right away when the offline buffer is disabled", func() {
+		transport := &recordingTransporter{}
+		pubsub, delegates := createPubSub(transport, moleculer.Config{}, remoteNode)
+		eventContext := context.BrokerContext(delegates).ChildEventContext("user.created", payload.New("John"), nil, false)
+		pubsub.Emit(eventContext)
+		Expect(transport.commands).Should(Equal([]string{"EVENT"}))
+	})
+})
+
+// flushingTransporter call onPublish once, on the first published packet.
+type flushingTransporter struct {
+	recordingTransporter
+	onPublish func()
+}
+
+func (t *flushingTransporter) Publish(command, nodeID string, message moleculer.Payload) {
+	t.recordingTransporter.Publish(command, nodeID, message)
+	if onPublish := t.onPublish; onPublish != nil {
+		t.onPublish = nil
+		onPublish()
+	}
+}
+
+// unreachableTransporter fail all connection attempts.
+type unreachableTransporter struct {
+	mockTransporter
+}
+
+func (t *unreachableTransporter) Connect() chan error {
+	endChan := make(chan error, 1)
+	endChan <- errors.New("connection refused")
+	return endChan
+}
@@ -0,0 +1,74 @@
+package pubsub
+
+import (
+	"strconv"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/version"
+)
+
+// protocol3Fields list the packet fields that do not exist in the protocol version 3
+// and are removed when sending packets to nodes using that version.
+var protocol3Fields = map[string][]string{
+	"REQ":       []string{"seq", "caller", "tracing"},
+	"RES":       []string{"seq"},
+	"EVENT":     []string{"id", "meta", "level", "requestID", "parentID", "caller", "tracing", "needAck", "stream", "seq"},
+	"INFO":      []string{"instanceID", "metadata", "mem"},
+	"HEARTBEAT": []string{"cpuSeq", "mem"},
+}
+
+// downgradePacket remove the fields not supported by the protocol version.
+func downgradePacket(command string, values map[string]interface{}, protocol string) {
+	if protocol != "3" {
+		return
+	}
+	for _, field := range protocol3Fields[command] {
+		delete(values, field)
+	}
+}
+
+// registerProtocol store the protocol version used by the sender of the packet.
+func (pubsub *PubSub) registerProtocol(message moleculer.Payload) {
+	sender := message.Get("sender").String()
+	protocol := message.Get("ver").String()
+	pubsub.nodeProtocols.Store(sender, protocol)
+}
+
+// protocolFor return the protocol version to be used in the packets sent to the node.
+// Packets sent to all nodes (empty nodeID) use the lowest version of the known nodes,
+// so nodes using the protocol version 3 can still process them.
+func (pubsub *PubSub) protocolFor(nodeID string) string {
+	protocol := version.MoleculerProtocol()
+	if nodeID != "" {
+		if nodeProtocol, exists := pubsub.nodeProtocols.Load(nodeID); exists {
+			return nodeProtocol.(string)
+		}
+		return protocol
+	}
+	pubsub.nodeProtocols.Range(func(key, value interface{}) bool {
+		if lowerProtocol(value.(string), protocol) {
+			protocol = value.(string)
+		}
+		return true
+	})
+	return protocol
+}
+
+// lowerProtocol check if the protocol version is lower than the other. Versions are compared
+// as numbers, so "10" is not lower than "9".
+func lowerProtocol(protocol, other string) bool {
+	number, err := strconv.Atoi(protocol)
+	otherNumber, otherErr := strconv.Atoi(other)
+	if err != nil || otherErr != nil {
+		return protocol < other
+	}
+	return number < otherNumber
+}
+
+// setVersion set the protocol version of the packet sent to the node and
+// remove the fields the version does not support.
+func (pubsub *PubSub) setVersion(command, nodeID string, values map[string]interface{}) {
+	protocol := pubsub.protocolFor(nodeID)
+	values["ver"] = protocol
+	downgradePacket(command, values, protocol)
+}
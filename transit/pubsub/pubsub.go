@@ -3,10 +3,14 @@ package pubsub
 import (
 	"errors"
 	"fmt"
+	"io"
 	"math"
+	"math/rand"
+	"net/url"
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/moleculer-go/moleculer/version"
@@ -15,11 +19,16 @@ import (
 
 	"github.com/moleculer-go/moleculer/context"
 	"github.com/moleculer-go/moleculer/transit"
+	"github.com/moleculer-go/moleculer/transit/amqp"
+	"github.com/moleculer-go/moleculer/transit/kafka"
 	"github.com/moleculer-go/moleculer/transit/memory"
 	"github.com/moleculer-go/moleculer/transit/nats"
+	"github.com/moleculer-go/moleculer/transit/redis"
+	"github.com/moleculer-go/moleculer/transit/websocket"
 
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/serializer"
+	"github.com/moleculer-go/moleculer/util"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -27,8 +36,10 @@ import (
 type PubSub struct {
 	logger               *log.Entry
 	transport            transit.Transport
+	transportMutex       sync.RWMutex
+	instanceID           string
 	broker               *moleculer.BrokerDelegates
-	isConnected          bool
+	isConnected          atomicFlag
 	pendingRequests      map[string]pendingRequest
 	pendingRequestsMutex *sync.Mutex
 	serializer           serializer.Serializer
@@ -36,17 +47,37 @@ type PubSub struct {
 	knownNeighbours   map[string]int64
 	neighboursTimeout time.Duration
 	neighboursMutex   *sync.Mutex
-	brokerStarted     bool
+	brokerStarted     atomicFlag
+	stopped           atomicFlag
+	paused            atomicFlag
+
+	compressionPeers map[string]bool
+	compressionMutex *sync.Mutex
+
+	streams      map[string]*streamReceiver
+	streamsMutex *sync.Mutex
+
+	nodeProtocols sync.Map
+
+	runningRequests sync.Map
+
+	status      transit.ConnectionStatus
+	statusMutex sync.Mutex
+
+	offlinePackets  []offlinePacket
+	offlineMutex    sync.Mutex
+	flushingOffline atomicFlag
 }
 
+// onServiceAdded broadcast the node info when a local service is added or removed.
 func (pubsub *PubSub) onServiceAdded(values ...interface{}) {
-	if pubsub.isConnected && pubsub.brokerStarted {
+	if pubsub.isConnected.isSet() && pubsub.brokerStarted.isSet() {
 		localNodeID := pubsub.broker.LocalNode().GetID()
 
 		// Checking that was added local service
 		isLocalServiceAdded := false
 		for _, value := range values {
-			if value.(map[string]string)["nodeID"] == localNodeID {
+			if svc, isService := value.(map[string]string); isService && svc["nodeID"] == localNodeID {
 				isLocalServiceAdded = true
 				break
 			}
@@ -60,9 +91,9 @@ func (pubsub *PubSub) onServiceAdded(values ...interface{}) {
 }
 
 func (pubsub *PubSub) onBrokerStarted(values ...interface{}) {
-	if pubsub.isConnected {
+	if pubsub.isConnected.isSet() {
 		pubsub.broadcastNodeInfo("")
-		pubsub.brokerStarted = true
+		pubsub.brokerStarted.set(true)
 	}
 }
 
@@ -71,7 +102,7 @@ func Create(broker *moleculer.BrokerDelegates) transit.Transit {
 	knownNeighbours := make(map[string]int64)
 	transitImpl := PubSub{
 		broker:               broker,
-		isConnected:          false,
+		instanceID:           util.RandomString(12),
 		pendingRequests:      pendingRequests,
 		logger:               broker.Logger("Transit", ""),
 		serializer:           serializer.New(broker),
@@ -79,16 +110,55 @@ func Create(broker *moleculer.BrokerDelegates) transit.Transit {
 		knownNeighbours:      knownNeighbours,
 		neighboursMutex:      &sync.Mutex{},
 		pendingRequestsMutex: &sync.Mutex{},
+		compressionPeers:     map[string]bool{},
+		compressionMutex:     &sync.Mutex{},
+		streams:              map[string]*streamReceiver{},
+		streamsMutex:         &sync.Mutex{},
+	}
+	if broker.Config.Compression != "" {
+		transitImpl.serializer = serializer.CreateCompressionSerializer(
+			transitImpl.serializer,
+			broker.Config.Compression,
+			broker.Config.CompressionThreshold,
+			broker.Logger("serializer", "compression"),
+		)
+	}
+	if broker.MiddlewareHandler != nil {
+		transitImpl.serializer = middlewareSerializer{transitImpl.serializer, broker.MiddlewareHandler}
 	}
 
 	broker.Bus().On("$node.disconnected", transitImpl.onNodeDisconnected)
 	broker.Bus().On("$node.connected", transitImpl.onNodeConnected)
+	broker.Bus().On("$node.reconnected", transitImpl.onNodeConnected)
 	broker.Bus().On("$broker.started", transitImpl.onBrokerStarted)
 	broker.Bus().On("$registry.service.added", transitImpl.onServiceAdded)
+	broker.Bus().On("$registry.service.removed", transitImpl.onServiceAdded)
 
 	return &transitImpl
 }
 
+// middlewareSerializer invoke the transporterSend and transporterReceive middlewares
+// with the packet bytes, so middlewares can change the bytes sent and received (e.g. encryption).
+type middlewareSerializer struct {
+	serializer.Serializer
+	middlewareHandler moleculer.MiddlewareHandlerFunc
+}
+
+func (ms middlewareSerializer) PayloadToBytes(message moleculer.Payload) []byte {
+	data := ms.Serializer.PayloadToBytes(message)
+	return ms.middlewareHandler("transporterSend", data).([]byte)
+}
+
+// BytesToPayload return an error payload when a middleware rejects the packet, e.g. it can't be decrypted.
+func (ms middlewareSerializer) BytesToPayload(data *[]byte) moleculer.Payload {
+	result := ms.middlewareHandler("transporterReceive", *data)
+	if err, isError := result.(error); isError {
+		return payload.New(err)
+	}
+	bytes := result.([]byte)
+	return ms.Serializer.BytesToPayload(&bytes)
+}
+
 func (pubsub *PubSub) pendingRequestsByNode(nodeId string) []pendingRequest {
 	list := []pendingRequest{}
 	for _, p := range pubsub.pendingRequests {
@@ -100,7 +170,7 @@ func (pubsub *PubSub) pendingRequestsByNode(nodeId string) []pendingRequest {
 }
 
 func (pubsub *PubSub) requestTimedOut(resultChan *chan moleculer.Payload, context moleculer.BrokerContext) func() {
-	pError := payload.New(errors.New("request timeout"))
+	pError := payload.New(moleculer.NewRequestTimeoutError(context.ActionName(), context.TargetNodeID()))
 	return func() {
 		pubsub.logger.Debug("requestTimedOut() nodeID: ", context.TargetNodeID())
 		pubsub.pendingRequestsMutex.Lock()
@@ -110,7 +180,8 @@ func (pubsub *PubSub) requestTimedOut(resultChan *chan moleculer.Payload, contex
 		if exists {
 			(*p.resultChan) <- pError
 			p.timer.Stop()
-			delete(pubsub.pendingRequests, p.context.ID())
+			pubsub.deletePendingRequest(p.context.ID())
+			pubsub.sendCancel(context)
 		}
 	}
 }
@@ -122,11 +193,14 @@ func (pubsub *PubSub) onNodeDisconnected(values ...interface{}) {
 	pending := pubsub.pendingRequestsByNode(nodeID)
 	pubsub.logger.Debug("onNodeDisconnected() nodeID: ", nodeID, " pending: ", len(pending))
 	if len(pending) > 0 {
-		pError := payload.New(fmt.Errorf("Node %s disconnected. The request was canceled.", nodeID))
+		err := moleculer.NewRetryableError(fmt.Sprintf("Node %s disconnected. The request was canceled.", nodeID),
+			503, "REQUEST_REJECTED", map[string]interface{}{"nodeID": nodeID})
+		err.Name = "RequestRejectedError"
+		pError := payload.New(err)
 		for _, p := range pending {
 			(*p.resultChan) <- pError
 			p.timer.Stop()
-			delete(pubsub.pendingRequests, p.context.ID())
+			pubsub.deletePendingRequest(p.context.ID())
 		}
 	}
 	pubsub.pendingRequestsMutex.Unlock()
@@ -134,6 +208,57 @@ func (pubsub *PubSub) onNodeDisconnected(values ...interface{}) {
 	pubsub.neighboursMutex.Lock()
 	delete(pubsub.knownNeighbours, nodeID)
 	pubsub.neighboursMutex.Unlock()
+
+	pubsub.compressionMutex.Lock()
+	delete(pubsub.compressionPeers, nodeID)
+	pubsub.compressionMutex.Unlock()
+
+	pubsub.failStreamsByNode(nodeID)
+	pubsub.cancelRequestsByNode(nodeID)
+
+	pubsub.nodeProtocols.Delete(nodeID)
+}
+
+// supportsCompression check if the node advertised support for the configured compression in the
+// INFO packet. Nodes whose INFO did not arrive yet are considered as not supporting it. Packets sent
+// to all nodes (empty nodeID) are compressed only when all the known nodes support it.
+func (pubsub *PubSub) supportsCompression(nodeID string) bool {
+	pubsub.compressionMutex.Lock()
+	defer pubsub.compressionMutex.Unlock()
+	if nodeID != "" {
+		return pubsub.compressionPeers[nodeID]
+	}
+	if len(pubsub.compressionPeers) == 0 {
+		return false
+	}
+	for _, supported := range pubsub.compressionPeers {
+		if !supported {
+			return false
+		}
+	}
+	return true
+}
+
+// registerCompressionSupport record if the sender of the INFO packet can decompress the configured compression.
+func (pubsub *PubSub) registerCompressionSupport(message moleculer.Payload) {
+	supported := false
+	for _, item := range message.Get("compression").StringArray() {
+		if item == pubsub.broker.Config.Compression {
+			supported = true
+			break
+		}
+	}
+	pubsub.compressionMutex.Lock()
+	pubsub.compressionPeers[message.Get("sender").String()] = supported
+	pubsub.compressionMutex.Unlock()
+}
+
+func (pubsub *PubSub) infoHandler() transit.TransportHandler {
+	emitInfo := pubsub.emitRegistryEvent("INFO")
+	return func(message moleculer.Payload) {
+		pubsub.registerCompressionSupport(message)
+		emitInfo(message)
+	}
 }
 
 func (pubsub *PubSub) onNodeConnected(values ...interface{}) {
@@ -145,74 +270,197 @@ func (pubsub *PubSub) onNodeConnected(values ...interface{}) {
 	pubsub.neighboursMutex.Unlock()
 }
 
-func isNats(v string) bool {
-	return strings.Index(v, "nats://") > -1
+func init() {
+	transit.RegisterTransporter("memory", createMemoryTransporter)
+	transit.RegisterTransporter("nats", createNatsTransporter)
+	transit.RegisterTransporter("stan", createStanTransporter)
+	transit.RegisterTransporter("redis", createRedisTransporter)
+	transit.RegisterTransporter("rediss", createRedisTransporter)
+	transit.RegisterTransporter("amqp", createAmqpTransporter)
+	transit.RegisterTransporter("amqps", createAmqpTransporter)
+	transit.RegisterTransporter("kafka", createKafkaTransporter)
+	transit.RegisterTransporter("ws", createWebSocketTransporter)
+	transit.RegisterTransporter("wss", createWebSocketTransporter)
+}
+
+// natsURL return the NATS url from the transporter config.
+// When only "NATS" is informed it defaults to the local NATS server.
+func natsURL(v string) string {
+	if strings.ToUpper(v) == "NATS" {
+		return "nats://localhost:4222"
+	}
+	return v
 }
 
-// CreateTransport : based on config it will load the transporter
-// for now is hard coded for NATS Streaming localhost
+// topicPrefix return the prefix of the transporter topics. The namespace is appended
+// to the prefix (e.g. MOL-staging), so clusters with different namespaces sharing
+// the same transporter server are isolated from each other.
+func topicPrefix(config moleculer.Config) string {
+	prefix := config.TopicPrefix
+	if prefix == "" {
+		prefix = "MOL"
+	}
+	if config.Namespace != "" {
+		return prefix + "-" + config.Namespace
+	}
+	return prefix
+}
+
+// createTransport : based on config it will load the transporter.
+// Custom factories in the config take precedence over the registered transporters.
 func (pubsub *PubSub) createTransport() transit.Transport {
 	var transport transit.Transport
 	if pubsub.broker.Config.TransporterFactory != nil {
 		pubsub.logger.Info("Transporter: Custom factory")
 		transport = pubsub.broker.Config.TransporterFactory().(transit.Transport)
-	} else if pubsub.broker.Config.Transporter == "STAN" {
-		pubsub.logger.Info("Transporter: NatsStreamingTransporter")
-		transport = pubsub.createStanTransporter()
-	} else if isNats(pubsub.broker.Config.Transporter) {
-		pubsub.logger.Info("Transporter: NatsTransporter")
-		transport = pubsub.createNatsTransporter()
-
+	} else if factory, exists := transit.FindTransporter(pubsub.broker.Config.Transporter); exists {
+		transport = factory(pubsub.transporterConfig())
+		pubsub.logger.Info(fmt.Sprintf("Transporter: %T", transport))
 	} else {
 		pubsub.logger.Info("Transporter: Memory")
-		transport = pubsub.createMemoryTransporter()
+		transport = createMemoryTransporter(pubsub.transporterConfig())
 	}
-	if pubsub.broker.Config.Namespace != "" {
-		transport.SetPrefix("MOL-"+pubsub.broker.Config.Namespace)
-	} else {
-		transport.SetPrefix("MOL")
+	if pubsub.broker.Config.MaxPacketSize > 0 {
+		transport = createChunkedTransport(
+			transport,
+			pubsub.broker.Config.MaxPacketSize,
+			pubsub.broker.Config.RequestTimeout,
+			pubsub.logger.WithField("transport", "chunked"),
+		)
+	}
+	if pubsub.broker.Config.Metrics {
+		transport = createMeteredTransport(transport, pubsub.broker.MiddlewareHandler)
+	}
+	if pubsub.broker.Config.Compression != "" {
+		transport = createCompressionTransport(transport, pubsub.supportsCompression)
 	}
+	transport.SetPrefix(topicPrefix(pubsub.broker.Config))
 	transport.SetNodeID(pubsub.broker.LocalNode().GetID())
 	transport.SetSerializer(pubsub.serializer)
 	return transport
 }
 
-func (pubsub *PubSub) createMemoryTransporter() transit.Transport {
-	pubsub.logger.Debug("createMemoryTransporter() ... ")
-	logger := pubsub.logger.WithField("transport", "memory")
-	mem := memory.Create(logger, &memory.SharedMemory{})
+func (pubsub *PubSub) transporterConfig() transit.TransporterConfig {
+	return transit.TransporterConfig{
+		URL:        pubsub.broker.Config.Transporter,
+		NodeID:     pubsub.broker.LocalNode().GetID(),
+		Logger:     pubsub.logger,
+		Serializer: pubsub.serializer,
+	}
+}
+
+func createMemoryTransporter(config transit.TransporterConfig) transit.Transport {
+	logger := config.Logger.WithField("transport", "memory")
+	logger.Debug("createMemoryTransporter() ... ")
+	mem := memory.Create(logger, memory.Bus(memoryBusName(config.URL)))
 	return &mem
 }
 
-func (pubsub *PubSub) createNatsTransporter() transit.Transport {
-	pubsub.logger.Debug("createNatsTransporter()")
+// memoryBusName return the name of the memory bus from the transporter config.
+// Example: memory://bus-1 -> bus-1 and MEMORY -> default
+func memoryBusName(url string) string {
+	if index := strings.Index(url, "://"); index > -1 && url[index+3:] != "" {
+		return url[index+3:]
+	}
+	return "default"
+}
+
+func createNatsTransporter(config transit.TransporterConfig) transit.Transport {
+	config.Logger.Debug("createNatsTransporter()")
 
 	return nats.CreateNatsTransporter(nats.NATSOptions{
-		URL:            pubsub.broker.Config.Transporter,
-		Name:           pubsub.broker.LocalNode().GetID(),
-		Logger:         pubsub.logger.WithField("transport", "nats"),
-		Serializer:     pubsub.serializer,
+		URL:            natsURL(config.URL),
+		Name:           config.NodeID,
+		Logger:         config.Logger.WithField("transport", "nats"),
+		Serializer:     config.Serializer,
 		AllowReconnect: true,
 		ReconnectWait:  time.Second * 2,
 		MaxReconnect:   -1,
 	})
 }
 
-func (pubsub *PubSub) createStanTransporter() transit.Transport {
+func createRedisTransporter(config transit.TransporterConfig) transit.Transport {
+	config.Logger.Debug("createRedisTransporter()")
+
+	return redis.CreateRedisTransporter(redis.RedisOptions{
+		URL:        config.URL,
+		Logger:     config.Logger.WithField("transport", "redis"),
+		Serializer: config.Serializer,
+	})
+}
+
+func createAmqpTransporter(config transit.TransporterConfig) transit.Transport {
+	config.Logger.Debug("createAmqpTransporter()")
+
+	return amqp.CreateAmqpTransporter(amqp.AmqpOptions{
+		Url:        transit.ParseURLs(config.URL),
+		Logger:     config.Logger.WithField("transport", "amqp"),
+		Serializer: config.Serializer,
+	})
+}
+
+func createWebSocketTransporter(config transit.TransporterConfig) transit.Transport {
+	config.Logger.Debug("createWebSocketTransporter()")
+
+	urls, listen := webSocketListen(config.URL)
+	return websocket.CreateWebSocketTransporter(websocket.WebSocketOptions{
+		URL:        urls,
+		Listen:     listen,
+		Logger:     config.Logger.WithField("transport", "websocket"),
+		Serializer: config.Serializer,
+	})
+}
+
+// webSocketListen read the address of the embedded server from the listen param of the urls, e.g.
+// ws://localhost:4000?listen=:4000, and return the urls without it. A url with only the listen param,
+// e.g. ws://?listen=:4000, starts the server and connects to it.
+func webSocketListen(transporter string) (string, string) {
+	listen := ""
+	urls := []string{}
+	for _, item := range transit.ParseURLs(transporter) {
+		parsed, err := url.Parse(item)
+		if err != nil || parsed.Query().Get("listen") == "" {
+			urls = append(urls, item)
+			continue
+		}
+		query := parsed.Query()
+		listen = query.Get("listen")
+		query.Del("listen")
+		parsed.RawQuery = query.Encode()
+		if parsed.Host != "" {
+			urls = append(urls, parsed.String())
+		}
+	}
+	return strings.Join(urls, ","), listen
+}
+
+func createKafkaTransporter(config transit.TransporterConfig) transit.Transport {
+	config.Logger.Debug("createKafkaTransporter()")
+
+	return kafka.CreateKafkaTransporter(kafka.KafkaOptions{
+		URL:        config.URL,
+		Logger:     config.Logger.WithField("transport", "kafka"),
+		Serializer: config.Serializer,
+	})
+}
+
+func createStanTransporter(config transit.TransporterConfig) transit.Transport {
 	//TODO: move this to config and params
-	broker := pubsub.broker
-	url := "stan://" + os.Getenv("STAN_HOST") + ":4222"
+	url := config.URL
+	if strings.Index(url, "stan://") == -1 {
+		url = "stan://" + os.Getenv("STAN_HOST") + ":4222"
+	}
 	clusterID := "test-cluster"
 
-	localNodeID := broker.LocalNode().GetID()
-	logger := broker.Logger("transport", "stan")
+	localNodeID := config.NodeID
+	logger := config.Logger.WithField("transport", "stan")
 
 	options := nats.StanOptions{
 		url,
 		clusterID,
 		localNodeID,
 		logger,
-		pubsub.serializer,
+		config.Serializer,
 		func(message moleculer.Payload) bool {
 			sender := message.Get("sender").String()
 			return sender != localNodeID
@@ -228,10 +476,38 @@ type pendingRequest struct {
 	context    moleculer.BrokerContext
 	resultChan *chan moleculer.Payload
 	timer      *time.Timer
+	completed  chan struct{}
+}
+
+// deletePendingRequest remove the pending request and close its completed channel.
+// The pendingRequestsMutex must be locked.
+func (pubsub *PubSub) deletePendingRequest(id string) {
+	p, exists := pubsub.pendingRequests[id]
+	if !exists {
+		return
+	}
+	delete(pubsub.pendingRequests, id)
+	if p.completed != nil {
+		close(p.completed)
+	}
 }
 
-func (pubsub *PubSub) checkMaxQueueSize() {
-	//TODO: check transit.js line 524
+// checkMaxQueueSize return an error when the number of pending requests reached the MaxQueueSize config.
+// The pendingRequestsMutex must be locked, so the request is added in the same critical section.
+func (pubsub *PubSub) checkMaxQueueSize(context moleculer.BrokerContext) error {
+	maxQueueSize := pubsub.broker.Config.MaxQueueSize
+	if maxQueueSize <= 0 {
+		return nil
+	}
+	pending := len(pubsub.pendingRequests)
+	if pending >= maxQueueSize {
+		pubsub.logger.Warn("Outgoing request queue is full. Pending requests: ", pending, " max: ", maxQueueSize)
+		err := moleculer.NewRetryableError(fmt.Sprintf("Queue is full. Request '%s' action on '%s' node is rejected.", context.ActionName(), context.TargetNodeID()),
+			429, "QUEUE_FULL", map[string]interface{}{"action": context.ActionName(), "nodeID": context.TargetNodeID()})
+		err.Name = "QueueIsFullError"
+		return err
+	}
+	return nil
 }
 
 // waitForNeighbours this function will wait for neighbour nodes or timeout if the expected number is not received after a time out.
@@ -251,7 +527,7 @@ func (pubsub *PubSub) waitForNeighbours() bool {
 			pubsub.logger.Warn("waitForNeighbours() - Time out ! did not receive info from all expected neighbours: ", expected, "  INFOs received: ", neighbours)
 			return false
 		}
-		if !pubsub.isConnected {
+		if !pubsub.isConnected.isSet() {
 			return false
 		}
 		time.Sleep(pubsub.broker.Config.WaitForNeighboursInterval)
@@ -271,25 +547,44 @@ func (pubsub *PubSub) DiscoverNodes() chan bool {
 func (pubsub *PubSub) SendHeartbeat() {
 	node := pubsub.broker.LocalNode().ExportAsMap()
 	payload := map[string]interface{}{
-		"sender": node["id"],
-		"cpu":    node["cpu"],
-		"cpuSeq": node["cpuSeq"],
-		"ver":    version.MoleculerProtocol(),
+		"sender":     node["id"],
+		"cpu":        node["cpu"],
+		"cpuSeq":     node["cpuSeq"],
+		"mem":        node["mem"],
+		"instanceID": pubsub.instanceID,
 	}
+	pubsub.setVersion("HEARTBEAT", "", payload)
 	message, err := pubsub.serializer.MapToPayload(&payload)
 	if err == nil {
-		pubsub.transport.Publish("HEARTBEAT", "", message)
+		pubsub.currentTransport().Publish("HEARTBEAT", "", message)
+	}
+	// ping a random neighbour on each heartbeat to keep the latency updated.
+	if nodeID := pubsub.randomNeighbour(); nodeID != "" {
+		pubsub.SendPing(nodeID)
+	}
+}
+
+func (pubsub *PubSub) randomNeighbour() string {
+	pubsub.neighboursMutex.Lock()
+	defer pubsub.neighboursMutex.Unlock()
+	nodes := make([]string, 0, len(pubsub.knownNeighbours))
+	for nodeID := range pubsub.knownNeighbours {
+		nodes = append(nodes, nodeID)
+	}
+	if len(nodes) == 0 {
+		return ""
 	}
+	return nodes[rand.Intn(len(nodes))]
 }
 
 func (pubsub *PubSub) DiscoverNode(nodeID string) {
 	payload := map[string]interface{}{
 		"sender": pubsub.broker.LocalNode().GetID(),
-		"ver":    version.MoleculerProtocol(),
 	}
+	pubsub.setVersion("DISCOVER", nodeID, payload)
 	message, err := pubsub.serializer.MapToPayload(&payload)
 	if err == nil {
-		pubsub.transport.Publish("DISCOVER", nodeID, message)
+		pubsub.currentTransport().Publish("DISCOVER", nodeID, message)
 	}
 }
 
@@ -298,27 +593,82 @@ func (pubsub *PubSub) Emit(context moleculer.BrokerContext) {
 	targetNodeID := context.TargetNodeID()
 	payload := context.AsMap()
 	payload["sender"] = pubsub.broker.LocalNode().GetID()
-	payload["ver"] = version.MoleculerProtocol()
+	pubsub.setVersion("EVENT", targetNodeID, payload)
 
 	pubsub.logger.Trace("Emit() targetNodeID: ", targetNodeID, " payload: ", payload)
 
+	if pubsub.isOffline() {
+		buffered, err := pubsub.bufferOffline(offlinePacket{command: "EVENT", nodeID: targetNodeID, values: payload})
+		if err != nil {
+			pubsub.deadLetter("EVENT", targetNodeID, payload, err)
+			return
+		}
+		if buffered {
+			return
+		}
+	}
+	pubsub.publishPacket("EVENT", targetNodeID, payload)
+}
+
+// EmitConfirmed publish the event and wait for the transporter acknowledgment. Failed
+// attempts are retried according to the Config.EventDelivery policy. Transports that
+// do not support confirmations publish the event in best-effort mode.
+func (pubsub *PubSub) EmitConfirmed(context moleculer.BrokerContext) error {
+	targetNodeID := context.TargetNodeID()
+	payload := context.AsMap()
+	payload["sender"] = pubsub.broker.LocalNode().GetID()
+	pubsub.setVersion("EVENT", targetNodeID, payload)
+
+	pubsub.logger.Trace("EmitConfirmed() targetNodeID: ", targetNodeID, " payload: ", payload)
+
 	message, err := pubsub.serializer.MapToPayload(&payload)
 	if err != nil {
-		pubsub.logger.Error("Emit() Error serializing the payload: ", payload, " error: ", err)
-		panic(fmt.Errorf("Error trying to serialize the payload. Likely issues with the action params. Error: %s", err))
+		pubsub.logger.Error("EmitConfirmed() Error serializing the payload: ", payload, " error: ", err)
+		err = fmt.Errorf("Error trying to serialize the payload. Likely issues with the action params. Error: %s", err)
+		pubsub.deadLetter("EVENT", targetNodeID, payload, err)
+		return err
 	}
-	pubsub.transport.Publish("EVENT", targetNodeID, message)
+	policy := pubsub.broker.Config.EventDelivery
+	for attempt := 0; ; attempt++ {
+		err = pubsub.publishConfirm("EVENT", targetNodeID, message)
+		if err == nil {
+			return nil
+		}
+		if attempt >= policy.Retries {
+			break
+		}
+		pubsub.logger.Warn("EmitConfirmed() Error publishing event: ", context.EventName(), " attempt: ", attempt+1, " error: ", err)
+		time.Sleep(policy.Delay)
+	}
+	pubsub.logger.Error("EmitConfirmed() Could not deliver event: ", context.EventName(), " after ", policy.Retries+1, " attempts - error: ", err)
+	pubsub.deadLetter("EVENT", targetNodeID, payload, err)
+	return err
 }
 
-func (pubsub *PubSub) Request(context moleculer.BrokerContext) chan moleculer.Payload {
-	pubsub.checkMaxQueueSize()
+// publishConfirm publish the message waiting for the transporter acknowledgment when supported.
+func (pubsub *PubSub) publishConfirm(command, nodeID string, message moleculer.Payload) error {
+	if confirm, ok := pubsub.currentTransport().(transit.ConfirmTransport); ok {
+		return confirm.PublishConfirm(command, nodeID, message)
+	}
+	return pubsub.safePublish(command, nodeID, message)
+}
 
+func (pubsub *PubSub) Request(context moleculer.BrokerContext) chan moleculer.Payload {
 	resultChan := make(chan moleculer.Payload)
 
 	targetNodeID := context.TargetNodeID()
 	payload := context.AsMap()
 	payload["sender"] = pubsub.broker.LocalNode().GetID()
-	payload["ver"] = version.MoleculerProtocol()
+
+	// when the params is a stream, the first packet has no params and the
+	// stream data is sent in the next packets.
+	stream, isStream := context.Payload().Value().(io.Reader)
+	if isStream {
+		payload["params"] = nil
+		payload["stream"] = true
+		payload["seq"] = 0
+	}
+	pubsub.setVersion("REQ", targetNodeID, payload)
 
 	pubsub.logger.Trace("Request() targetNodeID: ", targetNodeID, " payload: ", payload)
 
@@ -328,27 +678,57 @@ func (pubsub *PubSub) Request(context moleculer.BrokerContext) chan moleculer.Pa
 		panic(fmt.Errorf("Error trying to serialize the payload. Likely issues with the action params. Error: %s", err))
 	}
 
+	timeout := pubsub.broker.Config.RequestTimeout
+	if context.Timeout() > 0 {
+		timeout = context.Timeout()
+	}
+
+	completed := make(chan struct{})
 	pubsub.pendingRequestsMutex.Lock()
-	pubsub.logger.Debug("Request() pending request id: ", context.ID(), " targetNodeId: ", context.TargetNodeID())
+	if err := pubsub.checkMaxQueueSize(context); err != nil {
+		pubsub.pendingRequestsMutex.Unlock()
+		return errorResult(err)
+	}
+	pubsub.logger.Debug("Request() pending request id: ", context.ID(), " targetNodeId: ", context.TargetNodeID(), " timeout: ", timeout)
 	pubsub.pendingRequests[context.ID()] = pendingRequest{
 		context,
 		&resultChan,
 
 		time.AfterFunc(
-			pubsub.broker.Config.RequestTimeout,
+			timeout,
 			pubsub.requestTimedOut(&resultChan, context)),
+		completed,
 	}
 	pubsub.pendingRequestsMutex.Unlock()
+	pubsub.watchCancellation(context, completed)
 
-	pubsub.transport.Publish("REQ", targetNodeID, message)
+	if !isStream && pubsub.isOffline() {
+		buffered, err := pubsub.bufferOffline(offlinePacket{command: "REQ", nodeID: targetNodeID, requestID: context.ID(), values: payload, message: message})
+		if err != nil {
+			return pubsub.dropPendingRequest(context.ID(), err)
+		}
+		if buffered {
+			return resultChan
+		}
+	}
+	pubsub.currentTransport().Publish("REQ", targetNodeID, message)
+	if isStream {
+		go pubsub.publishStream("REQ", targetNodeID, context.ID(), "params", stream)
+	}
 	return resultChan
 }
 
-// validateVersion check that version of the message is correct.
+// validate check that version of the message is supported and register the version used by the sender.
+// Packets that could not be read are dropped.
 func (pubsub *PubSub) validate(handler func(message moleculer.Payload)) transit.TransportHandler {
 	return func(msg moleculer.Payload) {
+		if err, isError := msg.Value().(error); isError {
+			pubsub.logger.Warn("Discarding packet - error: ", err)
+			return
+		}
 		valid := pubsub.validateVersion(msg) && !pubsub.sameHost(msg)
 		if valid {
+			pubsub.registerProtocol(msg)
 			handler(msg)
 		} else {
 			pubsub.logger.Trace("Discarding invalid msg -> ", msg.Value())
@@ -356,19 +736,22 @@ func (pubsub *PubSub) validate(handler func(message moleculer.Payload)) transit.
 	}
 }
 
+// sameHost check if the message was sent by this broker instance. The INFO and HEARTBEAT packets of another
+// instance with the same node ID carry a different instanceID and are passed to the registry, which reports the conflict.
 func (pubsub *PubSub) sameHost(msg moleculer.Payload) bool {
 	sender := msg.Get("sender").String()
 	localNodeID := pubsub.broker.LocalNode().GetID()
-	return sender == localNodeID
+	instanceID := msg.Get("instanceID")
+	return sender == localNodeID && (!instanceID.Exists() || instanceID.String() == pubsub.instanceID)
 }
 
 // validateVersion check that version of the message is correct.
 func (pubsub *PubSub) validateVersion(msg moleculer.Payload) bool {
 	msgVersion := msg.Get("ver").String()
-	if msgVersion == version.MoleculerProtocol() {
+	if version.IsSupportedProtocol(msgVersion) {
 		return true
 	} else {
-		pubsub.logger.Error("Discarding msg - wronging version: ", msgVersion, " expected: ", version.MoleculerProtocol(), " msg: ", msg)
+		pubsub.logger.Error("Discarding msg - wronging version: ", msgVersion, " expected one of: ", version.SupportedProtocols(), " msg: ", msg)
 		return false
 	}
 }
@@ -383,6 +766,21 @@ func (pubsub *PubSub) reponseHandler() transit.TransportHandler {
 		sender := message.Get("sender").String()
 		pubsub.logger.Debug("reponseHandler() - response arrived from nodeID: ", sender, " context id: ", id)
 
+		var stream *streamReceiver
+		if isStreamPacket(message) {
+			receiver := pubsub.streamReceiver("RES", message)
+			if !message.Get("success").Bool() {
+				receiver.fail(streamError(message))
+				pubsub.removeStream("RES", message)
+			} else {
+				pubsub.receiveStreamPacket("RES", receiver, message, "data")
+			}
+			if message.Get("seq").Int() != 0 {
+				return
+			}
+			stream = receiver
+		}
+
 		request, exists := pubsub.pendingRequests[id]
 
 		if !exists {
@@ -395,14 +793,21 @@ func (pubsub *PubSub) reponseHandler() transit.TransportHandler {
 		}
 
 		request.timer.Stop()
-		defer delete(pubsub.pendingRequests, id)
+		defer pubsub.deletePendingRequest(id)
 		var result moleculer.Payload
-		if message.Get("success").Bool() {
+		if stream != nil {
+			result = payload.New(stream.reader)
+		} else if message.Get("success").Bool() {
 			result = message.Get("data")
 		} else {
 			result = pubsub.parseError(message)
 		}
 
+		// the meta changed by the remote action is merged into the caller context.
+		if meta := message.Get("meta"); meta.Exists() && meta.IsMap() {
+			request.context.UpdateMeta(request.context.Meta().AddMany(meta.RawMap()))
+		}
+
 		pubsub.logger.Trace("reponseHandler() id: ", id, " result: ", result)
 		(*request.resultChan) <- result
 	}
@@ -419,12 +824,36 @@ func (pubsub *PubSub) isMoleculerJSError(message moleculer.Payload) bool {
 	return message.Get("error").Get("message").Exists()
 }
 
+// moleculerJSError create the error with all the fields sent by the remote node.
 func (pubsub *PubSub) moleculerJSError(message moleculer.Payload) error {
-	msg := message.Get("error").Get("message").String()
 	if message.Get("error").Get("stack").Exists() {
 		pubsub.logger.Error(message.Get("error").Get("stack").Value())
 	}
-	return errors.New(msg)
+	values := message.Get("error").RawMap()
+	if nodeID, _ := values["nodeID"].(string); nodeID == "" {
+		values["nodeID"] = message.Get("sender").String()
+	}
+	return moleculer.ParseError(values)
+}
+
+// errorMap export the error sent in the response. Errors that are not a *moleculer.Error
+// are sent with the name "Error" and the stack of the action panic, when available.
+func (pubsub *PubSub) errorMap(err error) map[string]interface{} {
+	var moleculerError *moleculer.Error
+	if exporter, isExporter := err.(moleculer.ErrorExporter); isExporter {
+		moleculerError = exporter.MoleculerError()
+	}
+	if moleculerError == nil {
+		moleculerError = &moleculer.Error{Name: "Error", Message: err.Error()}
+		if actionError, isActionError := err.(ActionError); isActionError {
+			moleculerError.StackTrace = actionError.Stack()
+		}
+	}
+	values := moleculerError.Map()
+	if moleculerError.NodeID == "" {
+		values["nodeID"] = pubsub.broker.LocalNode().GetID()
+	}
+	return values
 }
 
 func (pubsub *PubSub) sendResponse(context moleculer.BrokerContext, response moleculer.Payload) {
@@ -438,41 +867,33 @@ func (pubsub *PubSub) sendResponse(context moleculer.BrokerContext, response mol
 
 	values := make(map[string]interface{})
 	values["sender"] = pubsub.broker.LocalNode().GetID()
-	values["ver"] = version.MoleculerProtocol()
 	values["id"] = context.ID()
 	values["meta"] = context.Meta()
 
 	if response.IsError() {
-		var errMap map[string]string
-		actionError, isActionError := response.Value().(ActionError)
-		if isActionError {
-			errMap = map[string]string{
-				"message": actionError.Error(),
-				"stack":   actionError.Stack(),
-				"name":    "Error",
-			}
-		} else {
-			errMap = map[string]string{
-				"message": response.String(),
-				"name":    "Error",
-			}
-		}
 		values["success"] = false
-		values["error"] = errMap
+		values["error"] = pubsub.errorMap(response.Error())
 	} else {
 		values["success"] = true
 		values["data"] = response.Value()
 	}
 
-	message, err := pubsub.serializer.MapToPayload(&values)
-	if err != nil {
-		pubsub.logger.Error("sendResponse() Erro serializing the values: ", values, " error: ", err)
-		panic(err)
+	stream, isStream := response.Value().(io.Reader)
+	if isStream {
+		values["data"] = nil
+		values["stream"] = true
+		values["seq"] = 0
 	}
+	pubsub.setVersion("RES", targetNodeID, values)
 
-	pubsub.logger.Trace("sendResponse() targetNodeID: ", targetNodeID, " values: ", values, " message: ", message)
+	pubsub.logger.Trace("sendResponse() targetNodeID: ", targetNodeID, " values: ", values)
 
-	pubsub.transport.Publish("RES", targetNodeID, message)
+	if !pubsub.publishPacket("RES", targetNodeID, values) {
+		return
+	}
+	if isStream {
+		go pubsub.publishStream("RES", targetNodeID, context.ID(), "data", stream)
+	}
 }
 
 type ActionError interface {
@@ -486,9 +907,31 @@ type ActionError interface {
 // 3: send a response
 func (pubsub *PubSub) requestHandler() transit.TransportHandler {
 	return func(message moleculer.Payload) {
+		var stream *streamReceiver
+		if isStreamPacket(message) {
+			receiver := pubsub.streamReceiver("REQ", message)
+			pubsub.receiveStreamPacket("REQ", receiver, message, "params")
+			if message.Get("seq").Int() != 0 {
+				return
+			}
+			stream = receiver
+		}
 		values := pubsub.serializer.PayloadToContextMap(message)
+		if stream != nil {
+			values["params"] = stream.reader
+		}
+		sender := message.Get("sender").String()
 		context := context.ActionContext(pubsub.broker, values)
-		result := <-pubsub.broker.ActionDelegate(context)
+		if stream != nil {
+			// the handler consumes the stream while the next packets arrive.
+			go func() {
+				result := pubsub.runRequest(sender, context)
+				stream.reader.Close()
+				pubsub.sendResponse(context, result)
+			}()
+			return
+		}
+		result := pubsub.runRequest(sender, context)
 		pubsub.sendResponse(context, result)
 	}
 }
@@ -520,24 +963,34 @@ func (pubsub *PubSub) expectedNeighbours() int64 {
 
 // neighbours return the total number of known neighbours.
 func (pubsub *PubSub) neighbours() int64 {
+	pubsub.neighboursMutex.Lock()
+	defer pubsub.neighboursMutex.Unlock()
 	return int64(len(pubsub.knownNeighbours))
 }
 
+// InstanceID return the random ID of this broker instance.
+func (pubsub *PubSub) InstanceID() string {
+	return pubsub.instanceID
+}
+
 // broadcastNodeInfo send the local node info to the target node, if empty to all nodes.
 func (pubsub *PubSub) broadcastNodeInfo(targetNodeID string) {
 	payload := pubsub.broker.LocalNode().ExportAsMap()
 	payload["sender"] = payload["id"]
 	payload["neighbours"] = pubsub.neighbours()
-	payload["ver"] = version.MoleculerProtocol()
+	payload["instanceID"] = pubsub.instanceID
+	payload["heartbeatInterval"] = pubsub.broker.Config.HeartbeatFrequency.Seconds()
+	pubsub.setVersion("INFO", targetNodeID, payload)
+	payload["compression"] = serializer.SupportedCompressions
 
 	message, _ := pubsub.serializer.MapToPayload(&payload)
-	pubsub.transport.Publish("INFO", targetNodeID, message)
+	pubsub.currentTransport().Publish("INFO", targetNodeID, message)
 }
 
 func (pubsub *PubSub) discoverHandler() transit.TransportHandler {
 	return func(message moleculer.Payload) {
 		sender := message.Get("sender").String()
-		if pubsub.brokerStarted {
+		if pubsub.brokerStarted.isSet() {
 			pubsub.broadcastNodeInfo(sender)
 		} else {
 			pubsub.broker.Bus().Once("$broker.started", func(...interface{}) {
@@ -554,35 +1007,41 @@ func (pubsub *PubSub) emitRegistryEvent(command string) transit.TransportHandler
 	}
 }
 
-func (pubsub *PubSub) SendPing() {
+// SendPing send a PING to the node. The PONG updates the latency of the connection status.
+func (pubsub *PubSub) SendPing(nodeID string) {
 	ping := make(map[string]interface{})
-	sender := pubsub.broker.LocalNode().GetID()
-	ping["sender"] = sender
-	ping["ver"] = version.MoleculerProtocol()
-	ping["time"] = time.Now().Unix()
+	ping["sender"] = pubsub.broker.LocalNode().GetID()
+	pubsub.setVersion("PING", nodeID, ping)
+	ping["time"] = milliseconds(time.Now())
 	pingMessage, _ := pubsub.serializer.MapToPayload(&ping)
-	pubsub.transport.Publish("PING", sender, pingMessage)
+	pubsub.currentTransport().Publish("PING", nodeID, pingMessage)
+}
 
+func milliseconds(t time.Time) int64 {
+	return t.UnixNano() / int64(time.Millisecond)
 }
 
 func (pubsub *PubSub) pingHandler() transit.TransportHandler {
 	return func(message moleculer.Payload) {
 		pong := make(map[string]interface{})
 		sender := message.Get("sender").String()
-		pong["sender"] = sender
-		pong["ver"] = version.MoleculerProtocol()
-		pong["time"] = message.Get("time").Int()
-		pong["arrived"] = time.Now().Unix()
+		pong["sender"] = pubsub.broker.LocalNode().GetID()
+		pubsub.setVersion("PONG", sender, pong)
+		pong["time"] = message.Get("time").Int64()
+		pong["arrived"] = milliseconds(time.Now())
 
 		pongMessage, _ := pubsub.serializer.MapToPayload(&pong)
-		pubsub.transport.Publish("PONG", sender, pongMessage)
+		pubsub.currentTransport().Publish("PONG", sender, pongMessage)
 	}
 }
 
 func (pubsub *PubSub) pongHandler() transit.TransportHandler {
 	return func(message moleculer.Payload) {
-		now := time.Now().Unix()
+		now := milliseconds(time.Now())
 		elapsed := now - message.Get("time").Int64()
+		pubsub.statusMutex.Lock()
+		pubsub.status.Latency = time.Duration(elapsed) * time.Millisecond
+		pubsub.statusMutex.Unlock()
 		arrived := message.Get("arrived").Int64()
 		timeDiff := math.Round(
 			float64(now) - float64(arrived) - float64(elapsed)/2)
@@ -598,70 +1057,276 @@ func (pubsub *PubSub) pongHandler() transit.TransportHandler {
 
 func (pubsub *PubSub) subscribe() {
 	nodeID := pubsub.broker.LocalNode().GetID()
-	pubsub.transport.Subscribe("RES", nodeID, pubsub.validate(pubsub.reponseHandler()))
+	pubsub.currentTransport().Subscribe("RES", nodeID, pubsub.validate(pubsub.reponseHandler()))
 
-	pubsub.transport.Subscribe("REQ", nodeID, pubsub.validate(pubsub.requestHandler()))
+	requestHandler := pubsub.requestHandler()
+	pubsub.currentTransport().Subscribe("REQ", nodeID, pubsub.validate(pubsub.whenActive(requestHandler, pubsub.rejectRequest(requestHandler))))
 	//pubsub.transport.Subscribe("REQB", nodeID, pubsub.requestHandler())
-	pubsub.transport.Subscribe("EVENT", nodeID, pubsub.validate(pubsub.eventHandler()))
+	pubsub.currentTransport().Subscribe("EVENT", nodeID, pubsub.validate(pubsub.whenActive(pubsub.eventHandler(), pubsub.discardEvent)))
+
+	pubsub.currentTransport().Subscribe("HEARTBEAT", "", pubsub.validate(pubsub.emitRegistryEvent("HEARTBEAT")))
+	pubsub.currentTransport().Subscribe("DISCONNECT", "", pubsub.validate(pubsub.emitRegistryEvent("DISCONNECT")))
+	pubsub.currentTransport().Subscribe("INFO", "", pubsub.validate(pubsub.infoHandler()))
+	pubsub.currentTransport().Subscribe("INFO", nodeID, pubsub.validate(pubsub.infoHandler()))
+	pubsub.currentTransport().Subscribe("DISCOVER", nodeID, pubsub.validate(pubsub.discoverHandler()))
+	pubsub.currentTransport().Subscribe("DISCOVER", "", pubsub.validate(pubsub.discoverHandler()))
+	pubsub.currentTransport().Subscribe("PING", nodeID, pubsub.validate(pubsub.pingHandler()))
+	pubsub.currentTransport().Subscribe("PONG", nodeID, pubsub.validate(pubsub.pongHandler()))
+	pubsub.currentTransport().Subscribe("CANCEL", nodeID, pubsub.validate(pubsub.cancelHandler()))
+
+}
+
+// Pause stops processing the incoming requests and events. Requests are rejected and
+// events discarded until Resume is called. Heartbeats and discovery keep working, so
+// this node is not considered offline by the other nodes.
+func (pubsub *PubSub) Pause() {
+	pubsub.logger.Warn("PubSub - Transit paused. Incoming requests and events will be rejected.")
+	pubsub.paused.set(true)
+}
 
-	pubsub.transport.Subscribe("HEARTBEAT", "", pubsub.validate(pubsub.emitRegistryEvent("HEARTBEAT")))
-	pubsub.transport.Subscribe("DISCONNECT", "", pubsub.validate(pubsub.emitRegistryEvent("DISCONNECT")))
-	pubsub.transport.Subscribe("INFO", "", pubsub.validate(pubsub.emitRegistryEvent("INFO")))
-	pubsub.transport.Subscribe("INFO", nodeID, pubsub.validate(pubsub.emitRegistryEvent("INFO")))
-	pubsub.transport.Subscribe("DISCOVER", nodeID, pubsub.validate(pubsub.discoverHandler()))
-	pubsub.transport.Subscribe("DISCOVER", "", pubsub.validate(pubsub.discoverHandler()))
-	pubsub.transport.Subscribe("PING", nodeID, pubsub.validate(pubsub.pingHandler()))
-	pubsub.transport.Subscribe("PONG", nodeID, pubsub.validate(pubsub.pongHandler()))
+// Resume restart processing the incoming requests and events.
+func (pubsub *PubSub) Resume() {
+	pubsub.logger.Info("PubSub - Transit resumed.")
+	pubsub.paused.set(false)
+}
+
+func (pubsub *PubSub) IsPaused() bool {
+	return pubsub.paused.isSet()
+}
+
+// whenActive call the handler when transit is not paused, otherwise call the paused handler.
+// Packets of a stream that already started are always handled, so the stream is not broken.
+func (pubsub *PubSub) whenActive(handler transit.TransportHandler, paused transit.TransportHandler) transit.TransportHandler {
+	return func(message moleculer.Payload) {
+		if pubsub.paused.isSet() && !(isStreamPacket(message) && message.Get("seq").Int() > 0) {
+			paused(message)
+			return
+		}
+		handler(message)
+	}
+}
+
+// rejectRequest send an error response for the requests received while transit is paused.
+// Requests to the $node service are still handled, so the node can be resumed remotely.
+func (pubsub *PubSub) rejectRequest(handler transit.TransportHandler) transit.TransportHandler {
+	return func(message moleculer.Payload) {
+		if strings.HasPrefix(message.Get("action").String(), "$node.") {
+			handler(message)
+			return
+		}
+		values := pubsub.serializer.PayloadToContextMap(message)
+		context := context.ActionContext(pubsub.broker, values)
+		pubsub.logger.Warn("Transit is paused. Rejecting request: ", context.ActionName(), " from: ", context.TargetNodeID())
+		err := fmt.Errorf("NodePaused: Node '%s' is paused. Request '%s' is rejected.", pubsub.broker.LocalNode().GetID(), context.ActionName())
+		pubsub.sendResponse(context, payload.New(err))
+	}
+}
 
+func (pubsub *PubSub) discardEvent(message moleculer.Payload) {
+	pubsub.logger.Warn("Transit is paused. Discarding event: ", message.Get("event").String(), " from: ", message.Get("sender").String())
 }
 
 // sendDisconnect broadcast a DISCONNECT pkt to all nodes informing this one is stopping.
 func (pubsub *PubSub) sendDisconnect() {
 	payload := make(map[string]interface{})
 	payload["sender"] = pubsub.broker.LocalNode().GetID()
-	payload["ver"] = version.MoleculerProtocol()
+	pubsub.setVersion("DISCONNECT", "", payload)
 	msg, _ := pubsub.serializer.MapToPayload(&payload)
-	pubsub.transport.Publish("DISCONNECT", "", msg)
+	pubsub.currentTransport().Publish("DISCONNECT", "", msg)
 }
 
 // Disconnect : disconnect the transit's  transporter.
+// Disconnect wait for the pending requests to finish, publish the DISCONNECT packet so the
+// other nodes mark this node as offline right away and then close the transport.
 func (pubsub *PubSub) Disconnect() chan error {
-	endChan := make(chan error)
-	if !pubsub.isConnected {
+	endChan := make(chan error, 1)
+	if !pubsub.isConnected.isSet() {
 		endChan <- nil
 		return endChan
 	}
 	pubsub.logger.Info("PubSub - Disconnecting transport...")
+	// the other nodes don't publish the responses to a disconnected node.
+	pubsub.drainPendingRequests(pubsub.broker.Config.GracefulStopTimeout)
 	pubsub.sendDisconnect()
-	pubsub.isConnected = false
-	return pubsub.transport.Disconnect()
+	pubsub.isConnected.set(false)
+	pubsub.setConnected(false)
+	pubsub.stopped.set(true)
+	return pubsub.currentTransport().Disconnect()
+}
+
+// drainPendingRequests wait until there are no pending requests or the timeout is reached.
+func (pubsub *PubSub) drainPendingRequests(timeout time.Duration) {
+	start := time.Now()
+	for {
+		pubsub.pendingRequestsMutex.Lock()
+		pending := len(pubsub.pendingRequests)
+		pubsub.pendingRequestsMutex.Unlock()
+		if pending == 0 {
+			return
+		}
+		if time.Since(start) >= timeout {
+			pubsub.logger.Warn("PubSub - Disconnecting with ", pending, " pending requests.")
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
 }
 
-// Connect : connect the transit with the transporter, subscribe to all events and start publishing its node info
 func (pubsub *PubSub) Connect() chan error {
-	endChan := make(chan error)
-	if pubsub.isConnected {
+	endChan := make(chan error, 1)
+	if pubsub.isConnected.isSet() {
 		endChan <- nil
 		return endChan
 	}
+	pubsub.stopped.set(false)
+	return pubsub.connect()
+}
+
+func (pubsub *PubSub) connect() chan error {
+	endChan := make(chan error)
 	pubsub.logger.Debug("PubSub - Connecting transport...")
-	pubsub.transport = pubsub.createTransport()
+	transport := pubsub.createTransport()
+	pubsub.setTransport(transport)
 	go func() {
-		err := <-pubsub.transport.Connect()
+		err := <-transport.Connect()
 		if err == nil {
-			pubsub.isConnected = true
+			pubsub.flushingOffline.set(true)
+			pubsub.isConnected.set(true)
 			pubsub.logger.Debug("PubSub - Transport Connected!")
 
 			pubsub.subscribe()
-
+			pubsub.watchTransport(transport)
+			pubsub.setConnected(true)
+			pubsub.flushOffline()
+			pubsub.broker.Bus().EmitAsync("$transporter.connected", []interface{}{})
 		} else {
 			pubsub.logger.Debug("PubSub - Error connecting transport - error: ", err)
+			pubsub.transportError(err)
 		}
 		endChan <- err
 	}()
 	return endChan
 }
 
+// watchTransport listen for connection drops when the transport supports it.
+func (pubsub *PubSub) watchTransport(transport transit.Transport) {
+	notifier, ok := transport.(transit.DisconnectNotifier)
+	if !ok {
+		return
+	}
+	notifier.OnDisconnect(func(err error) {
+		if !pubsub.isConnected.isSet() || pubsub.currentTransport() != transport {
+			return
+		}
+		pubsub.logger.Warn("PubSub - Transport disconnected - error: ", err)
+		pubsub.isConnected.set(false)
+		pubsub.setConnected(false)
+		if err != nil {
+			pubsub.transportError(err)
+		}
+		pubsub.broker.Bus().EmitAsync("$transporter.disconnected", []interface{}{err})
+		go func() {
+			<-transport.Disconnect()
+		}()
+		if !pubsub.broker.Config.ReconnectPolicy.Disabled {
+			go pubsub.reconnect()
+		}
+	})
+}
+
+// currentTransport return the transport in use. The reconnect replaces it from another goroutine.
+func (pubsub *PubSub) currentTransport() transit.Transport {
+	pubsub.transportMutex.RLock()
+	defer pubsub.transportMutex.RUnlock()
+	return pubsub.transport
+}
+
+func (pubsub *PubSub) setTransport(transport transit.Transport) {
+	pubsub.transportMutex.Lock()
+	pubsub.transport = transport
+	pubsub.transportMutex.Unlock()
+}
+
+// atomicFlag is a boolean that is read and written by different goroutines.
+type atomicFlag int32
+
+func (flag *atomicFlag) set(value bool) {
+	var intValue int32
+	if value {
+		intValue = 1
+	}
+	atomic.StoreInt32((*int32)(flag), intValue)
+}
+
+func (flag *atomicFlag) isSet() bool {
+	return atomic.LoadInt32((*int32)(flag)) == 1
+}
+
+// Status return the state of the connection with the transporter.
+func (pubsub *PubSub) Status() transit.ConnectionStatus {
+	pubsub.statusMutex.Lock()
+	defer pubsub.statusMutex.Unlock()
+	return pubsub.status
+}
+
+func (pubsub *PubSub) setConnected(connected bool) {
+	pubsub.statusMutex.Lock()
+	pubsub.status.Connected = connected
+	pubsub.statusMutex.Unlock()
+}
+
+// transportError store the error as the last error and emit the $transporter.error event.
+func (pubsub *PubSub) transportError(err error) {
+	pubsub.statusMutex.Lock()
+	pubsub.status.LastError = err
+	pubsub.statusMutex.Unlock()
+	pubsub.broker.Bus().EmitAsync("$transporter.error", []interface{}{err})
+}
+
+// reconnect keeps trying to connect the transport using the exponential backoff of the reconnect policy.
+func (pubsub *PubSub) reconnect() {
+	policy := pubsub.broker.Config.ReconnectPolicy
+	for attempt := 0; policy.Retries == 0 || attempt < policy.Retries; attempt++ {
+		delay := reconnectDelay(policy, attempt, rand.Float64())
+		pubsub.logger.Info("PubSub - Reconnecting transport in ", delay, " attempt: ", attempt+1)
+		time.Sleep(delay)
+		if pubsub.stopped.isSet() {
+			return
+		}
+		err := <-pubsub.connect()
+		if err == nil {
+			pubsub.logger.Info("PubSub - Transport reconnected!")
+			pubsub.statusMutex.Lock()
+			pubsub.status.Reconnects++
+			pubsub.statusMutex.Unlock()
+			pubsub.broker.Bus().EmitAsync("$transporter.reconnected", []interface{}{})
+			if pubsub.brokerStarted.isSet() {
+				pubsub.broadcastNodeInfo("")
+			}
+			return
+		}
+	}
+	pubsub.logger.Error("PubSub - Could not reconnect transport after ", policy.Retries, " attempts.")
+	// no more attempts, so the packets are not buffered anymore until Connect is called again.
+	pubsub.stopped.set(true)
+	pubsub.dropOffline(fmt.Errorf("Could not reconnect the transporter after %d attempts.", policy.Retries))
+}
+
+// reconnectDelay return the delay before the reconnect attempt. random must be between 0 and 1
+// and is used to apply the jitter.
+func reconnectDelay(policy moleculer.ReconnectPolicy, attempt int, random float64) time.Duration {
+	factor := policy.Factor
+	if factor < 1 {
+		factor = 1
+	}
+	delay := float64(policy.Delay) * math.Pow(factor, float64(attempt))
+	if policy.MaxDelay > 0 && delay > float64(policy.MaxDelay) {
+		delay = float64(policy.MaxDelay)
+	}
+	delay = delay * (1 - policy.Jitter + 2*policy.Jitter*random)
+	return time.Duration(delay)
+}
+
 func (pubsub *PubSub) Ready() {
 
 }
@@ -0,0 +1,236 @@
+package pubsub
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// streamChunkSize is the max number of bytes sent in each stream packet.
+const streamChunkSize = 64 * 1024
+
+// streamReceiver reassemble the stream packets in the seq order and write
+// the data into a pipe, which is consumed by the action handler or the caller.
+type streamReceiver struct {
+	sender  string
+	writer  *io.PipeWriter
+	reader  *io.PipeReader
+	nextSeq int
+	last    int
+	pending map[int][]byte
+	chunks  [][]byte
+	closed  bool
+	err     error
+	cond    *sync.Cond
+}
+
+func createStreamReceiver(sender string) *streamReceiver {
+	reader, writer := io.Pipe()
+	receiver := &streamReceiver{
+		sender:  sender,
+		reader:  reader,
+		writer:  writer,
+		last:    -1,
+		pending: map[int][]byte{},
+		cond:    sync.NewCond(&sync.Mutex{}),
+	}
+	go receiver.writeLoop()
+	return receiver
+}
+
+// receive store the packet and queue the data of all packets that are in sequence.
+// It returns true when the last packet of the stream was received and all the data queued.
+func (receiver *streamReceiver) receive(seq int, data []byte, last bool) bool {
+	receiver.cond.L.Lock()
+	defer receiver.cond.L.Unlock()
+	if last {
+		receiver.last = seq
+	}
+	receiver.pending[seq] = data
+	for {
+		chunk, exists := receiver.pending[receiver.nextSeq]
+		if !exists {
+			break
+		}
+		delete(receiver.pending, receiver.nextSeq)
+		if len(chunk) > 0 {
+			receiver.chunks = append(receiver.chunks, chunk)
+		}
+		receiver.nextSeq++
+	}
+	done := receiver.last >= 0 && receiver.nextSeq > receiver.last
+	if done {
+		receiver.closed = true
+	}
+	receiver.cond.Signal()
+	return done
+}
+
+// fail close the stream with an error. The reader receives the error after the queued data.
+func (receiver *streamReceiver) fail(err error) {
+	receiver.cond.L.Lock()
+	defer receiver.cond.L.Unlock()
+	receiver.err = err
+	receiver.closed = true
+	receiver.cond.Signal()
+}
+
+// writeLoop write the queued chunks into the pipe, so a slow reader does not block the transport.
+func (receiver *streamReceiver) writeLoop() {
+	for {
+		receiver.cond.L.Lock()
+		for len(receiver.chunks) == 0 && !receiver.closed {
+			receiver.cond.Wait()
+		}
+		chunks := receiver.chunks
+		receiver.chunks = nil
+		closed := receiver.closed
+		err := receiver.err
+		receiver.cond.L.Unlock()
+
+		for _, chunk := range chunks {
+			if _, writeErr := receiver.writer.Write(chunk); writeErr != nil {
+				return
+			}
+		}
+		if closed {
+			receiver.writer.CloseWithError(err)
+			return
+		}
+	}
+}
+
+// isStreamPacket check if the packet is part of a stream. The last packet of a stream
+// has the stream flag false, but still carries the seq field.
+func isStreamPacket(message moleculer.Payload) bool {
+	return message.Get("stream").Bool() || message.Get("seq").Exists()
+}
+
+// streamData decode the data of a stream packet.
+func streamData(message moleculer.Payload, field string) ([]byte, error) {
+	value := message.Get(field)
+	if !value.Exists() || value.Value() == nil {
+		return nil, nil
+	}
+	return base64.StdEncoding.DecodeString(value.String())
+}
+
+func streamKey(sender, id string) string {
+	return sender + "." + id
+}
+
+// streamReceiver return the receiver for the stream, creating it on the first packet.
+func (pubsub *PubSub) streamReceiver(command string, message moleculer.Payload) *streamReceiver {
+	sender := message.Get("sender").String()
+	key := command + "." + streamKey(sender, message.Get("id").String())
+	pubsub.streamsMutex.Lock()
+	defer pubsub.streamsMutex.Unlock()
+	receiver, exists := pubsub.streams[key]
+	if !exists {
+		receiver = createStreamReceiver(sender)
+		pubsub.streams[key] = receiver
+	}
+	return receiver
+}
+
+// receiveStreamPacket add the packet data to the stream and remove the stream when complete.
+func (pubsub *PubSub) receiveStreamPacket(command string, receiver *streamReceiver, message moleculer.Payload, field string) {
+	seq := message.Get("seq").Int()
+	data, err := streamData(message, field)
+	if err != nil {
+		pubsub.logger.Error("Invalid stream packet received from: ", message.Get("sender").String(), " error: ", err)
+		data = nil
+	}
+	if receiver.receive(seq, data, !message.Get("stream").Bool()) {
+		pubsub.removeStream(command, message)
+	}
+}
+
+func (pubsub *PubSub) removeStream(command string, message moleculer.Payload) {
+	key := command + "." + streamKey(message.Get("sender").String(), message.Get("id").String())
+	pubsub.streamsMutex.Lock()
+	delete(pubsub.streams, key)
+	pubsub.streamsMutex.Unlock()
+}
+
+// failStreamsByNode close with an error all the streams being received from a node.
+func (pubsub *PubSub) failStreamsByNode(nodeID string) {
+	pubsub.streamsMutex.Lock()
+	defer pubsub.streamsMutex.Unlock()
+	for key, receiver := range pubsub.streams {
+		if receiver.sender == nodeID {
+			receiver.fail(fmt.Errorf("Node %s disconnected. The stream was canceled.", nodeID))
+			delete(pubsub.streams, key)
+		}
+	}
+}
+
+// publishStream read the stream and publish a packet for each chunk. The first packet
+// (seq 0) was already sent with the request or response, so the chunks start at seq 1.
+// The last packet has the stream flag false.
+func (pubsub *PubSub) publishStream(command, targetNodeID, id, field string, reader io.Reader) {
+	buffer := make([]byte, streamChunkSize)
+	seq := 1
+	for {
+		read, err := reader.Read(buffer)
+		if read > 0 {
+			chunk := make([]byte, read)
+			copy(chunk, buffer[:read])
+			pubsub.publishStreamPacket(command, targetNodeID, id, field, seq, chunk, true, nil)
+			seq++
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			pubsub.logger.Error("publishStream() Error reading the stream - id: ", id, " error: ", err)
+			pubsub.publishStreamPacket(command, targetNodeID, id, field, seq, nil, false, err)
+			return
+		}
+	}
+	pubsub.publishStreamPacket(command, targetNodeID, id, field, seq, nil, false, nil)
+	if closer, ok := reader.(io.Closer); ok {
+		closer.Close()
+	}
+}
+
+func (pubsub *PubSub) publishStreamPacket(command, targetNodeID, id, field string, seq int, data []byte, stream bool, streamErr error) {
+	values := map[string]interface{}{
+		"sender": pubsub.broker.LocalNode().GetID(),
+		"id":     id,
+		"seq":    seq,
+		"stream": stream,
+		field:    nil,
+	}
+	if data != nil {
+		values[field] = base64.StdEncoding.EncodeToString(data)
+	}
+	if command == "RES" {
+		values["success"] = streamErr == nil
+		if streamErr != nil {
+			values["error"] = map[string]string{
+				"message": streamErr.Error(),
+				"name":    "Error",
+			}
+		}
+	}
+	pubsub.setVersion(command, targetNodeID, values)
+	message, err := pubsub.serializer.MapToPayload(&values)
+	if err != nil {
+		pubsub.logger.Error("publishStreamPacket() Error serializing the stream packet - id: ", id, " error: ", err)
+		return
+	}
+	pubsub.currentTransport().Publish(command, targetNodeID, message)
+}
+
+// streamError return the error of a failed response stream packet.
+func streamError(message moleculer.Payload) error {
+	if message.Get("error").Get("message").Exists() {
+		return errors.New(message.Get("error").Get("message").String())
+	}
+	return errors.New(message.Get("error").String())
+}
@@ -0,0 +1,35 @@
+package pubsub
+
+import (
+	"errors"
+	"io/ioutil"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Stream receiver", func() {
+
+	It("should write the packets in the seq order", func() {
+		receiver := createStreamReceiver("node-1")
+		Expect(receiver.receive(2, []byte("world"), false)).Should(BeFalse())
+		Expect(receiver.receive(3, nil, true)).Should(BeFalse())
+		Expect(receiver.receive(0, nil, false)).Should(BeFalse())
+		Expect(receiver.receive(1, []byte("hello "), false)).Should(BeTrue())
+
+		bts, err := ioutil.ReadAll(receiver.reader)
+		Expect(err).Should(BeNil())
+		Expect(string(bts)).Should(Equal("hello world"))
+	})
+
+	It("should return the error to the reader when the stream fails", func() {
+		receiver := createStreamReceiver("node-1")
+		receiver.receive(0, nil, false)
+		receiver.receive(1, []byte("partial"), false)
+		receiver.fail(errors.New("stream failed"))
+
+		bts, err := ioutil.ReadAll(receiver.reader)
+		Expect(string(bts)).Should(Equal("partial"))
+		Expect(err).Should(MatchError("stream failed"))
+	})
+})
@@ -0,0 +1,200 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/go-redis/redis"
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/serializer"
+	"github.com/moleculer-go/moleculer/transit"
+	log "github.com/sirupsen/logrus"
+)
+
+var errNotConnected = errors.New("No connection to Redis")
+
+type RedisTransporter struct {
+	prefix        string
+	urls          []string
+	current       int
+	client        *redis.Client
+	logger        *log.Entry
+	serializer    serializer.Serializer
+	subscriptions []*redis.PubSub
+	// clientMutex guard the client and the subscriptions, used by the transit and the connection goroutines.
+	clientMutex *sync.Mutex
+}
+
+type RedisOptions struct {
+	URL        string
+	Logger     *log.Entry
+	Serializer serializer.Serializer
+}
+
+func CreateRedisTransporter(options RedisOptions) transit.Transport {
+	return &RedisTransporter{
+		urls:          transit.ParseURLs(options.URL),
+		logger:        options.Logger,
+		serializer:    options.Serializer,
+		subscriptions: []*redis.PubSub{},
+		clientMutex:   &sync.Mutex{},
+	}
+}
+
+func (t *RedisTransporter) Connect() chan error {
+	endChan := make(chan error)
+	go func() {
+		if len(t.urls) == 0 {
+			endChan <- errors.New("Invalid Redis url. No url informed.")
+			return
+		}
+		var err error
+		// try the urls in order, starting from the last one that worked.
+		for attempt := 0; attempt < len(t.urls); attempt++ {
+			index := (t.current + attempt) % len(t.urls)
+			var client *redis.Client
+			client, err = t.connect(t.urls[index])
+			if err == nil {
+				t.logger.Info("Connected to ", t.urls[index])
+				t.current = index
+				t.clientMutex.Lock()
+				t.client = client
+				t.clientMutex.Unlock()
+				endChan <- nil
+				return
+			}
+		}
+		endChan <- err
+	}()
+	return endChan
+}
+
+func (t *RedisTransporter) connect(url string) (*redis.Client, error) {
+	t.logger.Debug("Redis Connect() - url: ", url)
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		t.logger.Error("Redis Connect() - Invalid url: ", url, " error: ", err)
+		return nil, errors.New(fmt.Sprint("Invalid Redis url. error: ", err, " url: ", url))
+	}
+	client := redis.NewClient(opts)
+	if err := client.Ping().Err(); err != nil {
+		t.logger.Error("Redis Connect() - Error: ", err, " url: ", url)
+		client.Close()
+		return nil, errors.New(fmt.Sprint("Error connection to Redis. error: ", err, " url: ", url))
+	}
+	return client, nil
+}
+
+func (t *RedisTransporter) Disconnect() chan error {
+	endChan := make(chan error)
+	go func() {
+		t.clientMutex.Lock()
+		client, subscriptions := t.client, t.subscriptions
+		t.client = nil
+		t.subscriptions = []*redis.PubSub{}
+		t.clientMutex.Unlock()
+		if client == nil {
+			endChan <- nil
+			return
+		}
+		for _, sub := range subscriptions {
+			if err := sub.Close(); err != nil {
+				t.logger.Error(err)
+			}
+		}
+		endChan <- client.Close()
+	}()
+	return endChan
+}
+
+// topicName return the channel name used by moleculer-js. Example: MOL.REQ.nodeID
+func (t *RedisTransporter) topicName(command string, nodeID string) string {
+	parts := []string{t.prefix, command}
+	if nodeID != "" {
+		parts = append(parts, nodeID)
+	}
+	return strings.Join(parts, ".")
+}
+
+// currentClient return the connected client, nil when not connected.
+func (t *RedisTransporter) currentClient() *redis.Client {
+	t.clientMutex.Lock()
+	defer t.clientMutex.Unlock()
+	return t.client
+}
+
+func (t *RedisTransporter) Subscribe(command, nodeID string, handler transit.TransportHandler) {
+	client := t.currentClient()
+	if client == nil {
+		t.logger.Error("redis.Subscribe() No connection :( -> command: ", command, " nodeID: ", nodeID)
+		return
+	}
+
+	topic := t.topicName(command, nodeID)
+
+	sub := client.Subscribe(topic)
+	if _, err := sub.Receive(); err != nil {
+		t.logger.Error("Cannot subscribe: ", topic, " error: ", err)
+		sub.Close()
+		return
+	}
+	t.clientMutex.Lock()
+	t.subscriptions = append(t.subscriptions, sub)
+	t.clientMutex.Unlock()
+
+	go func() {
+		for msg := range sub.Channel() {
+			data := []byte(msg.Payload)
+			payload := t.serializer.BytesToPayload(&data)
+			t.logger.Debug(fmt.Sprintf("Incoming %s packet from '%s'", topic, payload.Get("sender").String()))
+			handler(payload)
+		}
+	}()
+}
+
+// Publish log the message dropped when not connected, the errors of the redis server are raised
+// as panics, so the transit routes the packet to the dead letters.
+func (t *RedisTransporter) Publish(command, nodeID string, message moleculer.Payload) {
+	err := t.publish(command, nodeID, message)
+	if err == errNotConnected {
+		t.logger.Error("redis.Publish() No connection :( -> command: ", command, " nodeID: ", nodeID)
+	} else if err != nil {
+		panic(err)
+	}
+}
+
+// PublishConfirm publish the message and return the error instead of panicking. The redis
+// PUBLISH command only returns after the server received the message.
+func (t *RedisTransporter) PublishConfirm(command, nodeID string, message moleculer.Payload) error {
+	return t.publish(command, nodeID, message)
+}
+
+func (t *RedisTransporter) publish(command, nodeID string, message moleculer.Payload) error {
+	client := t.currentClient()
+	if client == nil {
+		return errNotConnected
+	}
+
+	topic := t.topicName(command, nodeID)
+	t.logger.Debug("redis.Publish() command: ", command, " topic: ", topic, " nodeID: ", nodeID)
+	t.logger.Trace("message: \n", message, "\n - end")
+	err := client.Publish(topic, t.serializer.PayloadToBytes(message)).Err()
+	if err != nil {
+		t.logger.Error("Error on publish: error: ", err, " command: ", command, " topic: ", topic)
+		return err
+	}
+	return nil
+}
+
+func (t *RedisTransporter) SetPrefix(prefix string) {
+	t.prefix = prefix
+}
+
+func (t *RedisTransporter) SetNodeID(nodeID string) {
+}
+
+func (t *RedisTransporter) SetSerializer(serializer serializer.Serializer) {
+	t.serializer = serializer
+}
@@ -0,0 +1,57 @@
+package redis
+
+import (
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/serializer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("Redis Transit", func() {
+	logger := log.WithField("unit test pkg", "redis_test")
+
+	It("should use moleculer-js compatible channel names", func() {
+		transporter := CreateRedisTransporter(RedisOptions{URL: "redis://localhost:6379", Logger: logger}).(*RedisTransporter)
+		transporter.SetPrefix("MOL")
+		Expect(transporter.topicName("REQ", "node-1")).Should(Equal("MOL.REQ.node-1"))
+		Expect(transporter.topicName("INFO", "")).Should(Equal("MOL.INFO"))
+
+		transporter.SetPrefix("MOL-staging")
+		Expect(transporter.topicName("EVENT", "node-2")).Should(Equal("MOL-staging.EVENT.node-2"))
+	})
+
+	It("should fail to connect with an invalid url", func() {
+		transporter := CreateRedisTransporter(RedisOptions{
+			URL:        "http://not-redis",
+			Logger:     logger,
+			Serializer: serializer.CreateJSONSerializer(logger),
+		})
+		Expect(<-transporter.Connect()).Should(HaveOccurred())
+	})
+
+	It("should keep the failover order of multiple urls", func() {
+		transporter := CreateRedisTransporter(RedisOptions{
+			URL:        "http://not-redis,http://not-redis-either",
+			Logger:     logger,
+			Serializer: serializer.CreateJSONSerializer(logger),
+		}).(*RedisTransporter)
+		Expect(transporter.urls).Should(Equal([]string{"http://not-redis", "http://not-redis-either"}))
+		err := <-transporter.Connect()
+		Expect(err).Should(HaveOccurred())
+		Expect(err.Error()).Should(ContainSubstring("not-redis-either"))
+	})
+
+	It("should not panic when publishing or subscribing without a connection", func() {
+		transporter := CreateRedisTransporter(RedisOptions{URL: "redis://localhost:6379", Logger: logger})
+		Expect(func() {
+			transporter.Publish("INFO", "", nil)
+			transporter.Subscribe("INFO", "", func(moleculer.Payload) {})
+		}).ShouldNot(Panic())
+	})
+
+	It("should return the connection error when confirming a publish without a connection", func() {
+		transporter := CreateRedisTransporter(RedisOptions{URL: "redis://localhost:6379", Logger: logger}).(*RedisTransporter)
+		Expect(transporter.PublishConfirm("EVENT", "node-1", nil)).Should(MatchError(errNotConnected))
+	})
+})
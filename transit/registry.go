@@ -0,0 +1,44 @@
+package transit
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/moleculer-go/moleculer/serializer"
+	log "github.com/sirupsen/logrus"
+)
+
+// TransporterConfig is the information a TransporterFactory receives to create a transport.
+type TransporterConfig struct {
+	URL        string
+	NodeID     string
+	Logger     *log.Entry
+	Serializer serializer.Serializer
+}
+
+type TransporterFactory func(config TransporterConfig) Transport
+
+var transporters = map[string]TransporterFactory{}
+var transportersMutex = &sync.RWMutex{}
+
+// RegisterTransporter register a transport factory for a name. The name is matched
+// (case insensitive) against the url scheme of the broker transporter config,
+// or the whole config value when it is not an url. Example: "nats" matches
+// "nats://localhost:4222" and "NATS".
+func RegisterTransporter(name string, factory TransporterFactory) {
+	transportersMutex.Lock()
+	defer transportersMutex.Unlock()
+	transporters[strings.ToLower(name)] = factory
+}
+
+// FindTransporter return the factory registered for the transporter config.
+func FindTransporter(transporter string) (TransporterFactory, bool) {
+	name := transporter
+	if index := strings.Index(transporter, "://"); index > -1 {
+		name = transporter[:index]
+	}
+	transportersMutex.RLock()
+	defer transportersMutex.RUnlock()
+	factory, exists := transporters[strings.ToLower(name)]
+	return factory, exists
+}
@@ -0,0 +1,30 @@
+package transit_test
+
+import (
+	"github.com/moleculer-go/moleculer/transit"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transporter registry", func() {
+
+	It("Should find a registered transporter by url scheme or name", func() {
+		urls := []string{}
+		transit.RegisterTransporter("Custom", func(config transit.TransporterConfig) transit.Transport {
+			urls = append(urls, config.URL)
+			return nil
+		})
+
+		factory, exists := transit.FindTransporter("custom://localhost:1234")
+		Expect(exists).Should(BeTrue())
+		factory(transit.TransporterConfig{URL: "custom://localhost:1234"})
+		Expect(urls).Should(Equal([]string{"custom://localhost:1234"}))
+
+		_, exists = transit.FindTransporter("CUSTOM")
+		Expect(exists).Should(BeTrue())
+
+		_, exists = transit.FindTransporter("unknown://localhost")
+		Expect(exists).Should(BeFalse())
+	})
+
+})
@@ -1,6 +1,8 @@
 package transit
 
 import (
+	"time"
+
 	"github.com/moleculer-go/moleculer"
 	"github.com/moleculer-go/moleculer/serializer"
 )
@@ -11,6 +13,8 @@ type ValidateMsgFunc func(moleculer.Payload) bool
 
 type Transit interface {
 	Emit(moleculer.BrokerContext)
+	//EmitConfirmed publish the event and wait for the transporter acknowledgment, retrying on failure.
+	EmitConfirmed(moleculer.BrokerContext) error
 	Request(moleculer.BrokerContext) chan moleculer.Payload
 	Connect() chan error
 	Disconnect() chan error
@@ -19,6 +23,30 @@ type Transit interface {
 	//DiscoverNodes checks if there are neighbours and return true if any are found ;).
 	DiscoverNodes() chan bool
 	SendHeartbeat()
+	//SendPing send a PING to the node. The round-trip time is emitted in the $node.pong event.
+	SendPing(nodeID string)
+
+	//Pause stops processing the incoming requests and events, heartbeats and discovery keep working.
+	Pause()
+	Resume()
+	IsPaused() bool
+
+	//Status return the state of the connection with the transporter.
+	Status() ConnectionStatus
+
+	//InstanceID identify this broker instance in the INFO and HEARTBEAT packets, so the
+	//registry can detect another instance using the same node ID.
+	InstanceID() string
+}
+
+// ConnectionStatus is the state of the connection with the transporter.
+type ConnectionStatus struct {
+	Connected bool
+	// Reconnects is the number of times the transport was reconnected after a connection drop.
+	Reconnects int
+	LastError  error
+	// Latency is the round-trip time of the last PING/PONG with a remote node.
+	Latency time.Duration
 }
 
 type Transport interface {
@@ -31,3 +59,15 @@ type Transport interface {
 	SetNodeID(nodeID string)
 	SetSerializer(serializer serializer.Serializer)
 }
+
+// ConfirmTransport is implemented by transports that can wait for the transporter
+// to acknowledge a published message (e.g. AMQP publisher confirms, Kafka acks, STAN).
+type ConfirmTransport interface {
+	PublishConfirm(command, nodeID string, message moleculer.Payload) error
+}
+
+// DisconnectNotifier is implemented by transports that can detect when the
+// connection is lost, so transit can reconnect.
+type DisconnectNotifier interface {
+	OnDisconnect(handler func(err error))
+}
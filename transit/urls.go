@@ -0,0 +1,17 @@
+package transit
+
+import "strings"
+
+// ParseURLs return the list of urls from a transporter config with multiple
+// comma separated urls. Example: "nats://a:4222,nats://b:4222". The order is
+// kept, so transporters can use it as the failover order.
+func ParseURLs(transporter string) []string {
+	urls := []string{}
+	for _, item := range strings.Split(transporter, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			urls = append(urls, item)
+		}
+	}
+	return urls
+}
@@ -0,0 +1,18 @@
+package transit_test
+
+import (
+	"github.com/moleculer-go/moleculer/transit"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Transporter urls", func() {
+
+	It("Should parse multiple urls keeping the order", func() {
+		Expect(transit.ParseURLs("nats://a:4222,nats://b:4222")).Should(Equal([]string{"nats://a:4222", "nats://b:4222"}))
+		Expect(transit.ParseURLs(" nats://a:4222 , nats://b:4222,")).Should(Equal([]string{"nats://a:4222", "nats://b:4222"}))
+		Expect(transit.ParseURLs("redis://localhost:6379")).Should(Equal([]string{"redis://localhost:6379"}))
+		Expect(transit.ParseURLs("")).Should(BeEmpty())
+	})
+
+})
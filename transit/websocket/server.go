@@ -0,0 +1,152 @@
+package websocket
+
+import (
+	"net"
+	"net/http"
+	"sync"
+
+	ws "github.com/gorilla/websocket"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	frameSubscribe = "sub"
+	framePublish   = "pub"
+)
+
+// frame is the message exchanged between the transporters and the server.
+type frame struct {
+	Type  string `json:"type"`
+	Topic string `json:"topic"`
+	Data  []byte `json:"data,omitempty"`
+}
+
+type serverClient struct {
+	conn   *ws.Conn
+	mutex  *sync.Mutex
+	topics map[string]bool
+}
+
+func (client *serverClient) send(message frame) error {
+	client.mutex.Lock()
+	defer client.mutex.Unlock()
+	return client.conn.WriteJSON(message)
+}
+
+// Server is a websocket hub that relays published messages to all the
+// clients subscribed to the topic.
+type Server struct {
+	logger   *log.Entry
+	upgrader ws.Upgrader
+	listener net.Listener
+	http     *http.Server
+
+	clients      map[*serverClient]bool
+	clientsMutex *sync.RWMutex
+}
+
+func CreateServer(logger *log.Entry) *Server {
+	return &Server{
+		logger: logger,
+		upgrader: ws.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		clients:      map[*serverClient]bool{},
+		clientsMutex: &sync.RWMutex{},
+	}
+}
+
+// Listen starts the server on the given address. Use Addr() to find the
+// address when the port is not informed (e.g. 127.0.0.1:0).
+func (server *Server) Listen(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	server.listener = listener
+	server.http = &http.Server{Handler: server}
+	go func() {
+		if err := server.http.Serve(listener); err != nil && err != http.ErrServerClosed {
+			server.logger.Error("WebSocket server stopped. error: ", err)
+		}
+	}()
+	server.logger.Info("WebSocket server listening on ", listener.Addr().String())
+	return nil
+}
+
+// Addr return the address the server is listening on.
+func (server *Server) Addr() string {
+	if server.listener == nil {
+		return ""
+	}
+	return server.listener.Addr().String()
+}
+
+func (server *Server) Close() error {
+	if server.http == nil {
+		return nil
+	}
+	err := server.http.Close()
+	server.clientsMutex.Lock()
+	for client := range server.clients {
+		client.conn.Close()
+		delete(server.clients, client)
+	}
+	server.clientsMutex.Unlock()
+	return err
+}
+
+// ServeHTTP upgrades the request to a websocket connection and handles the client frames.
+func (server *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := server.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		server.logger.Error("Could not upgrade connection. error: ", err)
+		return
+	}
+	client := &serverClient{conn: conn, mutex: &sync.Mutex{}, topics: map[string]bool{}}
+	server.clientsMutex.Lock()
+	server.clients[client] = true
+	server.clientsMutex.Unlock()
+
+	defer func() {
+		server.clientsMutex.Lock()
+		delete(server.clients, client)
+		server.clientsMutex.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		var message frame
+		if err := conn.ReadJSON(&message); err != nil {
+			server.logger.Debug("Client disconnected: ", conn.RemoteAddr(), " error: ", err)
+			return
+		}
+		switch message.Type {
+		case frameSubscribe:
+			server.clientsMutex.Lock()
+			client.topics[message.Topic] = true
+			server.clientsMutex.Unlock()
+		case framePublish:
+			server.relay(message)
+		default:
+			server.logger.Warn("Unknown frame type: ", message.Type)
+		}
+	}
+}
+
+func (server *Server) relay(message frame) {
+	server.clientsMutex.RLock()
+	targets := []*serverClient{}
+	for client := range server.clients {
+		if client.topics[message.Topic] {
+			targets = append(targets, client)
+		}
+	}
+	server.clientsMutex.RUnlock()
+
+	for _, client := range targets {
+		if err := client.send(message); err != nil {
+			server.logger.Error("Error relaying message to: ", client.conn.RemoteAddr(), " error: ", err)
+		}
+	}
+}
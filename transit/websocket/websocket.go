@@ -0,0 +1,220 @@
+package websocket
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	ws "github.com/gorilla/websocket"
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/serializer"
+	"github.com/moleculer-go/moleculer/transit"
+	log "github.com/sirupsen/logrus"
+)
+
+type WebSocketTransporter struct {
+	prefix     string
+	url        string
+	listen     string
+	logger     *log.Entry
+	serializer serializer.Serializer
+
+	server     *Server
+	conn       *ws.Conn
+	writeMutex *sync.Mutex
+
+	handlers      map[string][]transit.TransportHandler
+	handlersMutex *sync.RWMutex
+	onDisconnect  func(err error)
+}
+
+type WebSocketOptions struct {
+	// URL of the websocket server to connect to. Example: ws://localhost:5000
+	URL string
+	// Listen is the address of the embedded server. When informed this node
+	// starts a server and the other nodes can connect to it. In the broker Transporter
+	// config it is set with the listen param of the url, e.g. ws://?listen=:4000.
+	Listen     string
+	Logger     *log.Entry
+	Serializer serializer.Serializer
+}
+
+func CreateWebSocketTransporter(options WebSocketOptions) transit.Transport {
+	return &WebSocketTransporter{
+		url:           options.URL,
+		listen:        options.Listen,
+		logger:        options.Logger,
+		serializer:    options.Serializer,
+		writeMutex:    &sync.Mutex{},
+		handlers:      map[string][]transit.TransportHandler{},
+		handlersMutex: &sync.RWMutex{},
+	}
+}
+
+// Addr return the address of the embedded server, or empty when this node is only a client.
+func (t *WebSocketTransporter) Addr() string {
+	if t.server == nil {
+		return ""
+	}
+	return t.server.Addr()
+}
+
+func (t *WebSocketTransporter) Connect() chan error {
+	endChan := make(chan error)
+	go func() {
+		urls := transit.ParseURLs(t.url)
+		if t.listen != "" {
+			server := CreateServer(t.logger.WithField("websocket", "server"))
+			if err := server.Listen(t.listen); err != nil {
+				t.logger.Error("WebSocket Connect() - Could not start server on: ", t.listen, " error: ", err)
+				endChan <- errors.New(fmt.Sprint("Error starting WebSocket server. error: ", err, " address: ", t.listen))
+				return
+			}
+			t.server = server
+			if len(urls) == 0 {
+				urls = []string{"ws://" + server.Addr()}
+			}
+		}
+
+		// the urls are tried in order until one of the servers accepts the connection.
+		var conn *ws.Conn
+		var url string
+		err := errors.New("No WebSocket url informed.")
+		for _, url = range urls {
+			t.logger.Debug("WebSocket Connect() - url: ", url)
+			conn, _, err = ws.DefaultDialer.Dial(url, nil)
+			if err == nil {
+				break
+			}
+			t.logger.Warn("WebSocket Connect() - Could not connect to: ", url, " error: ", err)
+		}
+		if err != nil {
+			t.logger.Error("WebSocket Connect() - Error: ", err, " urls: ", urls)
+			if t.server != nil {
+				t.server.Close()
+				t.server = nil
+			}
+			endChan <- errors.New(fmt.Sprint("Error connection to WebSocket server. error: ", err, " urls: ", urls))
+			return
+		}
+
+		t.logger.Info("Connected to ", url)
+		t.conn = conn
+		go t.readLoop(conn)
+		endChan <- nil
+	}()
+	return endChan
+}
+
+func (t *WebSocketTransporter) readLoop(conn *ws.Conn) {
+	for {
+		var message frame
+		if err := conn.ReadJSON(&message); err != nil {
+			t.logger.Debug("WebSocket read loop stopped. error: ", err)
+			t.writeMutex.Lock()
+			dropped := t.conn == conn
+			t.writeMutex.Unlock()
+			if dropped && t.onDisconnect != nil {
+				t.onDisconnect(err)
+			}
+			return
+		}
+		payload := t.serializer.BytesToPayload(&message.Data)
+		t.logger.Debug(fmt.Sprintf("Incoming %s packet from '%s'", message.Topic, payload.Get("sender").String()))
+		t.handlersMutex.RLock()
+		handlers := t.handlers[message.Topic]
+		t.handlersMutex.RUnlock()
+		for _, handler := range handlers {
+			handler(payload)
+		}
+	}
+}
+
+// OnDisconnect register a handler called when the connection to the server is lost.
+func (t *WebSocketTransporter) OnDisconnect(handler func(err error)) {
+	t.onDisconnect = handler
+}
+
+func (t *WebSocketTransporter) Disconnect() chan error {
+	endChan := make(chan error)
+	go func() {
+		if t.conn == nil {
+			endChan <- nil
+			return
+		}
+		t.writeMutex.Lock()
+		t.conn.WriteMessage(ws.CloseMessage, ws.FormatCloseMessage(ws.CloseNormalClosure, ""))
+		err := t.conn.Close()
+		t.conn = nil
+		t.writeMutex.Unlock()
+		if t.server != nil {
+			t.server.Close()
+			t.server = nil
+		}
+		endChan <- err
+	}()
+	return endChan
+}
+
+func (t *WebSocketTransporter) topicName(command string, nodeID string) string {
+	parts := []string{t.prefix, command}
+	if nodeID != "" {
+		parts = append(parts, nodeID)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (t *WebSocketTransporter) send(message frame) error {
+	t.writeMutex.Lock()
+	defer t.writeMutex.Unlock()
+	if t.conn == nil {
+		return errors.New("WebSocket connection closed")
+	}
+	return t.conn.WriteJSON(message)
+}
+
+func (t *WebSocketTransporter) Subscribe(command, nodeID string, handler transit.TransportHandler) {
+	if t.conn == nil {
+		msg := fmt.Sprint("websocket.Subscribe() No connection :( -> command: ", command, " nodeID: ", nodeID)
+		t.logger.Warn(msg)
+		panic(errors.New(msg))
+	}
+
+	topic := t.topicName(command, nodeID)
+	t.handlersMutex.Lock()
+	t.handlers[topic] = append(t.handlers[topic], handler)
+	t.handlersMutex.Unlock()
+
+	if err := t.send(frame{Type: frameSubscribe, Topic: topic}); err != nil {
+		t.logger.Error("Cannot subscribe: ", topic, " error: ", err)
+	}
+}
+
+func (t *WebSocketTransporter) Publish(command, nodeID string, message moleculer.Payload) {
+	if t.conn == nil {
+		msg := fmt.Sprint("websocket.Publish() No connection :( -> command: ", command, " nodeID: ", nodeID)
+		t.logger.Warn(msg)
+		panic(errors.New(msg))
+	}
+
+	topic := t.topicName(command, nodeID)
+	t.logger.Debug("websocket.Publish() command: ", command, " topic: ", topic, " nodeID: ", nodeID)
+	t.logger.Trace("message: \n", message, "\n - end")
+	err := t.send(frame{Type: framePublish, Topic: topic, Data: t.serializer.PayloadToBytes(message)})
+	if err != nil {
+		t.logger.Error("Error on publish: error: ", err, " command: ", command, " topic: ", topic)
+		panic(err)
+	}
+}
+
+func (t *WebSocketTransporter) SetPrefix(prefix string) {
+	t.prefix = prefix
+}
+
+func (t *WebSocketTransporter) SetNodeID(nodeID string) {
+}
+
+func (t *WebSocketTransporter) SetSerializer(serializer serializer.Serializer) {
+	t.serializer = serializer
+}
@@ -0,0 +1,13 @@
+package websocket
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestWebSocket(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "WebSocket Suite")
+}
@@ -0,0 +1,110 @@
+package websocket
+
+import (
+	"sync"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/payload"
+	"github.com/moleculer-go/moleculer/serializer"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+	log "github.com/sirupsen/logrus"
+)
+
+var _ = Describe("WebSocket Transit", func() {
+	logger := log.WithField("unit test pkg", "websocket_test")
+	json := serializer.CreateJSONSerializer(logger)
+
+	It("should use moleculer-js compatible topic names", func() {
+		transporter := CreateWebSocketTransporter(WebSocketOptions{URL: "ws://localhost:5000", Logger: logger}).(*WebSocketTransporter)
+		transporter.SetPrefix("MOL")
+		Expect(transporter.topicName("REQ", "node-1")).Should(Equal("MOL.REQ.node-1"))
+		Expect(transporter.topicName("INFO", "")).Should(Equal("MOL.INFO"))
+	})
+
+	It("should fail to connect when there is no server", func() {
+		transporter := CreateWebSocketTransporter(WebSocketOptions{URL: "ws://127.0.0.1:1", Logger: logger, Serializer: json})
+		Expect(<-transporter.Connect()).Should(HaveOccurred())
+	})
+
+	It("should fail over to the next url when a server is not available", func() {
+		server := CreateWebSocketTransporter(WebSocketOptions{Listen: "127.0.0.1:0", Logger: logger, Serializer: json}).(*WebSocketTransporter)
+		Expect(<-server.Connect()).Should(Succeed())
+		defer func() { <-server.Disconnect() }()
+
+		client := CreateWebSocketTransporter(WebSocketOptions{URL: "ws://127.0.0.1:1,ws://" + server.Addr(), Logger: logger, Serializer: json})
+		Expect(<-client.Connect()).Should(Succeed())
+		<-client.Disconnect()
+	})
+
+	It("should notify when the connection to the server is lost", func() {
+		server := CreateWebSocketTransporter(WebSocketOptions{Listen: "127.0.0.1:0", Logger: logger, Serializer: json}).(*WebSocketTransporter)
+		Expect(<-server.Connect()).Should(Succeed())
+
+		client := CreateWebSocketTransporter(WebSocketOptions{URL: "ws://" + server.Addr(), Logger: logger, Serializer: json}).(*WebSocketTransporter)
+		Expect(<-client.Connect()).Should(Succeed())
+		dropped := make(chan error, 1)
+		client.OnDisconnect(func(err error) {
+			dropped <- err
+		})
+
+		<-server.Disconnect()
+		Eventually(dropped).Should(Receive(HaveOccurred()))
+		<-client.Disconnect()
+	})
+
+	It("should panic when publishing without a connection", func() {
+		transporter := CreateWebSocketTransporter(WebSocketOptions{URL: "ws://localhost:5000", Logger: logger})
+		Expect(func() {
+			transporter.Publish("INFO", "", nil)
+		}).Should(Panic())
+	})
+
+	It("should exchange messages through the embedded server", func() {
+		server := CreateWebSocketTransporter(WebSocketOptions{Listen: "127.0.0.1:0", Logger: logger, Serializer: json}).(*WebSocketTransporter)
+		server.SetPrefix("MOL")
+		Expect(<-server.Connect()).Should(Succeed())
+		defer func() { <-server.Disconnect() }()
+		Expect(server.Addr()).ShouldNot(BeEmpty())
+
+		client := CreateWebSocketTransporter(WebSocketOptions{URL: "ws://" + server.Addr(), Logger: logger, Serializer: json})
+		client.SetPrefix("MOL")
+		Expect(<-client.Connect()).Should(Succeed())
+		defer func() { <-client.Disconnect() }()
+
+		mutex := &sync.Mutex{}
+		received := []string{}
+		handler := func(name string) func(moleculer.Payload) {
+			return func(message moleculer.Payload) {
+				mutex.Lock()
+				received = append(received, name+":"+message.Get("sender").String())
+				mutex.Unlock()
+			}
+		}
+		server.Subscribe("INFO", "", handler("server"))
+		client.Subscribe("INFO", "", handler("client"))
+		client.Subscribe("REQ", "client", handler("client-req"))
+
+		count := func() int {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return len(received)
+		}
+
+		Eventually(func() int {
+			server.Publish("REQ", "client", payload.New(map[string]string{"sender": "server"}))
+			return count()
+		}).Should(BeNumerically(">", 0))
+
+		client.Publish("INFO", "", payload.New(map[string]string{"sender": "client"}))
+		Eventually(func() []string {
+			mutex.Lock()
+			defer mutex.Unlock()
+			return append([]string{}, received...)
+		}).Should(SatisfyAll(
+			ContainElement("server:client"),
+			ContainElement("client:client"),
+			ContainElement("client-req:server"),
+		))
+	})
+})
@@ -2,6 +2,7 @@ package util
 
 import (
 	"math/rand"
+	"sync"
 	"time"
 )
 
@@ -12,9 +13,13 @@ const (
 	letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
 )
 
+// randomSource is shared by all brokers of the process, rand.Source is not safe for concurrent use.
 var randomSource = rand.NewSource(time.Now().UnixNano())
+var randomMutex sync.Mutex
 
 func RandomString(size int) string {
+	randomMutex.Lock()
+	defer randomMutex.Unlock()
 	buffer := make([]byte, size)
 	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
 	for index, cache, remain := size-1, randomSource.Int63(), letterIdxMax; index >= 0; {
@@ -0,0 +1,267 @@
+package validator
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// rule is the compiled validation of a field.
+type rule struct {
+	Type     string
+	Optional bool
+	Min      *float64
+	Max      *float64
+	Pattern  *regexp.Regexp
+	Values   []interface{}
+	Props    map[string]*rule
+	Items    *rule
+	Options  map[string]interface{}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// compile create the rules of the params schema. The schema can be a map with the rules of each
+// field or a moleculer.ObjectSchema with a struct. It returns nil when the schema has no rules.
+func (validator *Validator) compile(schema moleculer.ActionSchema) (map[string]*rule, error) {
+	switch value := schema.(type) {
+	case nil:
+		return nil, nil
+	case map[string]interface{}:
+		return validator.compileProps(value)
+	case map[string]string:
+		props := make(map[string]interface{}, len(value))
+		for field, definition := range value {
+			props[field] = definition
+		}
+		return validator.compileProps(props)
+	case moleculer.ObjectSchema:
+		if value.Source == nil {
+			return nil, nil
+		}
+		if props, isMap := value.Source.(map[string]interface{}); isMap {
+			return validator.compileProps(props)
+		}
+		return validator.compileProps(validator.structProps(reflect.TypeOf(value.Source)))
+	}
+	return nil, fmt.Errorf("Invalid params schema type: %T", schema)
+}
+
+func (validator *Validator) compileProps(props map[string]interface{}) (map[string]*rule, error) {
+	if len(props) == 0 {
+		return nil, nil
+	}
+	rules := make(map[string]*rule, len(props))
+	for field, definition := range props {
+		compiled, err := validator.compileRule(definition)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid rule for field '%s': %s", field, err)
+		}
+		rules[field] = compiled
+	}
+	return rules, nil
+}
+
+// compileRule compile the shorthand form ("string|optional|min:3") or the map form
+// ({"type": "string", "optional": true, "min": 3}) of a rule.
+func (validator *Validator) compileRule(definition interface{}) (*rule, error) {
+	switch value := definition.(type) {
+	case string:
+		return validator.compileShorthand(value)
+	case map[string]interface{}:
+		return validator.compileMap(value)
+	case *rule:
+		return value, nil
+	}
+	return nil, fmt.Errorf("invalid rule definition: %v", definition)
+}
+
+func (validator *Validator) compileShorthand(definition string) (*rule, error) {
+	parts := strings.Split(definition, "|")
+	values := map[string]interface{}{"type": strings.TrimSpace(parts[0])}
+	for _, part := range parts[1:] {
+		option := strings.SplitN(strings.TrimSpace(part), ":", 2)
+		if len(option) == 1 {
+			values[option[0]] = true
+			continue
+		}
+		if option[0] == "values" {
+			items := []interface{}{}
+			for _, item := range strings.Split(option[1], ",") {
+				items = append(items, item)
+			}
+			values["values"] = items
+			continue
+		}
+		values[option[0]] = option[1]
+	}
+	return validator.compileMap(values)
+}
+
+func (validator *Validator) compileMap(values map[string]interface{}) (*rule, error) {
+	compiled := &rule{Type: "any", Options: values}
+	if ruleType, ok := values["type"].(string); ok && ruleType != "" {
+		compiled.Type = ruleType
+	}
+	if !validType(compiled.Type) && validator.customRule(compiled.Type) == nil {
+		return nil, fmt.Errorf("unknown type '%s'", compiled.Type)
+	}
+	compiled.Optional = isTrue(values["optional"])
+	var err error
+	if compiled.Min, err = numberOption(values, "min"); err != nil {
+		return nil, err
+	}
+	if compiled.Max, err = numberOption(values, "max"); err != nil {
+		return nil, err
+	}
+	if pattern, ok := values["pattern"].(string); ok {
+		if compiled.Pattern, err = regexp.Compile(pattern); err != nil {
+			return nil, err
+		}
+	}
+	if items, ok := values["values"].([]interface{}); ok {
+		compiled.Values = items
+	} else if items, ok := values["values"].([]string); ok {
+		for _, item := range items {
+			compiled.Values = append(compiled.Values, item)
+		}
+	}
+	if props, ok := values["props"].(map[string]interface{}); ok {
+		if compiled.Props, err = validator.compileProps(props); err != nil {
+			return nil, err
+		}
+	}
+	if items, exists := values["items"]; exists {
+		if compiled.Items, err = validator.compileRule(items); err != nil {
+			return nil, err
+		}
+	}
+	return compiled, nil
+}
+
+var validTypes = []string{"any", "string", "number", "boolean", "object", "array", "email", "enum", "date"}
+
+func validType(ruleType string) bool {
+	for _, item := range validTypes {
+		if item == ruleType {
+			return true
+		}
+	}
+	return false
+}
+
+func isTrue(value interface{}) bool {
+	switch option := value.(type) {
+	case bool:
+		return option
+	case string:
+		return option == "true"
+	}
+	return false
+}
+
+func numberOption(values map[string]interface{}, name string) (*float64, error) {
+	value, exists := values[name]
+	if !exists {
+		return nil, nil
+	}
+	if number, isNumber := toNumber(value); isNumber {
+		return &number, nil
+	}
+	if text, isString := value.(string); isString {
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s value '%s'", name, text)
+		}
+		return &number, nil
+	}
+	return nil, fmt.Errorf("invalid %s value '%v'", name, value)
+}
+
+// structProps create the rules of the exported fields of the struct. The field type defines
+// the rule type and the validate tag has the other options in the shorthand form, example:
+// `json:"name" validate:"optional|min:3"`. Pointer fields are optional and fields with
+// the tag validate:"-" are not validated.
+func (validator *Validator) structProps(structType reflect.Type) map[string]interface{} {
+	for structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	props := map[string]interface{}{}
+	if structType.Kind() != reflect.Struct {
+		return props
+	}
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("validate")
+		if field.PkgPath != "" || tag == "-" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		definition := typeName(field.Type)
+		if tag != "" {
+			definition = definition + "|" + tag
+		}
+		compiled, err := validator.compileShorthand(definition)
+		if err != nil {
+			panic(fmt.Errorf("Invalid validate tag for field '%s': %s", field.Name, err))
+		}
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			compiled.Optional = true
+		}
+		for fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		if compiled.Type == "object" && fieldType.Kind() == reflect.Struct {
+			compiled.Props, _ = validator.compileProps(validator.structProps(fieldType))
+		}
+		if compiled.Type == "array" && compiled.Items == nil {
+			compiled.Items, _ = validator.compileShorthand(typeName(fieldType.Elem()))
+			elemType := fieldType.Elem()
+			for elemType.Kind() == reflect.Ptr {
+				elemType = elemType.Elem()
+			}
+			if elemType.Kind() == reflect.Struct && elemType != timeType {
+				compiled.Items.Props, _ = validator.compileProps(validator.structProps(elemType))
+			}
+		}
+		props[name] = compiled
+	}
+	return props
+}
+
+// typeName return the rule type for the go type.
+func typeName(fieldType reflect.Type) string {
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType == timeType {
+		return "date"
+	}
+	switch fieldType.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	return "any"
+}
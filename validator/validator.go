@@ -0,0 +1,313 @@
+// Package validator validates the action params against the params schema declared in
+// the action, with rules similar to the moleculer-js fastest-validator.
+//
+// The schema is a map with the rule of each field, in the shorthand form:
+//
+//	"name": "string|min:3|max:50", "age": "number|optional|min:18", "role": "enum|values:admin,user"
+//
+// or the map form, used also for nested objects and lists:
+//
+//	"address": map[string]interface{}{"type": "object", "props": map[string]interface{}{"city": "string"}}
+//	"tags": map[string]interface{}{"type": "array", "items": "string", "max": 10}
+//
+// The schema can also be a moleculer.ObjectSchema with a struct, using the field types and
+// the options of the validate tag: `json:"name" validate:"min:3"`.
+package validator
+
+import (
+	"fmt"
+	"net/mail"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+)
+
+// FieldError is the error of a field that failed the validation.
+type FieldError struct {
+	Type     string
+	Field    string
+	Message  string
+	Expected interface{}
+	Actual   interface{}
+}
+
+// Map export the field error using the fastest-validator names.
+func (e FieldError) Map() map[string]interface{} {
+	values := map[string]interface{}{
+		"type":    e.Type,
+		"field":   e.Field,
+		"message": e.Message,
+	}
+	if e.Expected != nil {
+		values["expected"] = e.Expected
+	}
+	if e.Actual != nil {
+		values["actual"] = e.Actual
+	}
+	return values
+}
+
+var messages = map[string]string{
+	"required":      "The '{field}' field is required.",
+	"string":        "The '{field}' field must be a string.",
+	"stringMin":     "The '{field}' field length must be greater than or equal to {expected} characters long.",
+	"stringMax":     "The '{field}' field length must be less than or equal to {expected} characters long.",
+	"stringPattern": "The '{field}' field fails to match the required pattern.",
+	"number":        "The '{field}' field must be a number.",
+	"numberMin":     "The '{field}' field must be greater than or equal to {expected}.",
+	"numberMax":     "The '{field}' field must be less than or equal to {expected}.",
+	"boolean":       "The '{field}' field must be a boolean.",
+	"object":        "The '{field}' must be an Object.",
+	"array":         "The '{field}' field must be an array.",
+	"arrayMin":      "The '{field}' field must contain at least {expected} items.",
+	"arrayMax":      "The '{field}' field must contain less than or equal to {expected} items.",
+	"email":         "The '{field}' field must be a valid e-mail.",
+	"enumValue":     "The '{field}' field value '{actual}' does not match any of the allowed values.",
+	"date":          "The '{field}' field must be a Date.",
+}
+
+func fieldError(errorType, field string, expected, actual interface{}) FieldError {
+	message := strings.NewReplacer(
+		"{field}", field,
+		"{expected}", fmt.Sprint(expected),
+		"{actual}", fmt.Sprint(actual),
+	).Replace(messages[errorType])
+	return FieldError{Type: errorType, Field: field, Message: message, Expected: expected, Actual: actual}
+}
+
+// RuleFunc check a value of a custom rule type. It receives the rule options, and all
+// the params of the action, so it can compare the value with other fields.
+// The returned error message is used as the field error message.
+type RuleFunc func(value moleculer.Payload, options map[string]interface{}, params moleculer.Payload) error
+
+// Validator is the built-in params validator. Custom rule types can be added with AddRule.
+type Validator struct {
+	rules map[string]RuleFunc
+	mutex sync.RWMutex
+}
+
+// Create return a validator with the built-in rules.
+func Create() *Validator {
+	return &Validator{rules: map[string]RuleFunc{}}
+}
+
+var defaultValidator = Create()
+
+// Default return the validator used when no validator is set in the broker config.
+func Default() *Validator {
+	return defaultValidator
+}
+
+// AddRule add a custom rule type, which can be used in the schemas like the built-in types.
+// Example: "password2": "equalsField|field:password".
+func (validator *Validator) AddRule(name string, check RuleFunc) {
+	validator.mutex.Lock()
+	defer validator.mutex.Unlock()
+	validator.rules[name] = check
+}
+
+func (validator *Validator) customRule(name string) RuleFunc {
+	validator.mutex.RLock()
+	defer validator.mutex.RUnlock()
+	return validator.rules[name]
+}
+
+// Validate check the params against the schema with the default validator.
+func Validate(schema moleculer.ActionSchema, params moleculer.Payload) error {
+	return defaultValidator.Validate(schema, params)
+}
+
+// Validate check the params against the schema and return a ValidationError when any field is not valid.
+func (validator *Validator) Validate(schema moleculer.ActionSchema, params moleculer.Payload) error {
+	rules, err := validator.compile(schema)
+	if err != nil {
+		return err
+	}
+	if rules == nil {
+		return nil
+	}
+	fieldErrors := validator.validateProps(rules, "", params, params)
+	if len(fieldErrors) == 0 {
+		return nil
+	}
+	return ValidationError(fieldErrors)
+}
+
+// ValidationError create the error returned to the caller with the list of field errors as data.
+func ValidationError(fieldErrors []FieldError) *moleculer.Error {
+	data := make([]map[string]interface{}, len(fieldErrors))
+	for index, fieldError := range fieldErrors {
+		data[index] = fieldError.Map()
+	}
+	err := moleculer.NewError("Parameters validation error!", 422, "VALIDATION_ERROR", data)
+	err.Name = "ValidationError"
+	return err
+}
+
+func (validator *Validator) validateProps(rules map[string]*rule, prefix string, params, root moleculer.Payload) []FieldError {
+	fields := make([]string, 0, len(rules))
+	for field := range rules {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	fieldErrors := []FieldError{}
+	for _, field := range fields {
+		var value moleculer.Payload
+		if params != nil && params.IsMap() {
+			value = params.Get(field)
+		}
+		fieldErrors = append(fieldErrors, validator.validateRule(rules[field], prefix+field, value, root)...)
+	}
+	return fieldErrors
+}
+
+func isMissing(value moleculer.Payload) bool {
+	return value == nil || !value.Exists() || value.Value() == nil
+}
+
+func (validator *Validator) validateRule(fieldRule *rule, field string, value, root moleculer.Payload) []FieldError {
+	if isMissing(value) {
+		if fieldRule.Optional {
+			return nil
+		}
+		return []FieldError{fieldError("required", field, nil, nil)}
+	}
+	if check := validator.customRule(fieldRule.Type); check != nil {
+		if err := check(value, fieldRule.Options, root); err != nil {
+			return []FieldError{{Type: fieldRule.Type, Field: field, Message: err.Error(), Actual: value.Value()}}
+		}
+		return nil
+	}
+	switch fieldRule.Type {
+	case "string":
+		return validateString(fieldRule, field, value)
+	case "number":
+		return validateNumber(fieldRule, field, value)
+	case "boolean":
+		if _, isBool := value.Value().(bool); !isBool {
+			return []FieldError{fieldError("boolean", field, nil, value.Value())}
+		}
+	case "object":
+		if !value.IsMap() {
+			return []FieldError{fieldError("object", field, nil, value.Value())}
+		}
+		if fieldRule.Props != nil {
+			return validator.validateProps(fieldRule.Props, field+".", value, root)
+		}
+	case "array":
+		return validator.validateArray(fieldRule, field, value, root)
+	case "email":
+		text, isString := value.Value().(string)
+		if !isString {
+			return []FieldError{fieldError("string", field, nil, value.Value())}
+		}
+		if address, err := mail.ParseAddress(text); err != nil || address.Address != text {
+			return []FieldError{fieldError("email", field, nil, text)}
+		}
+	case "enum":
+		for _, allowed := range fieldRule.Values {
+			if fmt.Sprint(allowed) == fmt.Sprint(value.Value()) {
+				return nil
+			}
+		}
+		return []FieldError{fieldError("enumValue", field, fieldRule.Values, value.Value())}
+	case "date":
+		if _, isTime := value.Value().(time.Time); isTime {
+			return nil
+		}
+		if text, isString := value.Value().(string); isString {
+			if _, err := time.Parse(time.RFC3339Nano, text); err == nil {
+				return nil
+			}
+		}
+		return []FieldError{fieldError("date", field, nil, value.Value())}
+	}
+	return nil
+}
+
+func validateString(fieldRule *rule, field string, value moleculer.Payload) []FieldError {
+	text, isString := value.Value().(string)
+	if !isString {
+		return []FieldError{fieldError("string", field, nil, value.Value())}
+	}
+	length := float64(len([]rune(text)))
+	fieldErrors := []FieldError{}
+	if fieldRule.Min != nil && length < *fieldRule.Min {
+		fieldErrors = append(fieldErrors, fieldError("stringMin", field, *fieldRule.Min, len([]rune(text))))
+	}
+	if fieldRule.Max != nil && length > *fieldRule.Max {
+		fieldErrors = append(fieldErrors, fieldError("stringMax", field, *fieldRule.Max, len([]rune(text))))
+	}
+	if fieldRule.Pattern != nil && !fieldRule.Pattern.MatchString(text) {
+		fieldErrors = append(fieldErrors, fieldError("stringPattern", field, fieldRule.Pattern.String(), text))
+	}
+	return fieldErrors
+}
+
+func validateNumber(fieldRule *rule, field string, value moleculer.Payload) []FieldError {
+	number, isNumber := toNumber(value.Value())
+	if !isNumber {
+		return []FieldError{fieldError("number", field, nil, value.Value())}
+	}
+	fieldErrors := []FieldError{}
+	if fieldRule.Min != nil && number < *fieldRule.Min {
+		fieldErrors = append(fieldErrors, fieldError("numberMin", field, *fieldRule.Min, number))
+	}
+	if fieldRule.Max != nil && number > *fieldRule.Max {
+		fieldErrors = append(fieldErrors, fieldError("numberMax", field, *fieldRule.Max, number))
+	}
+	return fieldErrors
+}
+
+func (validator *Validator) validateArray(fieldRule *rule, field string, value, root moleculer.Payload) []FieldError {
+	if !value.IsArray() {
+		return []FieldError{fieldError("array", field, nil, value.Value())}
+	}
+	items := value.Array()
+	fieldErrors := []FieldError{}
+	if fieldRule.Min != nil && float64(len(items)) < *fieldRule.Min {
+		fieldErrors = append(fieldErrors, fieldError("arrayMin", field, *fieldRule.Min, len(items)))
+	}
+	if fieldRule.Max != nil && float64(len(items)) > *fieldRule.Max {
+		fieldErrors = append(fieldErrors, fieldError("arrayMax", field, *fieldRule.Max, len(items)))
+	}
+	if fieldRule.Items != nil {
+		for index, item := range items {
+			fieldErrors = append(fieldErrors, validator.validateRule(fieldRule.Items, fmt.Sprintf("%s[%d]", field, index), item, root)...)
+		}
+	}
+	return fieldErrors
+}
+
+func toNumber(value interface{}) (float64, bool) {
+	switch number := value.(type) {
+	case int:
+		return float64(number), true
+	case int8:
+		return float64(number), true
+	case int16:
+		return float64(number), true
+	case int32:
+		return float64(number), true
+	case int64:
+		return float64(number), true
+	case uint:
+		return float64(number), true
+	case uint8:
+		return float64(number), true
+	case uint16:
+		return float64(number), true
+	case uint32:
+		return float64(number), true
+	case uint64:
+		return float64(number), true
+	case float32:
+		return float64(number), true
+	case float64:
+		return number, true
+	}
+	return 0, false
+}
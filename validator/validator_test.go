@@ -0,0 +1,160 @@
+package validator
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/moleculer-go/moleculer"
+	"github.com/moleculer-go/moleculer/payload"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func fieldErrors(err error) []map[string]interface{} {
+	Expect(err).Should(HaveOccurred())
+	validationError, isValidationError := err.(*moleculer.Error)
+	Expect(isValidationError).Should(BeTrue())
+	Expect(validationError.Name).Should(Equal("ValidationError"))
+	Expect(validationError.Code).Should(Equal(422))
+	return validationError.Data.([]map[string]interface{})
+}
+
+type address struct {
+	City string `json:"city" validate:"min:2"`
+}
+
+type user struct {
+	Name     string    `json:"name" validate:"min:3"`
+	Email    string    `json:"email" validate:"type:email"`
+	Age      *int      `json:"age" validate:"min:18"`
+	Address  address   `json:"address"`
+	Tags     []string  `json:"tags" validate:"optional|max:2"`
+	Birthday time.Time `json:"birthday" validate:"optional"`
+	Internal string    `json:"-"`
+	ignored  string
+}
+
+var _ = Describe("Validator", func() {
+
+	It("should accept valid params", func() {
+		schema := map[string]interface{}{
+			"name":  "string|min:3|max:10",
+			"age":   "number|optional|min:18",
+			"role":  "enum|values:admin,user",
+			"email": "email",
+			"tags":  map[string]interface{}{"type": "array", "items": "string", "max": 3},
+			"address": map[string]interface{}{
+				"type":  "object",
+				"props": map[string]interface{}{"city": "string"},
+			},
+		}
+		params := payload.New(map[string]interface{}{
+			"name":    "John",
+			"role":    "admin",
+			"email":   "john@snow.com",
+			"tags":    []string{"stark", "snow"},
+			"address": map[string]interface{}{"city": "Winterfell"},
+		})
+		Expect(Validate(schema, params)).Should(Succeed())
+		Expect(Validate(nil, params)).Should(Succeed())
+		Expect(Validate(moleculer.ObjectSchema{Source: nil}, params)).Should(Succeed())
+	})
+
+	It("should return the field errors", func() {
+		schema := map[string]interface{}{
+			"name":   "string|min:3",
+			"age":    "number|min:18|max:99",
+			"role":   "enum|values:admin,user",
+			"active": "boolean",
+			"email":  "email",
+			"code":   "string|pattern:^[A-Z]+$",
+			"tags":   map[string]interface{}{"type": "array", "items": "number", "min": 1},
+			"address": map[string]interface{}{
+				"type":  "object",
+				"props": map[string]interface{}{"city": "string"},
+			},
+		}
+		errors := fieldErrors(Validate(schema, payload.New(map[string]interface{}{
+			"name":    "Jo",
+			"age":     12,
+			"role":    "guest",
+			"active":  "yes",
+			"email":   "not an email",
+			"code":    "abc",
+			"tags":    []interface{}{1, "two"},
+			"address": map[string]interface{}{},
+		})))
+		Expect(errors).Should(Equal([]map[string]interface{}{
+			{"type": "boolean", "field": "active", "message": "The 'active' field must be a boolean.", "actual": "yes"},
+			{"type": "required", "field": "address.city", "message": "The 'address.city' field is required."},
+			{"type": "numberMin", "field": "age", "message": "The 'age' field must be greater than or equal to 18.", "expected": float64(18), "actual": float64(12)},
+			{"type": "stringPattern", "field": "code", "message": "The 'code' field fails to match the required pattern.", "expected": "^[A-Z]+$", "actual": "abc"},
+			{"type": "email", "field": "email", "message": "The 'email' field must be a valid e-mail.", "actual": "not an email"},
+			{"type": "stringMin", "field": "name", "message": "The 'name' field length must be greater than or equal to 3 characters long.", "expected": float64(3), "actual": 2},
+			{"type": "enumValue", "field": "role", "message": "The 'role' field value 'guest' does not match any of the allowed values.", "expected": []interface{}{"admin", "user"}, "actual": "guest"},
+			{"type": "number", "field": "tags[1]", "message": "The 'tags[1]' field must be a number.", "actual": "two"},
+		}))
+	})
+
+	It("should require the fields when the params are missing", func() {
+		errors := fieldErrors(Validate(map[string]interface{}{"a": "any", "b": "string|optional"}, payload.New(nil)))
+		Expect(errors).Should(HaveLen(1))
+		Expect(errors[0]["type"]).Should(Equal("required"))
+		Expect(errors[0]["field"]).Should(Equal("a"))
+	})
+
+	It("should validate struct schemas", func() {
+		schema := moleculer.ObjectSchema{Source: user{}}
+		age := 20
+		valid := payload.Create(user{Name: "John", Email: "john@snow.com", Age: &age, Address: address{City: "Winterfell"}})
+		Expect(Validate(schema, valid)).Should(Succeed())
+
+		errors := fieldErrors(Validate(schema, payload.New(map[string]interface{}{
+			"name":    "Jo",
+			"email":   "john",
+			"age":     10,
+			"address": map[string]interface{}{"city": "W"},
+			"tags":    []string{"a", "b", "c"},
+		})))
+		fields := []interface{}{}
+		for _, item := range errors {
+			fields = append(fields, item["type"].(string)+":"+item["field"].(string))
+		}
+		Expect(fields).Should(Equal([]interface{}{
+			"stringMin:address.city", "numberMin:age", "email:email", "stringMin:name", "arrayMax:tags",
+		}))
+	})
+
+	It("should validate custom rules with access to the other params", func() {
+		validator := Create()
+		validator.AddRule("equalsField", func(value moleculer.Payload, options map[string]interface{}, params moleculer.Payload) error {
+			field := options["field"].(string)
+			if value.String() != params.Get(field).String() {
+				return fmt.Errorf("The '%s' field must be equal to '%s'.", "password2", field)
+			}
+			return nil
+		})
+		schema := map[string]interface{}{
+			"password":  "string|min:6",
+			"password2": "equalsField|field:password",
+		}
+		Expect(validator.Validate(schema, payload.New(map[string]interface{}{
+			"password": "secret", "password2": "secret",
+		}))).Should(Succeed())
+
+		errors := fieldErrors(validator.Validate(schema, payload.New(map[string]interface{}{
+			"password": "secret", "password2": "other",
+		})))
+		Expect(errors).Should(Equal([]map[string]interface{}{
+			{"type": "equalsField", "field": "password2", "message": "The 'password2' field must be equal to 'password'.", "actual": "other"},
+		}))
+
+		Expect(Validate(schema, payload.Empty())).Should(MatchError("Invalid rule for field 'password2': unknown type 'equalsField'"))
+	})
+
+	It("should fail for invalid schemas", func() {
+		Expect(Validate(map[string]interface{}{"a": "unknown"}, payload.Empty())).Should(MatchError("Invalid rule for field 'a': unknown type 'unknown'"))
+		Expect(Validate(map[string]interface{}{"a": "string|min:x"}, payload.Empty())).Should(HaveOccurred())
+		Expect(Validate("schema", payload.Empty())).Should(MatchError("Invalid params schema type: string"))
+	})
+})
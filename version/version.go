@@ -6,7 +6,22 @@ func Moleculer() string {
 }
 
 func MoleculerProtocol() string {
-	return "3"
+	return "4"
+}
+
+// SupportedProtocols list the protocol versions this node can communicate with.
+func SupportedProtocols() []string {
+	return []string{"3", "4"}
+}
+
+// IsSupportedProtocol check if the protocol version is one of the SupportedProtocols.
+func IsSupportedProtocol(protocol string) bool {
+	for _, item := range SupportedProtocols() {
+		if item == protocol {
+			return true
+		}
+	}
+	return false
 }
 
 func Go() string {